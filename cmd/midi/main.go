@@ -0,0 +1,102 @@
+// Command midi gathers tools for working with MIDI setups.
+//
+// The doctor subcommand checks for common problems — no devices found, devices held by
+// another application, driver errors and missing permissions — and prints steps to fix
+// them:
+//
+//	midi doctor
+//	midi doctor -backend serial -ports /dev/ttyAMA0
+//	midi doctor -backend remote -address studio.local:7000
+//	midi doctor -backend remote -address venue.example.com:7000 -tls -token $MIDI_TOKEN
+//
+// It exits with status 1 when it finds a problem.
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/doctor"
+)
+
+// usage describes the subcommands.
+const usage = `Usage: midi <command> [flags]
+
+Commands:
+  doctor  check the MIDI setup for common problems and suggest fixes
+
+Run "midi <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "doctor":
+		err = runDoctor(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "midi: unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "midi:", err)
+		os.Exit(1)
+	}
+}
+
+// errProblems is returned by runDoctor when the report has problems, which it already printed.
+var errProblems = errors.New("problems found")
+
+// runDoctor diagnoses the setup selected by args and prints the report.
+func runDoctor(args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	backend := flags.String("backend", "", "backend to check (remote, serial, usb); the native backend of the OS by default")
+	address := flags.String("address", "", "address (host:port) of the server, for the remote backend")
+	useTLS := flags.Bool("tls", false, "connect to the server over TLS, verifying its certificate with the system roots, for the remote backend")
+	token := flags.String("token", "", "token accepted by the server, for the remote backend")
+	ports := flags.String("ports", "", "comma-separated serial ports to check, for the serial backend; all ports by default")
+	baud := flags.Int("baud", 0, "speed of the serial ports (default 31250)")
+	noOpen := flags.Bool("no-open", false, "only list devices instead of opening each of them, which may interrupt applications using them")
+	flags.Parse(args)
+
+	clientOptions := []contracts.Option{contracts.WithBackend(*backend)}
+	if *address != "" || *useTLS || *token != "" {
+		config := contracts.RemoteConfig{Address: *address, Token: *token}
+		if *useTLS {
+			config.TLS = &tls.Config{}
+		}
+		clientOptions = append(clientOptions, contracts.WithRemoteConfig(config))
+	}
+	if *ports != "" || *baud != 0 {
+		config := contracts.SerialConfig{BaudRate: *baud}
+		if *ports != "" {
+			config.Ports = strings.Split(*ports, ",")
+		}
+		clientOptions = append(clientOptions, contracts.WithSerialConfig(config))
+	}
+
+	opts := []doctor.Option{doctor.WithClientOptions(clientOptions...)}
+	if *noOpen {
+		opts = append(opts, doctor.WithoutOpeningDevices())
+	}
+	report := doctor.Run(opts...)
+	if _, err := report.WriteTo(os.Stdout); err != nil {
+		return err
+	}
+	if !report.Healthy() {
+		return errProblems
+	}
+	return nil
+}
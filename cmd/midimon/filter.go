@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/notes"
+	"github.com/leandrodaf/midi/sdk/sink"
+)
+
+// filter holds the filter flags and keeps the events they select.
+type filter struct {
+	channels    numberList // Channels shown, from 1 to 16; all when empty.
+	types       typeList   // Message types shown, as named by sink.MessageType; all when empty.
+	notes       noteRange  // Notes of note messages shown; all when unset.
+	controllers numberList // Controllers of control changes shown; all when empty.
+	realtime    bool       // Whether clock and active sensing messages are shown.
+}
+
+// keep reports whether event passes the filter. It is the event predicate of the client.
+func (f *filter) keep(event contracts.MIDI) bool {
+	if !f.realtime && (event.Command == 0xF8 || event.Command == 0xFE) {
+		return false
+	}
+	if len(f.types) > 0 && !slices.Contains(f.types, sink.MessageType(event)) {
+		return false
+	}
+	isChannel := event.Command >= 0x80 && event.Command < 0xF0
+	if len(f.channels.values) > 0 && (!isChannel || !slices.Contains(f.channels.values, int(event.Channel())+1)) {
+		return false
+	}
+	switch event.Command & 0xF0 {
+	case 0x80, 0x90, 0xA0:
+		return !f.notes.set || event.Note >= f.notes.low && event.Note <= f.notes.high
+	case 0xB0:
+		return len(f.controllers.values) == 0 || slices.Contains(f.controllers.values, int(event.Note))
+	}
+	return true
+}
+
+// numberList is a flag of comma-separated numbers within bounds.
+type numberList struct {
+	values    []int
+	low, high int // Bounds of the numbers, inclusive.
+}
+
+// String returns the numbers separated by commas.
+func (l *numberList) String() string {
+	parts := make([]string, len(l.values))
+	for i, n := range l.values {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Set parses comma-separated numbers.
+func (l *numberList) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < l.low || n > l.high {
+			return fmt.Errorf("invalid number %q, not from %d to %d", part, l.low, l.high)
+		}
+		l.values = append(l.values, n)
+	}
+	return nil
+}
+
+// typeAliases maps the short names accepted by -type to the names of sink.MessageType.
+var typeAliases = map[string][]string{
+	"note":       {"note_on", "note_off"},
+	"cc":         {"control_change"},
+	"pc":         {"program_change"},
+	"program":    {"program_change"},
+	"aftertouch": {"poly_aftertouch", "channel_aftertouch"},
+	"pb":         {"pitch_bend"},
+	"bend":       {"pitch_bend"},
+}
+
+// messageTypes are the names of sink.MessageType.
+var messageTypes = []string{
+	"note_on", "note_off", "poly_aftertouch", "control_change", "program_change",
+	"channel_aftertouch", "pitch_bend", "system",
+}
+
+// typeList is a flag of comma-separated message types.
+type typeList []string
+
+// String returns the message types separated by commas.
+func (l *typeList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set parses comma-separated message types and their aliases.
+func (l *typeList) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case typeAliases[name] != nil:
+			*l = append(*l, typeAliases[name]...)
+		case slices.Contains(messageTypes, name):
+			*l = append(*l, name)
+		default:
+			return fmt.Errorf("unknown message type %q", part)
+		}
+	}
+	return nil
+}
+
+// noteRange is a flag of a range of notes, such as "C2-C4", "36-60" or a single note.
+type noteRange struct {
+	low, high byte
+	set       bool
+}
+
+// String returns the range as note names.
+func (r *noteRange) String() string {
+	if !r.set {
+		return ""
+	}
+	return notes.Name(r.low) + "-" + notes.Name(r.high)
+}
+
+// Set parses a range of note names or numbers separated by "-" or ":", or a single note.
+func (r *noteRange) Set(value string) error {
+	if low, high, ok := strings.Cut(value, ":"); ok {
+		return r.setRange(low, high)
+	}
+	// Octaves can be negative, as in C-1-C1, so every dash is tried as the separator.
+	for i := 1; i < len(value); i++ {
+		if value[i] == '-' && r.setRange(value[:i], value[i+1:]) == nil {
+			return nil
+		}
+	}
+	return r.setRange(value, value)
+}
+
+// setRange sets the range from its two ends, in any order.
+func (r *noteRange) setRange(low, high string) error {
+	a, err := parseNote(low)
+	if err != nil {
+		return err
+	}
+	b, err := parseNote(high)
+	if err != nil {
+		return err
+	}
+	r.low, r.high, r.set = min(a, b), max(a, b), true
+	return nil
+}
+
+// parseNote parses a note name or number.
+func parseNote(text string) (byte, error) {
+	text = strings.TrimSpace(text)
+	if n, err := strconv.Atoi(text); err == nil {
+		if n < 0 || n > 127 {
+			return 0, fmt.Errorf("invalid note %q", text)
+		}
+		return byte(n), nil
+	}
+	return notes.Parse(text)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/implchart"
+	"github.com/leandrodaf/midi/sdk/notes"
+	"github.com/leandrodaf/midi/sdk/sink"
+)
+
+// systemNames are the names of the system messages.
+var systemNames = map[byte]string{
+	0xF1: "mtc_quarter_frame",
+	0xF2: "song_position",
+	0xF3: "song_select",
+	0xF6: "tune_request",
+	0xF8: "clock",
+	0xFA: "start",
+	0xFB: "continue",
+	0xFC: "stop",
+	0xFE: "active_sensing",
+	0xFF: "reset",
+}
+
+// messageName names the kind of message of event: the names of sink.MessageType, with
+// system messages named individually.
+func messageName(event contracts.MIDI) string {
+	if event.Command >= 0xF0 {
+		if name, ok := systemNames[event.Command]; ok {
+			return name
+		}
+	}
+	return sink.MessageType(event)
+}
+
+// describe returns the data of event in words, such as "C4 (60) velocity 100" or
+// "Sustain (64) 127".
+func describe(event contracts.MIDI) string {
+	switch event.Command & 0xF0 {
+	case 0x80, 0x90:
+		return fmt.Sprintf("%-4s (%3d) velocity %3d", notes.Name(event.Note), event.Note, event.Velocity)
+	case 0xA0:
+		return fmt.Sprintf("%-4s (%3d) pressure %3d", notes.Name(event.Note), event.Note, event.Velocity)
+	case 0xB0:
+		name := implchart.ControllerName(int(event.Note))
+		if name == "" {
+			name = "Controller"
+		}
+		return fmt.Sprintf("%s (%d) %d", name, event.Note, event.Velocity)
+	case 0xC0:
+		return fmt.Sprintf("program %d", int(event.Note)+1)
+	case 0xD0:
+		return fmt.Sprintf("pressure %d", event.Note)
+	case 0xE0:
+		return fmt.Sprintf("%+d", (int(event.Velocity)<<7|int(event.Note))-8192)
+	}
+	switch event.Command {
+	case 0xF1:
+		return fmt.Sprintf("piece %d value %d", event.Note>>4, event.Note&0x0F)
+	case 0xF2:
+		return fmt.Sprintf("%d sixteenths", int(event.Velocity)<<7|int(event.Note))
+	case 0xF3:
+		return fmt.Sprintf("song %d", event.Note)
+	}
+	if _, ok := systemNames[event.Command]; ok {
+		return ""
+	}
+	return fmt.Sprintf("0x%02X %d %d", event.Command, event.Note, event.Velocity)
+}
+
+// channelLabel returns the one-based channel of a channel message, or "-" for system
+// messages.
+func channelLabel(event contracts.MIDI) string {
+	if event.Command >= 0xF0 {
+		return "-"
+	}
+	return fmt.Sprint(event.Channel() + 1)
+}
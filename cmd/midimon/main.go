@@ -0,0 +1,212 @@
+// Command midimon monitors MIDI devices: it lists them, prints the events they send
+// decoded with note names, controller names and channels, and can save the events to a
+// Standard MIDI File or to JSON lines for later analysis.
+//
+//	midimon -list
+//	midimon -device Keystation
+//	midimon -device all -channel 1,10 -type note,cc
+//	midimon -device 0 -notes C2-C4 -smf take.mid -json take.jsonl
+//	midimon -backend remote -address studio.local:7000 -cc 1,64
+//
+// Without -device, the devices are listed and one is asked for. Clock and active sensing
+// messages are hidden unless -realtime is set. It runs until interrupted or for -duration.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/midi"
+)
+
+func main() {
+	list := flag.Bool("list", false, "list the input and output devices and exit")
+	device := flag.String("device", "", `comma-separated IDs or names (or parts of names) of the devices to monitor, or "all"; asked for by default`)
+	backend := flag.String("backend", "", "backend to use (remote, serial, loopback, ...); the native backend of the OS by default")
+	address := flag.String("address", "", "address (host:port) of the server, for the remote backend")
+	duration := flag.Duration("duration", 0, "stop after this time instead of waiting for an interrupt")
+	quiet := flag.Bool("quiet", false, "do not print events, e.g. when only saving them")
+	smfPath := flag.String("smf", "", "save the events to this Standard MIDI File")
+	jsonPath := flag.String("json", "", `save the events to this file as JSON lines, or "-" to print them as such`)
+	selection := filter{channels: numberList{low: 1, high: 16}, controllers: numberList{low: 0, high: 127}}
+	flag.Var(&selection.channels, "channel", "comma-separated channels (1-16) to show; all by default")
+	flag.Var(&selection.types, "type", "comma-separated message types to show: note, note_on, note_off, cc, pc, aftertouch, poly_aftertouch, channel_aftertouch, pitch_bend, system; all by default")
+	flag.Var(&selection.notes, "notes", "range of notes of note messages to show, such as C2-C4 or 36-60")
+	flag.Var(&selection.controllers, "cc", "comma-separated controller numbers of control changes to show")
+	flag.BoolVar(&selection.realtime, "realtime", false, "show clock and active sensing messages")
+	flag.Parse()
+
+	clientOptions := []contracts.Option{
+		contracts.WithBackend(*backend),
+		contracts.WithoutLogging(),
+		contracts.WithTimestampClock(contracts.WallClock),
+		contracts.WithEventPredicate(selection.keep),
+	}
+	if *address != "" {
+		clientOptions = append(clientOptions, contracts.WithRemoteConfig(contracts.RemoteConfig{Address: *address}))
+	}
+
+	var err error
+	if *list {
+		err = listDevices(clientOptions)
+	} else {
+		err = monitor(*device, *duration, output{quiet: *quiet, smf: *smfPath, json: *jsonPath}, clientOptions)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "midimon:", err)
+		os.Exit(1)
+	}
+}
+
+// listDevices prints the input and output devices of the backend.
+func listDevices(clientOptions []contracts.Option) error {
+	client, err := midi.NewMIDIClient(clientOptions...)
+	if err != nil {
+		return err
+	}
+	defer client.Stop()
+
+	inputs, err := client.ListDevices()
+	if err != nil {
+		return err
+	}
+	printDevices("Inputs", inputs)
+	if outputs, err := client.ListOutputDevices(); err == nil {
+		printDevices("Outputs", outputs)
+	}
+	return nil
+}
+
+// printDevices prints a list of devices under a title.
+func printDevices(title string, devices []contracts.DeviceInfo) {
+	fmt.Printf("%s:\n", title)
+	if len(devices) == 0 {
+		fmt.Println("  none")
+	}
+	for id, device := range devices {
+		label := device.Name
+		if device.Manufacturer != "" {
+			label += " (" + device.Manufacturer + ")"
+		}
+		if device.Offline {
+			label += " [offline]"
+		}
+		fmt.Printf("  %d: %s\n", id, label)
+	}
+}
+
+// source is a client or group of clients capturing the monitored devices.
+type source interface {
+	StartCapture(eventChannel chan contracts.MIDI) error
+	Stop() error
+}
+
+// monitor captures the devices selected by device and writes their events to out until
+// interrupted or until duration has passed.
+func monitor(device string, duration time.Duration, out output, clientOptions []contracts.Option) error {
+	client, err := midi.NewMIDIClient(clientOptions...)
+	if err != nil {
+		return err
+	}
+	devices, err := client.ListDevices()
+	if err != nil {
+		client.Stop()
+		return err
+	}
+
+	var capture source = client
+	switch ids, err := deviceIDs(device, devices); {
+	case err != nil:
+		client.Stop()
+		return err
+	case len(ids) == 0:
+		if _, err := midi.PromptSelectDevice(client, os.Stdin, os.Stderr); err != nil {
+			client.Stop()
+			return err
+		}
+	case len(ids) == 1:
+		if err := client.SelectDevice(ids[0]); err != nil {
+			client.Stop()
+			return err
+		}
+	default:
+		client.Stop()
+		if capture, err = midi.NewInputGroup(ids, clientOptions...); err != nil {
+			return err
+		}
+	}
+	defer capture.Stop()
+
+	w, err := out.open(devices)
+	if err != nil {
+		return err
+	}
+	events := make(chan contracts.MIDI, 1024)
+	if err := capture.StartCapture(events); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+	for {
+		select {
+		case event := <-events:
+			if err := w.write(event); err != nil {
+				w.close()
+				return err
+			}
+		case <-ctx.Done():
+			return w.close()
+		}
+	}
+}
+
+// deviceIDs returns the IDs of the devices selected by the -device flag: "all", or
+// comma-separated IDs, names or parts of names. It returns none for an empty flag.
+func deviceIDs(flagValue string, devices []contracts.DeviceInfo) ([]int, error) {
+	if strings.TrimSpace(flagValue) == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(strings.TrimSpace(flagValue), "all") {
+		if len(devices) == 0 {
+			return nil, contracts.ErrNoDevices
+		}
+		ids := make([]int, len(devices))
+		for id := range devices {
+			ids[id] = id
+		}
+		return ids, nil
+	}
+
+	var ids []int
+	for _, part := range strings.Split(flagValue, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("%w: empty device name in %q", midi.ErrDeviceNotFound, flagValue)
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			id = midi.FindDevice(devices, midi.MatchName(part))
+			if id < 0 {
+				id = midi.FindDevice(devices, midi.MatchSubstring(part))
+			}
+		}
+		if id < 0 || id >= len(devices) {
+			return nil, fmt.Errorf("%w: %s", midi.ErrDeviceNotFound, part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/implchart"
+	"github.com/leandrodaf/midi/sdk/notes"
+	"github.com/leandrodaf/midi/sdk/smf"
+)
+
+// output holds the output flags: where events are printed and saved.
+type output struct {
+	quiet bool   // Whether printing is disabled.
+	smf   string // Path of the Standard MIDI File events are saved to; none when empty.
+	json  string // Path of the JSON lines file events are saved to, "-" for the standard output; none when empty.
+}
+
+// jsonEvent is an event as saved to JSON lines.
+type jsonEvent struct {
+	Time       time.Time `json:"time"`                 // Time the event was received.
+	Timestamp  uint64    `json:"timestamp"`            // Timestamp of the event, from the backend.
+	Device     string    `json:"device,omitempty"`     // Name of the device the event came from.
+	Type       string    `json:"type"`                 // Kind of message, as printed.
+	Channel    int       `json:"channel,omitempty"`    // One-based channel of channel messages.
+	Note       string    `json:"note,omitempty"`       // Name of the note of note messages.
+	Controller string    `json:"controller,omitempty"` // Name of the controller of control changes.
+	Data       [3]byte   `json:"data"`                 // Status and data bytes.
+}
+
+// writer prints and saves events as selected by an output.
+type writer struct {
+	devices  []contracts.DeviceInfo // Devices of the backend, naming the sources of events.
+	start    time.Time              // Time monitoring started; printed times are relative to it.
+	print    io.Writer              // Destination of printed events; nil when not printing.
+	file     *os.File               // Standard MIDI File; nil when not saving one.
+	stream   *smf.Stream            // Writes the Standard MIDI File.
+	json     *json.Encoder          // Writes JSON lines; nil when not saving them.
+	jsonOut  *bufio.Writer          // Buffers the JSON lines.
+	jsonFile *os.File               // File of the JSON lines; nil for the standard output.
+}
+
+// open creates the files of the output and returns a writer for them. Events are not
+// printed when JSON lines go to the standard output.
+func (o output) open(devices []contracts.DeviceInfo) (w *writer, err error) {
+	w = &writer{devices: devices, start: time.Now()}
+	defer func() {
+		if err != nil {
+			w.close()
+		}
+	}()
+	if !o.quiet && o.json != "-" {
+		w.print = os.Stdout
+	}
+	if o.smf != "" {
+		if w.file, err = os.Create(o.smf); err != nil {
+			return nil, err
+		}
+		origin := uint64(w.start.UnixNano())
+		if w.stream, err = smf.NewStream(w.file, smf.WithName("midimon"), smf.WithOrigin(origin)); err != nil {
+			return nil, err
+		}
+	}
+	switch o.json {
+	case "":
+	case "-":
+		w.jsonOut = bufio.NewWriter(os.Stdout)
+	default:
+		if w.jsonFile, err = os.Create(o.json); err != nil {
+			return nil, err
+		}
+		w.jsonOut = bufio.NewWriter(w.jsonFile)
+	}
+	if w.jsonOut != nil {
+		w.json = json.NewEncoder(w.jsonOut)
+	}
+	if w.print != nil {
+		fmt.Fprintf(w.print, "%10s  %-16s  %-2s  %-18s  %s\n", "TIME", "DEVICE", "CH", "MESSAGE", "DATA")
+	}
+	return w, nil
+}
+
+// write prints and saves event, received now.
+func (w *writer) write(event contracts.MIDI) error {
+	now := time.Now()
+	device := ""
+	if event.SourceDeviceID >= 0 && event.SourceDeviceID < len(w.devices) {
+		device = w.devices[event.SourceDeviceID].Name
+	}
+	if w.print != nil {
+		fmt.Fprintf(w.print, "%10.3f  %-16.16s  %-2s  %-18s  %s\n",
+			now.Sub(w.start).Seconds(), device, channelLabel(event), messageName(event), describe(event))
+	}
+	if w.stream != nil {
+		// Timestamps differ between backends, so the file is timed by arrival.
+		stamped := event
+		stamped.Timestamp = uint64(now.UnixNano())
+		if err := w.stream.Write(stamped); err != nil {
+			return err
+		}
+	}
+	if w.json != nil {
+		record := jsonEvent{
+			Time:      now,
+			Timestamp: event.Timestamp,
+			Device:    device,
+			Type:      messageName(event),
+			Data:      [3]byte{event.Command, event.Note, event.Velocity},
+		}
+		if event.Command < 0xF0 {
+			record.Channel = int(event.Channel()) + 1
+		}
+		switch event.Command & 0xF0 {
+		case 0x80, 0x90, 0xA0:
+			record.Note = notes.Name(event.Note)
+		case 0xB0:
+			record.Controller = implchart.ControllerName(int(event.Note))
+		}
+		if err := w.json.Encode(record); err != nil {
+			return err
+		}
+		if w.jsonFile == nil {
+			return w.jsonOut.Flush()
+		}
+	}
+	return nil
+}
+
+// close completes the Standard MIDI File and flushes and closes the files.
+func (w *writer) close() error {
+	var errs []error
+	if w.stream != nil {
+		errs = append(errs, w.stream.Close())
+	}
+	if w.file != nil {
+		errs = append(errs, w.file.Close())
+	}
+	if w.jsonOut != nil {
+		errs = append(errs, w.jsonOut.Flush())
+	}
+	if w.jsonFile != nil {
+		errs = append(errs, w.jsonFile.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,115 @@
+// Command vkeyboard plays MIDI notes from the computer keyboard into the loopback backend,
+// for exercising applications without hardware.
+//
+// By default the captured events are printed. With -listen, the loopback device is shared
+// over gRPC instead, so another process can capture it with the remote backend:
+//
+//	vkeyboard -listen :7000
+//
+// and, in the application:
+//
+//	midi.NewMIDIClient(
+//		contracts.WithBackend(contracts.BackendRemote),
+//		contracts.WithRemoteConfig(contracts.RemoteConfig{Address: "localhost:7000"}),
+//	)
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/leandrodaf/midi/internal/logger"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/keyboard"
+	"github.com/leandrodaf/midi/sdk/midi"
+	"github.com/leandrodaf/midi/sdk/remote"
+	"github.com/leandrodaf/midi/sdk/sink"
+	"golang.org/x/term"
+)
+
+func main() {
+	listen := flag.String("listen", "", "share the loopback device over gRPC on this address instead of printing events")
+	channel := flag.Int("channel", 1, "MIDI channel of the notes (1-16)")
+	octave := flag.Int("octave", 3, "octave of the lower key row")
+	velocity := flag.Int("velocity", 100, "velocity of the notes (1-127)")
+	length := flag.Duration("length", 0, "time after which notes are released (default 300ms)")
+	flag.Parse()
+
+	if err := run(*listen, keyboard.WithChannel(byte(*channel-1)), keyboard.WithOctave(*octave),
+		keyboard.WithVelocity(byte(*velocity)), keyboard.WithNoteLength(*length)); err != nil {
+		fmt.Fprintln(os.Stderr, "vkeyboard:", err)
+		os.Exit(1)
+	}
+}
+
+// run plays the keyboard into a loopback client until the user quits.
+func run(listen string, opts ...keyboard.Option) error {
+	client, err := midi.NewMIDIClient(contracts.WithBackend(contracts.BackendLoopback), contracts.WithoutLogging())
+	if err != nil {
+		return err
+	}
+	defer client.Stop()
+
+	sender, ok := client.(keyboard.Sender)
+	if !ok {
+		return errors.New("loopback backend does not accept events")
+	}
+	if err := client.SelectDevice(0); err != nil {
+		return err
+	}
+
+	out := &terminal{w: os.Stdout}
+	if listen != "" {
+		lis, err := net.Listen("tcp", listen)
+		if err != nil {
+			return err
+		}
+		server := remote.NewServer(client, remote.WithServerLogger(logger.NewNopLogger()))
+		go server.Serve(lis)
+		defer server.Stop()
+		fmt.Fprintf(out, "Sharing the loopback device on %s\r\n\r\n", lis.Addr())
+	} else {
+		events := make(chan contracts.MIDI, 64)
+		if err := client.StartCapture(events); err != nil {
+			return err
+		}
+		go printEvents(events, out)
+	}
+
+	// Raw mode delivers every keypress immediately; piped input is read as is.
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+	}
+
+	return keyboard.New(sender, opts...).Run(os.Stdin, out)
+}
+
+// printEvents prints captured events above the status line.
+func printEvents(events chan contracts.MIDI, out io.Writer) {
+	for event := range events {
+		fmt.Fprintf(out, "\r\x1b[K%-15s channel %-2d data %3d %3d\r\n",
+			sink.MessageType(event), sink.Channel(event)+1, event.Note, event.Velocity)
+	}
+}
+
+// terminal serializes writes of the keyboard and the event printer.
+type terminal struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write writes p to the terminal.
+func (t *terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.w.Write(p)
+}
@@ -3,9 +3,48 @@ module github.com/leandrodaf/midi
 go 1.23.2
 
 require (
-	github.com/youpy/go-coremidi v0.0.0-20210828055444-d16028a71dfe
+	github.com/coder/websocket v1.8.14
+	github.com/ebitengine/oto/v3 v3.3.3
+	github.com/google/gousb v1.1.3
+	github.com/grandcat/zeroconf v1.0.0
+	github.com/parquet-go/parquet-go v0.25.0
+	github.com/rs/zerolog v1.35.1
+	github.com/sirupsen/logrus v1.9.3
+	go.bug.st/serial v1.6.2
+	go.etcd.io/bbolt v1.4.0
 	go.uber.org/zap v1.27.0
-	golang.org/x/sys v0.26.0
+	golang.org/x/sys v0.29.0
+	golang.org/x/term v0.27.0
+	google.golang.org/grpc v1.70.0
+	google.golang.org/protobuf v1.35.2
+	tinygo.org/x/bluetooth v0.14.0
 )
 
-require go.uber.org/multierr v1.11.0 // indirect
+require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/cenkalti/backoff v2.2.1+incompatible // indirect
+	github.com/creack/goselect v0.1.2 // indirect
+	github.com/ebitengine/purego v0.8.0 // indirect
+	github.com/go-ole/go-ole v1.2.6 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/mattn/go-colorable v0.1.14 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/mattn/go-runewidth v0.0.15 // indirect
+	github.com/miekg/dns v1.1.27 // indirect
+	github.com/olekukonko/tablewriter v0.0.5 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	github.com/rivo/uniseg v0.4.7 // indirect
+	github.com/saltosystems/winrt-go v0.0.0-20240509164145-4f7860a3bd2b // indirect
+	github.com/soypat/cyw43439 v0.0.0-20250505012923-830110c8f4af // indirect
+	github.com/soypat/seqs v0.0.0-20250124201400-0d65bc7c1710 // indirect
+	github.com/tinygo-org/cbgo v0.0.4 // indirect
+	github.com/tinygo-org/pio v0.2.0 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/crypto v0.30.0 // indirect
+	golang.org/x/exp v0.0.0-20241204233417-43b7b7cde48d // indirect
+	golang.org/x/net v0.32.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241202173237-19429a94021a // indirect
+)
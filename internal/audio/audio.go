@@ -0,0 +1,8 @@
+// Package audio plays rendered samples on the default audio output. Samples are mono
+// little-endian 32-bit floats. Output is available on macOS and Windows.
+package audio
+
+import "errors"
+
+// ErrUnsupported is returned by Open on platforms without audio output.
+var ErrUnsupported = errors.New("audio output is not supported on this platform")
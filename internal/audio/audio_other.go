@@ -0,0 +1,11 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package audio
+
+import "io"
+
+// Open reports ErrUnsupported.
+func Open(sampleRate int, source io.Reader) (io.Closer, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,47 @@
+//go:build darwin || windows
+// +build darwin windows
+
+package audio
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ebitengine/oto/v3"
+)
+
+// oto allows a single audio context per process, so every player shares it.
+var (
+	contextOnce  sync.Once
+	audioContext *oto.Context
+	contextRate  int
+	contextErr   error
+)
+
+// Open starts playing samples read from source at the given sample rate. The first call
+// fixes the sample rate of the process; later calls must use the same one.
+func Open(sampleRate int, source io.Reader) (io.Closer, error) {
+	contextOnce.Do(func() {
+		var ready chan struct{}
+		contextRate = sampleRate
+		audioContext, ready, contextErr = oto.NewContext(&oto.NewContextOptions{
+			SampleRate:   contextRate,
+			ChannelCount: 1,
+			Format:       oto.FormatFloat32LE,
+		})
+		if contextErr == nil {
+			<-ready
+		}
+	})
+	if contextErr != nil {
+		return nil, fmt.Errorf("error opening audio output: %w", contextErr)
+	}
+	if sampleRate != contextRate {
+		return nil, fmt.Errorf("audio output already opened at %d Hz; cannot play at %d Hz", contextRate, sampleRate)
+	}
+
+	player := audioContext.NewPlayer(source)
+	player.Play()
+	return player, nil
+}
@@ -0,0 +1,29 @@
+//go:build darwin && cgo && !nomidihw
+// +build darwin,cgo,!nomidihw
+
+package coremidi
+
+// The exported callbacks live apart from the C helpers: a cgo preamble in a file with
+// //export directives may only contain declarations.
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import "unsafe"
+
+//export coremidiRead
+func coremidiRead(list unsafe.Pointer, port, source C.uintptr_t) {
+	readPackets(list, uintptr(port), uintptr(source))
+}
+
+//export coremidiReadEvents
+func coremidiReadEvents(list unsafe.Pointer, port, source C.uintptr_t) {
+	readEvents(list, uintptr(port), uintptr(source))
+}
+
+//export coremidiNotify
+func coremidiNotify(message unsafe.Pointer, client C.uintptr_t) {
+	deliverNotification(message, uintptr(client))
+}
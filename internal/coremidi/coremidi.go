@@ -0,0 +1,151 @@
+// Package coremidi is a cgo binding to the parts of CoreMIDI used by the macOS backend:
+// clients with setup notifications, the sources and destinations of the system with their
+// entities and devices, input ports delivering packets with their host timestamps, or
+// Universal MIDI Packets of the MIDI 2.0 protocol from macOS 11, output ports, and virtual
+// sources and destinations published to other applications.
+//
+// The binding is available in darwin builds with cgo enabled and without the nomidihw tag.
+// Notifications are delivered on a run loop the package runs on a dedicated OS thread,
+// started with the first client.
+package coremidi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// OSStatus values returned by CoreMIDI, from MIDIServices.h.
+const (
+	statusInvalidClient      = -10830
+	statusInvalidPort        = -10831
+	statusWrongEndpointType  = -10832
+	statusNoConnection       = -10833
+	statusUnknownEndpoint    = -10834
+	statusUnknownProperty    = -10835
+	statusWrongPropertyType  = -10836
+	statusNoCurrentSetup     = -10837
+	statusMessageSendErr     = -10838
+	statusServerStartErr     = -10839
+	statusSetupFormatErr     = -10840
+	statusWrongThread        = -10841
+	statusObjectNotFound     = -10842
+	statusIDNotUnique        = -10843
+	statusNotPermitted       = -10844
+	statusUnknownError       = -10845
+	statusMsgMissingDestPort = -10846
+)
+
+// statusText describes the OSStatus values of CoreMIDI.
+var statusText = map[int32]string{
+	statusInvalidClient:      "invalid client",
+	statusInvalidPort:        "invalid port",
+	statusWrongEndpointType:  "wrong endpoint type",
+	statusNoConnection:       "no connection",
+	statusUnknownEndpoint:    "unknown endpoint",
+	statusUnknownProperty:    "unknown property",
+	statusWrongPropertyType:  "wrong property type",
+	statusNoCurrentSetup:     "no current MIDI setup",
+	statusMessageSendErr:     "communication with the MIDI server failed",
+	statusServerStartErr:     "the MIDI server could not be started",
+	statusSetupFormatErr:     "invalid MIDI setup",
+	statusWrongThread:        "called from the wrong thread",
+	statusObjectNotFound:     "object not found",
+	statusIDNotUnique:        "unique ID already in use",
+	statusNotPermitted:       "not permitted",
+	statusUnknownError:       "unknown error",
+	statusMsgMissingDestPort: "missing destination port",
+}
+
+// ErrMIDI2Unsupported is returned by NewInputPortMIDI2 before macOS 11, whose CoreMIDI has
+// no MIDI 2.0 protocol.
+var ErrMIDI2Unsupported = errors.New("coremidi: MIDI 2.0 requires macOS 11 or later")
+
+// Error is a failed CoreMIDI call.
+type Error struct {
+	Op     string // CoreMIDI function that failed.
+	Status int32  // OSStatus it returned.
+}
+
+// Error describes the call and its status.
+func (e *Error) Error() string {
+	if text, ok := statusText[e.Status]; ok {
+		return fmt.Sprintf("coremidi: %s: %s (%d)", e.Op, text, e.Status)
+	}
+	return fmt.Sprintf("coremidi: %s: OSStatus %d", e.Op, e.Status)
+}
+
+// Is reports denied access, from sandboxed applications without the MIDI entitlements,
+// as fs.ErrPermission.
+func (e *Error) Is(target error) bool {
+	return target == fs.ErrPermission && e.Status == statusNotPermitted
+}
+
+// NotFound reports whether the call failed because the object it refers to no longer
+// exists, such as a source removed after being listed.
+func (e *Error) NotFound() bool {
+	return e.Status == statusObjectNotFound || e.Status == statusUnknownEndpoint
+}
+
+// Message identifies the kind of a Notification.
+type Message int32
+
+// Notification messages, from MIDIServices.h.
+const (
+	SetupChanged           Message = 1 // Something changed; sent after the other messages.
+	ObjectAdded            Message = 2 // A device, entity or endpoint was added.
+	ObjectRemoved          Message = 3 // A device, entity or endpoint was removed.
+	PropertyChanged        Message = 4 // A property of an object changed.
+	ThruConnectionsChanged Message = 5 // A persistent MIDI thru connection changed.
+	SerialPortOwnerChanged Message = 6 // A serial port owner changed.
+	IOError                Message = 7 // A driver failed to communicate with a device.
+)
+
+// ObjectType identifies the kind of an Object in notifications.
+type ObjectType int32
+
+// Object types, from MIDIServices.h. External objects, which describe gear connected to a
+// MIDI interface rather than the interface itself, have the ObjectTypeExternal bit set.
+const (
+	ObjectTypeOther       ObjectType = -1
+	ObjectTypeDevice      ObjectType = 0
+	ObjectTypeEntity      ObjectType = 1
+	ObjectTypeSource      ObjectType = 2
+	ObjectTypeDestination ObjectType = 3
+	ObjectTypeExternal    ObjectType = 0x10
+)
+
+// Object is a reference to a CoreMIDI object. The zero Object refers to nothing.
+type Object uint32
+
+// Notification describes a change of the MIDI setup.
+type Notification struct {
+	Message    Message    // What changed.
+	Object     Object     // Object added, removed or changed; zero for other messages.
+	ObjectType ObjectType // Type of Object.
+}
+
+// NotifyFunc receives the notifications of a client. It runs on the notification thread
+// of the package and must not block.
+type NotifyFunc func(Notification)
+
+// Packet is a group of MIDI bytes received at the same time.
+type Packet struct {
+	Data      []byte // Bytes of the packet, owned by CoreMIDI and valid during the callback only.
+	Timestamp uint64 // Host time of arrival, in mach_absolute_time units; see HostTimeToNanos.
+}
+
+// EventPacket is a group of Universal MIDI Packets received at the same time.
+type EventPacket struct {
+	Words     []uint32 // Words of the packets, owned by CoreMIDI and valid during the callback only.
+	Timestamp uint64   // Host time of arrival, in mach_absolute_time units; see HostTimeToNanos.
+}
+
+// Protocol is the MIDI protocol an endpoint communicates with.
+type Protocol int32
+
+// Protocols, from MIDIMessages.h.
+const (
+	ProtocolMIDI1 Protocol = 1 // MIDI 1.0, also for endpoints predating macOS 11.
+	ProtocolMIDI2 Protocol = 2 // MIDI 2.0.
+)
@@ -0,0 +1,713 @@
+//go:build darwin && cgo && !nomidihw
+// +build darwin,cgo,!nomidihw
+
+package coremidi
+
+/*
+#cgo CFLAGS: -Wno-deprecated-declarations
+#cgo LDFLAGS: -framework CoreMIDI -framework CoreFoundation
+#include <stdint.h>
+#include <stdlib.h>
+#include <mach/mach_time.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <CoreMIDI/CoreMIDI.h>
+
+extern void coremidiRead(void *list, uintptr_t port, uintptr_t source);
+extern void coremidiReadEvents(void *list, uintptr_t port, uintptr_t source);
+extern void coremidiNotify(void *message, uintptr_t client);
+
+static void readProc(const MIDIPacketList *list, void *port, void *source) {
+	coremidiRead((void *)list, (uintptr_t)port, (uintptr_t)source);
+}
+
+static void notifyProc(const MIDINotification *message, void *client) {
+	coremidiNotify((void *)message, (uintptr_t)client);
+}
+
+static OSStatus clientCreate(CFStringRef name, uintptr_t client, MIDIClientRef *ref) {
+	return MIDIClientCreate(name, notifyProc, (void *)client, ref);
+}
+
+static OSStatus inputPortCreate(MIDIClientRef client, CFStringRef name, uintptr_t port, MIDIPortRef *ref) {
+	return MIDIInputPortCreate(client, name, readProc, (void *)port, ref);
+}
+
+static int midi2Available(void) {
+	if (__builtin_available(macOS 11.0, *)) {
+		return 1;
+	}
+	return 0;
+}
+
+// inputPortCreateMIDI2 creates an input port receiving event lists of the MIDI 2.0
+// protocol. The caller must check midi2Available first.
+static OSStatus inputPortCreateMIDI2(MIDIClientRef client, CFStringRef name, uintptr_t port, MIDIPortRef *ref) {
+	if (__builtin_available(macOS 11.0, *)) {
+		return MIDIInputPortCreateWithProtocol(client, name, kMIDIProtocol_2_0, ref, ^(const MIDIEventList *list, void *source) {
+			coremidiReadEvents((void *)list, port, (uintptr_t)source);
+		});
+	}
+	return kMIDIUnknownError;
+}
+
+// protocolProperty returns the key of the protocol property, or NULL before macOS 11.
+static CFStringRef protocolProperty(void) {
+	if (__builtin_available(macOS 11.0, *)) {
+		return kMIDIPropertyProtocolID;
+	}
+	return NULL;
+}
+
+// portConnectSource passes the source as the connection reference, so readProc knows
+// where each packet list comes from.
+static OSStatus portConnectSource(MIDIPortRef port, MIDIEndpointRef source) {
+	return MIDIPortConnectSource(port, source, (void *)(uintptr_t)source);
+}
+
+// packetList returns a packet list holding length bytes in a single packet, timestamped
+// now, or NULL if it cannot be allocated. The list is sized for the data, so messages of
+// any length fit. The caller must free it.
+static MIDIPacketList *packetList(const Byte *data, UInt16 length) {
+	ByteCount size = sizeof(MIDIPacketList) + length;
+	MIDIPacketList *list = malloc(size);
+	if (list == NULL) {
+		return NULL;
+	}
+	MIDIPacket *packet = MIDIPacketListInit(list);
+	if (MIDIPacketListAdd(list, size, packet, 0, length, data) == NULL) {
+		free(list);
+		return NULL;
+	}
+	return list;
+}
+
+// sendData sends length bytes to destination through port in a single packet.
+static OSStatus sendData(MIDIPortRef port, MIDIEndpointRef destination, const Byte *data, UInt16 length) {
+	MIDIPacketList *list = packetList(data, length);
+	if (list == NULL) {
+		return kMIDIUnknownError;
+	}
+	OSStatus status = MIDISend(port, destination, list);
+	free(list);
+	return status;
+}
+
+// receivedData distributes length bytes to the clients connected to the virtual source.
+static OSStatus receivedData(MIDIEndpointRef source, const Byte *data, UInt16 length) {
+	MIDIPacketList *list = packetList(data, length);
+	if (list == NULL) {
+		return kMIDIUnknownError;
+	}
+	OSStatus status = MIDIReceived(source, list);
+	free(list);
+	return status;
+}
+
+// destinationCreate creates a virtual destination delivering its packets to readProc with
+// port as the port reference and no source.
+static OSStatus destinationCreate(MIDIClientRef client, CFStringRef name, uintptr_t port, MIDIEndpointRef *ref) {
+	return MIDIDestinationCreate(client, name, readProc, (void *)port, ref);
+}
+
+static UInt32 packetListCount(const MIDIPacketList *list) { return list->numPackets; }
+static const MIDIPacket *packetListFirst(const MIDIPacketList *list) { return &list->packet[0]; }
+static const MIDIPacket *packetNext(const MIDIPacket *packet) { return MIDIPacketNext(packet); }
+static const Byte *packetData(const MIDIPacket *packet) { return packet->data; }
+static UInt16 packetLength(const MIDIPacket *packet) { return packet->length; }
+static MIDITimeStamp packetTimeStamp(const MIDIPacket *packet) { return packet->timeStamp; }
+
+static UInt32 eventListCount(const MIDIEventList *list) { return list->numPackets; }
+static const MIDIEventPacket *eventListFirst(const MIDIEventList *list) { return &list->packet[0]; }
+static const UInt32 *eventPacketWords(const MIDIEventPacket *packet) { return packet->words; }
+static UInt32 eventPacketWordCount(const MIDIEventPacket *packet) { return packet->wordCount; }
+static MIDITimeStamp eventPacketTimeStamp(const MIDIEventPacket *packet) { return packet->timeStamp; }
+
+static const MIDIEventPacket *eventPacketNext(const MIDIEventPacket *packet) {
+	if (__builtin_available(macOS 11.0, *)) {
+		return MIDIEventPacketNext(packet);
+	}
+	return NULL;
+}
+
+static MIDINotificationMessageID notificationMessage(const MIDINotification *message) {
+	return message->messageID;
+}
+
+// notificationObject returns the object a notification is about, and its type.
+static MIDIObjectRef notificationObject(const MIDINotification *message, MIDIObjectType *type) {
+	switch (message->messageID) {
+	case kMIDIMsgObjectAdded:
+	case kMIDIMsgObjectRemoved: {
+		const MIDIObjectAddRemoveNotification *change = (const MIDIObjectAddRemoveNotification *)message;
+		*type = change->childType;
+		return change->child;
+	}
+	case kMIDIMsgPropertyChanged: {
+		const MIDIObjectPropertyChangeNotification *change = (const MIDIObjectPropertyChangeNotification *)message;
+		*type = change->objectType;
+		return change->object;
+	}
+	default:
+		*type = kMIDIObjectType_Other;
+		return 0;
+	}
+}
+
+static void keepAlive(CFRunLoopTimerRef timer, void *info) {}
+
+// runLoop runs the run loop of the calling thread forever. The timer keeps it from
+// returning while CoreMIDI has not attached its sources yet.
+static void runLoop(void) {
+	CFRunLoopTimerRef timer = CFRunLoopTimerCreate(NULL, CFAbsoluteTimeGetCurrent() + 1e9, 1e9, 0, 0, keepAlive, NULL);
+	CFRunLoopAddTimer(CFRunLoopGetCurrent(), timer, kCFRunLoopDefaultMode);
+	CFRunLoopRun();
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Registries of the clients, ports and virtual destinations receiving callbacks, by the
+// reference passed to CoreMIDI. Callbacks for entries removed by Dispose are dropped.
+var (
+	clients sync.Map // uintptr -> *Client
+	ports   sync.Map // uintptr -> ReadFunc or EventReadFunc of an input port or virtual destination
+	lastRef atomic.Uintptr
+
+	notifyThread sync.Once // Starts the thread running the notification run loop.
+
+	timebase struct {
+		once         sync.Once
+		numer, denom uint64
+	}
+)
+
+// Client is a CoreMIDI client, which owns ports and receives notifications.
+type Client struct {
+	ref    C.MIDIClientRef
+	id     uintptr
+	notify NotifyFunc
+}
+
+// NewClient creates a client named name. notify, if not nil, receives the notifications
+// of setup changes.
+//
+// CoreMIDI delivers notifications on the run loop current when the first client of the
+// process is created, so the first call creates its client on a dedicated OS thread that
+// then runs its run loop for the life of the process.
+func NewClient(name string, notify NotifyFunc) (*Client, error) {
+	client := &Client{id: lastRef.Add(1), notify: notify}
+	clients.Store(client.id, client)
+
+	cname := cfString(name)
+	defer C.CFRelease(C.CFTypeRef(cname))
+
+	var status C.OSStatus
+	created := false
+	notifyThread.Do(func() {
+		done := make(chan struct{})
+		go func() {
+			runtime.LockOSThread()
+			status = C.clientCreate(cname, C.uintptr_t(client.id), &client.ref)
+			close(done)
+			C.runLoop()
+		}()
+		<-done
+		created = true
+	})
+	if !created {
+		status = C.clientCreate(cname, C.uintptr_t(client.id), &client.ref)
+	}
+	if status != 0 {
+		clients.Delete(client.id)
+		return nil, &Error{Op: "MIDIClientCreate", Status: int32(status)}
+	}
+	return client, nil
+}
+
+// Dispose disposes of the client and its ports. Its notify function is not called
+// afterwards.
+func (c *Client) Dispose() error {
+	clients.Delete(c.id)
+	if status := C.MIDIClientDispose(c.ref); status != 0 {
+		return &Error{Op: "MIDIClientDispose", Status: int32(status)}
+	}
+	return nil
+}
+
+// ReadFunc receives the packets of the sources connected to an input port. It runs on a
+// high-priority thread of CoreMIDI and must not block.
+type ReadFunc func(source Endpoint, packet Packet)
+
+// InputPort receives the packets of the sources connected to it.
+type InputPort struct {
+	ref C.MIDIPortRef
+	id  uintptr
+}
+
+// NewInputPort creates an input port named name delivering packets to read.
+func (c *Client) NewInputPort(name string, read ReadFunc) (*InputPort, error) {
+	port := &InputPort{id: lastRef.Add(1)}
+	ports.Store(port.id, read)
+
+	cname := cfString(name)
+	defer C.CFRelease(C.CFTypeRef(cname))
+
+	if status := C.inputPortCreate(c.ref, cname, C.uintptr_t(port.id), &port.ref); status != 0 {
+		ports.Delete(port.id)
+		return nil, &Error{Op: "MIDIInputPortCreate", Status: int32(status)}
+	}
+	return port, nil
+}
+
+// EventReadFunc receives the Universal MIDI Packets of the sources connected to a MIDI 2.0
+// input port. It runs on a high-priority thread of CoreMIDI and must not block.
+type EventReadFunc func(source Endpoint, packet EventPacket)
+
+// MIDI2Available reports whether CoreMIDI supports the MIDI 2.0 protocol, from macOS 11.
+func MIDI2Available() bool {
+	return C.midi2Available() != 0
+}
+
+// NewInputPortMIDI2 creates an input port named name delivering packets to read as
+// Universal MIDI Packets of the MIDI 2.0 protocol. CoreMIDI converts the messages of
+// MIDI 1.0 sources to MIDI 2.0. It fails with ErrMIDI2Unsupported before macOS 11.
+func (c *Client) NewInputPortMIDI2(name string, read EventReadFunc) (*InputPort, error) {
+	if !MIDI2Available() {
+		return nil, ErrMIDI2Unsupported
+	}
+	port := &InputPort{id: lastRef.Add(1)}
+	ports.Store(port.id, read)
+
+	cname := cfString(name)
+	defer C.CFRelease(C.CFTypeRef(cname))
+
+	if status := C.inputPortCreateMIDI2(c.ref, cname, C.uintptr_t(port.id), &port.ref); status != 0 {
+		ports.Delete(port.id)
+		return nil, &Error{Op: "MIDIInputPortCreateWithProtocol", Status: int32(status)}
+	}
+	return port, nil
+}
+
+// Connect starts delivering the packets of source to the port.
+func (p *InputPort) Connect(source Endpoint) error {
+	if status := C.portConnectSource(p.ref, C.MIDIEndpointRef(source.Object)); status != 0 {
+		return &Error{Op: "MIDIPortConnectSource", Status: int32(status)}
+	}
+	return nil
+}
+
+// Disconnect stops delivering the packets of source to the port.
+func (p *InputPort) Disconnect(source Endpoint) error {
+	if status := C.MIDIPortDisconnectSource(p.ref, C.MIDIEndpointRef(source.Object)); status != 0 {
+		return &Error{Op: "MIDIPortDisconnectSource", Status: int32(status)}
+	}
+	return nil
+}
+
+// Dispose disconnects every source and disposes of the port. Packets arriving while it
+// runs are dropped, but a read function already running may still be running when it
+// returns.
+func (p *InputPort) Dispose() error {
+	ports.Delete(p.id)
+	if status := C.MIDIPortDispose(p.ref); status != 0 {
+		return &Error{Op: "MIDIPortDispose", Status: int32(status)}
+	}
+	return nil
+}
+
+// OutputPort sends packets to destinations.
+type OutputPort struct {
+	ref C.MIDIPortRef
+}
+
+// NewOutputPort creates an output port named name.
+func (c *Client) NewOutputPort(name string) (*OutputPort, error) {
+	cname := cfString(name)
+	defer C.CFRelease(C.CFTypeRef(cname))
+
+	port := &OutputPort{}
+	if status := C.MIDIOutputPortCreate(c.ref, cname, &port.ref); status != 0 {
+		return nil, &Error{Op: "MIDIOutputPortCreate", Status: int32(status)}
+	}
+	return port, nil
+}
+
+// Send sends data to destination as a single packet, to be played immediately. data must
+// hold complete messages.
+func (p *OutputPort) Send(destination Endpoint, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := checkPacketSize(data); err != nil {
+		return err
+	}
+	cdata := C.CBytes(data)
+	defer C.free(cdata)
+	if status := C.sendData(p.ref, C.MIDIEndpointRef(destination.Object), (*C.Byte)(cdata), C.UInt16(len(data))); status != 0 {
+		return &Error{Op: "MIDISend", Status: int32(status)}
+	}
+	return nil
+}
+
+// Dispose disposes of the port.
+func (p *OutputPort) Dispose() error {
+	if status := C.MIDIPortDispose(p.ref); status != 0 {
+		return &Error{Op: "MIDIPortDispose", Status: int32(status)}
+	}
+	return nil
+}
+
+// VirtualSource is a source created by a client, which the other applications of the
+// system list among their sources and receive the packets of.
+type VirtualSource struct{ Endpoint }
+
+// NewVirtualSource creates a virtual source named name.
+func (c *Client) NewVirtualSource(name string) (*VirtualSource, error) {
+	cname := cfString(name)
+	defer C.CFRelease(C.CFTypeRef(cname))
+
+	var ref C.MIDIEndpointRef
+	if status := C.MIDISourceCreate(c.ref, cname, &ref); status != 0 {
+		return nil, &Error{Op: "MIDISourceCreate", Status: int32(status)}
+	}
+	return &VirtualSource{Endpoint{Object(ref)}}, nil
+}
+
+// Received distributes data as a single packet, to be played immediately, to the clients
+// connected to the source. data must hold complete messages.
+func (s *VirtualSource) Received(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := checkPacketSize(data); err != nil {
+		return err
+	}
+	cdata := C.CBytes(data)
+	defer C.free(cdata)
+	if status := C.receivedData(C.MIDIEndpointRef(s.Object), (*C.Byte)(cdata), C.UInt16(len(data))); status != 0 {
+		return &Error{Op: "MIDIReceived", Status: int32(status)}
+	}
+	return nil
+}
+
+// Dispose removes the source from the system.
+func (s *VirtualSource) Dispose() error {
+	if status := C.MIDIEndpointDispose(C.MIDIEndpointRef(s.Object)); status != 0 {
+		return &Error{Op: "MIDIEndpointDispose", Status: int32(status)}
+	}
+	return nil
+}
+
+// VirtualDestination is a destination created by a client, which the other applications
+// of the system list among their destinations and send packets to.
+type VirtualDestination struct {
+	Endpoint
+	id uintptr
+}
+
+// NewVirtualDestination creates a virtual destination named name delivering the packets
+// sent to it to read, with the zero Endpoint as their source.
+func (c *Client) NewVirtualDestination(name string, read ReadFunc) (*VirtualDestination, error) {
+	destination := &VirtualDestination{id: lastRef.Add(1)}
+	ports.Store(destination.id, read)
+
+	cname := cfString(name)
+	defer C.CFRelease(C.CFTypeRef(cname))
+
+	var ref C.MIDIEndpointRef
+	if status := C.destinationCreate(c.ref, cname, C.uintptr_t(destination.id), &ref); status != 0 {
+		ports.Delete(destination.id)
+		return nil, &Error{Op: "MIDIDestinationCreate", Status: int32(status)}
+	}
+	destination.Object = Object(ref)
+	return destination, nil
+}
+
+// Dispose removes the destination from the system. Packets arriving while it runs are
+// dropped, but a read function already running may still be running when it returns.
+func (d *VirtualDestination) Dispose() error {
+	ports.Delete(d.id)
+	if status := C.MIDIEndpointDispose(C.MIDIEndpointRef(d.Object)); status != 0 {
+		return &Error{Op: "MIDIEndpointDispose", Status: int32(status)}
+	}
+	return nil
+}
+
+// Endpoint is a source or a destination.
+type Endpoint struct{ Object }
+
+// Sources returns the sources of the system, in the order CoreMIDI lists them.
+func Sources() []Endpoint {
+	count := int(C.MIDIGetNumberOfSources())
+	sources := make([]Endpoint, 0, count)
+	for i := 0; i < count; i++ {
+		if ref := C.MIDIGetSource(C.ItemCount(i)); ref != 0 {
+			sources = append(sources, Endpoint{Object(ref)})
+		}
+	}
+	return sources
+}
+
+// Destinations returns the destinations of the system, in the order CoreMIDI lists them.
+func Destinations() []Endpoint {
+	count := int(C.MIDIGetNumberOfDestinations())
+	destinations := make([]Endpoint, 0, count)
+	for i := 0; i < count; i++ {
+		if ref := C.MIDIGetDestination(C.ItemCount(i)); ref != 0 {
+			destinations = append(destinations, Endpoint{Object(ref)})
+		}
+	}
+	return destinations
+}
+
+// Entity returns the entity of the endpoint, or the zero Entity for virtual endpoints,
+// which have none.
+func (e Endpoint) Entity() Entity {
+	var entity C.MIDIEntityRef
+	if C.MIDIEndpointGetEntity(C.MIDIEndpointRef(e.Object), &entity) != 0 {
+		return Entity{}
+	}
+	return Entity{Object(entity)}
+}
+
+// Entity is a group of endpoints of a device, such as the ports of one connector.
+type Entity struct{ Object }
+
+// Sources returns the sources of the entity.
+func (e Entity) Sources() []Endpoint {
+	ref := C.MIDIEntityRef(e.Object)
+	count := int(C.MIDIEntityGetNumberOfSources(ref))
+	sources := make([]Endpoint, 0, count)
+	for i := 0; i < count; i++ {
+		if source := C.MIDIEntityGetSource(ref, C.ItemCount(i)); source != 0 {
+			sources = append(sources, Endpoint{Object(source)})
+		}
+	}
+	return sources
+}
+
+// Destinations returns the destinations of the entity.
+func (e Entity) Destinations() []Endpoint {
+	ref := C.MIDIEntityRef(e.Object)
+	count := int(C.MIDIEntityGetNumberOfDestinations(ref))
+	destinations := make([]Endpoint, 0, count)
+	for i := 0; i < count; i++ {
+		if destination := C.MIDIEntityGetDestination(ref, C.ItemCount(i)); destination != 0 {
+			destinations = append(destinations, Endpoint{Object(destination)})
+		}
+	}
+	return destinations
+}
+
+// Device returns the device of the entity, or the zero Device if it has none.
+func (e Entity) Device() Device {
+	var device C.MIDIDeviceRef
+	if C.MIDIEntityGetDevice(C.MIDIEntityRef(e.Object), &device) != 0 {
+		return Device{}
+	}
+	return Device{Object(device)}
+}
+
+// Device is a MIDI interface or instrument, made of entities.
+type Device struct{ Object }
+
+// Entities returns the entities of the device.
+func (d Device) Entities() []Entity {
+	ref := C.MIDIDeviceRef(d.Object)
+	count := int(C.MIDIDeviceGetNumberOfEntities(ref))
+	entities := make([]Entity, 0, count)
+	for i := 0; i < count; i++ {
+		if entity := C.MIDIDeviceGetEntity(ref, C.ItemCount(i)); entity != 0 {
+			entities = append(entities, Entity{Object(entity)})
+		}
+	}
+	return entities
+}
+
+// Name returns the name of the object, or "" if it has none. Endpoints and entities
+// without a name of their own do not inherit the name of their device.
+func (o Object) Name() string {
+	return o.stringProperty(C.kMIDIPropertyName)
+}
+
+// DisplayName returns the name of the object as shown to users, combining the names of
+// the device and the endpoint, or "" if it has none.
+func (o Object) DisplayName() string {
+	return o.stringProperty(C.kMIDIPropertyDisplayName)
+}
+
+// Manufacturer returns the manufacturer of the object, inherited from its device, or "".
+func (o Object) Manufacturer() string {
+	return o.stringProperty(C.kMIDIPropertyManufacturer)
+}
+
+// Model returns the model of the object, inherited from its device, or "".
+func (o Object) Model() string {
+	return o.stringProperty(C.kMIDIPropertyModel)
+}
+
+// UniqueID returns the system-wide unique ID of the object, which persists across
+// reconnections and restarts, and false if it has none.
+func (o Object) UniqueID() (int32, bool) {
+	return o.integerProperty(C.kMIDIPropertyUniqueID)
+}
+
+// DriverVersion returns the version of the driver of the object, inherited from its
+// device, and false if the driver does not report one.
+func (o Object) DriverVersion() (int32, bool) {
+	return o.integerProperty(C.kMIDIPropertyDriverVersion)
+}
+
+// Offline reports whether the object is temporarily absent, such as a device that was
+// unplugged but is still part of the setup.
+func (o Object) Offline() bool {
+	offline, _ := o.integerProperty(C.kMIDIPropertyOffline)
+	return offline != 0
+}
+
+// Protocol returns the MIDI protocol of the object, ProtocolMIDI1 when it does not say.
+func (o Object) Protocol() Protocol {
+	key := C.protocolProperty()
+	if key == 0 {
+		return ProtocolMIDI1
+	}
+	if protocol, ok := o.integerProperty(key); ok && Protocol(protocol) == ProtocolMIDI2 {
+		return ProtocolMIDI2
+	}
+	return ProtocolMIDI1
+}
+
+// stringProperty returns a string property of the object, or "" if it is not set.
+func (o Object) stringProperty(key C.CFStringRef) string {
+	if o == 0 {
+		return ""
+	}
+	var value C.CFStringRef
+	if C.MIDIObjectGetStringProperty(C.MIDIObjectRef(o), key, &value) != 0 || value == 0 {
+		return ""
+	}
+	defer C.CFRelease(C.CFTypeRef(value))
+	return goString(value)
+}
+
+// integerProperty returns an integer property of the object, and false if it is not set.
+func (o Object) integerProperty(key C.CFStringRef) (int32, bool) {
+	if o == 0 {
+		return 0, false
+	}
+	var value C.SInt32
+	if C.MIDIObjectGetIntegerProperty(C.MIDIObjectRef(o), key, &value) != 0 {
+		return 0, false
+	}
+	return int32(value), true
+}
+
+// HostTime returns the current host time, the clock of packet timestamps.
+func HostTime() uint64 {
+	return uint64(C.mach_absolute_time())
+}
+
+// HostTimeToNanos converts a host time to nanoseconds since boot.
+func HostTimeToNanos(hostTime uint64) uint64 {
+	timebase.once.Do(func() {
+		var info C.mach_timebase_info_data_t
+		C.mach_timebase_info(&info)
+		timebase.numer, timebase.denom = uint64(info.numer), uint64(info.denom)
+	})
+	// Split the conversion so large host times do not overflow.
+	return hostTime/timebase.denom*timebase.numer + hostTime%timebase.denom*timebase.numer/timebase.denom
+}
+
+// readPackets delivers a packet list received by the port registered as port.
+func readPackets(list unsafe.Pointer, port, source uintptr) {
+	value, ok := ports.Load(port)
+	if !ok {
+		return
+	}
+	read, ok := value.(ReadFunc)
+	if !ok {
+		return
+	}
+	endpoint := Endpoint{Object(source)}
+
+	packets := (*C.MIDIPacketList)(list)
+	packet := C.packetListFirst(packets)
+	for i := C.UInt32(0); i < C.packetListCount(packets); i++ {
+		data := unsafe.Slice((*byte)(unsafe.Pointer(C.packetData(packet))), int(C.packetLength(packet)))
+		read(endpoint, Packet{Data: data, Timestamp: uint64(C.packetTimeStamp(packet))})
+		packet = C.packetNext(packet)
+	}
+}
+
+// readEvents delivers an event list received by the MIDI 2.0 port registered as port.
+func readEvents(list unsafe.Pointer, port, source uintptr) {
+	value, ok := ports.Load(port)
+	if !ok {
+		return
+	}
+	read, ok := value.(EventReadFunc)
+	if !ok {
+		return
+	}
+	endpoint := Endpoint{Object(source)}
+
+	events := (*C.MIDIEventList)(list)
+	packet := C.eventListFirst(events)
+	for i := C.UInt32(0); i < C.eventListCount(events) && packet != nil; i++ {
+		words := unsafe.Slice((*uint32)(unsafe.Pointer(C.eventPacketWords(packet))), int(C.eventPacketWordCount(packet)))
+		read(endpoint, EventPacket{Words: words, Timestamp: uint64(C.eventPacketTimeStamp(packet))})
+		packet = C.eventPacketNext(packet)
+	}
+}
+
+// deliverNotification delivers a notification to the client registered as client.
+func deliverNotification(message unsafe.Pointer, client uintptr) {
+	value, ok := clients.Load(client)
+	if !ok || value.(*Client).notify == nil {
+		return
+	}
+	notification := (*C.MIDINotification)(message)
+	var objectType C.MIDIObjectType
+	object := C.notificationObject(notification, &objectType)
+	value.(*Client).notify(Notification{
+		Message:    Message(C.notificationMessage(notification)),
+		Object:     Object(object),
+		ObjectType: ObjectType(objectType),
+	})
+}
+
+// checkPacketSize returns an error if data does not fit in a packet.
+func checkPacketSize(data []byte) error {
+	if len(data) > 0xFFFF {
+		return fmt.Errorf("coremidi: packet of %d bytes exceeds the packet size limit", len(data))
+	}
+	return nil
+}
+
+// cfString returns s as a CFString, which the caller must release.
+func cfString(s string) C.CFStringRef {
+	cs := C.CString(s)
+	defer C.free(unsafe.Pointer(cs))
+	return C.CFStringCreateWithCString(C.kCFAllocatorDefault, cs, C.kCFStringEncodingUTF8)
+}
+
+// goString returns the contents of a CFString.
+func goString(s C.CFStringRef) string {
+	if ptr := C.CFStringGetCStringPtr(s, C.kCFStringEncodingUTF8); ptr != nil {
+		return C.GoString(ptr)
+	}
+	size := C.CFStringGetMaximumSizeForEncoding(C.CFStringGetLength(s), C.kCFStringEncodingUTF8) + 1
+	buf := (*C.char)(C.malloc(C.size_t(size)))
+	defer C.free(unsafe.Pointer(buf))
+	if C.CFStringGetCString(s, buf, size, C.kCFStringEncodingUTF8) == 0 {
+		return ""
+	}
+	return C.GoString(buf)
+}
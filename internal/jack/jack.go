@@ -0,0 +1,95 @@
+// Package jack is a cgo binding to the parts of the JACK Audio Connection Kit used by the
+// JACK backend: clients joining the graph of a server, the MIDI ports of the graph, and
+// MIDI ports registered by the client, whose connections other applications manage.
+//
+// Ports are served by the process callback of the client, which runs on the realtime
+// thread of the server. It only copies messages between the port buffers and lock-free
+// ring buffers, so it never blocks or allocates; Go reads and writes the rings and is
+// woken by Client.Wait when messages were captured.
+//
+// The binding is available in linux builds with cgo enabled, the jack tag and without the
+// nomidihw tag.
+package jack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors of ports and clients.
+var (
+	ErrClosed       = errors.New("jack: closed")
+	ErrQueueFull    = errors.New("jack: port queue full")
+	ErrTooManyPorts = errors.New("jack: too many ports")
+)
+
+// Status bits of jack_status_t, from jack/types.h.
+const (
+	statusFailure       = 0x01
+	statusInvalidOption = 0x02
+	statusNameNotUnique = 0x04
+	statusServerStarted = 0x08
+	statusServerFailed  = 0x10
+	statusServerError   = 0x20
+	statusNoSuchClient  = 0x40
+	statusLoadFailure   = 0x80
+	statusInitFailure   = 0x100
+	statusShmFailure    = 0x200
+	statusVersionError  = 0x400
+	statusBackendError  = 0x800
+	statusClientZombie  = 0x1000
+)
+
+// statusText describes the status bits of JACK.
+var statusText = []struct {
+	bit  int
+	text string
+}{
+	{statusFailure, "overall operation failed"},
+	{statusInvalidOption, "invalid or unsupported option"},
+	{statusNameNotUnique, "client name not unique"},
+	{statusServerStarted, "server started"},
+	{statusServerFailed, "unable to connect to the server"},
+	{statusServerError, "communication error with the server"},
+	{statusNoSuchClient, "no such client"},
+	{statusLoadFailure, "unable to load internal client"},
+	{statusInitFailure, "unable to initialize client"},
+	{statusShmFailure, "unable to access shared memory"},
+	{statusVersionError, "client protocol version mismatch"},
+	{statusBackendError, "backend error"},
+	{statusClientZombie, "client zombified"},
+}
+
+// Error is a failed call to JACK.
+type Error struct {
+	Op     string // JACK function that failed.
+	Status int    // jack_status_t bits for jack_client_open, or the returned error code otherwise.
+}
+
+// Error describes the call and its status.
+func (e *Error) Error() string {
+	if e.Op != "jack_client_open" {
+		return fmt.Sprintf("jack: %s: error %d", e.Op, e.Status)
+	}
+	var reasons []string
+	for _, status := range statusText {
+		if e.Status&status.bit != 0 {
+			reasons = append(reasons, status.text)
+		}
+	}
+	return fmt.Sprintf("jack: %s: %s (0x%x)", e.Op, strings.Join(reasons, ", "), e.Status)
+}
+
+// ServerUnavailable reports whether the client could not be opened because no server is
+// running or reachable.
+func (e *Error) ServerUnavailable() bool {
+	return e.Op == "jack_client_open" && e.Status&(statusServerFailed|statusServerError|statusShmFailure) != 0
+}
+
+// Event is a MIDI message captured by an input port.
+type Event struct {
+	Frame uint32 // Frame of the graph the message arrived at; it wraps around.
+	Time  uint64 // Time of the frame on the clock of Client.Now, in microseconds.
+	Data  []byte // Message, aliasing the buffer given to Port.Read.
+}
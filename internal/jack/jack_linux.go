@@ -0,0 +1,629 @@
+//go:build linux && cgo && jack && !nomidihw
+// +build linux,cgo,jack,!nomidihw
+
+package jack
+
+/*
+#cgo pkg-config: jack
+#include <errno.h>
+#include <semaphore.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <jack/jack.h>
+#include <jack/midiport.h>
+#include <jack/ringbuffer.h>
+
+#define GO_JACK_PORTS 64
+
+// go_jack_port is a MIDI port of the client with the ring exchanging its messages with Go.
+typedef struct {
+	jack_port_t *port;
+	jack_ringbuffer_t *ring; // Captured events for input ports; messages to send for output ports.
+	int input;
+} go_jack_port;
+
+// go_jack_event precedes every message captured in the ring of an input port.
+typedef struct {
+	uint32_t frame; // Frame of the graph the message arrived at.
+	uint32_t size;  // Bytes of the message following the header.
+	uint64_t usecs; // Time of the frame on the clock of jack_get_time.
+} go_jack_event;
+
+// go_jack is a client with its ports and the state shared with its callbacks.
+typedef struct {
+	jack_client_t *client;
+	go_jack_port *ports[GO_JACK_PORTS]; // Ports served by the process callback; NULL slots are free.
+	sem_t wake;        // Posted when messages were captured, ports changed or the server shut down.
+	int shutdown;      // Set when the server shut the client down.
+	uint64_t changes;  // Ports registered or unregistered in the graph.
+	uint64_t cycles;   // Process cycles run.
+	uint64_t overruns; // Captured messages discarded because their ring was full.
+	uint64_t xruns;    // Cycles the graph missed.
+} go_jack;
+
+// go_jack_capture copies the messages of an input port buffer to its ring and reports
+// whether it copied any.
+static int go_jack_capture(go_jack *j, go_jack_port *p, void *buffer, jack_nframes_t base) {
+	uint32_t count = jack_midi_get_event_count(buffer);
+	int captured = 0;
+	for (uint32_t i = 0; i < count; i++) {
+		jack_midi_event_t event;
+		if (jack_midi_event_get(&event, buffer, i) != 0) {
+			continue;
+		}
+		go_jack_event header = {base + event.time, (uint32_t)event.size, jack_frames_to_time(j->client, base + event.time)};
+		if (jack_ringbuffer_write_space(p->ring) < sizeof header + event.size) {
+			__atomic_add_fetch(&j->overruns, 1, __ATOMIC_RELAXED);
+			continue;
+		}
+		jack_ringbuffer_write(p->ring, (const char *)&header, sizeof header);
+		jack_ringbuffer_write(p->ring, (const char *)event.buffer, event.size);
+		captured = 1;
+	}
+	return captured;
+}
+
+// go_jack_play writes the messages queued in the ring of an output port to its buffer, at
+// the start of the cycle. Messages that do not fit wait for the next cycle, except those
+// not fitting in an empty buffer, such as long SysEx messages, which are dropped.
+static void go_jack_play(go_jack_port *p, void *buffer) {
+	jack_midi_clear_buffer(buffer);
+	uint32_t size;
+	while (jack_ringbuffer_read_space(p->ring) >= sizeof size) {
+		jack_ringbuffer_peek(p->ring, (char *)&size, sizeof size);
+		if (jack_ringbuffer_read_space(p->ring) < sizeof size + size) {
+			break;
+		}
+		jack_midi_data_t *data = jack_midi_event_reserve(buffer, 0, size);
+		if (data == NULL && jack_midi_get_event_count(buffer) == 0) {
+			jack_ringbuffer_read_advance(p->ring, sizeof size + size);
+			continue;
+		}
+		if (data == NULL) {
+			break;
+		}
+		jack_ringbuffer_read_advance(p->ring, sizeof size);
+		jack_ringbuffer_read(p->ring, (char *)data, size);
+	}
+}
+
+static int go_jack_process(jack_nframes_t nframes, void *arg) {
+	go_jack *j = arg;
+	jack_nframes_t base = jack_last_frame_time(j->client);
+	int captured = 0;
+	for (int i = 0; i < GO_JACK_PORTS; i++) {
+		go_jack_port *p = __atomic_load_n(&j->ports[i], __ATOMIC_ACQUIRE);
+		if (p == NULL) {
+			continue;
+		}
+		void *buffer = jack_port_get_buffer(p->port, nframes);
+		if (p->input) {
+			captured |= go_jack_capture(j, p, buffer, base);
+		} else {
+			go_jack_play(p, buffer);
+		}
+	}
+	__atomic_add_fetch(&j->cycles, 1, __ATOMIC_RELEASE);
+	if (captured) {
+		sem_post(&j->wake);
+	}
+	return 0;
+}
+
+static void go_jack_shutdown(void *arg) {
+	go_jack *j = arg;
+	__atomic_store_n(&j->shutdown, 1, __ATOMIC_RELEASE);
+	sem_post(&j->wake);
+}
+
+static void go_jack_registration(jack_port_id_t port, int registered, void *arg) {
+	go_jack *j = arg;
+	__atomic_add_fetch(&j->changes, 1, __ATOMIC_RELEASE);
+	sem_post(&j->wake);
+}
+
+static int go_jack_xrun(void *arg) {
+	go_jack *j = arg;
+	__atomic_add_fetch(&j->xruns, 1, __ATOMIC_RELAXED);
+	return 0;
+}
+
+// go_jack_open opens a client without starting a server, and installs its callbacks.
+static go_jack *go_jack_open(const char *name, const char *server, int *status) {
+	jack_status_t result = 0;
+	jack_client_t *client = server != NULL
+		? jack_client_open(name, JackNoStartServer | JackServerName, &result, server)
+		: jack_client_open(name, JackNoStartServer, &result);
+	*status = (int)result;
+	if (client == NULL) {
+		return NULL;
+	}
+	go_jack *j = calloc(1, sizeof *j);
+	j->client = client;
+	sem_init(&j->wake, 0, 0);
+	jack_set_process_callback(client, go_jack_process, j);
+	jack_on_shutdown(client, go_jack_shutdown, j);
+	jack_set_port_registration_callback(client, go_jack_registration, j);
+	jack_set_xrun_callback(client, go_jack_xrun, j);
+	return j;
+}
+
+// go_jack_register registers a MIDI port with a ring of size bytes and serves it from
+// slot. It returns 0, 1 when JACK refuses the port or 2 when the ring cannot be allocated.
+static int go_jack_register(go_jack *j, int slot, const char *name, int input, size_t size, go_jack_port **out) {
+	jack_port_t *port = jack_port_register(j->client, name, JACK_DEFAULT_MIDI_TYPE, input ? JackPortIsInput : JackPortIsOutput, 0);
+	if (port == NULL) {
+		return 1;
+	}
+	go_jack_port *p = calloc(1, sizeof *p);
+	p->port = port;
+	p->input = input;
+	p->ring = jack_ringbuffer_create(size);
+	if (p->ring == NULL) {
+		jack_port_unregister(j->client, port);
+		free(p);
+		return 2;
+	}
+	jack_ringbuffer_mlock(p->ring);
+	*out = p;
+	__atomic_store_n(&j->ports[slot], p, __ATOMIC_RELEASE);
+	return 0;
+}
+
+// go_jack_detach stops serving the port in slot and returns the number of cycles run, which
+// grows once the process callback no longer uses the port.
+static uint64_t go_jack_detach(go_jack *j, int slot) {
+	__atomic_store_n(&j->ports[slot], NULL, __ATOMIC_SEQ_CST);
+	return __atomic_load_n(&j->cycles, __ATOMIC_SEQ_CST);
+}
+
+// go_jack_release unregisters a detached port from client, unless NULL, and frees it.
+static void go_jack_release(jack_client_t *client, go_jack_port *p) {
+	if (client != NULL) {
+		jack_port_unregister(client, p->port);
+	}
+	jack_ringbuffer_free(p->ring);
+	free(p);
+}
+
+// go_jack_close closes the client, which unregisters its ports, and frees it.
+static int go_jack_close(go_jack *j) {
+	int result = jack_client_close(j->client);
+	for (int i = 0; i < GO_JACK_PORTS; i++) {
+		if (j->ports[i] != NULL) {
+			go_jack_release(NULL, j->ports[i]);
+		}
+	}
+	sem_destroy(&j->wake);
+	free(j);
+	return result;
+}
+
+// go_jack_read moves the next message captured by an input port to header and data, which
+// holds size bytes. It returns 1, 0 when there is none, or 2 when the message is longer
+// than size and was skipped.
+static int go_jack_read(go_jack_port *p, go_jack_event *header, char *data, size_t size) {
+	if (jack_ringbuffer_read_space(p->ring) < sizeof *header) {
+		return 0;
+	}
+	jack_ringbuffer_read(p->ring, (char *)header, sizeof *header);
+	if (header->size > size) {
+		jack_ringbuffer_read_advance(p->ring, header->size);
+		return 2;
+	}
+	jack_ringbuffer_read(p->ring, data, header->size);
+	return 1;
+}
+
+// go_jack_write queues a message for an output port and returns 1, or 0 when the ring is full.
+static int go_jack_write(go_jack_port *p, const char *data, uint32_t size) {
+	if (jack_ringbuffer_write_space(p->ring) < sizeof size + size) {
+		return 0;
+	}
+	jack_ringbuffer_write(p->ring, (const char *)&size, sizeof size);
+	jack_ringbuffer_write(p->ring, data, size);
+	return 1;
+}
+
+static const char **go_jack_get_ports(go_jack *j, int input) {
+	return jack_get_ports(j->client, NULL, JACK_DEFAULT_MIDI_TYPE, input ? JackPortIsInput : JackPortIsOutput);
+}
+
+static void go_jack_wait(go_jack *j) {
+	while (sem_wait(&j->wake) != 0 && errno == EINTR) {
+	}
+}
+
+static void go_jack_wake(go_jack *j) { sem_post(&j->wake); }
+static int go_jack_is_shutdown(go_jack *j) { return __atomic_load_n(&j->shutdown, __ATOMIC_ACQUIRE); }
+static uint64_t go_jack_changes(go_jack *j) { return __atomic_load_n(&j->changes, __ATOMIC_ACQUIRE); }
+static uint64_t go_jack_cycles(go_jack *j) { return __atomic_load_n(&j->cycles, __ATOMIC_ACQUIRE); }
+static uint64_t go_jack_overruns(go_jack *j) { return __atomic_load_n(&j->overruns, __ATOMIC_RELAXED); }
+static uint64_t go_jack_xruns(go_jack *j) { return __atomic_load_n(&j->xruns, __ATOMIC_RELAXED); }
+*/
+import "C"
+
+import (
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// maxPorts is the number of ports a client can register, GO_JACK_PORTS.
+const maxPorts = C.GO_JACK_PORTS
+
+// releaseTimeout bounds the wait for the process callback to let go of a closed port. A
+// port still used after it is kept until the client closes.
+const releaseTimeout = time.Second
+
+// Client is a client of a JACK server. Its methods are safe for concurrent use, except
+// Wait, which a single goroutine calls.
+type Client struct {
+	j       *C.go_jack
+	mu      sync.Mutex // Protects the fields below.
+	ports   [maxPorts]*Port
+	zombies []*C.go_jack_port // Closed ports the process callback did not let go of in time.
+	closed  bool
+}
+
+// Open joins the server named server, or the default server if it is empty, as a client
+// named name, and activates it. It does not start a server. JACK appends a number to the
+// name if another client has it; see Name.
+func Open(name, server string) (*Client, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	var cserver *C.char
+	if server != "" {
+		cserver = C.CString(server)
+		defer C.free(unsafe.Pointer(cserver))
+	}
+
+	var status C.int
+	j := C.go_jack_open(cname, cserver, &status)
+	if j == nil {
+		return nil, &Error{Op: "jack_client_open", Status: int(status)}
+	}
+	if result := C.jack_activate(j.client); result != 0 {
+		C.go_jack_close(j)
+		return nil, &Error{Op: "jack_activate", Status: int(result)}
+	}
+	return &Client{j: j}, nil
+}
+
+// Name returns the name of the client in the graph, which prefixes the names of its ports.
+func (c *Client) Name() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ""
+	}
+	return C.GoString(C.jack_get_client_name(c.j.client))
+}
+
+// SampleRate returns the sample rate of the graph, in frames per second.
+func (c *Client) SampleRate() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0
+	}
+	return int(C.jack_get_sample_rate(c.j.client))
+}
+
+// BufferSize returns the number of frames of a process cycle.
+func (c *Client) BufferSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0
+	}
+	return int(C.jack_get_buffer_size(c.j.client))
+}
+
+// Now returns the current time on the clock of Event.Time, in microseconds. It must not
+// be called concurrently with Close.
+func (c *Client) Now() uint64 {
+	return uint64(C.jack_get_time())
+}
+
+// Sources returns the names of the MIDI ports of the graph messages can be received from,
+// the output ports of the clients.
+func (c *Client) Sources() ([]string, error) {
+	return c.portNames(false)
+}
+
+// Destinations returns the names of the MIDI ports of the graph messages can be sent to,
+// the input ports of the clients.
+func (c *Client) Destinations() ([]string, error) {
+	return c.portNames(true)
+}
+
+// portNames returns the names of the MIDI input or output ports of the graph.
+func (c *Client) portNames(input bool) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	ports := C.go_jack_get_ports(c.j, cbool(input))
+	if ports == nil {
+		return nil, nil
+	}
+	defer C.jack_free(unsafe.Pointer(ports))
+	var names []string
+	for port := ports; *port != nil; port = (**C.char)(unsafe.Add(unsafe.Pointer(port), unsafe.Sizeof(*port))) {
+		names = append(names, C.GoString(*port))
+	}
+	return names, nil
+}
+
+// Exists reports whether the graph has a port with the given full name.
+func (c *Client) Exists(port string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	cport := C.CString(port)
+	defer C.free(unsafe.Pointer(cport))
+	return C.jack_port_by_name(c.j.client, cport) != nil
+}
+
+// Connect connects the output port source to the input port destination, by their full
+// names. Connecting ports that are already connected succeeds.
+func (c *Client) Connect(source, destination string) error {
+	return c.connection("jack_connect", source, destination)
+}
+
+// Disconnect removes the connection of the output port source to the input port
+// destination.
+func (c *Client) Disconnect(source, destination string) error {
+	return c.connection("jack_disconnect", source, destination)
+}
+
+// connection connects or disconnects two ports with the JACK function op.
+func (c *Client) connection(op, source, destination string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	csource, cdestination := C.CString(source), C.CString(destination)
+	defer C.free(unsafe.Pointer(csource))
+	defer C.free(unsafe.Pointer(cdestination))
+
+	var result C.int
+	if op == "jack_connect" {
+		result = C.jack_connect(c.j.client, csource, cdestination)
+	} else {
+		result = C.jack_disconnect(c.j.client, csource, cdestination)
+	}
+	if result != 0 && !(op == "jack_connect" && result == C.EEXIST) {
+		return &Error{Op: op, Status: int(result)}
+	}
+	return nil
+}
+
+// RegisterInput registers a MIDI input port named name, whose captured messages are
+// queued in a ring of size bytes for Port.Read.
+func (c *Client) RegisterInput(name string, size int) (*Port, error) {
+	return c.register(name, true, size)
+}
+
+// RegisterOutput registers a MIDI output port named name, whose messages written with
+// Port.Write are queued in a ring of size bytes until the next process cycle.
+func (c *Client) RegisterOutput(name string, size int) (*Port, error) {
+	return c.register(name, false, size)
+}
+
+// register registers a MIDI port in a free slot.
+func (c *Client) register(name string, input bool, size int) (*Port, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	slot := -1
+	for i, port := range c.ports {
+		if port == nil {
+			slot = i
+			break
+		}
+	}
+	if slot < 0 {
+		return nil, ErrTooManyPorts
+	}
+
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	var p *C.go_jack_port
+	switch C.go_jack_register(c.j, C.int(slot), cname, cbool(input), C.size_t(size), &p) {
+	case 1:
+		return nil, &Error{Op: "jack_port_register", Status: -1}
+	case 2:
+		return nil, &Error{Op: "jack_ringbuffer_create", Status: -1}
+	}
+	port := &Port{client: c, slot: slot, name: C.GoString(C.jack_port_name(p.port)), p: p}
+	c.ports[slot] = port
+	return port, nil
+}
+
+// Wait blocks until messages were captured, ports were registered or unregistered in the
+// graph, the server shut the client down or Wake is called. Wakeups may be spurious. It
+// must not be called concurrently with Close.
+func (c *Client) Wait() {
+	C.go_jack_wait(c.j)
+}
+
+// Wake wakes the goroutine blocked in Wait.
+func (c *Client) Wake() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		C.go_jack_wake(c.j)
+	}
+}
+
+// ShutDown reports whether the server shut the client down, after which it serves no port.
+func (c *Client) ShutDown() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed || C.go_jack_is_shutdown(c.j) != 0
+}
+
+// Changes returns the number of ports registered or unregistered in the graph so far.
+func (c *Client) Changes() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0
+	}
+	return uint64(C.go_jack_changes(c.j))
+}
+
+// Overruns returns the number of captured messages discarded because Go did not read
+// their port fast enough.
+func (c *Client) Overruns() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0
+	}
+	return uint64(C.go_jack_overruns(c.j))
+}
+
+// Xruns returns the number of cycles the graph missed since the client opened.
+func (c *Client) Xruns() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0
+	}
+	return uint64(C.go_jack_xruns(c.j))
+}
+
+// Close deactivates the client, which leaves the graph with its ports.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	for _, port := range c.ports {
+		if port != nil {
+			port.mu.Lock()
+			port.p = nil
+			port.mu.Unlock()
+		}
+	}
+	result := C.go_jack_close(c.j)
+	for _, p := range c.zombies {
+		C.go_jack_release(nil, p)
+	}
+	c.zombies = nil
+	if result != 0 {
+		return &Error{Op: "jack_client_close", Status: int(result)}
+	}
+	return nil
+}
+
+// Port is a MIDI port registered by a Client.
+type Port struct {
+	client *Client
+	slot   int
+	name   string
+	mu     sync.Mutex      // Serializes Read, Write and Close.
+	p      *C.go_jack_port // nil once closed.
+}
+
+// Name returns the full name of the port, prefixed with the name of its client.
+func (p *Port) Name() string {
+	return p.name
+}
+
+// Read returns the next message captured by an input port, or false when there is none.
+// buf must be as long as the ring of the port, so that every message fits; longer
+// messages are skipped. The message aliases buf.
+func (p *Port) Read(buf []byte) (Event, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.p == nil || len(buf) == 0 {
+		return Event{}, false
+	}
+
+	var header C.go_jack_event
+	for {
+		switch C.go_jack_read(p.p, &header, (*C.char)(unsafe.Pointer(&buf[0])), C.size_t(len(buf))) {
+		case 0:
+			return Event{}, false
+		case 1:
+			return Event{Frame: uint32(header.frame), Time: uint64(header.usecs), Data: buf[:header.size]}, true
+		}
+	}
+}
+
+// Write queues a message for an output port, sent at the start of the next process cycle.
+// It fails with ErrQueueFull when the ring of the port is full.
+func (p *Port) Write(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.p == nil {
+		return ErrClosed
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if C.go_jack_write(p.p, (*C.char)(unsafe.Pointer(&data[0])), C.uint32_t(len(data))) == 0 {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// Close unregisters the port once the process callback no longer uses it.
+func (p *Port) Close() error {
+	c := p.client
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.p == nil {
+		return nil
+	}
+
+	cycles := C.go_jack_detach(c.j, C.int(p.slot))
+	c.ports[p.slot] = nil
+	if c.released(cycles) {
+		C.go_jack_release(c.j.client, p.p)
+	} else {
+		c.zombies = append(c.zombies, p.p)
+	}
+	p.p = nil
+	return nil
+}
+
+// released waits for the process cycle running when a port was detached, at the given
+// cycle count, to end. It reports false if the cycle does not end within releaseTimeout
+// while the client is active.
+func (c *Client) released(cycles C.uint64_t) bool {
+	deadline := time.Now().Add(releaseTimeout)
+	for C.go_jack_cycles(c.j) == cycles {
+		if C.go_jack_is_shutdown(c.j) != 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+// cbool converts b to a C boolean.
+func cbool(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -3,26 +3,42 @@ package logger
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/logging"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 // ZapLogger é uma implementação do contrato de Logger que usa o logger do Uber.
 type ZapLogger struct {
-	logger *zap.Logger
-	level  contracts.LogLevel // Nível de log
+	logger atomic.Pointer[zap.Logger] // Replaced by SetDestination.
+	level  contracts.LogLevel         // Nível de log
+
+	mu       sync.Mutex            // Serializes SetDestination, SetRotation and Close.
+	rotation contracts.LogRotation // Rotation of the files of SetDestination.
+	output   io.Closer             // Writer of the destination, closed when it is replaced; nil before SetDestination.
 }
 
 // NewZapLogger cria um novo logger do Uber.
 func NewZapLogger() contracts.Logger {
-	logger, _ := zap.NewProduction() // Ou zap.NewDevelopment() para desenvolvimento
-	return &ZapLogger{logger: logger, level: contracts.InfoLevel}
+	z := &ZapLogger{level: contracts.InfoLevel}
+	z.logger.Store(newZap(zapcore.Lock(os.Stderr)))
+	return z
+}
+
+// newZap creates a logger like zap.NewProduction, writing JSON lines to output.
+func newZap(output zapcore.WriteSyncer) *zap.Logger {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), output, zapcore.InfoLevel)
+	return zap.New(zapcore.NewSamplerWithOptions(core, time.Second, 100, 100),
+		zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel), zap.ErrorOutput(zapcore.Lock(os.Stderr)))
 }
 
 // Info logs a message at the INFO level
@@ -61,9 +77,53 @@ func (z *ZapLogger) SetLevel(level contracts.LogLevel) {
 	z.level = level
 }
 
-// SetDestination sets the logging destination (não aplicável para ZapLogger).
+// SetRotation sets the rotation of the files of the next call to SetDestination.
+func (z *ZapLogger) SetRotation(rotation contracts.LogRotation) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.rotation = rotation
+}
+
+// SetDestination sets where messages are written: standard error for ConsoleLog, the file
+// at filePath for FileLog, or both for ConsoleAndFileLog. Files are appended to and
+// rotated as set with SetRotation. The file of the previous destination is closed; the
+// destination is left unchanged if the new file cannot be opened, and the error is logged.
 func (z *ZapLogger) SetDestination(dest contracts.LogDestination, filePath ...string) {
-	// O ZapLogger não tem suporte a filePath, então não implementamos essa funcionalidade.
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	output, err := logging.OpenWriter(dest, z.rotation, filePath...)
+	if err != nil {
+		z.Error("Failed to set the log destination", z.Field().Error("error", err))
+		return
+	}
+	previous := z.logger.Swap(newZap(zapcore.Lock(zapcore.AddSync(output))))
+	previous.Sync()
+	if z.output != nil {
+		z.output.Close()
+	}
+	z.output = output
+}
+
+// Sync flushes the messages buffered by zap to the destination.
+func (z *ZapLogger) Sync() error {
+	return z.logger.Load().Sync()
+}
+
+// Close flushes the messages and closes the file of the destination, if any. Messages
+// logged afterwards are written to standard error.
+func (z *ZapLogger) Close() error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	previous := z.logger.Swap(newZap(zapcore.Lock(os.Stderr)))
+	previous.Sync()
+	if z.output == nil {
+		return nil
+	}
+	err := z.output.Close()
+	z.output = nil
+	return err
 }
 
 // log é a função interna para registrar mensagens
@@ -86,17 +146,18 @@ func (z *ZapLogger) log(level zapcore.Level, msg string, fields ...contracts.Fie
 	logMessage := fmt.Sprintf("%s [%s] %s:%d: %s%s", timestamp, level.String(), file, line, msg, formattedFields)
 
 	// Usar o logger do Uber
+	logger := z.logger.Load()
 	switch level {
 	case zapcore.InfoLevel:
-		z.logger.Info(logMessage)
+		logger.Info(logMessage)
 	case zapcore.ErrorLevel:
-		z.logger.Error(logMessage)
+		logger.Error(logMessage)
 	case zapcore.DebugLevel:
-		z.logger.Debug(logMessage)
+		logger.Debug(logMessage)
 	case zapcore.WarnLevel:
-		z.logger.Warn(logMessage)
+		logger.Warn(logMessage)
 	case zapcore.FatalLevel:
-		z.logger.Fatal(logMessage)
+		logger.Fatal(logMessage)
 	}
 }
 
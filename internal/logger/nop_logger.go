@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// NopLogger is an implementation of the Logger contract that discards every message.
+// It is installed by contracts.WithoutLogging.
+type NopLogger struct{}
+
+// NewNopLogger creates a logger that discards every message.
+func NewNopLogger() contracts.Logger {
+	return NopLogger{}
+}
+
+// Info discards the message
+func (NopLogger) Info(msg string, fields ...contracts.Field) {}
+
+// Error discards the message
+func (NopLogger) Error(msg string, fields ...contracts.Field) {}
+
+// Debug discards the message
+func (NopLogger) Debug(msg string, fields ...contracts.Field) {}
+
+// Warn discards the message
+func (NopLogger) Warn(msg string, fields ...contracts.Field) {}
+
+// Fatal discards the message; unlike the other loggers it does not terminate the application
+func (NopLogger) Fatal(msg string, fields ...contracts.Field) {}
+
+// Field returns a field builder whose fields are discarded
+func (NopLogger) Field() contracts.Field {
+	return nopField{}
+}
+
+// SetLevel has no effect
+func (NopLogger) SetLevel(level contracts.LogLevel) {}
+
+// SetDestination has no effect
+func (NopLogger) SetDestination(dest contracts.LogDestination, filePath ...string) {}
+
+// nopField implements contracts.Field without storing anything
+type nopField struct{}
+
+func (f nopField) Bool(key string, val bool) contracts.Field       { return f }
+func (f nopField) Int(key string, val int) contracts.Field         { return f }
+func (f nopField) Float64(key string, val float64) contracts.Field { return f }
+func (f nopField) String(key string, val string) contracts.Field   { return f }
+func (f nopField) Time(key string, val time.Time) contracts.Field  { return f }
+func (f nopField) Int64(key string, val int64) contracts.Field     { return f }
+func (f nopField) Error(key string, val error) contracts.Field     { return f }
+func (f nopField) Uint64(key string, val uint64) contracts.Field   { return f }
+func (f nopField) Uint8(key string, val uint8) contracts.Field     { return f }
@@ -0,0 +1,89 @@
+package dispatch
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// callbacks runs the handler of StartCaptureFunc on a pool of workers, fed by the channel
+// events are dispatched to.
+type callbacks struct {
+	workers   int                  // Number of goroutines calling handler.
+	buffer    int                  // Capacity of the channels of the handlers.
+	latencies latencies            // Time handler takes for each event, since the workers started.
+	mu        sync.Mutex           // Protects the fields below.
+	channel   chan contracts.MIDI  // Channel of the last handler; nil if there is none.
+	handler   func(contracts.MIDI) // Last handler.
+	done      chan struct{}        // Closed to stop the workers; nil when not running.
+}
+
+// newCallbacks prepares the workers configured in options.
+func newCallbacks(options *contracts.ClientOptions) *callbacks {
+	return &callbacks{workers: max(options.CaptureWorkers, 1), buffer: max(options.CaptureBuffer, 1)}
+}
+
+// Callback returns a channel to pass to Attach so that handler is called with the events
+// dispatched to it, by the workers configured with contracts.WithCaptureWorkers. The
+// workers run while the channel is attached. It returns nil if handler is nil.
+func (d *Dispatcher) Callback(handler func(contracts.MIDI)) chan contracts.MIDI {
+	if handler == nil {
+		return nil
+	}
+
+	c := d.callbacks
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done == nil {
+		c.channel, c.handler = make(chan contracts.MIDI, c.buffer), handler
+		return c.channel
+	}
+	// A handler is running; the backend refuses a second capture, so the new handler is
+	// never attached and the running one keeps its channel.
+	return make(chan contracts.MIDI, c.buffer)
+}
+
+// start starts the workers of the dispatcher d if eventChannel is the channel of the last
+// handler and they are not running.
+func (c *callbacks) start(d *Dispatcher, eventChannel chan contracts.MIDI) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if eventChannel == nil || eventChannel != c.channel || c.done != nil {
+		return
+	}
+	done, channel, handler := make(chan struct{}), c.channel, c.handler
+	c.done = done
+	c.latencies.reset()
+	for i := range c.workers {
+		profiling.Go(profiling.RoleDispatch, d.backend, "handler "+strconv.Itoa(i+1), func() { c.handle(d, channel, handler, done) })
+	}
+}
+
+// stop stops the workers, if running. Queued events are kept for the next start.
+func (c *callbacks) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done != nil {
+		close(c.done)
+		c.done = nil
+	}
+}
+
+// handle calls handler for the events of channel until done is closed, recording the time
+// each call takes. Panics in the handler are reported by d and do not stop the handling of
+// later events.
+func (c *callbacks) handle(d *Dispatcher, channel chan contracts.MIDI, handler func(contracts.MIDI), done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case event := <-channel:
+			start := time.Now()
+			d.protect("capture handler", func() { handler(event) })
+			c.latencies.record(time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package dispatch
+
+import (
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// ArrivalTimestamp returns the timestamp of an event arriving now on the clock of the
+// options, or native, the timestamp of the backend, with contracts.DefaultClock.
+func (d *Dispatcher) ArrivalTimestamp(native uint64) uint64 {
+	switch d.clock {
+	case contracts.WallClock:
+		return uint64(time.Now().UnixNano())
+	case contracts.MonotonicClock, contracts.HardwareClock:
+		return contracts.MonotonicNow()
+	default:
+		return native
+	}
+}
+
+// DriverTimestamp returns hardware, the time the driver received an event on the clock of
+// contracts.MonotonicNow, with contracts.HardwareClock, and the timestamp of its arrival
+// as by ArrivalTimestamp otherwise.
+func (d *Dispatcher) DriverTimestamp(hardware, native uint64) uint64 {
+	if d.clock == contracts.HardwareClock {
+		return hardware
+	}
+	return d.ArrivalTimestamp(native)
+}
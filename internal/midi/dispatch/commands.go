@@ -0,0 +1,59 @@
+package dispatch
+
+import (
+	"sync/atomic"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// commandCount holds the counters of one kind of event.
+type commandCount struct {
+	received atomic.Uint64 // Events received, before filtering.
+	filtered atomic.Uint64 // Events discarded by the event filter.
+}
+
+// commands counts the events of a source by status byte and, for control changes, by
+// controller. The counters are preallocated atomics so that recording stays lock- and
+// allocation-free on the capture path.
+type commands struct {
+	statuses    [256]commandCount
+	controllers [16][128]commandCount // Control changes by channel and controller.
+}
+
+// count returns the counters of the kind of event.
+func (c *commands) count(event contracts.MIDI) *commandCount {
+	if event.Command&0xF0 == 0xB0 {
+		return &c.controllers[event.Command&0x0F][event.Note&0x7F]
+	}
+	return &c.statuses[event.Command]
+}
+
+// snapshot returns the counts of the kinds of events received, ordered by status byte and
+// controller.
+func (c *commands) snapshot() []contracts.CommandStats {
+	var stats []contracts.CommandStats
+	for status := range c.statuses {
+		if status&0xF0 == 0xB0 {
+			for controller := range c.controllers[status&0x0F] {
+				stats = appendCommand(stats, &c.controllers[status&0x0F][controller], byte(status), controller)
+			}
+			continue
+		}
+		stats = appendCommand(stats, &c.statuses[status], byte(status), -1)
+	}
+	return stats
+}
+
+// appendCommand appends the counts of count to stats if it counted events.
+func appendCommand(stats []contracts.CommandStats, count *commandCount, status byte, controller int) []contracts.CommandStats {
+	received := count.received.Load()
+	if received == 0 {
+		return stats
+	}
+	return append(stats, contracts.CommandStats{
+		Command:    status,
+		Controller: controller,
+		Received:   received,
+		Filtered:   count.filtered.Load(),
+	})
+}
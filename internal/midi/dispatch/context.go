@@ -0,0 +1,23 @@
+package dispatch
+
+import (
+	"context"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// StartContext implements StartCaptureContext for a backend: it starts capturing into
+// eventChannel with start and calls stop once ctx is done. If ctx is already done the
+// capture is not started, stop is called right away and the error of ctx is returned; if
+// start fails, its error is returned and stop is not called.
+func StartContext(ctx context.Context, eventChannel chan contracts.MIDI, start func(chan contracts.MIDI) error, stop func() error) error {
+	if err := ctx.Err(); err != nil {
+		stop()
+		return err
+	}
+	if err := start(eventChannel); err != nil {
+		return err
+	}
+	context.AfterFunc(ctx, func() { stop() })
+	return nil
+}
@@ -0,0 +1,68 @@
+package dispatch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// dedupPruneSize is the number of remembered events above which expired ones are discarded.
+const dedupPruneSize = 1024
+
+// dedupKey identifies identical messages.
+type dedupKey struct {
+	command, note, velocity byte
+}
+
+// dedupEntry remembers the last device a message arrived from.
+type dedupEntry struct {
+	deviceID int   // SourceDeviceID of the message.
+	at       int64 // Unix nanoseconds of the arrival.
+}
+
+// Dedup suppresses identical messages arriving from different devices within a window, as
+// when a controller is connected both directly and through a MIDI thru chain. Devices are
+// told apart by the SourceDeviceID of their events, so a Dedup is shared by the
+// dispatchers of the devices compared, such as those of the clients of an input group.
+// Repeated messages from the same device are never suppressed. It is safe for concurrent
+// use.
+type Dedup struct {
+	window int64 // Window in nanoseconds.
+	mu     sync.Mutex
+	recent map[dedupKey]dedupEntry
+}
+
+// NewDedup creates a Dedup with the given window, or returns nil when window is not positive.
+func NewDedup(window time.Duration) *Dedup {
+	if window <= 0 {
+		return nil
+	}
+	return &Dedup{window: int64(window), recent: make(map[dedupKey]dedupEntry)}
+}
+
+// Duplicate reports whether event repeats a message another device delivered within the
+// window. Otherwise it remembers the event. A nil Dedup never reports duplicates.
+func (dd *Dedup) Duplicate(event contracts.MIDI) bool {
+	if dd == nil {
+		return false
+	}
+	key := dedupKey{event.Command, event.Note, event.Velocity}
+	now := time.Now().UnixNano()
+
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+
+	if last, ok := dd.recent[key]; ok && last.deviceID != event.SourceDeviceID && now-last.at <= dd.window {
+		return true
+	}
+	if len(dd.recent) >= dedupPruneSize {
+		for k, entry := range dd.recent {
+			if now-entry.at > dd.window {
+				delete(dd.recent, k)
+			}
+		}
+	}
+	dd.recent[key] = dedupEntry{deviceID: event.SourceDeviceID, at: now}
+	return false
+}
@@ -0,0 +1,403 @@
+package dispatch
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Dispatcher hands captured events to the consumer's channel. It is shared by the
+// backends so that filtering and delivery behave the same on every platform, and so
+// that the filter can be replaced while capture is running.
+type Dispatcher struct {
+	logger       contracts.Logger
+	backend      string                                    // Name of the backend, reported in health and profiles.
+	logging      bool                                      // Whether Dispatch may log; false with contracts.WithoutLogging.
+	filter       atomic.Pointer[contracts.MIDIEventFilter] // Current event filter; nil accepts every event.
+	predicate    func(contracts.MIDI) bool                 // Optional filter of the application applied after filter.
+	transforms   []contracts.Transform                     // Transforms of the application applied after predicate.
+	velocity     *velocityCurves                           // Optional velocity tables applied before transforms.
+	eventChannel atomic.Pointer[attachment]                // Consumer channel; nil when detached.
+	overflow     contracts.OverflowPolicy                  // What to do with events when the channel is full.
+	clock        contracts.TimestampClock                  // Clock of the timestamps of captured events.
+	coalescer    *coalescer                                // Backlog of OverflowCoalesce; nil with other policies.
+	lastEvent    atomic.Int64                              // Unix nanoseconds of the last received event.
+	received     atomic.Uint64                             // Events received, before filtering.
+	filtered     atomic.Uint64                             // Events discarded by the filter.
+	dropped      atomic.Uint64                             // Events discarded because the channel was full.
+	coalesced    atomic.Uint64                             // Events replaced by a later value under OverflowCoalesce.
+	duplicates   atomic.Uint64                             // Events suppressed as duplicates from another source.
+	duplicate    func(contracts.MIDI) bool                 // Optional detection of duplicates from another device; see contracts.ClientOptions.
+	malformed    atomic.Uint64                             // Malformed sequences discarded by the parsers.
+	panics       atomic.Uint64                             // Panics recovered in callbacks and capture paths.
+	strict       bool                                      // Whether malformed data is reported and split messages rejected; see contracts.StrictParsing.
+	errors       chan error                                // Optional channel for errors detected while capturing.
+	sourceQueue  int                                       // Length of the per-source queues; 0 dispatches on the capture callback.
+	realtime     bool                                      // Whether source goroutines run on locked, high-priority threads.
+	sourcesMu    sync.Mutex                                // Protects sources.
+	sources      []*Source                                 // Devices currently feeding the dispatcher.
+	sysex        *sysex                                    // Delivery of SysEx messages, separate from channel events.
+	midi2        midi2                                     // Delivery of MIDI 2.0 events, separate from channel events.
+	attachedAt   atomic.Int64                              // Unix nanoseconds of the last Attach.
+	watchdog     *contracts.InactivityWatchdog             // Optional inactivity watchdog configuration.
+	watchMu      sync.Mutex                                // Protects watchDone.
+	watchDone    chan struct{}                             // Closed to stop the running watchdog; nil when not running.
+	hooks        *hooks                                    // Optional lifecycle callbacks; nil when not configured.
+	callbacks    *callbacks                                // Workers of the handler of StartCaptureFunc.
+	thru         *contracts.ThruConfig                     // Optional echo of received events; nil when disabled.
+}
+
+// New creates a dispatcher for the named backend using the logger and initial event filter from options.
+func New(backend string, options *contracts.ClientOptions) *Dispatcher {
+	d := &Dispatcher{
+		logger:     options.Logger,
+		backend:    backend,
+		logging:    !options.DisableLogging,
+		predicate:  options.EventPredicate,
+		transforms: options.Transforms,
+		velocity:   newVelocityCurves(options.VelocityCurves),
+		watchdog:   options.InactivityWatchdog,
+		sysex:      newSysEx(options.SysEx),
+		duplicate:  options.Duplicate,
+
+		sourceQueue: options.SourceQueue,
+		realtime:    options.RealtimeDispatch,
+		strict:      options.ParsingMode == contracts.StrictParsing,
+		errors:      options.Errors,
+		overflow:    options.Overflow,
+		clock:       options.Clock,
+	}
+	if d.overflow == contracts.OverflowCoalesce {
+		d.coalescer = newCoalescer()
+	}
+	d.hooks = newHooks(d, options.Hooks)
+	d.callbacks = newCallbacks(options)
+	d.midi2.channel = options.MIDI2
+	if options.Thru != nil && options.Thru.Send != nil {
+		d.thru = options.Thru
+	}
+	d.filter.Store(options.MIDIEventFilter)
+	return d
+}
+
+// SetFilter replaces the event filter. A nil filter accepts every event.
+// It is safe to call while events are being dispatched.
+func (d *Dispatcher) SetFilter(filter *contracts.MIDIEventFilter) {
+	d.filter.Store(filter)
+}
+
+// CheckChannel reports whether eventChannel can be attached: it must not be nil, and with
+// OverflowBlock and OverflowRingBuffer it must be buffered, since an unbuffered channel
+// has no room to wait for or to make and would lose every event the consumer is not
+// already waiting for.
+func (d *Dispatcher) CheckChannel(eventChannel chan contracts.MIDI) error {
+	switch {
+	case eventChannel == nil:
+		return contracts.ErrNilChannel
+	case cap(eventChannel) == 0 && (d.overflow == contracts.OverflowBlock || d.overflow == contracts.OverflowRingBuffer):
+		return fmt.Errorf("%w (%s)", contracts.ErrUnbufferedChannel, d.overflow)
+	}
+	return nil
+}
+
+// Attach sets the channel events are delivered to and starts the inactivity watchdog and
+// the SysEx handler, if configured, the workers of a channel returned by Callback and the
+// delivery of the OverflowCoalesce backlog, and reports the start of capture to the hooks.
+// The interval statistics restart with every Attach.
+func (d *Dispatcher) Attach(eventChannel chan contracts.MIDI) {
+	d.sourcesMu.Lock()
+	for _, source := range d.sources {
+		source.intervals.reset()
+	}
+	d.sourcesMu.Unlock()
+
+	d.attachedAt.Store(time.Now().UnixNano())
+	var current *attachment
+	if eventChannel != nil {
+		current = &attachment{channel: eventChannel, detached: make(chan struct{})}
+	}
+	previous := d.eventChannel.Swap(current)
+	if previous != nil {
+		close(previous.detached)
+	}
+	d.startWatchdog()
+	d.sysex.start(d)
+	d.callbacks.start(d, eventChannel)
+	d.coalescer.start(d, current)
+	if previous == nil && current != nil {
+		d.hooks.captureStarted()
+	}
+}
+
+// Detach stops delivering events and stops the inactivity watchdog, the SysEx handler, the
+// workers of Callback and the delivery of the OverflowCoalesce backlog; subsequent events
+// are discarded, and so are the events waiting for room under OverflowBlock. It reports
+// the end of capture to the hooks if a channel was attached.
+func (d *Dispatcher) Detach() {
+	previous := d.eventChannel.Swap(nil)
+	if previous != nil {
+		close(previous.detached)
+	}
+	d.stopWatchdog()
+	d.sysex.stop()
+	d.callbacks.stop()
+	d.coalescer.stop()
+	if previous != nil {
+		d.hooks.captureStopped()
+	}
+}
+
+// DeviceSelected reports to the hooks that the device with the given ID was selected and
+// opened. Backends call it at the end of a successful SelectDevice.
+func (d *Dispatcher) DeviceSelected(id int, device contracts.DeviceInfo) {
+	d.hooks.deviceSelected(id, device)
+}
+
+// Channel returns the channel events are delivered to, or nil when detached.
+func (d *Dispatcher) Channel() chan contracts.MIDI {
+	if current := d.eventChannel.Load(); current != nil {
+		return current.channel
+	}
+	return nil
+}
+
+// Logging reports whether the capture path may log. Backends check it before logging
+// from their device callbacks.
+func (d *Dispatcher) Logging() bool {
+	return d.logging
+}
+
+// Attached reports whether a consumer channel is set.
+func (d *Dispatcher) Attached() bool {
+	return d.Channel() != nil
+}
+
+// Allowed reports whether the current filter and the event predicate let event through.
+func (d *Dispatcher) Allowed(event contracts.MIDI) bool {
+	return d.commandAllowed(event) && d.predicateAllowed(event)
+}
+
+// predicateAllowed reports whether the event predicate, if any, keeps event. An event the
+// predicate panics on is discarded.
+func (d *Dispatcher) predicateAllowed(event contracts.MIDI) bool {
+	if d.predicate == nil {
+		return true
+	}
+	keep := false
+	d.protect("event predicate", func() { keep = d.predicate(event) })
+	return keep
+}
+
+// commandAllowed reports whether the current filter lets event through.
+func (d *Dispatcher) commandAllowed(event contracts.MIDI) bool {
+	filter := d.filter.Load()
+	return filter == nil || filter.Matches(event)
+}
+
+// Dispatch records a received event and delivers it if the filter allows it. When the
+// consumer's channel is full the overflow policy applies; with the default OverflowDrop it
+// never blocks and the event is dropped with a warning, unless logging is disabled.
+// It reports whether the event was delivered.
+func (d *Dispatcher) Dispatch(event contracts.MIDI) bool {
+	return d.dispatch(nil, event)
+}
+
+// dispatch implements Dispatch for events of source, which may be nil. With deduplication
+// enabled, events of source repeating those of another device are suppressed before
+// filtering. With MIDI thru, the others are echoed before filtering, or after the
+// transforms for filtered thru.
+func (d *Dispatcher) dispatch(source *Source, event contracts.MIDI) bool {
+	now := time.Now().UnixNano()
+	d.received.Add(1)
+	d.lastEvent.Store(now)
+
+	if source != nil && d.duplicate != nil && d.duplicate(event) {
+		d.duplicates.Add(1)
+		return false
+	}
+
+	if d.thru != nil && !d.thru.Filtered {
+		d.echo(event)
+	}
+	delivered, ok := d.accept(source, event)
+	if !ok {
+		d.filtered.Add(1)
+		if source != nil {
+			source.commands.count(event).filtered.Add(1)
+		}
+		return false
+	}
+	if d.thru != nil && d.thru.Filtered {
+		d.echo(delivered)
+	}
+
+	current := d.eventChannel.Load()
+	if current == nil {
+		return false
+	}
+	return d.deliver(source, current, delivered)
+}
+
+// accept applies the filter, the event predicate, the velocity table of source and the
+// transforms to event, and returns the event to deliver and whether it is delivered. An
+// event a transform panics on is discarded.
+func (d *Dispatcher) accept(source *Source, event contracts.MIDI) (contracts.MIDI, bool) {
+	if !d.Allowed(event) {
+		return event, false
+	}
+	if source != nil {
+		event = remapVelocity(source.velocity, event)
+	} else if d.velocity != nil {
+		event = remapVelocity(d.velocity.fallback, event)
+	}
+	for _, transform := range d.transforms {
+		ok := false
+		d.protect("event transform", func() { event, ok = transform(event) })
+		if !ok {
+			return event, false
+		}
+	}
+	return event, true
+}
+
+// echo sends event to the output of MIDI thru, reporting failures to the error channel.
+func (d *Dispatcher) echo(event contracts.MIDI) {
+	if err := d.thru.Send(event); err != nil {
+		d.ReportError(fmt.Errorf("MIDI thru: %w", err))
+	}
+}
+
+// Strict reports whether strict parsing is enabled. Packet-based backends then reject
+// messages split across packets.
+func (d *Dispatcher) Strict() bool {
+	return d.strict
+}
+
+// MaxSysEx returns the longest SysEx message to deliver, in bytes, or zero when SysEx
+// messages are discarded, leaving the parsers their default bound.
+func (d *Dispatcher) MaxSysEx() int {
+	return d.sysex.maxSize
+}
+
+// SysExDriverBuffers returns the number and size of the buffers backends receiving SysEx
+// into fixed driver buffers should queue, or zeros when SysEx messages are discarded.
+func (d *Dispatcher) SysExDriverBuffers() (count, size int) {
+	return d.sysex.driverBuffers, d.sysex.driverBufferSize
+}
+
+// ReportError sends err to the error channel, if one is configured. It never blocks: the
+// error is dropped when the channel is full.
+func (d *Dispatcher) ReportError(err error) {
+	if d.errors == nil {
+		return
+	}
+	select {
+	case d.errors <- err:
+	default:
+	}
+}
+
+// Health returns the delivery-related part of the client health: capture state,
+// last event time and event counters. Backends fill in the rest.
+func (d *Dispatcher) Health() contracts.Health {
+	health := contracts.Health{
+		Backend:        d.backend,
+		Capturing:      d.Attached(),
+		DeviceID:       -1,
+		EventsReceived: d.received.Load(),
+		EventsFiltered: d.filtered.Load(),
+		EventsDropped:  d.dropped.Load(),
+		Duplicates:     d.duplicates.Load(),
+		Malformed:      d.malformed.Load(),
+		SysExReceived:  d.sysex.received.Load(),
+		SysExDropped:   d.sysex.dropped.Load(),
+		MIDI2Received:  d.midi2.received.Load(),
+		MIDI2Dropped:   d.midi2.dropped.Load(),
+		Panics:         d.panics.Load(),
+	}
+	if last := d.lastEvent.Load(); last != 0 {
+		health.LastEvent = time.Unix(0, last)
+	}
+	return health
+}
+
+// Stats returns the traffic statistics of every registered source, the event counters,
+// the occupancy of the attached channel and the time taken by the handler of Callback.
+func (d *Dispatcher) Stats() contracts.Stats {
+	stats := contracts.Stats{
+		Received:   d.received.Load(),
+		Filtered:   d.filtered.Load(),
+		Dropped:    d.dropped.Load(),
+		Coalesced:  d.coalesced.Load(),
+		SysExBytes: d.sysex.bytes.Load(),
+		Callbacks:  d.callbacks.latencies.snapshot(),
+	}
+	if channel := d.Channel(); channel != nil {
+		stats.Queued, stats.Capacity = len(channel), cap(channel)
+	}
+
+	d.sourcesMu.Lock()
+	defer d.sourcesMu.Unlock()
+	for _, source := range d.sources {
+		stats.Devices = append(stats.Devices, source.stats())
+	}
+	return stats
+}
+
+// startWatchdog starts the inactivity watchdog goroutine if it is configured and not running.
+func (d *Dispatcher) startWatchdog() {
+	if d.watchdog == nil || d.watchdog.Timeout <= 0 || d.watchdog.Callback == nil {
+		return
+	}
+
+	d.watchMu.Lock()
+	defer d.watchMu.Unlock()
+	if d.watchDone != nil {
+		return
+	}
+	done := make(chan struct{})
+	d.watchDone = done
+	profiling.Go(profiling.RoleWatchdog, d.backend, "", func() { d.watch(done) })
+}
+
+// stopWatchdog stops the inactivity watchdog goroutine, if running.
+func (d *Dispatcher) stopWatchdog() {
+	d.watchMu.Lock()
+	defer d.watchMu.Unlock()
+	if d.watchDone != nil {
+		close(d.watchDone)
+		d.watchDone = nil
+	}
+}
+
+// watch checks for silence a few times per timeout period and calls the watchdog callback
+// once per silent period, until done is closed.
+func (d *Dispatcher) watch(done chan struct{}) {
+	timeout := d.watchdog.Timeout
+	ticker := time.NewTicker(max(timeout/4, time.Millisecond))
+	defer ticker.Stop()
+
+	fired := false
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			idle := now.Sub(time.Unix(0, max(d.lastEvent.Load(), d.attachedAt.Load())))
+			if idle < timeout {
+				fired = false
+				continue
+			}
+			if !fired {
+				fired = true
+				d.logger.Warn("No MIDI events received within the inactivity timeout",
+					d.logger.Field().String("idle", idle.String()))
+				d.protect("inactivity watchdog", func() { d.watchdog.Callback(idle) })
+			}
+		}
+	}
+}
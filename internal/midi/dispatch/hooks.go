@@ -0,0 +1,91 @@
+package dispatch
+
+import (
+	"sync"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// hooks runs the lifecycle callbacks of the application one at a time, in the order of
+// the changes, on a goroutine that exists only while callbacks are pending. Backends
+// report changes while holding their locks, so the callbacks never run on their
+// goroutines and may call the client.
+type hooks struct {
+	config     contracts.Hooks
+	dispatcher *Dispatcher // Reports panics in the callbacks.
+	mu         sync.Mutex
+	pending    []func() // Callbacks waiting to run, oldest first.
+	running    bool     // Whether the goroutine running pending is alive.
+}
+
+// newHooks returns the runner of the callbacks in config for d, or nil if config is nil.
+func newHooks(d *Dispatcher, config *contracts.Hooks) *hooks {
+	if config == nil {
+		return nil
+	}
+	return &hooks{config: *config, dispatcher: d}
+}
+
+// run queues the callback named name and starts the goroutine running the queue if
+// needed. It never blocks.
+func (h *hooks) run(name string, call func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pending = append(h.pending, func() { h.dispatcher.protect(name+" hook", call) })
+	if !h.running {
+		h.running = true
+		profiling.Go(profiling.RoleHooks, h.dispatcher.backend, "", h.drain)
+	}
+}
+
+// drain runs the queued callbacks until none is left.
+func (h *hooks) drain() {
+	for {
+		h.mu.Lock()
+		if len(h.pending) == 0 {
+			h.running = false
+			h.mu.Unlock()
+			return
+		}
+		call := h.pending[0]
+		h.pending[0] = nil
+		h.pending = h.pending[1:]
+		h.mu.Unlock()
+
+		call()
+	}
+}
+
+// deviceSelected queues OnDeviceSelected.
+func (h *hooks) deviceSelected(id int, device contracts.DeviceInfo) {
+	if h == nil || h.config.OnDeviceSelected == nil {
+		return
+	}
+	h.run("OnDeviceSelected", func() { h.config.OnDeviceSelected(id, device) })
+}
+
+// captureStarted queues OnCaptureStarted.
+func (h *hooks) captureStarted() {
+	if h == nil || h.config.OnCaptureStarted == nil {
+		return
+	}
+	h.run("OnCaptureStarted", h.config.OnCaptureStarted)
+}
+
+// captureStopped queues OnCaptureStopped.
+func (h *hooks) captureStopped() {
+	if h == nil || h.config.OnCaptureStopped == nil {
+		return
+	}
+	h.run("OnCaptureStopped", h.config.OnCaptureStopped)
+}
+
+// deviceLost queues OnDeviceLost.
+func (h *hooks) deviceLost(id int, device contracts.DeviceInfo, err error) {
+	if h == nil || h.config.OnDeviceLost == nil {
+		return
+	}
+	h.run("OnDeviceLost", func() { h.config.OnDeviceLost(id, device, err) })
+}
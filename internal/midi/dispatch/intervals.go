@@ -0,0 +1,146 @@
+package dispatch
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// intervalWindow is the number of most recent samples kept for the statistics.
+const intervalWindow = 1024
+
+// window is a fixed-size ring of the most recent samples, in nanoseconds, so that recording
+// stays allocation-free on the capture path.
+type window struct {
+	samples [intervalWindow]int64 // Ring of samples.
+	count   int                   // Number of valid samples.
+	next    int                   // Index of the next sample to overwrite.
+}
+
+// add records sample, overwriting the oldest one when the ring is full.
+func (w *window) add(sample int64) {
+	w.samples[w.next] = sample
+	w.next = (w.next + 1) % intervalWindow
+	w.count = min(w.count+1, intervalWindow)
+}
+
+// sorted returns a sorted copy of the samples.
+func (w *window) sorted() []int64 {
+	samples := make([]int64, w.count)
+	copy(samples, w.samples[:w.count])
+	slices.Sort(samples)
+	return samples
+}
+
+// intervals records the time between consecutive events.
+type intervals struct {
+	mu     sync.Mutex
+	last   int64 // Unix nanoseconds of the previous event; zero before the first one.
+	window window
+}
+
+// record registers an event received at now (Unix nanoseconds).
+func (iv *intervals) record(now int64) {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+
+	if iv.last != 0 {
+		iv.window.add(max(now-iv.last, 0))
+	}
+	iv.last = now
+}
+
+// reset discards the recorded intervals.
+func (iv *intervals) reset() {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+
+	iv.last = 0
+	iv.window = window{}
+}
+
+// snapshot summarises the recorded intervals.
+func (iv *intervals) snapshot() contracts.IntervalStats {
+	iv.mu.Lock()
+	samples := iv.window.sorted()
+	iv.mu.Unlock()
+
+	if len(samples) == 0 {
+		return contracts.IntervalStats{}
+	}
+	summary := summarise(samples)
+	stats := contracts.IntervalStats{
+		Count: summary.Count,
+		Min:   summary.Min,
+		Max:   summary.Max,
+		Mean:  summary.Mean,
+		P50:   summary.P50,
+		P95:   summary.P95,
+		P99:   summary.P99,
+	}
+	var total int64
+	for _, sample := range samples {
+		total += sample
+	}
+	if total > 0 {
+		stats.EventsPerSecond = float64(len(samples)) / time.Duration(total).Seconds()
+	}
+	return stats
+}
+
+// latencies records the time the handler of StartCaptureFunc takes for each event.
+type latencies struct {
+	mu     sync.Mutex
+	window window
+}
+
+// record registers a call of the handler that took elapsed.
+func (l *latencies) record(elapsed time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.window.add(int64(elapsed))
+}
+
+// reset discards the recorded calls.
+func (l *latencies) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.window = window{}
+}
+
+// snapshot summarises the recorded calls.
+func (l *latencies) snapshot() contracts.LatencyStats {
+	l.mu.Lock()
+	samples := l.window.sorted()
+	l.mu.Unlock()
+
+	if len(samples) == 0 {
+		return contracts.LatencyStats{}
+	}
+	return summarise(samples)
+}
+
+// summarise returns the count, extremes, mean and percentiles of non-empty sorted samples.
+func summarise(sorted []int64) contracts.LatencyStats {
+	var total int64
+	for _, sample := range sorted {
+		total += sample
+	}
+	return contracts.LatencyStats{
+		Count: len(sorted),
+		Min:   time.Duration(sorted[0]),
+		Max:   time.Duration(sorted[len(sorted)-1]),
+		Mean:  time.Duration(total / int64(len(sorted))),
+		P50:   time.Duration(percentile(sorted, 50)),
+		P95:   time.Duration(percentile(sorted, 95)),
+		P99:   time.Duration(percentile(sorted, 99)),
+	}
+}
+
+// percentile returns the p-th percentile of sorted samples using the nearest-rank method.
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
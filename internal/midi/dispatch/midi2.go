@@ -0,0 +1,74 @@
+package dispatch
+
+import (
+	"sync/atomic"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/ump"
+)
+
+// midi2 delivers channel messages as MIDI 2.0 events to the consumer's MIDI 2.0 channel,
+// independently of the event channel.
+type midi2 struct {
+	channel  chan contracts.MIDI2Event // Consumer channel; nil when MIDI 2.0 events are not delivered.
+	received atomic.Uint64             // Events received while attached.
+	dropped  atomic.Uint64             // Events discarded because channel was full.
+}
+
+// MIDI2 reports whether channel messages are delivered as MIDI 2.0 events. Backends able
+// to receive MIDI 2.0 then use DispatchMIDI2 instead of Dispatch.
+func (d *Dispatcher) MIDI2() bool {
+	return d.midi2.channel != nil
+}
+
+// DispatchMIDI2 delivers a MIDI 2.0 event to the MIDI 2.0 channel. Like DispatchSysEx, it
+// never blocks and only delivers while a consumer is attached; the event is dropped when
+// the channel is full. It reports whether the event was delivered.
+func (d *Dispatcher) DispatchMIDI2(event contracts.MIDI2Event) bool {
+	if !d.Attached() {
+		return false
+	}
+	d.midi2.received.Add(1)
+	if d.midi2.channel == nil {
+		return false
+	}
+
+	select {
+	case d.midi2.channel <- event:
+		return true
+	default:
+		d.midi2.dropped.Add(1)
+		if d.logging {
+			d.logger.Warn("MIDI 2.0 channel full; dropping MIDI 2.0 event")
+		}
+		return false
+	}
+}
+
+// upconvert delivers a MIDI 1.0 channel message of source to the MIDI 2.0 channel, if
+// configured, converted to MIDI 2.0.
+func (s *Source) upconvert(event contracts.MIDI) {
+	if s.dispatcher.midi2.channel == nil {
+		return
+	}
+	if converted, ok := ump.Upconvert(event); ok {
+		s.dispatcher.DispatchMIDI2(converted)
+	}
+}
+
+// DispatchMIDI2 delivers a MIDI 2.0 event of the device, tagged with its ID, to the MIDI
+// 2.0 channel, and dispatches it converted to MIDI 1.0 like Dispatch. Events without
+// MIDI 1.0 equivalent, such as per-note controllers, only reach the MIDI 2.0 channel. The
+// same restrictions as Dispatch apply.
+func (s *Source) DispatchMIDI2(event contracts.MIDI2Event) {
+	if s.failed.Load() {
+		return
+	}
+	event.SourceDeviceID = s.id
+	s.dispatcher.DispatchMIDI2(event)
+
+	var converted [4]contracts.MIDI
+	for _, message := range ump.Downconvert(converted[:0], event) {
+		s.dispatch(message)
+	}
+}
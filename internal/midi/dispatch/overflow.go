@@ -0,0 +1,212 @@
+package dispatch
+
+import (
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+const (
+	coalesceLimit = 4096 // Events the OverflowCoalesce backlog holds; later ones are dropped.
+	ringAttempts  = 3    // Times OverflowRingBuffer makes room before dropping the event.
+)
+
+// attachment is a consumer channel together with the signal of its detachment, which
+// releases the deliveries waiting for room in it.
+type attachment struct {
+	channel  chan contracts.MIDI
+	detached chan struct{} // Closed when the channel is detached or replaced.
+}
+
+// deliver sends event to the consumer channel, applying the overflow policy when the
+// channel is full. It reports whether the event was delivered or queued for delivery.
+func (d *Dispatcher) deliver(source *Source, current *attachment, event contracts.MIDI) bool {
+	if d.coalescer != nil {
+		return d.coalescer.add(d, current, event)
+	}
+
+	select {
+	case current.channel <- event:
+		return true
+	default:
+	}
+
+	switch d.overflow {
+	case contracts.OverflowBlock:
+		var closed chan struct{} // Never ready without a source queue.
+		if source != nil {
+			closed = source.done
+		}
+		select {
+		case current.channel <- event:
+			return true
+		case <-current.detached:
+		case <-closed:
+		}
+		return false
+	case contracts.OverflowRingBuffer:
+		for range ringAttempts {
+			select {
+			case <-current.channel:
+				d.overflowed("Event buffer full; dropping oldest MIDI event")
+			default:
+			}
+			select {
+			case current.channel <- event:
+				return true
+			default:
+			}
+		}
+	}
+	d.overflowed("Event buffer full; dropping MIDI event")
+	return false
+}
+
+// overflowed counts an event discarded because the consumer channel was full.
+func (d *Dispatcher) overflowed(message string) {
+	d.dropped.Add(1)
+	if d.logging {
+		d.logger.Warn(message)
+	}
+}
+
+// coalesceKey identifies a continuous control, whose latest value supersedes the earlier
+// ones waiting in the backlog.
+type coalesceKey struct {
+	status byte // Status byte, including the channel.
+	data   byte // Note of polyphonic pressure, or controller of control changes.
+}
+
+// coalesceKeyOf returns the control event sets, and false for events that must all be
+// delivered, such as notes, bank select and the parameter number messages, whose meaning
+// depends on the messages around them.
+func coalesceKeyOf(event contracts.MIDI) (coalesceKey, bool) {
+	switch event.Command & 0xF0 {
+	case 0xA0:
+		return coalesceKey{event.Command, event.Note}, true
+	case 0xB0:
+		switch event.Note {
+		case 0, 32, 6, 38, 96, 97, 98, 99, 100, 101: // Bank select, data entry, increment and parameter numbers
+			return coalesceKey{}, false
+		}
+		return coalesceKey{event.Command, event.Note}, true
+	case 0xD0, 0xE0:
+		return coalesceKey{event.Command, 0}, true
+	}
+	return coalesceKey{}, false
+}
+
+// coalescer queues the events that do not fit in the consumer channel under
+// OverflowCoalesce and delivers them, in order, as the consumer makes room. An event of a
+// control already waiting in the backlog replaces the waiting one.
+type coalescer struct {
+	mu      sync.Mutex
+	pending []contracts.MIDI       // Events waiting for room, oldest first.
+	first   uint64                 // Sequence number of pending[0].
+	index   map[coalesceKey]uint64 // Sequence number of the waiting event of each control.
+	sending bool                   // Whether an event taken from pending is being delivered.
+	wake    chan struct{}          // Signals new pending events.
+	wg      sync.WaitGroup         // Waits for the delivery goroutine.
+}
+
+// newCoalescer creates an empty backlog.
+func newCoalescer() *coalescer {
+	return &coalescer{index: make(map[coalesceKey]uint64), wake: make(chan struct{}, 1)}
+}
+
+// start starts delivering the backlog to current, discarding the events left from the
+// previous attachment. It does nothing on a nil coalescer or attachment.
+func (c *coalescer) start(d *Dispatcher, current *attachment) {
+	if c == nil || current == nil {
+		return
+	}
+	c.wg.Wait() // The previous attachment was detached by the caller
+
+	c.mu.Lock()
+	d.dropped.Add(uint64(len(c.pending)))
+	c.pending, c.first, c.sending = nil, 0, false
+	clear(c.index)
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go c.deliver(current)
+}
+
+// stop waits for the delivery goroutine, which returns once its attachment is detached,
+// so no event is sent to the channel after Detach. It does nothing on a nil coalescer.
+func (c *coalescer) stop() {
+	if c != nil {
+		c.wg.Wait()
+	}
+}
+
+// add sends event to the channel of current, or queues it behind the waiting events. It
+// reports false when the backlog is full and the event was dropped.
+func (c *coalescer) add(d *Dispatcher, current *attachment, event contracts.MIDI) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.pending) == 0 && !c.sending {
+		select {
+		case current.channel <- event:
+			return true
+		default:
+		}
+	}
+
+	key, continuous := coalesceKeyOf(event)
+	if continuous {
+		if seq, ok := c.index[key]; ok {
+			c.pending[seq-c.first] = event
+			d.coalesced.Add(1)
+			return true
+		}
+	}
+	if len(c.pending) >= coalesceLimit {
+		d.overflowed("Event backlog full; dropping MIDI event")
+		return false
+	}
+	if continuous {
+		c.index[key] = c.first + uint64(len(c.pending))
+	}
+	c.pending = append(c.pending, event)
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// deliver sends the waiting events to the channel of current, in order, until it is
+// detached.
+func (c *coalescer) deliver(current *attachment) {
+	defer c.wg.Done()
+	for {
+		c.mu.Lock()
+		if len(c.pending) == 0 {
+			c.sending = false
+			c.mu.Unlock()
+			select {
+			case <-current.detached:
+				return
+			case <-c.wake:
+			}
+			continue
+		}
+		event := c.pending[0]
+		if key, ok := coalesceKeyOf(event); ok && c.index[key] == c.first {
+			delete(c.index, key)
+		}
+		c.pending = c.pending[1:]
+		c.first++
+		c.sending = true
+		c.mu.Unlock()
+
+		select {
+		case current.channel <- event:
+		case <-current.detached:
+			return
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package dispatch
+
+import (
+	"runtime/debug"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// recovered reports a recovered panic: it counts it, logs it and sends it to the error
+// channel as a *contracts.PanicError, which it returns.
+func (d *Dispatcher) recovered(callback string, deviceID int, value any) *contracts.PanicError {
+	err := &contracts.PanicError{
+		Backend:  d.backend,
+		Callback: callback,
+		DeviceID: deviceID,
+		Value:    value,
+		Stack:    debug.Stack(),
+	}
+	d.panics.Add(1)
+	if d.logging {
+		d.logger.Error(err.Error(), d.logger.Field().String("stack", string(err.Stack)))
+	}
+	d.ReportError(err)
+	return err
+}
+
+// protect calls fn, a callback of the application, recovering and reporting a panic in
+// it so that a faulty callback never takes the process down.
+func (d *Dispatcher) protect(callback string, fn func()) {
+	defer func() {
+		if value := recover(); value != nil {
+			d.recovered(callback, -1, value)
+		}
+	}()
+	fn()
+}
+
+// Recover recovers a panic on the capture path of the device, such as a send on an event
+// channel the application closed, and shuts down the capture of the device: the panic is
+// reported, later events of the source are discarded and the device is reported lost.
+// Backends defer it in their capture goroutines and OS callbacks.
+func (s *Source) Recover() {
+	value := recover()
+	if value == nil {
+		return
+	}
+	s.failed.Store(true)
+	s.Lost(s.dispatcher.recovered("capture", s.id, value))
+}
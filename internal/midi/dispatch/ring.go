@@ -0,0 +1,86 @@
+package dispatch
+
+import (
+	"math/bits"
+	"sync/atomic"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// cacheLine separates the indexes written by the producer and the consumer, so they do not
+// share a cache line.
+const cacheLine = 64
+
+// ring is a lock-free queue of events for a single producer, the capture callback of a
+// device, and a single consumer, the goroutine of its source. Events are copied into a
+// preallocated buffer, so queueing allocates nothing and takes no lock on the capture path.
+type ring struct {
+	buffer []contracts.MIDI
+	mask   uint64
+
+	_    [cacheLine]byte
+	head atomic.Uint64 // Next slot to read; written by the consumer.
+	_    [cacheLine]byte
+	tail atomic.Uint64 // Next slot to write; written by the producer.
+	_    [cacheLine]byte
+
+	waiting atomic.Bool   // Whether the consumer is about to wait for wake.
+	wake    chan struct{} // Signals the waiting consumer of a new event.
+}
+
+// newRing creates a ring holding size events, rounded up to a power of two.
+func newRing(size int) *ring {
+	capacity := uint64(1) << bits.Len(uint(max(size, 1)-1))
+	return &ring{
+		buffer: make([]contracts.MIDI, capacity),
+		mask:   capacity - 1,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// push queues event, waking up the consumer if it waits. It reports false, without
+// blocking, when the ring is full. Only the producer may call it.
+func (r *ring) push(event contracts.MIDI) bool {
+	tail := r.tail.Load()
+	if tail-r.head.Load() > r.mask {
+		return false
+	}
+	r.buffer[tail&r.mask] = event
+	r.tail.Store(tail + 1)
+
+	if r.waiting.Load() && r.waiting.CompareAndSwap(true, false) {
+		select {
+		case r.wake <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// pop returns the oldest queued event, or false when the ring is empty. Only the consumer
+// may call it.
+func (r *ring) pop() (contracts.MIDI, bool) {
+	head := r.head.Load()
+	if head == r.tail.Load() {
+		return contracts.MIDI{}, false
+	}
+	event := r.buffer[head&r.mask]
+	r.head.Store(head + 1)
+	return event, true
+}
+
+// wait blocks the consumer until an event may have been queued, and reports false when
+// done is closed first. Wake-ups may be spurious.
+func (r *ring) wait(done chan struct{}) bool {
+	r.waiting.Store(true)
+	if r.head.Load() != r.tail.Load() {
+		r.waiting.Store(false)
+		return true
+	}
+	select {
+	case <-r.wake:
+		return true
+	case <-done:
+		return false
+	}
+}
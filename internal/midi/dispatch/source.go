@@ -0,0 +1,192 @@
+package dispatch
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/internal/threadprio"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Source is the entry point of one device's events into the dispatcher. It tracks the
+// traffic statistics of the device and, when source queues are enabled, hands events to
+// the dispatcher from its own goroutine through a bounded lock-free ring, so a stalled
+// delivery for one device never holds up the capture callback of another.
+type Source struct {
+	dispatcher *Dispatcher
+	id         int                  // ID of the device.
+	device     contracts.DeviceInfo // Information about the device.
+	intervals  intervals            // Recent intervals between events of the device.
+	commands   commands             // Events of the device by kind.
+	velocity   *[128]byte           // Velocity table of the device; nil leaves velocities unchanged.
+	received   atomic.Uint64        // Events received from the device.
+	dropped    atomic.Uint64        // Events discarded because the queue was full.
+	queue      *ring                // Bounded queue before the merge stage; nil delivers directly.
+	done       chan struct{}        // Closed by Close to stop the queue goroutine.
+	wg         sync.WaitGroup       // Tracks the queue goroutine.
+	lost       atomic.Bool          // Whether the loss of the device was reported.
+	failed     atomic.Bool          // Whether the capture panicked; later events are discarded.
+	closeOnce  sync.Once
+}
+
+// AddSource registers a device whose events are dispatched through the returned Source.
+// Sources must be closed when the device is disconnected.
+func (d *Dispatcher) AddSource(id int, device contracts.DeviceInfo) *Source {
+	s := &Source{dispatcher: d, id: id, device: device, velocity: d.velocity.table(device)}
+	if d.sourceQueue > 0 {
+		s.queue = newRing(d.sourceQueue)
+		s.done = make(chan struct{})
+		s.wg.Add(1)
+		profiling.Go(profiling.RoleDispatch, d.backend, device.Name, s.forward)
+	}
+
+	d.sourcesMu.Lock()
+	d.sources = append(d.sources, s)
+	d.sourcesMu.Unlock()
+	return s
+}
+
+// Dispatch records an event of the device, tagged with its ID, and passes it on to the
+// dispatcher, through the source queue if enabled. It never blocks: when the queue is full
+// the event is dropped and counted against this source. Events are discarded once the
+// capture of the source panicked; see Recover. With WithMIDI2Channel, channel messages are
+// also delivered converted to MIDI 2.0. It must not be called concurrently for the same
+// source, as the queue has a single producer.
+func (s *Source) Dispatch(event contracts.MIDI) {
+	if s.failed.Load() {
+		return
+	}
+	event.SourceDeviceID = s.id
+	s.upconvert(event)
+	s.dispatch(event)
+}
+
+// dispatch implements Dispatch for an event already delivered to the MIDI 2.0 channel.
+func (s *Source) dispatch(event contracts.MIDI) {
+	s.received.Add(1)
+	s.commands.count(event).received.Add(1)
+	s.intervals.record(time.Now().UnixNano())
+
+	if s.queue == nil {
+		s.dispatcher.dispatch(s, event)
+		return
+	}
+	if !s.queue.push(event) {
+		s.dropped.Add(1)
+		if s.dispatcher.logging {
+			s.dispatcher.logger.Warn("Source queue full; dropping MIDI event",
+				s.dispatcher.logger.Field().String("device", s.device.Name))
+		}
+	}
+}
+
+// DispatchSysEx passes a SysEx message of the device on to the dispatcher. SysEx messages
+// have their own buffering, so they bypass the source queue. Like Dispatch, it discards
+// messages once the capture of the source panicked.
+func (s *Source) DispatchSysEx(event contracts.SysExEvent) bool {
+	if s.failed.Load() {
+		return false
+	}
+	return s.dispatcher.DispatchSysEx(event)
+}
+
+// Malformed records a malformed byte sequence of the device discarded by a parser and, in
+// strict parsing mode, sends a contracts.MalformedDataError describing it to the error
+// channel. It never blocks.
+func (s *Source) Malformed(data []byte, reason string) {
+	d := s.dispatcher
+	d.malformed.Add(1)
+	err := &contracts.MalformedDataError{
+		Backend:  d.backend,
+		DeviceID: s.id,
+		Device:   s.device,
+		Data:     data,
+		Reason:   reason,
+	}
+	if d.logging {
+		d.logger.Debug(err.Error())
+	}
+	if d.strict {
+		d.ReportError(err)
+	}
+}
+
+// Lost reports to the hooks that the device went away while open, for the reason err.
+// Backends call it when a read fails or the driver closes the device, not when the
+// application stops capture. Only the first call has an effect.
+func (s *Source) Lost(err error) {
+	if s.lost.CompareAndSwap(false, true) {
+		s.dispatcher.hooks.deviceLost(s.id, s.device, err)
+	}
+}
+
+// IsLost reports whether Lost was called.
+func (s *Source) IsLost() bool {
+	return s.lost.Load()
+}
+
+// Close unregisters the source and stops its queue goroutine. Queued events are discarded.
+func (s *Source) Close() {
+	s.closeOnce.Do(func() {
+		if s.done != nil {
+			close(s.done)
+			s.wg.Wait()
+		}
+
+		d := s.dispatcher
+		d.sourcesMu.Lock()
+		defer d.sourcesMu.Unlock()
+		for i, source := range d.sources {
+			if source == s {
+				d.sources = append(d.sources[:i], d.sources[i+1:]...)
+				break
+			}
+		}
+	})
+}
+
+// forward hands queued events to the dispatcher until the source is closed. With realtime
+// dispatch it runs on its own OS thread with raised priority.
+func (s *Source) forward() {
+	defer s.wg.Done()
+	defer s.Recover()
+
+	if s.dispatcher.realtime {
+		// The thread is not unlocked, so it exits with the goroutine instead of going back
+		// to the scheduler with a raised priority.
+		runtime.LockOSThread()
+		if err := threadprio.Raise(); err != nil && s.dispatcher.logging {
+			s.dispatcher.logger.Warn("Failed to raise dispatch thread priority",
+				s.dispatcher.logger.Field().Error("error", err))
+		}
+	}
+	for s.queue.wait(s.done) {
+		for {
+			event, ok := s.queue.pop()
+			if !ok {
+				break
+			}
+			s.dispatcher.dispatch(s, event)
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// stats returns the traffic statistics of the source.
+func (s *Source) stats() contracts.DeviceStats {
+	return contracts.DeviceStats{
+		DeviceID:     s.id,
+		Device:       s.device,
+		Received:     s.received.Load(),
+		QueueDropped: s.dropped.Load(),
+		Intervals:    s.intervals.snapshot(),
+		Commands:     s.commands.snapshot(),
+	}
+}
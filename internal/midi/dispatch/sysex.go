@@ -0,0 +1,108 @@
+package dispatch
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// sysex delivers SysEx messages independently of channel events, either to the consumer's
+// SysEx channel or to a handler running on its own goroutine.
+type sysex struct {
+	channel  chan contracts.SysExEvent  // Consumer channel, or the handler queue; nil discards messages.
+	handler  func(contracts.SysExEvent) // Optional handler draining channel.
+	received atomic.Uint64              // Messages received while attached.
+	dropped  atomic.Uint64              // Messages discarded because channel was full.
+	bytes    atomic.Uint64              // Bytes of the messages received while attached.
+	mu       sync.Mutex                 // Protects done.
+	done     chan struct{}              // Closed to stop the handler goroutine; nil when not running.
+
+	maxSize          int // Longest message delivered; 0 when messages are discarded.
+	driverBuffers    int // Number of driver buffers to queue; 0 when messages are discarded.
+	driverBufferSize int // Size of each driver buffer.
+}
+
+// newSysEx prepares SysEx delivery from the client configuration; config may be nil.
+func newSysEx(config *contracts.SysExConfig) *sysex {
+	s := &sysex{}
+	switch {
+	case config == nil:
+	case config.Channel != nil:
+		s.channel = config.Channel
+	case config.Handler != nil:
+		s.channel = make(chan contracts.SysExEvent, max(config.Buffer, 1))
+		s.handler = config.Handler
+	}
+	if s.channel != nil {
+		s.maxSize = config.MaxSize
+		s.driverBuffers, s.driverBufferSize = config.DriverBuffers, config.DriverBufferSize
+	}
+	return s
+}
+
+// start starts the handler goroutine of the dispatcher d, if a handler is configured and
+// it is not running.
+func (s *sysex) start(d *Dispatcher) {
+	if s.handler == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		return
+	}
+	done := make(chan struct{})
+	s.done = done
+	profiling.Go(profiling.RoleDispatch, d.backend, "sysex", func() { s.handle(d, done) })
+}
+
+// stop stops the handler goroutine, if running. Queued messages are kept for the next start.
+func (s *sysex) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+}
+
+// handle calls the handler for queued messages until done is closed. Panics in the
+// handler are reported by d and do not stop the delivery of later messages.
+func (s *sysex) handle(d *Dispatcher, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case event := <-s.channel:
+			d.protect("SysEx handler", func() { s.handler(event) })
+		}
+	}
+}
+
+// DispatchSysEx delivers a complete SysEx message received from the device. Like Dispatch,
+// it never blocks and only delivers while a consumer is attached; the message is dropped
+// when the SysEx channel or handler queue is full. It reports whether the message was delivered.
+func (d *Dispatcher) DispatchSysEx(event contracts.SysExEvent) bool {
+	if !d.Attached() {
+		return false
+	}
+	d.sysex.received.Add(1)
+	d.sysex.bytes.Add(uint64(len(event.Data)))
+	if d.sysex.channel == nil {
+		return false
+	}
+
+	select {
+	case d.sysex.channel <- event:
+		return true
+	default:
+		d.sysex.dropped.Add(1)
+		if d.logging {
+			d.logger.Warn("SysEx buffer full; dropping SysEx message")
+		}
+		return false
+	}
+}
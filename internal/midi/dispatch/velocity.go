@@ -0,0 +1,51 @@
+package dispatch
+
+import (
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// velocityCurves holds the velocity tables of contracts.WithVelocityCurve and
+// contracts.WithDeviceVelocityCurve.
+type velocityCurves struct {
+	fallback *[128]byte            // Table of the devices without one of their own; nil leaves them unchanged.
+	devices  map[string]*[128]byte // Tables of devices by lowercase name.
+}
+
+// newVelocityCurves copies the tables of config, or returns nil when config is nil.
+func newVelocityCurves(config *contracts.VelocityCurves) *velocityCurves {
+	if config == nil {
+		return nil
+	}
+	vc := &velocityCurves{devices: make(map[string]*[128]byte, len(config.Devices))}
+	if config.Default != nil {
+		table := *config.Default
+		vc.fallback = &table
+	}
+	for name, table := range config.Devices {
+		vc.devices[strings.ToLower(name)] = &table
+	}
+	return vc
+}
+
+// table returns the table of device, or nil if its velocities are left unchanged. A nil
+// velocityCurves has no tables.
+func (vc *velocityCurves) table(device contracts.DeviceInfo) *[128]byte {
+	if vc == nil {
+		return nil
+	}
+	if table, ok := vc.devices[strings.ToLower(device.Name)]; ok {
+		return table
+	}
+	return vc.fallback
+}
+
+// remapVelocity returns event with its velocity remapped by table if it is a note on. Note
+// ons keep a velocity of at least 1, so they are not turned into note offs.
+func remapVelocity(table *[128]byte, event contracts.MIDI) contracts.MIDI {
+	if table != nil && event.Command&0xF0 == 0x90 && event.Velocity > 0 {
+		event.Velocity = max(table[event.Velocity&0x7F], 1)
+	}
+	return event
+}
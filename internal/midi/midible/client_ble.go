@@ -0,0 +1,587 @@
+//go:build ble && !nomidihw
+// +build ble,!nomidihw
+
+package midible
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/internal/midi/retry"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"tinygo.org/x/bluetooth"
+)
+
+const (
+	defaultScanTimeout = 2 * time.Second  // How long ListDevices scans for peripherals by default.
+	connectTimeout     = 10 * time.Second // How long a peripheral has to accept a connection.
+	stopScanInterval   = 10 * time.Millisecond
+)
+
+var (
+	midiService        = mustParseUUID(serviceUUID)
+	midiCharacteristic = mustParseUUID(characteristicUUID)
+)
+
+// mustParseUUID parses a UUID constant of the package.
+func mustParseUUID(s string) bluetooth.UUID {
+	uuid, err := bluetooth.ParseUUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return uuid
+}
+
+// bleDevice is a peripheral advertising the MIDI service.
+type bleDevice struct {
+	info    contracts.DeviceInfo
+	address bluetooth.Address
+}
+
+// link is a connection to a peripheral, shared by the input and the output when both
+// select it.
+type link struct {
+	device         bleDevice
+	peripheral     bluetooth.Device
+	characteristic bluetooth.DeviceCharacteristic
+}
+
+// ClientMid implements contracts.ClientMIDI over Bluetooth Low Energy: it lists the
+// peripherals advertising the MIDI service, subscribes to the notifications of their MIDI
+// characteristic and writes to it without response. It uses the default adapter of the
+// system and its connection handler, so only one client should use the backend at a time.
+type ClientMid struct {
+	logger     contracts.Logger
+	adapter    *bluetooth.Adapter
+	config     contracts.BLEConfig
+	dispatcher *dispatch.Dispatcher // Filters events and delivers them to the event channel.
+	openRetry  *contracts.OpenRetry // Retry policy for connecting to a device; nil disables retries.
+
+	mu       sync.Mutex       // Mutex for thread safety on shared resources.
+	devices  []bleDevice      // Devices of the last listing.
+	deviceID int              // ID of the selected input device, or -1.
+	input    *link            // Connection of the selected input device.
+	outputID int              // ID of the selected output device, or -1.
+	output   *link            // Connection of the selected output device.
+	source   *dispatch.Source // Dispatcher entry of the input device while capturing.
+	decoder  *packetDecoder   // Decoder of the packets of source.
+
+	notifyMu sync.Mutex // Serializes the notifications and the closing of their source.
+	sendMu   sync.Mutex // Orders the packets written to the output.
+	stopOnce sync.Once  // Ensures Stop() is executed only once.
+}
+
+// NewMIDIClient enables the default Bluetooth adapter of the system.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	config := contracts.BLEConfig{}
+	if options.BLEConfig != nil {
+		config = *options.BLEConfig
+	}
+	if config.ScanTimeout == 0 {
+		config.ScanTimeout = defaultScanTimeout
+	}
+
+	adapter := bluetooth.DefaultAdapter
+	if err := adapter.Enable(); err != nil {
+		return nil, fmt.Errorf("error enabling Bluetooth adapter: %w", err)
+	}
+	m := &ClientMid{
+		logger:     options.Logger,
+		adapter:    adapter,
+		config:     config,
+		dispatcher: dispatch.New(contracts.BackendBLE, options),
+		openRetry:  options.OpenRetry,
+		deviceID:   -1,
+		outputID:   -1,
+	}
+	adapter.SetConnectHandler(m.connectionChanged)
+	options.Logger.Info("BLE MIDI client created")
+	return m, nil
+}
+
+// ListDevices scans for the peripherals advertising the MIDI service during the scan
+// timeout, and lists them by name.
+func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
+	devices, err := m.list()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]contracts.DeviceInfo, len(devices))
+	for i, device := range devices {
+		infos[i] = device.info
+	}
+	return infos, nil
+}
+
+// list scans for MIDI peripherals and remembers them for SelectDevice.
+func (m *ClientMid) list() ([]bleDevice, error) {
+	var (
+		devices []bleDevice
+		seen    = make(map[string]bool)
+	)
+	done := make(chan struct{})
+	go m.stopScanAfter(m.config.ScanTimeout, done)
+	err := m.adapter.Scan(func(adapter *bluetooth.Adapter, result bluetooth.ScanResult) {
+		address := result.Address.String()
+		if seen[address] || !result.HasServiceUUID(midiService) {
+			return
+		}
+		seen[address] = true
+		name := result.LocalName()
+		if name == "" {
+			name = "BLE MIDI " + address
+		}
+		devices = append(devices, bleDevice{
+			info:    contracts.DeviceInfo{Name: name, EntityName: address},
+			address: result.Address,
+		})
+	})
+	close(done)
+	if err != nil {
+		m.logger.Error("Failed to scan for BLE MIDI devices", m.logger.Field().Error("error", err))
+		return nil, fmt.Errorf("error scanning for BLE MIDI devices: %w", err)
+	}
+
+	// Peripherals advertise in no particular order; sorting keeps the IDs of a listing stable.
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].info.Name != devices[j].info.Name {
+			return devices[i].info.Name < devices[j].info.Name
+		}
+		return devices[i].info.EntityName < devices[j].info.EntityName
+	})
+
+	m.mu.Lock()
+	m.devices = devices
+	m.mu.Unlock()
+
+	if len(devices) == 0 {
+		m.logger.Warn("No BLE MIDI devices found")
+		return nil, contracts.ErrNoDevices
+	}
+	return devices, nil
+}
+
+// stopScanAfter stops the scan once timeout has passed, retrying until it has started,
+// unless done is closed first.
+func (m *ClientMid) stopScanAfter(timeout time.Duration, done chan struct{}) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+	for m.adapter.StopScan() != nil {
+		select {
+		case <-done:
+			return
+		case <-time.After(stopScanInterval):
+		}
+	}
+}
+
+// locate returns the device with an ID from the last listing, listing devices if needed.
+func (m *ClientMid) locate(deviceID int) (bleDevice, error) {
+	m.mu.Lock()
+	devices := m.devices
+	m.mu.Unlock()
+
+	if devices == nil {
+		var err error
+		if devices, err = m.list(); err != nil {
+			return bleDevice{}, err
+		}
+	}
+	if deviceID < 0 || deviceID >= len(devices) {
+		return bleDevice{}, fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	return devices[deviceID], nil
+}
+
+// DeviceCapabilities reports what a BLE MIDI peripheral supports through this backend: one
+// input and one output on its MIDI characteristic, SysEx included, with the timestamps of
+// the sender.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	if _, err := m.locate(deviceID); err != nil {
+		return contracts.DeviceCapabilities{}, err
+	}
+	return contracts.DeviceCapabilities{SysExIn: true, SysExOut: true, Timestamps: true, InputPorts: 1, OutputPorts: 1}, nil
+}
+
+// SelectDevice connects to a peripheral, unless it is the selected output device, and
+// subscribes to its MIDI characteristic, retrying according to the open retry policy. The
+// previously selected device is disconnected unless it is the output. If the capture of a
+// lost device is still running, it continues on the new device.
+func (m *ClientMid) SelectDevice(deviceID int) error {
+	device, err := m.locate(deviceID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	if m.source != nil {
+		name := m.input.device.info.Name
+		m.mu.Unlock()
+		return fmt.Errorf("%w from %s", contracts.ErrCaptureRunning, name)
+	}
+	l := m.connected(device)
+	m.mu.Unlock()
+
+	if l == nil {
+		if l, err = m.open(device); err != nil {
+			m.logger.Error("Failed to connect to BLE MIDI device", m.logger.Field().Error("error", err))
+			return fmt.Errorf("error connecting to BLE MIDI device %d: %w", deviceID, err)
+		}
+	}
+
+	m.mu.Lock()
+	previous := m.input
+	m.deviceID, m.input = deviceID, l
+	stale := m.unused(previous)
+	m.logger.Info("BLE MIDI device connected", m.logger.Field().String("device", device.info.Name))
+	m.dispatcher.DeviceSelected(deviceID, device.info)
+	if eventChannel := m.dispatcher.Channel(); eventChannel != nil {
+		m.startCapture(eventChannel)
+	}
+	m.mu.Unlock()
+
+	m.disconnect(stale)
+	return nil
+}
+
+// ListOutputDevices lists the same peripherals as ListDevices.
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	return m.ListDevices()
+}
+
+// SelectOutputDevice connects to a peripheral, unless it is the selected input device, as
+// the destination of Send. The previously selected output is disconnected unless it is
+// the input.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	device, err := m.locate(deviceID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	l := m.connected(device)
+	m.mu.Unlock()
+
+	if l == nil {
+		if l, err = m.open(device); err != nil {
+			m.logger.Error("Failed to connect to BLE MIDI device", m.logger.Field().Error("error", err))
+			return fmt.Errorf("error connecting to BLE MIDI device %d: %w", deviceID, err)
+		}
+	}
+
+	m.mu.Lock()
+	previous := m.output
+	m.outputID, m.output = deviceID, l
+	stale := m.unused(previous)
+	m.mu.Unlock()
+
+	m.disconnect(stale)
+	m.logger.Info("BLE MIDI output device connected", m.logger.Field().String("device", device.info.Name))
+	return nil
+}
+
+// open connects to a peripheral, finds its MIDI characteristic and subscribes to it.
+func (m *ClientMid) open(device bleDevice) (*link, error) {
+	l := &link{device: device}
+	err := retry.Do(m.openRetry, m.logger, "Connect", func() error {
+		peripheral, err := m.adapter.Connect(device.address, bluetooth.ConnectionParams{ConnectionTimeout: bluetooth.NewDuration(connectTimeout)})
+		if err != nil {
+			return err
+		}
+		l.peripheral = peripheral
+		if err := m.subscribe(l); err != nil {
+			_ = peripheral.Disconnect()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// subscribe finds the MIDI characteristic of the connected peripheral of l and enables
+// its notifications.
+func (m *ClientMid) subscribe(l *link) error {
+	services, err := l.peripheral.DiscoverServices([]bluetooth.UUID{midiService})
+	if err != nil {
+		return err
+	}
+	if len(services) == 0 {
+		return fmt.Errorf("%w: %s has no MIDI service", contracts.ErrInvalidDevice, l.device.info.Name)
+	}
+	characteristics, err := services[0].DiscoverCharacteristics([]bluetooth.UUID{midiCharacteristic})
+	if err != nil {
+		return err
+	}
+	if len(characteristics) == 0 {
+		return fmt.Errorf("%w: %s has no MIDI characteristic", contracts.ErrInvalidDevice, l.device.info.Name)
+	}
+	l.characteristic = characteristics[0]
+	return l.characteristic.EnableNotifications(func(packet []byte) { m.notify(l, packet) })
+}
+
+// connected returns the connection of the input or output to device, or nil. The caller
+// must hold m.mu.
+func (m *ClientMid) connected(device bleDevice) *link {
+	for _, l := range []*link{m.input, m.output} {
+		if l != nil && l.device.address.String() == device.address.String() {
+			return l
+		}
+	}
+	return nil
+}
+
+// unused returns l unless the input or output still uses it. The caller must hold m.mu.
+func (m *ClientMid) unused(l *link) *link {
+	if l == m.input || l == m.output {
+		return nil
+	}
+	return l
+}
+
+// disconnect disconnects the peripheral of l, if any. It must not be called with m.mu
+// held, as the adapter calls connectionChanged before it returns.
+func (m *ClientMid) disconnect(l *link) {
+	if l == nil {
+		return
+	}
+	if err := l.peripheral.Disconnect(); err != nil && m.dispatcher.Logging() {
+		m.logger.Warn("Failed to disconnect BLE MIDI device", m.logger.Field().Error("error", err))
+	}
+}
+
+// connectionChanged forgets the selected devices whose peripheral disconnected, and
+// reports the input device lost. The event channel stays attached for the capture to
+// resume on the next selection.
+func (m *ClientMid) connectionChanged(peripheral bluetooth.Device, connected bool) {
+	if connected {
+		return
+	}
+	address := peripheral.Address.String()
+
+	m.mu.Lock()
+	var source *dispatch.Source
+	if m.input != nil && m.input.device.address.String() == address {
+		source, m.source, m.decoder = m.source, nil, nil
+		m.deviceID, m.input = -1, nil
+	}
+	if m.output != nil && m.output.device.address.String() == address {
+		m.outputID, m.output = -1, nil
+	}
+	m.mu.Unlock()
+
+	if source != nil {
+		source.Lost(fmt.Errorf("%w: %s", contracts.ErrDeviceDisconnected, address))
+		m.notifyMu.Lock()
+		source.Close()
+		m.notifyMu.Unlock()
+	}
+}
+
+// Send writes a channel, system common or real-time message to the output device in a
+// packet of its own.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	data, err := midistream.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	output := m.output
+	m.mu.Unlock()
+	if output == nil {
+		return contracts.ErrNoOutputDevice
+	}
+
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+	if _, err := output.characteristic.WriteWithoutResponse(encodePacket(time.Now().UnixMilli(), data)); err != nil {
+		return fmt.Errorf("error sending BLE MIDI message: %w", err)
+	}
+	return nil
+}
+
+// SendSysEx writes a complete SysEx message, including F0 and F7, to the MIDI
+// characteristic of the selected device, in as many packets as the negotiated MTU
+// requires.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	if err := midistream.CheckSysEx(data); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	output := m.output
+	m.mu.Unlock()
+	if output == nil {
+		return contracts.ErrNoOutputDevice
+	}
+
+	size := defaultPacketSize
+	if mtu, err := output.characteristic.GetMTU(); err == nil && int(mtu)-attHeaderSize > size {
+		size = int(mtu) - attHeaderSize
+	}
+
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+	for _, packet := range encodeSysEx(time.Now().UnixMilli(), data, size) {
+		if _, err := output.characteristic.WriteWithoutResponse(packet); err != nil {
+			return fmt.Errorf("error sending BLE MIDI SysEx message: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateVirtualSource reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	return nil, fmt.Errorf("%w: BLE backend", contracts.ErrVirtualUnsupported)
+}
+
+// CreateVirtualDestination reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	return nil, fmt.Errorf("%w: BLE backend", contracts.ErrVirtualUnsupported)
+}
+
+// StartCapture delivers the messages notified by the selected device to eventChannel.
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if m.input == nil {
+		m.logger.Error("No MIDI device selected")
+		return contracts.ErrNoDeviceSelected
+	}
+	if m.source != nil {
+		m.logger.Warn("Capture already started")
+		return contracts.ErrCaptureStarted
+	}
+	m.startCapture(eventChannel)
+	return nil
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
+}
+
+// startCapture starts delivering the messages of the selected device. Timestamps are
+// milliseconds since capture started, unless another clock is selected with
+// contracts.WithTimestampClock; with HardwareClock, the arrival time is moved back by how
+// much earlier the sender timestamped the message than the last one of its packet. The
+// caller must hold m.mu.
+func (m *ClientMid) startCapture(eventChannel chan contracts.MIDI) {
+	m.dispatcher.Attach(eventChannel)
+	source := m.dispatcher.AddSource(m.deviceID, m.input.device.info)
+
+	start := time.Now()
+	var decoder *packetDecoder
+	timestamp := func() uint64 {
+		native := uint64(time.Since(start).Milliseconds())
+		return m.dispatcher.DriverTimestamp(contracts.MonotonicNow()-uint64(decoder.age()), native)
+	}
+	decoder = newPacketDecoder(midistream.Parser{
+		OnMessage: func(event contracts.MIDI) {
+			event.Timestamp = timestamp()
+			source.Dispatch(event)
+		},
+		OnSysEx: func(data []byte) {
+			source.DispatchSysEx(contracts.SysExEvent{Timestamp: timestamp(), Data: data})
+		},
+		OnError:  source.Malformed,
+		Strict:   m.dispatcher.Strict(),
+		MaxSysEx: m.dispatcher.MaxSysEx(),
+	})
+
+	m.source, m.decoder = source, decoder
+	m.logger.Info("BLE MIDI capture started", m.logger.Field().String("device", m.input.device.info.Name))
+}
+
+// notify decodes a packet notified by the peripheral of l, if it is the input device
+// being captured. The adapter calls it from the goroutine receiving its events.
+func (m *ClientMid) notify(l *link, packet []byte) {
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+
+	m.mu.Lock()
+	source, decoder := m.source, m.decoder
+	capturing := source != nil && l == m.input
+	m.mu.Unlock()
+	if !capturing {
+		return
+	}
+	defer source.Recover()
+	decoder.decode(packet)
+}
+
+// SetMIDIEventFilter replaces the event filter; it takes effect for the next received message.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
+}
+
+// Health reports whether the selected device is still connected, together with the
+// dispatcher counters.
+func (m *ClientMid) Health() contracts.Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := m.dispatcher.Health()
+	health.DeviceID = m.deviceID
+	if m.input != nil {
+		health.Connected = true
+		health.Device = m.input.device.info
+		health.Diagnostics = map[string]string{"address": m.input.device.address.String()}
+	}
+	return health
+}
+
+// Stats reports the event traffic statistics of the selected device.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// Stop ends capture and disconnects the selected devices.
+func (m *ClientMid) Stop() error {
+	m.stopOnce.Do(func() {
+		m.logger.Info("Stopping BLE MIDI client")
+		m.mu.Lock()
+		source, input, output := m.source, m.input, m.output
+		m.source, m.decoder, m.input, m.output = nil, nil, nil, nil
+		m.deviceID, m.outputID = -1, -1
+		m.mu.Unlock()
+
+		m.dispatcher.Detach()
+		if source != nil {
+			m.notifyMu.Lock()
+			source.Close()
+			m.notifyMu.Unlock()
+		}
+		m.disconnect(input)
+		if output != input {
+			m.disconnect(output)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !ble || nomidihw
+// +build !ble nomidihw
+
+package midible
+
+import "github.com/leandrodaf/midi/sdk/contracts"
+
+// NewMIDIClient reports ErrBLEUnsupported; the BLE backend pulls in a Bluetooth stack only
+// in builds with the ble tag, and builds with the nomidihw tag leave it out.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	return nil, ErrBLEUnsupported
+}
@@ -0,0 +1,6 @@
+package midible
+
+import "errors"
+
+// ErrBLEUnsupported is returned when the package was built without Bluetooth support.
+var ErrBLEUnsupported = errors.New("BLE MIDI backend requires building with the ble tag (go build -tags ble), and cgo on macOS")
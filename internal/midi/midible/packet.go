@@ -0,0 +1,107 @@
+package midible
+
+import (
+	"time"
+
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+)
+
+// UUIDs of the MIDI service and its data characteristic (MIDI over Bluetooth Low Energy).
+const (
+	serviceUUID        = "03B80E5A-EDE8-4B33-A751-6CE34EC4C700"
+	characteristicUUID = "7772E5DB-3868-4112-A1A9-F2669D106BF3"
+	timestampMask      = 1<<13 - 1          // Timestamps are milliseconds on a 13-bit clock of the sender.
+	attHeaderSize      = 3                  // Bytes of a write taken by the ATT protocol out of the MTU.
+	defaultPacketSize  = 23 - attHeaderSize // Longest packet with the default MTU of 23 bytes.
+)
+
+// packetDecoder turns BLE MIDI packets into messages. A packet starts with a header byte
+// carrying the 6 high bits of the timestamp, and every message, or a SysEx continuation,
+// is preceded by a timestamp byte carrying its 7 low bits. Within the packet, a byte with
+// its high bit set is a timestamp unless it follows one, in which case it is a status
+// byte. SysEx messages span packets.
+type packetDecoder struct {
+	parser    midistream.Parser
+	timestamp uint16  // Timestamp of the byte being parsed.
+	latest    uint16  // Timestamp of the last message of the packet being parsed.
+	one       [1]byte // Buffer feeding the parser a byte at a time.
+}
+
+// newPacketDecoder creates a decoder whose messages and errors go to the handlers of parser.
+func newPacketDecoder(parser midistream.Parser) *packetDecoder {
+	return &packetDecoder{parser: parser}
+}
+
+// decode parses a packet received in a notification of the MIDI characteristic.
+func (d *packetDecoder) decode(packet []byte) {
+	if len(packet) < 2 || packet[0]&0xC0 != 0x80 {
+		if len(packet) > 0 && d.parser.OnError != nil {
+			d.parser.OnError(packet, "BLE MIDI packet without header")
+		}
+		return
+	}
+	d.latest = d.walk(packet, false)
+	d.walk(packet, true)
+	d.parser.EndPacket()
+}
+
+// walk goes through the bytes of packet, feeding the MIDI bytes to the parser if feed is
+// set, and returns the timestamp of the last one. The low part of the timestamps wrapping
+// around within the packet carries into the high part of the header.
+func (d *packetDecoder) walk(packet []byte, feed bool) uint16 {
+	high := uint16(packet[0]&0x3F) << 7
+	timestamp, low, seen, afterTimestamp := high, uint16(0), false, false
+	for _, b := range packet[1:] {
+		if b&0x80 != 0 && !afterTimestamp {
+			next := uint16(b & 0x7F)
+			if seen && next < low {
+				high += 1 << 7
+			}
+			low, seen = next, true
+			timestamp = (high | low) & timestampMask
+			afterTimestamp = true
+			continue
+		}
+		afterTimestamp = false
+		if feed {
+			d.timestamp = timestamp
+			d.one[0] = b
+			d.parser.Write(d.one[:])
+		}
+	}
+	return timestamp
+}
+
+// age returns how long before the last message of the packet being decoded the sender
+// sent the current one.
+func (d *packetDecoder) age() time.Duration {
+	return time.Duration((d.latest-d.timestamp)&timestampMask) * time.Millisecond
+}
+
+// encodePacket frames message in a packet of its own, sent at timestamp milliseconds.
+func encodePacket(timestamp int64, message []byte) []byte {
+	packet := make([]byte, 0, 2+len(message))
+	packet = append(packet, 0x80|byte(timestamp>>7&0x3F), 0x80|byte(timestamp&0x7F))
+	return append(packet, message...)
+}
+
+// encodeSysEx frames a complete SysEx message in packets of at most size bytes: the first
+// one carries the header, a timestamp and the start of the message, the next ones a
+// header followed by the continuation, and 0xF7 is preceded by a timestamp of its own.
+func encodeSysEx(timestamp int64, message []byte, size int) [][]byte {
+	header, stamp := 0x80|byte(timestamp>>7&0x3F), 0x80|byte(timestamp&0x7F)
+	var packets [][]byte
+	packet := []byte{header, stamp}
+	for _, b := range message[:len(message)-1] {
+		if len(packet) == size {
+			packets = append(packets, packet)
+			packet = []byte{header}
+		}
+		packet = append(packet, b)
+	}
+	if len(packet)+2 > size {
+		packets = append(packets, packet)
+		packet = []byte{header}
+	}
+	return append(packets, append(packet, stamp, 0xF7))
+}
@@ -1,74 +1,103 @@
-//go:build darwin
-// +build darwin
+//go:build darwin && cgo && !nomidihw
+// +build darwin,cgo,!nomidihw
 
 package mididarwin
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"runtime/pprof"
+	"strconv"
 	"sync"
-	"sync/atomic"
 	"time"
 
+	"github.com/leandrodaf/midi/internal/coremidi"
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/internal/midi/retry"
+	"github.com/leandrodaf/midi/internal/profiling"
 	"github.com/leandrodaf/midi/sdk/contracts"
-	"github.com/youpy/go-coremidi"
+	"github.com/leandrodaf/midi/sdk/ump"
 )
 
 // Error definitions for MIDI connection and handling issues.
+// ErrNoMIDIDevices and ErrInvalidMIDIDevice are the contracts errors shared by all backends.
 var (
-	ErrNoMIDIDevices        = errors.New("no MIDI devices found")
-	ErrInvalidMIDIDevice    = errors.New("invalid MIDI device")
+	ErrNoMIDIDevices        = contracts.ErrNoDevices
+	ErrInvalidMIDIDevice    = contracts.ErrInvalidDevice
 	ErrMIDIConnectionError  = errors.New("error connecting to MIDI device")
 	ErrCreateInputPort      = errors.New("error creating input port")
-	ErrIncompleteMIDIPacket = errors.New("incomplete MIDI packet")
+	ErrCreateOutputPort     = errors.New("error creating output port")
+	ErrIncompleteMIDIPacket = contracts.ErrMalformedMessage
 )
 
-// internalPortConnection is an interface for handling disconnection from a MIDI port.
-type internalPortConnection interface {
-	Disconnect()
-}
+// backendName identifies this backend in health reports and profiles.
+const backendName = "coremidi"
 
 // ClientMid manages MIDI operations on Darwin (macOS) systems.
 // This struct handles connections to MIDI devices, manages event capturing,
 // and ensures safe concurrency handling.
 type ClientMid struct {
-	logger          contracts.Logger
-	eventChannel    atomic.Value               // Atomic storage for the event channel to ensure thread safety.
-	client          coremidi.Client            // CoreMIDI client instance for MIDI operations.
-	inputPort       coremidi.InputPort         // Input port for receiving MIDI events.
-	portConn        internalPortConnection     // Connection to the MIDI port.
-	midiEventFilter *contracts.MIDIEventFilter // Filter for specific MIDI events.
-	coreMIDIConfig  *contracts.CoreMIDIConfig  // Configuration for MIDI client.
-	mu              sync.Mutex                 // Mutex for thread safety on shared resources.
-	capturing       bool                       // Indicates if event capturing is currently active.
-	wg              sync.WaitGroup             // WaitGroup for managing concurrent MIDI event processing.
-	stopOnce        sync.Once                  // Ensures Stop() is executed only once.
+	logger         contracts.Logger
+	dispatcher     *dispatch.Dispatcher      // Filters events and delivers them to the event channel.
+	client         *coremidi.Client          // CoreMIDI client instance for MIDI operations.
+	inputPort      *coremidi.InputPort       // Input port for receiving MIDI events; created on the first connection.
+	endpoint       coremidi.Endpoint         // Source connected to the input port.
+	connected      bool                      // Whether endpoint is connected.
+	source         *dispatch.Source          // Dispatcher entry of the connected source; nil when disconnected.
+	parser         *midistream.Parser        // Assembles messages from the packets of the connected source.
+	events         *ump.Parser               // Assembles messages from the MIDI 2.0 packets of the connected source.
+	parserSource   *dispatch.Source          // Source fed by parser and events.
+	labels         context.Context           // Profiler labels applied to the CoreMIDI thread delivering packets.
+	parserMu       sync.Mutex                // Protects parser, events, parserSource, labels and timestamp.
+	timestamp      uint64                    // Timestamp of the packet being parsed.
+	hostOffset     int64                     // Host time in nanoseconds minus contracts.MonotonicNow.
+	deviceID       int                       // ID of the selected device, or -1 when none is selected.
+	device         contracts.DeviceInfo      // Information about the selected device.
+	coreMIDIConfig *contracts.CoreMIDIConfig // Configuration for MIDI client.
+	openRetry      *contracts.OpenRetry      // Retry policy for connecting to a device; nil disables retries.
+	mu             sync.Mutex                // Mutex for thread safety on shared resources.
+	capturing      bool                      // Indicates if event capturing is currently active.
+	wg             sync.WaitGroup            // WaitGroup for managing concurrent MIDI event processing.
+	stopOnce       sync.Once                 // Ensures Stop() is executed only once.
+	outputPort     *coremidi.OutputPort      // Output port for sending MIDI events; created on the first output selection.
+	destination    coremidi.Endpoint         // Destination Send writes to.
+	outputID       int                       // ID of the selected output device, or -1 when none is selected.
+	outputMu       sync.Mutex                // Protects outputPort, destination and outputID.
+	notifyMu       sync.Mutex                // Protects notifiers and lastNotifier.
+	notifiers      map[int]func()            // Functions registered with NotifyDeviceChanges, by registration.
+	lastNotifier   int                       // Key of the last registered notifier.
+	virtualMu      sync.Mutex                // Protects virtuals.
+	virtuals       map[io.Closer]struct{}    // Virtual endpoints closed by Stop.
 }
 
 // NewMIDIClient initializes a new ClientMid for handling MIDI events on macOS.
 // Applies logging and configurations based on the provided options.
 func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
-	client, err := coremidi.NewClient(options.CoreMIDIConfig.ClientName)
+	m := &ClientMid{
+		logger:         options.Logger,
+		dispatcher:     dispatch.New(backendName, options),
+		deviceID:       -1,
+		outputID:       -1,
+		coreMIDIConfig: options.CoreMIDIConfig,
+		openRetry:      options.OpenRetry,
+	}
+	client, err := coremidi.NewClient(options.CoreMIDIConfig.ClientName, m.setupChanged)
 	if err != nil {
-		return nil, err
+		return nil, coreMIDIError(-1, err)
 	}
+	m.client = client
+	m.hostOffset = int64(coremidi.HostTimeToNanos(coremidi.HostTime())) - int64(contracts.MonotonicNow())
 	options.Logger.Info("MIDI client successfully created")
-
-	return &ClientMid{
-		logger:          options.Logger,
-		client:          client,
-		midiEventFilter: options.MIDIEventFilter,
-		coreMIDIConfig:  options.CoreMIDIConfig,
-	}, nil
+	return m, nil
 }
 
 // ListDevices retrieves and returns available MIDI devices.
 // If no devices are found, an error is logged and returned.
 func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
-	sources, err := coremidi.AllSources()
-	if err != nil {
-		return nil, fmt.Errorf("error listing MIDI sources: %w", err)
-	}
+	sources := coremidi.Sources()
 	if len(sources) == 0 {
 		m.logger.Warn(ErrNoMIDIDevices.Error())
 		return nil, ErrNoMIDIDevices
@@ -76,146 +105,360 @@ func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
 
 	devices := make([]contracts.DeviceInfo, len(sources))
 	for i, source := range sources {
-		sourceEntity := source.Entity()
-		devices[i] = contracts.DeviceInfo{
-			Name:         source.Name(),
-			EntityName:   sourceEntity.Name(),
-			Manufacturer: sourceEntity.Manufacturer(),
-		}
+		devices[i] = endpointInfo(source, contracts.DirectionInput)
 	}
 	return devices, nil
 }
 
+// coreMIDIError converts a failed CoreMIDI call about the device with the given ID, or -1,
+// into a *contracts.Error carrying its OSStatus. Calls failing because the endpoint no
+// longer exists are classified as contracts.ErrDeviceNotFound, other ones as
+// contracts.ErrDriverFailure. Errors not coming from CoreMIDI are returned as they are.
+func coreMIDIError(deviceID int, err error) error {
+	var coreErr *coremidi.Error
+	if !errors.As(err, &coreErr) {
+		return err
+	}
+	kind := contracts.ErrDriverFailure
+	if coreErr.NotFound() {
+		kind = contracts.ErrDeviceNotFound
+	}
+	return &contracts.Error{
+		Backend:  backendName,
+		Op:       coreErr.Op,
+		DeviceID: deviceID,
+		Code:     int64(coreErr.Status),
+		Kind:     kind,
+		Err:      err,
+	}
+}
+
+// endpointInfo describes a source or destination, as given by direction. Its port count
+// is the number of endpoints of the same direction in its entity.
+func endpointInfo(endpoint coremidi.Endpoint, direction contracts.DeviceDirection) contracts.DeviceInfo {
+	entity := endpoint.Entity()
+	device := contracts.DeviceInfo{
+		Name:         endpoint.Name(),
+		EntityName:   entity.Name(),
+		Manufacturer: entity.Manufacturer(),
+		Direction:    direction,
+		Offline:      endpoint.Offline(),
+	}
+	if id, ok := endpoint.UniqueID(); ok {
+		device.UniqueID = strconv.FormatInt(int64(id), 10)
+	}
+	if version, ok := entity.Device().DriverVersion(); ok {
+		device.DriverVersion = strconv.FormatInt(int64(version), 10)
+	}
+	if direction == contracts.DirectionInput {
+		device.Ports = len(entity.Sources())
+	} else {
+		device.Ports = len(entity.Destinations())
+	}
+	return device
+}
+
+// DeviceCapabilities reports what a CoreMIDI source supports. Port counts are those of the
+// entity the source belongs to, and SysEx can be sent to entities with a destination.
+// SysEx messages spanning several packets are reassembled.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	sources := coremidi.Sources()
+	if deviceID < 0 || deviceID >= len(sources) {
+		return contracts.DeviceCapabilities{}, ErrInvalidMIDIDevice
+	}
+
+	entity := sources[deviceID].Entity()
+	capabilities := contracts.DeviceCapabilities{SysExIn: true, InputPorts: 1}
+	if entitySources := entity.Sources(); len(entitySources) > 0 {
+		capabilities.InputPorts = len(entitySources)
+	}
+	capabilities.OutputPorts = len(entity.Destinations())
+	capabilities.SysExOut = capabilities.OutputPorts > 0
+	capabilities.MIDI2 = sources[deviceID].Protocol() == coremidi.ProtocolMIDI2
+	return capabilities, nil
+}
+
 // SelectDevice selects a MIDI device by ID and connects to it.
-// If a device is already connected, it disconnects first.
+// If a device is already connected, it disconnects first. Failed connections are
+// retried according to the configured open retry policy.
 func (m *ClientMid) SelectDevice(deviceID int) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	sources, err := coremidi.AllSources()
-	if err != nil {
-		return fmt.Errorf("error retrieving MIDI sources: %w", err)
+	m.disconnect()
+
+	if err := retry.Do(m.openRetry, m.logger, "connect", func() error { return m.connect(deviceID) }); err != nil {
+		return err
 	}
+
+	m.logger.Info("MIDI device successfully connected")
+	m.dispatcher.DeviceSelected(deviceID, m.device)
+	return nil
+}
+
+// connect connects the input port to the source with the given ID. The caller must hold m.mu.
+func (m *ClientMid) connect(deviceID int) error {
+	sources := coremidi.Sources()
 	if deviceID < 0 || deviceID >= len(sources) {
 		m.logger.Error(ErrInvalidMIDIDevice.Error())
 		return ErrInvalidMIDIDevice
 	}
 
-	if m.portConn != nil {
-		m.portConn.Disconnect()
-		m.portConn = nil
-	}
-
 	source := sources[deviceID]
 	m.logger.Info("MIDI device selected",
 		m.logger.Field().Int("deviceID", deviceID),
 		m.logger.Field().String("deviceName", source.Name()))
 
-	m.inputPort, err = coremidi.NewInputPort(m.client, "Input Port", m.handleMIDIMessage)
-	if err != nil {
-		m.logger.Error(ErrCreateInputPort.Error())
-		return fmt.Errorf("%w: %v", ErrCreateInputPort, err)
+	if m.inputPort == nil {
+		inputPort, err := m.newInputPort()
+		if err != nil {
+			m.logger.Error(ErrCreateInputPort.Error())
+			return fmt.Errorf("%w: %w", ErrCreateInputPort, coreMIDIError(-1, err))
+		}
+		m.inputPort = inputPort
 	}
 
-	m.portConn, err = m.inputPort.Connect(source)
-	if err != nil {
+	device := endpointInfo(source, contracts.DirectionInput)
+	m.source = m.dispatcher.AddSource(deviceID, device)
+	m.setParser(m.source, source.Name())
+
+	if err := m.inputPort.Connect(source); err != nil {
+		m.setParser(nil, "")
+		m.source.Close()
+		m.source = nil
 		m.logger.Error(ErrMIDIConnectionError.Error())
-		return fmt.Errorf("%w: %v", ErrMIDIConnectionError, err)
+		return fmt.Errorf("%w: %w: %w", ErrMIDIConnectionError, contracts.ErrDeviceDisconnected, coreMIDIError(deviceID, err))
 	}
 
-	m.logger.Info("MIDI device successfully connected")
+	m.endpoint = source
+	m.connected = true
+	m.deviceID = deviceID
+	m.device = device
 	return nil
 }
 
-// handleMIDIMessage processes incoming MIDI messages and applies filtering.
-// If an event channel is valid and the message meets filter criteria, it is sent to the channel.
+// newInputPort creates the input port. It receives the MIDI 2.0 protocol when MIDI 2.0
+// events are delivered and CoreMIDI supports it, and MIDI 1.0 otherwise, whose messages
+// the dispatcher converts.
+func (m *ClientMid) newInputPort() (*coremidi.InputPort, error) {
+	if m.dispatcher.MIDI2() {
+		inputPort, err := m.client.NewInputPortMIDI2("Input Port", m.handleEventPacket)
+		if !errors.Is(err, coremidi.ErrMIDI2Unsupported) {
+			return inputPort, err
+		}
+		m.logger.Warn("MIDI 2.0 input requires macOS 11; converting MIDI 1.0 messages")
+	}
+	return m.client.NewInputPort("Input Port", m.handleMIDIMessage)
+}
+
+// disconnect disconnects the selected source, if any. The caller must hold m.mu.
+func (m *ClientMid) disconnect() {
+	if m.connected {
+		if err := m.inputPort.Disconnect(m.endpoint); err != nil {
+			m.logger.Warn("Failed to disconnect MIDI source", m.logger.Field().Error("error", err))
+		}
+		m.connected = false
+		m.deviceID = -1
+	}
+	if m.source != nil {
+		m.setParser(nil, "")
+		m.source.Close()
+		m.source = nil
+	}
+}
+
+// handleMIDIMessage parses incoming packets and hands the messages to the dispatcher,
+// which applies filtering and sends them to the event channel. SysEx messages are
+// delivered separately and may span packets.
 // Adds to WaitGroup to ensure safe concurrent processing.
-func (m *ClientMid) handleMIDIMessage(source coremidi.Source, packet coremidi.Packet) {
+func (m *ClientMid) handleMIDIMessage(source coremidi.Endpoint, packet coremidi.Packet) {
 	m.wg.Add(1)
 	defer m.wg.Done()
 
-	eventChannel, _ := m.eventChannel.Load().(chan contracts.MIDI)
-	if eventChannel == nil {
-		m.logger.Warn("eventChannel not initialized or of invalid type")
+	m.parserMu.Lock()
+	defer m.parserMu.Unlock()
+	if m.parser == nil {
 		return
 	}
+	defer m.parserSource.Recover()
+	pprof.SetGoroutineLabels(m.labels)
+	m.timestamp = m.packetTimestamp(packet.Timestamp)
+	m.parser.Write(packet.Data)
+	m.parser.EndPacket()
+}
 
-	if len(packet.Data) >= 3 {
-		event := contracts.MIDI{
-			Timestamp: uint64(time.Now().UTC().UnixNano()),
-			Command:   packet.Data[0],
-			Note:      packet.Data[1],
-			Velocity:  packet.Data[2],
-		}
+// handleEventPacket is handleMIDIMessage for the Universal MIDI Packets of a MIDI 2.0
+// input port. MIDI 2.0 channel voice messages reach the dispatcher at full resolution.
+func (m *ClientMid) handleEventPacket(source coremidi.Endpoint, packet coremidi.EventPacket) {
+	m.wg.Add(1)
+	defer m.wg.Done()
 
-		if m.midiEventFilter != nil && !isCommandAllowed(event.Command, m.midiEventFilter.Commands) {
-			return
-		}
-		select {
-		case eventChannel <- event:
-		default:
-			m.logger.Warn("Event buffer full; dropping MIDI event")
-		}
-	} else {
-		m.logger.Warn(ErrIncompleteMIDIPacket.Error())
+	m.parserMu.Lock()
+	defer m.parserMu.Unlock()
+	if m.events == nil {
+		return
 	}
+	defer m.parserSource.Recover()
+	pprof.SetGoroutineLabels(m.labels)
+	m.timestamp = m.packetTimestamp(packet.Timestamp)
+	m.events.Timestamp = m.timestamp
+	m.events.Write(packet.Words)
 }
 
-// isCommandAllowed verifies if a MIDI command is allowed based on the event filter configuration.
-func isCommandAllowed(command byte, allowedCommands []contracts.MIDICommand) bool {
-	for _, allowedCommand := range allowedCommands {
-		if command == byte(allowedCommand) {
-			return true
-		}
+// packetTimestamp returns the timestamp of the messages of a packet with the given host
+// time on the clock of the options. With contracts.HardwareClock, it is the host time
+// converted to the monotonic clock, or the time of arrival for packets without timestamp.
+func (m *ClientMid) packetTimestamp(hostTime uint64) uint64 {
+	hardware := contracts.MonotonicNow()
+	if hostTime != 0 {
+		hardware = uint64(max(int64(coremidi.HostTimeToNanos(hostTime))-m.hostOffset, 0))
 	}
-	return false
+	return m.dispatcher.DriverTimestamp(hardware, uint64(time.Now().UTC().UnixNano()))
 }
 
-// StartCapture begins capturing MIDI events by storing the event channel and marking capturing as active.
-// Ensures any ongoing capture is stopped before starting a new one.
-func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) {
+// newParser returns a parser delivering the messages of a source to the dispatcher. The
+// parser copies SysEx data, which CoreMIDI owns, before delivery.
+func (m *ClientMid) newParser(source *dispatch.Source) *midistream.Parser {
+	return &midistream.Parser{
+		OnMessage: func(event contracts.MIDI) {
+			event.Timestamp = m.timestamp
+			source.Dispatch(event)
+		},
+		OnSysEx: func(data []byte) {
+			source.DispatchSysEx(contracts.SysExEvent{Timestamp: m.timestamp, Data: data})
+		},
+		OnError:  source.Malformed,
+		Strict:   m.dispatcher.Strict(),
+		MaxSysEx: m.dispatcher.MaxSysEx(),
+	}
+}
+
+// newEventParser returns a parser delivering the messages of the Universal MIDI Packets
+// of a source to the dispatcher.
+func (m *ClientMid) newEventParser(source *dispatch.Source) *ump.Parser {
+	return &ump.Parser{
+		OnMIDI2: source.DispatchMIDI2,
+		OnMIDI1: func(_ byte, event contracts.MIDI) { source.Dispatch(event) },
+		OnSysEx: func(_ byte, data []byte) {
+			source.DispatchSysEx(contracts.SysExEvent{Timestamp: m.timestamp, Data: data})
+		},
+		OnError: func(words []uint32, reason string) {
+			source.Malformed(ump.AppendBytes(nil, words), reason)
+		},
+		MaxSysEx: m.dispatcher.MaxSysEx(),
+	}
+}
+
+// setParser replaces the parsers used by handleMIDIMessage and handleEventPacket with ones
+// delivering to source, the device named name; a nil source discards incoming packets.
+func (m *ClientMid) setParser(source *dispatch.Source, name string) {
+	var parser *midistream.Parser
+	var events *ump.Parser
+	labels := context.Background()
+	if source != nil {
+		parser, events = m.newParser(source), m.newEventParser(source)
+		labels = profiling.Context(profiling.RoleCapture, backendName, name)
+	}
+	m.parserMu.Lock()
+	m.parser = parser
+	m.events = events
+	m.parserSource = source
+	m.labels = labels
+	m.parserMu.Unlock()
+}
+
+// Stats reports the event traffic statistics of the selected device.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// SetMIDIEventFilter replaces the event filter; it takes effect for the next received message.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
+}
+
+// Health reports the connection state of the selected source together with the dispatcher counters.
+func (m *ClientMid) Health() contracts.Health {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if eventChannel == nil {
-		m.logger.Error("StartCapture called with nil eventChannel")
-		return
+	health := m.dispatcher.Health()
+	health.Connected = m.connected
+	health.DeviceID = m.deviceID
+	health.Device = m.device
+	health.Diagnostics = map[string]string{
+		"client_name": m.coreMIDIConfig.ClientName,
 	}
+	return health
+}
+
+// StartCapture begins capturing MIDI events by storing the event channel and marking capturing as active.
+// It fails when no device is selected or capture already started.
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if m.source == nil {
+		m.logger.Error("No MIDI device selected")
+		return contracts.ErrNoDeviceSelected
+	}
 	if m.capturing {
-		m.logger.Warn("Capture already started; attempting to stop existing capture")
-		if err := m.Stop(); err != nil {
-			m.logger.Error("Failed to stop existing capture", m.logger.Field().Error("error", err))
-		}
+		m.logger.Warn("Capture already started")
+		return contracts.ErrCaptureStarted
 	}
 
 	m.logger.Info("Starting MIDI event capture")
-	m.eventChannel.Store(eventChannel)
+	m.dispatcher.Attach(eventChannel)
 	m.capturing = true
+	return nil
 }
 
-// Stop halts MIDI event capturing, disconnects from the device, and waits for ongoing processing to complete.
-// This function ensures it only executes once, even if called multiple times.
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
+}
+
+// Stop halts MIDI event capturing, disconnects from the selected device, waits for ongoing
+// processing to complete and disposes of the CoreMIDI ports and client, whether or not
+// capture was started. This function ensures it only executes once, even if called multiple times.
 func (m *ClientMid) Stop() error {
 	m.stopOnce.Do(func() {
 		m.logger.Info("Stopping MIDI capture")
+		m.closeOutput()
+		m.closeVirtuals()
+
 		m.mu.Lock()
 		defer m.mu.Unlock()
 
+		m.disconnect()
 		if m.capturing {
 			m.capturing = false
 
-			if m.portConn != nil {
-				m.portConn.Disconnect()
-				m.portConn = nil
-			}
-
-			// Store a closed dummy channel to prevent further writes and avoid any panic.
-			dummyChannel := make(chan contracts.MIDI)
-			m.eventChannel.Store(dummyChannel)
-
+			// Detach the event channel to prevent further writes and avoid any panic.
+			m.dispatcher.Detach()
 			m.logger.Info("MIDI capture stopped")
-			m.wg.Wait() // Wait for all ongoing MIDI event processing to complete
+		}
+		m.wg.Wait() // Wait for all ongoing MIDI event processing to complete
+
+		if m.inputPort != nil {
+			if err := m.inputPort.Dispose(); err != nil {
+				m.logger.Warn("Failed to dispose of MIDI input port", m.logger.Field().Error("error", err))
+			}
+			m.inputPort = nil
+		}
+		if err := m.client.Dispose(); err != nil {
+			m.logger.Warn("Failed to dispose of MIDI client", m.logger.Field().Error("error", err))
 		}
 	})
 	return nil
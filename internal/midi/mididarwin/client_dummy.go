@@ -4,6 +4,7 @@
 package mididarwin
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/leandrodaf/midi/sdk/contracts"
@@ -22,16 +23,76 @@ func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, erro
 
 func (m *DummyMIDIClient) ListDevices() ([]contracts.DeviceInfo, error) {
 	m.logger.Warn("ListDevices called on dummy MIDI client")
-	return nil, fmt.Errorf("MIDI functionality is not available on this platform")
+	return nil, fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoDevices)
 }
 
 func (m *DummyMIDIClient) SelectDevice(deviceID int) error {
 	m.logger.Warn("SelectDevice called on dummy MIDI client")
-	return fmt.Errorf("MIDI functionality is not available on this platform")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrInvalidDevice)
 }
 
-func (m *DummyMIDIClient) StartCapture(eventChannel chan contracts.MIDI) {
+func (m *DummyMIDIClient) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	m.logger.Warn("DeviceCapabilities called on dummy MIDI client")
+	return contracts.DeviceCapabilities{}, fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrInvalidDevice)
+}
+
+func (m *DummyMIDIClient) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	m.logger.Warn("ListOutputDevices called on dummy MIDI client")
+	return nil, fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoDevices)
+}
+
+func (m *DummyMIDIClient) SelectOutputDevice(deviceID int) error {
+	m.logger.Warn("SelectOutputDevice called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrInvalidDevice)
+}
+
+func (m *DummyMIDIClient) Send(event contracts.MIDI) error {
+	m.logger.Warn("Send called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoOutputDevice)
+}
+
+func (m *DummyMIDIClient) SendSysEx(data []byte) error {
+	m.logger.Warn("SendSysEx called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoOutputDevice)
+}
+
+func (m *DummyMIDIClient) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	m.logger.Warn("CreateVirtualSource called on dummy MIDI client")
+	return nil, fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrVirtualUnsupported)
+}
+
+func (m *DummyMIDIClient) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	m.logger.Warn("CreateVirtualDestination called on dummy MIDI client")
+	return nil, fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrVirtualUnsupported)
+}
+
+func (m *DummyMIDIClient) StartCapture(eventChannel chan contracts.MIDI) error {
 	m.logger.Warn("StartCapture called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoDeviceSelected)
+}
+
+// StartCaptureFunc logs a warning indicating that StartCaptureFunc was called on the dummy MIDI client.
+func (m *DummyMIDIClient) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	m.logger.Warn("StartCaptureFunc called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoDeviceSelected)
+}
+
+// StartCaptureContext logs a warning indicating that StartCaptureContext was called on the dummy MIDI client.
+func (m *DummyMIDIClient) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	m.logger.Warn("StartCaptureContext called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoDeviceSelected)
+}
+
+func (m *DummyMIDIClient) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.logger.Warn("SetMIDIEventFilter called on dummy MIDI client")
+}
+
+func (m *DummyMIDIClient) Health() contracts.Health {
+	return contracts.Health{Backend: "dummy", DeviceID: -1}
+}
+
+func (m *DummyMIDIClient) Stats() contracts.Stats {
+	return contracts.Stats{}
 }
 
 func (m *DummyMIDIClient) Stop() error {
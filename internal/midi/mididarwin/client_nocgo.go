@@ -0,0 +1,13 @@
+//go:build darwin && (!cgo || nomidihw)
+// +build darwin
+// +build !cgo nomidihw
+
+package mididarwin
+
+import "github.com/leandrodaf/midi/sdk/contracts"
+
+// NewMIDIClient reports ErrCoreMIDIUnsupported; the CoreMIDI backend needs cgo, and builds
+// with the nomidihw tag leave it out.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	return nil, ErrCoreMIDIUnsupported
+}
@@ -0,0 +1,7 @@
+package mididarwin
+
+import "errors"
+
+// ErrCoreMIDIUnsupported is returned by NewMIDIClient in darwin builds without cgo, which
+// cannot call CoreMIDI.
+var ErrCoreMIDIUnsupported = errors.New("CoreMIDI backend requires building with cgo (CGO_ENABLED=1)")
@@ -0,0 +1,66 @@
+//go:build darwin && cgo && !nomidihw
+// +build darwin,cgo,!nomidihw
+
+package mididarwin
+
+import (
+	"fmt"
+
+	"github.com/leandrodaf/midi/internal/coremidi"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// NotifyDeviceChanges calls changed after every change of the MIDI setup reported by
+// CoreMIDI, such as a device being plugged in or removed, until stop is called.
+func (m *ClientMid) NotifyDeviceChanges(changed func()) (stop func()) {
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+
+	if m.notifiers == nil {
+		m.notifiers = make(map[int]func())
+	}
+	m.lastNotifier++
+	key := m.lastNotifier
+	m.notifiers[key] = changed
+	return func() {
+		m.notifyMu.Lock()
+		defer m.notifyMu.Unlock()
+		delete(m.notifiers, key)
+	}
+}
+
+// setupChanged receives the notifications of the CoreMIDI client. SetupChanged follows
+// every batch of additions and removals, so it is the only one passed on.
+func (m *ClientMid) setupChanged(notification coremidi.Notification) {
+	if notification.Message != coremidi.SetupChanged {
+		return
+	}
+	// The check takes m.mu, which must not be awaited on the notification thread.
+	go m.checkConnected()
+
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+	for _, changed := range m.notifiers {
+		changed()
+	}
+}
+
+// checkConnected reports the connected source as lost when it was removed from the setup
+// or went offline, as when its device is unplugged.
+func (m *ClientMid) checkConnected() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.connected || m.source == nil {
+		return
+	}
+	for _, source := range coremidi.Sources() {
+		if source == m.endpoint {
+			if source.Offline() {
+				m.source.Lost(fmt.Errorf("%w: source %q went offline", contracts.ErrDeviceDisconnected, m.device.Name))
+			}
+			return
+		}
+	}
+	m.source.Lost(fmt.Errorf("%w: source %q was removed", contracts.ErrDeviceDisconnected, m.device.Name))
+}
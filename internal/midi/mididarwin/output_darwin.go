@@ -0,0 +1,129 @@
+//go:build darwin && cgo && !nomidihw
+// +build darwin,cgo,!nomidihw
+
+package mididarwin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leandrodaf/midi/internal/coremidi"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// maxPacketSize is the longest packet coremidi.OutputPort.Send accepts.
+const maxPacketSize = 0xFFFF
+
+// ListOutputDevices retrieves and returns the CoreMIDI destinations.
+// If no destinations are found, an error is logged and returned.
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	destinations := coremidi.Destinations()
+	if len(destinations) == 0 {
+		m.logger.Warn(ErrNoMIDIDevices.Error())
+		return nil, ErrNoMIDIDevices
+	}
+
+	devices := make([]contracts.DeviceInfo, len(destinations))
+	for i, destination := range destinations {
+		devices[i] = endpointInfo(destination, contracts.DirectionOutput)
+	}
+	return devices, nil
+}
+
+// SelectOutputDevice selects the destination with the given ID as the target of Send,
+// creating the output port on the first call.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	destinations := coremidi.Destinations()
+	if deviceID < 0 || deviceID >= len(destinations) {
+		m.logger.Error(ErrInvalidMIDIDevice.Error())
+		return ErrInvalidMIDIDevice
+	}
+
+	m.outputMu.Lock()
+	defer m.outputMu.Unlock()
+
+	if m.outputPort == nil {
+		outputPort, err := m.client.NewOutputPort("Output Port")
+		if err != nil {
+			m.logger.Error(ErrCreateOutputPort.Error())
+			return fmt.Errorf("%w: %w", ErrCreateOutputPort, coreMIDIError(-1, err))
+		}
+		m.outputPort = outputPort
+	}
+	m.destination = destinations[deviceID]
+	m.outputID = deviceID
+
+	m.logger.Info("MIDI output device selected",
+		m.logger.Field().Int("deviceID", deviceID),
+		m.logger.Field().String("deviceName", m.destination.Name()))
+	return nil
+}
+
+// Send sends event to the selected destination, to be played immediately. Its timestamp
+// is ignored.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	data, err := midistream.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	m.outputMu.Lock()
+	defer m.outputMu.Unlock()
+
+	if m.outputID < 0 {
+		return contracts.ErrNoOutputDevice
+	}
+	return m.send(data)
+}
+
+// SendSysEx sends a complete SysEx message, including F0 and F7, to the selected
+// destination. Messages longer than a CoreMIDI packet are sent in several packets, which
+// destinations reassemble.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	if err := midistream.CheckSysEx(data); err != nil {
+		return err
+	}
+
+	m.outputMu.Lock()
+	defer m.outputMu.Unlock()
+
+	if m.outputID < 0 {
+		return contracts.ErrNoOutputDevice
+	}
+	for len(data) > 0 {
+		n := min(len(data), maxPacketSize)
+		if err := m.send(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// send sends data to the selected destination in a single packet. The caller must hold
+// m.outputMu.
+func (m *ClientMid) send(data []byte) error {
+	if err := m.outputPort.Send(m.destination, data); err != nil {
+		var coreErr *coremidi.Error
+		if errors.As(err, &coreErr) && coreErr.NotFound() {
+			return fmt.Errorf("%w: %w", contracts.ErrDeviceDisconnected, coreMIDIError(m.outputID, err))
+		}
+		return coreMIDIError(m.outputID, err)
+	}
+	return nil
+}
+
+// closeOutput disposes of the output port and clears the output selection.
+func (m *ClientMid) closeOutput() {
+	m.outputMu.Lock()
+	defer m.outputMu.Unlock()
+
+	if m.outputPort != nil {
+		if err := m.outputPort.Dispose(); err != nil {
+			m.logger.Warn("Failed to dispose of MIDI output port", m.logger.Field().Error("error", err))
+		}
+		m.outputPort = nil
+	}
+	m.outputID = -1
+}
@@ -0,0 +1,160 @@
+//go:build darwin && cgo && !nomidihw
+// +build darwin,cgo,!nomidihw
+
+package mididarwin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/leandrodaf/midi/internal/coremidi"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Errors creating virtual endpoints.
+var (
+	ErrCreateVirtualSource      = errors.New("error creating virtual source")
+	ErrCreateVirtualDestination = errors.New("error creating virtual destination")
+	ErrVirtualEndpointClosed    = errors.New("virtual endpoint closed")
+)
+
+// CreateVirtualSource publishes a CoreMIDI source named name, which other applications
+// list among their inputs. It is removed by its Close method or by Stop.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	source, err := m.client.NewVirtualSource(name)
+	if err != nil {
+		m.logger.Error(ErrCreateVirtualSource.Error(), m.logger.Field().Error("error", err))
+		return nil, fmt.Errorf("%w: %w", ErrCreateVirtualSource, coreMIDIError(-1, err))
+	}
+	v := &virtualSource{source: source}
+	m.addVirtual(v)
+	m.logger.Info("Virtual MIDI source created", m.logger.Field().String("name", name))
+	return v, nil
+}
+
+// CreateVirtualDestination publishes a CoreMIDI destination named name, which other
+// applications list among their outputs. The messages sent to it are timestamped like
+// captured events and delivered to eventChannel, without filtering; they are dropped while the
+// channel is full, and SysEx messages are discarded. It is removed by its Close method or
+// by Stop.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	v := &virtualDestination{client: m, eventChannel: eventChannel}
+	v.parser = &midistream.Parser{
+		OnMessage: func(event contracts.MIDI) {
+			event.Timestamp = v.timestamp
+			select {
+			case v.eventChannel <- event:
+			default:
+			}
+		},
+		MaxSysEx: m.dispatcher.MaxSysEx(),
+	}
+	destination, err := m.client.NewVirtualDestination(name, v.read)
+	if err != nil {
+		m.logger.Error(ErrCreateVirtualDestination.Error(), m.logger.Field().Error("error", err))
+		return nil, fmt.Errorf("%w: %w", ErrCreateVirtualDestination, coreMIDIError(-1, err))
+	}
+	v.destination = destination
+	m.addVirtual(v)
+	m.logger.Info("Virtual MIDI destination created", m.logger.Field().String("name", name))
+	return v, nil
+}
+
+// addVirtual registers a virtual endpoint to be closed by Stop.
+func (m *ClientMid) addVirtual(v io.Closer) {
+	m.virtualMu.Lock()
+	defer m.virtualMu.Unlock()
+	if m.virtuals == nil {
+		m.virtuals = make(map[io.Closer]struct{})
+	}
+	m.virtuals[v] = struct{}{}
+}
+
+// closeVirtuals closes the virtual endpoints that are still open.
+func (m *ClientMid) closeVirtuals() {
+	m.virtualMu.Lock()
+	virtuals := m.virtuals
+	m.virtuals = nil
+	m.virtualMu.Unlock()
+
+	for v := range virtuals {
+		if err := v.Close(); err != nil {
+			m.logger.Warn("Failed to dispose of virtual MIDI endpoint", m.logger.Field().Error("error", err))
+		}
+	}
+}
+
+// virtualSource sends messages to the applications connected to a CoreMIDI virtual source.
+type virtualSource struct {
+	mu     sync.Mutex
+	source *coremidi.VirtualSource
+	closed bool
+}
+
+// Send encodes event and sends it to the applications connected to the source.
+func (v *virtualSource) Send(event contracts.MIDI) error {
+	data, err := midistream.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed {
+		return ErrVirtualEndpointClosed
+	}
+	return v.source.Received(data)
+}
+
+// Close removes the source.
+func (v *virtualSource) Close() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed {
+		return nil
+	}
+	v.closed = true
+	return v.source.Dispose()
+}
+
+// virtualDestination delivers the messages sent to a CoreMIDI virtual destination to an
+// event channel.
+type virtualDestination struct {
+	client       *ClientMid // Client that created the destination, which timestamps its packets.
+	destination  *coremidi.VirtualDestination
+	eventChannel chan contracts.MIDI
+
+	mu        sync.Mutex         // Serializes read with itself and with Close.
+	parser    *midistream.Parser // Assembles messages from the packets sent to the destination.
+	timestamp uint64             // Arrival time of the packet being parsed.
+	closed    bool
+}
+
+// read parses a packet sent to the destination.
+func (v *virtualDestination) read(_ coremidi.Endpoint, packet coremidi.Packet) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed {
+		return
+	}
+	v.timestamp = v.client.packetTimestamp(packet.Timestamp)
+	v.parser.Write(packet.Data)
+	v.parser.EndPacket()
+}
+
+// Close removes the destination. No message is delivered to the event channel once it
+// returns, so the channel can then be closed.
+func (v *virtualDestination) Close() error {
+	v.mu.Lock()
+	closed := v.closed
+	v.closed = true
+	v.mu.Unlock()
+	if closed {
+		return nil
+	}
+	// Disposed without holding v.mu, as CoreMIDI may wait for a read in progress
+	return v.destination.Dispose()
+}
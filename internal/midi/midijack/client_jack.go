@@ -0,0 +1,511 @@
+//go:build linux && cgo && jack && !nomidihw
+// +build linux,cgo,jack,!nomidihw
+
+package midijack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/leandrodaf/midi/internal/jack"
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/internal/midi/retry"
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+const (
+	inputPortName  = "midi_in"  // Port of the client receiving the messages of the selected device.
+	outputPortName = "midi_out" // Port of the client sending the messages of Send.
+	minInputRing   = 64 * 1024  // Smallest ring of the input ports, in bytes.
+	outputRing     = 4 * 1024   // Ring of the output ports, in bytes.
+)
+
+// receiver parses the messages captured by an input port of the client.
+type receiver struct {
+	port      *jack.Port
+	parser    *midistream.Parser
+	capture   bool             // Whether the port is the input of the client, delivering to source.
+	source    *dispatch.Source // Capturing source while the messages are parsed; nil when not capturing.
+	timestamp uint64           // Timestamp of the message being parsed.
+}
+
+// ClientMid implements contracts.ClientMIDI as a client of a JACK server. The client has
+// an input and an output MIDI port other applications can connect to, such as Carla or
+// QjackCtl: the selected device is connected to the input port, and the output port to
+// the selected output device. Messages from the other ports connected to the input port
+// are captured too, as coming from the selected device.
+type ClientMid struct {
+	logger     contracts.Logger
+	config     *contracts.JACKConfig
+	dispatcher *dispatch.Dispatcher // Filters events and delivers them to the event channel.
+	openRetry  *contracts.OpenRetry // Retry policy for connecting a device; nil disables retries.
+	client     *jack.Client
+	input      *jack.Port // Input port of the client.
+	output     *jack.Port // Output port of the client.
+	ringSize   int        // Size of the rings of the input ports, which holds the longest SysEx message.
+
+	mu       sync.Mutex           // Mutex for thread safety on shared resources.
+	deviceID int                  // ID of the selected device, or -1.
+	device   contracts.DeviceInfo // Information about the selected device.
+	port     string               // Full name of the port of the selected device; "" when none is connected.
+	source   *dispatch.Source     // Dispatcher entry of the device while capturing.
+	capture  atomic.Pointer[dispatch.Source]
+
+	outputMu   sync.Mutex // Protects the output selection.
+	outputID   int        // ID of the selected output device, or -1.
+	outputPort string     // Full name of the port of the selected output device.
+	outputLost bool       // Whether the selected output device left the graph.
+
+	receiversMu sync.RWMutex // Held by the reading goroutine while it delivers messages.
+	receivers   []*receiver  // Input port of the client, then the virtual destinations.
+
+	frames   frameClock     // Extends the frame times of captured messages; used by the reading goroutine only.
+	closing  atomic.Bool    // Set by Stop to end the reading goroutine.
+	wg       sync.WaitGroup // WaitGroup for the reading goroutine.
+	stopOnce sync.Once      // Ensures Stop() is executed only once.
+}
+
+// NewMIDIClient joins the JACK server of options.JACKConfig with the ports of the client
+// and starts reading them. It fails with ErrServerUnavailable when no server is running;
+// it does not start one.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	config := options.JACKConfig
+	if config == nil {
+		config = &contracts.JACKConfig{ClientName: "Go MIDI"}
+	}
+	client, err := jack.Open(config.ClientName, config.ServerName)
+	if err != nil {
+		options.Logger.Error("Failed to open JACK client", options.Logger.Field().Error("error", err))
+		return nil, jackError(err)
+	}
+
+	m := &ClientMid{
+		logger:     options.Logger,
+		config:     config,
+		dispatcher: dispatch.New(contracts.BackendJACK, options),
+		openRetry:  options.OpenRetry,
+		client:     client,
+		deviceID:   -1,
+		outputID:   -1,
+	}
+	m.ringSize = max(minInputRing, 2*m.dispatcher.MaxSysEx())
+	if m.input, err = client.RegisterInput(inputPortName, m.ringSize); err == nil {
+		m.output, err = client.RegisterOutput(outputPortName, outputRing)
+	}
+	if err != nil {
+		client.Close()
+		options.Logger.Error("Failed to register JACK ports", options.Logger.Field().Error("error", err))
+		return nil, fmt.Errorf("%w: %w", contracts.ErrDriverFailure, err)
+	}
+
+	r := &receiver{port: m.input, capture: true}
+	r.parser = &midistream.Parser{
+		OnMessage: func(event contracts.MIDI) {
+			if r.source != nil {
+				event.Timestamp = r.timestamp
+				r.source.Dispatch(event)
+			}
+		},
+		OnSysEx: func(data []byte) {
+			if r.source != nil {
+				r.source.DispatchSysEx(contracts.SysExEvent{Timestamp: r.timestamp, Data: data})
+			}
+		},
+		OnError: func(data []byte, reason string) {
+			if r.source != nil {
+				r.source.Malformed(data, reason)
+			}
+		},
+		Strict:   m.dispatcher.Strict(),
+		MaxSysEx: m.dispatcher.MaxSysEx(),
+	}
+	m.receivers = []*receiver{r}
+
+	m.wg.Add(1)
+	profiling.Go(profiling.RoleCapture, contracts.BackendJACK, client.Name(), m.read)
+	options.Logger.Info("JACK MIDI client created",
+		options.Logger.Field().String("client", client.Name()),
+		options.Logger.Field().Int("sampleRate", client.SampleRate()))
+	return m, nil
+}
+
+// jackError maps the errors of the binding to the errors of the backend.
+func jackError(err error) error {
+	var jackErr *jack.Error
+	if errors.As(err, &jackErr) && jackErr.ServerUnavailable() {
+		return fmt.Errorf("%w: %w", ErrServerUnavailable, err)
+	}
+	if errors.Is(err, jack.ErrClosed) {
+		return fmt.Errorf("%w: %w", contracts.ErrDeviceDisconnected, ErrServerShutDown)
+	}
+	return fmt.Errorf("%w: %w", contracts.ErrDriverFailure, err)
+}
+
+// ListDevices lists the MIDI ports of the other clients of the graph that messages can be
+// received from, such as hardware inputs bridged from ALSA and the outputs of applications.
+func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
+	ports, err := m.ports(false)
+	if err != nil {
+		return nil, err
+	}
+	return portInfos(ports, contracts.DirectionInput), nil
+}
+
+// ports returns the MIDI ports of the other clients of the graph that messages are
+// received from or, when output is set, sent to.
+func (m *ClientMid) ports(output bool) ([]string, error) {
+	var ports []string
+	var err error
+	if output {
+		ports, err = m.client.Destinations()
+	} else {
+		ports, err = m.client.Sources()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing JACK ports: %w", jackError(err))
+	}
+
+	own := m.client.Name() + ":"
+	ports = slices.DeleteFunc(ports, func(port string) bool { return strings.HasPrefix(port, own) })
+	if len(ports) == 0 {
+		m.logger.Warn("No JACK MIDI ports found")
+		return nil, contracts.ErrNoDevices
+	}
+	return ports, nil
+}
+
+// portName returns the port of a device ID, listing the ports again.
+func (m *ClientMid) portName(deviceID int, output bool) (string, error) {
+	ports, err := m.ports(output)
+	if err != nil {
+		return "", err
+	}
+	if deviceID < 0 || deviceID >= len(ports) {
+		return "", fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	return ports[deviceID], nil
+}
+
+// portInfos describes ports, by their full name, in the given direction. Their entity is
+// the client owning them.
+func portInfos(ports []string, direction contracts.DeviceDirection) []contracts.DeviceInfo {
+	counts := make(map[string]int)
+	for _, port := range ports {
+		counts[clientOf(port)]++
+	}
+	infos := make([]contracts.DeviceInfo, len(ports))
+	for i, port := range ports {
+		infos[i] = portInfo(port, direction)
+		infos[i].Ports = counts[infos[i].EntityName]
+	}
+	return infos
+}
+
+// portInfo describes a port by its full name.
+func portInfo(port string, direction contracts.DeviceDirection) contracts.DeviceInfo {
+	return contracts.DeviceInfo{Name: port, EntityName: clientOf(port), UniqueID: port, Direction: direction}
+}
+
+// clientOf returns the client part of the full name of a port.
+func clientOf(port string) string {
+	client, _, _ := strings.Cut(port, ":")
+	return client
+}
+
+// DeviceCapabilities reports what a port supports: complete messages, SysEx included,
+// timestamped with the frame they arrived at.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	if _, err := m.portName(deviceID, false); err != nil {
+		return contracts.DeviceCapabilities{}, err
+	}
+	return contracts.DeviceCapabilities{SysExIn: true, Timestamps: true, InputPorts: 1}, nil
+}
+
+// SelectDevice connects the port with the given ID to the input port of the client,
+// retrying according to the open retry policy, and disconnects the previously selected
+// one. Connections made with other tools are kept. If the capture of a lost device is
+// still running, it continues with the new one.
+func (m *ClientMid) SelectDevice(deviceID int) error {
+	port, err := m.portName(deviceID, false)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.source != nil {
+		return fmt.Errorf("%w from %s", contracts.ErrCaptureRunning, m.device.Name)
+	}
+	if m.port != "" {
+		if err := m.client.Disconnect(m.port, m.input.Name()); err != nil {
+			m.logger.Warn("Failed to disconnect JACK port", m.logger.Field().String("port", m.port), m.logger.Field().Error("error", err))
+		}
+		m.port = ""
+	}
+
+	err = retry.Do(m.openRetry, m.logger, "Connect", func() error { return m.connect(port, m.input.Name(), port) })
+	if err != nil {
+		m.logger.Error("Failed to connect JACK port", m.logger.Field().String("port", port), m.logger.Field().Error("error", err))
+		return fmt.Errorf("error connecting JACK port %s: %w", port, err)
+	}
+
+	m.port = port
+	m.deviceID = deviceID
+	m.device = portInfo(port, contracts.DirectionInput)
+	m.logger.Info("JACK MIDI port connected", m.logger.Field().String("port", port))
+	m.dispatcher.DeviceSelected(deviceID, m.device)
+	if eventChannel := m.dispatcher.Channel(); eventChannel != nil {
+		m.startCapture(eventChannel)
+	}
+	return nil
+}
+
+// connect connects the port source to the port destination, one of which is the port of
+// a device, by their full names.
+func (m *ClientMid) connect(source, destination, device string) error {
+	err := m.client.Connect(source, destination)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, jack.ErrClosed):
+		return jackError(err)
+	case !m.client.Exists(device):
+		return fmt.Errorf("%w: %s", contracts.ErrInvalidDevice, device)
+	default:
+		return fmt.Errorf("%w: %w", contracts.ErrDriverFailure, err)
+	}
+}
+
+// StartCapture delivers the messages received from the selected device to eventChannel.
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if m.port == "" {
+		m.logger.Error("No MIDI device selected")
+		return contracts.ErrNoDeviceSelected
+	}
+	if m.source != nil {
+		m.logger.Warn("Capture already started")
+		return contracts.ErrCaptureStarted
+	}
+	m.startCapture(eventChannel)
+	return nil
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
+}
+
+// startCapture starts delivering the messages of the input port. The caller must hold m.mu.
+func (m *ClientMid) startCapture(eventChannel chan contracts.MIDI) {
+	m.dispatcher.Attach(eventChannel)
+	m.source = m.dispatcher.AddSource(m.deviceID, m.device)
+	m.capture.Store(m.source)
+	m.logger.Info("JACK MIDI capture started", m.logger.Field().String("port", m.port))
+}
+
+// read drains the input ports whenever the process callback captured messages, and checks
+// that the selected devices are still in the graph when its ports change, until Stop or
+// until the server shuts the client down.
+func (m *ClientMid) read() {
+	defer m.wg.Done()
+
+	buffer := make([]byte, m.ringSize)
+	changes := m.client.Changes()
+	for {
+		m.client.Wait()
+		if m.closing.Load() {
+			return
+		}
+
+		now, monotonic := m.client.Now(), contracts.MonotonicNow()
+		m.receiversMu.RLock()
+		for _, r := range m.receivers {
+			m.receive(r, buffer, now, monotonic)
+		}
+		m.receiversMu.RUnlock()
+
+		if m.client.ShutDown() {
+			if m.dispatcher.Logging() {
+				m.logger.Error(ErrServerShutDown.Error())
+			}
+			m.lose("", fmt.Errorf("%w: %w", contracts.ErrDeviceDisconnected, ErrServerShutDown))
+			return
+		}
+		if current := m.client.Changes(); current != changes {
+			changes = current
+			m.checkPorts()
+		}
+	}
+}
+
+// receive parses the messages captured by the port of r. now and monotonic are the same
+// instant on the JACK clock and on the clock of contracts.MonotonicNow.
+func (m *ClientMid) receive(r *receiver, buffer []byte, now, monotonic uint64) {
+	if r.capture {
+		r.source = m.capture.Load()
+		if r.source != nil {
+			defer r.source.Recover()
+		}
+	}
+	for {
+		event, ok := r.port.Read(buffer)
+		if !ok {
+			return
+		}
+		r.timestamp = m.timestamp(event, now, monotonic)
+		r.parser.Write(event.Data)
+		r.parser.EndPacket()
+	}
+}
+
+// timestamp returns the timestamp of a captured message on the clock of the options: by
+// default, the time of its frame on the JACK clock in nanoseconds, or the frame itself
+// with JACKConfig.FrameTimestamps. With contracts.HardwareClock, it is the time of the
+// frame on the clock of contracts.MonotonicNow, which is sample-accurate.
+func (m *ClientMid) timestamp(event jack.Event, now, monotonic uint64) uint64 {
+	native := event.Time * 1000
+	if m.config.FrameTimestamps {
+		native = m.frames.extend(event.Frame)
+	}
+	var age uint64
+	if now > event.Time {
+		age = (now - event.Time) * 1000
+	}
+	return m.dispatcher.DriverTimestamp(monotonic-min(age, monotonic), native)
+}
+
+// checkPorts deselects the devices whose ports left the graph, reporting the loss of the
+// capturing one.
+func (m *ClientMid) checkPorts() {
+	m.mu.Lock()
+	port := m.port
+	m.mu.Unlock()
+	if port != "" && !m.client.Exists(port) {
+		if m.dispatcher.Logging() {
+			m.logger.Error("JACK MIDI port disappeared", m.logger.Field().String("port", port))
+		}
+		m.lose(port, fmt.Errorf("%w: %s", contracts.ErrDeviceDisconnected, port))
+	}
+
+	m.outputMu.Lock()
+	defer m.outputMu.Unlock()
+	if m.outputPort != "" && !m.client.Exists(m.outputPort) {
+		m.outputLost = true
+	}
+}
+
+// lose deselects the device connected to port, or any device when port is empty, so it
+// can be selected again once it is back, and reports it lost for the reason err if it was
+// capturing. The event channel stays attached for the capture to resume on the next
+// selection.
+func (m *ClientMid) lose(port string, err error) {
+	m.mu.Lock()
+	if port != "" && m.port != port {
+		m.mu.Unlock()
+		return
+	}
+	source := m.source
+	m.source, m.port = nil, ""
+	m.capture.Store(nil)
+	m.mu.Unlock()
+
+	if source != nil {
+		source.Lost(err)
+		source.Close()
+	}
+}
+
+// SetMIDIEventFilter replaces the event filter; it takes effect for the next received message.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
+}
+
+// Health reports the state of the selected device and of the JACK graph together with the
+// dispatcher counters.
+func (m *ClientMid) Health() contracts.Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := m.dispatcher.Health()
+	health.Connected = m.port != "" && !m.client.ShutDown()
+	health.DeviceID = m.deviceID
+	health.Device = m.device
+	health.Diagnostics = map[string]string{
+		"client_name": m.client.Name(),
+		"input_port":  m.input.Name(),
+		"output_port": m.output.Name(),
+		"sample_rate": fmt.Sprint(m.client.SampleRate()),
+		"buffer_size": fmt.Sprint(m.client.BufferSize()),
+		"xruns":       fmt.Sprint(m.client.Xruns()),
+		"overruns":    fmt.Sprint(m.client.Overruns()),
+	}
+	return health
+}
+
+// Stats reports the event traffic statistics of the selected device.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// Stop ends capture and closes the JACK client, which leaves the graph with its ports and
+// virtual endpoints.
+func (m *ClientMid) Stop() error {
+	var err error
+	m.stopOnce.Do(func() {
+		m.logger.Info("Stopping JACK MIDI client")
+		m.mu.Lock()
+		source := m.source
+		m.source, m.port = nil, ""
+		m.capture.Store(nil)
+		m.mu.Unlock()
+
+		m.dispatcher.Detach()
+		m.closing.Store(true)
+		m.client.Wake()
+		m.wg.Wait()
+		if source != nil {
+			source.Close()
+		}
+		err = m.client.Close()
+	})
+	return err
+}
+
+// frameClock extends the 32-bit frame times of JACK, which wrap around after about a day
+// at 48 kHz, to 64 bits, assuming consecutive messages are less than half a wrap apart.
+type frameClock struct {
+	last    uint64
+	started bool
+}
+
+// extend returns the 64-bit frame time of frame.
+func (f *frameClock) extend(frame uint32) uint64 {
+	if !f.started {
+		f.last, f.started = uint64(frame), true
+		return f.last
+	}
+	f.last = uint64(int64(f.last) + int64(int32(frame-uint32(f.last))))
+	return f.last
+}
@@ -0,0 +1,12 @@
+//go:build !jack || !cgo || !linux || nomidihw
+// +build !jack !cgo !linux nomidihw
+
+package midijack
+
+import "github.com/leandrodaf/midi/sdk/contracts"
+
+// NewMIDIClient reports ErrJACKUnsupported; the JACK backend needs libjack through cgo,
+// and builds with the nomidihw tag leave it out.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	return nil, ErrJACKUnsupported
+}
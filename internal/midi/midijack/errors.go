@@ -0,0 +1,14 @@
+package midijack
+
+import "errors"
+
+// Errors of the JACK backend.
+var (
+	ErrJACKUnsupported          = errors.New("JACK MIDI backend requires Linux and building with cgo and the jack tag (go build -tags jack)")
+	ErrServerUnavailable        = errors.New("JACK server is not running")
+	ErrServerShutDown           = errors.New("JACK server shut the client down")
+	ErrOutputQueueFull          = errors.New("JACK output queue full")
+	ErrCreateVirtualSource      = errors.New("error creating virtual source")
+	ErrCreateVirtualDestination = errors.New("error creating virtual destination")
+	ErrVirtualEndpointClosed    = errors.New("virtual endpoint closed")
+)
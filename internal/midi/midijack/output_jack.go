@@ -0,0 +1,194 @@
+//go:build linux && cgo && jack && !nomidihw
+// +build linux,cgo,jack,!nomidihw
+
+package midijack
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/leandrodaf/midi/internal/jack"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// ListOutputDevices lists the MIDI ports of the other clients of the graph that messages
+// can be sent to, such as hardware outputs bridged from ALSA and synthesizers.
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	ports, err := m.ports(true)
+	if err != nil {
+		return nil, err
+	}
+	return portInfos(ports, contracts.DirectionOutput), nil
+}
+
+// SelectOutputDevice connects the output port of the client to the port with the given
+// ID, the target of Send, and disconnects the previously selected one. Connections made
+// with other tools are kept, and receive the messages of Send too.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	port, err := m.portName(deviceID, true)
+	if err != nil {
+		return err
+	}
+
+	m.outputMu.Lock()
+	defer m.outputMu.Unlock()
+
+	if m.outputPort != "" {
+		if err := m.client.Disconnect(m.output.Name(), m.outputPort); err != nil {
+			m.logger.Warn("Failed to disconnect JACK port", m.logger.Field().String("port", m.outputPort), m.logger.Field().Error("error", err))
+		}
+		m.outputID, m.outputPort = -1, ""
+	}
+	if err := m.connect(m.output.Name(), port, port); err != nil {
+		m.logger.Error("Failed to connect JACK port", m.logger.Field().String("port", port), m.logger.Field().Error("error", err))
+		return fmt.Errorf("error connecting JACK port %s: %w", port, err)
+	}
+
+	m.outputID, m.outputPort, m.outputLost = deviceID, port, false
+	m.logger.Info("MIDI output device selected",
+		m.logger.Field().Int("deviceID", deviceID),
+		m.logger.Field().String("deviceName", port))
+	return nil
+}
+
+// Send queues event for the output port of the client, to be sent at the start of the
+// next process cycle. Its timestamp is ignored.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	data, err := midistream.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	m.outputMu.Lock()
+	defer m.outputMu.Unlock()
+
+	if m.outputID < 0 {
+		return contracts.ErrNoOutputDevice
+	}
+	if m.outputLost {
+		return fmt.Errorf("%w: %s", contracts.ErrDeviceDisconnected, m.outputPort)
+	}
+	return m.write(m.output, data)
+}
+
+// SendSysEx queues a complete SysEx message, including F0 and F7, for the port connected
+// to the output port of the client, as a single event of the next cycle. Messages longer
+// than the MIDI buffer of a cycle are dropped by JACK.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	if err := midistream.CheckSysEx(data); err != nil {
+		return err
+	}
+
+	m.outputMu.Lock()
+	defer m.outputMu.Unlock()
+
+	if m.outputID < 0 {
+		return contracts.ErrNoOutputDevice
+	}
+	if m.outputLost {
+		return fmt.Errorf("%w: %s", contracts.ErrDeviceDisconnected, m.outputPort)
+	}
+	return m.write(m.output, data)
+}
+
+// write queues data for an output port.
+func (m *ClientMid) write(port *jack.Port, data []byte) error {
+	if m.client.ShutDown() {
+		return fmt.Errorf("%w: %w", contracts.ErrDeviceDisconnected, ErrServerShutDown)
+	}
+	if err := port.Write(data); err != nil {
+		if errors.Is(err, jack.ErrQueueFull) {
+			return ErrOutputQueueFull
+		}
+		return err
+	}
+	return nil
+}
+
+// CreateVirtualSource registers an output port named name, which other applications can
+// connect to their inputs. It is removed by its Close method or by Stop.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	port, err := m.client.RegisterOutput(name, outputRing)
+	if err != nil {
+		m.logger.Error(ErrCreateVirtualSource.Error(), m.logger.Field().Error("error", err))
+		return nil, fmt.Errorf("%w: %w", ErrCreateVirtualSource, jackError(err))
+	}
+	m.logger.Info("Virtual MIDI source created", m.logger.Field().String("port", port.Name()))
+	return &virtualSource{client: m, port: port}, nil
+}
+
+// CreateVirtualDestination registers an input port named name, which other applications
+// can connect their outputs to. The messages sent to it are timestamped like captured
+// events and delivered to eventChannel, without filtering; they are dropped while the
+// channel is full, and SysEx messages are discarded. It is removed by its Close method or
+// by Stop.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	port, err := m.client.RegisterInput(name, m.ringSize)
+	if err != nil {
+		m.logger.Error(ErrCreateVirtualDestination.Error(), m.logger.Field().Error("error", err))
+		return nil, fmt.Errorf("%w: %w", ErrCreateVirtualDestination, jackError(err))
+	}
+
+	r := &receiver{port: port}
+	r.parser = &midistream.Parser{
+		OnMessage: func(event contracts.MIDI) {
+			event.Timestamp = r.timestamp
+			select {
+			case eventChannel <- event:
+			default:
+			}
+		},
+		MaxSysEx: m.dispatcher.MaxSysEx(),
+	}
+	m.receiversMu.Lock()
+	m.receivers = append(m.receivers, r)
+	m.receiversMu.Unlock()
+
+	m.logger.Info("Virtual MIDI destination created", m.logger.Field().String("port", port.Name()))
+	return &virtualDestination{client: m, receiver: r}, nil
+}
+
+// virtualSource sends messages through an output port registered by CreateVirtualSource.
+type virtualSource struct {
+	client *ClientMid
+	port   *jack.Port
+}
+
+// Send encodes event and queues it for the port, to be sent at the start of the next
+// process cycle.
+func (v *virtualSource) Send(event contracts.MIDI) error {
+	data, err := midistream.Encode(event)
+	if err != nil {
+		return err
+	}
+	if err := v.client.write(v.port, data); err != nil {
+		if errors.Is(err, jack.ErrClosed) {
+			return ErrVirtualEndpointClosed
+		}
+		return err
+	}
+	return nil
+}
+
+// Close unregisters the port.
+func (v *virtualSource) Close() error {
+	return v.port.Close()
+}
+
+// virtualDestination is an input port registered by CreateVirtualDestination.
+type virtualDestination struct {
+	client   *ClientMid
+	receiver *receiver
+}
+
+// Close unregisters the port. No message is delivered to the event channel once it
+// returns, so the channel can then be closed.
+func (v *virtualDestination) Close() error {
+	m := v.client
+	m.receiversMu.Lock()
+	m.receivers = slices.DeleteFunc(m.receivers, func(r *receiver) bool { return r == v.receiver })
+	m.receiversMu.Unlock()
+	return v.receiver.port.Close()
+}
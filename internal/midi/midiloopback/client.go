@@ -0,0 +1,210 @@
+package midiloopback
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// deviceName is the name of the single device of the loopback backend.
+const deviceName = "Loopback"
+
+// ClientMid implements contracts.ClientMIDI without hardware: events passed to Send are
+// captured from its single device as if a controller had played them. It lets tools and
+// tests drive the capture path of an application.
+//
+// The output of the device is also wired to the virtual destinations of the client, and
+// its virtual sources to its input, so applications publishing virtual ports can be tested
+// end to end.
+type ClientMid struct {
+	logger       contracts.Logger
+	dispatcher   *dispatch.Dispatcher      // Filters events and delivers them to the event channel.
+	mu           sync.Mutex                // Mutex for thread safety on shared resources.
+	selected     bool                      // Indicates if the loopback device is selected.
+	source       *dispatch.Source          // Source of the loopback device while capturing.
+	sendMu       sync.Mutex                // Serializes the events passed to the source, which has a single producer.
+	stopOnce     sync.Once                 // Ensures Stop() is executed only once.
+	virtualMu    sync.Mutex                // Protects destinations.
+	destinations map[*destination]struct{} // Virtual destinations receiving the events passed to Send.
+}
+
+// NewMIDIClient creates a loopback client.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	return &ClientMid{
+		logger:       options.Logger,
+		dispatcher:   dispatch.New(contracts.BackendLoopback, options),
+		destinations: make(map[*destination]struct{}),
+	}, nil
+}
+
+// ListDevices lists the single loopback device.
+func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
+	return []contracts.DeviceInfo{{Name: deviceName}}, nil
+}
+
+// DeviceCapabilities reports the capabilities of the loopback device.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	if deviceID != 0 {
+		return contracts.DeviceCapabilities{}, fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	return contracts.DeviceCapabilities{SysExIn: true, SysExOut: true, InputPorts: 1, OutputPorts: 1}, nil
+}
+
+// SelectDevice selects the loopback device, whose ID is 0.
+func (m *ClientMid) SelectDevice(deviceID int) error {
+	if deviceID != 0 {
+		return fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+
+	m.mu.Lock()
+	m.selected = true
+	m.mu.Unlock()
+
+	m.logger.Info("Loopback MIDI device selected")
+	m.dispatcher.DeviceSelected(0, contracts.DeviceInfo{Name: deviceName})
+	return nil
+}
+
+// StartCapture delivers the events passed to Send to eventChannel.
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if !m.selected {
+		m.logger.Error("No MIDI device selected")
+		return contracts.ErrNoDeviceSelected
+	}
+	if m.source != nil {
+		m.logger.Warn("Capture already started")
+		return contracts.ErrCaptureStarted
+	}
+
+	m.dispatcher.Attach(eventChannel)
+	m.source = m.dispatcher.AddSource(0, contracts.DeviceInfo{Name: deviceName})
+	m.logger.Info("Loopback MIDI capture started")
+	return nil
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
+}
+
+// ListOutputDevices lists the single loopback device, which is also the output.
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	return []contracts.DeviceInfo{{Name: deviceName}}, nil
+}
+
+// SelectOutputDevice accepts the loopback device, whose ID is 0. Send works without it, so
+// tools can inject events without selecting an output.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	if deviceID != 0 {
+		return fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	return nil
+}
+
+// Send injects an event, which is captured as if the loopback device had sent it: the
+// output of the loopback device is its input. The event also reaches the virtual
+// destinations of the client. It returns contracts.ErrNotCapturing when capture is not
+// running.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	m.mu.Lock()
+	source := m.source
+	m.mu.Unlock()
+
+	if source == nil {
+		return contracts.ErrNotCapturing
+	}
+	m.sendMu.Lock()
+	source.Dispatch(event)
+	m.sendMu.Unlock()
+
+	m.virtualMu.Lock()
+	for destination := range m.destinations {
+		destination.deliver(event)
+	}
+	m.virtualMu.Unlock()
+	return nil
+}
+
+// SendSysEx injects a complete SysEx message, including F0 and F7, which is delivered to
+// the SysEx channel or handler as if the loopback device had sent it. It returns
+// contracts.ErrNotCapturing when capture is not running, and an error wrapping
+// contracts.ErrMalformedMessage for data that is not a SysEx message.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	if err := midistream.CheckSysEx(data); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	source := m.source
+	m.mu.Unlock()
+
+	if source == nil {
+		return contracts.ErrNotCapturing
+	}
+	m.sendMu.Lock()
+	source.DispatchSysEx(contracts.SysExEvent{Data: append([]byte(nil), data...)})
+	m.sendMu.Unlock()
+	return nil
+}
+
+// SetMIDIEventFilter replaces the event filter applied to injected events.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
+}
+
+// Health reports the dispatcher counters together with the selection state.
+func (m *ClientMid) Health() contracts.Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := m.dispatcher.Health()
+	health.Connected = m.selected
+	if m.selected {
+		health.DeviceID = 0
+		health.Device = contracts.DeviceInfo{Name: deviceName}
+	}
+	return health
+}
+
+// Stats reports the traffic statistics of the injected events.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// Stop ends capture and closes the virtual destinations. Events sent afterwards are
+// rejected.
+func (m *ClientMid) Stop() error {
+	m.stopOnce.Do(func() {
+		m.logger.Info("Stopping loopback MIDI capture")
+		m.closeVirtuals()
+		m.mu.Lock()
+		source := m.source
+		m.source = nil
+		m.selected = false
+		m.mu.Unlock()
+
+		m.dispatcher.Detach()
+		if source != nil {
+			source.Close()
+		}
+	})
+	return nil
+}
@@ -0,0 +1,25 @@
+package midiloopback_test
+
+import (
+	"testing"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/midi"
+	"github.com/leandrodaf/midi/sdk/midi/backendtest"
+)
+
+func TestConformance(t *testing.T) {
+	backendtest.Run(t, backendtest.Config{
+		New: func(t *testing.T, opts ...contracts.Option) contracts.ClientMIDI {
+			opts = append(opts, contracts.WithBackend(contracts.BackendLoopback), contracts.WithoutLogging())
+			client, err := midi.NewMIDIClient(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return client
+		},
+		Inject: func(client contracts.ClientMIDI, event contracts.MIDI) error {
+			return client.Send(event)
+		},
+	})
+}
@@ -0,0 +1,91 @@
+package midiloopback
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// source is a virtual source of the loopback client. Its messages are captured from the
+// loopback device, as if an application connected to the source sent them to it.
+type source struct {
+	client *ClientMid
+	mu     sync.Mutex // Protects closed.
+	closed bool
+}
+
+// CreateVirtualSource publishes a source whose messages are captured from the loopback
+// device, so the output of an application to its virtual source can be checked on the
+// capture path. Its timestamps are kept, like those of Send.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	m.logger.Info("Loopback virtual source created", m.logger.Field().String("name", name))
+	return &source{client: m}, nil
+}
+
+// Send captures event from the loopback device. It fails with
+// contracts.ErrDeviceDisconnected once the source is closed.
+func (s *source) Send(event contracts.MIDI) error {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return contracts.ErrDeviceDisconnected
+	}
+	return s.client.Send(event)
+}
+
+// Close removes the source.
+func (s *source) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return nil
+}
+
+// destination is a virtual destination of the loopback client, receiving the messages
+// sent to the loopback device.
+type destination struct {
+	client       *ClientMid
+	eventChannel chan contracts.MIDI
+}
+
+// CreateVirtualDestination publishes a destination delivering the messages passed to Send
+// to eventChannel without blocking, as if an application connected to the destination
+// sent them, so the input of an application from its virtual destination can be driven
+// by tests. Messages are dropped when eventChannel is full.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	if eventChannel == nil {
+		return nil, fmt.Errorf("%w: nil event channel for virtual destination %q", contracts.ErrInvalidDevice, name)
+	}
+	d := &destination{client: m, eventChannel: eventChannel}
+	m.virtualMu.Lock()
+	m.destinations[d] = struct{}{}
+	m.virtualMu.Unlock()
+	m.logger.Info("Loopback virtual destination created", m.logger.Field().String("name", name))
+	return d, nil
+}
+
+// deliver passes event to the channel of the destination, dropping it when full. The
+// caller must hold the virtualMu of the client.
+func (d *destination) deliver(event contracts.MIDI) {
+	select {
+	case d.eventChannel <- event:
+	default:
+	}
+}
+
+// Close removes the destination; no message is delivered once it returns.
+func (d *destination) Close() error {
+	d.client.virtualMu.Lock()
+	delete(d.client.destinations, d)
+	d.client.virtualMu.Unlock()
+	return nil
+}
+
+// closeVirtuals removes the virtual destinations, for Stop.
+func (m *ClientMid) closeVirtuals() {
+	m.virtualMu.Lock()
+	clear(m.destinations)
+	m.virtualMu.Unlock()
+}
@@ -0,0 +1,309 @@
+package midiremote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/internal/midi/retry"
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/remote"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// Error definitions for the remote backend.
+var (
+	ErrMissingAddress   = errors.New("remote backend requires an address")
+	ErrRemoteConnection = errors.New("error connecting to remote MIDI server")
+)
+
+// ClientMid implements contracts.ClientMIDI by forwarding every call to a remote
+// MIDI server, so a device attached to another machine behaves like a local one.
+type ClientMid struct {
+	logger        contracts.Logger
+	ctx           context.Context // Parent of the contexts of the calls to the server.
+	address       string
+	conn          *grpc.ClientConn
+	service       *remote.ServiceClient
+	dispatcher    *dispatch.Dispatcher // Filters events and delivers them to the event channel.
+	mu            sync.Mutex           // Mutex for thread safety on shared resources.
+	capturing     bool                 // Indicates if event capturing is currently active.
+	cancelCapture context.CancelFunc   // Cancels the Capture stream.
+	source        *dispatch.Source     // Dispatcher entry of the Capture stream; nil when not capturing.
+	wg            sync.WaitGroup       // WaitGroup for the stream receiving goroutine.
+	stopOnce      sync.Once            // Ensures Stop() is executed only once.
+	deviceID      int                  // ID of the remote device last selected through this client, or -1.
+	openRetry     *contracts.OpenRetry // Retry policy for selecting a device; nil disables retries.
+}
+
+// NewMIDIClient connects to the remote MIDI server configured in options.RemoteConfig,
+// with the TLS, token and payload keys it holds. The connection is established lazily by
+// gRPC, so an unreachable server or rejected credentials are reported by the first call
+// rather than here.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	if options.RemoteConfig == nil || options.RemoteConfig.Address == "" {
+		return nil, ErrMissingAddress
+	}
+
+	dialOptions, err := remote.DialOptions(*options.RemoteConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRemoteConnection, err)
+	}
+	conn, err := grpc.NewClient(options.RemoteConfig.Address, dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRemoteConnection, err)
+	}
+	options.Logger.Info("Remote MIDI client created",
+		options.Logger.Field().String("address", options.RemoteConfig.Address))
+
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &ClientMid{
+		logger:     options.Logger,
+		ctx:        ctx,
+		address:    options.RemoteConfig.Address,
+		conn:       conn,
+		service:    remote.NewServiceClient(conn),
+		dispatcher: dispatch.New(contracts.BackendRemote, options),
+		deviceID:   -1,
+		openRetry:  options.OpenRetry,
+	}, nil
+}
+
+// ListDevices lists the devices available on the remote host.
+func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
+	devices, err := m.service.ListDevices(m.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing remote MIDI devices: %w", err)
+	}
+	return devices, nil
+}
+
+// DeviceCapabilities reports what a device on the remote host supports.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	capabilities, err := m.service.DeviceCapabilities(m.ctx, deviceID)
+	if err != nil {
+		return contracts.DeviceCapabilities{}, fmt.Errorf("error querying remote MIDI device %d: %w", deviceID, err)
+	}
+	return capabilities, nil
+}
+
+// SelectDevice selects a device on the remote host, retrying according to the open retry
+// policy. If the capture of a lost stream is still running, a new stream is opened.
+func (m *ClientMid) SelectDevice(deviceID int) error {
+	err := retry.Do(m.openRetry, m.logger, "SelectDevice", func() error {
+		return m.service.SelectDevice(m.ctx, deviceID)
+	})
+	if err != nil {
+		m.logger.Error("Failed to select remote MIDI device", m.logger.Field().Error("error", err))
+		return fmt.Errorf("error selecting remote MIDI device %d: %w", deviceID, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deviceID = deviceID
+
+	m.logger.Info("Remote MIDI device selected", m.logger.Field().Int("deviceID", deviceID))
+	m.dispatcher.DeviceSelected(deviceID, contracts.DeviceInfo{Name: m.address})
+	if eventChannel := m.dispatcher.Channel(); eventChannel != nil && !m.capturing {
+		if err := m.startCapture(eventChannel); err != nil {
+			return fmt.Errorf("failed to resume remote MIDI capture: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListOutputDevices lists the output devices available on the remote host.
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	devices, err := m.service.ListOutputDevices(m.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing remote MIDI output devices: %w", err)
+	}
+	return devices, nil
+}
+
+// SelectOutputDevice selects the output device of the remote host that Send writes to.
+// The selection is held by the server, so it is shared by every client of the server.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	if err := m.service.SelectOutputDevice(m.ctx, deviceID); err != nil {
+		m.logger.Error("Failed to select remote MIDI output device", m.logger.Field().Error("error", err))
+		return fmt.Errorf("error selecting remote MIDI output device %d: %w", deviceID, err)
+	}
+	m.logger.Info("Remote MIDI output device selected", m.logger.Field().Int("deviceID", deviceID))
+	return nil
+}
+
+// Send sends an event to the output device selected on the remote host.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	if err := m.service.Send(m.ctx, event); err != nil {
+		return fmt.Errorf("error sending to remote MIDI output device: %w", err)
+	}
+	return nil
+}
+
+// SendSysEx sends a complete SysEx message, including F0 and F7, to the output device
+// selected on the remote server.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	if err := midistream.CheckSysEx(data); err != nil {
+		return err
+	}
+	if err := m.service.SendSysEx(m.ctx, data); err != nil {
+		return fmt.Errorf("error sending SysEx to remote MIDI output device: %w", err)
+	}
+	return nil
+}
+
+// CreateVirtualSource reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	return nil, fmt.Errorf("%w: remote backend", contracts.ErrVirtualUnsupported)
+}
+
+// CreateVirtualDestination reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	return nil, fmt.Errorf("%w: remote backend", contracts.ErrVirtualUnsupported)
+}
+
+// StartCapture opens a Capture stream on the remote server and forwards its events
+// to eventChannel, applying the local event filter.
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if m.capturing {
+		m.logger.Warn("Capture already started")
+		return contracts.ErrCaptureStarted
+	}
+	return m.startCapture(eventChannel)
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
+}
+
+// startCapture opens a Capture stream delivering to eventChannel. The caller must hold m.mu.
+func (m *ClientMid) startCapture(eventChannel chan contracts.MIDI) error {
+	ctx, cancel := context.WithCancel(m.ctx)
+	stream, err := m.service.Capture(ctx)
+	if err != nil {
+		cancel()
+		m.logger.Error("Failed to start remote MIDI capture", m.logger.Field().Error("error", err))
+		return fmt.Errorf("error starting remote MIDI capture: %w", err)
+	}
+
+	m.dispatcher.Attach(eventChannel)
+	m.cancelCapture = cancel
+	m.capturing = true
+
+	source := m.dispatcher.AddSource(m.deviceID, contracts.DeviceInfo{Name: m.address})
+	m.source = source
+	m.wg.Add(1)
+	profiling.Go(profiling.RoleCapture, contracts.BackendRemote, m.address, func() { m.receive(stream, source) })
+	m.logger.Info("Remote MIDI capture started")
+	return nil
+}
+
+// receive forwards events from the Capture stream to source until the stream ends.
+func (m *ClientMid) receive(stream *remote.EventStream, source *dispatch.Source) {
+	defer m.wg.Done()
+	defer source.Close()
+	defer source.Recover()
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if status.Code(err) == codes.Canceled {
+				return
+			}
+			if err != io.EOF && m.dispatcher.Logging() {
+				m.logger.Error("Remote MIDI capture stream ended", m.logger.Field().Error("error", err))
+			}
+			source.Lost(fmt.Errorf("%w: capture stream ended: %v", contracts.ErrDeviceDisconnected, err))
+			m.drop(source)
+			return
+		}
+		source.Dispatch(event)
+	}
+}
+
+// drop forgets a Capture stream that ended, so the next SelectDevice opens a new one. The
+// event channel stays attached for the capture to resume then.
+func (m *ClientMid) drop(source *dispatch.Source) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.source == source {
+		m.source = nil
+		m.capturing = false
+		m.cancelCapture()
+	}
+}
+
+// SetMIDIEventFilter replaces the local event filter applied to events received from the server.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
+}
+
+// Health reports the state of the gRPC connection together with the dispatcher counters.
+// The device information is not fetched from the server to keep the call local.
+func (m *ClientMid) Health() contracts.Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := m.conn.GetState()
+	health := m.dispatcher.Health()
+	health.Connected = state == connectivity.Ready
+	health.Capturing = m.capturing
+	health.DeviceID = m.deviceID
+	health.Diagnostics = map[string]string{
+		"address":          m.address,
+		"connection_state": state.String(),
+	}
+	return health
+}
+
+// Stats reports the traffic statistics of the events received from the server, measured on arrival.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// Stop ends the Capture stream and closes the connection to the remote server.
+// The remote device itself keeps running, since it is owned by the server.
+func (m *ClientMid) Stop() error {
+	var err error
+	m.stopOnce.Do(func() {
+		m.logger.Info("Stopping remote MIDI capture")
+		m.mu.Lock()
+		if m.capturing {
+			m.capturing = false
+			m.cancelCapture()
+		}
+		m.mu.Unlock()
+
+		m.dispatcher.Detach()
+
+		m.wg.Wait()
+		err = m.conn.Close()
+	})
+	return err
+}
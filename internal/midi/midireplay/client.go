@@ -0,0 +1,269 @@
+package midireplay
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// deviceName is the name of the single device of the replay backend.
+const deviceName = "Replay"
+
+// Fast playback waits for room in a full event channel by yielding to the consumer up to
+// roomSpins times, then by polling every roomPoll.
+const (
+	roomSpins = 64
+	roomPoll  = 100 * time.Microsecond
+)
+
+// ClientMid implements contracts.ClientMIDI by playing back recorded events from its
+// single device, paced according to the configured replay timing.
+type ClientMid struct {
+	logger     contracts.Logger
+	dispatcher *dispatch.Dispatcher    // Filters events and delivers them to the event channel.
+	config     *contracts.ReplayConfig // Recorded events and how to read their timestamps.
+	timing     contracts.ReplayTiming  // Pacing of the played-back events.
+	mu         sync.Mutex              // Mutex for thread safety on shared resources.
+	selected   bool                    // Indicates if the replay device is selected.
+	source     *dispatch.Source        // Source of the replay device while capturing.
+	done       chan struct{}           // Closed by Stop to end playback.
+	played     atomic.Uint64           // Events played back so far.
+	wg         sync.WaitGroup          // Tracks the playback goroutine.
+	stopOnce   sync.Once               // Ensures Stop() is executed only once.
+}
+
+// NewMIDIClient creates a replay client for the events of options.ReplayConfig.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	return &ClientMid{
+		logger:     options.Logger,
+		dispatcher: dispatch.New(contracts.BackendReplay, options),
+		config:     options.ReplayConfig,
+		timing:     options.ReplayTiming,
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// ListDevices lists the single replay device.
+func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
+	return []contracts.DeviceInfo{{Name: deviceName}}, nil
+}
+
+// DeviceCapabilities reports the capabilities of the replay device.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	if deviceID != 0 {
+		return contracts.DeviceCapabilities{}, fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	return contracts.DeviceCapabilities{InputPorts: 1}, nil
+}
+
+// ListOutputDevices reports contracts.ErrOutputUnsupported; recordings are played back as input only.
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	return nil, fmt.Errorf("%w: replay backend", contracts.ErrOutputUnsupported)
+}
+
+// SelectOutputDevice reports contracts.ErrOutputUnsupported.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	return fmt.Errorf("%w: replay backend", contracts.ErrOutputUnsupported)
+}
+
+// Send reports contracts.ErrOutputUnsupported.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	return fmt.Errorf("%w: replay backend", contracts.ErrOutputUnsupported)
+}
+
+// SendSysEx reports contracts.ErrOutputUnsupported.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	return fmt.Errorf("%w: replay backend", contracts.ErrOutputUnsupported)
+}
+
+// CreateVirtualSource reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	return nil, fmt.Errorf("%w: replay backend", contracts.ErrVirtualUnsupported)
+}
+
+// CreateVirtualDestination reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	return nil, fmt.Errorf("%w: replay backend", contracts.ErrVirtualUnsupported)
+}
+
+// SelectDevice selects the replay device, whose ID is 0.
+func (m *ClientMid) SelectDevice(deviceID int) error {
+	if deviceID != 0 {
+		return fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+
+	m.mu.Lock()
+	m.selected = true
+	m.mu.Unlock()
+
+	m.logger.Info("Replay MIDI device selected")
+	m.dispatcher.DeviceSelected(0, contracts.DeviceInfo{Name: deviceName})
+	return nil
+}
+
+// StartCapture starts playing the recorded events back to eventChannel.
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if !m.selected {
+		m.logger.Error("No MIDI device selected")
+		return contracts.ErrNoDeviceSelected
+	}
+	if m.source != nil {
+		m.logger.Warn("Capture already started")
+		return contracts.ErrCaptureStarted
+	}
+
+	m.dispatcher.Attach(eventChannel)
+	m.source = m.dispatcher.AddSource(0, contracts.DeviceInfo{Name: deviceName})
+	m.wg.Add(1)
+	source := m.source
+	profiling.Go(profiling.RoleCapture, contracts.BackendReplay, deviceName, func() { m.play(source) })
+	m.logger.Info("Replay MIDI capture started",
+		m.logger.Field().Int("events", len(m.config.Events)))
+	return nil
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
+}
+
+// play delivers the recorded events to source until they run out, or until Stop when
+// looping.
+func (m *ClientMid) play(source *dispatch.Source) {
+	defer m.wg.Done()
+	defer source.Recover()
+
+	events := m.config.Events
+	if len(events) == 0 {
+		return
+	}
+	for {
+		start := time.Now()
+		first := events[0].Timestamp
+		for _, event := range events {
+			if !m.wait(start, event.Timestamp-min(first, event.Timestamp)) {
+				return
+			}
+			source.Dispatch(event)
+			m.played.Add(1)
+		}
+		if !m.config.Loop {
+			return
+		}
+	}
+}
+
+// wait waits until an event recorded elapsed timestamp units after the first one is due,
+// according to the replay timing, or with fast playback until the event channel has room.
+// It reports false when playback was stopped.
+func (m *ClientMid) wait(start time.Time, elapsed uint64) bool {
+	offset := time.Duration(elapsed) * m.config.TimestampUnit
+	switch m.timing.Mode {
+	case contracts.ReplayAsFastAsPossible:
+		for spins := 0; ; spins++ {
+			eventChannel := m.dispatcher.Channel()
+			if eventChannel == nil || cap(eventChannel) == 0 || len(eventChannel) < cap(eventChannel) {
+				break
+			}
+			if spins < roomSpins {
+				runtime.Gosched()
+				continue
+			}
+			select {
+			case <-m.done:
+				return false
+			case <-time.After(roomPoll):
+			}
+		}
+		select {
+		case <-m.done:
+			return false
+		default:
+			return true
+		}
+	case contracts.ReplayScaled:
+		offset = time.Duration(float64(offset) / m.timing.Speed)
+	}
+
+	timer := time.NewTimer(time.Until(start.Add(offset)))
+	defer timer.Stop()
+	select {
+	case <-m.done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// SetMIDIEventFilter replaces the event filter applied to played-back events.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
+}
+
+// Health reports the dispatcher counters together with the playback progress.
+func (m *ClientMid) Health() contracts.Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := m.dispatcher.Health()
+	health.Connected = m.selected
+	health.DeviceID = -1
+	if m.selected {
+		health.DeviceID = 0
+		health.Device = contracts.DeviceInfo{Name: deviceName}
+	}
+	health.Diagnostics = map[string]string{
+		"events": strconv.Itoa(len(m.config.Events)),
+		"played": strconv.FormatUint(m.played.Load(), 10),
+		"loop":   strconv.FormatBool(m.config.Loop),
+	}
+	return health
+}
+
+// Stats reports the traffic statistics of the played-back events.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// Stop ends playback and capture.
+func (m *ClientMid) Stop() error {
+	m.stopOnce.Do(func() {
+		m.logger.Info("Stopping replay MIDI capture")
+		close(m.done)
+		m.wg.Wait()
+
+		m.mu.Lock()
+		source := m.source
+		m.source = nil
+		m.selected = false
+		m.mu.Unlock()
+
+		m.dispatcher.Detach()
+		if source != nil {
+			source.Close()
+		}
+	})
+	return nil
+}
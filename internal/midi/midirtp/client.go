@@ -0,0 +1,571 @@
+package midirtp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/applemidi"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/discovery"
+)
+
+const (
+	defaultBrowseTimeout = time.Second     // How long ListDevices browses for sessions by default.
+	inviteTimeout        = 5 * time.Second // How long a peer has to accept an invitation.
+	sessionClockUnit     = 100 * time.Microsecond
+	manufacturer         = "AppleMIDI (RTP-MIDI)"
+	maxSegment           = 1024 // Longest SysEx segment of a packet, keeping packets within the MTU of common networks.
+)
+
+// peer is an RTP-MIDI session listed as a device.
+type peer struct {
+	name    string
+	address string // Control address (host:port).
+	ssrc    uint32 // Synchronization source, for participants that invited this side.
+	joined  bool   // Whether the peer invited this side, so ssrc identifies it.
+}
+
+// info describes the peer as a device.
+func (p peer) info() contracts.DeviceInfo {
+	return contracts.DeviceInfo{Name: p.name, Manufacturer: manufacturer, EntityName: p.address}
+}
+
+// ClientMid implements contracts.ClientMIDI over RTP-MIDI: it runs an AppleMIDI session
+// and lists the sessions of the local network as devices, the configured peers first, then
+// those found via mDNS and those that invited this session. Selecting a device invites
+// its session, whose messages are then captured or sent to.
+type ClientMid struct {
+	logger     contracts.Logger
+	ctx        context.Context // Parent of the invitations and network browsing.
+	config     contracts.RTPConfig
+	session    *applemidi.Session
+	dispatcher *dispatch.Dispatcher // Filters events and delivers them to the event channel.
+
+	mu       sync.Mutex           // Mutex for thread safety on shared resources.
+	peers    []peer               // Devices of the last listing.
+	deviceID int                  // ID of the selected input device, or -1.
+	device   contracts.DeviceInfo // Information about the selected input device.
+	input    uint32               // Synchronization source of the selected input device.
+	source   *dispatch.Source     // Dispatcher entry of the input device while capturing.
+	outputID int                  // ID of the selected output device, or -1.
+	output   uint32               // Synchronization source of the selected output device.
+
+	sendMu   sync.Mutex // Orders the packets sent and their sequence numbers.
+	sequence uint16     // Sequence number of the next packet sent.
+
+	sysex    []byte // SysEx message being reassembled from segments; only used by receive.
+	stopOnce sync.Once
+}
+
+// NewMIDIClient starts an AppleMIDI session with options.RTPConfig, listening on its
+// control and data ports.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	config := contracts.RTPConfig{}
+	if options.RTPConfig != nil {
+		config = *options.RTPConfig
+	}
+	if config.BrowseTimeout == 0 {
+		config.BrowseTimeout = defaultBrowseTimeout
+	}
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	m := &ClientMid{
+		logger:     options.Logger,
+		ctx:        ctx,
+		config:     config,
+		dispatcher: dispatch.New(contracts.BackendRTP, options),
+		deviceID:   -1,
+		outputID:   -1,
+	}
+	sessionOptions := []applemidi.SessionOption{
+		applemidi.WithLogger(options.Logger),
+		applemidi.WithDataHandler(m.receive),
+		applemidi.WithParticipantHandlers(nil, m.left),
+	}
+	if config.Name != "" {
+		sessionOptions = append(sessionOptions, applemidi.WithName(config.Name))
+	}
+	if config.Port != 0 {
+		sessionOptions = append(sessionOptions, applemidi.WithPort(config.Port))
+	}
+	if config.Advertise {
+		sessionOptions = append(sessionOptions, applemidi.WithAdvertisement())
+	}
+
+	session, err := applemidi.NewSession(sessionOptions...)
+	if err != nil {
+		return nil, err
+	}
+	m.session = session
+	options.Logger.Info("RTP-MIDI client created", options.Logger.Field().Int("peers", len(config.Peers)))
+	return m, nil
+}
+
+// ListDevices lists the configured peers, the sessions announced on the network during
+// the browse timeout and the participants that invited this session.
+func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
+	peers := m.listPeers()
+
+	m.mu.Lock()
+	m.peers = peers
+	m.mu.Unlock()
+
+	if len(peers) == 0 {
+		m.logger.Warn("No RTP-MIDI sessions found")
+		return nil, contracts.ErrNoDevices
+	}
+	devices := make([]contracts.DeviceInfo, len(peers))
+	for i, p := range peers {
+		devices[i] = p.info()
+	}
+	return devices, nil
+}
+
+// listPeers collects the sessions listed as devices, without duplicate addresses.
+func (m *ClientMid) listPeers() []peer {
+	var peers []peer
+	index := make(map[string]int)
+	add := func(p peer) {
+		if i, ok := index[p.address]; ok {
+			if p.joined {
+				peers[i].ssrc, peers[i].joined = p.ssrc, true
+			}
+			return
+		}
+		index[p.address] = len(peers)
+		peers = append(peers, p)
+	}
+
+	for _, address := range m.config.Peers {
+		add(peer{name: address, address: address})
+	}
+	if m.config.BrowseTimeout > 0 {
+		ctx, cancel := context.WithTimeout(m.ctx, m.config.BrowseTimeout)
+		endpoints, err := discovery.Browse(ctx, discovery.AppleMIDIService)
+		cancel()
+		if err != nil {
+			m.logger.Warn("RTP-MIDI session discovery failed", m.logger.Field().Error("error", err))
+		}
+		for _, endpoint := range endpoints {
+			add(peer{name: endpoint.Instance, address: endpoint.Address()})
+		}
+	}
+	for _, participant := range m.session.Participants() {
+		if !participant.Initiator {
+			add(peer{name: participant.Name, address: participant.ControlAddr.String(), ssrc: participant.SSRC, joined: true})
+		}
+	}
+	return peers
+}
+
+// peer returns the session of a device ID of the last listing, listing the devices if
+// they were never listed.
+func (m *ClientMid) peer(deviceID int) (peer, error) {
+	m.mu.Lock()
+	listed := m.peers != nil
+	m.mu.Unlock()
+	if !listed {
+		if _, err := m.ListDevices(); err != nil {
+			return peer{}, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if deviceID < 0 || deviceID >= len(m.peers) {
+		return peer{}, fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	return m.peers[deviceID], nil
+}
+
+// join returns the participant of the session of p, inviting it unless it is already in
+// this session.
+func (m *ClientMid) join(p peer) (applemidi.Participant, error) {
+	address := p.address
+	if resolved, err := net.ResolveUDPAddr("udp", p.address); err == nil {
+		address = resolved.String()
+	}
+	for _, participant := range m.session.Participants() {
+		if (p.joined && participant.SSRC == p.ssrc) || participant.ControlAddr.String() == address {
+			return participant, nil
+		}
+	}
+	if p.joined {
+		return applemidi.Participant{}, fmt.Errorf("%w: %s left the session", contracts.ErrDeviceDisconnected, p.name)
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, inviteTimeout)
+	defer cancel()
+	participant, err := m.session.Invite(ctx, p.address)
+	switch {
+	case errors.Is(err, applemidi.ErrInvitationRejected):
+		return applemidi.Participant{}, fmt.Errorf("%w: %v", contracts.ErrDeviceBusy, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return applemidi.Participant{}, fmt.Errorf("%w: %s did not answer the invitation", contracts.ErrInvalidDevice, p.address)
+	case err != nil:
+		return applemidi.Participant{}, err
+	}
+	return participant, nil
+}
+
+// DeviceCapabilities reports what an RTP-MIDI session supports: one input and one output,
+// SysEx both ways and timestamps taken by the sender.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	if _, err := m.peer(deviceID); err != nil {
+		return contracts.DeviceCapabilities{}, err
+	}
+	return contracts.DeviceCapabilities{SysExIn: true, SysExOut: true, Timestamps: true, InputPorts: 1, OutputPorts: 1}, nil
+}
+
+// SelectDevice invites the session of a device, if it is not in this session yet, and
+// captures its messages. If the capture of a device that left is still running, it
+// continues on the new one.
+func (m *ClientMid) SelectDevice(deviceID int) error {
+	p, err := m.peer(deviceID)
+	if err != nil {
+		return err
+	}
+	participant, err := m.join(p)
+	if err != nil {
+		m.logger.Error("Failed to join RTP-MIDI session", m.logger.Field().String("peer", p.address), m.logger.Field().Error("error", err))
+		return fmt.Errorf("error joining RTP-MIDI session %s: %w", p.address, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.source != nil {
+		return fmt.Errorf("%w from %s", contracts.ErrCaptureRunning, m.device.Name)
+	}
+	m.deviceID = deviceID
+	m.device = p.info()
+	m.input = participant.SSRC
+
+	m.logger.Info("RTP-MIDI session selected", m.logger.Field().String("peer", p.name))
+	m.dispatcher.DeviceSelected(deviceID, m.device)
+	if eventChannel := m.dispatcher.Channel(); eventChannel != nil {
+		m.startCapture(eventChannel)
+	}
+	return nil
+}
+
+// ListOutputDevices lists the same sessions as ListDevices.
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	return m.ListDevices()
+}
+
+// SelectOutputDevice invites the session of a device, if it is not in this session yet,
+// as the destination of Send.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	p, err := m.peer(deviceID)
+	if err != nil {
+		return err
+	}
+	participant, err := m.join(p)
+	if err != nil {
+		m.logger.Error("Failed to join RTP-MIDI session", m.logger.Field().String("peer", p.address), m.logger.Field().Error("error", err))
+		return fmt.Errorf("error joining RTP-MIDI session %s: %w", p.address, err)
+	}
+
+	m.mu.Lock()
+	m.outputID = deviceID
+	m.output = participant.SSRC
+	m.mu.Unlock()
+
+	m.logger.Info("RTP-MIDI output session selected", m.logger.Field().String("peer", p.name))
+	return nil
+}
+
+// Send sends a channel, system common or real-time message to the output session in a
+// packet of its own.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	data, err := midistream.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	outputID, output := m.outputID, m.output
+	m.mu.Unlock()
+	if outputID < 0 {
+		return contracts.ErrNoOutputDevice
+	}
+
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+	return m.send(output, data)
+}
+
+// SendSysEx sends a complete SysEx message, including F0 and F7, to the selected device.
+// Messages longer than maxSegment bytes are split into segments sent in packets of their
+// own, which the receiver reassembles.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	if err := midistream.CheckSysEx(data); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	outputID, output := m.outputID, m.output
+	m.mu.Unlock()
+	if outputID < 0 {
+		return contracts.ErrNoOutputDevice
+	}
+
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+	if len(data) <= maxSegment {
+		return m.send(output, data)
+	}
+	// Segments start with 0xF0, or 0xF7 when continuing, and end with 0xF0 when another
+	// follows, or with the final 0xF7.
+	body := data[1 : len(data)-1]
+	start := byte(0xF0)
+	for len(body) > maxSegment-2 {
+		segment := append(append([]byte{start}, body[:maxSegment-2]...), 0xF0)
+		if err := m.send(output, segment); err != nil {
+			return err
+		}
+		body, start = body[maxSegment-2:], 0xF7
+	}
+	return m.send(output, append(append([]byte{start}, body...), 0xF7))
+}
+
+// send sends data in a packet of its own to output. The caller must hold m.sendMu.
+func (m *ClientMid) send(output uint32, data []byte) error {
+	packet, err := applemidi.DataPacket{
+		Sequence:  m.sequence,
+		Timestamp: uint32(m.session.Now()),
+		SSRC:      m.session.SSRC(),
+		Commands:  []applemidi.Command{{Data: data}},
+	}.Marshal()
+	if err != nil {
+		return err
+	}
+	m.sequence++
+	if err := m.session.SendTo(output, packet); err != nil {
+		return fmt.Errorf("error sending RTP-MIDI message: %w", err)
+	}
+	return nil
+}
+
+// CreateVirtualSource reports contracts.ErrVirtualUnsupported; peers reach the session of
+// the client itself, announced with RTPConfig.Advertise.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	return nil, fmt.Errorf("%w: RTP-MIDI backend", contracts.ErrVirtualUnsupported)
+}
+
+// CreateVirtualDestination reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	return nil, fmt.Errorf("%w: RTP-MIDI backend", contracts.ErrVirtualUnsupported)
+}
+
+// StartCapture delivers the messages of the selected session to eventChannel.
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if m.deviceID < 0 {
+		m.logger.Error("No MIDI device selected")
+		return contracts.ErrNoDeviceSelected
+	}
+	if m.source != nil {
+		m.logger.Warn("Capture already started")
+		return contracts.ErrCaptureStarted
+	}
+	m.startCapture(eventChannel)
+	return nil
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
+}
+
+// startCapture starts delivering the messages of the selected session. The caller must
+// hold m.mu.
+func (m *ClientMid) startCapture(eventChannel chan contracts.MIDI) {
+	m.dispatcher.Attach(eventChannel)
+	m.source = m.dispatcher.AddSource(m.deviceID, m.device)
+	m.logger.Info("RTP-MIDI capture started", m.logger.Field().String("peer", m.device.Name))
+}
+
+// receive dispatches the messages of a data packet of the selected session. The session
+// calls it from the goroutine reading its data port.
+func (m *ClientMid) receive(participant applemidi.Participant, b []byte) {
+	m.mu.Lock()
+	source := m.source
+	capturing := source != nil && participant.SSRC == m.input
+	m.mu.Unlock()
+	if !capturing {
+		return
+	}
+	defer source.Recover()
+
+	packet, err := applemidi.ParseDataPacket(b)
+	if err != nil {
+		source.Malformed(b, err.Error())
+		return
+	}
+
+	native := uint64(time.Now().UnixNano())
+	arrival := contracts.MonotonicNow()
+	sent := packet.Timestamp
+	for _, command := range packet.Commands {
+		sent += command.Delta
+		timestamp := m.dispatcher.DriverTimestamp(m.senderTime(participant, sent, arrival), native)
+
+		data := command.Data
+		if data[0] == 0xF0 || data[0] == 0xF7 {
+			if message := m.reassemble(source, data); message != nil {
+				source.DispatchSysEx(contracts.SysExEvent{Timestamp: timestamp, Data: message})
+			}
+			continue
+		}
+		event := contracts.MIDI{Timestamp: timestamp, Command: data[0]}
+		if len(data) > 1 {
+			event.Note = data[1]
+		}
+		if len(data) > 2 {
+			event.Velocity = data[2]
+		}
+		source.Dispatch(event)
+	}
+}
+
+// senderTime converts sent, a time on the session clock of participant, to the clock of
+// contracts.MonotonicNow with the offset of the last clock synchronization. Without one it
+// returns arrival, the time the packet was received.
+func (m *ClientMid) senderTime(participant applemidi.Participant, sent uint32, arrival uint64) uint64 {
+	if !participant.Synced {
+		return arrival
+	}
+	peerNow := uint32(int64(m.session.Now()) + int64(participant.ClockOffset/sessionClockUnit))
+	age := time.Duration(max(int32(peerNow-sent), 0)) * sessionClockUnit
+	return arrival - min(uint64(age), arrival)
+}
+
+// reassemble handles a SysEx message or segment and returns the complete message, or nil
+// while segments are missing. Segments after a lost first one are discarded.
+func (m *ClientMid) reassemble(source *dispatch.Source, segment []byte) []byte {
+	first, last := segment[0], segment[len(segment)-1]
+	switch {
+	case last == 0xF4: // Cancelled by the sender
+		m.sysex = nil
+	case first == 0xF0 && last == 0xF7:
+		m.sysex = nil
+		return segment
+	case first == 0xF0:
+		m.sysex = append([]byte(nil), segment[:len(segment)-1]...)
+	case m.sysex == nil:
+		source.Malformed(segment, "SysEx segment without its start")
+	case last == 0xF0:
+		m.sysex = append(m.sysex, segment[1:len(segment)-1]...)
+	default:
+		message := append(m.sysex, segment[1:]...)
+		m.sysex = nil
+		return message
+	}
+	if len(m.sysex) > m.dispatcher.MaxSysEx() {
+		source.Malformed(m.sysex[:min(len(m.sysex), 16)], "SysEx message longer than "+strconv.Itoa(m.dispatcher.MaxSysEx())+" bytes")
+		m.sysex = nil
+	}
+	return nil
+}
+
+// left forgets the selected input or output session when its participant leaves, and
+// reports the input device lost. The event channel stays attached for the capture to
+// resume on the next selection.
+func (m *ClientMid) left(participant applemidi.Participant) {
+	m.mu.Lock()
+	var source *dispatch.Source
+	if m.deviceID >= 0 && participant.SSRC == m.input {
+		source, m.source = m.source, nil
+		m.deviceID = -1
+	}
+	if m.outputID >= 0 && participant.SSRC == m.output {
+		m.outputID = -1
+	}
+	m.mu.Unlock()
+
+	if source != nil {
+		source.Lost(fmt.Errorf("%w: %s left the session", contracts.ErrDeviceDisconnected, participant.Name))
+		source.Close()
+	}
+}
+
+// SetMIDIEventFilter replaces the event filter; it takes effect for the next received message.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
+}
+
+// Health reports whether the selected session is still joined, with its latency and
+// clock offset, together with the dispatcher counters.
+func (m *ClientMid) Health() contracts.Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := m.dispatcher.Health()
+	health.DeviceID = m.deviceID
+	health.Device = m.device
+	health.Diagnostics = map[string]string{"ssrc": strconv.FormatUint(uint64(m.session.SSRC()), 16)}
+	if m.deviceID < 0 {
+		return health
+	}
+	for _, participant := range m.session.Participants() {
+		if participant.SSRC == m.input {
+			health.Connected = true
+			health.Diagnostics["peer_ssrc"] = strconv.FormatUint(uint64(participant.SSRC), 16)
+			if participant.Synced {
+				health.Diagnostics["latency"] = participant.Latency.String()
+				health.Diagnostics["clock_offset"] = participant.ClockOffset.String()
+			}
+		}
+	}
+	return health
+}
+
+// Stats reports the event traffic statistics of the selected session.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// Stop ends capture, leaves the sessions of the peers and closes the ports.
+func (m *ClientMid) Stop() error {
+	var err error
+	m.stopOnce.Do(func() {
+		m.logger.Info("Stopping RTP-MIDI client")
+		m.mu.Lock()
+		source := m.source
+		m.source = nil
+		m.mu.Unlock()
+
+		m.dispatcher.Detach()
+		// Closing the session waits for the goroutine reading the data port.
+		err = m.session.Close()
+		if source != nil {
+			source.Close()
+		}
+	})
+	return err
+}
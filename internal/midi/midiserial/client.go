@@ -0,0 +1,352 @@
+//go:build !js
+// +build !js
+
+package midiserial
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/internal/midi/retry"
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"go.bug.st/serial"
+)
+
+// ClientMid implements contracts.ClientMIDI over serial ports carrying the MIDI byte stream,
+// such as a UART wired to DIN jacks or a USB-serial adapter.
+type ClientMid struct {
+	logger     contracts.Logger
+	config     *contracts.SerialConfig
+	dispatcher *dispatch.Dispatcher // Filters events and delivers them to the event channel.
+	openRetry  *contracts.OpenRetry // Retry policy for opening a port; nil disables retries.
+	mu         sync.Mutex           // Mutex for thread safety on shared resources.
+	port       serial.Port          // Open port of the selected device; nil when none is selected.
+	deviceID   int                  // ID of the selected device, or -1.
+	device     contracts.DeviceInfo // Information about the selected device.
+	source     *dispatch.Source     // Dispatcher entry of the port while capturing.
+	wg         sync.WaitGroup       // WaitGroup for the reading goroutine.
+	stopOnce   sync.Once            // Ensures Stop() is executed only once.
+}
+
+// NewMIDIClient creates a serial client using options.SerialConfig.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	config := options.SerialConfig
+	if config == nil {
+		config = &contracts.SerialConfig{BaudRate: 31250}
+	}
+	options.Logger.Info("Serial MIDI client created", options.Logger.Field().Int("baudRate", config.BaudRate))
+
+	return &ClientMid{
+		logger:     options.Logger,
+		config:     config,
+		dispatcher: dispatch.New(contracts.BackendSerial, options),
+		openRetry:  options.OpenRetry,
+		deviceID:   -1,
+	}, nil
+}
+
+// ListDevices lists the configured serial ports or, when none are configured, every serial
+// port of the system. Ports cannot tell whether a MIDI device is attached.
+func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
+	ports, err := m.ports()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]contracts.DeviceInfo, len(ports))
+	for i, port := range ports {
+		devices[i] = contracts.DeviceInfo{Name: port, EntityName: port}
+	}
+	return devices, nil
+}
+
+// ports returns the serial ports listed as devices.
+func (m *ClientMid) ports() ([]string, error) {
+	ports := m.config.Ports
+	if len(ports) == 0 {
+		var err error
+		if ports, err = serial.GetPortsList(); err != nil {
+			return nil, fmt.Errorf("error listing serial ports: %w", err)
+		}
+	}
+	if len(ports) == 0 {
+		m.logger.Warn("No serial ports found")
+		return nil, contracts.ErrNoDevices
+	}
+	return ports, nil
+}
+
+// portName returns the serial port of a device ID.
+func (m *ClientMid) portName(deviceID int) (string, error) {
+	ports, err := m.ports()
+	if err != nil {
+		return "", err
+	}
+	if deviceID < 0 || deviceID >= len(ports) {
+		return "", fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	return ports[deviceID], nil
+}
+
+// DeviceCapabilities reports what a serial port supports: a single input carrying the
+// complete byte stream, SysEx included, without driver timestamps.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	if _, err := m.portName(deviceID); err != nil {
+		return contracts.DeviceCapabilities{}, err
+	}
+	return contracts.DeviceCapabilities{SysExIn: true, InputPorts: 1}, nil
+}
+
+// ListOutputDevices reports contracts.ErrOutputUnsupported; serial ports are opened for input only.
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	return nil, fmt.Errorf("%w: serial backend", contracts.ErrOutputUnsupported)
+}
+
+// SelectOutputDevice reports contracts.ErrOutputUnsupported.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	return fmt.Errorf("%w: serial backend", contracts.ErrOutputUnsupported)
+}
+
+// Send reports contracts.ErrOutputUnsupported.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	return fmt.Errorf("%w: serial backend", contracts.ErrOutputUnsupported)
+}
+
+// SendSysEx reports contracts.ErrOutputUnsupported.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	return fmt.Errorf("%w: serial backend", contracts.ErrOutputUnsupported)
+}
+
+// CreateVirtualSource reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	return nil, fmt.Errorf("%w: serial backend", contracts.ErrVirtualUnsupported)
+}
+
+// CreateVirtualDestination reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	return nil, fmt.Errorf("%w: serial backend", contracts.ErrVirtualUnsupported)
+}
+
+// SelectDevice opens a serial port at the configured baud rate, retrying according to the
+// open retry policy. A previously selected port is closed. If the capture of a lost port
+// is still running, it continues on the new port.
+func (m *ClientMid) SelectDevice(deviceID int) error {
+	name, err := m.portName(deviceID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.source != nil {
+		return fmt.Errorf("%w from %s", contracts.ErrCaptureRunning, m.device.Name)
+	}
+	if m.port != nil {
+		m.port.Close()
+		m.port = nil
+	}
+
+	mode := &serial.Mode{BaudRate: m.config.BaudRate, DataBits: 8, Parity: serial.NoParity, StopBits: serial.OneStopBit}
+	var port serial.Port
+	err = retry.Do(m.openRetry, m.logger, "Open", func() error {
+		var err error
+		port, err = serial.Open(name, mode)
+		return portError(err)
+	})
+	if err != nil {
+		m.logger.Error("Failed to open serial port", m.logger.Field().String("port", name), m.logger.Field().Error("error", err))
+		return fmt.Errorf("error opening serial port %s: %w", name, err)
+	}
+
+	m.port = port
+	m.deviceID = deviceID
+	m.device = contracts.DeviceInfo{Name: name, EntityName: name}
+	m.logger.Info("Serial MIDI port opened", m.logger.Field().String("port", name))
+	m.dispatcher.DeviceSelected(deviceID, m.device)
+	if eventChannel := m.dispatcher.Channel(); eventChannel != nil {
+		m.startCapture(eventChannel)
+	}
+	return nil
+}
+
+// portError maps the errors of the serial library to the errors of contracts, and
+// permission errors to fs.ErrPermission.
+func portError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %v", contracts.ErrInvalidDevice, err)
+	}
+	var portErr *serial.PortError
+	if !errors.As(err, &portErr) {
+		return err
+	}
+	switch portErr.Code() {
+	case serial.PortBusy:
+		return fmt.Errorf("%w: %v", contracts.ErrDeviceBusy, err)
+	case serial.PortNotFound, serial.InvalidSerialPort:
+		return fmt.Errorf("%w: %v", contracts.ErrInvalidDevice, err)
+	case serial.PortClosed:
+		return fmt.Errorf("%w: %v", contracts.ErrDeviceDisconnected, err)
+	case serial.PermissionDenied:
+		return fmt.Errorf("%w: %v", fs.ErrPermission, err)
+	default:
+		return err
+	}
+}
+
+// StartCapture reads the selected port and delivers its messages to eventChannel.
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if m.port == nil {
+		m.logger.Error("No MIDI device selected")
+		return contracts.ErrNoDeviceSelected
+	}
+	if m.source != nil {
+		m.logger.Warn("Capture already started")
+		return contracts.ErrCaptureStarted
+	}
+	m.startCapture(eventChannel)
+	return nil
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
+}
+
+// startCapture starts reading the selected port. The caller must hold m.mu.
+func (m *ClientMid) startCapture(eventChannel chan contracts.MIDI) {
+	m.dispatcher.Attach(eventChannel)
+	m.source = m.dispatcher.AddSource(m.deviceID, m.device)
+
+	port, source := m.port, m.source
+	m.wg.Add(1)
+	profiling.Go(profiling.RoleCapture, contracts.BackendSerial, m.device.Name, func() { m.read(port, source) })
+	m.logger.Info("Serial MIDI capture started", m.logger.Field().String("port", m.device.Name))
+}
+
+// read parses the byte stream of port into messages for source until the port is closed.
+// Timestamps are milliseconds since capture started, unless another clock is selected with
+// contracts.WithTimestampClock.
+func (m *ClientMid) read(port serial.Port, source *dispatch.Source) {
+	defer m.wg.Done()
+	defer source.Recover()
+
+	start := time.Now()
+	timestamp := func() uint64 { return m.dispatcher.ArrivalTimestamp(uint64(time.Since(start).Milliseconds())) }
+	parser := &midistream.Parser{
+		OnMessage: func(event contracts.MIDI) {
+			event.Timestamp = timestamp()
+			source.Dispatch(event)
+		},
+		OnSysEx: func(data []byte) {
+			source.DispatchSysEx(contracts.SysExEvent{Timestamp: timestamp(), Data: data})
+		},
+		OnError:  source.Malformed,
+		MaxSysEx: m.dispatcher.MaxSysEx(),
+	}
+
+	buf := make([]byte, 256)
+	for {
+		n, err := port.Read(buf)
+		if err != nil {
+			m.mu.Lock()
+			capturing := m.source == source
+			m.mu.Unlock()
+			if !capturing {
+				return
+			}
+			err = portError(err)
+			if m.dispatcher.Logging() {
+				m.logger.Error("Serial MIDI port read failed", m.logger.Field().Error("error", err))
+			}
+			source.Lost(err)
+			m.drop(port, source)
+			return
+		}
+		parser.Write(buf[:n])
+	}
+}
+
+// drop closes a port that failed while capturing, so it can be selected again once it is
+// back. The event channel stays attached for the capture to resume on the next selection.
+func (m *ClientMid) drop(port serial.Port, source *dispatch.Source) {
+	m.mu.Lock()
+	if m.source == source {
+		m.source = nil
+	}
+	if m.port == port {
+		m.port.Close()
+		m.port = nil
+	}
+	m.mu.Unlock()
+	source.Close()
+}
+
+// SetMIDIEventFilter replaces the event filter; it takes effect for the next received message.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
+}
+
+// Health reports the state of the selected port together with the dispatcher counters.
+func (m *ClientMid) Health() contracts.Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := m.dispatcher.Health()
+	health.Connected = m.port != nil
+	health.DeviceID = m.deviceID
+	health.Device = m.device
+	health.Diagnostics = map[string]string{
+		"baud_rate": fmt.Sprint(m.config.BaudRate),
+	}
+	return health
+}
+
+// Stats reports the event traffic statistics of the selected port.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// Stop ends capture and closes the selected port.
+func (m *ClientMid) Stop() error {
+	var err error
+	m.stopOnce.Do(func() {
+		m.logger.Info("Stopping serial MIDI capture")
+		m.mu.Lock()
+		port, source := m.port, m.source
+		m.port, m.source = nil, nil
+		m.mu.Unlock()
+
+		m.dispatcher.Detach()
+		if port != nil {
+			// Closing the port unblocks the reading goroutine.
+			err = port.Close()
+		}
+		m.wg.Wait()
+		if source != nil {
+			source.Close()
+		}
+	})
+	return err
+}
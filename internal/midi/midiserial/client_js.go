@@ -0,0 +1,20 @@
+//go:build js
+// +build js
+
+package midiserial
+
+import (
+	"errors"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// ErrSerialUnsupported is returned in js/wasm builds, where browsers give no access to
+// serial ports through this package.
+var ErrSerialUnsupported = errors.New("serial MIDI ports are not supported in js/wasm builds")
+
+// NewMIDIClient reports ErrSerialUnsupported; the serial backend needs system access
+// browsers do not give.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	return nil, ErrSerialUnsupported
+}
@@ -0,0 +1,211 @@
+// Package midistream parses the MIDI 1.0 byte stream of transports that carry raw MIDI
+// bytes, such as DIN serial links, into complete messages.
+package midistream
+
+import (
+	"fmt"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+const (
+	maxSysEx = 64 * 1024 // Default bound of the size of a SysEx message; longer ones are discarded.
+	maxStray = 256       // Bounds the stray data bytes kept for an error report.
+)
+
+// Parser assembles messages from a MIDI byte stream, handling running status, system
+// real-time bytes interleaved with other messages and SysEx messages. Malformed data is
+// skipped, resynchronizing on the next status byte, and reported to OnError. It is not
+// safe for concurrent use.
+type Parser struct {
+	OnMessage func(event contracts.MIDI)       // Called for every channel, system common and real-time message.
+	OnSysEx   func(data []byte)                // Called for every complete SysEx message, including F0 and F7.
+	OnError   func(data []byte, reason string) // Called with the bytes of every malformed sequence and why they were discarded.
+	Strict    bool                             // Whether EndPacket rejects messages left incomplete at the end of a packet.
+	MaxSysEx  int                              // Longest SysEx message accepted, in bytes, including F0 and F7; 64 KiB when zero.
+
+	status   byte // Running status, or the status of the message being assembled; 0 when none.
+	data     [2]byte
+	count    int    // Data bytes received for the current message.
+	stray    []byte // Data bytes without status being skipped, up to maxStray.
+	inSysEx  bool   // Whether a SysEx message is being assembled.
+	sysex    []byte // SysEx message being assembled.
+	overflow bool   // Whether the current SysEx message exceeded maxSysEx.
+}
+
+// Write parses data, calling the handlers for the messages it completes. Messages may span
+// calls. It never fails.
+func (p *Parser) Write(data []byte) (int, error) {
+	for _, b := range data {
+		p.parse(b)
+	}
+	return len(data), nil
+}
+
+// EndPacket marks the end of a packet on transports that deliver complete messages, such
+// as CoreMIDI and USB-MIDI, and reports the stray data bytes it ended with. In strict
+// mode, a message left incomplete is discarded and reported; otherwise it may be
+// completed by the next packet. SysEx messages may always span packets.
+func (p *Parser) EndPacket() {
+	p.flushStray()
+	if p.Strict && p.count > 0 {
+		p.fail(p.message(), "message 0x%02X truncated at end of packet with %d of %d data bytes", p.status, p.count, DataLength(p.status))
+		p.status, p.count = 0, 0
+	}
+}
+
+// parse handles one byte of the stream.
+func (p *Parser) parse(b byte) {
+	if b < 0x80 || b == 0xF7 || b >= 0xF8 {
+		p.parseData(b)
+		return
+	}
+
+	// Any other status byte ends the message being assembled.
+	p.flushStray()
+	if p.inSysEx {
+		p.fail(p.sysex, "SysEx message interrupted by status 0x%02X", b)
+		p.inSysEx = false
+	}
+	if p.count > 0 {
+		p.fail(p.message(), "message 0x%02X truncated by status 0x%02X with %d of %d data bytes", p.status, b, p.count, DataLength(p.status))
+	}
+
+	p.status, p.count = b, 0
+	switch {
+	case b == 0xF0:
+		p.inSysEx, p.overflow = true, false
+		p.sysex = append(p.sysex[:0], b)
+		p.status = 0
+	case DataLength(b) == 0:
+		p.emit(contracts.MIDI{Command: b})
+		p.status = 0
+	}
+}
+
+// parseData handles a data byte, an end of SysEx or a real-time byte.
+func (p *Parser) parseData(b byte) {
+	switch {
+	case b >= 0xF8:
+		// Real-time messages may appear anywhere, even inside other messages.
+		p.emit(contracts.MIDI{Command: b})
+	case b == 0xF7:
+		if !p.inSysEx {
+			p.flushStray()
+			p.fail([]byte{b}, "end of SysEx without start")
+			return
+		}
+		p.inSysEx = false
+		if p.overflow {
+			p.fail(p.sysex, "SysEx message longer than %d bytes", p.maxSysEx())
+			return
+		}
+		if p.OnSysEx != nil {
+			p.OnSysEx(append(append([]byte(nil), p.sysex...), b))
+		}
+	case p.inSysEx:
+		if len(p.sysex) >= p.maxSysEx()-1 {
+			p.overflow = true
+			return
+		}
+		p.sysex = append(p.sysex, b)
+	case p.status == 0:
+		// A run of stray data bytes is reported once it ends.
+		if len(p.stray) < maxStray {
+			p.stray = append(p.stray, b)
+		}
+	default:
+		p.data[p.count] = b
+		p.count++
+		if p.count < DataLength(p.status) {
+			return
+		}
+		p.emit(contracts.MIDI{Command: p.status, Note: p.data[0], Velocity: p.data[1]})
+		p.data, p.count = [2]byte{}, 0
+		if p.status >= 0xF0 {
+			// System common messages cancel running status.
+			p.status = 0
+		}
+	}
+}
+
+// maxSysEx returns the longest SysEx message accepted.
+func (p *Parser) maxSysEx() int {
+	if p.MaxSysEx > 0 {
+		return p.MaxSysEx
+	}
+	return maxSysEx
+}
+
+// emit calls OnMessage.
+func (p *Parser) emit(event contracts.MIDI) {
+	if p.OnMessage != nil {
+		p.OnMessage(event)
+	}
+}
+
+// message returns the status and data bytes of the message being assembled.
+func (p *Parser) message() []byte {
+	return append([]byte{p.status}, p.data[:p.count]...)
+}
+
+// flushStray reports the pending run of stray data bytes, if any.
+func (p *Parser) flushStray() {
+	if len(p.stray) == 0 {
+		return
+	}
+	p.fail(p.stray, "%d data bytes without status", len(p.stray))
+	p.stray = p.stray[:0]
+}
+
+// fail calls OnError with a copy of the bytes of a malformed sequence and a description
+// of the problem.
+func (p *Parser) fail(data []byte, format string, args ...any) {
+	if p.OnError != nil {
+		p.OnError(append([]byte(nil), data...), fmt.Sprintf(format, args...))
+	}
+}
+
+// DataLength returns the number of data bytes following a status byte.
+func DataLength(status byte) int {
+	switch status & 0xF0 {
+	case 0xC0, 0xD0:
+		return 1
+	case 0xF0:
+		switch status {
+		case 0xF1, 0xF3:
+			return 1
+		case 0xF2:
+			return 2
+		default:
+			return 0
+		}
+	default:
+		return 2
+	}
+}
+
+// Encode returns the bytes of a channel, system common or realtime message, for sending.
+// Events whose command is not a status byte, or is a SysEx delimiter, cannot be encoded
+// and return an error wrapping contracts.ErrMalformedMessage.
+func Encode(event contracts.MIDI) ([]byte, error) {
+	if event.Command < 0x80 || event.Command == 0xF0 || event.Command == 0xF7 {
+		return nil, fmt.Errorf("%w: 0x%02X is not the status of a short message", contracts.ErrMalformedMessage, event.Command)
+	}
+	data := []byte{event.Command, event.Note & 0x7F, event.Velocity & 0x7F}
+	return data[:1+DataLength(event.Command)], nil
+}
+
+// CheckSysEx reports whether data is a complete SysEx message, for sending: 0xF0, data
+// bytes and 0xF7. Other data returns an error wrapping contracts.ErrMalformedMessage.
+func CheckSysEx(data []byte) error {
+	if len(data) < 2 || data[0] != 0xF0 || data[len(data)-1] != 0xF7 {
+		return fmt.Errorf("%w: SysEx messages start with 0xF0 and end with 0xF7", contracts.ErrMalformedMessage)
+	}
+	for _, b := range data[1 : len(data)-1] {
+		if b >= 0x80 {
+			return fmt.Errorf("%w: status byte 0x%02X within a SysEx message", contracts.ErrMalformedMessage, b)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !usb || !cgo || nomidihw
+// +build !usb !cgo nomidihw
+
+package midiusb
+
+import "github.com/leandrodaf/midi/sdk/contracts"
+
+// NewMIDIClient reports ErrUSBUnsupported; the USB backend needs libusb through cgo, and
+// builds with the nomidihw tag leave it out.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	return nil, ErrUSBUnsupported
+}
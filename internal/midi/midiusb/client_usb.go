@@ -0,0 +1,475 @@
+//go:build usb && cgo && !nomidihw
+// +build usb,cgo,!nomidihw
+
+package midiusb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/google/gousb"
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/internal/midi/retry"
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// usbDevice locates the MIDI streaming interface of a USB device.
+type usbDevice struct {
+	info      contracts.DeviceInfo
+	bus       int
+	address   int
+	config    int
+	iface     int
+	alternate int
+	endpoint  int // Number of the bulk IN endpoint.
+	packet    int // Maximum packet size of the endpoint.
+}
+
+// ClientMid implements contracts.ClientMIDI by talking the USB MIDI class protocol through
+// libusb, bypassing the MIDI stack of the operating system. The interface is claimed
+// exclusively, detaching the kernel driver where needed. All virtual cables of a device
+// are merged into one stream.
+type ClientMid struct {
+	logger     contracts.Logger
+	usb        *gousb.Context
+	dispatcher *dispatch.Dispatcher // Filters events and delivers them to the event channel.
+	openRetry  *contracts.OpenRetry // Retry policy for opening a device; nil disables retries.
+	mu         sync.Mutex           // Mutex for thread safety on shared resources.
+	devices    []usbDevice          // Devices of the last listing.
+	deviceID   int                  // ID of the selected device, or -1.
+	selected   usbDevice            // Location of the selected device.
+	device     *gousb.Device        // Open device; nil when none is selected.
+	config     *gousb.Config
+	iface      *gousb.Interface
+	endpoint   *gousb.InEndpoint
+	source     *dispatch.Source   // Dispatcher entry of the device while capturing.
+	cancel     context.CancelFunc // Cancels the reading goroutine.
+	wg         sync.WaitGroup     // WaitGroup for the reading goroutine.
+	stopOnce   sync.Once          // Ensures Stop() is executed only once.
+}
+
+// NewMIDIClient creates a USB MIDI client with its own libusb context.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	options.Logger.Info("USB MIDI client created")
+	return &ClientMid{
+		logger:     options.Logger,
+		usb:        gousb.NewContext(),
+		dispatcher: dispatch.New(contracts.BackendUSB, options),
+		openRetry:  options.OpenRetry,
+		deviceID:   -1,
+	}, nil
+}
+
+// ListDevices lists the USB devices with a MIDI streaming interface.
+func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
+	devices, err := m.list()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]contracts.DeviceInfo, len(devices))
+	for i, device := range devices {
+		infos[i] = device.info
+	}
+	return infos, nil
+}
+
+// list enumerates the MIDI streaming interfaces and remembers them for SelectDevice.
+func (m *ClientMid) list() ([]usbDevice, error) {
+	var devices []usbDevice
+	opened, err := m.usb.OpenDevices(func(desc *gousb.DeviceDesc) bool {
+		location, ok := streamingInterface(desc)
+		if ok {
+			devices = append(devices, location)
+		}
+		return ok
+	})
+	// Opening is only needed for the names; devices that failed to open are still listed.
+	for _, device := range opened {
+		for i := range devices {
+			if devices[i].bus == device.Desc.Bus && devices[i].address == device.Desc.Address {
+				devices[i].info.Manufacturer, _ = device.Manufacturer()
+				if product, err := device.Product(); err == nil && product != "" {
+					devices[i].info.Name = product
+					devices[i].info.EntityName = product
+				}
+			}
+		}
+		device.Close()
+	}
+	if err != nil && m.dispatcher.Logging() {
+		m.logger.Warn("Failed to open some USB devices", m.logger.Field().Error("error", err))
+	}
+
+	m.mu.Lock()
+	m.devices = devices
+	m.mu.Unlock()
+
+	if len(devices) == 0 {
+		m.logger.Warn("No USB MIDI devices found")
+		return nil, contracts.ErrNoDevices
+	}
+	return devices, nil
+}
+
+// streamingInterface finds the first MIDI streaming interface of a device that has a bulk
+// IN endpoint.
+func streamingInterface(desc *gousb.DeviceDesc) (usbDevice, bool) {
+	for _, config := range desc.Configs {
+		for _, iface := range config.Interfaces {
+			for _, setting := range iface.AltSettings {
+				if setting.Class != classAudio || setting.SubClass != subclassStreaming {
+					continue
+				}
+				for _, endpoint := range setting.Endpoints {
+					if endpoint.Direction != gousb.EndpointDirectionIn || endpoint.TransferType != gousb.TransferTypeBulk {
+						continue
+					}
+					name := fmt.Sprintf("USB MIDI %s:%s", desc.Vendor, desc.Product)
+					return usbDevice{
+						info:      contracts.DeviceInfo{Name: name, EntityName: name},
+						bus:       desc.Bus,
+						address:   desc.Address,
+						config:    config.Number,
+						iface:     setting.Number,
+						alternate: setting.Alternate,
+						endpoint:  endpoint.Number,
+						packet:    endpoint.MaxPacketSize,
+					}, true
+				}
+			}
+		}
+	}
+	return usbDevice{}, false
+}
+
+// locate returns the device with an ID from the last listing, listing devices if needed.
+func (m *ClientMid) locate(deviceID int) (usbDevice, error) {
+	m.mu.Lock()
+	devices := m.devices
+	m.mu.Unlock()
+
+	if devices == nil {
+		var err error
+		if devices, err = m.list(); err != nil {
+			return usbDevice{}, err
+		}
+	}
+	if deviceID < 0 || deviceID >= len(devices) {
+		return usbDevice{}, fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	return devices[deviceID], nil
+}
+
+// DeviceCapabilities reports what a USB MIDI device supports through this backend: the
+// merged input of its cables, SysEx included, without driver timestamps.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	if _, err := m.locate(deviceID); err != nil {
+		return contracts.DeviceCapabilities{}, err
+	}
+	return contracts.DeviceCapabilities{SysExIn: true, InputPorts: 1}, nil
+}
+
+// ListOutputDevices reports contracts.ErrOutputUnsupported; only the input endpoints of USB devices are claimed.
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	return nil, fmt.Errorf("%w: usb backend", contracts.ErrOutputUnsupported)
+}
+
+// SelectOutputDevice reports contracts.ErrOutputUnsupported.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	return fmt.Errorf("%w: usb backend", contracts.ErrOutputUnsupported)
+}
+
+// Send reports contracts.ErrOutputUnsupported.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	return fmt.Errorf("%w: usb backend", contracts.ErrOutputUnsupported)
+}
+
+// SendSysEx reports contracts.ErrOutputUnsupported.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	return fmt.Errorf("%w: usb backend", contracts.ErrOutputUnsupported)
+}
+
+// CreateVirtualSource reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	return nil, fmt.Errorf("%w: usb backend", contracts.ErrVirtualUnsupported)
+}
+
+// CreateVirtualDestination reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	return nil, fmt.Errorf("%w: usb backend", contracts.ErrVirtualUnsupported)
+}
+
+// SelectDevice opens a USB MIDI device and claims its streaming interface, retrying
+// according to the open retry policy. A previously selected device is released. If the
+// capture of a lost device is still running, it continues on the new device.
+func (m *ClientMid) SelectDevice(deviceID int) error {
+	location, err := m.locate(deviceID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.source != nil {
+		return fmt.Errorf("%w from %s", contracts.ErrCaptureRunning, m.selected.info.Name)
+	}
+	m.release()
+
+	err = retry.Do(m.openRetry, m.logger, "Open", func() error {
+		return usbError(m.open(location))
+	})
+	if err != nil {
+		m.logger.Error("Failed to open USB MIDI device", m.logger.Field().Error("error", err))
+		return fmt.Errorf("error opening USB MIDI device %d: %w", deviceID, err)
+	}
+
+	m.deviceID = deviceID
+	m.selected = location
+	m.logger.Info("USB MIDI device opened", m.logger.Field().String("device", location.info.Name))
+	m.dispatcher.DeviceSelected(deviceID, location.info)
+	if eventChannel := m.dispatcher.Channel(); eventChannel != nil {
+		m.startCapture(eventChannel)
+	}
+	return nil
+}
+
+// open opens the device at location and claims its streaming interface. m.mu must be held.
+func (m *ClientMid) open(location usbDevice) error {
+	opened, err := m.usb.OpenDevices(func(desc *gousb.DeviceDesc) bool {
+		return desc.Bus == location.bus && desc.Address == location.address
+	})
+	if len(opened) == 0 {
+		if err == nil {
+			err = contracts.ErrDeviceDisconnected
+		}
+		return err
+	}
+	for _, extra := range opened[1:] {
+		extra.Close()
+	}
+
+	device := opened[0]
+	if err := device.SetAutoDetach(true); err != nil {
+		device.Close()
+		return err
+	}
+	config, err := device.Config(location.config)
+	if err != nil {
+		device.Close()
+		return err
+	}
+	iface, err := config.Interface(location.iface, location.alternate)
+	if err != nil {
+		config.Close()
+		device.Close()
+		return err
+	}
+	endpoint, err := iface.InEndpoint(location.endpoint)
+	if err != nil {
+		iface.Close()
+		config.Close()
+		device.Close()
+		return err
+	}
+
+	m.device, m.config, m.iface, m.endpoint = device, config, iface, endpoint
+	return nil
+}
+
+// release closes the open device, if any. m.mu must be held.
+func (m *ClientMid) release() {
+	if m.device == nil {
+		return
+	}
+	m.iface.Close()
+	m.config.Close()
+	m.device.Close()
+	m.device, m.config, m.iface, m.endpoint = nil, nil, nil, nil
+}
+
+// usbError maps libusb errors to the errors of contracts, and permission errors to
+// fs.ErrPermission.
+func usbError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gousb.ErrorBusy):
+		return fmt.Errorf("%w: %v", contracts.ErrDeviceBusy, err)
+	case errors.Is(err, gousb.ErrorNoDevice):
+		return fmt.Errorf("%w: %v", contracts.ErrDeviceDisconnected, err)
+	case errors.Is(err, gousb.ErrorNotFound):
+		return fmt.Errorf("%w: %v", contracts.ErrInvalidDevice, err)
+	case errors.Is(err, gousb.ErrorAccess):
+		return fmt.Errorf("%w: %v", fs.ErrPermission, err)
+	default:
+		return err
+	}
+}
+
+// StartCapture reads event packets from the selected device and delivers their messages
+// to eventChannel.
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if m.endpoint == nil {
+		m.logger.Error("No MIDI device selected")
+		return contracts.ErrNoDeviceSelected
+	}
+	if m.source != nil {
+		m.logger.Warn("Capture already started")
+		return contracts.ErrCaptureStarted
+	}
+	m.startCapture(eventChannel)
+	return nil
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
+}
+
+// startCapture starts reading the selected device. The caller must hold m.mu.
+func (m *ClientMid) startCapture(eventChannel chan contracts.MIDI) {
+	m.dispatcher.Attach(eventChannel)
+	m.source = m.dispatcher.AddSource(m.deviceID, m.selected.info)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	endpoint, source, packet := m.endpoint, m.source, m.selected.packet
+	m.wg.Add(1)
+	profiling.Go(profiling.RoleCapture, contracts.BackendUSB, m.selected.info.Name, func() { m.read(ctx, endpoint, packet, source) })
+	m.logger.Info("USB MIDI capture started", m.logger.Field().String("device", m.selected.info.Name))
+}
+
+// read decodes the event packets of endpoint for source until ctx is cancelled or the
+// device fails. Timestamps are milliseconds since capture started, unless another clock
+// is selected with contracts.WithTimestampClock.
+func (m *ClientMid) read(ctx context.Context, endpoint *gousb.InEndpoint, packet int, source *dispatch.Source) {
+	defer m.wg.Done()
+	defer source.Recover()
+
+	start := time.Now()
+	timestamp := func() uint64 { return m.dispatcher.ArrivalTimestamp(uint64(time.Since(start).Milliseconds())) }
+	decoder := newPacketDecoder(midistream.Parser{
+		OnMessage: func(event contracts.MIDI) {
+			event.Timestamp = timestamp()
+			source.Dispatch(event)
+		},
+		OnSysEx: func(data []byte) {
+			source.DispatchSysEx(contracts.SysExEvent{Timestamp: timestamp(), Data: data})
+		},
+		OnError:  source.Malformed,
+		Strict:   m.dispatcher.Strict(),
+		MaxSysEx: m.dispatcher.MaxSysEx(),
+	})
+
+	buf := make([]byte, max(packet, 64))
+	for {
+		n, err := endpoint.ReadContext(ctx, buf)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			err = usbError(err)
+			if m.dispatcher.Logging() {
+				m.logger.Error("USB MIDI read failed", m.logger.Field().Error("error", err))
+			}
+			source.Lost(err)
+			m.drop(source)
+			return
+		}
+		decoder.decode(buf[:n])
+	}
+}
+
+// drop releases a device that failed while capturing, so it can be selected again once it
+// is back. The event channel stays attached for the capture to resume on the next
+// selection.
+func (m *ClientMid) drop(source *dispatch.Source) {
+	m.mu.Lock()
+	if m.source == source {
+		m.source = nil
+		m.cancel()
+		m.cancel = nil
+		m.release()
+	}
+	m.mu.Unlock()
+	source.Close()
+}
+
+// SetMIDIEventFilter replaces the event filter; it takes effect for the next received message.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
+}
+
+// Health reports the state of the selected device together with the dispatcher counters.
+func (m *ClientMid) Health() contracts.Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := m.dispatcher.Health()
+	health.Connected = m.device != nil
+	health.DeviceID = m.deviceID
+	health.Device = m.selected.info
+	if m.device != nil {
+		health.Diagnostics = map[string]string{
+			"bus":       fmt.Sprint(m.selected.bus),
+			"address":   fmt.Sprint(m.selected.address),
+			"interface": fmt.Sprint(m.selected.iface),
+			"endpoint":  fmt.Sprint(m.selected.endpoint),
+		}
+	}
+	return health
+}
+
+// Stats reports the event traffic statistics of the selected device.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// Stop ends capture, releases the device and closes the libusb context.
+func (m *ClientMid) Stop() error {
+	var err error
+	m.stopOnce.Do(func() {
+		m.logger.Info("Stopping USB MIDI capture")
+		m.mu.Lock()
+		cancel, source := m.cancel, m.source
+		m.cancel, m.source = nil, nil
+		m.mu.Unlock()
+
+		m.dispatcher.Detach()
+		if cancel != nil {
+			cancel()
+		}
+		m.wg.Wait()
+		if source != nil {
+			source.Close()
+		}
+
+		m.mu.Lock()
+		m.release()
+		m.mu.Unlock()
+		err = m.usb.Close()
+	})
+	return err
+}
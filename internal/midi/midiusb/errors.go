@@ -0,0 +1,6 @@
+package midiusb
+
+import "errors"
+
+// ErrUSBUnsupported is returned when the package was built without libusb support.
+var ErrUSBUnsupported = errors.New("USB MIDI backend requires building with cgo and the usb tag (go build -tags usb)")
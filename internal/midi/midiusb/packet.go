@@ -0,0 +1,61 @@
+package midiusb
+
+import (
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+)
+
+// USB class codes of MIDI interfaces.
+const (
+	classAudio        = 0x01
+	subclassStreaming = 0x03
+	usbMIDIPacketSize = 4
+	usbMIDICableCount = 16
+)
+
+// packetDecoder turns 4-byte USB-MIDI event packets into messages. Each packet carries a
+// cable number and a code index number (CIN) telling how many of its three MIDI bytes are
+// used; the bytes of every cable are an independent MIDI stream.
+type packetDecoder struct {
+	cables [usbMIDICableCount]midistream.Parser
+}
+
+// newPacketDecoder creates a decoder parsing every cable with a copy of parser, whose
+// handlers receive the messages and errors of all cables.
+func newPacketDecoder(parser midistream.Parser) *packetDecoder {
+	d := &packetDecoder{}
+	for i := range d.cables {
+		d.cables[i] = midistream.Parser{
+			OnMessage: parser.OnMessage,
+			OnSysEx:   parser.OnSysEx,
+			OnError:   parser.OnError,
+			Strict:    parser.Strict,
+			MaxSysEx:  parser.MaxSysEx,
+		}
+	}
+	return d
+}
+
+// decode parses a buffer of consecutive event packets. A trailing partial packet is ignored.
+func (d *packetDecoder) decode(data []byte) {
+	for ; len(data) >= usbMIDIPacketSize; data = data[usbMIDIPacketSize:] {
+		cable, cin := data[0]>>4, data[0]&0x0F
+		if n := cinLength(cin); n > 0 {
+			d.cables[cable].Write(data[1 : 1+n])
+			d.cables[cable].EndPacket()
+		}
+	}
+}
+
+// cinLength returns the number of MIDI bytes carried by a packet with the given code index number.
+func cinLength(cin byte) int {
+	switch cin {
+	case 0x2, 0x6, 0xC, 0xD: // Two-byte system common, SysEx end with two bytes, program change, channel pressure.
+		return 2
+	case 0x3, 0x4, 0x7, 0x8, 0x9, 0xA, 0xB, 0xE: // Three-byte system common, SysEx start or end, channel messages.
+		return 3
+	case 0x5, 0xF: // Single-byte system common or SysEx end with one byte, single byte.
+		return 1
+	default: // Reserved for future extensions.
+		return 0
+	}
+}
@@ -0,0 +1,526 @@
+//go:build js && wasm
+// +build js,wasm
+
+package midiweb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// backendName identifies this backend in health reports and profiles.
+const backendName = "webmidi"
+
+// port is a MIDI port of the browser listed as a device.
+type port struct {
+	info contracts.DeviceInfo
+	id   string // ID of the port, stable while the page is open.
+}
+
+// capture is the state of the capture of the input port.
+type capture struct {
+	source    *dispatch.Source
+	parser    midistream.Parser
+	timestamp uint64 // Timestamp of the message being parsed.
+}
+
+// ClientMid implements contracts.ClientMIDI on the Web MIDI API of the browser, for
+// programs compiled to WebAssembly (GOOS=js GOARCH=wasm). The inputs and outputs of the
+// MIDIAccess object are listed as devices, and messages arrive through the event loop of
+// the page, so its methods other than NewMIDIClient may be called from JavaScript
+// callbacks.
+type ClientMid struct {
+	logger      contracts.Logger
+	access      js.Value // MIDIAccess granted by the browser.
+	performance js.Value // The performance object, the clock of the message timestamps; undefined if missing.
+	dispatcher  *dispatch.Dispatcher
+
+	onMessage     js.Func // Handler of the messages of the input port.
+	onStateChange js.Func // Handler of the ports being connected and disconnected.
+
+	mu       sync.Mutex // Mutex for thread safety on shared resources.
+	inputs   []port     // Input ports of the last listing.
+	outputs  []port     // Output ports of the last listing.
+	deviceID int        // ID of the selected input device, or -1.
+	device   port       // The selected input port.
+	input    js.Value   // MIDIInput of the selected device.
+	capture  *capture   // Capture of the input port; nil when not capturing.
+	outputID int        // ID of the selected output device, or -1.
+	output   js.Value   // MIDIOutput of the selected output device.
+
+	notifyMu     sync.Mutex     // Guards the notifiers.
+	notifiers    map[int]func() // Callbacks of NotifyDeviceChanges, by key.
+	lastNotifier int            // Key of the last registered notifier.
+	stopOnce     sync.Once
+}
+
+// NewMIDIClient requests access to the MIDI devices of the browser, which may prompt the
+// user for permission, SysEx access included if options.WebMIDIConfig asks for it. It
+// blocks until the user answers, so it must be called from a goroutine rather than from a
+// JavaScript callback.
+func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	navigator := js.Global().Get("navigator")
+	if navigator.Type() != js.TypeObject || navigator.Get("requestMIDIAccess").Type() != js.TypeFunction {
+		return nil, ErrWebMIDIUnavailable
+	}
+	sysex := options.WebMIDIConfig != nil && options.WebMIDIConfig.SysEx
+	access, err := await(navigator.Call("requestMIDIAccess", map[string]any{"sysex": sysex}))
+	if err != nil {
+		options.Logger.Error("Web MIDI access denied", options.Logger.Field().Error("error", err))
+		return nil, fmt.Errorf("error requesting Web MIDI access: %w", err)
+	}
+
+	m := &ClientMid{
+		logger:      options.Logger,
+		access:      access,
+		performance: js.Global().Get("performance"),
+		dispatcher:  dispatch.New(backendName, options),
+		deviceID:    -1,
+		outputID:    -1,
+	}
+	m.onMessage = js.FuncOf(func(this js.Value, args []js.Value) any {
+		m.receive(argument(args))
+		return nil
+	})
+	m.onStateChange = js.FuncOf(func(this js.Value, args []js.Value) any {
+		m.stateChanged(argument(args).Get("port"))
+		return nil
+	})
+	access.Set("onstatechange", m.onStateChange)
+
+	options.Logger.Info("Web MIDI client created", options.Logger.Field().Bool("sysex", access.Get("sysexEnabled").Truthy()))
+	return m, nil
+}
+
+// ListDevices lists the MIDI inputs of the browser.
+func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
+	inputs := ports(m.access.Get("inputs"))
+	m.mu.Lock()
+	m.inputs = inputs
+	m.mu.Unlock()
+
+	if len(inputs) == 0 {
+		m.logger.Warn("No Web MIDI inputs found")
+		return nil, contracts.ErrNoDevices
+	}
+	return infos(inputs), nil
+}
+
+// ListOutputDevices lists the MIDI outputs of the browser.
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	outputs := ports(m.access.Get("outputs"))
+	m.mu.Lock()
+	m.outputs = outputs
+	m.mu.Unlock()
+
+	if len(outputs) == 0 {
+		m.logger.Warn("No Web MIDI outputs found")
+		return nil, contracts.ErrNoDevices
+	}
+	return infos(outputs), nil
+}
+
+// ports lists the ports of a MIDIInputMap or MIDIOutputMap, in the order of the browser.
+func ports(portMap js.Value) []port {
+	var ports []port
+	each := js.FuncOf(func(this js.Value, args []js.Value) any {
+		p := argument(args)
+		ports = append(ports, port{
+			info: contracts.DeviceInfo{
+				Name:          p.Get("name").String(),
+				Manufacturer:  p.Get("manufacturer").String(),
+				EntityName:    p.Get("id").String(),
+				UniqueID:      p.Get("id").String(),
+				DriverVersion: optionalString(p.Get("version")),
+				Direction:     direction(p.Get("type").String()),
+				Ports:         1,
+				Offline:       p.Get("state").String() == "disconnected",
+			},
+			id: p.Get("id").String(),
+		})
+		return nil
+	})
+	defer each.Release()
+	portMap.Call("forEach", each)
+	return ports
+}
+
+// direction returns the direction of a port from its type, "input" or "output".
+func direction(portType string) contracts.DeviceDirection {
+	switch portType {
+	case "input":
+		return contracts.DirectionInput
+	case "output":
+		return contracts.DirectionOutput
+	default:
+		return 0
+	}
+}
+
+// optionalString returns a string attribute of a port, or "" when the browser leaves it
+// null.
+func optionalString(v js.Value) string {
+	if v.Type() != js.TypeString {
+		return ""
+	}
+	return v.String()
+}
+
+// infos returns the device information of ports.
+func infos(ports []port) []contracts.DeviceInfo {
+	infos := make([]contracts.DeviceInfo, len(ports))
+	for i, p := range ports {
+		infos[i] = p.info
+	}
+	return infos
+}
+
+// locate returns the port with an ID from the last listing of list, listing ports if
+// needed.
+func (m *ClientMid) locate(deviceID int, listed func() []port, list func() ([]contracts.DeviceInfo, error)) (port, error) {
+	m.mu.Lock()
+	ports := listed()
+	m.mu.Unlock()
+
+	if ports == nil {
+		if _, err := list(); err != nil {
+			return port{}, err
+		}
+		m.mu.Lock()
+		ports = listed()
+		m.mu.Unlock()
+	}
+	if deviceID < 0 || deviceID >= len(ports) {
+		return port{}, fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	return ports[deviceID], nil
+}
+
+// DeviceCapabilities reports what a Web MIDI input supports: one input and the output of
+// the same name, if any, with the timestamps of the browser, and SysEx if the user granted
+// it.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	p, err := m.locate(deviceID, func() []port { return m.inputs }, m.ListDevices)
+	if err != nil {
+		return contracts.DeviceCapabilities{}, err
+	}
+	sysex := m.access.Get("sysexEnabled").Truthy()
+	capabilities := contracts.DeviceCapabilities{SysExIn: sysex, SysExOut: sysex, Timestamps: true, InputPorts: 1}
+	for _, output := range ports(m.access.Get("outputs")) {
+		if output.info.Name == p.info.Name && output.info.Manufacturer == p.info.Manufacturer {
+			capabilities.OutputPorts = 1
+		}
+	}
+	return capabilities, nil
+}
+
+// SelectDevice selects an input of the browser. A previously selected input is closed.
+// If the capture of a lost device is still running, it continues on the new device.
+func (m *ClientMid) SelectDevice(deviceID int) error {
+	p, err := m.locate(deviceID, func() []port { return m.inputs }, m.ListDevices)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.capture != nil {
+		return fmt.Errorf("%w from %s", contracts.ErrCaptureRunning, m.device.info.Name)
+	}
+	input := m.access.Get("inputs").Call("get", p.id)
+	if input.Type() != js.TypeObject {
+		return fmt.Errorf("%w: %s", contracts.ErrDeviceDisconnected, p.info.Name)
+	}
+	m.closeInput()
+
+	m.deviceID, m.device, m.input = deviceID, p, input
+	m.logger.Info("Web MIDI input selected", m.logger.Field().String("device", p.info.Name))
+	m.dispatcher.DeviceSelected(deviceID, p.info)
+	if eventChannel := m.dispatcher.Channel(); eventChannel != nil {
+		m.startCapture(eventChannel)
+	}
+	return nil
+}
+
+// SelectOutputDevice selects an output of the browser as the destination of Send.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	p, err := m.locate(deviceID, func() []port { return m.outputs }, m.ListOutputDevices)
+	if err != nil {
+		return err
+	}
+	output := m.access.Get("outputs").Call("get", p.id)
+	if output.Type() != js.TypeObject {
+		return fmt.Errorf("%w: %s", contracts.ErrDeviceDisconnected, p.info.Name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outputID, m.output = deviceID, output
+	m.logger.Info("Web MIDI output selected", m.logger.Field().String("device", p.info.Name))
+	return nil
+}
+
+// Send sends a channel, system common or real-time message to the output device at once.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	data, err := midistream.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.outputID < 0 {
+		return contracts.ErrNoOutputDevice
+	}
+	return send(m.output, data)
+}
+
+// SendSysEx sends a complete SysEx message, including F0 and F7, to the output device at
+// once. Browsers only send SysEx messages with SysEx access, requested with
+// contracts.WebMIDIConfig; without it, it reports contracts.ErrSysExUnsupported.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	if err := midistream.CheckSysEx(data); err != nil {
+		return err
+	}
+	if !m.access.Get("sysexEnabled").Truthy() {
+		return fmt.Errorf("%w: Web MIDI SysEx access was not granted", contracts.ErrSysExUnsupported)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.outputID < 0 {
+		return contracts.ErrNoOutputDevice
+	}
+	return send(m.output, data)
+}
+
+// send sends data to output, converting the exceptions of the browser to errors.
+func send(output js.Value, data []byte) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			if exception, ok := value.(js.Error); ok {
+				err = fmt.Errorf("error sending Web MIDI message: %w", jsError(exception.Value))
+				return
+			}
+			panic(value)
+		}
+	}()
+	array := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(array, data)
+	output.Call("send", array)
+	return nil
+}
+
+// CreateVirtualSource reports contracts.ErrVirtualUnsupported; Web MIDI cannot create ports.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	return nil, fmt.Errorf("%w: Web MIDI backend", contracts.ErrVirtualUnsupported)
+}
+
+// CreateVirtualDestination reports contracts.ErrVirtualUnsupported.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	return nil, fmt.Errorf("%w: Web MIDI backend", contracts.ErrVirtualUnsupported)
+}
+
+// StartCapture delivers the messages of the selected input to eventChannel.
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if m.deviceID < 0 {
+		m.logger.Error("No MIDI device selected")
+		return contracts.ErrNoDeviceSelected
+	}
+	if m.capture != nil {
+		m.logger.Warn("Capture already started")
+		return contracts.ErrCaptureStarted
+	}
+	m.startCapture(eventChannel)
+	return nil
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
+}
+
+// startCapture subscribes to the messages of the selected input, which opens it. The
+// caller must hold m.mu.
+func (m *ClientMid) startCapture(eventChannel chan contracts.MIDI) {
+	m.dispatcher.Attach(eventChannel)
+	c := &capture{source: m.dispatcher.AddSource(m.deviceID, m.device.info)}
+	c.parser = midistream.Parser{
+		OnMessage: func(event contracts.MIDI) {
+			event.Timestamp = c.timestamp
+			c.source.Dispatch(event)
+		},
+		OnSysEx: func(data []byte) {
+			c.source.DispatchSysEx(contracts.SysExEvent{Timestamp: c.timestamp, Data: data})
+		},
+		OnError:  c.source.Malformed,
+		Strict:   m.dispatcher.Strict(),
+		MaxSysEx: m.dispatcher.MaxSysEx(),
+	}
+	m.capture = c
+	m.input.Set("onmidimessage", m.onMessage)
+	m.logger.Info("Web MIDI capture started", m.logger.Field().String("device", m.device.info.Name))
+}
+
+// receive parses a MIDIMessageEvent of the input port. Each event carries one complete
+// message. Timestamps are Unix nanoseconds of arrival, unless another clock is selected
+// with contracts.WithTimestampClock; HardwareClock uses the timestamp the browser gave the
+// message when it received it.
+func (m *ClientMid) receive(event js.Value) {
+	m.mu.Lock()
+	c := m.capture
+	m.mu.Unlock()
+	if c == nil {
+		return
+	}
+	defer c.source.Recover()
+
+	native := uint64(time.Now().UnixNano())
+	hardware := contracts.MonotonicNow()
+	if m.performance.Type() == js.TypeObject {
+		if age := m.performance.Call("now").Float() - event.Get("timeStamp").Float(); age > 0 {
+			hardware -= uint64(age * float64(time.Millisecond))
+		}
+	}
+	c.timestamp = m.dispatcher.DriverTimestamp(hardware, native)
+
+	array := event.Get("data")
+	data := make([]byte, array.Get("length").Int())
+	js.CopyBytesToGo(data, array)
+	c.parser.Write(data)
+	c.parser.EndPacket()
+}
+
+// stateChanged forgets the selected input or output when its port is disconnected, and
+// reports the input device lost, then calls the notifiers of NotifyDeviceChanges. The
+// event channel stays attached for the capture to resume on the next selection.
+func (m *ClientMid) stateChanged(p js.Value) {
+	if p.Get("state").String() == "disconnected" {
+		id, kind := p.Get("id").String(), p.Get("type").String()
+		m.mu.Lock()
+		var c *capture
+		if kind == "input" && m.deviceID >= 0 && id == m.device.id {
+			c, m.capture = m.capture, nil
+			m.closeInput()
+			m.deviceID = -1
+		}
+		if kind == "output" && m.outputID >= 0 && id == m.output.Get("id").String() {
+			m.outputID, m.output = -1, js.Undefined()
+		}
+		m.mu.Unlock()
+
+		if c != nil {
+			c.source.Lost(fmt.Errorf("%w: %s", contracts.ErrDeviceDisconnected, p.Get("name").String()))
+			c.source.Close()
+		}
+	}
+
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+	for _, changed := range m.notifiers {
+		changed()
+	}
+}
+
+// NotifyDeviceChanges calls changed whenever the browser reports a port being connected,
+// disconnected, opened or closed, until stop is called.
+func (m *ClientMid) NotifyDeviceChanges(changed func()) (stop func()) {
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+
+	if m.notifiers == nil {
+		m.notifiers = make(map[int]func())
+	}
+	m.lastNotifier++
+	key := m.lastNotifier
+	m.notifiers[key] = changed
+	return func() {
+		m.notifyMu.Lock()
+		defer m.notifyMu.Unlock()
+		delete(m.notifiers, key)
+	}
+}
+
+// closeInput unsubscribes from the selected input and closes it, if any. The caller must
+// hold m.mu.
+func (m *ClientMid) closeInput() {
+	if m.deviceID < 0 {
+		return
+	}
+	m.input.Set("onmidimessage", js.Null())
+	m.input.Call("close")
+	m.input = js.Undefined()
+}
+
+// SetMIDIEventFilter replaces the event filter; it takes effect for the next received message.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
+}
+
+// Health reports the state of the selected input port together with the dispatcher counters.
+func (m *ClientMid) Health() contracts.Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := m.dispatcher.Health()
+	health.DeviceID = m.deviceID
+	if m.deviceID >= 0 {
+		health.Device = m.device.info
+		health.Connected = m.input.Get("state").String() == "connected"
+		health.Diagnostics = map[string]string{
+			"id":         m.device.id,
+			"connection": m.input.Get("connection").String(),
+			"sysex":      fmt.Sprint(m.access.Get("sysexEnabled").Truthy()),
+		}
+	}
+	return health
+}
+
+// Stats reports the event traffic statistics of the selected input port.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// Stop ends capture, closes the selected input and releases the JavaScript callbacks.
+func (m *ClientMid) Stop() error {
+	m.stopOnce.Do(func() {
+		m.logger.Info("Stopping Web MIDI client")
+		m.mu.Lock()
+		c := m.capture
+		m.capture = nil
+		m.closeInput()
+		m.deviceID, m.outputID = -1, -1
+		m.access.Set("onstatechange", js.Null())
+		m.mu.Unlock()
+
+		m.dispatcher.Detach()
+		if c != nil {
+			c.source.Close()
+		}
+		m.onMessage.Release()
+		m.onStateChange.Release()
+	})
+	return nil
+}
@@ -0,0 +1,8 @@
+package midiweb
+
+import "errors"
+
+// ErrWebMIDIUnavailable is returned when the JavaScript environment has no Web MIDI API,
+// such as browsers without support for it, pages not served over HTTPS or localhost, and
+// Node.js.
+var ErrWebMIDIUnavailable = errors.New("Web MIDI API not available (navigator.requestMIDIAccess)")
@@ -0,0 +1,67 @@
+//go:build js && wasm
+// +build js,wasm
+
+package midiweb
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall/js"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// await blocks until promise settles, returning its value or its rejection as an error.
+// It must not be called from a JavaScript callback, which would block the event loop the
+// promise settles on.
+func await(promise js.Value) (js.Value, error) {
+	type result struct {
+		value js.Value
+		err   error
+	}
+	done := make(chan result, 1)
+	onFulfilled := js.FuncOf(func(this js.Value, args []js.Value) any {
+		done <- result{value: argument(args)}
+		return nil
+	})
+	defer onFulfilled.Release()
+	onRejected := js.FuncOf(func(this js.Value, args []js.Value) any {
+		done <- result{err: jsError(argument(args))}
+		return nil
+	})
+	defer onRejected.Release()
+
+	promise.Call("then", onFulfilled, onRejected)
+	r := <-done
+	return r.value, r.err
+}
+
+// argument returns the first argument of a callback, or undefined.
+func argument(args []js.Value) js.Value {
+	if len(args) == 0 {
+		return js.Undefined()
+	}
+	return args[0]
+}
+
+// jsError converts a rejection reason to an error. The DOMExceptions of denied
+// permissions wrap fs.ErrPermission, those of ports opened by another application
+// contracts.ErrDeviceBusy, and other failures of the browser contracts.ErrDriverFailure.
+func jsError(reason js.Value) error {
+	if reason.Type() != js.TypeObject {
+		return fmt.Errorf("%w: Web MIDI: %s", contracts.ErrDriverFailure, reason.String())
+	}
+	name, message := reason.Get("name").String(), reason.Get("message").String()
+	switch name {
+	case "SecurityError", "NotAllowedError":
+		return fmt.Errorf("%w: %s: %s", fs.ErrPermission, name, message)
+	case "NotSupportedError":
+		return fmt.Errorf("%w: %s", ErrWebMIDIUnavailable, message)
+	case "InvalidStateError":
+		return fmt.Errorf("%w: %s", contracts.ErrDeviceDisconnected, message)
+	case "InvalidAccessError":
+		return fmt.Errorf("%w: %s", contracts.ErrDeviceBusy, message)
+	default:
+		return fmt.Errorf("%w: Web MIDI: %s: %s", contracts.ErrDriverFailure, name, message)
+	}
+}
@@ -4,6 +4,7 @@
 package midiwindows
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/leandrodaf/midi/sdk/contracts"
@@ -24,18 +25,88 @@ func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, erro
 // ListDevices logs a warning and returns an error indicating that MIDI functionality is unavailable on this platform.
 func (m *dummyMIDIClient) ListDevices() ([]contracts.DeviceInfo, error) {
 	m.logger.Warn("ListDevices called on dummy MIDI client")
-	return nil, fmt.Errorf("MIDI functionality is not available on this platform")
+	return nil, fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoDevices)
 }
 
 // SelectDevice logs a warning and returns an error indicating that MIDI functionality is unavailable on this platform.
 func (m *dummyMIDIClient) SelectDevice(deviceID int) error {
 	m.logger.Warn("SelectDevice called on dummy MIDI client")
-	return fmt.Errorf("MIDI functionality is not available on this platform")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrInvalidDevice)
+}
+
+// DeviceCapabilities logs a warning and returns an error indicating that MIDI functionality is unavailable on this platform.
+func (m *dummyMIDIClient) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	m.logger.Warn("DeviceCapabilities called on dummy MIDI client")
+	return contracts.DeviceCapabilities{}, fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrInvalidDevice)
+}
+
+// ListOutputDevices logs a warning and returns an error indicating that MIDI functionality is unavailable on this platform.
+func (m *dummyMIDIClient) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	m.logger.Warn("ListOutputDevices called on dummy MIDI client")
+	return nil, fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoDevices)
+}
+
+// SelectOutputDevice logs a warning and returns an error indicating that MIDI functionality is unavailable on this platform.
+func (m *dummyMIDIClient) SelectOutputDevice(deviceID int) error {
+	m.logger.Warn("SelectOutputDevice called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrInvalidDevice)
+}
+
+// Send logs a warning and returns an error indicating that MIDI functionality is unavailable on this platform.
+func (m *dummyMIDIClient) Send(event contracts.MIDI) error {
+	m.logger.Warn("Send called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoOutputDevice)
+}
+
+// SendSysEx logs a warning and returns an error indicating that MIDI functionality is unavailable on this platform.
+func (m *dummyMIDIClient) SendSysEx(data []byte) error {
+	m.logger.Warn("SendSysEx called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoOutputDevice)
+}
+
+// CreateVirtualSource logs a warning and returns an error indicating that virtual endpoints are unavailable on this platform.
+func (m *dummyMIDIClient) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	m.logger.Warn("CreateVirtualSource called on dummy MIDI client")
+	return nil, fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrVirtualUnsupported)
+}
+
+// CreateVirtualDestination logs a warning and returns an error indicating that virtual endpoints are unavailable on this platform.
+func (m *dummyMIDIClient) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	m.logger.Warn("CreateVirtualDestination called on dummy MIDI client")
+	return nil, fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrVirtualUnsupported)
 }
 
 // StartCapture logs a warning indicating that StartCapture was called on the dummy MIDI client.
-func (m *dummyMIDIClient) StartCapture(eventChannel chan contracts.MIDI) {
+func (m *dummyMIDIClient) StartCapture(eventChannel chan contracts.MIDI) error {
 	m.logger.Warn("StartCapture called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoDeviceSelected)
+}
+
+// StartCaptureFunc logs a warning indicating that StartCaptureFunc was called on the dummy MIDI client.
+func (m *dummyMIDIClient) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	m.logger.Warn("StartCaptureFunc called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoDeviceSelected)
+}
+
+// StartCaptureContext logs a warning indicating that StartCaptureContext was called on the dummy MIDI client.
+func (m *dummyMIDIClient) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	m.logger.Warn("StartCaptureContext called on dummy MIDI client")
+	return fmt.Errorf("%w: MIDI functionality is not available on this platform", contracts.ErrNoDeviceSelected)
+}
+
+// SetMIDIEventFilter logs a warning indicating that SetMIDIEventFilter was called on the dummy MIDI client.
+func (m *dummyMIDIClient) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.logger.Warn("SetMIDIEventFilter called on dummy MIDI client")
+}
+
+// Health reports a dummy client that is never connected.
+func (m *dummyMIDIClient) Health() contracts.Health {
+	return contracts.Health{Backend: "dummy", DeviceID: -1}
+}
+
+// Stats reports no devices, since the dummy client never captures.
+func (m *dummyMIDIClient) Stats() contracts.Stats {
+	return contracts.Stats{}
 }
 
 // Stop logs a warning indicating that Stop was called on the dummy MIDI client.
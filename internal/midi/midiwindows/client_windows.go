@@ -4,13 +4,17 @@
 package midiwindows
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"runtime/pprof"
 	"sync"
-	"sync/atomic"
 	"time"
 	"unsafe"
 
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/internal/midi/retry"
+	"github.com/leandrodaf/midi/internal/profiling"
 	"github.com/leandrodaf/midi/sdk/contracts"
 	"golang.org/x/sys/windows"
 )
@@ -18,6 +22,9 @@ import (
 // Type definitions for MIDI handles
 type HMIDIIN windows.Handle
 
+// backendName identifies this backend in health reports and profiles.
+const backendName = "winmm"
+
 // Constants for callback flags
 const (
 	CALLBACK_FUNCTION = 0x00030000 // Indicates that the callback is a function
@@ -45,14 +52,20 @@ type midiInCaps struct {
 
 // ClientMid manages MIDI on Windows
 type ClientMid struct {
-	logger          contracts.Logger
-	eventChannel    atomic.Value
-	handle          HMIDIIN
-	portConn        bool
-	mu              sync.Mutex
-	callback        uintptr
-	midiEventFilter *contracts.MIDIEventFilter
-	coreMIDIConfig  *contracts.CoreMIDIConfig
+	logger         contracts.Logger
+	dispatcher     *dispatch.Dispatcher
+	handle         HMIDIIN
+	portConn       bool
+	deviceID       int
+	device         contracts.DeviceInfo
+	source         *dispatch.Source // Dispatcher entry of the opened device; nil when closed.
+	sysex          *sysexBuffers    // SysEx buffers queued with the opened device; nil when SysEx is discarded.
+	mu             sync.Mutex
+	coreMIDIConfig *contracts.CoreMIDIConfig
+	openRetry      *contracts.OpenRetry // Retry policy for opening and starting a device; nil disables retries.
+	outHandle      HMIDIOUT             // Handle of the selected output device; zero when none is open.
+	outDeviceID    int                  // ID of the selected output device, or -1.
+	outMu          sync.Mutex           // Protects outHandle and outDeviceID.
 }
 
 // Load the winmm.dll library and required functions
@@ -66,14 +79,21 @@ var (
 	procMidiInClose      = winmm.NewProc("midiInClose")
 )
 
+// midiInCallbackPtr is shared by every open handle, since Windows callbacks created with
+// windows.NewCallback are never released.
+var midiInCallbackPtr = windows.NewCallback(midiInCallback)
+
 // NewMIDIClient creates a MIDI client for Windows
 func NewMIDIClient(options *contracts.ClientOptions) (contracts.ClientMIDI, error) {
 	options.Logger.Info("MIDI client created for Windows")
 
 	return &ClientMid{
-		logger:          options.Logger,
-		midiEventFilter: options.MIDIEventFilter,
-		coreMIDIConfig:  options.CoreMIDIConfig,
+		logger:         options.Logger,
+		dispatcher:     dispatch.New(backendName, options),
+		deviceID:       -1,
+		outDeviceID:    -1,
+		coreMIDIConfig: options.CoreMIDIConfig,
+		openRetry:      options.OpenRetry,
 	}, nil
 }
 
@@ -83,147 +103,288 @@ func (m *ClientMid) ListDevices() ([]contracts.DeviceInfo, error) {
 	numDevices := uint32(r0)
 	if numDevices == 0 {
 		m.logger.Warn("No MIDI devices found")
-		return nil, errors.New("no MIDI devices found")
+		return nil, contracts.ErrNoDevices
 	}
 
 	devices := make([]contracts.DeviceInfo, numDevices)
 	for i := uint32(0); i < numDevices; i++ {
-		var caps midiInCaps
-		r1, _, _ := procMidiInGetDevCaps.Call(
-			uintptr(i),
-			uintptr(unsafe.Pointer(&caps)),
-			unsafe.Sizeof(caps),
-		)
-		if r1 != 0 {
+		device, ok := deviceInfo(i)
+		if !ok {
 			m.logger.Warn(fmt.Sprintf("Failed to get information for MIDI device %d", i))
 			continue
 		}
-		deviceName := windows.UTF16ToString(caps.szPname[:])
-		devices[i] = contracts.DeviceInfo{
-			Name:         deviceName,
-			EntityName:   deviceName,
-			Manufacturer: fmt.Sprintf("MID: %d PID: %d", caps.wMid, caps.wPid),
-		}
+		devices[i] = device
 	}
 	return devices, nil
 }
 
-// SelectDevice selects a MIDI device
+// deviceInfo queries the capabilities of a MIDI input device.
+func deviceInfo(deviceID uint32) (contracts.DeviceInfo, bool) {
+	var caps midiInCaps
+	r1, _, _ := procMidiInGetDevCaps.Call(
+		uintptr(deviceID),
+		uintptr(unsafe.Pointer(&caps)),
+		unsafe.Sizeof(caps),
+	)
+	if r1 != 0 {
+		return contracts.DeviceInfo{}, false
+	}
+	return capsInfo(caps.wMid, caps.wPid, caps.vDriverVersion, caps.szPname[:], contracts.DirectionInput), true
+}
+
+// capsInfo describes a winmm device from its capabilities. winmm identifies devices only by
+// their manufacturer and product IDs and their name, which make up the unique ID; units of
+// the same model plugged in together share it.
+func capsInfo(mid, pid uint16, driverVersion uint32, name []uint16, direction contracts.DeviceDirection) contracts.DeviceInfo {
+	deviceName := windows.UTF16ToString(name)
+	return contracts.DeviceInfo{
+		Name:          deviceName,
+		EntityName:    deviceName,
+		Manufacturer:  fmt.Sprintf("MID: %d PID: %d", mid, pid),
+		UniqueID:      fmt.Sprintf("%d:%d:%s", mid, pid, deviceName),
+		DriverVersion: fmt.Sprintf("%d.%d", driverVersion>>8&0xFF, driverVersion&0xFF),
+		Direction:     direction,
+		Ports:         1,
+	}
+}
+
+// DeviceCapabilities reports what a winmm input device supports. Each winmm device is a
+// single input port; SysEx (long messages) is received into the buffers configured with
+// contracts.WithSysExDriverBuffers.
+func (m *ClientMid) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	if deviceID < 0 {
+		return contracts.DeviceCapabilities{}, fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	if _, ok := deviceInfo(uint32(deviceID)); !ok {
+		return contracts.DeviceCapabilities{}, fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	return contracts.DeviceCapabilities{SysExIn: true, InputPorts: 1}, nil
+}
+
+// SelectDevice selects a MIDI device. If capture is running, it continues on the new device.
 func (m *ClientMid) SelectDevice(deviceID int) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	eventChannel := m.dispatcher.Channel()
 	if m.portConn {
 		if err := m.stopCapture(); err != nil {
 			return fmt.Errorf("failed to stop previous MIDI capture: %w", err)
 		}
 	}
 
-	m.callback = windows.NewCallback(midiInCallback)
+	if deviceID < 0 {
+		return fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+
+	device, _ := deviceInfo(uint32(deviceID))
+	m.source = m.dispatcher.AddSource(deviceID, device)
+
 	fdwOpen := CALLBACK_FUNCTION | MIDI_IO_STATUS
 
-	r1, _, err := procMidiInOpen.Call(
-		uintptr(unsafe.Pointer(&m.handle)),
-		uintptr(deviceID),
-		m.callback,
-		uintptr(unsafe.Pointer(m)),
-		uintptr(fdwOpen),
-	)
-	if r1 != 0 {
+	err := retry.Do(m.openRetry, m.logger, "midiInOpen", func() error {
+		r1, _, _ := procMidiInOpen.Call(
+			uintptr(unsafe.Pointer(&m.handle)),
+			uintptr(deviceID),
+			midiInCallbackPtr,
+			0,
+			uintptr(fdwOpen),
+		)
+		if r1 != MMSYSERR_NOERROR {
+			return resultError("midiInOpen", deviceID, r1)
+		}
+		return nil
+	})
+	if err != nil {
+		m.source.Close()
+		m.source = nil
 		m.logger.Error(fmt.Sprintf("Failed to open MIDI device %d: %v", deviceID, err))
-		return fmt.Errorf("failed to open MIDI device %d: %v", deviceID, err)
+		return fmt.Errorf("failed to open MIDI device %d: %w", deviceID, err)
 	}
 
+	count, size := m.dispatcher.SysExDriverBuffers()
+	sysex, err := newSysExBuffers(m.handle, deviceID, count, size, m.dispatcher.MaxSysEx(), m.source, m.dispatcher)
+	if err != nil {
+		procMidiInClose.Call(uintptr(m.handle))
+		m.handle = 0
+		m.source.Close()
+		m.source = nil
+		m.logger.Error(fmt.Sprintf("Failed to queue SysEx buffers for MIDI device %d: %v", deviceID, err))
+		return fmt.Errorf("failed to open MIDI device %d: %w", deviceID, err)
+	}
+	m.sysex = sysex
+
+	registerPort(m.handle, &openPort{
+		client: m,
+		source: m.source,
+		labels: profiling.Context(profiling.RoleCapture, backendName, device.Name),
+		sysex:  sysex,
+	})
 	m.portConn = true
+	m.deviceID = deviceID
+	m.device = device
 	m.logger.Info(fmt.Sprintf("MIDI device %d connected", deviceID))
+	m.dispatcher.DeviceSelected(deviceID, device)
+
+	if eventChannel != nil {
+		m.dispatcher.Attach(eventChannel)
+		if err := m.start(); err != nil {
+			m.dispatcher.Detach()
+			return fmt.Errorf("failed to resume MIDI capture on device %d: %w", deviceID, err)
+		}
+	}
 	return nil
 }
 
 // StartCapture initializes MIDI event capture
-func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) {
+func (m *ClientMid) StartCapture(eventChannel chan contracts.MIDI) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if !m.portConn {
+	if err := m.dispatcher.CheckChannel(eventChannel); err != nil {
+		m.logger.Error("StartCapture called with an invalid eventChannel", m.logger.Field().Error("error", err))
+		return err
+	}
+	if !m.portConn || m.handle == 0 {
 		m.logger.Error("Cannot start capture: No MIDI device selected")
-		return
+		return contracts.ErrNoDeviceSelected
 	}
-
-	if _, ok := m.eventChannel.Load().(chan contracts.MIDI); ok {
+	if m.dispatcher.Attached() {
 		m.logger.Warn("Capture already started")
-		return
-	}
-
-	m.eventChannel.Store(eventChannel)
-
-	if m.handle == 0 {
-		m.logger.Error("Invalid MIDI device handle")
-		return
+		return contracts.ErrCaptureStarted
 	}
 
-	r1, _, err := procMidiInStart.Call(uintptr(m.handle))
-	if r1 != 0 {
+	m.dispatcher.Attach(eventChannel)
+	if err := m.start(); err != nil {
+		m.dispatcher.Detach()
 		m.logger.Error(fmt.Sprintf("Failed to start MIDI capture: %v", err))
-		return
+		return fmt.Errorf("failed to start MIDI capture on device %d: %w", m.deviceID, err)
 	}
 
 	m.logger.Info("MIDI capture started")
+	return nil
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (m *ClientMid) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return m.StartCapture(m.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (m *ClientMid) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, m.StartCapture, m.Stop)
 }
 
-// midiInCallback processes incoming MIDI messages
+// start starts input on the opened device, retrying according to the open retry policy.
+// It records when, as the timestamps of winmm count from the start of input.
+func (m *ClientMid) start() error {
+	port := lookupPort(m.handle)
+	return retry.Do(m.openRetry, m.logger, "midiInStart", func() error {
+		if port != nil {
+			port.started.Store(contracts.MonotonicNow())
+		}
+		if r1, _, _ := procMidiInStart.Call(uintptr(m.handle)); r1 != MMSYSERR_NOERROR {
+			return resultError("midiInStart", m.deviceID, r1)
+		}
+		return nil
+	})
+}
+
+// midiInCallback processes incoming MIDI messages of every open handle
 func midiInCallback(hMidiIn uintptr, wMsg uint32, dwInstance uintptr, dwParam1 uintptr, dwParam2 uintptr) uintptr {
-	m := (*ClientMid)(unsafe.Pointer(dwInstance))
+	port := lookupPort(HMIDIIN(hMidiIn))
+	if port == nil {
+		// MIM_OPEN arrives before midiInOpen returns the handle, and MIM_CLOSE after it is unregistered.
+		return 0
+	}
+	// A panic must not unwind into winmm; it shuts down the capture of the port instead.
+	defer port.source.Recover()
+	m := port.client
+	pprof.SetGoroutineLabels(port.labels)
+	logging := m.dispatcher.Logging()
 
 	switch wMsg {
-	case MIM_OPEN:
-		m.logger.Info("MIDI device opened")
-	case MIM_CLOSE:
-		m.logger.Info("MIDI device closed")
 	case MIM_DATA:
-		if dwParam2 == 0 {
-			return 0
-		}
-
 		status := byte(dwParam1 & 0xFF)
 		data1 := byte((dwParam1 >> 8) & 0xFF)
 		data2 := byte((dwParam1 >> 16) & 0xFF)
+		if status < 0x80 {
+			port.source.Malformed([]byte{status, data1, data2}, "data byte without status")
+			return 0
+		}
 
-		command := status & 0xF0
-		channel := status & 0x0F
+		// The packed message holds the status and up to two data bytes; bytes past the
+		// length of the message are undefined.
+		switch midistream.DataLength(status) {
+		case 0:
+			data1, data2 = 0, 0
+		case 1:
+			data2 = 0
+		}
 
+		// dwParam2 is the time the driver received the message, in milliseconds since
+		// input was started.
+		hardware := port.started.Load() + uint64(dwParam2)*uint64(time.Millisecond)
 		midiEvent := contracts.MIDI{
-			Timestamp: uint64(time.Now().UTC().UnixNano()),
-			Command:   command,
+			Timestamp: m.dispatcher.DriverTimestamp(hardware, uint64(time.Now().UTC().UnixNano())),
+			Command:   status,
 			Note:      data1,
 			Velocity:  data2,
 		}
 
-		// Apply the MIDI event filter, checking if the command is allowed
-		if m.midiEventFilter != nil && !isCommandAllowed(midiEvent.Command, m.midiEventFilter.Commands) {
-			m.logger.Debug(fmt.Sprintf("MIDI command 0x%X filtered out", command))
-			return 0
+		if logging {
+			switch midiEvent.Type() {
+			case contracts.MessageNoteOff:
+				m.logger.Debug(fmt.Sprintf("Note Off: Channel %d, Note %d", midiEvent.Channel()+1, midiEvent.Note))
+			case contracts.MessageNoteOn:
+				m.logger.Debug(fmt.Sprintf("Note On: Channel %d, Note %d, Velocity %d", midiEvent.Channel()+1, midiEvent.Note, midiEvent.Velocity))
+			}
 		}
 
-		if command == byte(contracts.NoteOn) && midiEvent.Velocity == 0 || command == byte(contracts.NoteOff) {
-			m.logger.Debug(fmt.Sprintf("Note Off: Channel %d, Note %d", channel+1, midiEvent.Note))
-		} else if command == byte(contracts.NoteOn) {
-			m.logger.Debug(fmt.Sprintf("Note On: Channel %d, Note %d, Velocity %d", channel+1, midiEvent.Note, midiEvent.Velocity))
+		// Filter the event and send it to the channel, with a warning in case the channel is full
+		port.source.Dispatch(midiEvent)
+	case MIM_CLOSE:
+		// The port is still registered, so the driver closed it rather than Stop or
+		// SelectDevice, e.g. because the device was unplugged.
+		if logging {
+			m.logger.Warn("MIDI device closed by the driver")
 		}
-
-		// Send the event to the channel, with a warning in case the channel is full
-		if ch, ok := m.eventChannel.Load().(chan contracts.MIDI); ok && ch != nil {
-			select {
-			case ch <- midiEvent:
-			default:
-				m.logger.Warn("MIDI event channel is full; event discarded")
+		port.source.Lost(fmt.Errorf("%w: closed by the driver", contracts.ErrDeviceDisconnected))
+	case MIM_ERROR:
+		// The driver rejected a short message as invalid.
+		if logging {
+			m.logger.Error(fmt.Sprintf("MIDI error: msg=0x%X", wMsg))
+		}
+		port.source.Malformed([]byte{byte(dwParam1), byte(dwParam1 >> 8), byte(dwParam1 >> 16)}, "invalid message rejected by the driver")
+	case MIM_LONGDATA:
+		// A SysEx buffer was filled, or returned empty by midiInReset.
+		if port.sysex != nil {
+			if header := port.sysex.header(dwParam1); header != nil {
+				port.sysex.filled(header, true)
 			}
 		}
-	case MIM_ERROR, MIM_LONGERROR:
-		m.logger.Error(fmt.Sprintf("MIDI error: msg=0x%X", wMsg))
+	case MIM_LONGERROR:
+		// The driver received an invalid or incomplete SysEx message.
+		if logging {
+			m.logger.Error(fmt.Sprintf("MIDI error: msg=0x%X", wMsg))
+		}
+		if port.sysex != nil {
+			if header := port.sysex.header(dwParam1); header != nil {
+				port.sysex.filled(header, false)
+				return 0
+			}
+		}
+		port.source.Malformed(nil, "invalid or incomplete SysEx message rejected by the driver")
 	case MIM_MOREDATA:
-		m.logger.Debug("Received MIM_MOREDATA message; ignored")
+		if logging {
+			m.logger.Debug("Received MIM_MOREDATA message; ignored")
+		}
 	default:
-		m.logger.Warn(fmt.Sprintf("Unknown MIDI message: 0x%X", wMsg))
+		if logging {
+			m.logger.Warn(fmt.Sprintf("Unknown MIDI message: 0x%X", wMsg))
+		}
 	}
 
 	return 0
@@ -231,6 +392,13 @@ func midiInCallback(hMidiIn uintptr, wMsg uint32, dwInstance uintptr, dwParam1 u
 
 // Stop terminates MIDI event capture and disconnects the device
 func (m *ClientMid) Stop() error {
+	m.outMu.Lock()
+	outErr := m.closeOutput()
+	m.outMu.Unlock()
+	if outErr != nil {
+		return fmt.Errorf("failed to close MIDI output device: %w", outErr)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -249,33 +417,64 @@ func (m *ClientMid) Stop() error {
 // stopCapture stops the capture and releases resources
 func (m *ClientMid) stopCapture() error {
 	if m.handle == 0 {
-		return fmt.Errorf("invalid MIDI device handle")
+		return contracts.ErrNotCapturing
 	}
 
-	r1, _, err := procMidiInStop.Call(uintptr(m.handle))
-	if r1 != 0 {
+	// A device closed by the driver may reject the calls; it is released all the same.
+	lost := m.source != nil && m.source.IsLost()
+
+	r1, _, _ := procMidiInStop.Call(uintptr(m.handle))
+	if r1 != MMSYSERR_NOERROR && !lost {
+		err := resultError("midiInStop", m.deviceID, r1)
 		m.logger.Error(fmt.Sprintf("Failed to stop MIDI capture: %v", err))
 		return err
 	}
 
-	r1, _, err = procMidiInClose.Call(uintptr(m.handle))
-	if r1 != 0 {
+	if m.sysex != nil {
+		m.sysex.reset()
+		m.sysex = nil
+	}
+	unregisterPort(m.handle)
+	r1, _, _ = procMidiInClose.Call(uintptr(m.handle))
+	if r1 != MMSYSERR_NOERROR && !lost {
+		err := resultError("midiInClose", m.deviceID, r1)
 		m.logger.Error(fmt.Sprintf("Failed to close MIDI device: %v", err))
 		return err
 	}
 
 	m.portConn = false
 	m.handle = 0
-	m.eventChannel.Store(nil)
+	m.deviceID = -1
+	m.device = contracts.DeviceInfo{}
+	m.dispatcher.Detach()
+	if m.source != nil {
+		m.source.Close()
+		m.source = nil
+	}
 	return nil
 }
 
-// isCommandAllowed checks if the MIDI command is allowed by the filter
-func isCommandAllowed(command byte, allowedCommands []contracts.MIDICommand) bool {
-	for _, allowedCommand := range allowedCommands {
-		if command == byte(allowedCommand) {
-			return true
-		}
+// Health reports the state of the opened device together with the dispatcher counters.
+func (m *ClientMid) Health() contracts.Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := m.dispatcher.Health()
+	health.Connected = m.portConn
+	health.DeviceID = m.deviceID
+	health.Device = m.device
+	health.Diagnostics = map[string]string{
+		"handle": fmt.Sprintf("0x%X", m.handle),
 	}
-	return false
+	return health
+}
+
+// Stats reports the event traffic statistics of the selected device.
+func (m *ClientMid) Stats() contracts.Stats {
+	return m.dispatcher.Stats()
+}
+
+// SetMIDIEventFilter replaces the event filter; it takes effect for the next received message.
+func (m *ClientMid) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	m.dispatcher.SetFilter(filter)
 }
@@ -0,0 +1,104 @@
+//go:build windows
+// +build windows
+
+package midiwindows
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"golang.org/x/sys/windows"
+)
+
+// MMRESULT codes returned by the winmm functions
+const (
+	MMSYSERR_NOERROR      = 0  // No error
+	MMSYSERR_ERROR        = 1  // Unspecified error
+	MMSYSERR_BADDEVICEID  = 2  // Device ID out of range
+	MMSYSERR_NOTENABLED   = 3  // Driver failed to enable
+	MMSYSERR_ALLOCATED    = 4  // Device already allocated
+	MMSYSERR_INVALHANDLE  = 5  // Device handle is invalid
+	MMSYSERR_NODRIVER     = 6  // No device driver present
+	MMSYSERR_NOMEM        = 7  // Memory allocation error
+	MMSYSERR_NOTSUPPORTED = 8  // Function isn't supported
+	MMSYSERR_INVALFLAG    = 10 // Invalid flag passed
+	MMSYSERR_INVALPARAM   = 11 // Invalid parameter passed
+	MIDIERR_UNPREPARED    = 64 // Header not prepared
+	MIDIERR_STILLPLAYING  = 65 // Buffers still queued
+	MIDIERR_NOMAP         = 66 // No configured instruments
+	MIDIERR_NOTREADY      = 67 // Hardware is still busy
+	MIDIERR_NODEVICE      = 68 // Port no longer connected
+	MIDIERR_INVALIDSETUP  = 69 // Invalid MIDI setup
+	MIDIERR_BADOPENMODE   = 70 // Operation unsupported with the open mode
+)
+
+// MAXERRORLENGTH is the size, in characters, of the buffer passed to midiInGetErrorTextW.
+const MAXERRORLENGTH = 256
+
+var procMidiInGetErrorText = winmm.NewProc("midiInGetErrorTextW")
+
+// mmResult describes an MMRESULT code.
+type mmResult struct {
+	name string // Name of the constant, as used in the Windows documentation.
+	text string // Description used when winmm cannot provide one.
+	err  error  // Contracts error classifying the code.
+}
+
+// mmResults describes the MMRESULT codes the MIDI functions return. Codes without a more
+// specific contracts error are driver failures.
+var mmResults = map[uintptr]mmResult{
+	MMSYSERR_ERROR:        {"MMSYSERR_ERROR", "unspecified error", contracts.ErrDriverFailure},
+	MMSYSERR_BADDEVICEID:  {"MMSYSERR_BADDEVICEID", "device ID out of range", contracts.ErrInvalidDevice},
+	MMSYSERR_NOTENABLED:   {"MMSYSERR_NOTENABLED", "driver failed to enable", contracts.ErrDriverFailure},
+	MMSYSERR_ALLOCATED:    {"MMSYSERR_ALLOCATED", "device already allocated by another application", contracts.ErrDeviceBusy},
+	MMSYSERR_INVALHANDLE:  {"MMSYSERR_INVALHANDLE", "device handle is invalid", contracts.ErrDriverFailure},
+	MMSYSERR_NODRIVER:     {"MMSYSERR_NODRIVER", "no device driver present", contracts.ErrDeviceDisconnected},
+	MMSYSERR_NOMEM:        {"MMSYSERR_NOMEM", "unable to allocate memory", contracts.ErrDriverFailure},
+	MMSYSERR_NOTSUPPORTED: {"MMSYSERR_NOTSUPPORTED", "function not supported by the driver", contracts.ErrDriverFailure},
+	MMSYSERR_INVALFLAG:    {"MMSYSERR_INVALFLAG", "invalid flag", contracts.ErrDriverFailure},
+	MMSYSERR_INVALPARAM:   {"MMSYSERR_INVALPARAM", "invalid parameter", contracts.ErrDriverFailure},
+	MIDIERR_UNPREPARED:    {"MIDIERR_UNPREPARED", "buffer header not prepared", contracts.ErrDriverFailure},
+	MIDIERR_STILLPLAYING:  {"MIDIERR_STILLPLAYING", "buffers are still queued", contracts.ErrDriverFailure},
+	MIDIERR_NOMAP:         {"MIDIERR_NOMAP", "no MIDI mapper instruments configured", contracts.ErrDriverFailure},
+	MIDIERR_NOTREADY:      {"MIDIERR_NOTREADY", "hardware is still busy", contracts.ErrDeviceBusy},
+	MIDIERR_NODEVICE:      {"MIDIERR_NODEVICE", "port no longer connected", contracts.ErrDeviceDisconnected},
+	MIDIERR_INVALIDSETUP:  {"MIDIERR_INVALIDSETUP", "invalid MIDI setup", contracts.ErrDriverFailure},
+	MIDIERR_BADOPENMODE:   {"MIDIERR_BADOPENMODE", "operation not supported in the current open mode", contracts.ErrDriverFailure},
+}
+
+// resultError converts the failed MMRESULT of the winmm function op on the device with the
+// given ID, or -1, into a *contracts.Error. The description comes from
+// midiInGetErrorTextW, falling back to mmResults, and the error is classified by the
+// matching contracts error so callers can use errors.Is.
+func resultError(op string, deviceID int, result uintptr) error {
+	known, ok := mmResults[result]
+	if !ok {
+		known = mmResult{name: fmt.Sprintf("MMRESULT %d", result), text: "unknown winmm error", err: contracts.ErrDriverFailure}
+	}
+
+	text := errorText(result)
+	if text == "" {
+		text = known.text
+	}
+
+	return &contracts.Error{
+		Backend:  backendName,
+		Op:       op,
+		DeviceID: deviceID,
+		Code:     int64(result),
+		Kind:     known.err,
+		Err:      fmt.Errorf("%s (%s)", text, known.name),
+	}
+}
+
+// errorText asks winmm for the description of an MMRESULT code, returning an empty string if
+// none is available.
+func errorText(result uintptr) string {
+	var buf [MAXERRORLENGTH]uint16
+	r1, _, _ := procMidiInGetErrorText.Call(result, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	if r1 != MMSYSERR_NOERROR {
+		return ""
+	}
+	return windows.UTF16ToString(buf[:])
+}
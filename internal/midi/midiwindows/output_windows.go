@@ -0,0 +1,219 @@
+//go:build windows
+// +build windows
+
+package midiwindows
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+	"unsafe"
+
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"golang.org/x/sys/windows"
+)
+
+// Type definitions for MIDI output handles
+type HMIDIOUT windows.Handle
+
+// CALLBACK_NULL opens an output device without a callback
+const CALLBACK_NULL = 0x00000000
+
+// Struct representing MIDI output device capabilities
+type midiOutCaps struct {
+	wMid           uint16
+	wPid           uint16
+	vDriverVersion uint32
+	szPname        [32]uint16
+	wTechnology    uint16
+	wVoices        uint16
+	wNotes         uint16
+	wChannelMask   uint16
+	dwSupport      uint32
+}
+
+// Load the MIDI output functions of winmm.dll
+var (
+	procMidiOutGetNumDevs = winmm.NewProc("midiOutGetNumDevs")
+	procMidiOutGetDevCaps = winmm.NewProc("midiOutGetDevCapsW")
+	procMidiOutOpen       = winmm.NewProc("midiOutOpen")
+	procMidiOutShortMsg   = winmm.NewProc("midiOutShortMsg")
+	procMidiOutClose      = winmm.NewProc("midiOutClose")
+
+	procMidiOutPrepareHeader   = winmm.NewProc("midiOutPrepareHeader")
+	procMidiOutUnprepareHeader = winmm.NewProc("midiOutUnprepareHeader")
+	procMidiOutLongMsg         = winmm.NewProc("midiOutLongMsg")
+	procMidiOutReset           = winmm.NewProc("midiOutReset")
+)
+
+// sysexByteTime is how long a byte takes on a MIDI cable at 31250 baud; SendSysEx waits
+// twice as long per byte for the driver to send a message before giving up.
+const sysexByteTime = 320 * time.Microsecond
+
+// ListOutputDevices lists the available MIDI output devices
+func (m *ClientMid) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	r0, _, _ := procMidiOutGetNumDevs.Call()
+	numDevices := uint32(r0)
+	if numDevices == 0 {
+		m.logger.Warn("No MIDI output devices found")
+		return nil, contracts.ErrNoDevices
+	}
+
+	devices := make([]contracts.DeviceInfo, numDevices)
+	for i := uint32(0); i < numDevices; i++ {
+		device, ok := outputDeviceInfo(i)
+		if !ok {
+			m.logger.Warn(fmt.Sprintf("Failed to get information for MIDI output device %d", i))
+			continue
+		}
+		devices[i] = device
+	}
+	return devices, nil
+}
+
+// outputDeviceInfo queries the capabilities of a MIDI output device.
+func outputDeviceInfo(deviceID uint32) (contracts.DeviceInfo, bool) {
+	var caps midiOutCaps
+	r1, _, _ := procMidiOutGetDevCaps.Call(
+		uintptr(deviceID),
+		uintptr(unsafe.Pointer(&caps)),
+		unsafe.Sizeof(caps),
+	)
+	if r1 != 0 {
+		return contracts.DeviceInfo{}, false
+	}
+	return capsInfo(caps.wMid, caps.wPid, caps.vDriverVersion, caps.szPname[:], contracts.DirectionOutput), true
+}
+
+// SelectOutputDevice opens a MIDI output device as the target of Send, closing the
+// previously selected one.
+func (m *ClientMid) SelectOutputDevice(deviceID int) error {
+	if deviceID < 0 {
+		return fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+
+	m.outMu.Lock()
+	defer m.outMu.Unlock()
+
+	if err := m.closeOutput(); err != nil {
+		return fmt.Errorf("failed to close previous MIDI output device: %w", err)
+	}
+
+	var handle HMIDIOUT
+	r1, _, _ := procMidiOutOpen.Call(
+		uintptr(unsafe.Pointer(&handle)),
+		uintptr(deviceID),
+		0,
+		0,
+		CALLBACK_NULL,
+	)
+	if r1 != MMSYSERR_NOERROR {
+		err := resultError("midiOutOpen", deviceID, r1)
+		m.logger.Error(fmt.Sprintf("Failed to open MIDI output device %d: %v", deviceID, err))
+		return fmt.Errorf("failed to open MIDI output device %d: %w", deviceID, err)
+	}
+
+	m.outHandle = handle
+	m.outDeviceID = deviceID
+	m.logger.Info(fmt.Sprintf("MIDI output device %d opened", deviceID))
+	return nil
+}
+
+// Send sends event to the selected output device as a short message. Its timestamp is
+// ignored.
+func (m *ClientMid) Send(event contracts.MIDI) error {
+	data, err := midistream.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	var msg uintptr
+	for i, b := range data {
+		msg |= uintptr(b) << (8 * i)
+	}
+
+	m.outMu.Lock()
+	defer m.outMu.Unlock()
+
+	if m.outHandle == 0 {
+		return contracts.ErrNoOutputDevice
+	}
+	if r1, _, _ := procMidiOutShortMsg.Call(uintptr(m.outHandle), msg); r1 != MMSYSERR_NOERROR {
+		return fmt.Errorf("failed to send MIDI message: %w", resultError("midiOutShortMsg", m.outDeviceID, r1))
+	}
+	return nil
+}
+
+// SendSysEx sends a complete SysEx message, including F0 and F7, to the selected output
+// device as a long message, and waits until the driver has sent it.
+func (m *ClientMid) SendSysEx(data []byte) error {
+	if err := midistream.CheckSysEx(data); err != nil {
+		return err
+	}
+
+	m.outMu.Lock()
+	defer m.outMu.Unlock()
+
+	if m.outHandle == 0 {
+		return contracts.ErrNoOutputDevice
+	}
+
+	buffer := append([]byte(nil), data...)
+	header := &midiHdr{lpData: &buffer[0], dwBufferLength: uint32(len(buffer))}
+	defer runtime.KeepAlive(buffer)
+	if r1, _, _ := procMidiOutPrepareHeader.Call(uintptr(m.outHandle), uintptr(unsafe.Pointer(header)), unsafe.Sizeof(*header)); r1 != MMSYSERR_NOERROR {
+		return fmt.Errorf("failed to prepare SysEx message: %w", resultError("midiOutPrepareHeader", m.outDeviceID, r1))
+	}
+	if r1, _, _ := procMidiOutLongMsg.Call(uintptr(m.outHandle), uintptr(unsafe.Pointer(header)), unsafe.Sizeof(*header)); r1 != MMSYSERR_NOERROR {
+		procMidiOutUnprepareHeader.Call(uintptr(m.outHandle), uintptr(unsafe.Pointer(header)), unsafe.Sizeof(*header))
+		return fmt.Errorf("failed to send SysEx message: %w", resultError("midiOutLongMsg", m.outDeviceID, r1))
+	}
+
+	// The buffer stays with the driver until it is sent; unpreparing fails until then.
+	deadline := time.Now().Add(time.Second + 2*time.Duration(len(buffer))*sysexByteTime)
+	for {
+		r1, _, _ := procMidiOutUnprepareHeader.Call(uintptr(m.outHandle), uintptr(unsafe.Pointer(header)), unsafe.Sizeof(*header))
+		switch {
+		case r1 == MMSYSERR_NOERROR:
+			return nil
+		case r1 != MIDIERR_STILLPLAYING:
+			return fmt.Errorf("failed to release SysEx message: %w", resultError("midiOutUnprepareHeader", m.outDeviceID, r1))
+		case time.Now().After(deadline):
+			// Resetting returns the buffer, so it can be released before it is freed.
+			procMidiOutReset.Call(uintptr(m.outHandle))
+			procMidiOutUnprepareHeader.Call(uintptr(m.outHandle), uintptr(unsafe.Pointer(header)), unsafe.Sizeof(*header))
+			return fmt.Errorf("%w: SysEx message not sent by the driver in time", contracts.ErrDriverFailure)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// CreateVirtualSource reports contracts.ErrVirtualUnsupported: the Windows Multimedia API
+// cannot publish ports. Install a virtual loopback driver, such as loopMIDI, and select
+// its port with SelectOutputDevice instead.
+func (m *ClientMid) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	return nil, fmt.Errorf("%w: winmm cannot create ports; use a loopback driver such as loopMIDI and select its output", contracts.ErrVirtualUnsupported)
+}
+
+// CreateVirtualDestination reports contracts.ErrVirtualUnsupported: the Windows Multimedia
+// API cannot publish ports. Install a virtual loopback driver, such as loopMIDI, and
+// capture from its port with SelectDevice instead.
+func (m *ClientMid) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	return nil, fmt.Errorf("%w: winmm cannot create ports; use a loopback driver such as loopMIDI and capture from its input", contracts.ErrVirtualUnsupported)
+}
+
+// closeOutput closes the selected output device, if any. The caller must hold m.outMu.
+func (m *ClientMid) closeOutput() error {
+	if m.outHandle == 0 {
+		return nil
+	}
+	if r1, _, _ := procMidiOutClose.Call(uintptr(m.outHandle)); r1 != MMSYSERR_NOERROR {
+		err := resultError("midiOutClose", m.outDeviceID, r1)
+		m.logger.Error(fmt.Sprintf("Failed to close MIDI output device: %v", err))
+		return err
+	}
+	m.outHandle = 0
+	m.outDeviceID = -1
+	return nil
+}
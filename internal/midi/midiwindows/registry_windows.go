@@ -0,0 +1,51 @@
+//go:build windows
+// +build windows
+
+package midiwindows
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+)
+
+// openPort is what the winmm callback needs to know about an open input handle.
+type openPort struct {
+	client *ClientMid       // Client that opened the handle.
+	source *dispatch.Source // Dispatcher entry of the device.
+	labels context.Context  // pprof labels applied to the callback thread.
+	sysex  *sysexBuffers    // Buffers receiving SysEx messages; nil when they are discarded.
+
+	started atomic.Uint64 // contracts.MonotonicNow when input was last started; winmm timestamps count from it.
+}
+
+// The registry maps open input handles to their ports. winmm passes the handle to the
+// callback, so no Go pointer has to travel through dwInstance, and any number of handles
+// can be open at once.
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[HMIDIIN]*openPort)
+)
+
+// registerPort makes the callback deliver the messages of handle to port.
+func registerPort(handle HMIDIIN, port *openPort) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[handle] = port
+}
+
+// unregisterPort stops delivering the messages of handle.
+func unregisterPort(handle HMIDIIN) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, handle)
+}
+
+// lookupPort returns the port of handle, or nil if the handle is not registered.
+func lookupPort(handle HMIDIIN) *openPort {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[handle]
+}
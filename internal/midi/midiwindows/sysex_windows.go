@@ -0,0 +1,136 @@
+//go:build windows
+// +build windows
+
+package midiwindows
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// MIM_LONGDATA reports a buffer filled with SysEx data
+const MIM_LONGDATA = 0x3C4
+
+// midiHdr is the MIDIHDR structure describing a buffer queued for long messages
+type midiHdr struct {
+	lpData          *byte
+	dwBufferLength  uint32
+	dwBytesRecorded uint32
+	dwUser          uintptr
+	dwFlags         uint32
+	lpNext          *midiHdr
+	reserved        uintptr
+	dwOffset        uint32
+	dwReserved      [8]uintptr
+}
+
+// Load the long message functions of winmm.dll
+var (
+	procMidiInPrepareHeader   = winmm.NewProc("midiInPrepareHeader")
+	procMidiInUnprepareHeader = winmm.NewProc("midiInUnprepareHeader")
+	procMidiInAddBuffer       = winmm.NewProc("midiInAddBuffer")
+	procMidiInReset           = winmm.NewProc("midiInReset")
+)
+
+// sysexBuffers holds the buffers queued with winmm for the SysEx messages of an open
+// handle, and assembles the messages they carry, which may span buffers.
+type sysexBuffers struct {
+	handle   HMIDIIN
+	deviceID int                // ID of the device of handle, for errors.
+	headers  []*midiHdr         // Headers of the buffers; kept referenced while queued.
+	data     [][]byte           // Memory of the buffers.
+	parser   *midistream.Parser // Assembles messages across buffers.
+	closing  atomic.Bool        // Set before the buffers are returned, so they are not queued again.
+}
+
+// newSysExBuffers prepares and queues count buffers of size bytes with handle, the device
+// with the given ID, delivering the messages they receive to source, timestamped on
+// arrival on the clock of d. It returns nil without error when count is zero.
+func newSysExBuffers(handle HMIDIIN, deviceID, count, size, maxSize int, source *dispatch.Source, d *dispatch.Dispatcher) (*sysexBuffers, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+	b := &sysexBuffers{handle: handle, deviceID: deviceID}
+	b.parser = &midistream.Parser{
+		OnSysEx: func(data []byte) {
+			source.DispatchSysEx(contracts.SysExEvent{Timestamp: d.ArrivalTimestamp(uint64(time.Now().UTC().UnixNano())), Data: data})
+		},
+		OnError:  source.Malformed,
+		MaxSysEx: maxSize,
+	}
+	for range count {
+		data := make([]byte, size)
+		header := &midiHdr{lpData: &data[0], dwBufferLength: uint32(size)}
+		if r1, _, _ := procMidiInPrepareHeader.Call(uintptr(handle), uintptr(unsafe.Pointer(header)), unsafe.Sizeof(*header)); r1 != MMSYSERR_NOERROR {
+			b.release()
+			return nil, fmt.Errorf("failed to prepare SysEx buffer: %w", resultError("midiInPrepareHeader", deviceID, r1))
+		}
+		b.headers = append(b.headers, header)
+		b.data = append(b.data, data)
+		if err := b.queue(header); err != nil {
+			b.release()
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// queue hands a prepared buffer to winmm.
+func (b *sysexBuffers) queue(header *midiHdr) error {
+	header.dwBytesRecorded = 0
+	if r1, _, _ := procMidiInAddBuffer.Call(uintptr(b.handle), uintptr(unsafe.Pointer(header)), unsafe.Sizeof(*header)); r1 != MMSYSERR_NOERROR {
+		return fmt.Errorf("failed to queue SysEx buffer: %w", resultError("midiInAddBuffer", b.deviceID, r1))
+	}
+	return nil
+}
+
+// filled parses a buffer returned by winmm with MIM_LONGDATA or MIM_LONGERROR and queues
+// it again, unless the handle is closing.
+func (b *sysexBuffers) filled(header *midiHdr, complete bool) {
+	if recorded := int(header.dwBytesRecorded); recorded > 0 {
+		data := unsafe.Slice(header.lpData, recorded)
+		if complete {
+			b.parser.Write(data)
+		} else {
+			b.parser.OnError(append([]byte(nil), data...), "invalid or incomplete SysEx message rejected by the driver")
+		}
+	}
+	if b.closing.Load() {
+		return
+	}
+	if err := b.queue(header); err != nil {
+		b.parser.OnError(nil, err.Error())
+	}
+}
+
+// header returns the header of the buffer at address, or nil if it is not one of ours.
+func (b *sysexBuffers) header(address uintptr) *midiHdr {
+	for _, header := range b.headers {
+		if uintptr(unsafe.Pointer(header)) == address {
+			return header
+		}
+	}
+	return nil
+}
+
+// reset returns every queued buffer, which winmm reports with MIM_LONGDATA, and
+// unprepares them. Input must be stopped.
+func (b *sysexBuffers) reset() {
+	b.closing.Store(true)
+	procMidiInReset.Call(uintptr(b.handle))
+	b.release()
+}
+
+// release unprepares the buffers.
+func (b *sysexBuffers) release() {
+	b.closing.Store(true)
+	for _, header := range b.headers {
+		procMidiInUnprepareHeader.Call(uintptr(b.handle), uintptr(unsafe.Pointer(header)), unsafe.Sizeof(*header))
+	}
+}
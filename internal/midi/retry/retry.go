@@ -0,0 +1,34 @@
+// Package retry implements the device open retry policy configured with contracts.WithOpenRetry.
+package retry
+
+import (
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Do runs fn until it succeeds or the attempts of policy are exhausted, sleeping with
+// exponential backoff between attempts. A nil policy runs fn once. The error of the
+// last attempt is returned.
+func Do(policy *contracts.OpenRetry, logger contracts.Logger, operation string, fn func() error) error {
+	err := fn()
+	if err == nil || policy == nil {
+		return err
+	}
+
+	backoff := policy.Backoff
+	for attempt := 2; attempt <= policy.Attempts; attempt++ {
+		logger.Warn("Retrying MIDI device operation",
+			logger.Field().String("operation", operation),
+			logger.Field().Int("attempt", attempt),
+			logger.Field().Error("error", err))
+
+		time.Sleep(backoff)
+		backoff *= 2
+
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package profiling
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// Label keys attached to the goroutines started by this module, so that CPU, heap and
+// goroutine profiles of applications embedding the SDK attribute time to the MIDI layer.
+const (
+	RoleKey    = "midi.role"    // What the goroutine does (see the Role constants).
+	BackendKey = "midi.backend" // Backend or component the goroutine belongs to.
+	DeviceKey  = "midi.device"  // Device or endpoint the goroutine serves, when known.
+)
+
+// Roles of the goroutines started by this module.
+const (
+	RoleCapture   = "capture"   // Reads events from a device, driver or network peer.
+	RoleDispatch  = "dispatch"  // Delivers captured events to consumers.
+	RoleScheduler = "scheduler" // Runs timed work such as periodic flushes and clock sync.
+	RoleWatchdog  = "watchdog"  // Monitors capture health.
+	RoleHooks     = "hooks"     // Runs the lifecycle callbacks of the application.
+)
+
+// Labels returns the pprof label set for a goroutine. Empty values are omitted.
+func Labels(role, backend, device string) pprof.LabelSet {
+	args := []string{RoleKey, role}
+	if backend != "" {
+		args = append(args, BackendKey, backend)
+	}
+	if device != "" {
+		args = append(args, DeviceKey, device)
+	}
+	return pprof.Labels(args...)
+}
+
+// Go runs fn on a new goroutine carrying the labels. Goroutines started by fn inherit them.
+func Go(role, backend, device string, fn func()) {
+	go Do(role, backend, device, fn)
+}
+
+// Do runs fn on the current goroutine with the labels applied, restoring the previous
+// labels afterwards. Use it around calls into OS bindings that start their own goroutines,
+// which then inherit the labels.
+func Do(role, backend, device string, fn func()) {
+	pprof.Do(context.Background(), Labels(role, backend, device), func(context.Context) {
+		fn()
+	})
+}
+
+// Context returns a context carrying the labels. OS callbacks, which run on goroutines the
+// module does not start, apply it on every call with pprof.SetGoroutineLabels; building the
+// context once keeps that call allocation-free.
+func Context(role, backend, device string) context.Context {
+	return pprof.WithLabels(context.Background(), Labels(role, backend, device))
+}
@@ -0,0 +1,9 @@
+// Package threadprio raises the scheduling priority of the calling OS thread for the
+// latency-sensitive dispatch path. Callers must lock the goroutine to its thread with
+// runtime.LockOSThread first, otherwise the priority applies to an arbitrary thread.
+package threadprio
+
+import "errors"
+
+// ErrUnsupported is returned by Raise on platforms without a thread priority implementation.
+var ErrUnsupported = errors.New("raising thread priority is not supported on this platform")
@@ -0,0 +1,21 @@
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package threadprio
+
+/*
+#include <pthread.h>
+#include <pthread/qos.h>
+*/
+import "C"
+
+import "fmt"
+
+// Raise sets the calling thread to the user-interactive quality of service class, the
+// class macOS reserves for work with immediate, user-visible results.
+func Raise() error {
+	if rc := C.pthread_set_qos_class_self_np(C.QOS_CLASS_USER_INTERACTIVE, 0); rc != 0 {
+		return fmt.Errorf("pthread_set_qos_class_self_np: error %d", int(rc))
+	}
+	return nil
+}
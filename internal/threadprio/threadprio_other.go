@@ -0,0 +1,10 @@
+//go:build !windows && !(darwin && cgo)
+// +build !windows
+// +build !darwin !cgo
+
+package threadprio
+
+// Raise reports ErrUnsupported; the thread keeps its default priority.
+func Raise() error {
+	return ErrUnsupported
+}
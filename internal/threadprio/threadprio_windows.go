@@ -0,0 +1,31 @@
+//go:build windows
+// +build windows
+
+package threadprio
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// THREAD_PRIORITY_TIME_CRITICAL is the highest priority of a thread within its priority class.
+const THREAD_PRIORITY_TIME_CRITICAL = 15
+
+var (
+	kernel32              = windows.NewLazySystemDLL("kernel32.dll")
+	procSetThreadPriority = kernel32.NewProc("SetThreadPriority")
+)
+
+// Raise sets the calling thread to time-critical priority.
+func Raise() error {
+	thread, err := windows.GetCurrentThread()
+	if err != nil {
+		return err
+	}
+	r1, _, err := procSetThreadPriority.Call(uintptr(thread), uintptr(THREAD_PRIORITY_TIME_CRITICAL))
+	if r1 == 0 {
+		return fmt.Errorf("SetThreadPriority: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package applemidi
+
+import (
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// SessionOptions holds the configuration of a Session.
+type SessionOptions struct {
+	Name         string                    // Session name shown to peers; defaults to "Go MIDI Session".
+	Port         int                       // Control port; the data port is Port+1. Defaults to 5004.
+	SyncInterval time.Duration             // Interval between clock synchronizations initiated by this side.
+	Advertise    bool                      // Announce the session via mDNS (_apple-midi._udp).
+	Logger       contracts.Logger          // Logger for session events and errors.
+	OnInvitation func(Invitation) bool     // Decides whether an incoming invitation is accepted.
+	OnJoined     func(Participant)         // Called when a participant has fully joined.
+	OnLeft       func(Participant)         // Called when a participant leaves or is removed.
+	OnData       func(Participant, []byte) // Called with every RTP packet received on the data port.
+}
+
+// SessionOption is a function that modifies SessionOptions.
+type SessionOption func(*SessionOptions)
+
+// WithName sets the session name shown to peers.
+func WithName(name string) SessionOption {
+	return func(opts *SessionOptions) {
+		opts.Name = name
+	}
+}
+
+// WithPort sets the control port; the data port is the following one.
+func WithPort(port int) SessionOption {
+	return func(opts *SessionOptions) {
+		opts.Port = port
+	}
+}
+
+// WithSyncInterval sets how often clock synchronization runs with participants this side invited.
+func WithSyncInterval(interval time.Duration) SessionOption {
+	return func(opts *SessionOptions) {
+		opts.SyncInterval = interval
+	}
+}
+
+// WithAdvertisement announces the session on the local network via mDNS.
+func WithAdvertisement() SessionOption {
+	return func(opts *SessionOptions) {
+		opts.Advertise = true
+	}
+}
+
+// WithLogger sets the logger used by the session.
+func WithLogger(l contracts.Logger) SessionOption {
+	return func(opts *SessionOptions) {
+		opts.Logger = l
+	}
+}
+
+// WithInvitationHandler sets the callback deciding whether incoming invitations are
+// accepted. Without it every invitation is accepted.
+func WithInvitationHandler(handler func(Invitation) bool) SessionOption {
+	return func(opts *SessionOptions) {
+		opts.OnInvitation = handler
+	}
+}
+
+// WithParticipantHandlers sets the callbacks invoked when participants join and leave.
+func WithParticipantHandlers(joined, left func(Participant)) SessionOption {
+	return func(opts *SessionOptions) {
+		opts.OnJoined = joined
+		opts.OnLeft = left
+	}
+}
+
+// WithDataHandler sets the callback receiving RTP-MIDI packets from participants.
+func WithDataHandler(handler func(Participant, []byte)) SessionOption {
+	return func(opts *SessionOptions) {
+		opts.OnData = handler
+	}
+}
@@ -0,0 +1,139 @@
+package applemidi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// Exchange commands of the AppleMIDI session protocol.
+const (
+	commandInvitation = "IN" // Invitation to join a session.
+	commandAccept     = "OK" // Invitation accepted.
+	commandReject     = "NO" // Invitation rejected.
+	commandEnd        = "BY" // Participant leaves the session.
+	commandSync       = "CK" // Clock synchronization.
+	commandFeedback   = "RS" // Receiver feedback (last sequence number received).
+)
+
+// protocolVersion is the AppleMIDI protocol version sent in invitations.
+const protocolVersion = 2
+
+// ErrMalformedPacket is returned when an exchange packet cannot be decoded.
+var ErrMalformedPacket = errors.New("malformed AppleMIDI packet")
+
+// isExchangePacket reports whether b is an AppleMIDI exchange packet rather than RTP data.
+func isExchangePacket(b []byte) bool {
+	return len(b) >= 4 && b[0] == 0xFF && b[1] == 0xFF
+}
+
+// exchangeCommand returns the two-letter command of an exchange packet.
+func exchangeCommand(b []byte) string {
+	return string(b[2:4])
+}
+
+// invitationPacket is an IN, OK, NO or BY exchange packet.
+type invitationPacket struct {
+	Command string
+	Token   uint32 // Initiator token, echoed by the responder.
+	SSRC    uint32 // Synchronization source of the sender.
+	Name    string // Session name of the sender; empty for BY.
+}
+
+// marshal encodes the packet in wire format.
+func (p invitationPacket) marshal() []byte {
+	b := make([]byte, 16, 16+len(p.Name)+1)
+	b[0], b[1] = 0xFF, 0xFF
+	copy(b[2:4], p.Command)
+	binary.BigEndian.PutUint32(b[4:8], protocolVersion)
+	binary.BigEndian.PutUint32(b[8:12], p.Token)
+	binary.BigEndian.PutUint32(b[12:16], p.SSRC)
+	if p.Name != "" {
+		b = append(b, p.Name...)
+		b = append(b, 0)
+	}
+	return b
+}
+
+// parseInvitation decodes an IN, OK, NO or BY exchange packet.
+func parseInvitation(b []byte) (invitationPacket, error) {
+	if len(b) < 16 {
+		return invitationPacket{}, ErrMalformedPacket
+	}
+
+	p := invitationPacket{
+		Command: exchangeCommand(b),
+		Token:   binary.BigEndian.Uint32(b[8:12]),
+		SSRC:    binary.BigEndian.Uint32(b[12:16]),
+	}
+	if name := b[16:]; len(name) > 0 {
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		p.Name = string(name)
+	}
+	return p, nil
+}
+
+// syncPacket is a CK exchange packet. Timestamps are in units of 100 microseconds.
+type syncPacket struct {
+	SSRC       uint32
+	Count      uint8 // 0, 1 or 2: which timestamp was filled in last.
+	Timestamps [3]uint64
+}
+
+// marshal encodes the packet in wire format.
+func (p syncPacket) marshal() []byte {
+	b := make([]byte, 36)
+	b[0], b[1] = 0xFF, 0xFF
+	copy(b[2:4], commandSync)
+	binary.BigEndian.PutUint32(b[4:8], p.SSRC)
+	b[8] = p.Count
+	for i, ts := range p.Timestamps {
+		binary.BigEndian.PutUint64(b[12+8*i:20+8*i], ts)
+	}
+	return b
+}
+
+// parseSync decodes a CK exchange packet.
+func parseSync(b []byte) (syncPacket, error) {
+	if len(b) < 36 {
+		return syncPacket{}, ErrMalformedPacket
+	}
+
+	p := syncPacket{
+		SSRC:  binary.BigEndian.Uint32(b[4:8]),
+		Count: b[8],
+	}
+	for i := range p.Timestamps {
+		p.Timestamps[i] = binary.BigEndian.Uint64(b[12+8*i : 20+8*i])
+	}
+	return p, nil
+}
+
+// feedbackPacket is an RS exchange packet.
+type feedbackPacket struct {
+	SSRC     uint32
+	Sequence uint16 // Last RTP sequence number received.
+}
+
+// marshal encodes the packet in wire format.
+func (p feedbackPacket) marshal() []byte {
+	b := make([]byte, 12)
+	b[0], b[1] = 0xFF, 0xFF
+	copy(b[2:4], commandFeedback)
+	binary.BigEndian.PutUint32(b[4:8], p.SSRC)
+	binary.BigEndian.PutUint16(b[8:10], p.Sequence)
+	return b
+}
+
+// parseFeedback decodes an RS exchange packet.
+func parseFeedback(b []byte) (feedbackPacket, error) {
+	if len(b) < 12 {
+		return feedbackPacket{}, ErrMalformedPacket
+	}
+	return feedbackPacket{
+		SSRC:     binary.BigEndian.Uint32(b[4:8]),
+		Sequence: binary.BigEndian.Uint16(b[8:10]),
+	}, nil
+}
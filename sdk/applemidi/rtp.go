@@ -0,0 +1,208 @@
+package applemidi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+)
+
+const (
+	rtpVersion     = 2
+	rtpPayloadType = 0x61   // Dynamic payload type used by AppleMIDI for RTP-MIDI.
+	maxCommandList = 0x0FFF // Longest MIDI command list of a packet, the 12 bits of a long header.
+)
+
+// Flags of the header of the MIDI command section (RFC 6295, section 3).
+const (
+	flagLong  = 0x80 // B: the length has 12 bits.
+	flagDelta = 0x20 // Z: the first command has a delta time.
+)
+
+// ErrPacketTooLarge is returned when the commands of a data packet do not fit in the
+// 4095 bytes of an RTP-MIDI command list.
+var ErrPacketTooLarge = errors.New("RTP-MIDI command list too large")
+
+// Command is a MIDI message of an RTP-MIDI data packet.
+type Command struct {
+	// Delta is the time of the command after the previous one, or after the timestamp of
+	// the packet for the first one, in the units of the session clock (100 microseconds).
+	Delta uint32
+	// Data is the message with its status byte. A SysEx message split across packets is
+	// carried in segments: the first one starts with 0xF0 and ends with 0xF0, the middle
+	// ones start with 0xF7 and end with 0xF0, and the last one starts and ends with 0xF7.
+	// A segment ending with 0xF4 cancels the message.
+	Data []byte
+}
+
+// DataPacket is an RTP packet carrying MIDI commands, as exchanged on the data port of a
+// session (RFC 6295). The recovery journal of received packets is ignored, and sent
+// packets carry none.
+type DataPacket struct {
+	Sequence  uint16    // RTP sequence number, incremented by the sender for every packet.
+	Timestamp uint32    // Session clock of the sender when the packet was sent, truncated to 32 bits.
+	SSRC      uint32    // Synchronization source of the sender.
+	Commands  []Command // MIDI commands, in order.
+}
+
+// Marshal encodes the packet in wire format. Every command is written with its status
+// byte, and all but the first one with its delta time.
+//
+// Returns:
+//   - []byte: The encoded packet.
+//   - error: ErrPacketTooLarge if the commands exceed the command list.
+func (p DataPacket) Marshal() ([]byte, error) {
+	var list []byte
+	for i, command := range p.Commands {
+		if i > 0 {
+			list = appendDelta(list, command.Delta)
+		}
+		list = append(list, command.Data...)
+	}
+	if len(list) > maxCommandList {
+		return nil, fmt.Errorf("%w: %d bytes", ErrPacketTooLarge, len(list))
+	}
+
+	b := make([]byte, 12, 14+len(list))
+	b[0] = rtpVersion << 6
+	b[1] = rtpPayloadType
+	binary.BigEndian.PutUint16(b[2:4], p.Sequence)
+	binary.BigEndian.PutUint32(b[4:8], p.Timestamp)
+	binary.BigEndian.PutUint32(b[8:12], p.SSRC)
+	if len(list) > 0x0F {
+		b = append(b, flagLong|byte(len(list)>>8), byte(len(list)))
+	} else {
+		b = append(b, byte(len(list)))
+	}
+	return append(b, list...), nil
+}
+
+// ParseDataPacket decodes an RTP-MIDI data packet. Commands written with running status
+// are returned with their status byte.
+//
+// b []byte: The packet as received on the data port.
+//
+// Returns:
+//   - DataPacket: The decoded packet, whose commands do not share memory with b.
+//   - error: An error wrapping ErrMalformedPacket if b is not a valid RTP-MIDI packet.
+func ParseDataPacket(b []byte) (DataPacket, error) {
+	if len(b) < 12 || b[0]>>6 != rtpVersion {
+		return DataPacket{}, fmt.Errorf("%w: not an RTP packet", ErrMalformedPacket)
+	}
+	p := DataPacket{
+		Sequence:  binary.BigEndian.Uint16(b[2:4]),
+		Timestamp: binary.BigEndian.Uint32(b[4:8]),
+		SSRC:      binary.BigEndian.Uint32(b[8:12]),
+	}
+
+	header := 12 + 4*int(b[0]&0x0F) // Past the contributing sources, if any
+	if len(b) <= header {
+		return DataPacket{}, fmt.Errorf("%w: missing MIDI command section", ErrMalformedPacket)
+	}
+	section := b[header:]
+	flags, length, header := section[0], int(section[0]&0x0F), 1
+	if flags&flagLong != 0 {
+		if len(section) < 2 {
+			return DataPacket{}, fmt.Errorf("%w: truncated MIDI command section header", ErrMalformedPacket)
+		}
+		length, header = length<<8|int(section[1]), 2
+	}
+	if len(section) < header+length {
+		return DataPacket{}, fmt.Errorf("%w: command list of %d bytes in %d", ErrMalformedPacket, length, len(section)-header)
+	}
+
+	commands, err := parseCommands(section[header:header+length], flags&flagDelta != 0)
+	if err != nil {
+		return DataPacket{}, err
+	}
+	p.Commands = commands
+	return p, nil
+}
+
+// parseCommands decodes a MIDI command list. firstDelta tells whether the first command
+// has a delta time.
+func parseCommands(list []byte, firstDelta bool) ([]Command, error) {
+	var (
+		commands []Command
+		running  byte // Running status of channel messages; 0 when none.
+	)
+	for i := 0; i < len(list); {
+		var command Command
+		if len(commands) > 0 || firstDelta {
+			delta, n, err := readDelta(list[i:])
+			if err != nil {
+				return nil, err
+			}
+			command.Delta = delta
+			i += n
+			if i >= len(list) {
+				return nil, fmt.Errorf("%w: delta time without command", ErrMalformedPacket)
+			}
+		}
+
+		status, start := list[i], i
+		switch {
+		case status >= 0xF8:
+			i++
+		case status == 0xF0 || status == 0xF7:
+			end := i + 1
+			for end < len(list) && list[end] != 0xF0 && list[end] != 0xF7 && list[end] != 0xF4 {
+				end++
+			}
+			if end == len(list) {
+				return nil, fmt.Errorf("%w: unterminated SysEx segment", ErrMalformedPacket)
+			}
+			i = end + 1
+			running = 0
+		case status >= 0x80:
+			i += 1 + midistream.DataLength(status)
+			if status < 0xF0 {
+				running = status
+			} else {
+				running = 0
+			}
+		default:
+			if running == 0 {
+				return nil, fmt.Errorf("%w: data byte 0x%02X without status", ErrMalformedPacket, status)
+			}
+			i += midistream.DataLength(running)
+			if i > len(list) {
+				return nil, fmt.Errorf("%w: truncated message 0x%02X", ErrMalformedPacket, running)
+			}
+			command.Data = append([]byte{running}, list[start:i]...)
+			commands = append(commands, command)
+			continue
+		}
+		if i > len(list) {
+			return nil, fmt.Errorf("%w: truncated message 0x%02X", ErrMalformedPacket, status)
+		}
+		command.Data = append([]byte(nil), list[start:i]...)
+		commands = append(commands, command)
+	}
+	return commands, nil
+}
+
+// readDelta decodes a delta time of one to four bytes, seven bits each, most significant
+// first, and returns it with its length.
+func readDelta(b []byte) (uint32, int, error) {
+	var delta uint32
+	for i := 0; i < len(b) && i < 4; i++ {
+		delta = delta<<7 | uint32(b[i]&0x7F)
+		if b[i]&0x80 == 0 {
+			return delta, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("%w: invalid delta time", ErrMalformedPacket)
+}
+
+// appendDelta encodes a delta time of up to 28 bits.
+func appendDelta(b []byte, delta uint32) []byte {
+	delta = min(delta, 1<<28-1)
+	for shift := 21; shift > 0; shift -= 7 {
+		if delta >= 1<<shift {
+			b = append(b, byte(delta>>shift&0x7F)|0x80)
+		}
+	}
+	return append(b, byte(delta&0x7F))
+}
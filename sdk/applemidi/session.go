@@ -0,0 +1,563 @@
+// Package applemidi implements the session layer of AppleMIDI (RTP-MIDI, RFC 6295 with
+// Apple's session protocol): accepting and rejecting invitations, inviting peers, keeping
+// the participant list and synchronizing clocks. The MIDI payload carried in RTP packets
+// is handed to a data handler and written with SendData; ParseDataPacket and
+// DataPacket.Marshal convert it from and to MIDI commands.
+package applemidi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/logger"
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/discovery"
+)
+
+// Error definitions for session handling.
+var (
+	ErrInvitationRejected = errors.New("invitation rejected by peer")
+	ErrSessionClosed      = errors.New("AppleMIDI session closed")
+	ErrUnknownParticipant = errors.New("unknown AppleMIDI participant")
+)
+
+// invitationRetry is how often an unanswered invitation is repeated.
+const invitationRetry = time.Second
+
+// Invitation describes an incoming request to join the session.
+type Invitation struct {
+	Name string       // Session name of the inviting peer.
+	SSRC uint32       // Synchronization source of the inviting peer.
+	Addr *net.UDPAddr // Control address of the inviting peer.
+}
+
+// Participant is a snapshot of a peer in the session.
+type Participant struct {
+	Name        string        // Session name of the peer.
+	SSRC        uint32        // Synchronization source of the peer.
+	ControlAddr *net.UDPAddr  // Control port address of the peer.
+	DataAddr    *net.UDPAddr  // Data port address of the peer; nil until the peer has fully joined.
+	Initiator   bool          // True if this side invited the peer.
+	JoinedAt    time.Time     // Time the peer fully joined.
+	Synced      bool          // True once a clock synchronization has completed.
+	LastSync    time.Time     // Time of the last completed clock synchronization.
+	ClockOffset time.Duration // Peer clock minus local clock, as estimated by the last synchronization.
+	Latency     time.Duration // One-way network latency estimated by the last synchronization.
+	LastSeq     uint16        // Last sequence number the peer acknowledged with receiver feedback.
+}
+
+// Session is an AppleMIDI session listening on a control and a data UDP port.
+type Session struct {
+	options      SessionOptions
+	ssrc         uint32
+	start        time.Time // Origin of the session clock.
+	control      *net.UDPConn
+	data         *net.UDPConn
+	advert       *discovery.Advertisement
+	mu           sync.Mutex
+	participants map[uint32]*Participant
+	pending      map[uint32]chan invitationPacket // Answers to outgoing invitations, keyed by token.
+	closed       bool
+	done         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewSession opens the control and data ports and starts answering peers.
+//
+// opts ...SessionOption: A variadic list of option functions to customize the session.
+//
+// Returns:
+//   - *Session: The running session.
+//   - error: An error if the ports could not be opened.
+func NewSession(opts ...SessionOption) (*Session, error) {
+	options := SessionOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.Name == "" {
+		options.Name = "Go MIDI Session"
+	}
+	if options.Port == 0 {
+		options.Port = 5004
+	}
+	if options.SyncInterval <= 0 {
+		options.SyncInterval = 10 * time.Second
+	}
+	if options.Logger == nil {
+		options.Logger = logger.NewZapLogger()
+	}
+
+	control, err := net.ListenUDP("udp", &net.UDPAddr{Port: options.Port})
+	if err != nil {
+		return nil, fmt.Errorf("error opening AppleMIDI control port %d: %w", options.Port, err)
+	}
+	data, err := net.ListenUDP("udp", &net.UDPAddr{Port: options.Port + 1})
+	if err != nil {
+		control.Close()
+		return nil, fmt.Errorf("error opening AppleMIDI data port %d: %w", options.Port+1, err)
+	}
+
+	s := &Session{
+		options:      options,
+		ssrc:         rand.Uint32(),
+		start:        time.Now(),
+		control:      control,
+		data:         data,
+		participants: make(map[uint32]*Participant),
+		pending:      make(map[uint32]chan invitationPacket),
+		done:         make(chan struct{}),
+	}
+
+	if options.Advertise {
+		s.advert, err = discovery.Advertise(options.Name, discovery.AppleMIDIService, options.Port)
+		if err != nil {
+			options.Logger.Warn("Failed to announce AppleMIDI session via mDNS", options.Logger.Field().Error("error", err))
+		}
+	}
+
+	s.wg.Add(3)
+	profiling.Go(profiling.RoleCapture, "applemidi", options.Name, func() { s.listen(control, false) })
+	profiling.Go(profiling.RoleCapture, "applemidi", options.Name, func() { s.listen(data, true) })
+	profiling.Go(profiling.RoleScheduler, "applemidi", options.Name, s.syncPeriodically)
+
+	options.Logger.Info("AppleMIDI session started",
+		options.Logger.Field().String("name", options.Name),
+		options.Logger.Field().Int("port", options.Port))
+	return s, nil
+}
+
+// SSRC returns the synchronization source identifying this side of the session.
+func (s *Session) SSRC() uint32 {
+	return s.ssrc
+}
+
+// Now returns the session clock in units of 100 microseconds, as used by AppleMIDI timestamps.
+func (s *Session) Now() uint64 {
+	return uint64(time.Since(s.start) / (100 * time.Microsecond))
+}
+
+// Participants returns a snapshot of the peers that have fully joined the session.
+func (s *Session) Participants() []Participant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	participants := make([]Participant, 0, len(s.participants))
+	for _, p := range s.participants {
+		if p.DataAddr != nil {
+			participants = append(participants, *p)
+		}
+	}
+	return participants
+}
+
+// Invite invites the peer whose control port is at address (host:port) and waits until
+// it has joined on both ports, ctx is done or the peer rejects the invitation.
+func (s *Session) Invite(ctx context.Context, address string) (Participant, error) {
+	controlAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return Participant{}, fmt.Errorf("error resolving AppleMIDI peer %s: %w", address, err)
+	}
+	dataAddr := &net.UDPAddr{IP: controlAddr.IP, Port: controlAddr.Port + 1, Zone: controlAddr.Zone}
+
+	answer, err := s.invite(ctx, s.control, controlAddr)
+	if err != nil {
+		return Participant{}, err
+	}
+	if _, err := s.invite(ctx, s.data, dataAddr); err != nil {
+		return Participant{}, err
+	}
+
+	s.mu.Lock()
+	p := &Participant{
+		Name:        answer.Name,
+		SSRC:        answer.SSRC,
+		ControlAddr: controlAddr,
+		DataAddr:    dataAddr,
+		Initiator:   true,
+		JoinedAt:    time.Now(),
+	}
+	s.participants[p.SSRC] = p
+	snapshot := *p
+	s.mu.Unlock()
+
+	s.joined(snapshot)
+	s.synchronize(snapshot)
+	return snapshot, nil
+}
+
+// invite sends an invitation from conn to addr until it is answered.
+func (s *Session) invite(ctx context.Context, conn *net.UDPConn, addr *net.UDPAddr) (invitationPacket, error) {
+	token := rand.Uint32()
+	answers := make(chan invitationPacket, 1)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return invitationPacket{}, ErrSessionClosed
+	}
+	s.pending[token] = answers
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, token)
+		s.mu.Unlock()
+	}()
+
+	packet := invitationPacket{Command: commandInvitation, Token: token, SSRC: s.ssrc, Name: s.options.Name}.marshal()
+	ticker := time.NewTicker(invitationRetry)
+	defer ticker.Stop()
+
+	for {
+		if _, err := conn.WriteToUDP(packet, addr); err != nil {
+			return invitationPacket{}, fmt.Errorf("error sending AppleMIDI invitation to %s: %w", addr, err)
+		}
+
+		select {
+		case answer := <-answers:
+			if answer.Command == commandReject {
+				return invitationPacket{}, fmt.Errorf("%w: %s", ErrInvitationRejected, addr)
+			}
+			return answer, nil
+		case <-ctx.Done():
+			return invitationPacket{}, ctx.Err()
+		case <-s.done:
+			return invitationPacket{}, ErrSessionClosed
+		case <-ticker.C:
+		}
+	}
+}
+
+// End removes a participant from the session, notifying it with an end packet.
+func (s *Session) End(ssrc uint32) error {
+	s.mu.Lock()
+	p, ok := s.participants[ssrc]
+	if ok {
+		delete(s.participants, ssrc)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %08x", ErrUnknownParticipant, ssrc)
+	}
+
+	bye := invitationPacket{Command: commandEnd, Token: rand.Uint32(), SSRC: s.ssrc}.marshal()
+	_, err := s.control.WriteToUDP(bye, p.ControlAddr)
+	s.left(*p)
+	return err
+}
+
+// SendData sends an RTP-MIDI packet to every participant that has fully joined.
+func (s *Session) SendData(packet []byte) error {
+	var errs []error
+	for _, p := range s.Participants() {
+		if _, err := s.data.WriteToUDP(packet, p.DataAddr); err != nil {
+			errs = append(errs, fmt.Errorf("error sending to %s: %w", p.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendTo sends an RTP-MIDI packet to the participant identified by ssrc.
+func (s *Session) SendTo(ssrc uint32, packet []byte) error {
+	s.mu.Lock()
+	var participant Participant
+	if p, ok := s.participants[ssrc]; ok {
+		participant = *p
+	}
+	s.mu.Unlock()
+
+	if participant.DataAddr == nil {
+		return fmt.Errorf("%w: %08x", ErrUnknownParticipant, ssrc)
+	}
+	if _, err := s.data.WriteToUDP(packet, participant.DataAddr); err != nil {
+		return fmt.Errorf("error sending to %s: %w", participant.Name, err)
+	}
+	return nil
+}
+
+// SendFeedback acknowledges the last RTP sequence number received from all participants,
+// allowing senders to trim their recovery journal.
+func (s *Session) SendFeedback(sequence uint16) error {
+	packet := feedbackPacket{SSRC: s.ssrc, Sequence: sequence}.marshal()
+
+	var errs []error
+	for _, p := range s.Participants() {
+		if _, err := s.control.WriteToUDP(packet, p.ControlAddr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close ends the session with every participant and closes the ports.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	participants := make([]uint32, 0, len(s.participants))
+	for ssrc := range s.participants {
+		participants = append(participants, ssrc)
+	}
+	s.mu.Unlock()
+
+	for _, ssrc := range participants {
+		_ = s.End(ssrc)
+	}
+	if s.advert != nil {
+		_ = s.advert.Close()
+	}
+
+	close(s.done)
+	err := errors.Join(s.control.Close(), s.data.Close())
+	s.wg.Wait()
+
+	s.options.Logger.Info("AppleMIDI session closed", s.options.Logger.Field().String("name", s.options.Name))
+	return err
+}
+
+// listen reads packets from one of the session ports until it is closed.
+func (s *Session) listen(conn *net.UDPConn, isData bool) {
+	defer s.wg.Done()
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				s.options.Logger.Error("AppleMIDI socket read failed", s.options.Logger.Field().Error("error", err))
+			}
+			return
+		}
+
+		packet := buf[:n]
+		if !isExchangePacket(packet) {
+			if isData {
+				s.handleData(packet)
+			}
+			continue
+		}
+
+		switch exchangeCommand(packet) {
+		case commandInvitation, commandAccept, commandReject, commandEnd:
+			if p, err := parseInvitation(packet); err == nil {
+				s.handleInvitation(conn, addr, p, isData)
+			}
+		case commandSync:
+			if p, err := parseSync(packet); err == nil {
+				s.handleSync(conn, addr, p)
+			}
+		case commandFeedback:
+			if p, err := parseFeedback(packet); err == nil {
+				s.handleFeedback(p)
+			}
+		default:
+			s.options.Logger.Debug("Unknown AppleMIDI command", s.options.Logger.Field().String("command", exchangeCommand(packet)))
+		}
+	}
+}
+
+// handleInvitation processes IN, OK, NO and BY packets.
+func (s *Session) handleInvitation(conn *net.UDPConn, addr *net.UDPAddr, p invitationPacket, isData bool) {
+	switch p.Command {
+	case commandAccept, commandReject:
+		s.mu.Lock()
+		answers, ok := s.pending[p.Token]
+		s.mu.Unlock()
+		if ok {
+			select {
+			case answers <- p:
+			default:
+			}
+		}
+
+	case commandEnd:
+		s.mu.Lock()
+		participant, ok := s.participants[p.SSRC]
+		delete(s.participants, p.SSRC)
+		s.mu.Unlock()
+		if ok {
+			s.left(*participant)
+		}
+
+	case commandInvitation:
+		answer := invitationPacket{Command: commandAccept, Token: p.Token, SSRC: s.ssrc, Name: s.options.Name}
+
+		s.mu.Lock()
+		participant, known := s.participants[p.SSRC]
+		s.mu.Unlock()
+
+		switch {
+		case !isData && known:
+			// A repeated control invitation: answer again without asking the handler.
+		case !isData:
+			invitation := Invitation{Name: p.Name, SSRC: p.SSRC, Addr: addr}
+			if s.options.OnInvitation != nil && !s.options.OnInvitation(invitation) {
+				answer.Command = commandReject
+				s.options.Logger.Info("AppleMIDI invitation rejected", s.options.Logger.Field().String("peer", p.Name))
+				break
+			}
+			s.mu.Lock()
+			s.participants[p.SSRC] = &Participant{Name: p.Name, SSRC: p.SSRC, ControlAddr: addr}
+			s.mu.Unlock()
+		case !known:
+			// Data invitations are only accepted after the control invitation.
+			answer.Command = commandReject
+		default:
+			s.mu.Lock()
+			first := participant.DataAddr == nil
+			participant.DataAddr = addr
+			if first {
+				participant.JoinedAt = time.Now()
+			}
+			snapshot := *participant
+			s.mu.Unlock()
+			if first {
+				defer s.joined(snapshot)
+			}
+		}
+
+		if _, err := conn.WriteToUDP(answer.marshal(), addr); err != nil {
+			s.options.Logger.Error("Failed to answer AppleMIDI invitation", s.options.Logger.Field().Error("error", err))
+		}
+	}
+}
+
+// handleSync processes a clock synchronization packet. The initiator sends count 0,
+// the responder answers with count 1 and the initiator completes with count 2. Both
+// sides estimate latency and clock offset once they know all three timestamps.
+func (s *Session) handleSync(conn *net.UDPConn, addr *net.UDPAddr, p syncPacket) {
+	switch p.Count {
+	case 0:
+		p.Count = 1
+		p.Timestamps[1] = s.Now()
+	case 1:
+		p.Count = 2
+		p.Timestamps[2] = s.Now()
+		s.recordSync(p.SSRC, p.Timestamps, true)
+	case 2:
+		s.recordSync(p.SSRC, p.Timestamps, false)
+		return
+	default:
+		return
+	}
+
+	p.SSRC = s.ssrc
+	if _, err := conn.WriteToUDP(p.marshal(), addr); err != nil {
+		s.options.Logger.Error("Failed to answer AppleMIDI clock sync", s.options.Logger.Field().Error("error", err))
+	}
+}
+
+// recordSync stores the result of a completed clock synchronization with a participant.
+// Timestamps 0 and 2 are taken on the initiator's clock, timestamp 1 on the responder's.
+func (s *Session) recordSync(ssrc uint32, ts [3]uint64, initiator bool) {
+	const unit = 100 * time.Microsecond
+
+	midpoint := int64(ts[0]+ts[2]) / 2
+	offset := time.Duration(int64(ts[1])-midpoint) * unit // Responder clock minus initiator clock.
+	if !initiator {
+		offset = -offset
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.participants[ssrc]
+	if !ok {
+		return
+	}
+	p.Synced = true
+	p.LastSync = time.Now()
+	p.ClockOffset = offset
+	p.Latency = time.Duration(ts[2]-ts[0]) * unit / 2
+}
+
+// handleFeedback records the sequence number a participant acknowledged.
+func (s *Session) handleFeedback(p feedbackPacket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if participant, ok := s.participants[p.SSRC]; ok {
+		participant.LastSeq = p.Sequence
+	}
+}
+
+// handleData hands an RTP packet to the data handler, identifying the sender by its SSRC.
+func (s *Session) handleData(packet []byte) {
+	if s.options.OnData == nil || len(packet) < 12 {
+		return
+	}
+
+	ssrc := binary.BigEndian.Uint32(packet[8:12])
+	s.mu.Lock()
+	participant, ok := s.participants[ssrc]
+	var snapshot Participant
+	if ok {
+		snapshot = *participant
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		s.options.Logger.Debug("RTP-MIDI packet from unknown participant", s.options.Logger.Field().String("ssrc", strconv.FormatUint(uint64(ssrc), 16)))
+		return
+	}
+	s.options.OnData(snapshot, packet)
+}
+
+// synchronize starts a clock synchronization with a participant this side invited.
+func (s *Session) synchronize(p Participant) {
+	packet := syncPacket{SSRC: s.ssrc, Count: 0}
+	packet.Timestamps[0] = s.Now()
+	if _, err := s.data.WriteToUDP(packet.marshal(), p.DataAddr); err != nil {
+		s.options.Logger.Warn("Failed to send AppleMIDI clock sync", s.options.Logger.Field().Error("error", err))
+	}
+}
+
+// syncPeriodically repeats clock synchronization with invited participants.
+func (s *Session) syncPeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.options.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			for _, p := range s.Participants() {
+				if p.Initiator {
+					s.synchronize(p)
+				}
+			}
+		}
+	}
+}
+
+// joined logs a participant that fully joined and calls the handler.
+func (s *Session) joined(p Participant) {
+	s.options.Logger.Info("AppleMIDI participant joined", s.options.Logger.Field().String("peer", p.Name))
+	if s.options.OnJoined != nil {
+		s.options.OnJoined(p)
+	}
+}
+
+// left logs a participant that left and calls the handler.
+func (s *Session) left(p Participant) {
+	s.options.Logger.Info("AppleMIDI participant left", s.options.Logger.Field().String("peer", p.Name))
+	if s.options.OnLeft != nil {
+		s.options.OnLeft(p)
+	}
+}
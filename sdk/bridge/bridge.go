@@ -0,0 +1,306 @@
+// Package bridge streams the events of a MIDI client to web pages over WebSockets and
+// sends the messages the pages write back to the output device of the client, so
+// visualizers, teaching apps and other web UIs can use local MIDI devices:
+//
+//	server := bridge.NewServer(client, bridge.WithOriginPatterns("localhost:5173"))
+//	defer server.Stop()
+//	http.Handle("/midi", server)
+//	http.ListenAndServe(":8080", nil)
+//
+// and in the page:
+//
+//	const socket = new WebSocket("ws://localhost:8080/midi")
+//	socket.onmessage = (message) => console.log(JSON.parse(message.data))
+//	socket.send(JSON.stringify({command: 0x90, note: 60, velocity: 100}))
+//
+// Events are sent as text messages holding their JSON form, that of
+// contracts.MIDI.MarshalJSON, or, with "?format=protobuf" in the URL, as binary messages
+// holding the MIDIEvent of sdk/wire/midi.proto. Messages from the page are decoded the
+// same way: text messages as JSON and binary messages as Protobuf. When the client fails
+// to send one, the page receives a text message {"error": "..."}.
+package bridge
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/wire"
+)
+
+const (
+	// maxMessageSize limits the messages read from pages; an encoded event takes a few
+	// dozen bytes.
+	maxMessageSize = 4096
+	// writeTimeout is how long a page may take to receive a message before it is
+	// disconnected.
+	writeTimeout = 10 * time.Second
+	// maxCloseReason is the longest reason a close message can hold.
+	maxCloseReason = 123
+)
+
+// Server shares a local MIDI client with web pages. Events captured from the selected
+// device are broadcast to every connected page. Server is an http.Handler, to be mounted
+// on a path of an existing HTTP server, or served on its own with Serve.
+type Server struct {
+	client      contracts.ClientMIDI
+	options     Options
+	mu          sync.Mutex                       // Protects subscribers, capturing and httpServer.
+	subscribers map[chan contracts.MIDI]struct{} // Event channels of the connected pages.
+	capturing   bool                             // Indicates if the local capture has been started.
+	events      chan contracts.MIDI              // Channel the local client captures into.
+	httpServer  *http.Server
+	done        chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewServer creates a server exposing client. The server starts capturing on the local
+// client when the first page connects.
+//
+// client contracts.ClientMIDI: The local client whose events are streamed.
+// opts ...Option: A variadic list of option functions to customize the server.
+//
+// Returns:
+//   - *Server: The server, ready to be mounted or served.
+func NewServer(client contracts.ClientMIDI, opts ...Option) *Server {
+	options := applyDefaultOptions(opts...)
+	return &Server{
+		client:      client,
+		options:     options,
+		subscribers: make(map[chan contracts.MIDI]struct{}),
+		events:      make(chan contracts.MIDI, options.SubscriberBuffer),
+		done:        make(chan struct{}),
+	}
+}
+
+// Serve creates an HTTP server answering WebSocket connections on every path and serves
+// it on lis. It blocks until Stop is called or lis fails.
+func (s *Server) Serve(lis net.Listener) error {
+	s.mu.Lock()
+	s.httpServer = &http.Server{Handler: s}
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	s.options.Logger.Info("MIDI WebSocket bridge listening", s.options.Logger.Field().String("address", lis.Addr().String()))
+	if err := httpServer.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Stop disconnects the pages, stops the HTTP server started by Serve and the local
+// capture. The local client itself is stopped too, since the server owns its capture.
+func (s *Server) Stop() error {
+	var err error
+	s.stopOnce.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		httpServer := s.httpServer
+		s.mu.Unlock()
+
+		if httpServer != nil {
+			_ = httpServer.Close()
+		}
+		err = s.client.Stop()
+	})
+	return err
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection, then streams events to it and
+// sends the messages it receives until either side closes it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+		return
+	}
+	format, ok := requestFormat(r, s.options.Format)
+	if !ok {
+		http.Error(w, "unknown format "+r.URL.Query().Get("format"), http.StatusBadRequest)
+		return
+	}
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: s.options.OriginPatterns})
+	if err != nil {
+		s.options.Logger.Warn("Failed to accept WebSocket connection", s.options.Logger.Field().Error("error", err))
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(maxMessageSize)
+
+	sub, err := s.subscribe()
+	if err != nil {
+		conn.Close(websocket.StatusInternalError, closeReason(err))
+		return
+	}
+	defer s.unsubscribe(sub)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	profiling.Go(profiling.RoleCapture, "bridge", r.RemoteAddr, func() {
+		defer cancel()
+		s.receive(ctx, conn)
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			conn.Close(websocket.StatusGoingAway, "server stopping")
+			return
+		case event := <-sub:
+			if err := s.write(ctx, conn, event, format); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// write sends event to a page in format.
+func (s *Server) write(ctx context.Context, conn *websocket.Conn, event contracts.MIDI, format wire.Format) error {
+	data, err := wire.Marshal(event, format)
+	if err != nil {
+		return err
+	}
+	messageType := websocket.MessageText
+	if format == wire.Protobuf {
+		messageType = websocket.MessageBinary
+	}
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	return conn.Write(ctx, messageType, data)
+}
+
+// receive sends the messages of a page to the output device of the client until the page
+// disconnects. Pages sending undecodable messages, or any message to a read-only server,
+// are disconnected; failures to send are reported to the page.
+func (s *Server) receive(ctx context.Context, conn *websocket.Conn) {
+	for {
+		messageType, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+		if s.options.ReadOnly {
+			conn.Close(websocket.StatusPolicyViolation, "the bridge does not accept messages")
+			return
+		}
+		format := wire.JSON
+		if messageType == websocket.MessageBinary {
+			format = wire.Protobuf
+		}
+		event, err := wire.Unmarshal(data, format)
+		if err != nil {
+			conn.Close(websocket.StatusUnsupportedData, closeReason(err))
+			return
+		}
+		if err := s.client.Send(event); err != nil {
+			s.options.Logger.Warn("Failed to send MIDI message from WebSocket", s.options.Logger.Field().Error("error", err))
+			reply, _ := json.Marshal(map[string]string{"error": err.Error()})
+			if err := conn.Write(ctx, websocket.MessageText, reply); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// authorized reports whether the request carries one of the accepted tokens, in the
+// "token" query parameter or as "Authorization: Bearer <token>". Every request is
+// accepted when there are none.
+func (s *Server) authorized(r *http.Request) bool {
+	if len(s.options.Tokens) == 0 {
+		return true
+	}
+	token := r.URL.Query().Get("token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+	for _, accepted := range s.options.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(accepted)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// requestFormat returns the format asked for in the "format" query parameter of r, "json"
+// or "protobuf", or fallback when there is none. It reports false for unknown formats.
+func requestFormat(r *http.Request, fallback wire.Format) (wire.Format, bool) {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "":
+		return fallback, true
+	case "json":
+		return wire.JSON, true
+	case "protobuf":
+		return wire.Protobuf, true
+	default:
+		return 0, false
+	}
+}
+
+// closeReason returns the message of err, shortened to fit in a close message.
+func closeReason(err error) string {
+	reason := err.Error()
+	if len(reason) > maxCloseReason {
+		reason = reason[:maxCloseReason]
+	}
+	return reason
+}
+
+// subscribe registers a new page, starting the local capture on first use. It fails with
+// the error of the local client when the capture cannot start.
+func (s *Server) subscribe() (chan contracts.MIDI, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.capturing {
+		if err := s.client.StartCapture(s.events); err != nil {
+			s.options.Logger.Error("Failed to start local MIDI capture", s.options.Logger.Field().Error("error", err))
+			return nil, err
+		}
+		s.capturing = true
+		profiling.Go(profiling.RoleDispatch, "bridge", "", s.broadcast)
+	}
+
+	sub := make(chan contracts.MIDI, s.options.SubscriberBuffer)
+	s.subscribers[sub] = struct{}{}
+	s.options.Logger.Info("WebSocket page connected", s.options.Logger.Field().Int("subscribers", len(s.subscribers)))
+	return sub, nil
+}
+
+// unsubscribe removes a page.
+func (s *Server) unsubscribe(sub chan contracts.MIDI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subscribers, sub)
+	s.options.Logger.Info("WebSocket page disconnected", s.options.Logger.Field().Int("subscribers", len(s.subscribers)))
+}
+
+// broadcast forwards locally captured events to every page. A page that cannot keep up
+// loses events instead of stalling the others.
+func (s *Server) broadcast() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case event := <-s.events:
+			s.mu.Lock()
+			for sub := range s.subscribers {
+				select {
+				case sub <- event:
+				default:
+					s.options.Logger.Warn("WebSocket subscriber buffer full; dropping MIDI event")
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package bridge
+
+import (
+	"github.com/leandrodaf/midi/internal/logger"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/wire"
+)
+
+// Options holds the configuration of a Server.
+type Options struct {
+	Logger           contracts.Logger // Logger for server events and errors.
+	SubscriberBuffer int              // Number of events buffered per connected browser before dropping.
+	Format           wire.Format      // Encoding of the events sent to browsers not asking for one.
+	OriginPatterns   []string         // Host patterns of the pages allowed to connect besides the server's own.
+	Tokens           []string         // Tokens accepted from browsers; empty accepts every browser.
+	ReadOnly         bool             // Whether messages received from browsers are rejected instead of sent.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithLogger sets the logger used by the server.
+func WithLogger(l contracts.Logger) Option {
+	return func(opts *Options) {
+		opts.Logger = l
+	}
+}
+
+// WithSubscriberBuffer sets how many events are buffered for each connected browser.
+func WithSubscriberBuffer(size int) Option {
+	return func(opts *Options) {
+		opts.SubscriberBuffer = size
+	}
+}
+
+// WithFormat sets how events are encoded for browsers that do not ask for a format in
+// the URL: wire.JSON in text messages, the default, or wire.Protobuf in binary messages.
+func WithFormat(format wire.Format) Option {
+	return func(opts *Options) {
+		opts.Format = format
+	}
+}
+
+// WithOriginPatterns allows pages served from other hosts to connect, such as
+// "localhost:5173" for a development server or "*.example.com". Without it, only pages
+// served by the same host as the bridge can connect.
+func WithOriginPatterns(patterns ...string) Option {
+	return func(opts *Options) {
+		opts.OriginPatterns = append(opts.OriginPatterns, patterns...)
+	}
+}
+
+// WithTokens makes the server reject browsers without one of the tokens, passed in the
+// "token" query parameter of the URL since browsers cannot set headers on WebSockets.
+// Serve over TLS to keep them secret.
+func WithTokens(tokens ...string) Option {
+	return func(opts *Options) {
+		opts.Tokens = append(opts.Tokens, tokens...)
+	}
+}
+
+// WithReadOnly makes the server only stream events, closing the connections of browsers
+// that send messages, e.g. for visualizers shared with an audience.
+func WithReadOnly() Option {
+	return func(opts *Options) {
+		opts.ReadOnly = true
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.Logger == nil {
+		options.Logger = logger.NewZapLogger()
+	}
+	if options.SubscriberBuffer <= 0 {
+		options.SubscriberBuffer = 256
+	}
+	return options
+}
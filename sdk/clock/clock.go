@@ -0,0 +1,128 @@
+// Package clock follows external sequencers through the MIDI real-time messages they
+// send: the timing clock, 24 ticks per quarter note, Start, Stop, Continue and the song
+// position pointer. TempoEstimator derives the tempo from the clock ticks, and Follower
+// tracks the transport and the song position along with it. The other way around,
+// ClockMaster sends the clock and the transport messages to lead external devices.
+//
+// Real-time messages are delivered as events by every backend, so the components are fed
+// from a capture channel or handler:
+//
+//	follower := clock.NewFollower()
+//	client.StartCaptureFunc(func(event contracts.MIDI) { follower.Handle(event) })
+package clock
+
+import (
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+const (
+	PulsesPerQuarter   = 24                   // Clock ticks per quarter note.
+	PulsesPerSixteenth = PulsesPerQuarter / 4 // Clock ticks per sixteenth note, the unit of song position pointers.
+)
+
+// State is the transport state of an external sequencer.
+type State int
+
+const (
+	Stopped State = iota // Stopped by a Stop message, or not started yet.
+	Playing              // Started by a Start or Continue message.
+)
+
+// String names the state.
+func (s State) String() string {
+	if s == Playing {
+		return "playing"
+	}
+	return "stopped"
+}
+
+// Follower tracks the transport, the song position and the tempo of an external
+// sequencer from its real-time messages. It is safe for concurrent use.
+type Follower struct {
+	options Options
+	tempo   *TempoEstimator
+
+	mu       sync.Mutex
+	state    State
+	position int // Position of the next tick, in ticks from the start of the song.
+}
+
+// NewFollower creates a stopped follower at the start of the song.
+//
+// opts ...Option: A variadic list of option functions to customize the follower and its tempo estimate.
+//
+// Returns:
+//   - *Follower: The follower, ready to handle events.
+func NewFollower(opts ...Option) *Follower {
+	options := applyDefaultOptions(opts...)
+	return &Follower{options: options, tempo: NewTempoEstimator(opts...)}
+}
+
+// Handle updates the follower with event and reports whether it was a clock, transport or
+// song position pointer message. Other events are ignored. Clock ticks feed the tempo
+// estimate whether the sequencer is playing or not, as many send them while stopped, and
+// advance the position while playing.
+func (f *Follower) Handle(event contracts.MIDI) bool {
+	f.mu.Lock()
+	switch event.Type() {
+	case contracts.MessageClock:
+		if f.state == Playing {
+			f.position++
+		}
+		f.mu.Unlock()
+		f.tempo.Tick(event.Timestamp)
+		return true
+	case contracts.MessageStart:
+		f.state, f.position = Playing, 0
+	case contracts.MessageContinue:
+		f.state = Playing
+	case contracts.MessageStop:
+		f.state = Stopped
+	case contracts.MessageSongPosition:
+		f.position = event.SongPosition() * PulsesPerSixteenth
+	default:
+		f.mu.Unlock()
+		return false
+	}
+	state, position := f.state, f.position
+	f.mu.Unlock()
+
+	if f.options.OnTransport != nil {
+		f.options.OnTransport(state, position)
+	}
+	return true
+}
+
+// State returns the transport state of the sequencer.
+func (f *Follower) State() State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+// Position returns the position of the song the next clock tick plays, in ticks from its
+// start: the number of ticks played since Start, or since the position set by a song
+// position pointer.
+func (f *Follower) Position() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.position
+}
+
+// Beats returns the position in quarter notes from the start of the song.
+func (f *Follower) Beats() float64 {
+	return float64(f.Position()) / PulsesPerQuarter
+}
+
+// BPM returns the tempo of the clock in quarter notes per minute, and false until it is
+// known.
+func (f *Follower) BPM() (float64, bool) {
+	return f.tempo.BPM()
+}
+
+// Tempo returns the estimator of the tempo, fed with the clock ticks the follower handles.
+func (f *Follower) Tempo() *TempoEstimator {
+	return f.tempo
+}
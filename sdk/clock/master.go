@@ -0,0 +1,289 @@
+package clock
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/internal/threadprio"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// DefaultBPM is the tempo of a ClockMaster created with a tempo of zero or less.
+const DefaultBPM = 120
+
+// Sender sends messages to an output device. contracts.ClientMIDI implements it once an
+// output device is selected with SelectOutputDevice.
+type Sender interface {
+	Send(event contracts.MIDI) error
+}
+
+// ClockMaster makes the application the tempo master of external devices: it sends the
+// timing clock, 24 ticks per quarter note, and Start, Stop, Continue and song position
+// pointer messages to an output. It is safe for concurrent use.
+//
+// Ticks are scheduled on a dedicated OS thread with raised priority where the platform
+// supports it, at fixed times from the last start or tempo change rather than one interval
+// after the previous tick, so the lateness of one tick does not delay the following ones
+// and the clock does not drift. When ticks are late by more than a quarter note, e.g.
+// after the system was suspended, the missed ticks are skipped instead of sent in a burst.
+type ClockMaster struct {
+	out     Sender
+	options Options
+
+	sendMu sync.Mutex // Orders the ticks sent by the scheduler and the transport messages.
+
+	mu         sync.Mutex
+	state      State
+	bpm        float64
+	position   int       // Position of the next tick, in ticks from the start of the song.
+	anchorTime time.Time // When the first tick since the last start or tempo change was due.
+	ticks      int64     // Ticks sent since anchorTime.
+	generation uint64    // Incremented on every change of the transport or tempo.
+	wake       chan struct{}
+	done       chan struct{}
+	wg         sync.WaitGroup
+	closeOnce  sync.Once
+}
+
+// NewClockMaster creates a stopped clock master sending to out. It sends no ticks until
+// Start or Continue, unless WithClockWhileStopped is given.
+//
+// out Sender: The destination of the clock, such as a client with an output device selected.
+// bpm float64: The tempo in quarter notes per minute; DefaultBPM if zero or less.
+// opts ...Option: A variadic list of option functions to customize the clock master.
+//
+// Returns:
+//   - *ClockMaster: The clock master, ready to Start.
+func NewClockMaster(out Sender, bpm float64, opts ...Option) *ClockMaster {
+	if bpm <= 0 {
+		bpm = DefaultBPM
+	}
+	c := &ClockMaster{
+		out:        out,
+		options:    applyDefaultOptions(opts...),
+		bpm:        bpm,
+		anchorTime: time.Now(),
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+
+	c.wg.Add(1)
+	profiling.Go(profiling.RoleScheduler, "clock", "", c.schedule)
+	return c
+}
+
+// Start sends a Start message and plays from the start of the song, the first tick
+// following right after it.
+func (c *ClockMaster) Start() {
+	c.transport(contracts.MessageStart, func() {
+		c.state, c.position = Playing, 0
+		c.anchorLocked(time.Now())
+	})
+}
+
+// Continue sends a Continue message and plays from the current position. It does nothing
+// while playing.
+func (c *ClockMaster) Continue() {
+	if c.State() == Playing {
+		return
+	}
+	c.transport(contracts.MessageContinue, func() {
+		c.state = Playing
+		c.anchorLocked(time.Now())
+	})
+}
+
+// Stop sends a Stop message and holds the current position. Continue resumes from it.
+func (c *ClockMaster) Stop() {
+	c.transport(contracts.MessageStop, func() {
+		c.state = Stopped
+	})
+}
+
+// SetPosition sends a song position pointer moving the song to sixteenths sixteenth notes
+// from its start. Devices follow it while stopped, so send it before Continue.
+func (c *ClockMaster) SetPosition(sixteenths int) {
+	sixteenths = min(max(sixteenths, 0), 0x3FFF)
+	event := contracts.MIDI{Command: byte(contracts.MessageSongPosition), Note: byte(sixteenths & 0x7F), Velocity: byte(sixteenths >> 7)}
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	c.mu.Lock()
+	c.position = sixteenths * PulsesPerSixteenth
+	c.mu.Unlock()
+	c.send(event)
+}
+
+// SetBPM changes the tempo from the next tick on. Tempos of zero or less are ignored.
+func (c *ClockMaster) SetBPM(bpm float64) {
+	if bpm <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ticks > 0 {
+		// The next tick is due one interval of the new tempo after the last one
+		c.anchorTime = c.dueLocked(c.ticks - 1)
+		c.ticks = 1
+	}
+	c.bpm = bpm
+	c.changedLocked()
+}
+
+// BPM returns the tempo in quarter notes per minute.
+func (c *ClockMaster) BPM() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bpm
+}
+
+// State returns the transport state.
+func (c *ClockMaster) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Position returns the position of the song the next tick plays, in ticks from its start.
+func (c *ClockMaster) Position() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.position
+}
+
+// Close sends a Stop message if playing and stops the scheduler. The clock master cannot
+// be used afterwards.
+func (c *ClockMaster) Close() error {
+	if c.State() == Playing {
+		c.Stop()
+	}
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.wg.Wait()
+	})
+	return nil
+}
+
+// transport applies change and sends a message of type kind, before any further tick.
+func (c *ClockMaster) transport(kind contracts.MessageType, change func()) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	c.mu.Lock()
+	change()
+	c.changedLocked()
+	c.mu.Unlock()
+	c.send(contracts.MIDI{Command: byte(kind)})
+}
+
+// schedule sends the ticks as they are due, until Close.
+func (c *ClockMaster) schedule() {
+	defer c.wg.Done()
+
+	// The thread is not unlocked, so it exits with the goroutine instead of going back to
+	// the scheduler with a raised priority.
+	runtime.LockOSThread()
+	_ = threadprio.Raise() // Best effort; timing is still accurate with polling
+
+	for {
+		c.mu.Lock()
+		if c.state != Playing && !c.options.ClockWhileStopped {
+			c.mu.Unlock()
+			select {
+			case <-c.done:
+				return
+			case <-c.wake:
+			}
+			continue
+		}
+		if late := time.Since(c.dueLocked(c.ticks)); late > c.intervalLocked()*PulsesPerQuarter {
+			c.anchorLocked(time.Now())
+		}
+		generation := c.generation
+		due := c.dueLocked(c.ticks)
+		c.mu.Unlock()
+
+		if !c.wait(due) {
+			return
+		}
+
+		c.sendMu.Lock()
+		c.mu.Lock()
+		if c.generation != generation || time.Now().Before(due) {
+			c.mu.Unlock()
+			c.sendMu.Unlock()
+			continue
+		}
+		c.ticks++
+		if c.state == Playing {
+			c.position++
+		}
+		c.mu.Unlock()
+		c.send(contracts.MIDI{Command: byte(contracts.MessageClock)})
+		c.sendMu.Unlock()
+	}
+}
+
+// wait waits until due, sleeping until the spin time before it and polling the clock for
+// the rest. It returns early when woken up by a change of the transport or tempo, and
+// reports false after Close.
+func (c *ClockMaster) wait(due time.Time) bool {
+	if sleep := time.Until(due) - c.options.Spin; sleep > 0 {
+		timer := time.NewTimer(sleep)
+		defer timer.Stop()
+		select {
+		case <-c.done:
+			return false
+		case <-c.wake:
+			return true // The caller checks whether the tick is still due
+		case <-timer.C:
+		}
+	}
+	for time.Now().Before(due) {
+		select {
+		case <-c.done:
+			return false
+		case <-c.wake:
+			return true
+		default:
+			runtime.Gosched()
+		}
+	}
+	return true
+}
+
+// send sends event, reporting errors to the error handler of the options.
+func (c *ClockMaster) send(event contracts.MIDI) {
+	if err := c.out.Send(event); err != nil && c.options.OnError != nil {
+		c.options.OnError(err)
+	}
+}
+
+// anchorLocked makes the next tick due at now. The caller must hold c.mu.
+func (c *ClockMaster) anchorLocked(now time.Time) {
+	c.anchorTime, c.ticks = now, 0
+}
+
+// dueLocked returns when the tick with the given index from the anchor is due. The caller
+// must hold c.mu.
+func (c *ClockMaster) dueLocked(tick int64) time.Time {
+	return c.anchorTime.Add(time.Duration(float64(tick) * float64(time.Minute) / (c.bpm * PulsesPerQuarter)))
+}
+
+// intervalLocked returns the interval between two ticks at the tempo. The caller must
+// hold c.mu.
+func (c *ClockMaster) intervalLocked() time.Duration {
+	return time.Duration(float64(time.Minute) / (c.bpm * PulsesPerQuarter))
+}
+
+// changedLocked invalidates the tick the scheduler waits for and wakes it up. The caller
+// must hold c.mu.
+func (c *ClockMaster) changedLocked() {
+	c.generation++
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
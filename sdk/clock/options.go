@@ -0,0 +1,112 @@
+package clock
+
+import "time"
+
+const (
+	// DefaultWindow is the number of clock ticks the tempo is averaged over, one quarter
+	// note, unless WithWindow is given.
+	DefaultWindow = PulsesPerQuarter
+
+	// DefaultTimeout is the longest interval between two clock ticks, a quarter note at
+	// 10 BPM, unless WithTimeout is given. A longer silence means the clock stopped, and
+	// the tempo is estimated afresh from the ticks after it.
+	DefaultTimeout = 250 * time.Millisecond
+
+	// DefaultSpin is how long before a tick is due a ClockMaster stops sleeping and polls
+	// the clock instead, unless WithSpin is given.
+	DefaultSpin = time.Millisecond
+)
+
+// Options holds the configuration of a TempoEstimator, a Follower and a ClockMaster.
+type Options struct {
+	TimestampUnit     time.Duration                   // Duration of one unit of the event timestamps.
+	Window            int                             // Number of clock ticks the tempo is averaged over.
+	Timeout           time.Duration                   // Longest interval between two ticks of a running clock.
+	OnTransport       func(state State, position int) // Called by a Follower when the transport changes; none by default.
+	Spin              time.Duration                   // How long before a tick is due a ClockMaster polls the clock.
+	ClockWhileStopped bool                            // Whether a ClockMaster sends ticks while stopped.
+	OnError           func(err error)                 // Called with the errors of a ClockMaster sending messages; none by default.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithTimestampUnit sets the duration of one unit of the event timestamps, which is a
+// nanosecond for the events of the native backends.
+func WithTimestampUnit(unit time.Duration) Option {
+	return func(opts *Options) {
+		opts.TimestampUnit = unit
+	}
+}
+
+// WithWindow sets the number of clock ticks the tempo is averaged over. Longer windows
+// smooth out the jitter of the ticks but follow tempo changes more slowly.
+func WithWindow(ticks int) Option {
+	return func(opts *Options) {
+		opts.Window = ticks
+	}
+}
+
+// WithTimeout sets the longest interval between two ticks of a running clock. The tempo is
+// estimated afresh after a longer silence.
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.Timeout = timeout
+	}
+}
+
+// WithOnTransport calls fn when a Follower handles a Start, Stop, Continue or song
+// position pointer message, with the new state and position. It is called from Handle.
+func WithOnTransport(fn func(state State, position int)) Option {
+	return func(opts *Options) {
+		opts.OnTransport = fn
+	}
+}
+
+// WithSpin sets how long before a tick is due a ClockMaster stops sleeping and polls the
+// clock instead. Sleeping wakes up late by up to the timer resolution of the system;
+// polling keeps a CPU busy for up to spin per tick but sends ticks on time. Zero turns
+// polling off.
+func WithSpin(spin time.Duration) Option {
+	return func(opts *Options) {
+		opts.Spin = spin
+	}
+}
+
+// WithClockWhileStopped makes a ClockMaster send ticks from its creation on, also while
+// stopped, so devices can lock to the tempo before Start.
+func WithClockWhileStopped() Option {
+	return func(opts *Options) {
+		opts.ClockWhileStopped = true
+	}
+}
+
+// WithOnError calls fn with the errors of a ClockMaster sending messages. The clock goes
+// on after them.
+func WithOnError(fn func(err error)) Option {
+	return func(opts *Options) {
+		opts.OnError = fn
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{Spin: -1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.TimestampUnit <= 0 {
+		options.TimestampUnit = time.Nanosecond
+	}
+	if options.Window < 1 {
+		options.Window = DefaultWindow
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.Spin < 0 {
+		options.Spin = DefaultSpin
+	}
+	return options
+}
@@ -0,0 +1,91 @@
+package clock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// TempoEstimator derives the tempo of an external sequencer from the timestamps of its
+// clock messages, averaging the interval between ticks over a window to smooth out their
+// jitter. Ticks dropped on the way, e.g. by a full event channel, make the tempo look
+// slower, so capture clocks with a channel large enough to never overflow. It is safe for
+// concurrent use.
+type TempoEstimator struct {
+	options Options
+
+	mu    sync.Mutex
+	ticks []uint64 // Timestamps of the latest ticks, a ring of Window+1 entries.
+	next  int      // Index in ticks of the next tick.
+	count int      // Number of ticks in the ring.
+}
+
+// NewTempoEstimator creates an estimator with no tempo until it receives ticks.
+//
+// opts ...Option: A variadic list of option functions to customize the estimator.
+//
+// Returns:
+//   - *TempoEstimator: The estimator, ready to receive ticks.
+func NewTempoEstimator(opts ...Option) *TempoEstimator {
+	options := applyDefaultOptions(opts...)
+	return &TempoEstimator{options: options, ticks: make([]uint64, options.Window+1)}
+}
+
+// Observe records event if it is a clock message, and ignores any other event.
+func (e *TempoEstimator) Observe(event contracts.MIDI) {
+	if event.Type() == contracts.MessageClock {
+		e.Tick(event.Timestamp)
+	}
+}
+
+// Tick records a clock tick received at timestamp. A tick earlier than the previous one,
+// or later than it by more than the timeout, starts the estimate afresh.
+func (e *TempoEstimator) Tick(timestamp uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.count > 0 {
+		last := e.ticks[(e.next+len(e.ticks)-1)%len(e.ticks)]
+		if timestamp < last || time.Duration(timestamp-last)*e.options.TimestampUnit > e.options.Timeout {
+			e.count = 0
+		}
+	}
+	e.ticks[e.next] = timestamp
+	e.next = (e.next + 1) % len(e.ticks)
+	e.count = min(e.count+1, len(e.ticks))
+}
+
+// BPM returns the tempo in quarter notes per minute, and false until two ticks were
+// recorded since the start or the last silence.
+func (e *TempoEstimator) BPM() (float64, bool) {
+	interval, ok := e.Interval()
+	if !ok {
+		return 0, false
+	}
+	return float64(time.Minute) / float64(interval*PulsesPerQuarter), true
+}
+
+// Interval returns the average interval between two ticks over the window, and false
+// until two ticks were recorded since the start or the last silence.
+func (e *TempoEstimator) Interval() (time.Duration, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.count < 2 {
+		return 0, false
+	}
+	newest := e.ticks[(e.next+len(e.ticks)-1)%len(e.ticks)]
+	oldest := e.ticks[(e.next+len(e.ticks)-e.count)%len(e.ticks)]
+	if newest == oldest {
+		return 0, false
+	}
+	return time.Duration(newest-oldest) * e.options.TimestampUnit / time.Duration(e.count-1), true
+}
+
+// Reset forgets the recorded ticks.
+func (e *TempoEstimator) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.count = 0
+}
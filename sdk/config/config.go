@@ -0,0 +1,263 @@
+// Package config loads MIDI client settings from a JSON file and can watch the file,
+// applying changes to a running client without restarting capture.
+//
+// Example file:
+//
+//	{
+//	  "log_level": "debug",
+//	  "device": "Arturia KeyStep",
+//	  "filter": {"commands": ["note_on", "note_off", "0xB0"]},
+//	  "routes": [
+//	    {"input": "keys", "output": "synth", "channel": 10, "transpose": 12}
+//	  ]
+//	}
+//
+// "device_pattern" selects the device with a regular expression instead, such as
+// "(?i)^arturia keystep( 37)?$". "routes" are the routes of an sdk/router Router, between
+// the inputs and outputs the application added to it under those names.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/midi"
+	"github.com/leandrodaf/midi/sdk/router"
+	"github.com/leandrodaf/midi/sdk/transform"
+)
+
+// ErrInvalidConfig wraps every validation error of a configuration file.
+var ErrInvalidConfig = errors.New("invalid MIDI configuration")
+
+// Config is the file-based configuration of a MIDI client.
+type Config struct {
+	LogLevel string        `json:"log_level,omitempty"`      // info, debug, warn, error or fatal.
+	Device   string        `json:"device,omitempty"`         // Name (or part of the name) of the device to capture from.
+	Pattern  string        `json:"device_pattern,omitempty"` // Regular expression matching the name of the device, instead of Device.
+	Filter   *FilterConfig `json:"filter,omitempty"`         // Event filter; omitted to capture every event.
+	Routes   []RouteConfig `json:"routes,omitempty"`         // Routes of the router given to WithRouter.
+}
+
+// RouteConfig is the file representation of a router.Route.
+type RouteConfig struct {
+	Input     string        `json:"input,omitempty"`     // Name of the input whose events are routed; empty routes every input.
+	Output    string        `json:"output"`              // Name of the output the events are sent to.
+	Filter    *FilterConfig `json:"filter,omitempty"`    // Commands routed; omitted to route every command.
+	Channel   int           `json:"channel,omitempty"`   // Channel, from 1 to 16, the channel messages are moved to; 0 keeps their channels.
+	Transpose int           `json:"transpose,omitempty"` // Semitones notes are shifted by.
+}
+
+// FilterConfig is the file representation of contracts.MIDIEventFilter.
+type FilterConfig struct {
+	Commands []string `json:"commands"` // note_on, note_off, or status bytes such as "0xB0".
+}
+
+// commandNames maps the command names accepted in filters to MIDI commands.
+var commandNames = map[string]contracts.MIDICommand{
+	"note_on":  contracts.NoteOn,
+	"note_off": contracts.NoteOff,
+}
+
+// logLevels maps the log level names accepted in files to log levels.
+var logLevels = map[string]contracts.LogLevel{
+	"info":  contracts.InfoLevel,
+	"debug": contracts.DebugLevel,
+	"warn":  contracts.WarnLevel,
+	"error": contracts.ErrorLevel,
+	"fatal": contracts.FatalLevel,
+}
+
+// Load reads, parses and validates the configuration file at path.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("error reading MIDI configuration: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a JSON configuration.
+func Parse(data []byte) (Config, error) {
+	var c Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return Config{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return c, c.Validate()
+}
+
+// Validate reports every problem in the configuration, joined into one error.
+func (c Config) Validate() error {
+	var errs []error
+	if _, err := c.Level(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.EventFilter(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.DevicePattern(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.RouterRoutes(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+}
+
+// Level returns the configured log level, InfoLevel when none is set.
+func (c Config) Level() (contracts.LogLevel, error) {
+	if c.LogLevel == "" {
+		return contracts.InfoLevel, nil
+	}
+	level, ok := logLevels[strings.ToLower(c.LogLevel)]
+	if !ok {
+		return contracts.InfoLevel, fmt.Errorf("unknown log level %q", c.LogLevel)
+	}
+	return level, nil
+}
+
+// EventFilter returns the configured event filter, or nil when none is set.
+func (c Config) EventFilter() (*contracts.MIDIEventFilter, error) {
+	if c.Filter == nil {
+		return nil, nil
+	}
+
+	return c.Filter.eventFilter()
+}
+
+// eventFilter returns the event filter of the configuration.
+func (f *FilterConfig) eventFilter() (*contracts.MIDIEventFilter, error) {
+	filter := &contracts.MIDIEventFilter{}
+	var errs []error
+	for _, name := range f.Commands {
+		command, err := parseCommand(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		filter.Commands = append(filter.Commands, command)
+	}
+	return filter, errors.Join(errs...)
+}
+
+// RouterRoutes returns the configured routes, for router.Router.AddRoute.
+func (c Config) RouterRoutes() ([]router.Route, error) {
+	routes := make([]router.Route, 0, len(c.Routes))
+	var errs []error
+	for i, rc := range c.Routes {
+		route, err := rc.route()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("route %d: %w", i+1, err))
+			continue
+		}
+		routes = append(routes, route)
+	}
+	return routes, errors.Join(errs...)
+}
+
+// route returns the router route of the configuration.
+func (rc RouteConfig) route() (router.Route, error) {
+	route := router.Route{Input: rc.Input, Output: rc.Output}
+	var errs []error
+	if rc.Output == "" {
+		errs = append(errs, errors.New("missing output"))
+	}
+	if rc.Filter != nil {
+		filter, err := rc.Filter.eventFilter()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		route.Filter = filter
+	}
+	if rc.Transpose < -127 || rc.Transpose > 127 {
+		errs = append(errs, fmt.Errorf("transpose %d is not from -127 to 127", rc.Transpose))
+	} else if rc.Transpose != 0 {
+		route.Transforms = append(route.Transforms, transform.Transpose(rc.Transpose))
+	}
+	if rc.Channel < 0 || rc.Channel > 16 {
+		errs = append(errs, fmt.Errorf("channel %d is not from 1 to 16", rc.Channel))
+	} else if rc.Channel != 0 {
+		route.Transforms = append(route.Transforms, transform.SetChannel(byte(rc.Channel-1)))
+	}
+	return route, errors.Join(errs...)
+}
+
+// DevicePattern returns the compiled Pattern, or nil when none is set.
+func (c Config) DevicePattern() (*regexp.Regexp, error) {
+	if c.Pattern == "" {
+		return nil, nil
+	}
+	if c.Device != "" {
+		return nil, errors.New("device and device_pattern are exclusive")
+	}
+	re, err := regexp.Compile(c.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device_pattern: %w", err)
+	}
+	return re, nil
+}
+
+// SelectDevice selects the configured device on client, by name or pattern, so files
+// keep referring to the same device when IDs change across reboots. It does nothing when
+// no device is configured.
+//
+// client contracts.ClientMIDI: The client whose device to select.
+//
+// Returns:
+//   - int: The ID of the selected device, or -1 when no device is configured.
+//   - error: midi.ErrDeviceNotFound if no device matches, or the error listing or selecting the device.
+func (c Config) SelectDevice(client contracts.ClientMIDI) (int, error) {
+	re, err := c.DevicePattern()
+	switch {
+	case err != nil:
+		return -1, err
+	case re == nil && c.Device == "":
+		return -1, nil
+	case re == nil:
+		return midi.SelectDeviceByName(client, c.Device)
+	}
+	id, err := midi.SelectDeviceMatching(client, midi.MatchRegexp(re))
+	if errors.Is(err, midi.ErrDeviceNotFound) {
+		return -1, fmt.Errorf("%w: %s", err, c.Pattern)
+	}
+	return id, err
+}
+
+// Options returns the client options described by the configuration, for use with
+// midi.NewMIDIClient. The device is not part of the options; select it with SelectDevice
+// once the client is created.
+func (c Config) Options() ([]contracts.Option, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	level, _ := c.Level()
+	opts := []contracts.Option{contracts.WithLogLevel(level)}
+	if filter, _ := c.EventFilter(); filter != nil {
+		opts = append(opts, contracts.WithMIDIEventFilter(*filter))
+	}
+	return opts, nil
+}
+
+// parseCommand converts a command name or status byte literal into a MIDI command.
+func parseCommand(name string) (contracts.MIDICommand, error) {
+	if command, ok := commandNames[strings.ToLower(name)]; ok {
+		return command, nil
+	}
+	value, err := strconv.ParseUint(name, 0, 8)
+	if err != nil || value < 0x80 {
+		return 0, fmt.Errorf("unknown MIDI command %q", name)
+	}
+	return contracts.MIDICommand(value), nil
+}
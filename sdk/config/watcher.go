@@ -0,0 +1,254 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/midi"
+	"github.com/leandrodaf/midi/sdk/router"
+)
+
+// ErrDeviceNotFound is reported when the configured device is not connected.
+var ErrDeviceNotFound = midi.ErrDeviceNotFound
+
+// ConfigReloaded is emitted by a Watcher every time the configuration file changes.
+type ConfigReloaded struct {
+	Time   time.Time // Time the change was detected.
+	Config Config    // The configuration read from the file.
+	Err    error     // Validation or apply errors; nil when the configuration was fully applied.
+}
+
+// WatcherOptions holds the configuration of a Watcher.
+type WatcherOptions struct {
+	Interval time.Duration  // How often the file is checked for changes.
+	Router   *router.Router // Router the routes of the file are applied to; nil ignores them.
+}
+
+// WatcherOption is a function that modifies WatcherOptions.
+type WatcherOption func(*WatcherOptions)
+
+// WithInterval sets how often the file is checked for changes.
+func WithInterval(interval time.Duration) WatcherOption {
+	return func(opts *WatcherOptions) {
+		opts.Interval = interval
+	}
+}
+
+// WithRouter applies the routes of the file to r: they are added when the watcher starts
+// and replaced whenever they change, while events keep flowing through the router. Routes
+// added to r otherwise are left alone.
+func WithRouter(r *router.Router) WatcherOption {
+	return func(opts *WatcherOptions) {
+		opts.Router = r
+	}
+}
+
+// Watcher polls a configuration file and applies changes to a running client: the log
+// level, the event filter and the selected device, and to the routes of a router given
+// with WithRouter. Capture keeps running while changes are applied. An invalid file is
+// reported and otherwise ignored, so the client keeps the last valid configuration.
+type Watcher struct {
+	path     string
+	client   contracts.ClientMIDI
+	logger   contracts.Logger
+	options  WatcherOptions
+	events   chan ConfigReloaded
+	contents []byte // Last contents read from the file.
+	current  Config // Last configuration that passed validation.
+	routeIDs []int  // IDs of the routes added to the router from the file.
+	mu       sync.Mutex
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewWatcher starts watching the configuration file at path. The file's current contents
+// are taken as the baseline and are not applied; build the client from them with Load and
+// Config.Options. Only their routes are added, to the router given with WithRouter.
+//
+// path string: The configuration file to watch.
+// client contracts.ClientMIDI: The client changes are applied to.
+// logger contracts.Logger: The logger whose level follows the configuration.
+// opts ...WatcherOption: A variadic list of option functions to customize the watcher.
+//
+// Returns:
+//   - *Watcher: The running watcher.
+//   - error: An error if the file cannot be read or is invalid, or a route cannot be added.
+func NewWatcher(path string, client contracts.ClientMIDI, logger contracts.Logger, opts ...WatcherOption) (*Watcher, error) {
+	options := WatcherOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.Interval <= 0 {
+		options.Interval = time.Second
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading MIDI configuration: %w", err)
+	}
+	current, err := Parse(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		path:     path,
+		client:   client,
+		logger:   logger,
+		options:  options,
+		events:   make(chan ConfigReloaded, 8),
+		contents: contents,
+		current:  current,
+		done:     make(chan struct{}),
+	}
+	if err := w.replaceRoutes(current); err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	profiling.Go(profiling.RoleScheduler, "config", "", w.watch)
+	return w, nil
+}
+
+// Events returns the channel ConfigReloaded events are emitted on. Events are dropped
+// if the channel is not drained.
+func (w *Watcher) Events() <-chan ConfigReloaded {
+	return w.events
+}
+
+// Current returns the configuration currently applied.
+func (w *Watcher) Current() Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Stop stops watching the file and closes the events channel.
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.wg.Wait()
+		close(w.events)
+	})
+}
+
+// watch checks the file every interval until the watcher is stopped.
+func (w *Watcher) watch() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check reloads the file if its contents changed since the last check.
+func (w *Watcher) check() {
+	contents, err := os.ReadFile(w.path)
+	if err != nil {
+		// Editors often replace the file in several steps; retry on the next tick.
+		w.logger.Debug("MIDI configuration not readable", w.logger.Field().Error("error", err))
+		return
+	}
+	if bytes.Equal(contents, w.contents) {
+		return
+	}
+	w.contents = contents
+
+	event := ConfigReloaded{Time: time.Now()}
+	event.Config, event.Err = Parse(contents)
+	if event.Err == nil {
+		event.Err = w.apply(event.Config)
+	}
+
+	if event.Err != nil {
+		w.logger.Warn("MIDI configuration reloaded with errors", w.logger.Field().Error("error", event.Err))
+	} else {
+		w.logger.Info("MIDI configuration reloaded", w.logger.Field().String("path", w.path))
+	}
+
+	select {
+	case w.events <- event:
+	default:
+	}
+}
+
+// apply applies the settings that differ from the current configuration.
+func (w *Watcher) apply(next Config) error {
+	w.mu.Lock()
+	prev := w.current
+	w.current = next
+	w.mu.Unlock()
+
+	if level, _ := next.Level(); next.LogLevel != prev.LogLevel {
+		w.logger.SetLevel(level)
+	}
+
+	if !sameFilter(prev.Filter, next.Filter) {
+		filter, _ := next.EventFilter()
+		w.client.SetMIDIEventFilter(filter)
+	}
+
+	var errs []error
+	if !slices.EqualFunc(prev.Routes, next.Routes, sameRoute) {
+		errs = append(errs, w.replaceRoutes(next))
+	}
+	if next.Device != prev.Device || next.Pattern != prev.Pattern {
+		_, err := next.SelectDevice(w.client)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// replaceRoutes replaces the routes added to the router from the file with those of c.
+// The previous routes are removed first, so events are never routed twice.
+func (w *Watcher) replaceRoutes(c Config) error {
+	if w.options.Router == nil {
+		return nil
+	}
+	routes, err := c.RouterRoutes()
+	if err != nil {
+		return err
+	}
+	for _, id := range w.routeIDs {
+		w.options.Router.RemoveRoute(id)
+	}
+	w.routeIDs = w.routeIDs[:0]
+	for _, route := range routes {
+		id, err := w.options.Router.AddRoute(route)
+		if err != nil {
+			return err
+		}
+		w.routeIDs = append(w.routeIDs, id)
+	}
+	return nil
+}
+
+// sameRoute reports whether two route configurations are equivalent.
+func sameRoute(a, b RouteConfig) bool {
+	return a.Input == b.Input && a.Output == b.Output && a.Channel == b.Channel &&
+		a.Transpose == b.Transpose && sameFilter(a.Filter, b.Filter)
+}
+
+// sameFilter reports whether two filter configurations are equivalent.
+func sameFilter(a, b *FilterConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return slices.Equal(a.Commands, b.Commands)
+}
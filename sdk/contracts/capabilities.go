@@ -0,0 +1,12 @@
+package contracts
+
+// DeviceCapabilities describes what a device supports through the backend serving it, so
+// applications can adapt their features at runtime instead of failing mid-operation.
+type DeviceCapabilities struct {
+	SysExIn     bool // SysEx messages received from the device are delivered (see WithSysExChannel).
+	SysExOut    bool // SysEx messages can be sent to the device.
+	Timestamps  bool // Events carry timestamps taken by the driver or hardware rather than on arrival.
+	InputPorts  int  // Number of input ports of the device.
+	OutputPorts int  // Number of output ports of the device.
+	MIDI2       bool // The device is reached through MIDI 2.0 (Universal MIDI Packets).
+}
@@ -0,0 +1,50 @@
+package contracts
+
+import "time"
+
+// ConnectionState is the state of the device selected on a client with automatic
+// reconnection.
+type ConnectionState int
+
+const (
+	// Connected reports that the selected device was opened by SelectDevice.
+	Connected ConnectionState = iota
+	// Disconnected reports that the selected device went away while open.
+	Disconnected
+	// Reconnecting reports a failed attempt to open the device again; more will follow.
+	Reconnecting
+	// Reconnected reports that the device came back and was opened again, resuming capture
+	// if it was running.
+	Reconnected
+)
+
+// String names the state.
+func (s ConnectionState) String() string {
+	switch s {
+	case Connected:
+		return "connected"
+	case Disconnected:
+		return "disconnected"
+	case Reconnecting:
+		return "reconnecting"
+	case Reconnected:
+		return "reconnected"
+	default:
+		return "unknown"
+	}
+}
+
+// ConnectionEvent reports a change of the connection state of the selected device.
+type ConnectionEvent struct {
+	Time     time.Time       // Time of the change.
+	State    ConnectionState // New state.
+	DeviceID int             // ID of the device; it may change when the device comes back.
+	Device   DeviceInfo      // The device, identified by name, entity and manufacturer.
+	Err      error           // Why the device was lost or could not be opened again, if any.
+}
+
+// AutoReconnect holds the configuration of automatic reconnection.
+type AutoReconnect struct {
+	Interval time.Duration        // Delay between attempts to open a lost device again; defaults to a second.
+	Events   chan ConnectionEvent // Optional channel receiving the connection state changes; a full channel drops them.
+}
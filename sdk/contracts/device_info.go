@@ -1,8 +1,49 @@
 package contracts
 
-// DeviceInfo contains information about a MIDI device.
+// DeviceInfo contains information about a MIDI device. Backends fill in what they know;
+// other fields are left zero. Devices are compared as a whole by device watchers and
+// automatic reconnection, so a device going offline counts as a different device until it
+// is back online.
 type DeviceInfo struct {
-	Name         string // Device name.
-	Manufacturer string // Device manufacturer.
-	EntityName   string // Name of the entity to which the device belongs.
+	Name          string          // Device name.
+	Manufacturer  string          // Device manufacturer.
+	EntityName    string          // Name of the entity to which the device belongs.
+	UniqueID      string          // Identifier stable across reconnections and restarts, such as the CoreMIDI kMIDIPropertyUniqueID; "" when unknown.
+	DriverVersion string          // Version of the driver of the device; "" when unknown.
+	Direction     DeviceDirection // Whether the device is listed as an input, an output, or both; zero when unknown.
+	Ports         int             // Number of ports of the same direction the device belongs with, such as the sources of a CoreMIDI entity; 0 when unknown.
+	Offline       bool            // Whether the device is listed but temporarily absent, such as an unplugged CoreMIDI device still in the setup.
+}
+
+// DeviceDirection tells whether messages are received from a device, sent to it, or both.
+type DeviceDirection uint8
+
+const (
+	// DirectionInput marks devices listed by ListDevices, whose messages are captured.
+	DirectionInput DeviceDirection = 1 << iota
+	// DirectionOutput marks devices listed by ListOutputDevices, which messages are sent to.
+	DirectionOutput
+)
+
+// String returns "input", "output", "input/output" or "unknown".
+func (d DeviceDirection) String() string {
+	switch d {
+	case DirectionInput:
+		return "input"
+	case DirectionOutput:
+		return "output"
+	case DirectionInput | DirectionOutput:
+		return "input/output"
+	default:
+		return "unknown"
+	}
+}
+
+// DeviceNotifier is implemented by clients whose backend reports changes of the connected
+// devices, such as CoreMIDI, so device watchers react at once instead of on their next
+// poll.
+type DeviceNotifier interface {
+	// NotifyDeviceChanges calls changed whenever devices may have been added or removed,
+	// until stop is called. changed runs on a thread of the backend and must not block.
+	NotifyDeviceChanges(changed func()) (stop func())
 }
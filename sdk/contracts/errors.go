@@ -0,0 +1,125 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by every backend, so callers can handle failures with errors.Is
+// regardless of the platform. Backends wrap them with details about the failure.
+var (
+	ErrNoDevices          = errors.New("no MIDI devices found")
+	ErrDeviceBusy         = errors.New("MIDI device is in use by another application")
+	ErrDeviceDisconnected = errors.New("MIDI device disconnected")
+	ErrInvalidDevice      = errors.New("invalid MIDI device")
+	ErrNotCapturing       = errors.New("MIDI capture is not running")
+	ErrMalformedMessage   = errors.New("malformed MIDI message")
+	ErrNoOutputDevice     = errors.New("no MIDI output device selected")
+	ErrOutputUnsupported  = errors.New("MIDI output is not supported by this backend")
+	ErrSysExUnsupported   = errors.New("SysEx output is not supported by this backend")
+	ErrVirtualUnsupported = errors.New("virtual MIDI endpoints are not supported by this backend")
+	ErrDeviceNotFound     = errors.New("MIDI device not found")
+	ErrDriverFailure      = errors.New("MIDI driver failure")
+	ErrCaptureRunning     = errors.New("cannot select a device while capturing")
+	ErrNoDeviceSelected   = errors.New("no MIDI device selected")
+	ErrCaptureStarted     = errors.New("MIDI capture already started")
+	ErrNilChannel         = errors.New("nil MIDI event channel")
+	ErrUnbufferedChannel  = errors.New("unbuffered MIDI event channel; OverflowBlock and OverflowRingBuffer need a buffered one")
+)
+
+// Error is a failed call of a backend into the operating system or a driver. It carries
+// where the call failed and the error code of the system, for diagnostics with errors.As,
+// and wraps both Kind, the contracts error classifying the failure for errors.Is, and Err:
+//
+//	var midiErr *contracts.Error
+//	if errors.Is(err, contracts.ErrDeviceBusy) && errors.As(err, &midiErr) {
+//		log.Printf("device %d busy (code %d)", midiErr.DeviceID, midiErr.Code)
+//	}
+type Error struct {
+	Backend  string // Backend that made the call.
+	Op       string // Operation that failed, such as "midiInOpen" or "MIDIPortConnectSource".
+	DeviceID int    // ID of the device concerned, or -1 when the call concerns none.
+	Code     int64  // Error code of the system: an MMRESULT on Windows, an OSStatus on macOS; 0 when there is none.
+	Kind     error  // Contracts error classifying the failure, such as ErrDeviceBusy; ErrDriverFailure when nothing more specific applies.
+	Err      error  // Underlying error, such as the description of the code; nil when there is none.
+}
+
+// Error describes the failure, its operation, device and code.
+func (e *Error) Error() string {
+	kind := e.Kind
+	if kind == nil {
+		kind = ErrDriverFailure
+	}
+	message := fmt.Sprintf("%v: %s (%s", kind, e.Op, e.Backend)
+	if e.DeviceID >= 0 {
+		message += fmt.Sprintf(", device %d", e.DeviceID)
+	}
+	if e.Code != 0 {
+		message += fmt.Sprintf(", code %d", e.Code)
+	}
+	message += ")"
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+// Unwrap returns Kind and Err.
+func (e *Error) Unwrap() []error {
+	errs := make([]error, 0, 2)
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// MalformedDataError is a diagnostic event describing a malformed byte sequence a device
+// sent, such as data bytes without a status byte or a message cut short by another one.
+// Backends send it to the error channel in strict parsing mode. It wraps
+// ErrMalformedMessage.
+type MalformedDataError struct {
+	Backend  string     // Backend that received the bytes.
+	DeviceID int        // ID of the device that sent the bytes.
+	Device   DeviceInfo // Information about the device.
+	Data     []byte     // Discarded bytes.
+	Reason   string     // Why the bytes were discarded.
+}
+
+// Error describes the sequence, including its bytes in hexadecimal.
+func (e *MalformedDataError) Error() string {
+	return fmt.Sprintf("%v from %q (%s): %s [% X]", ErrMalformedMessage, e.Device.Name, e.Backend, e.Reason, e.Data)
+}
+
+// Unwrap returns ErrMalformedMessage.
+func (e *MalformedDataError) Unwrap() error {
+	return ErrMalformedMessage
+}
+
+// ErrPanic is wrapped by the errors reporting a panic the client recovered from.
+var ErrPanic = errors.New("panic recovered in MIDI client")
+
+// PanicError reports a panic recovered in a callback of the application, such as a hook or
+// a SysEx handler, or on the capture path of a device, such as a send on an event channel
+// the application closed. Callbacks of the application keep being called after a panic;
+// the capture of a device that panicked is shut down and the device is reported lost.
+// Panics are sent to the error channel and counted in Health().Panics. It wraps ErrPanic.
+type PanicError struct {
+	Backend  string // Backend of the client that recovered the panic.
+	Callback string // What panicked, e.g. "OnCaptureStarted hook" or "capture".
+	DeviceID int    // ID of the device whose capture was shut down, or -1 for application callbacks.
+	Value    any    // Value passed to panic.
+	Stack    []byte // Stack trace of the panicking goroutine.
+}
+
+// Error describes the panic, without the stack trace.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v: %s (%s): %v", ErrPanic, e.Callback, e.Backend, e.Value)
+}
+
+// Unwrap returns ErrPanic.
+func (e *PanicError) Unwrap() error {
+	return ErrPanic
+}
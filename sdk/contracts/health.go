@@ -0,0 +1,25 @@
+package contracts
+
+import "time"
+
+// Health describes the state of a MIDI client, for readiness and liveness probes
+// of long-running services.
+type Health struct {
+	Backend        string            // Name of the backend serving the client (e.g. "coremidi", "winmm").
+	Connected      bool              // Indicates if a device is selected and connected.
+	Capturing      bool              // Indicates if events are being delivered to a channel.
+	DeviceID       int               // ID of the selected device, or -1 when none is selected.
+	Device         DeviceInfo        // Information about the selected device.
+	LastEvent      time.Time         // Time the last event was received; zero if none was received.
+	EventsReceived uint64            // Events received from the device, before filtering.
+	EventsFiltered uint64            // Events discarded by the event filter.
+	EventsDropped  uint64            // Events discarded because the event channel was full.
+	Duplicates     uint64            // Events suppressed as duplicates of another device's; see WithDeduplication.
+	Malformed      uint64            // Malformed byte sequences discarded while parsing.
+	SysExReceived  uint64            // SysEx messages received while capturing.
+	SysExDropped   uint64            // SysEx messages discarded because their channel or queue was full.
+	MIDI2Received  uint64            // MIDI 2.0 events received while capturing; see WithMIDI2Channel.
+	MIDI2Dropped   uint64            // MIDI 2.0 events discarded because their channel was full.
+	Panics         uint64            // Panics recovered in callbacks and capture paths; see contracts.PanicError.
+	Diagnostics    map[string]string // Backend-specific details.
+}
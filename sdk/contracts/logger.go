@@ -26,8 +26,25 @@ const (
 	ConsoleLog LogDestination = "console"
 	// FileLog directs log messages to a file.
 	FileLog LogDestination = "file"
+	// ConsoleAndFileLog directs log messages to both the console output and a file.
+	ConsoleAndFileLog LogDestination = "console+file"
 )
 
+// LogRotation configures when a log file is replaced by a new one, the old one being kept
+// as a backup next to it. The zero LogRotation never rotates.
+type LogRotation struct {
+	MaxSize    int64         // Size in bytes the file is rotated at; 0 disables rotation by size.
+	Interval   time.Duration // Period the file is rotated after, aligned to multiples of it since the zero time, so 24h rotates at midnight UTC; 0 disables rotation by time.
+	MaxBackups int           // Backups kept, the oldest being removed first; 0 keeps them all.
+	MaxAge     time.Duration // Age backups are removed at; 0 keeps them regardless of age.
+}
+
+// RotatingLogger is implemented by loggers that can rotate the files they write to.
+type RotatingLogger interface {
+	// SetRotation sets the rotation of the files of the next call to SetDestination.
+	SetRotation(rotation LogRotation)
+}
+
 // Field representa um campo de log com vários tipos de dados.
 type Field interface {
 	Bool(key string, val bool) Field
@@ -0,0 +1,112 @@
+package contracts
+
+// MessageType identifies the kind of message an event carries, decoded from its status
+// byte. Channel message types share the values of their status byte on channel 1, and
+// system message types the value of their status byte.
+type MessageType byte
+
+// Message types of MIDI 1.0.
+const (
+	MessageUnknown           MessageType = 0x00 // Not a status byte.
+	MessageNoteOff           MessageType = 0x80 // Note off, or note on with velocity zero.
+	MessageNoteOn            MessageType = 0x90 // Note on with a non-zero velocity.
+	MessagePolyAftertouch    MessageType = 0xA0 // Pressure on one note.
+	MessageControlChange     MessageType = 0xB0 // Controller value, including channel mode messages.
+	MessageProgramChange     MessageType = 0xC0 // Program (patch) selection.
+	MessageChannelAftertouch MessageType = 0xD0 // Pressure on the whole channel.
+	MessagePitchBend         MessageType = 0xE0 // Pitch wheel position.
+	MessageSysEx             MessageType = 0xF0 // System exclusive; delivered apart from events.
+	MessageTimeCode          MessageType = 0xF1 // MIDI time code quarter frame.
+	MessageSongPosition      MessageType = 0xF2 // Song position pointer, in sixteenth notes.
+	MessageSongSelect        MessageType = 0xF3 // Song selection.
+	MessageTuneRequest       MessageType = 0xF6 // Request to tune analog oscillators.
+	MessageClock             MessageType = 0xF8 // Timing clock, 24 per quarter note.
+	MessageStart             MessageType = 0xFA // Start of the sequence.
+	MessageContinue          MessageType = 0xFB // Continuation of the sequence.
+	MessageStop              MessageType = 0xFC // Stop of the sequence.
+	MessageActiveSensing     MessageType = 0xFE // Keep-alive sent by some devices.
+	MessageReset             MessageType = 0xFF // System reset.
+)
+
+// String names the message type, e.g. "NoteOn", "CC" or "Clock".
+func (t MessageType) String() string {
+	if name, ok := commandNames[byte(t)]; ok {
+		return name
+	}
+	if name, ok := systemNames[byte(t)]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// IsChannel reports whether messages of the type are addressed to a channel.
+func (t MessageType) IsChannel() bool {
+	return t >= MessageNoteOff && t < MessageSysEx
+}
+
+// IsRealtime reports whether messages of the type are system real-time messages, which
+// may be interleaved with other messages.
+func (t MessageType) IsRealtime() bool {
+	return t >= MessageClock
+}
+
+// Type decodes the kind of message the event carries. A note on with velocity zero is
+// reported as MessageNoteOff, matching how devices use it.
+func (m MIDI) Type() MessageType {
+	switch {
+	case m.Command < 0x80:
+		return MessageUnknown
+	case m.Command < 0xF0:
+		if m.Command&0xF0 == 0x90 && m.Velocity == 0 {
+			return MessageNoteOff
+		}
+		return MessageType(m.Command & 0xF0)
+	}
+	if _, ok := systemNames[m.Command]; ok {
+		return MessageType(m.Command)
+	}
+	return MessageUnknown
+}
+
+// Channel returns the zero-based channel of a channel message, or zero for system
+// messages.
+func (m MIDI) Channel() byte {
+	if m.Command < 0x80 || m.Command >= 0xF0 {
+		return 0
+	}
+	return m.Command & 0x0F
+}
+
+// Controller returns the controller number of a control change.
+func (m MIDI) Controller() byte {
+	return m.Note
+}
+
+// Value returns the value of a control change.
+func (m MIDI) Value() byte {
+	return m.Velocity
+}
+
+// Program returns the program number of a program change.
+func (m MIDI) Program() byte {
+	return m.Note
+}
+
+// Pressure returns the pressure of a polyphonic or channel aftertouch message.
+func (m MIDI) Pressure() byte {
+	if m.Command&0xF0 == 0xD0 {
+		return m.Note
+	}
+	return m.Velocity
+}
+
+// PitchBend returns the position of a pitch bend message, from -8192 to 8191 with zero
+// at the center.
+func (m MIDI) PitchBend() int {
+	return int(m.Velocity&0x7F)<<7 | int(m.Note&0x7F) - 8192
+}
+
+// SongPosition returns the position of a song position pointer, in sixteenth notes.
+func (m MIDI) SongPosition() int {
+	return int(m.Velocity&0x7F)<<7 | int(m.Note&0x7F)
+}
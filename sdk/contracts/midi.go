@@ -1,17 +1,41 @@
 package contracts
 
+import "context"
+
 // MIDI represents a MIDI event with a timestamp, command, note, and velocity.
+// Type and Channel decode the status byte held in Command, and Controller, Value,
+// Program, Pressure and PitchBend name the data bytes of the other channel messages.
 type MIDI struct {
 	Timestamp uint64 // Timestamp indicates the time the event occurred.
-	Command   byte   // Command specifies the type of MIDI event (e.g., Note On, Note Off).
-	Note      byte   // Note represents the MIDI note number (0-127).
-	Velocity  byte   // Velocity indicates the strength of the note being played (0-127).
+	Command   byte   // Command is the status byte of the message, including the channel of channel messages (e.g. 0x91 for Note On on channel 2).
+	Note      byte   // Note represents the MIDI note number (0-127), or the first data byte of other messages.
+	Velocity  byte   // Velocity indicates the strength of the note being played (0-127), or the second data byte of other messages.
+
+	SourceDeviceID int // SourceDeviceID is the ID of the device a captured event came from, as listed by ListDevices.
 }
 
 // ClientMIDI defines an interface for MIDI client operations.
 type ClientMIDI interface {
-	Stop() error                         // Stops the MIDI client and releases resources.
-	ListDevices() ([]DeviceInfo, error)  // Lists all available MIDI devices.
-	SelectDevice(deviceID int) error     // Selects a MIDI device by its ID for communication.
-	StartCapture(eventChannel chan MIDI) // Starts capturing MIDI events and sends them to the specified channel.
+	Stop() error                                                           // Stops the MIDI client and releases resources.
+	ListDevices() ([]DeviceInfo, error)                                    // Lists all available MIDI devices.
+	SelectDevice(deviceID int) error                                       // Selects a MIDI device by its ID for communication.
+	StartCapture(eventChannel chan MIDI) error                             // Starts capturing MIDI events and sends them to the specified channel.
+	StartCaptureFunc(handler func(MIDI)) error                             // Starts capturing MIDI events and calls handler with each of them.
+	StartCaptureContext(ctx context.Context, eventChannel chan MIDI) error // Starts capturing MIDI events into the channel and stops the client when ctx is done.
+	SetMIDIEventFilter(filter *MIDIEventFilter)                            // Replaces the event filter, also while capturing; nil accepts every event.
+	Health() Health                                                        // Reports connection status, last event time and drop counts.
+	Stats() Stats                                                          // Reports event traffic statistics of the capturing devices.
+	DeviceCapabilities(deviceID int) (DeviceCapabilities, error)           // Reports what a device supports through this client.
+	ListOutputDevices() ([]DeviceInfo, error)                              // Lists the devices messages can be sent to.
+	SelectOutputDevice(deviceID int) error                                 // Opens an output device for Send, closing the previous one.
+	Send(event MIDI) error                                                 // Sends a message to the selected output device.
+	SendSysEx(data []byte) error                                           // Sends a complete SysEx message, including F0 and F7, to the selected output device.
+
+	// CreateVirtualSource publishes a source named name that other applications can
+	// receive from, or fails with ErrVirtualUnsupported where the platform has none.
+	CreateVirtualSource(name string) (VirtualSource, error)
+	// CreateVirtualDestination publishes a destination named name that other applications
+	// can send to, delivering their messages to eventChannel without blocking, or fails
+	// with ErrVirtualUnsupported where the platform has none.
+	CreateVirtualDestination(name string, eventChannel chan MIDI) (VirtualDestination, error)
 }
@@ -0,0 +1,98 @@
+package contracts
+
+import "strconv"
+
+// MIDI2Status identifies the kind of a MIDI 2.0 channel voice message, by the status
+// nibble of its Universal MIDI Packet. Messages shared with MIDI 1.0 keep the upper
+// nibble of their MIDI 1.0 status byte.
+type MIDI2Status byte
+
+// Statuses of MIDI 2.0 channel voice messages.
+const (
+	MIDI2RegisteredPerNoteController MIDI2Status = 0x0 // Registered controller of one note.
+	MIDI2AssignablePerNoteController MIDI2Status = 0x1 // Assignable controller of one note.
+	MIDI2RegisteredController        MIDI2Status = 0x2 // Registered parameter (RPN) set in one message.
+	MIDI2AssignableController        MIDI2Status = 0x3 // Assignable parameter (NRPN) set in one message.
+	MIDI2RelativeRegistered          MIDI2Status = 0x4 // Relative change of a registered parameter.
+	MIDI2RelativeAssignable          MIDI2Status = 0x5 // Relative change of an assignable parameter.
+	MIDI2PerNotePitchBend            MIDI2Status = 0x6 // Pitch bend of one note.
+	MIDI2NoteOff                     MIDI2Status = 0x8 // Note off, with release velocity and attribute.
+	MIDI2NoteOn                      MIDI2Status = 0x9 // Note on, with velocity and attribute.
+	MIDI2PolyPressure                MIDI2Status = 0xA // Pressure on one note.
+	MIDI2ControlChange               MIDI2Status = 0xB // Controller value.
+	MIDI2ProgramChange               MIDI2Status = 0xC // Program selection, with an optional bank.
+	MIDI2ChannelPressure             MIDI2Status = 0xD // Pressure on the whole channel.
+	MIDI2PitchBend                   MIDI2Status = 0xE // Pitch wheel position.
+	MIDI2PerNoteManagement           MIDI2Status = 0xF // Detaching or resetting the controllers of one note.
+)
+
+var midi2StatusNames = map[MIDI2Status]string{
+	MIDI2RegisteredPerNoteController: "RegisteredPerNoteController",
+	MIDI2AssignablePerNoteController: "AssignablePerNoteController",
+	MIDI2RegisteredController:        "RegisteredController",
+	MIDI2AssignableController:        "AssignableController",
+	MIDI2RelativeRegistered:          "RelativeRegisteredController",
+	MIDI2RelativeAssignable:          "RelativeAssignableController",
+	MIDI2PerNotePitchBend:            "PerNotePitchBend",
+	MIDI2NoteOff:                     "NoteOff",
+	MIDI2NoteOn:                      "NoteOn",
+	MIDI2PolyPressure:                "PolyPressure",
+	MIDI2ControlChange:               "CC",
+	MIDI2ProgramChange:               "ProgramChange",
+	MIDI2ChannelPressure:             "ChannelPressure",
+	MIDI2PitchBend:                   "PitchBend",
+	MIDI2PerNoteManagement:           "PerNoteManagement",
+}
+
+// String names the status, e.g. "NoteOn" or "PerNotePitchBend".
+func (s MIDI2Status) String() string {
+	if name, ok := midi2StatusNames[s]; ok {
+		return name
+	}
+	return "MIDI2Status(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Note attribute types of MIDI 2.0 note on and note off messages.
+const (
+	AttributeNone         byte = 0x00 // No attribute; Attribute is zero.
+	AttributeManufacturer byte = 0x01 // Attribute specific to a manufacturer.
+	AttributeProfile      byte = 0x02 // Attribute defined by a profile.
+	AttributePitch        byte = 0x03 // Pitch of the note in 7.9 fixed point semitones.
+)
+
+// Option flags of MIDI 2.0 program change and per-note management messages.
+const (
+	OptionBankValid byte = 0x01 // Program change: Bank holds the bank to select.
+	OptionSet       byte = 0x01 // Per-note management: set the controllers of the note to their defaults.
+	OptionDetach    byte = 0x02 // Per-note management: detach the controllers from previous notes.
+)
+
+// MIDI2Event is a MIDI 2.0 channel voice message, carrying the full resolution of Universal
+// MIDI Packets: 16-bit velocities, 32-bit controller values and per-note controllers. Which
+// fields are meaningful depends on Status:
+//   - NoteOn, NoteOff: Note, Velocity, AttributeType and Attribute.
+//   - PolyPressure, PerNotePitchBend: Note and Value.
+//   - RegisteredPerNoteController, AssignablePerNoteController: Note, Index and Value.
+//   - PerNoteManagement: Note and Options.
+//   - ControlChange: Index (the controller) and Value.
+//   - RegisteredController, AssignableController and their relative forms: Bank and Index,
+//     the MSB and LSB of the parameter number, and Value, two's complement when relative.
+//   - ProgramChange: Program, Options and Bank as 14 bits.
+//   - ChannelPressure, PitchBend: Value, with pitch bend centered at 0x80000000.
+type MIDI2Event struct {
+	Timestamp     uint64      // Timestamp indicates the time the event occurred.
+	Group         byte        // UMP group (0-15) the message was carried in.
+	Channel       byte        // Zero-based channel (0-15).
+	Status        MIDI2Status // Kind of message.
+	Note          byte        // Note number (0-127) of note and per-note messages.
+	Velocity      uint16      // Velocity of note messages (0-65535).
+	AttributeType byte        // Type of Attribute of note messages; see AttributeNone.
+	Attribute     uint16      // Attribute data of note messages.
+	Index         byte        // Controller number, or the parameter LSB of registered and assignable controllers.
+	Bank          uint16      // Parameter MSB of registered and assignable controllers, or the bank of a program change.
+	Program       byte        // Program number of a program change.
+	Options       byte        // Option flags of program change and per-note management messages.
+	Value         uint32      // Value of controllers, pressure and pitch bend messages.
+
+	SourceDeviceID int // SourceDeviceID is the ID of the device a captured event came from, as listed by ListDevices.
+}
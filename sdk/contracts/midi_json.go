@@ -0,0 +1,59 @@
+package contracts
+
+import "encoding/json"
+
+// midiJSON is the JSON form of MIDI.
+type midiJSON struct {
+	Timestamp      uint64 `json:"timestamp"`
+	Command        byte   `json:"command"`
+	Note           byte   `json:"note"`
+	Velocity       byte   `json:"velocity"`
+	SourceDeviceID int    `json:"source_device_id"`
+	Type           string `json:"type,omitempty"`    // Name of the message type; informative, ignored when decoding.
+	Channel        *byte  `json:"channel,omitempty"` // Zero-based channel of channel messages; informative, ignored when decoding.
+
+	LegacySourceDeviceID *int `json:"SourceDeviceID,omitempty"` // Key of the device ID before MIDI had a JSON form, still accepted when decoding.
+}
+
+// MarshalJSON encodes the event as a JSON object with the fields timestamp, command,
+// note, velocity and source_device_id, plus its message type, such as "NoteOn", and the
+// zero-based channel of channel messages for readers that do not decode status bytes:
+//
+//	{"timestamp":1700000000000000000,"command":144,"note":60,"velocity":100,"source_device_id":0,"type":"NoteOn","channel":0}
+func (m MIDI) MarshalJSON() ([]byte, error) {
+	v := midiJSON{
+		Timestamp:      m.Timestamp,
+		Command:        m.Command,
+		Note:           m.Note,
+		Velocity:       m.Velocity,
+		SourceDeviceID: m.SourceDeviceID,
+		Type:           m.Type().String(),
+	}
+	if m.Type().IsChannel() {
+		channel := m.Channel()
+		v.Channel = &channel
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes an event encoded by MarshalJSON. The type and channel are
+// ignored, as the status byte in command holds them, and missing fields are zero. Objects
+// with the field names of the struct, as encoded before MIDI had a JSON form, are decoded
+// too.
+func (m *MIDI) UnmarshalJSON(data []byte) error {
+	var v midiJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*m = MIDI{
+		Timestamp:      v.Timestamp,
+		Command:        v.Command,
+		Note:           v.Note,
+		Velocity:       v.Velocity,
+		SourceDeviceID: v.SourceDeviceID,
+	}
+	if v.LegacySourceDeviceID != nil {
+		m.SourceDeviceID = *v.LegacySourceDeviceID
+	}
+	return nil
+}
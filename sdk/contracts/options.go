@@ -1,5 +1,12 @@
 package contracts
 
+import (
+	"context"
+	"crypto/tls"
+	"strconv"
+	"time"
+)
+
 // MIDICommand represents the types of MIDI commands for event filtering.
 type MIDICommand byte
 
@@ -12,7 +19,21 @@ const (
 
 // MIDIEventFilter allows users to specify which MIDI commands to capture.
 type MIDIEventFilter struct {
-	Commands []MIDICommand // List of MIDI commands to filter.
+	Commands []MIDICommand // List of MIDI commands to filter. A channel message status on channel 1 (e.g. 0x90) matches every channel; other statuses (e.g. 0x93) match exactly.
+}
+
+// Matches reports whether the filter lets event through.
+func (f MIDIEventFilter) Matches(event MIDI) bool {
+	for _, allowedCommand := range f.Commands {
+		if event.Command == byte(allowedCommand) {
+			return true
+		}
+		// A channel message type without channel matches it on every channel.
+		if allowed := byte(allowedCommand); allowed >= 0x80 && allowed < 0xF0 && allowed&0x0F == 0 && event.Command&0xF0 == allowed {
+			return true
+		}
+	}
+	return false
 }
 
 // CoreMIDIConfig holds configuration for CoreMIDI.
@@ -20,13 +41,275 @@ type CoreMIDIConfig struct {
 	ClientName string // Name of the MIDI client.
 }
 
+// WebMIDIConfig holds configuration for the Web MIDI API, the native backend of js/wasm builds.
+type WebMIDIConfig struct {
+	SysEx bool // Whether to request SysEx access, for which browsers ask the user a stronger permission.
+}
+
+// Transform changes an event, or drops it by returning false.
+type Transform func(event MIDI) (MIDI, bool)
+
+// VelocityCurves holds the velocity tables remapping the velocities of captured note ons,
+// such as those of sdk/velocity: a velocity v becomes table[v].
+type VelocityCurves struct {
+	Default *[128]byte           // Table of the devices without a table of their own; nil leaves their velocities unchanged.
+	Devices map[string][128]byte // Tables of devices by name, matched regardless of case.
+}
+
+// SoftwareOutput is an output device implemented by the application, such as a
+// synthesizer rendering the messages it is sent to audio.
+type SoftwareOutput struct {
+	Name  string           // Name listed by ListOutputDevices.
+	Write func(MIDI) error // Handles the messages sent to the output.
+}
+
+// ThruConfig holds the configuration of MIDI thru, which echoes captured events to an
+// output device.
+type ThruConfig struct {
+	DeviceID int              // ID of the output device, as listed by ListOutputDevices.
+	Filtered bool             // Whether only the events passing the event filter and predicate are echoed, after the transforms.
+	Send     func(MIDI) error // Sends the echoed events; set by midi.NewMIDIClient to the Send method of the client.
+}
+
+// Backend names accepted by WithBackend. When no backend is set, the native
+// backend of the current operating system is used.
+const (
+	// BackendRemote connects to a remote instance of this package over gRPC.
+	BackendRemote = "remote"
+	// BackendLoopback provides a single device whose output is wired to its input:
+	// events and SysEx messages sent to it are captured at once, for tools and
+	// end-to-end tests without hardware.
+	BackendLoopback = "loopback"
+	// BackendSerial reads MIDI from serial ports, such as a UART wired to DIN jacks.
+	BackendSerial = "serial"
+	// BackendUSB talks the USB MIDI class protocol directly through libusb. It is only
+	// available in builds with cgo and the usb build tag.
+	BackendUSB = "usb"
+	// BackendReplay plays back recorded events as a device, for soak tests and
+	// deterministic tests of the capture path.
+	BackendReplay = "replay"
+	// BackendRTP joins RTP-MIDI (AppleMIDI) sessions on the local network, such as those
+	// of macOS, iOS apps and rtpMIDI on Windows, without a hardware interface.
+	BackendRTP = "rtpmidi"
+	// BackendBLE connects to Bluetooth LE MIDI peripherals. It is only available in builds
+	// with the ble build tag.
+	BackendBLE = "ble"
+	// BackendJACK joins the graph of a JACK server, or of PipeWire through its JACK
+	// library, as a client with MIDI ports other applications can connect to. It is only
+	// available on Linux, in builds with cgo and the jack build tag.
+	BackendJACK = "jack"
+)
+
+// RemoteConfig holds configuration for the remote backend.
+type RemoteConfig struct {
+	Address string         // Address (host:port) of the remote MIDI server.
+	TLS     *tls.Config    // TLS configuration of the connection; nil connects without TLS.
+	Token   string         // Token sent with every call to servers requiring one (remote.WithTokens). Use TLS to keep it secret.
+	Keys    []RemoteKey    // Keys encrypting the payloads for servers configured with remote.WithKeyring; the first encrypts outgoing payloads.
+	Keyring PayloadKeyring // Keyring encrypting the payloads instead of Keys, e.g. a *remote.Keyring shared with other clients so its keys rotate without reconnecting.
+}
+
+// PayloadKeyring encrypts and authenticates the payloads exchanged with a remote MIDI
+// server. *remote.Keyring implements it.
+type PayloadKeyring interface {
+	Seal(plaintext []byte) ([]byte, error) // Seal encrypts an outgoing payload.
+	Open(payload []byte) ([]byte, error)   // Open decrypts an incoming payload, failing if it was altered or replayed.
+}
+
+// RemoteKey is a shared key encrypting the payloads exchanged with a remote MIDI server.
+type RemoteKey struct {
+	ID     string // Identifier sent with every payload, so the receiver picks the key; at most 255 bytes.
+	Secret []byte // AES key of 16, 24 or 32 bytes, e.g. from crypto/rand.
+}
+
+// SerialConfig holds configuration for the serial backend.
+type SerialConfig struct {
+	Ports    []string // Serial ports listed as devices (e.g. "/dev/ttyAMA0"); all ports of the system when empty.
+	BaudRate int      // Speed of the ports; defaults to the MIDI rate of 31250 baud. USB-serial adapters often need 38400 or 115200.
+}
+
+// RTPConfig holds configuration for the RTP-MIDI backend.
+type RTPConfig struct {
+	Name          string        // Session name shown to peers; defaults to "Go MIDI Session".
+	Port          int           // Control port of the session; the data port is Port+1. Defaults to 5004.
+	Peers         []string      // Control addresses (host:port) of sessions listed as devices before the discovered ones.
+	BrowseTimeout time.Duration // How long ListDevices browses the network for sessions; one second by default, negative to list Peers only.
+	Advertise     bool          // Whether the session is announced on the network, so peers can connect to it.
+}
+
+// BLEConfig holds configuration for the Bluetooth LE MIDI backend.
+type BLEConfig struct {
+	ScanTimeout time.Duration // How long ListDevices scans for peripherals; two seconds by default.
+}
+
+// JACKConfig holds configuration for the JACK backend.
+type JACKConfig struct {
+	ClientName      string // Name of the client in the JACK graph, prefixing the names of its ports; defaults to "Go MIDI".
+	ServerName      string // Name of the JACK server to join; the default server when empty.
+	FrameTimestamps bool   // Stamps events with the frame of the graph they arrived at under DefaultClock, to align them with audio sample by sample.
+}
+
+// ReplayConfig holds configuration for the replay backend.
+type ReplayConfig struct {
+	Events        []MIDI        // Recorded events, in order.
+	TimestampUnit time.Duration // Duration of one unit of the event timestamps; defaults to a nanosecond.
+	Loop          bool          // Whether playback restarts from the first event after the last one.
+}
+
+// ReplayMode selects how recorded events are paced when played back.
+type ReplayMode int
+
+const (
+	// ReplayRealtime plays events with the intervals they were recorded with.
+	ReplayRealtime ReplayMode = iota
+	// ReplayScaled plays events with the recorded intervals divided by a speed factor.
+	ReplayScaled
+	// ReplayAsFastAsPossible plays events without waiting between them. Delivery waits for
+	// room in the event channel instead of dropping events, so runs are deterministic.
+	ReplayAsFastAsPossible
+)
+
+// ReplayTiming selects the pacing of played-back events.
+type ReplayTiming struct {
+	Mode  ReplayMode // Pacing mode.
+	Speed float64    // Speed factor of ReplayScaled; 10 plays ten times faster, 0.5 at half speed.
+}
+
+// Replay timings.
+var (
+	RealtimeReplay = ReplayTiming{Mode: ReplayRealtime}         // Faithful realtime playback.
+	FastReplay     = ReplayTiming{Mode: ReplayAsFastAsPossible} // Playback without delays, for unit tests.
+)
+
+// ScaledReplay returns a timing playing events speed times faster than recorded, e.g. for
+// soak tests that compress hours of traffic.
+func ScaledReplay(speed float64) ReplayTiming {
+	return ReplayTiming{Mode: ReplayScaled, Speed: speed}
+}
+
+// DiscoveryConfig holds configuration for discovering network MIDI endpoints via mDNS.
+type DiscoveryConfig struct {
+	Timeout time.Duration // How long ListDevices browses the network; defaults to one second.
+}
+
+// InactivityWatchdog holds configuration for detecting a silent device while capturing.
+type InactivityWatchdog struct {
+	Timeout  time.Duration            // Silence after which Callback fires.
+	Callback func(idle time.Duration) // Called with the time elapsed since the last event (or since capture started).
+}
+
+// OpenRetry holds the policy for retrying a device that fails to open or start.
+type OpenRetry struct {
+	Attempts int           // Total number of attempts, including the first one.
+	Backoff  time.Duration // Delay before the second attempt; doubled after every further failure.
+}
+
+// Hooks holds callbacks invoked on lifecycle changes of a client, so applications can
+// follow its state without polling. Nil callbacks are skipped. The callbacks of a client
+// run one at a time, in the order of the changes, on a goroutine of their own, so they
+// may call the client.
+type Hooks struct {
+	OnDeviceSelected func(deviceID int, device DeviceInfo)            // A device was selected and opened.
+	OnCaptureStarted func()                                           // Events started being delivered to a channel.
+	OnCaptureStopped func()                                           // Events stopped being delivered, by Stop or a change of device.
+	OnDeviceLost     func(deviceID int, device DeviceInfo, err error) // The selected device went away while open, e.g. unplugged.
+}
+
+// ParsingMode selects how backends that parse raw MIDI bytes treat malformed data, such as
+// data bytes without a status byte or messages cut short by another status byte.
+type ParsingMode int
+
+const (
+	// LenientParsing skips malformed bytes and resynchronizes on the next status byte
+	// without reporting them. Messages split across packets are reassembled.
+	LenientParsing ParsingMode = iota
+	// StrictParsing discards malformed sequences, including messages split across packets,
+	// and reports each one on the error channel as a *MalformedDataError.
+	StrictParsing
+)
+
+// OverflowPolicy selects what a client does with an event when the event channel is full,
+// trading latency for completeness.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop discards the new event, so the consumer always sees the oldest events
+	// first. It is the default.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock waits for room in the channel, so no event is discarded there while the
+	// consumer keeps up on average. The wait happens on the per-device queues of
+	// WithSourceQueues, which it enables with 256 events each unless set otherwise, so the
+	// driver callbacks never block; events are only lost when a device's queue fills up.
+	OverflowBlock
+	// OverflowRingBuffer discards the oldest event in the channel to make room, so the
+	// consumer always sees the most recent events.
+	OverflowRingBuffer
+	// OverflowCoalesce queues the events that do not fit, up to 4096, keeping only the
+	// latest value of each continuous control waiting in the queue: pitch bend, channel and
+	// polyphonic pressure, and control changes other than bank select and the parameter
+	// number messages. Dense sweeps then cost one event per control instead of flooding the
+	// channel, while notes are kept in order.
+	OverflowCoalesce
+)
+
+// String names the policy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDrop:
+		return "drop"
+	case OverflowBlock:
+		return "block"
+	case OverflowRingBuffer:
+		return "ring buffer"
+	case OverflowCoalesce:
+		return "coalesce"
+	default:
+		return "OverflowPolicy(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
 // ClientOptions defines the configuration options for the MIDI client.
 type ClientOptions struct {
-	Logger          Logger           // Logger for logging events and errors.
-	LogLevel        LogLevel         // Level of logging to use.
-	LogFilePath     string           // File path for logging if file logging is enabled.
-	MIDIEventFilter *MIDIEventFilter // Optional filter for MIDI events to capture.
-	CoreMIDIConfig  *CoreMIDIConfig  // Configuration specific to CoreMIDI.
+	Logger             Logger              // Logger for logging events and errors.
+	LogLevel           LogLevel            // Level of logging to use.
+	LogDestination     LogDestination      // Destination set on the logger; FileLog when only LogFilePath is set, unchanged otherwise.
+	LogFilePath        string              // File path for logging if file logging is enabled.
+	LogRotation        *LogRotation        // Optional rotation of the log file, for loggers implementing RotatingLogger.
+	MIDIEventFilter    *MIDIEventFilter    // Optional filter for MIDI events to capture.
+	EventPredicate     func(MIDI) bool     // Optional filter applied after MIDIEventFilter; events it returns false for are discarded.
+	Transforms         []Transform         // Transforms applied in order to the events passing the filters.
+	VelocityCurves     *VelocityCurves     // Optional remapping of the velocities of note ons, before the transforms.
+	CoreMIDIConfig     *CoreMIDIConfig     // Configuration specific to CoreMIDI.
+	WebMIDIConfig      *WebMIDIConfig      // Configuration specific to the Web MIDI API.
+	Backend            string              // Name of the backend to use instead of the native one.
+	RemoteConfig       *RemoteConfig       // Configuration specific to the remote backend.
+	SerialConfig       *SerialConfig       // Configuration specific to the serial backend.
+	ReplayConfig       *ReplayConfig       // Configuration specific to the replay backend.
+	RTPConfig          *RTPConfig          // Configuration specific to the RTP-MIDI backend.
+	BLEConfig          *BLEConfig          // Configuration specific to the Bluetooth LE MIDI backend.
+	JACKConfig         *JACKConfig         // Configuration specific to the JACK backend.
+	ReplayTiming       ReplayTiming        // Pacing of played-back events; RealtimeReplay by default.
+	Discovery          *DiscoveryConfig    // Optional discovery of network endpoints listed alongside devices.
+	InactivityWatchdog *InactivityWatchdog // Optional detection of missing events during capture.
+	OpenRetry          *OpenRetry          // Optional retry of failed device opens and starts.
+	DisableLogging     bool                // Discards all internal logging; the capture path makes no logging calls.
+	SysEx              *SysExConfig        // Optional delivery of SysEx messages; they are discarded otherwise.
+	MIDI2              chan MIDI2Event     // Optional delivery of channel messages as MIDI 2.0 events.
+	SourceQueue        int                 // Length of the per-device dispatch queues; 0 dispatches on the capture callback.
+	RealtimeDispatch   bool                // Pins the dispatch goroutines to OS threads with raised priority.
+	ParsingMode        ParsingMode         // Treatment of malformed data; LenientParsing by default.
+	Errors             chan error          // Optional channel receiving errors detected while capturing.
+	DedupWindow        time.Duration       // Window within which identical events from different devices of an input group are suppressed; 0 disables.
+	Duplicate          func(MIDI) bool     // Reports whether an event repeats one of another device; set by input groups from DedupWindow.
+	Hooks              *Hooks              // Optional lifecycle callbacks.
+	AutoReconnect      *AutoReconnect      // Optional reopening of the selected device after it is lost.
+	Thru               *ThruConfig         // Optional echo of captured events to an output device.
+	SoftwareOutputs    []SoftwareOutput    // Output devices implemented by the application, listed after those of the backend.
+	CaptureWorkers     int                 // Goroutines calling the handler of StartCaptureFunc; one by default.
+	CaptureBuffer      int                 // Events queued for the handler of StartCaptureFunc; 1024 by default.
+	Overflow           OverflowPolicy      // What to do with events when the event channel is full; OverflowDrop by default.
+	Clock              TimestampClock      // Clock of the timestamps of captured events; DefaultClock by default.
+	Context            context.Context     // Lifetime of the client, set by NewMIDIClientContext; calls to network peers end with it.
 }
 
 // Option is a function that modifies ClientOptions.
@@ -46,6 +329,33 @@ func WithLogLevel(level LogLevel) Option {
 	}
 }
 
+// WithLogDestination directs the logs of the client to the console, a file or both, with
+// Logger.SetDestination. It applies to the logger of WithLogger too.
+func WithLogDestination(dest LogDestination, filePath ...string) Option {
+	return func(opts *ClientOptions) {
+		opts.LogDestination = dest
+		if len(filePath) > 0 {
+			opts.LogFilePath = filePath[0]
+		}
+	}
+}
+
+// WithLogRotation rotates the log file of WithLogDestination when it reaches the size or
+// the age of rotation, keeping the backups it sets.
+func WithLogRotation(rotation LogRotation) Option {
+	return func(opts *ClientOptions) {
+		opts.LogRotation = &rotation
+	}
+}
+
+// WithoutLogging disables all internal logging, replacing any logger set with WithLogger.
+// The capture path then makes no logging calls at all, not even discarded ones.
+func WithoutLogging() Option {
+	return func(opts *ClientOptions) {
+		opts.DisableLogging = true
+	}
+}
+
 // WithMIDIEventFilter sets the MIDI event filter for the MIDI client.
 func WithMIDIEventFilter(filter MIDIEventFilter) Option {
 	return func(opts *ClientOptions) {
@@ -53,9 +363,353 @@ func WithMIDIEventFilter(filter MIDIEventFilter) Option {
 	}
 }
 
+// WithEventPredicate discards the events keep returns false for, after the event filter,
+// for filtering that commands alone cannot express, such as dropping aftertouch below a
+// threshold or keeping only the notes of a drum pad. Discarded events are counted as
+// filtered. keep runs on the capture path of every device, so it must be fast, must not
+// block and must be safe for concurrent use; events it panics on are discarded and the
+// panic is reported like that of a hook.
+func WithEventPredicate(keep func(event MIDI) bool) Option {
+	return func(opts *ClientOptions) {
+		opts.EventPredicate = keep
+	}
+}
+
+// WithTransforms changes the events passing the event filter and predicate with
+// transforms, applied in order before the events reach the consumer, e.g. to transpose a
+// keyboard or split it across channels; sdk/transform provides common ones. Events a
+// transform drops are counted as filtered. Transforms run on the capture path of every
+// device, so they must be fast, must not block and must be safe for concurrent use; events
+// they panic on are discarded and the panic is reported like that of a hook. Calling it
+// again adds transforms after the previous ones.
+func WithTransforms(transforms ...Transform) Option {
+	return func(opts *ClientOptions) {
+		opts.Transforms = append(opts.Transforms, transforms...)
+	}
+}
+
+// WithVelocityCurve remaps the velocities of captured note ons with table, e.g. to
+// compensate for a keybed that is too light: a velocity v becomes table[v].
+// sdk/velocity builds tables from curves, curve editor breakpoints and presets such as
+// velocity.LightKeybed. The table applies to every device without a table set with
+// WithDeviceVelocityCurve. Velocities are remapped after the event filter and predicate,
+// before the transforms set with WithTransforms; note ons keep a velocity of at least 1,
+// so they are not turned into note offs.
+func WithVelocityCurve(table [128]byte) Option {
+	return func(opts *ClientOptions) {
+		if opts.VelocityCurves == nil {
+			opts.VelocityCurves = &VelocityCurves{}
+		}
+		opts.VelocityCurves.Default = &table
+	}
+}
+
+// WithDeviceVelocityCurve remaps the velocities of the note ons captured from the device
+// named device, regardless of case, with table, like WithVelocityCurve for that device
+// only, e.g. to give each keyboard of a setup a preset of its own. Calling it again for the
+// same device replaces its table.
+func WithDeviceVelocityCurve(device string, table [128]byte) Option {
+	return func(opts *ClientOptions) {
+		if opts.VelocityCurves == nil {
+			opts.VelocityCurves = &VelocityCurves{}
+		}
+		if opts.VelocityCurves.Devices == nil {
+			opts.VelocityCurves.Devices = make(map[string][128]byte)
+		}
+		opts.VelocityCurves.Devices[device] = table
+	}
+}
+
 // WithCoreMIDIConfig sets the CoreMIDI configuration for the MIDI client.
 func WithCoreMIDIConfig(config CoreMIDIConfig) Option {
 	return func(opts *ClientOptions) {
 		opts.CoreMIDIConfig = &config
 	}
 }
+
+// WithWebMIDIConfig sets the Web MIDI configuration for the MIDI client in js/wasm builds.
+func WithWebMIDIConfig(config WebMIDIConfig) Option {
+	return func(opts *ClientOptions) {
+		opts.WebMIDIConfig = &config
+	}
+}
+
+// WithBackend selects a backend by name (e.g. BackendRemote) instead of the native one.
+func WithBackend(name string) Option {
+	return func(opts *ClientOptions) {
+		opts.Backend = name
+	}
+}
+
+// WithRemoteConfig sets the remote backend configuration for the MIDI client.
+func WithRemoteConfig(config RemoteConfig) Option {
+	return func(opts *ClientOptions) {
+		opts.RemoteConfig = &config
+	}
+}
+
+// WithSerialConfig sets the serial backend configuration for the MIDI client.
+func WithSerialConfig(config SerialConfig) Option {
+	return func(opts *ClientOptions) {
+		opts.SerialConfig = &config
+	}
+}
+
+// WithRTPConfig sets the RTP-MIDI backend configuration for the MIDI client.
+func WithRTPConfig(config RTPConfig) Option {
+	return func(opts *ClientOptions) {
+		opts.RTPConfig = &config
+	}
+}
+
+// WithBLEConfig sets the Bluetooth LE MIDI backend configuration for the MIDI client.
+func WithBLEConfig(config BLEConfig) Option {
+	return func(opts *ClientOptions) {
+		opts.BLEConfig = &config
+	}
+}
+
+// WithJACKConfig sets the JACK backend configuration for the MIDI client.
+func WithJACKConfig(config JACKConfig) Option {
+	return func(opts *ClientOptions) {
+		opts.JACKConfig = &config
+	}
+}
+
+// WithReplayConfig sets the events played back by the replay backend.
+func WithReplayConfig(config ReplayConfig) Option {
+	return func(opts *ClientOptions) {
+		opts.ReplayConfig = &config
+	}
+}
+
+// WithReplayTiming selects how played-back events are paced: RealtimeReplay,
+// ScaledReplay(speed) or FastReplay.
+func WithReplayTiming(timing ReplayTiming) Option {
+	return func(opts *ClientOptions) {
+		opts.ReplayTiming = timing
+	}
+}
+
+// WithNetworkDiscovery makes ListDevices also report AppleMIDI sessions and remote MIDI
+// servers announced on the local network via mDNS, after the hardware devices.
+func WithNetworkDiscovery(config DiscoveryConfig) Option {
+	return func(opts *ClientOptions) {
+		opts.Discovery = &config
+	}
+}
+
+// WithInactivityWatchdog calls callback when no events have arrived for d while capture is
+// active, which usually means a dead cable or a hung driver. The callback fires once per
+// silent period and runs on its own goroutine, never on the capture path.
+func WithInactivityWatchdog(d time.Duration, callback func(idle time.Duration)) Option {
+	return func(opts *ClientOptions) {
+		opts.InactivityWatchdog = &InactivityWatchdog{Timeout: d, Callback: callback}
+	}
+}
+
+// WithOpenRetry retries opening and starting a device up to attempts times in total, waiting
+// backoff before the second attempt and doubling the wait after every further failure. This
+// covers the transient failures common right after a USB device is enumerated.
+func WithOpenRetry(attempts int, backoff time.Duration) Option {
+	return func(opts *ClientOptions) {
+		opts.OpenRetry = &OpenRetry{Attempts: attempts, Backoff: backoff}
+	}
+}
+
+// WithSysExChannel delivers the SysEx messages received while capturing to ch, independently
+// of the event channel passed to StartCapture. Size ch for the largest burst expected; when
+// it is full, messages are dropped without affecting channel events.
+func WithSysExChannel(ch chan SysExEvent) Option {
+	return func(opts *ClientOptions) {
+		config := sysExConfig(opts)
+		config.Channel, config.Handler, config.Buffer = ch, nil, 0
+	}
+}
+
+// WithSysExHandler calls handler for every SysEx message received while capturing. Messages
+// are queued, up to buffer of them, and handled on a dedicated goroutine, so a slow handler
+// never delays channel events.
+func WithSysExHandler(handler func(SysExEvent), buffer int) Option {
+	return func(opts *ClientOptions) {
+		config := sysExConfig(opts)
+		config.Channel, config.Handler, config.Buffer = nil, handler, buffer
+	}
+}
+
+// WithSysExMaxSize sets the longest SysEx message delivered, in bytes, for sample dumps
+// and firmware transfers larger than the default 64 KiB. Longer messages are discarded
+// and reported as malformed. It requires WithSysExChannel or WithSysExHandler.
+func WithSysExMaxSize(size int) Option {
+	return func(opts *ClientOptions) {
+		sysExConfig(opts).MaxSize = size
+	}
+}
+
+// WithSysExDriverBuffers sets how many buffers of size bytes are queued with drivers
+// receiving SysEx into fixed buffers, such as winmm. More or larger buffers avoid losing
+// data on dense SysEx traffic. It requires WithSysExChannel or WithSysExHandler.
+func WithSysExDriverBuffers(count, size int) Option {
+	return func(opts *ClientOptions) {
+		config := sysExConfig(opts)
+		config.DriverBuffers, config.DriverBufferSize = count, size
+	}
+}
+
+// WithMIDI2Channel delivers the channel voice messages received while capturing to ch as
+// MIDI 2.0 events, in addition to the event channel passed to StartCapture. On macOS 11 and
+// later CoreMIDI delivers them at full resolution, with 16-bit velocities, 32-bit values and
+// per-note controllers; the event channel then receives them converted to MIDI 1.0. Other
+// backends receive MIDI 1.0 and deliver its messages converted to MIDI 2.0. The event filter
+// does not apply to ch, and when it is full events are dropped without affecting the event
+// channel.
+func WithMIDI2Channel(ch chan MIDI2Event) Option {
+	return func(opts *ClientOptions) {
+		opts.MIDI2 = ch
+	}
+}
+
+// sysExConfig returns the SysEx configuration of opts, creating it if needed, so the
+// SysEx options combine in any order.
+func sysExConfig(opts *ClientOptions) *SysExConfig {
+	if opts.SysEx == nil {
+		opts.SysEx = &SysExConfig{}
+	}
+	return opts.SysEx
+}
+
+// WithSourceQueues gives every capturing device its own dispatch goroutine fed by a
+// lock-free ring buffer of size events, rounded up to a power of two. The device callbacks
+// then only copy events into the ring, without locking or allocating, so delivery for one
+// device never holds up another; events that overflow a ring are dropped and counted in
+// that device's Stats. With OverflowBlock, the rings absorb bursts while the consumer
+// catches up.
+func WithSourceQueues(size int) Option {
+	return func(opts *ClientOptions) {
+		opts.SourceQueue = size
+	}
+}
+
+// WithRealtimeDispatch locks every device's dispatch goroutine to its own OS thread and raises
+// that thread's priority (time-critical on Windows, user-interactive QoS on macOS) to reduce
+// scheduling jitter. It enables the per-device queues of WithSourceQueues, with 256 events each
+// unless set otherwise. Where priorities are unsupported the threads are only locked.
+func WithRealtimeDispatch() Option {
+	return func(opts *ClientOptions) {
+		opts.RealtimeDispatch = true
+	}
+}
+
+// WithOverflowPolicy selects what the client does with events when the event channel is
+// full. Events discarded by any policy are counted in Stats().Dropped and Health().EventsDropped,
+// and events merged by OverflowCoalesce in Stats().Coalesced. OverflowBlock and
+// OverflowRingBuffer need a buffered event channel; StartCapture returns
+// ErrUnbufferedChannel for an unbuffered one.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(opts *ClientOptions) {
+		opts.Overflow = policy
+	}
+}
+
+// WithTimestampClock selects the clock captured events are timestamped with on the
+// CoreMIDI, winmm, Web MIDI, serial, USB and BLE backends. HardwareClock keeps the timing of the driver,
+// for recording and analysis that must not be skewed by callback delays. Remote,
+// loopback and replay events keep the timestamps they were given.
+func WithTimestampClock(clock TimestampClock) Option {
+	return func(opts *ClientOptions) {
+		opts.Clock = clock
+	}
+}
+
+// WithParsingMode selects how malformed data is treated. StrictParsing helps qualify flaky
+// hardware and adapters by reporting every malformed sequence on the error channel.
+func WithParsingMode(mode ParsingMode) Option {
+	return func(opts *ClientOptions) {
+		opts.ParsingMode = mode
+	}
+}
+
+// WithErrorChannel sends the errors detected while capturing, such as malformed data in
+// strict parsing mode, to ch. Errors are dropped when ch is full, so capture never blocks.
+func WithErrorChannel(ch chan error) Option {
+	return func(opts *ClientOptions) {
+		opts.Errors = ch
+	}
+}
+
+// WithDeduplication suppresses identical events arriving from different devices of an
+// input group within window of each other, as when a controller is connected both through
+// USB and through a MIDI thru chain. Only the first copy is delivered; repeated events from
+// the same device are never suppressed, so a client capturing a single device ignores the
+// option. Suppressed events are counted in the Health of the client that received them.
+// Keep the window short, a few milliseconds, so that identical events played on different
+// devices are still delivered.
+func WithDeduplication(window time.Duration) Option {
+	return func(opts *ClientOptions) {
+		opts.DedupWindow = window
+	}
+}
+
+// WithHooks sets callbacks invoked when a device is selected or lost and when capture
+// starts or stops. Every backend reports selections and capture changes, and every
+// backend whose devices can go away reports lost devices; loopback and replay devices are
+// never lost.
+func WithHooks(hooks Hooks) Option {
+	return func(opts *ClientOptions) {
+		opts.Hooks = &hooks
+	}
+}
+
+// WithAutoReconnect reopens the selected device when it is lost, e.g. briefly unplugged.
+// The device is looked up by name, entity and manufacturer every interval, since its ID
+// may change, and capture resumes on it once it is back. Connection state changes are
+// sent to events, which may be nil; a full channel drops them. Losses are detected by the
+// backends reporting them to WithHooks.
+func WithAutoReconnect(interval time.Duration, events chan ConnectionEvent) Option {
+	return func(opts *ClientOptions) {
+		opts.AutoReconnect = &AutoReconnect{Interval: interval, Events: events}
+	}
+}
+
+// WithThru echoes every captured event to the output device with the given ID, as listed
+// by ListOutputDevices, so the client can sit between a controller and a sound module.
+// Events are sent from the capture path as they are received, before the event filter,
+// without waiting for the consumer. The device is selected as the output of the client
+// when it is created, so Send also sends to it. Errors sending are reported to the error
+// channel set with WithErrorChannel.
+func WithThru(outputDeviceID int) Option {
+	return func(opts *ClientOptions) {
+		opts.Thru = &ThruConfig{DeviceID: outputDeviceID}
+	}
+}
+
+// WithFilteredThru echoes captured events to an output device like WithThru, but only the
+// events passing the event filter and the event predicate, changed by the transforms set
+// with WithTransforms, as they are delivered.
+func WithFilteredThru(outputDeviceID int) Option {
+	return func(opts *ClientOptions) {
+		opts.Thru = &ThruConfig{DeviceID: outputDeviceID, Filtered: true}
+	}
+}
+
+// WithSoftwareOutput adds an output device implemented by the application, such as the
+// SoundFont synthesizer of sdk/sink/soundfont, to every backend. ListOutputDevices lists
+// it after the devices of the backend, and once it is selected with SelectOutputDevice,
+// Send and thru pass messages to write instead of the backend. Several outputs can be
+// added; the client does not close them when stopped.
+func WithSoftwareOutput(name string, write func(MIDI) error) Option {
+	return func(opts *ClientOptions) {
+		opts.SoftwareOutputs = append(opts.SoftwareOutputs, SoftwareOutput{Name: name, Write: write})
+	}
+}
+
+// WithCaptureWorkers runs the handler passed to StartCaptureFunc on workers goroutines,
+// fed by a queue of buffer events. With one worker, the default, events are handled one
+// at a time in order; more workers handle events concurrently, without ordering, for
+// handlers that block, such as ones writing to a network. Events that overflow the queue
+// are dropped and counted like those of a full channel.
+func WithCaptureWorkers(workers, buffer int) Option {
+	return func(opts *ClientOptions) {
+		opts.CaptureWorkers, opts.CaptureBuffer = workers, buffer
+	}
+}
@@ -0,0 +1,124 @@
+package contracts
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Stats describes the event traffic of a MIDI client, for spotting stuck devices or
+// unexpected flooding, and for monitoring services that embed the client (see sdk/metrics).
+type Stats struct {
+	Devices    []DeviceStats // Statistics of each capturing device; empty when no device is selected.
+	Received   uint64        // Events received from every device, before filtering.
+	Filtered   uint64        // Events discarded by the event filter, the event predicate or a transform.
+	Dropped    uint64        // Events discarded because the event channel was full, under any overflow policy.
+	Coalesced  uint64        // Events replaced by a later value of the same control under OverflowCoalesce.
+	SysExBytes uint64        // Bytes of the SysEx messages received while capturing, including those dropped.
+	Queued     int           // Events waiting in the event channel; zero when capture is stopped.
+	Capacity   int           // Capacity of the event channel; zero when capture is stopped.
+	Callbacks  LatencyStats  // Time the handler of StartCaptureFunc takes for each event.
+}
+
+// Occupancy returns the fraction of the event channel in use, from 0 to 1, or zero when
+// capture is stopped. A channel staying close to full means the consumer cannot keep up
+// and events are about to be dropped.
+func (s Stats) Occupancy() float64 {
+	if s.Capacity == 0 {
+		return 0
+	}
+	return float64(s.Queued) / float64(s.Capacity)
+}
+
+// DeviceStats describes the event traffic of one device.
+type DeviceStats struct {
+	DeviceID     int            // ID of the device.
+	Device       DeviceInfo     // Information about the device.
+	Received     uint64         // Events received from the device, before filtering.
+	QueueDropped uint64         // Events discarded because the device's queue was full (see WithSourceQueues).
+	Intervals    IntervalStats  // Time between consecutive events received from the device.
+	Commands     []CommandStats // Events received from the device by kind, ordered by status byte and controller.
+}
+
+// CommandStats counts the events of one kind received from a device: a command type on a
+// channel, such as note on on channel 1, a controller on a channel for control changes,
+// such as the sustain pedal (CC64) on channel 1, or a system message such as clock.
+// Comparing Received with Filtered shows whether the event filter discards the traffic the
+// application expects.
+type CommandStats struct {
+	Command    byte   // Status byte of the events, with the zero-based channel for channel messages (e.g. 0x90 for note on on channel 1).
+	Controller int    // Controller number for control changes (status 0xB0-0xBF), or -1.
+	Received   uint64 // Events received, before filtering.
+	Filtered   uint64 // Events discarded by the event filter.
+}
+
+// commandNames names the channel message types, by the high nibble of the status byte.
+var commandNames = map[byte]string{
+	0x80: "NoteOff",
+	0x90: "NoteOn",
+	0xA0: "PolyPressure",
+	0xB0: "CC",
+	0xC0: "ProgramChange",
+	0xD0: "ChannelPressure",
+	0xE0: "PitchBend",
+}
+
+// systemNames names the system messages, by status byte.
+var systemNames = map[byte]string{
+	0xF0: "SysEx",
+	0xF1: "TimeCode",
+	0xF2: "SongPosition",
+	0xF3: "SongSelect",
+	0xF6: "TuneRequest",
+	0xF8: "Clock",
+	0xFA: "Start",
+	0xFB: "Continue",
+	0xFC: "Stop",
+	0xFE: "ActiveSensing",
+	0xFF: "Reset",
+}
+
+// String names the kind of events with a one-based channel, e.g. "NoteOn ch1", "CC64 ch1"
+// or "Clock".
+func (c CommandStats) String() string {
+	if c.Command >= 0xF0 {
+		if name, ok := systemNames[c.Command]; ok {
+			return name
+		}
+		return fmt.Sprintf("0x%02X", c.Command)
+	}
+	name, ok := commandNames[c.Command&0xF0]
+	if !ok {
+		return fmt.Sprintf("0x%02X", c.Command)
+	}
+	if c.Command&0xF0 == 0xB0 {
+		name += strconv.Itoa(c.Controller)
+	}
+	return fmt.Sprintf("%s ch%d", name, c.Command&0x0F+1)
+}
+
+// IntervalStats summarises the intervals between consecutive events received from a device,
+// before filtering. It covers the most recent intervals since capture started.
+type IntervalStats struct {
+	Count           int           // Number of intervals summarised.
+	Min             time.Duration // Shortest interval.
+	Max             time.Duration // Longest interval.
+	Mean            time.Duration // Average interval.
+	P50             time.Duration // Median interval.
+	P95             time.Duration // 95th percentile interval.
+	P99             time.Duration // 99th percentile interval.
+	EventsPerSecond float64       // Event rate over the summarised intervals.
+}
+
+// LatencyStats summarises the time the handler of StartCaptureFunc took for the most recent
+// events since capture started. A handler slower than the interval between events makes
+// events queue up in the event channel.
+type LatencyStats struct {
+	Count int           // Number of calls summarised.
+	Min   time.Duration // Shortest call.
+	Max   time.Duration // Longest call.
+	Mean  time.Duration // Average call.
+	P50   time.Duration // Median call.
+	P95   time.Duration // 95th percentile call.
+	P99   time.Duration // 99th percentile call.
+}
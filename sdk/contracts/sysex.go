@@ -0,0 +1,19 @@
+package contracts
+
+// SysExEvent is a complete System Exclusive message, delivered separately from channel
+// events so that large payloads neither block nor crowd out note traffic.
+type SysExEvent struct {
+	Timestamp uint64 // Timestamp indicates the time the message was received.
+	Data      []byte // Data holds the message, from the 0xF0 start byte to the 0xF7 end byte.
+}
+
+// SysExConfig holds configuration for delivering SysEx messages.
+type SysExConfig struct {
+	Channel chan SysExEvent  // Channel messages are sent to; a full channel drops messages.
+	Handler func(SysExEvent) // Called for every message on its own goroutine, used when Channel is nil.
+	Buffer  int              // Messages queued for Handler before dropping; defaults to 16.
+	MaxSize int              // Longest message delivered, in bytes; longer ones are discarded as malformed. Defaults to 64 KiB.
+
+	DriverBuffers    int // Buffers queued with the driver by backends receiving SysEx into fixed buffers (winmm); defaults to 4.
+	DriverBufferSize int // Size in bytes of each driver buffer; longer messages span several. Defaults to 1024.
+}
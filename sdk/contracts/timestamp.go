@@ -0,0 +1,63 @@
+package contracts
+
+import (
+	"strconv"
+	"time"
+)
+
+// TimestampClock selects the clock captured events are timestamped with.
+type TimestampClock int
+
+const (
+	// DefaultClock keeps the timestamps of each backend: Unix nanoseconds of arrival on
+	// CoreMIDI, winmm, Web MIDI and RTP-MIDI, milliseconds since the start of capture on
+	// serial, USB and BLE, and the time of the frame the message arrived at on JACK, in
+	// nanoseconds on the JACK clock or in frames with JACKConfig.FrameTimestamps.
+	DefaultClock TimestampClock = iota
+	// WallClock stamps events with the Unix nanoseconds of their arrival. Wall time can
+	// jump when the system clock is adjusted.
+	WallClock
+	// MonotonicClock stamps events with the time of their arrival on the monotonic clock of
+	// the process, in nanoseconds; see MonotonicNow.
+	MonotonicClock
+	// HardwareClock stamps events with the time their driver received them, on the
+	// monotonic clock of MonotonicClock: the packet timestamp on CoreMIDI, the
+	// millisecond timestamp of the message on winmm and Web MIDI, the time the sender sent
+	// it on RTP-MIDI, once the clocks of the session are synchronized, and on BLE the
+	// arrival of the packet moved back by the sender timestamps of its later messages, and
+	// the frame the message arrived at on JACK, which is sample-accurate. It
+	// excludes the delays of the driver callback and scheduling; backends without driver
+	// timestamps use the time of arrival.
+	HardwareClock
+)
+
+// String names the clock.
+func (c TimestampClock) String() string {
+	switch c {
+	case DefaultClock:
+		return "default"
+	case WallClock:
+		return "wall"
+	case MonotonicClock:
+		return "monotonic"
+	case HardwareClock:
+		return "hardware"
+	default:
+		return "TimestampClock(" + strconv.Itoa(int(c)) + ")"
+	}
+}
+
+// monotonicEpoch is the origin of the monotonic timestamps, taken when the process starts.
+var monotonicEpoch = time.Now()
+
+// MonotonicNow returns the current time on the clock of MonotonicClock and HardwareClock:
+// nanoseconds since the start of the process, never going back.
+func MonotonicNow() uint64 {
+	return uint64(time.Since(monotonicEpoch))
+}
+
+// MonotonicTime converts a timestamp of MonotonicClock or HardwareClock to a time.Time,
+// for display or comparison with wall times.
+func MonotonicTime(timestamp uint64) time.Time {
+	return monotonicEpoch.Add(time.Duration(timestamp))
+}
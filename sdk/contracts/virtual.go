@@ -0,0 +1,15 @@
+package contracts
+
+// VirtualSource is a MIDI source published by the client, which other applications, such
+// as DAWs, list among their inputs as if it were a hardware device.
+type VirtualSource interface {
+	Send(event MIDI) error // Sends a message to the applications connected to the source; its timestamp is ignored.
+	Close() error          // Removes the source; later sends fail.
+}
+
+// VirtualDestination is a MIDI destination published by the client, which other
+// applications list among their outputs. The messages they send to it are delivered to
+// the channel given when it was created.
+type VirtualDestination interface {
+	Close() error // Removes the destination; no message is delivered once it returns.
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// lsbOffset is the distance between the number of a controller MSB and of its LSB.
+const lsbOffset = 32
+
+// HighResolution is a 14-bit controller value, combined from the MSB and LSB controllers
+// of a pair such as the modulation wheel (1 and 33), or a pitch bend position.
+type HighResolution struct {
+	Timestamp      uint64 // Timestamp of the message completing the value.
+	Channel        byte   // Zero-based channel of the messages.
+	PitchBend      bool   // Whether the value is a pitch bend position rather than a controller value.
+	Controller     byte   // Number of the MSB controller, from 0 to 31; zero for pitch bend.
+	Value          int    // Value from 0 to 16383 for controllers; from -8192 to 8191, zero at the center, for pitch bend.
+	SourceDeviceID int    // ID of the device the messages came from.
+}
+
+// MSB returns the coarse 7 bits of a controller value, the value of the MSB controller.
+func (h HighResolution) MSB() byte {
+	return byte(h.Value >> 7)
+}
+
+// LSB returns the fine 7 bits of a controller value, the value of the LSB controller.
+func (h HighResolution) LSB() byte {
+	return byte(h.Value & 0x7F)
+}
+
+// controllerKey identifies a controller on the channel of a device.
+type controllerKey struct {
+	channelKey
+	controller byte
+}
+
+// Combiner combines the MSB and LSB controllers of the pairs it is configured for into
+// 14-bit values, separately for every device and channel, and reports pitch bends as
+// signed 14-bit positions. It is safe for concurrent use.
+type Combiner struct {
+	options Options
+	pairs   [lsbOffset]bool // Whether each MSB controller is combined with its LSB.
+
+	mu     sync.Mutex
+	values map[controllerKey]uint16 // Current value of every controller seen.
+}
+
+// NewCombiner creates a combiner of the controller pairs set with WithControllers, every
+// pair but data entry by default, whose values all start at zero.
+//
+// opts ...Option: Optional settings such as WithControllers and WithPairedControllers.
+//
+// Returns:
+//   - *Combiner: The combiner, ready to combine events.
+func NewCombiner(opts ...Option) *Combiner {
+	c := &Combiner{options: applyDefaultOptions(opts...), values: make(map[controllerKey]uint16)}
+	for msb := range c.pairs {
+		c.pairs[msb] = msb != ccDataMSB
+	}
+	if c.options.Controllers != nil {
+		for msb := range c.pairs {
+			c.pairs[msb] = slices.Contains(c.options.Controllers, byte(msb))
+		}
+	}
+	return c
+}
+
+// Combine follows event and reports the 14-bit value it sets, if any: the value of a
+// combined pair when its MSB or LSB controller changes, or the position of a pitch bend.
+// An MSB resets the LSB of its pair to zero, as the MIDI specification requires, so the
+// value of controllers sent without LSB is the MSB shifted by 7 bits. Other events are
+// ignored.
+func (c *Combiner) Combine(event contracts.MIDI) (HighResolution, bool) {
+	result := HighResolution{
+		Timestamp:      event.Timestamp,
+		Channel:        event.Channel(),
+		SourceDeviceID: event.SourceDeviceID,
+	}
+	switch event.Type() {
+	case contracts.MessagePitchBend:
+		result.PitchBend, result.Value = true, event.PitchBend()
+		return result, true
+	case contracts.MessageControlChange:
+	default:
+		return HighResolution{}, false
+	}
+
+	controller, value := event.Controller(), uint16(event.Value()&0x7F)
+	lsb := controller >= lsbOffset && controller < 2*lsbOffset
+	if lsb {
+		controller -= lsbOffset
+	}
+	if controller >= lsbOffset || !c.pairs[controller] {
+		return HighResolution{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := controllerKey{channelKey: channelKey{device: event.SourceDeviceID, channel: result.Channel}, controller: controller}
+	if lsb {
+		value = c.values[key]&^0x7F | value
+	} else {
+		value <<= 7
+	}
+	c.values[key] = value
+	if !lsb && c.options.PairedControllers {
+		return HighResolution{}, false
+	}
+	result.Controller, result.Value = controller, int(value)
+	return result, true
+}
+
+// Reset sets the values of every controller back to zero.
+func (c *Combiner) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.values)
+}
@@ -0,0 +1,48 @@
+package controller
+
+// Options holds the configuration of a Decoder and a Combiner.
+type Options struct {
+	PairedDataEntry   bool   // Whether a Decoder reports parameter values on the data entry LSB only.
+	Controllers       []byte // MSB controllers a Combiner combines with their LSB; nil for every pair but data entry.
+	PairedControllers bool   // Whether a Combiner reports controller values on their LSB only.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithPairedDataEntry reports parameter values only when the data entry LSB, controller 38,
+// arrives, for devices that always send it after the MSB, controller 6. Every value is
+// then reported once, with its full resolution, instead of once for each controller.
+func WithPairedDataEntry() Option {
+	return func(opts *Options) {
+		opts.PairedDataEntry = true
+	}
+}
+
+// WithControllers sets the controller pairs a Combiner combines, by the number of their
+// MSB controller, from 0 to 31, e.g. 1 for the modulation wheel, paired with 33. Other
+// controllers are ignored. By default every pair is combined but data entry, 6 and 38,
+// which a Decoder follows.
+func WithControllers(msb ...byte) Option {
+	return func(opts *Options) {
+		opts.Controllers = append([]byte{}, msb...)
+	}
+}
+
+// WithPairedControllers makes a Combiner report controller values only when their LSB
+// arrives, for devices that always send it after the MSB. Every value is then reported
+// once, with its full resolution, instead of once for each controller.
+func WithPairedControllers() Option {
+	return func(opts *Options) {
+		opts.PairedControllers = true
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
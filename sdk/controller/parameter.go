@@ -0,0 +1,222 @@
+// Package controller decodes controller data that MIDI 1.0 spreads over several control
+// changes into single high-resolution events. Registered and non-registered parameters
+// (RPN and NRPN) are selected with controllers 101 and 100, or 99 and 98, and set with the
+// data entry controllers 6 and 38 or stepped with the data increment and decrement
+// controllers 96 and 97; a Decoder follows these sequences for every device and channel
+// and reports each parameter change with its 14-bit number and value, or the step of an
+// increment or decrement when no data entry has set the value yet. A Combiner pairs
+// the MSB and LSB controllers 0-31 and 32-63, such as the modulation wheel 1 and 33, into
+// 14-bit values, and reports pitch bends as signed 14-bit positions:
+//
+//	decoder, combiner := controller.NewDecoder(), controller.NewCombiner(controller.WithControllers(1, 7))
+//	client.StartCaptureFunc(func(event contracts.MIDI) {
+//		if parameter, ok := decoder.Decode(event); ok {
+//			fmt.Println(parameter.Kind, parameter.Number, parameter.Value)
+//		}
+//		if value, ok := combiner.Combine(event); ok {
+//			fmt.Println(value.Controller, value.Value)
+//		}
+//	})
+package controller
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Controllers selecting and setting parameters.
+const (
+	ccDataMSB   = 6   // Data entry MSB.
+	ccDataLSB   = 38  // Data entry LSB.
+	ccIncrement = 96  // Data increment.
+	ccDecrement = 97  // Data decrement.
+	ccNRPNLSB   = 98  // Non-registered parameter number LSB.
+	ccNRPNMSB   = 99  // Non-registered parameter number MSB.
+	ccRPNLSB    = 100 // Registered parameter number LSB.
+	ccRPNMSB    = 101 // Registered parameter number MSB.
+)
+
+// MaxValue is the highest 14-bit parameter number and value.
+const MaxValue = 0x3FFF
+
+// Registered parameter numbers defined by the MIDI specifications.
+const (
+	PitchBendSensitivity uint16 = 0x0000 // Range of pitch bend, in semitones (MSB) and cents (LSB).
+	FineTuning           uint16 = 0x0001 // Tuning of the channel in cents, 0x2000 being A440.
+	CoarseTuning         uint16 = 0x0002 // Tuning of the channel in semitones (MSB), 64 being A440.
+	TuningProgram        uint16 = 0x0003 // Tuning program of the MIDI Tuning Standard.
+	TuningBank           uint16 = 0x0004 // Tuning bank of the MIDI Tuning Standard.
+	ModulationDepthRange uint16 = 0x0005 // Range of the modulation wheel.
+	MPEConfiguration     uint16 = 0x0006 // Number of channels of an MPE zone (MSB).
+	NullParameter        uint16 = 0x3FFF // Deselects the parameter, so data entry is ignored.
+)
+
+// Kind tells registered parameters from non-registered ones.
+type Kind uint8
+
+const (
+	RPN  Kind = iota + 1 // Registered parameter, defined by the MIDI specifications.
+	NRPN                 // Non-registered parameter, defined by the manufacturer of the device.
+)
+
+// String names the kind, "RPN" or "NRPN".
+func (k Kind) String() string {
+	switch k {
+	case RPN:
+		return "RPN"
+	case NRPN:
+		return "NRPN"
+	default:
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Parameter is a change of a registered or non-registered parameter.
+type Parameter struct {
+	Timestamp      uint64 // Timestamp of the control change completing the change.
+	Channel        byte   // Zero-based channel of the control changes.
+	Kind           Kind   // Whether the parameter is registered or non-registered.
+	Number         uint16 // 14-bit parameter number: the number MSB, shifted by 7 bits, and LSB.
+	Value          uint16 // 14-bit value: the data entry MSB, shifted by 7 bits, and LSB; zero unless HasValue.
+	HasValue       bool   // Whether Value holds the value of the parameter, known once data entry set it after the parameter was selected.
+	Delta          int    // Step of a data increment (1) or decrement (-1); zero for data entry.
+	SourceDeviceID int    // ID of the device the control changes came from.
+}
+
+// MSB returns the coarse 7 bits of the value, the data entry MSB.
+func (p Parameter) MSB() byte {
+	return byte(p.Value >> 7)
+}
+
+// LSB returns the fine 7 bits of the value, the data entry LSB.
+func (p Parameter) LSB() byte {
+	return byte(p.Value & 0x7F)
+}
+
+// channelKey identifies the channel of a device.
+type channelKey struct {
+	device  int
+	channel byte
+}
+
+// selection is the parameter selected on a channel and its current value.
+type selection struct {
+	kind   Kind      // Kind of the last parameter number controller; zero before any.
+	number [3]uint16 // Selected parameter number by kind; NullParameter before any.
+	value  uint16    // Current value of the selected parameter.
+	known  bool      // Whether data entry has set value since the parameter was selected.
+}
+
+// Decoder combines the control changes selecting and setting parameters into Parameter
+// events, separately for every device and channel. It is safe for concurrent use.
+type Decoder struct {
+	options Options
+
+	mu       sync.Mutex
+	channels map[channelKey]*selection
+}
+
+// NewDecoder creates a decoder without selected parameters.
+//
+// opts ...Option: Optional settings such as WithPairedDataEntry.
+//
+// Returns:
+//   - *Decoder: The decoder, ready to decode events.
+func NewDecoder(opts ...Option) *Decoder {
+	return &Decoder{options: applyDefaultOptions(opts...), channels: make(map[channelKey]*selection)}
+}
+
+// Decode follows event and reports the parameter change it completes, if any: a data
+// entry, increment or decrement controller while a parameter is selected on its channel.
+// A data entry MSB resets the LSB. Increments and decrements report their Delta, and
+// step Value only once a data entry has set it since the parameter was selected, as
+// devices do not send the value they step from. Other events are ignored, and so is data
+// entry after NullParameter is selected.
+func (d *Decoder) Decode(event contracts.MIDI) (Parameter, bool) {
+	if event.Type() != contracts.MessageControlChange {
+		return Parameter{}, false
+	}
+	value := event.Value() & 0x7F
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := channelKey{device: event.SourceDeviceID, channel: event.Channel()}
+	state, ok := d.channels[key]
+	if !ok {
+		state = &selection{number: [3]uint16{NullParameter, NullParameter, NullParameter}}
+		d.channels[key] = state
+	}
+
+	var delta int
+	switch event.Controller() {
+	case ccRPNMSB:
+		state.selectMSB(RPN, value)
+		return Parameter{}, false
+	case ccRPNLSB:
+		state.selectLSB(RPN, value)
+		return Parameter{}, false
+	case ccNRPNMSB:
+		state.selectMSB(NRPN, value)
+		return Parameter{}, false
+	case ccNRPNLSB:
+		state.selectLSB(NRPN, value)
+		return Parameter{}, false
+	case ccDataMSB:
+		state.value, state.known = uint16(value)<<7, true
+		if d.options.PairedDataEntry {
+			return Parameter{}, false
+		}
+	case ccDataLSB:
+		state.value, state.known = state.value&^0x7F|uint16(value), true
+	case ccIncrement:
+		delta = 1
+		if state.known {
+			state.value = min(state.value+1, MaxValue)
+		}
+	case ccDecrement:
+		delta = -1
+		if state.known {
+			state.value = max(state.value, 1) - 1
+		}
+	default:
+		return Parameter{}, false
+	}
+
+	if state.kind == 0 || state.number[state.kind] == NullParameter {
+		return Parameter{}, false
+	}
+	return Parameter{
+		Timestamp:      event.Timestamp,
+		Channel:        key.channel,
+		Kind:           state.kind,
+		Number:         state.number[state.kind],
+		Value:          state.value,
+		HasValue:       state.known,
+		Delta:          delta,
+		SourceDeviceID: key.device,
+	}, true
+}
+
+// Reset forgets the selected parameters and their values.
+func (d *Decoder) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	clear(d.channels)
+}
+
+// selectMSB sets the MSB of the number of the parameter of kind and selects it.
+func (s *selection) selectMSB(kind Kind, msb byte) {
+	s.kind = kind
+	s.number[kind] = uint16(msb)<<7 | s.number[kind]&0x7F
+	s.value, s.known = 0, false
+}
+
+// selectLSB sets the LSB of the number of the parameter of kind and selects it.
+func (s *selection) selectLSB(kind Kind, lsb byte) {
+	s.kind = kind
+	s.number[kind] = s.number[kind]&^0x7F | uint16(lsb)
+	s.value, s.known = 0, false
+}
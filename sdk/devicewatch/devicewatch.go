@@ -0,0 +1,166 @@
+// Package devicewatch reports MIDI devices being connected and unplugged while an
+// application runs, so long-running programs can offer a new keyboard or react to a lost
+// one without restarting.
+//
+// A Watcher lists the devices of a client periodically and compares each listing with the
+// previous one. Clients whose backend reports changes of the setup, such as CoreMIDI on
+// macOS, implement contracts.DeviceNotifier and are listed again as soon as a change is
+// reported; the others, such as winmm on Windows, are polled.
+package devicewatch
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Watcher reports the devices added to and removed from a client. Devices are identified
+// by their DeviceInfo; identical devices are counted, so plugging in a second unit of the
+// same model is reported too.
+type Watcher struct {
+	client   contracts.ClientMIDI
+	options  Options
+	mu       sync.Mutex
+	devices  []contracts.DeviceInfo // Devices of the last successful listing.
+	changed  chan struct{}          // Signalled by the client's change notifications.
+	stopFunc func()                 // Stops the change notifications; nil when polling only.
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// New starts watching the devices of client. The devices connected when it starts are
+// taken as the baseline and are not reported.
+//
+// client contracts.ClientMIDI: The client whose devices are watched.
+// opts ...Option: A variadic list of option functions to customize the watcher.
+//
+// Returns:
+//   - *Watcher: The running watcher.
+//   - error: An error if the devices cannot be listed.
+func New(client contracts.ClientMIDI, opts ...Option) (*Watcher, error) {
+	devices, err := list(client)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		client:  client,
+		options: applyDefaultOptions(opts...),
+		devices: devices,
+		changed: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if notifier, ok := client.(contracts.DeviceNotifier); ok {
+		w.stopFunc = notifier.NotifyDeviceChanges(w.notify)
+	}
+	w.wg.Add(1)
+	profiling.Go(profiling.RoleScheduler, "devicewatch", "", w.watch)
+	return w, nil
+}
+
+// Devices returns the devices of the last listing.
+func (w *Watcher) Devices() []contracts.DeviceInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.devices)
+}
+
+// Stop stops watching and waits for running callbacks to return. It must not be called
+// from a callback.
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		if w.stopFunc != nil {
+			w.stopFunc()
+		}
+		close(w.done)
+		w.wg.Wait()
+	})
+}
+
+// notify records a change reported by the client, without blocking its thread.
+func (w *Watcher) notify() {
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+// watch lists the devices on every tick and change notification until Stop.
+func (w *Watcher) watch() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		case <-w.changed:
+		}
+		w.check()
+	}
+}
+
+// check lists the devices and reports the differences with the previous listing.
+// Listings that fail are skipped, so a transient error does not report every device as
+// removed.
+func (w *Watcher) check() {
+	devices, err := list(w.client)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	added, removed := diff(w.devices, devices)
+	w.devices = devices
+	w.mu.Unlock()
+
+	for _, device := range removed {
+		if w.options.OnDeviceRemoved != nil {
+			w.options.OnDeviceRemoved(device)
+		}
+	}
+	for _, device := range added {
+		if w.options.OnDeviceAdded != nil {
+			w.options.OnDeviceAdded(device)
+		}
+	}
+}
+
+// list lists the devices of client, reporting no devices as an empty list.
+func list(client contracts.ClientMIDI) ([]contracts.DeviceInfo, error) {
+	devices, err := client.ListDevices()
+	if errors.Is(err, contracts.ErrNoDevices) {
+		return nil, nil
+	}
+	return devices, err
+}
+
+// diff returns the devices of next missing from previous and those of previous missing
+// from next, counting identical devices.
+func diff(previous, next []contracts.DeviceInfo) (added, removed []contracts.DeviceInfo) {
+	counts := make(map[contracts.DeviceInfo]int, len(previous))
+	for _, device := range previous {
+		counts[device]++
+	}
+	for _, device := range next {
+		if counts[device] > 0 {
+			counts[device]--
+			continue
+		}
+		added = append(added, device)
+	}
+	for _, device := range previous {
+		if counts[device] > 0 {
+			counts[device]--
+			removed = append(removed, device)
+		}
+	}
+	return added, removed
+}
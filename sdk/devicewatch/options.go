@@ -0,0 +1,55 @@
+package devicewatch
+
+import (
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// DefaultInterval is how often devices are listed unless WithInterval is given.
+const DefaultInterval = time.Second
+
+// Options holds the configuration of a Watcher.
+type Options struct {
+	Interval        time.Duration                     // How often the devices are listed.
+	OnDeviceAdded   func(device contracts.DeviceInfo) // Called for every device that appeared.
+	OnDeviceRemoved func(device contracts.DeviceInfo) // Called for every device that went away.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithInterval sets how often the devices are listed. Clients reporting changes
+// themselves, such as CoreMIDI ones, are also listed as soon as a change is reported.
+func WithInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Interval = interval
+	}
+}
+
+// WithOnDeviceAdded calls fn for every device that appears while watching.
+func WithOnDeviceAdded(fn func(device contracts.DeviceInfo)) Option {
+	return func(opts *Options) {
+		opts.OnDeviceAdded = fn
+	}
+}
+
+// WithOnDeviceRemoved calls fn for every device that goes away while watching.
+func WithOnDeviceRemoved(fn func(device contracts.DeviceInfo)) Option {
+	return func(opts *Options) {
+		opts.OnDeviceRemoved = fn
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Interval <= 0 {
+		options.Interval = DefaultInterval
+	}
+	return options
+}
@@ -0,0 +1,126 @@
+// Package discovery finds network MIDI endpoints on the local network using mDNS/Bonjour:
+// AppleMIDI (RTP-MIDI) sessions announced by macOS, iOS and rtpMIDI on Windows, and MIDI
+// servers of this package announced by sdk/remote.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/grandcat/zeroconf"
+)
+
+// Service types that can be browsed.
+const (
+	AppleMIDIService = "_apple-midi._udp" // AppleMIDI / RTP-MIDI sessions.
+	RemoteService    = "_gomidi._tcp"     // gRPC servers of this package (see sdk/remote).
+)
+
+// domain is the mDNS domain all services are browsed and announced in.
+const domain = "local."
+
+// Endpoint is a network MIDI endpoint found on the local network.
+type Endpoint struct {
+	Instance string   // Human-readable instance name announced by the endpoint.
+	Service  string   // Service type, e.g. AppleMIDIService.
+	Host     string   // Host name of the machine announcing the endpoint.
+	Port     int      // Port the endpoint listens on.
+	Addrs    []net.IP // Addresses of the host, IPv4 first.
+	Text     []string // TXT record entries.
+}
+
+// Address returns a dialable host:port for the endpoint, preferring a resolved IP address.
+func (e Endpoint) Address() string {
+	host := e.Host
+	if len(e.Addrs) > 0 {
+		host = e.Addrs[0].String()
+	}
+	return net.JoinHostPort(host, strconv.Itoa(e.Port))
+}
+
+// Browse collects the endpoints of the given service types announced on the local
+// network until ctx is done. Use a context with a timeout to bound the search; one or
+// two seconds is usually enough on a LAN.
+//
+// ctx context.Context: Bounds the duration of the search.
+// services ...string: Service types to browse; AppleMIDIService and RemoteService when empty.
+//
+// Returns:
+//   - []Endpoint: The endpoints found, in order of discovery.
+//   - error: An error if mDNS could not be queried.
+func Browse(ctx context.Context, services ...string) ([]Endpoint, error) {
+	if len(services) == 0 {
+		services = []string{AppleMIDIService, RemoteService}
+	}
+
+	var (
+		mu        sync.Mutex
+		endpoints []Endpoint
+		wg        sync.WaitGroup
+	)
+	for _, service := range services {
+		// Each browse needs its own resolver, since a resolver's connections are closed
+		// when its browse context ends.
+		resolver, err := zeroconf.NewResolver(nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating mDNS resolver: %w", err)
+		}
+
+		entries := make(chan *zeroconf.ServiceEntry)
+		if err := resolver.Browse(ctx, service, domain, entries); err != nil {
+			return nil, fmt.Errorf("error browsing %s: %w", service, err)
+		}
+
+		wg.Add(1)
+		go func(service string) {
+			defer wg.Done()
+			for entry := range entries {
+				mu.Lock()
+				endpoints = append(endpoints, Endpoint{
+					Instance: entry.Instance,
+					Service:  service,
+					Host:     entry.HostName,
+					Port:     entry.Port,
+					Addrs:    append(append([]net.IP{}, entry.AddrIPv4...), entry.AddrIPv6...),
+					Text:     entry.Text,
+				})
+				mu.Unlock()
+			}
+		}(service)
+	}
+
+	wg.Wait()
+	return endpoints, nil
+}
+
+// Advertisement is an mDNS announcement of a local service.
+type Advertisement struct {
+	server *zeroconf.Server
+}
+
+// Advertise announces a service on the local network until the returned advertisement is closed.
+//
+// instance string: Human-readable instance name, e.g. the host or studio name.
+// service string: Service type, usually RemoteService.
+// port int: Port the service listens on.
+// text ...string: Optional TXT record entries.
+//
+// Returns:
+//   - *Advertisement: The running announcement.
+//   - error: An error if the announcement could not be registered.
+func Advertise(instance, service string, port int, text ...string) (*Advertisement, error) {
+	server, err := zeroconf.Register(instance, service, domain, port, text, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error announcing %s: %w", service, err)
+	}
+	return &Advertisement{server: server}, nil
+}
+
+// Close withdraws the announcement.
+func (a *Advertisement) Close() error {
+	a.server.Shutdown()
+	return nil
+}
@@ -0,0 +1,138 @@
+// Package doctor checks a MIDI setup for common problems — no backend for the platform,
+// no devices found, devices held by another application, driver errors and missing
+// permissions — and suggests steps to fix each of them. It backs the `midi doctor`
+// command and can be embedded in applications to help their users before they ask for
+// support.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/midi"
+)
+
+// Run diagnoses the MIDI setup: it creates a client, lists the devices, queries their
+// capabilities and, unless WithoutOpeningDevices is given, opens each of them in turn.
+// Checks that depend on a failed one are skipped.
+//
+// opts ...Option: A variadic list of option functions to customize the diagnosis.
+//
+// Returns:
+//   - Report: The outcome of every check that ran.
+func Run(opts ...Option) Report {
+	options := applyDefaultOptions(opts...)
+
+	var clientOptions contracts.ClientOptions
+	for _, opt := range options.ClientOptions {
+		opt(&clientOptions)
+	}
+	d := &doctor{
+		options: options,
+		backend: clientOptions.Backend,
+		report:  Report{OS: runtime.GOOS, Backend: clientOptions.Backend},
+	}
+	d.run()
+	return d.report
+}
+
+// doctor runs the checks of one diagnosis.
+type doctor struct {
+	options Options
+	backend string
+	report  Report
+}
+
+// run runs the checks in order, stopping at the first one that leaves nothing to check.
+func (d *doctor) run() {
+	client, err := d.newClient()
+	if err != nil {
+		d.problem("backend", "the backend could not be initialized", err)
+		return
+	}
+	defer client.Stop()
+	d.ok("backend", "initialized")
+
+	devices, err := client.ListDevices()
+	if err == nil && len(devices) == 0 {
+		err = contracts.ErrNoDevices
+	}
+	if err != nil {
+		d.problem("devices", "no devices could be listed", err)
+		return
+	}
+	d.report.Devices = devices
+	d.ok("devices", fmt.Sprintf("%d found", len(devices)))
+
+	for id, device := range devices {
+		check := fmt.Sprintf("device %d (%s)", id, device.Name)
+		if _, err := client.DeviceCapabilities(id); err != nil {
+			d.add(d.diagnose(check, StatusWarning, "capabilities could not be queried", err))
+		}
+		if !d.options.OpenDevices {
+			continue
+		}
+		if err := d.open(id); err != nil {
+			d.problem(check, "the device could not be opened", err)
+			continue
+		}
+		d.ok(check, "opened")
+	}
+}
+
+// newClient creates a client with the options of the diagnosis and logging disabled.
+func (d *doctor) newClient() (contracts.ClientMIDI, error) {
+	opts := append(append([]contracts.Option(nil), d.options.ClientOptions...), contracts.WithoutLogging())
+	return midi.NewMIDIClient(opts...)
+}
+
+// open opens the device with the given ID with a client of its own, and closes it.
+func (d *doctor) open(id int) error {
+	client, err := d.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Stop()
+	return client.SelectDevice(id)
+}
+
+// ok records a passed check.
+func (d *doctor) ok(check, summary string) {
+	d.add(Finding{Check: check, Status: StatusOK, Summary: summary})
+}
+
+// problem records a failed check, with the remediation steps for err.
+func (d *doctor) problem(check, summary string, err error) {
+	d.add(d.diagnose(check, StatusProblem, summary, err))
+}
+
+// add records a finding.
+func (d *doctor) add(finding Finding) {
+	d.report.Findings = append(d.report.Findings, finding)
+}
+
+// diagnose builds the finding of a check that failed with err, refining the summary and
+// adding remediation steps when the cause is recognized.
+func (d *doctor) diagnose(check string, status Status, summary string, err error) Finding {
+	finding := Finding{Check: check, Status: status, Summary: summary, Err: err}
+	for _, cause := range causes {
+		if errors.Is(err, cause.err) {
+			finding.Summary = summary + ": " + cause.summary
+			finding.Remediation = cause.remediation(d.platform())
+			return finding
+		}
+	}
+	finding.Remediation = driverRemediation(d.platform())
+	return finding
+}
+
+// platform returns what remediation steps depend on: the backend, or the OS for the
+// native backend.
+func (d *doctor) platform() string {
+	if d.backend == "" {
+		return runtime.GOOS
+	}
+	return d.backend
+}
@@ -0,0 +1,37 @@
+package doctor
+
+import "github.com/leandrodaf/midi/sdk/contracts"
+
+// Options holds the configuration of a diagnosis.
+type Options struct {
+	ClientOptions []contracts.Option // Options of the clients under diagnosis, e.g. the backend.
+	OpenDevices   bool               // Whether each device is opened to detect busy and unusable devices.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithClientOptions sets the options of the clients under diagnosis, such as
+// contracts.WithBackend. The clients never log, whatever the options.
+func WithClientOptions(opts ...contracts.Option) Option {
+	return func(options *Options) {
+		options.ClientOptions = append(options.ClientOptions, opts...)
+	}
+}
+
+// WithoutOpeningDevices only lists the devices. Opening a device briefly may interrupt
+// an application capturing it on platforms where only one application can open a port.
+func WithoutOpeningDevices() Option {
+	return func(options *Options) {
+		options.OpenDevices = false
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{OpenDevices: true}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
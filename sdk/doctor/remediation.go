@@ -0,0 +1,295 @@
+package doctor
+
+import (
+	"io/fs"
+
+	"github.com/leandrodaf/midi/internal/midi/midible"
+	"github.com/leandrodaf/midi/internal/midi/mididarwin"
+	"github.com/leandrodaf/midi/internal/midi/midijack"
+	"github.com/leandrodaf/midi/internal/midi/midiusb"
+	"github.com/leandrodaf/midi/internal/midi/midiweb"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/midi"
+	"github.com/leandrodaf/midi/sdk/remote"
+)
+
+// cause is a recognized reason for a failed check.
+type cause struct {
+	err         error                          // Error the backends wrap for this cause.
+	summary     string                         // Explanation appended to the summary of the check.
+	remediation func(platform string) []string // Steps to fix it on a platform, as returned by doctor.platform.
+}
+
+// causes lists the recognized causes, most specific first.
+var causes = []cause{
+	{midi.ErrUnsupportedOS, "there is no native backend for this operating system", unsupportedOSRemediation},
+	{midi.ErrHardwareExcluded, "this build excludes the backends accessing MIDI hardware", hardwareExcludedRemediation},
+	{midi.ErrUnknownBackend, "the backend does not exist", unknownBackendRemediation},
+	{midi.ErrInvalidOptions, "the client options are invalid", invalidOptionsRemediation},
+	{mididarwin.ErrCoreMIDIUnsupported, "this build has no CoreMIDI support", coreMIDIUnsupportedRemediation},
+	{midiusb.ErrUSBUnsupported, "this build has no USB support", usbUnsupportedRemediation},
+	{midible.ErrBLEUnsupported, "this build has no Bluetooth LE support", bleUnsupportedRemediation},
+	{midijack.ErrJACKUnsupported, "this build has no JACK support", jackUnsupportedRemediation},
+	{midijack.ErrServerUnavailable, "no JACK server is running", jackServerRemediation},
+	{midiweb.ErrWebMIDIUnavailable, "the browser does not provide the Web MIDI API", noDevicesRemediation},
+	{contracts.ErrNoDevices, "no devices were found", noDevicesRemediation},
+	{contracts.ErrDeviceBusy, "it is in use by another application", busyRemediation},
+	{contracts.ErrDeviceDisconnected, "it is disconnected, or its driver or server is missing", disconnectedRemediation},
+	{contracts.ErrInvalidDevice, "it does not exist or disappeared after being listed", invalidDeviceRemediation},
+	{contracts.ErrDeviceNotFound, "it disappeared after being listed", invalidDeviceRemediation},
+	{remote.ErrUnauthenticated, "the remote server rejected the token", unauthenticatedRemediation},
+	{fs.ErrPermission, "access was denied", permissionRemediation},
+	{contracts.ErrDriverFailure, "the driver or MIDI service failed", driverFailureRemediation},
+}
+
+// unsupportedOSRemediation suggests the backends that work without a native one.
+func unsupportedOSRemediation(string) []string {
+	return []string{
+		"Native capture is available on macOS, Windows and in browsers (js/wasm) only. Elsewhere, select a backend with -backend (contracts.WithBackend).",
+		"For UARTs wired to DIN jacks and USB-serial adapters, use the serial backend.",
+		"For USB MIDI class devices, use the usb backend, built with -tags usb (requires cgo and libusb).",
+		"For Bluetooth LE MIDI peripherals, use the ble backend, built with -tags ble.",
+		"On Linux desktops and audio workstations, use the jack backend, built with -tags jack (requires cgo and the JACK or PipeWire JACK library).",
+		"To use a device attached to a macOS or Windows machine, share it with the remote backend.",
+	}
+}
+
+// hardwareExcludedRemediation explains how to build with the hardware backends.
+func hardwareExcludedRemediation(string) []string {
+	return []string{
+		"Rebuild without the nomidihw tag to include the native, serial, USB, BLE and JACK backends.",
+		"To keep a cgo-free build, share the devices from another machine and use the remote backend.",
+	}
+}
+
+// invalidOptionsRemediation points to the problems listed in the error.
+func invalidOptionsRemediation(string) []string {
+	return []string{
+		"Fix each problem listed in the error; they name the options involved.",
+		"Options for a backend, such as WithSerialConfig, only apply together with the matching WithBackend.",
+	}
+}
+
+// unknownBackendRemediation lists the backends.
+func unknownBackendRemediation(string) []string {
+	return []string{
+		"Use one of the backends " + contracts.BackendRemote + ", " + contracts.BackendLoopback + ", " +
+			contracts.BackendSerial + ", " + contracts.BackendUSB + ", " + contracts.BackendReplay + ", " +
+			contracts.BackendRTP + ", " + contracts.BackendBLE + " or " + contracts.BackendJACK +
+			", or none for the native backend of the operating system.",
+	}
+}
+
+// coreMIDIUnsupportedRemediation explains how to build with CoreMIDI support.
+func coreMIDIUnsupportedRemediation(string) []string {
+	return []string{
+		"Install the Xcode command line tools, which provide the C compiler cgo needs: xcode-select --install.",
+		"Build with cgo enabled: CGO_ENABLED=1 go build. Cross-compiled builds have cgo disabled by default.",
+	}
+}
+
+// usbUnsupportedRemediation explains how to build with USB support.
+func usbUnsupportedRemediation(string) []string {
+	return []string{
+		"Install libusb: libusb-1.0-0-dev on Debian and Ubuntu, libusb1-devel on Fedora, or brew install libusb on macOS.",
+		"Build with cgo enabled and the usb tag: CGO_ENABLED=1 go build -tags usb.",
+	}
+}
+
+// bleUnsupportedRemediation explains how to build with Bluetooth LE support.
+func bleUnsupportedRemediation(string) []string {
+	return []string{
+		"Build with the ble tag: go build -tags ble. On macOS, cgo must be enabled as well.",
+		"On Linux, the backend talks to BlueZ over D-Bus; check that the bluetooth service is running.",
+	}
+}
+
+// jackUnsupportedRemediation explains how to build with JACK support.
+func jackUnsupportedRemediation(string) []string {
+	return []string{
+		"Install the JACK development files: libjack-jackd2-dev on Debian and Ubuntu, or pipewire-jack-audio-connection-kit-devel on Fedora.",
+		"Build on Linux with cgo enabled and the jack tag: CGO_ENABLED=1 go build -tags jack.",
+	}
+}
+
+// jackServerRemediation explains how to start a JACK server.
+func jackServerRemediation(string) []string {
+	return []string{
+		"Start a JACK server with jackd or QjackCtl, or run PipeWire with its JACK support (pipewire-jack), which serves JACK clients without jackd.",
+		"If the server has a name other than default, set it with contracts.WithJACKConfig.",
+	}
+}
+
+// noDevicesRemediation suggests where to look for missing devices.
+func noDevicesRemediation(platform string) []string {
+	steps := []string{
+		"Check that the device is powered on and connected; try another cable, another port, or a powered hub.",
+	}
+	switch platform {
+	case "darwin":
+		steps = append(steps,
+			"Open Audio MIDI Setup, choose Window > Show MIDI Studio and check that the device appears and is not greyed out; click Rescan MIDI.",
+			"Bluetooth devices must be connected from the Bluetooth configuration of MIDI Studio first.",
+			"If the application runs sandboxed or from a new terminal, allow it under System Settings > Privacy & Security (Bluetooth, and Local Network for network sessions).")
+	case "windows":
+		steps = append(steps,
+			"Open Device Manager and check that the device is listed under Sound, video and game controllers without a warning sign.",
+			"Devices that are not class compliant need the manufacturer's driver; install it and reconnect the device.")
+	case "js":
+		steps = append(steps,
+			"Check that the browser supports Web MIDI (Chrome, Edge, Firefox and Opera do; Safari does not) and lists the device.",
+			"Devices connected after the page loaded are listed once the browser reports them; list the devices again.")
+	case contracts.BackendSerial:
+		steps = append(steps,
+			"Check that the port exists: /dev/ttyUSB*, /dev/ttyACM* or /dev/serial0 on Linux, /dev/cu.* on macOS, COM ports on Windows.",
+			"On a Raspberry Pi, enable the serial port and disable the serial console with raspi-config.",
+			"List the ports explicitly with contracts.WithSerialConfig if they are not detected.")
+	case contracts.BackendUSB:
+		steps = append(steps,
+			"Check that the device is a USB MIDI class device: lsusb -v lists an Audio interface with a MIDI Streaming subclass.",
+			"Devices with vendor-specific protocols need their own driver and are not listed.")
+	case contracts.BackendBLE:
+		steps = append(steps,
+			"Wake the peripheral and check that it is not connected to another computer or phone; most accept a single connection.",
+			"Check that Bluetooth is turned on, and raise the scan time with contracts.WithBLEConfig for peripherals that advertise slowly.")
+	case contracts.BackendJACK:
+		steps = append(steps,
+			"List the MIDI ports of the graph with jack_lsp -t; ports of other applications appear once they are running.",
+			"Hardware ports of ALSA appear in the graph only when bridged, with a2jmidid -e or the MIDI driver of jackd (-X seq); PipeWire bridges them itself.")
+	case contracts.BackendRemote:
+		steps = append(steps,
+			"The remote server is reachable but shares no devices; run the doctor on the server machine.")
+	}
+	return steps
+}
+
+// busyRemediation suggests how to free a device held by another application.
+func busyRemediation(platform string) []string {
+	steps := []string{
+		"Close the other applications using the device: DAWs, synth editors, MIDI monitors and browser tabs using Web MIDI.",
+	}
+	switch platform {
+	case "windows":
+		steps = append(steps,
+			"Windows lets a single application open a MIDI input at a time; the application holding it must close it first.")
+	case contracts.BackendSerial:
+		steps = append(steps,
+			"Stop services holding the port, such as a serial console or ModemManager: sudo systemctl stop ModemManager.")
+	case contracts.BackendUSB:
+		steps = append(steps,
+			"The usb backend claims the device exclusively; other applications using it through the system MIDI stack must close it.")
+	}
+	return append(steps, "If no application holds it, disconnect and reconnect the device.")
+}
+
+// disconnectedRemediation suggests how to bring back a device.
+func disconnectedRemediation(platform string) []string {
+	if platform == contracts.BackendRemote {
+		return []string{
+			"Check that the remote server is running and reachable at the configured address (-address, contracts.WithRemoteConfig).",
+			"Check firewalls between the two machines.",
+		}
+	}
+	steps := []string{
+		"Reconnect the device and check the cable and hub.",
+	}
+	if platform == "windows" {
+		steps = append(steps,
+			"If the device stays listed, its driver may be missing: reinstall it from the manufacturer or from Device Manager.")
+	}
+	return steps
+}
+
+// invalidDeviceRemediation suggests retrying when devices change during the diagnosis.
+func invalidDeviceRemediation(platform string) []string {
+	if platform == contracts.BackendSerial {
+		return []string{
+			"Check the name of the port given with -ports (contracts.WithSerialConfig), and that its adapter is connected.",
+		}
+	}
+	return []string{
+		"Devices changed during the diagnosis; check that the device stays connected and run the doctor again.",
+	}
+}
+
+// driverFailureRemediation suggests restarting the driver or MIDI service that failed.
+func driverFailureRemediation(platform string) []string {
+	switch platform {
+	case "darwin":
+		return []string{
+			"Open Audio MIDI Setup to restart the MIDI server, or restart the machine.",
+			"The code of the error is the OSStatus returned by CoreMIDI.",
+		}
+	case "windows":
+		return []string{
+			"Reconnect the device, or reinstall its driver from the manufacturer or from Device Manager.",
+			"The code of the error is the MMRESULT returned by winmm.",
+		}
+	default:
+		return []string{
+			"Reconnect the device and restart the application; check the logs of the backend for the cause.",
+		}
+	}
+}
+
+// permissionRemediation explains how to grant access to devices.
+func permissionRemediation(platform string) []string {
+	switch platform {
+	case "darwin":
+		return []string{
+			"Allow the application, or the terminal running it, under System Settings > Privacy & Security, then restart it.",
+		}
+	case "js":
+		return []string{
+			"Allow MIDI devices in the site permissions of the browser, then reload the page.",
+			"Web MIDI is only available to pages served over HTTPS or from localhost.",
+		}
+	case contracts.BackendSerial:
+		return []string{
+			"On Linux, add your user to the group owning the port, usually dialout: sudo usermod -aG dialout $USER, then log in again.",
+		}
+	case contracts.BackendUSB:
+		return []string{
+			"On Linux, grant access with a udev rule such as SUBSYSTEM==\"usb\", ATTR{idVendor}==\"<vendor id>\", MODE=\"0660\", GROUP=\"plugdev\" in /etc/udev/rules.d, then reconnect the device.",
+			"On Windows, the device needs the WinUSB driver (installable with Zadig) instead of the class driver.",
+		}
+	default:
+		return []string{
+			"Run the application as a user allowed to access the device.",
+		}
+	}
+}
+
+// unauthenticatedRemediation explains how to authenticate with a remote server.
+func unauthenticatedRemediation(string) []string {
+	return []string{
+		"Set the token accepted by the server with -token (contracts.RemoteConfig.Token).",
+		"If the server rotated its tokens, get the current one from its operator.",
+	}
+}
+
+// driverRemediation suggests how to recover from unrecognized errors, which usually come
+// from the driver or the MIDI service of the system.
+func driverRemediation(platform string) []string {
+	switch platform {
+	case "darwin":
+		return []string{
+			"Restart the MIDI service: click Rescan MIDI in the MIDI Studio window of Audio MIDI Setup.",
+			"If the error persists, restart Core Audio with sudo killall coreaudiod, or restart the computer.",
+		}
+	case "windows":
+		return []string{
+			"Open Device Manager and check the device for driver errors; update or reinstall its driver.",
+			"Disconnect the device, restart the computer and reconnect it.",
+		}
+	case contracts.BackendRemote:
+		return []string{
+			"Check that the remote server is running and reachable at the configured address (-address, contracts.WithRemoteConfig).",
+			"Check firewalls between the two machines.",
+		}
+	default:
+		return []string{
+			"Disconnect and reconnect the device, then run the doctor again.",
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package doctor
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Status is the outcome of a check.
+type Status int
+
+const (
+	// StatusOK means the check passed.
+	StatusOK Status = iota
+	// StatusWarning means the check found something that may cause problems.
+	StatusWarning
+	// StatusProblem means the check found something that prevents capturing.
+	StatusProblem
+)
+
+// String returns the label of the status in reports.
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusWarning:
+		return "warning"
+	case StatusProblem:
+		return "problem"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Finding is the outcome of one check.
+type Finding struct {
+	Check       string   // What was checked: "backend", "devices" or a device.
+	Status      Status   // Outcome of the check.
+	Summary     string   // What was found.
+	Err         error    // Error behind a warning or problem; nil if none.
+	Remediation []string // Steps that may fix a warning or problem, in the order to try them.
+}
+
+// Report is the outcome of a diagnosis.
+type Report struct {
+	OS       string                 // Operating system the diagnosis ran on.
+	Backend  string                 // Backend diagnosed; "" for the native backend of the OS.
+	Devices  []contracts.DeviceInfo // Devices listed by the backend.
+	Findings []Finding              // Outcomes of the checks, in the order they ran.
+}
+
+// Healthy reports whether no check found a problem. Warnings do not count.
+func (r Report) Healthy() bool {
+	for _, finding := range r.Findings {
+		if finding.Status == StatusProblem {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTo writes the report as plain text: one line per check, followed by its error
+// and numbered remediation steps.
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+
+	backend := r.Backend
+	if backend == "" {
+		backend = "native"
+	}
+	fmt.Fprintf(cw, "MIDI doctor (%s backend on %s)\n\n", backend, r.OS)
+	for _, finding := range r.Findings {
+		fmt.Fprintf(cw, "[%s] %s: %s\n", finding.Status, finding.Check, finding.Summary)
+		if finding.Err != nil {
+			fmt.Fprintf(cw, "    error: %v\n", finding.Err)
+		}
+		for i, step := range finding.Remediation {
+			fmt.Fprintf(cw, "    %d. %s\n", i+1, step)
+		}
+	}
+	if r.Healthy() {
+		fmt.Fprintf(cw, "\nNo problems found.\n")
+	}
+	return cw.n, cw.err
+}
+
+// countingWriter counts the bytes written and remembers the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// Write writes p unless a previous write failed.
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
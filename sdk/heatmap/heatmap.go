@@ -0,0 +1,95 @@
+// Package heatmap accumulates how often each note is played and how hard over a session,
+// for "which keys do I play most" visualizations and for estimating the wear of keys and
+// pads. Summaries can be exported as JSON or CSV.
+package heatmap
+
+import (
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// noteCount accumulates the hits of one note.
+type noteCount struct {
+	hits     uint64
+	velocity uint64 // Sum of the velocities of the hits.
+	min, max byte
+}
+
+// Heatmap accumulates the note on messages written to it. It implements sink.Sink, so it
+// can be fed with sink.Drain. It is safe for concurrent use.
+type Heatmap struct {
+	mu    sync.Mutex
+	notes [128]noteCount
+	start time.Time // Time of the first hit; zero before.
+	end   time.Time // Time of the last hit.
+}
+
+// New creates an empty heatmap.
+func New() *Heatmap {
+	return &Heatmap{}
+}
+
+// Write records a note on message with a non-zero velocity as a hit of its note, on any
+// channel. Other messages are ignored. It never fails.
+func (h *Heatmap) Write(event contracts.MIDI) error {
+	if event.Command&0xF0 != 0x90 || event.Velocity == 0 {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if h.start.IsZero() {
+		h.start = now
+	}
+	h.end = now
+
+	count := &h.notes[event.Note&0x7F]
+	if count.hits == 0 {
+		count.min, count.max = event.Velocity, event.Velocity
+	}
+	count.hits++
+	count.velocity += uint64(event.Velocity)
+	count.min = min(count.min, event.Velocity)
+	count.max = max(count.max, event.Velocity)
+	return nil
+}
+
+// Close does nothing; the heatmap can still be summarized and fed after it.
+func (h *Heatmap) Close() error {
+	return nil
+}
+
+// Reset discards every hit, starting a new session.
+func (h *Heatmap) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	*h = Heatmap{}
+}
+
+// Summary returns the hits accumulated so far.
+func (h *Heatmap) Summary() Summary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	summary := Summary{Start: h.start, End: h.end}
+	for note, count := range h.notes {
+		if count.hits == 0 {
+			continue
+		}
+		summary.Hits += count.hits
+		summary.Notes = append(summary.Notes, NoteStats{
+			Note:         byte(note),
+			Name:         NoteName(byte(note)),
+			Hits:         count.hits,
+			MeanVelocity: float64(count.velocity) / float64(count.hits),
+			MinVelocity:  count.min,
+			MaxVelocity:  count.max,
+		})
+	}
+	return summary
+}
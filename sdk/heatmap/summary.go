@@ -0,0 +1,84 @@
+package heatmap
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// noteNames are the names of the notes within an octave.
+var noteNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// NoteName returns the name of a note with its octave, middle C (60) being C4.
+func NoteName(note byte) string {
+	return noteNames[note%12] + strconv.Itoa(int(note)/12-1)
+}
+
+// NoteStats describes the hits of one note.
+type NoteStats struct {
+	Note         byte    `json:"note"`          // Note number, 0 to 127.
+	Name         string  `json:"name"`          // Name of the note, as returned by NoteName.
+	Hits         uint64  `json:"hits"`          // Note on messages with a non-zero velocity.
+	MeanVelocity float64 `json:"mean_velocity"` // Average velocity of the hits.
+	MinVelocity  byte    `json:"min_velocity"`  // Softest hit.
+	MaxVelocity  byte    `json:"max_velocity"`  // Hardest hit.
+}
+
+// Summary describes the hits of a session.
+type Summary struct {
+	Start time.Time   `json:"start"` // Time of the first hit; zero if there were none.
+	End   time.Time   `json:"end"`   // Time of the last hit.
+	Hits  uint64      `json:"hits"`  // Hits of all notes.
+	Notes []NoteStats `json:"notes"` // Notes hit at least once, by note number.
+}
+
+// Note returns the statistics of note, which are zero if it was not hit.
+func (s Summary) Note(note byte) NoteStats {
+	i, found := slices.BinarySearchFunc(s.Notes, note, func(stats NoteStats, note byte) int {
+		return cmp.Compare(stats.Note, note)
+	})
+	if !found {
+		return NoteStats{Note: note, Name: NoteName(note)}
+	}
+	return s.Notes[i]
+}
+
+// Top returns the n most hit notes, most hit first; notes with as many hits are ordered
+// by note number.
+func (s Summary) Top(n int) []NoteStats {
+	notes := slices.Clone(s.Notes)
+	slices.SortStableFunc(notes, func(x, y NoteStats) int {
+		return cmp.Compare(y.Hits, x.Hits)
+	})
+	return notes[:min(max(n, 0), len(notes))]
+}
+
+// WriteJSON writes the summary to w as an indented JSON object.
+func (s Summary) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteCSV writes the statistics of the notes hit to w as CSV, with a header row and one
+// row per note: note, name, hits, mean_velocity, min_velocity and max_velocity.
+func (s Summary) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"note", "name", "hits", "mean_velocity", "min_velocity", "max_velocity"})
+	for _, note := range s.Notes {
+		writer.Write([]string{
+			strconv.Itoa(int(note.Note)),
+			note.Name,
+			strconv.FormatUint(note.Hits, 10),
+			strconv.FormatFloat(note.MeanVelocity, 'f', 2, 64),
+			strconv.Itoa(int(note.MinVelocity)),
+			strconv.Itoa(int(note.MaxVelocity)),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
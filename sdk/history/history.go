@@ -0,0 +1,178 @@
+// Package history keeps the most recent captured events in a bounded in-memory ring, by
+// count and optionally by age, and answers queries by time range, channel and note, so
+// tools can show what happened just before a problem without recording whole sessions.
+package history
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/sink"
+)
+
+// Entry is an event kept in the history.
+type Entry struct {
+	Time  time.Time      // Time the event was written to the history.
+	Event contracts.MIDI // The event.
+}
+
+// History keeps the most recent events written to it. It implements sink.Sink, so it can
+// be fed with sink.Drain. It is safe for concurrent use.
+type History struct {
+	mu      sync.Mutex
+	maxAge  time.Duration
+	entries []Entry // Ring of kept entries.
+	head    int     // Index of the oldest entry.
+	count   int     // Number of kept entries.
+}
+
+// New creates an empty history.
+//
+// opts ...Option: A variadic list of option functions to customize the history.
+//
+// Returns:
+//   - *History: The history, keeping DefaultSize events unless configured otherwise.
+func New(opts ...Option) *History {
+	options := applyDefaultOptions(opts...)
+	return &History{
+		maxAge:  options.MaxAge,
+		entries: make([]Entry, options.Size),
+	}
+}
+
+// Write records event with the current time, dropping the oldest event when the history
+// is full. It never fails.
+func (h *History) Write(event contracts.MIDI) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if h.count == len(h.entries) {
+		h.head = (h.head + 1) % len(h.entries)
+		h.count--
+	}
+	h.entries[(h.head+h.count)%len(h.entries)] = Entry{Time: now, Event: event}
+	h.count++
+	h.expire(now)
+	return nil
+}
+
+// Close does nothing; the history can still be queried and fed after it.
+func (h *History) Close() error {
+	return nil
+}
+
+// Len returns the number of events kept.
+func (h *History) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.expire(time.Now())
+	return h.count
+}
+
+// Clear drops every event.
+func (h *History) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clear(h.entries)
+	h.head, h.count = 0, 0
+}
+
+// Events returns every event kept, oldest first.
+func (h *History) Events() []Entry {
+	return h.Select(nil)
+}
+
+// Last returns the n most recent events, oldest first.
+func (h *History) Last(n int) []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.expire(time.Now())
+	return h.collect(max(h.count-max(n, 0), 0), h.count, nil)
+}
+
+// Since returns the events written during the last d, oldest first.
+func (h *History) Since(d time.Duration) []Entry {
+	return h.Between(time.Now().Add(-d), time.Time{})
+}
+
+// Between returns the events written from from, inclusive, to to, exclusive, oldest
+// first. A zero from or to leaves that end of the range open.
+func (h *History) Between(from, to time.Time) []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.expire(time.Now())
+	start, end := 0, h.count
+	if !from.IsZero() {
+		start = sort.Search(h.count, func(i int) bool { return !h.at(i).Time.Before(from) })
+	}
+	if !to.IsZero() {
+		end = sort.Search(h.count, func(i int) bool { return !h.at(i).Time.Before(to) })
+	}
+	return h.collect(start, max(start, end), nil)
+}
+
+// Channel returns the channel messages of the zero-based channel, oldest first.
+func (h *History) Channel(channel byte) []Entry {
+	return h.Select(func(entry Entry) bool {
+		return entry.Event.Command < 0xF0 && sink.Channel(entry.Event) == channel
+	})
+}
+
+// Note returns the note on, note off and polyphonic aftertouch messages of note on any
+// channel, oldest first.
+func (h *History) Note(note byte) []Entry {
+	return h.Select(func(entry Entry) bool {
+		switch entry.Event.Command & 0xF0 {
+		case 0x80, 0x90, 0xA0:
+			return entry.Event.Note == note
+		}
+		return false
+	})
+}
+
+// Select returns the events for which match returns true, oldest first, or every event
+// if match is nil. match is called with the history locked and must not use it.
+func (h *History) Select(match func(Entry) bool) []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.expire(time.Now())
+	return h.collect(0, h.count, match)
+}
+
+// at returns the entry at position i from the oldest. The caller must hold h.mu.
+func (h *History) at(i int) Entry {
+	return h.entries[(h.head+i)%len(h.entries)]
+}
+
+// collect returns the entries at positions start to end from the oldest for which match
+// returns true, or all of them if match is nil. The caller must hold h.mu.
+func (h *History) collect(start, end int, match func(Entry) bool) []Entry {
+	var entries []Entry
+	for i := start; i < end; i++ {
+		if entry := h.at(i); match == nil || match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// expire drops the entries older than the maximum age. The caller must hold h.mu.
+func (h *History) expire(now time.Time) {
+	if h.maxAge == 0 {
+		return
+	}
+	cutoff := now.Add(-h.maxAge)
+	for h.count > 0 && h.at(0).Time.Before(cutoff) {
+		h.entries[h.head] = Entry{}
+		h.head = (h.head + 1) % len(h.entries)
+		h.count--
+	}
+}
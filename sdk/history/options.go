@@ -0,0 +1,46 @@
+package history
+
+import "time"
+
+// DefaultSize is the number of events a History keeps unless WithSize is given.
+const DefaultSize = 4096
+
+// Options holds the configuration of a History.
+type Options struct {
+	Size   int           // Maximum number of events kept; the oldest are dropped first.
+	MaxAge time.Duration // Maximum age of the events kept; zero keeps events of any age.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithSize sets the maximum number of events kept.
+func WithSize(events int) Option {
+	return func(opts *Options) {
+		opts.Size = events
+	}
+}
+
+// WithMaxAge drops events older than age, in addition to the size limit, e.g. to keep
+// the last 30 seconds of a session.
+func WithMaxAge(age time.Duration) Option {
+	return func(opts *Options) {
+		opts.MaxAge = age
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Size <= 0 {
+		options.Size = DefaultSize
+	}
+	if options.MaxAge < 0 {
+		options.MaxAge = 0
+	}
+	return options
+}
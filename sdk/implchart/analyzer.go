@@ -0,0 +1,229 @@
+// Package implchart watches a capture session and reports which message types, channels,
+// controllers, programs and SysEx IDs a device actually transmits, effectively generating
+// the transmit side of a MIDI implementation chart for undocumented controllers.
+package implchart
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/sink"
+)
+
+// usage accumulates the count, channels and value range of one kind of message.
+type usage struct {
+	count    uint64
+	channels uint16 // Bit i is set when channel i (zero-based) transmitted.
+	values   Range
+}
+
+// observe records a message on channel with value.
+func (u *usage) observe(channel byte, value int) {
+	if u.count == 0 {
+		u.values = Range{Min: value, Max: value}
+	}
+	u.count++
+	u.channels |= 1 << channel
+	u.values.Min = min(u.values.Min, value)
+	u.values.Max = max(u.values.Max, value)
+}
+
+// sysexKey identifies a group of SysEx messages: the manufacturer ID and, for universal
+// messages, the sub-IDs.
+type sysexKey struct {
+	id    string
+	subID string
+}
+
+// Analyzer observes captured events and builds a Report. It implements sink.Sink, so it
+// can be fed with sink.Drain, and WriteSysEx can serve as a SysEx handler. It is safe for
+// concurrent use.
+type Analyzer struct {
+	mu              sync.Mutex
+	events          uint64
+	messages        map[string]*usage // By message type, as named by MessageType.
+	notes           usage             // Note numbers of note on messages.
+	velocities      usage             // Velocities of note on messages.
+	releaseVelocity bool              // Whether a note off carried a velocity other than 0 and 64.
+	controllers     [128]usage        // Values by controller number.
+	programs        [128]bool         // Programs selected.
+	pitchBend       usage             // 14-bit pitch bend values.
+	sysex           map[sysexKey]*usage
+	sysexCount      uint64
+}
+
+// New creates an analyzer with no observations.
+func New() *Analyzer {
+	return &Analyzer{
+		messages: make(map[string]*usage),
+		sysex:    make(map[sysexKey]*usage),
+	}
+}
+
+// Write records a channel or system message. It never fails.
+func (a *Analyzer) Write(event contracts.MIDI) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.events++
+	typ := MessageType(event)
+	channel := sink.Channel(event)
+	u := a.messages[typ]
+	if u == nil {
+		u = &usage{}
+		a.messages[typ] = u
+	}
+	if event.Command >= 0xF0 {
+		u.count++
+		return nil
+	}
+	u.observe(channel, 0)
+
+	switch event.Command & 0xF0 {
+	case 0x80:
+		if event.Velocity != 0 && event.Velocity != 64 {
+			a.releaseVelocity = true
+		}
+	case 0x90:
+		if event.Velocity > 0 {
+			a.notes.observe(channel, int(event.Note))
+			a.velocities.observe(channel, int(event.Velocity))
+		}
+	case 0xB0:
+		a.controllers[event.Note&0x7F].observe(channel, int(event.Velocity))
+	case 0xC0:
+		a.programs[event.Note&0x7F] = true
+	case 0xE0:
+		a.pitchBend.observe(channel, int(event.Velocity)<<7|int(event.Note))
+	}
+	return nil
+}
+
+// WriteSysEx records a SysEx message by manufacturer ID, and by sub-IDs for universal
+// messages. It has the signature of a SysEx handler for contracts.WithSysExHandler.
+func (a *Analyzer) WriteSysEx(event contracts.SysExEvent) {
+	id, subID := sysexID(event.Data)
+	if id == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.sysexCount++
+	key := sysexKey{id: string(id), subID: string(subID)}
+	u := a.sysex[key]
+	if u == nil {
+		u = &usage{}
+		a.sysex[key] = u
+	}
+	u.observe(0, len(event.Data))
+}
+
+// Close does nothing; the analyzer can still be queried and fed after it.
+func (a *Analyzer) Close() error {
+	return nil
+}
+
+// Report returns what the device transmitted so far.
+func (a *Analyzer) Report() Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	report := Report{
+		Events:          a.events,
+		SysExMessages:   a.sysexCount,
+		ReleaseVelocity: a.releaseVelocity,
+	}
+
+	var channels uint16
+	for _, typ := range messageTypes {
+		u := a.messages[typ]
+		if u == nil {
+			continue
+		}
+		report.Messages = append(report.Messages, MessageUsage{Type: typ, Count: u.count, Channels: channelList(u.channels)})
+		channels |= u.channels
+	}
+	report.Channels = channelList(channels)
+
+	report.Notes = a.notes.rangeOrNil()
+	report.Velocities = a.velocities.rangeOrNil()
+	report.PitchBend = a.pitchBend.rangeOrNil()
+	for number, u := range a.controllers {
+		if u.count > 0 {
+			report.Controllers = append(report.Controllers, ControllerUsage{
+				Number:   number,
+				Name:     ControllerName(number),
+				Count:    u.count,
+				Channels: channelList(u.channels),
+				Values:   u.values,
+			})
+		}
+	}
+	for program, used := range a.programs {
+		if used {
+			report.Programs = append(report.Programs, program)
+		}
+	}
+
+	for key, u := range a.sysex {
+		report.SysEx = append(report.SysEx, SysExUsage{
+			ID:     []byte(key.id),
+			SubID:  []byte(key.subID),
+			Name:   ManufacturerName([]byte(key.id)),
+			Count:  u.count,
+			Length: u.values,
+		})
+	}
+	slices.SortFunc(report.SysEx, func(x, y SysExUsage) int {
+		if c := slices.Compare(x.ID, y.ID); c != 0 {
+			return c
+		}
+		return slices.Compare(x.SubID, y.SubID)
+	})
+	return report
+}
+
+// rangeOrNil returns the value range, or nil if nothing was observed.
+func (u *usage) rangeOrNil() *Range {
+	if u.count == 0 {
+		return nil
+	}
+	values := u.values
+	return &values
+}
+
+// channelList returns the one-based channels set in a channel mask.
+func channelList(mask uint16) []int {
+	var channels []int
+	for channel := range 16 {
+		if mask&(1<<channel) != 0 {
+			channels = append(channels, channel+1)
+		}
+	}
+	return channels
+}
+
+// sysexID returns the manufacturer ID of a SysEx message and, for universal messages, the
+// sub-IDs following the device ID. It returns nil for messages too short to carry an ID.
+func sysexID(data []byte) (id, subID []byte) {
+	if len(data) < 2 || data[0] != 0xF0 {
+		return nil, nil
+	}
+	switch data[1] {
+	case 0x00: // Three-byte manufacturer IDs.
+		if len(data) < 4 {
+			return nil, nil
+		}
+		return data[1:4], nil
+	case 0x7E, 0x7F: // Universal non-real-time and real-time messages.
+		if len(data) < 6 {
+			return data[1:2], nil
+		}
+		return data[1:2], data[3:5]
+	default:
+		return data[1:2], nil
+	}
+}
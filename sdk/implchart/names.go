@@ -0,0 +1,62 @@
+package implchart
+
+// controllerNames holds the standard names of the defined controller numbers.
+var controllerNames = map[int]string{
+	0: "Bank Select", 1: "Modulation", 2: "Breath", 4: "Foot", 5: "Portamento Time",
+	6: "Data Entry", 7: "Volume", 8: "Balance", 10: "Pan", 11: "Expression",
+	12: "Effect Control 1", 13: "Effect Control 2",
+	16: "General Purpose 1", 17: "General Purpose 2", 18: "General Purpose 3", 19: "General Purpose 4",
+	32: "Bank Select LSB", 33: "Modulation LSB", 34: "Breath LSB", 36: "Foot LSB",
+	37: "Portamento Time LSB", 38: "Data Entry LSB", 39: "Volume LSB", 40: "Balance LSB",
+	42: "Pan LSB", 43: "Expression LSB",
+	64: "Sustain", 65: "Portamento", 66: "Sostenuto", 67: "Soft Pedal", 68: "Legato", 69: "Hold 2",
+	70: "Sound Variation", 71: "Resonance", 72: "Release Time", 73: "Attack Time", 74: "Cutoff",
+	75: "Decay Time", 76: "Vibrato Rate", 77: "Vibrato Depth", 78: "Vibrato Delay", 79: "Sound Controller 10",
+	80: "General Purpose 5", 81: "General Purpose 6", 82: "General Purpose 7", 83: "General Purpose 8",
+	84: "Portamento Control", 88: "High Resolution Velocity",
+	91: "Reverb Send", 92: "Tremolo Depth", 93: "Chorus Send", 94: "Celeste Depth", 95: "Phaser Depth",
+	96: "Data Increment", 97: "Data Decrement", 98: "NRPN LSB", 99: "NRPN MSB", 100: "RPN LSB", 101: "RPN MSB",
+	120: "All Sound Off", 121: "Reset All Controllers", 122: "Local Control", 123: "All Notes Off",
+	124: "Omni Off", 125: "Omni On", 126: "Mono On", 127: "Poly On",
+}
+
+// ControllerName returns the standard name of a controller number, or "" if it is undefined.
+func ControllerName(number int) string {
+	return controllerNames[number]
+}
+
+// manufacturerNames holds the names of common SysEx IDs, keyed by their bytes.
+var manufacturerNames = map[string]string{
+	"\x01":         "Sequential",
+	"\x04":         "Moog",
+	"\x06":         "Lexicon",
+	"\x07":         "Kurzweil",
+	"\x0F":         "Ensoniq",
+	"\x10":         "Oberheim",
+	"\x18":         "E-mu",
+	"\x3E":         "Waldorf",
+	"\x40":         "Kawai",
+	"\x41":         "Roland",
+	"\x42":         "Korg",
+	"\x43":         "Yamaha",
+	"\x44":         "Casio",
+	"\x47":         "Akai",
+	"\x7D":         "Non-commercial",
+	"\x7E":         "Universal Non-Real Time",
+	"\x7F":         "Universal Real Time",
+	"\x00\x00\x0E": "Alesis",
+	"\x00\x00\x66": "Mackie",
+	"\x00\x01\x05": "M-Audio",
+	"\x00\x20\x29": "Novation",
+	"\x00\x20\x32": "Behringer",
+	"\x00\x20\x33": "Access",
+	"\x00\x20\x3C": "Elektron",
+	"\x00\x20\x6B": "Arturia",
+	"\x00\x21\x1D": "Ableton",
+	"\x00\x21\x27": "Teenage Engineering",
+}
+
+// ManufacturerName returns the name of a SysEx manufacturer or universal ID, or "" if it is unknown.
+func ManufacturerName(id []byte) string {
+	return manufacturerNames[string(id)]
+}
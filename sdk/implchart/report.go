@@ -0,0 +1,180 @@
+package implchart
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/sink"
+)
+
+// Range is the range of values observed, inclusive.
+type Range struct {
+	Min, Max int
+}
+
+// MessageUsage describes how often a message type was transmitted and on which channels.
+type MessageUsage struct {
+	Type     string // Message type, as named by MessageType.
+	Count    uint64 // Messages observed.
+	Channels []int  // One-based channels of channel messages; empty for system messages.
+}
+
+// ControllerUsage describes a control change number the device transmitted.
+type ControllerUsage struct {
+	Number   int    // Controller number, 0 to 127.
+	Name     string // Standard name of the controller, or "" if it is undefined.
+	Count    uint64 // Messages observed.
+	Channels []int  // One-based channels the controller was transmitted on.
+	Values   Range  // Values observed.
+}
+
+// SysExUsage describes a group of SysEx messages the device transmitted.
+type SysExUsage struct {
+	ID     []byte // Manufacturer ID: one byte, or three bytes starting with 00.
+	SubID  []byte // Sub-IDs of universal messages (ID 7E or 7F); empty otherwise.
+	Name   string // Name of the manufacturer or universal message, or "" if unknown.
+	Count  uint64 // Messages observed.
+	Length Range  // Lengths observed, in bytes including F0 and F7.
+}
+
+// Report is the transmit side of a MIDI implementation chart, built from observations.
+type Report struct {
+	Events          uint64            // Channel and system messages observed.
+	SysExMessages   uint64            // SysEx messages observed.
+	Channels        []int             // One-based channels that transmitted channel messages.
+	Messages        []MessageUsage    // Message types transmitted, in status byte order.
+	Notes           *Range            // Note numbers of note on messages; nil if none.
+	Velocities      *Range            // Velocities of note on messages; nil if none.
+	ReleaseVelocity bool              // Whether note off messages carried release velocities.
+	Controllers     []ControllerUsage // Control changes, by controller number.
+	Programs        []int             // Programs selected with program changes.
+	PitchBend       *Range            // 14-bit pitch bend values, 8192 at center; nil if none.
+	SysEx           []SysExUsage      // SysEx messages, by ID.
+}
+
+// messageTypes lists the message types in status byte order.
+var messageTypes = []string{
+	"note_off", "note_on", "poly_aftertouch", "control_change", "program_change",
+	"channel_aftertouch", "pitch_bend",
+	"mtc_quarter_frame", "song_position", "song_select", "tune_request",
+	"timing_clock", "start", "continue", "stop", "active_sensing", "system_reset", "system",
+}
+
+// systemTypes names the system common and real-time messages.
+var systemTypes = map[byte]string{
+	0xF1: "mtc_quarter_frame",
+	0xF2: "song_position",
+	0xF3: "song_select",
+	0xF6: "tune_request",
+	0xF8: "timing_clock",
+	0xFA: "start",
+	0xFB: "continue",
+	0xFC: "stop",
+	0xFE: "active_sensing",
+	0xFF: "system_reset",
+}
+
+// MessageType returns the name of the message type of event: the names of
+// sink.MessageType for channel messages, and the name of each system common and
+// real-time message, or "system" for undefined ones.
+func MessageType(event contracts.MIDI) string {
+	if event.Command < 0xF0 {
+		return sink.MessageType(event)
+	}
+	if name, ok := systemTypes[event.Command]; ok {
+		return name
+	}
+	return "system"
+}
+
+// WriteTo writes the report as a plain-text implementation chart.
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	tw := tabwriter.NewWriter(cw, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "MIDI implementation (transmitted)\n")
+	fmt.Fprintf(tw, "Events:\t%d channel and system, %d SysEx\n", r.Events, r.SysExMessages)
+	fmt.Fprintf(tw, "Channels:\t%s\n", joinInts(r.Channels))
+	if r.Notes != nil {
+		fmt.Fprintf(tw, "Notes:\t%d-%d\n", r.Notes.Min, r.Notes.Max)
+	}
+	if r.Velocities != nil {
+		fmt.Fprintf(tw, "Note on velocity:\t%d-%d\n", r.Velocities.Min, r.Velocities.Max)
+		fmt.Fprintf(tw, "Note off velocity:\t%s\n", yesNo(r.ReleaseVelocity))
+	}
+	if r.PitchBend != nil {
+		fmt.Fprintf(tw, "Pitch bend:\t%d-%d\n", r.PitchBend.Min, r.PitchBend.Max)
+	}
+	if len(r.Programs) > 0 {
+		fmt.Fprintf(tw, "Programs:\t%s\n", joinInts(r.Programs))
+	}
+
+	if len(r.Messages) > 0 {
+		fmt.Fprintf(tw, "\nMessage\tCount\tChannels\n")
+		for _, m := range r.Messages {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", m.Type, m.Count, joinInts(m.Channels))
+		}
+	}
+	if len(r.Controllers) > 0 {
+		fmt.Fprintf(tw, "\nCC\tName\tCount\tValues\tChannels\n")
+		for _, c := range r.Controllers {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%d-%d\t%s\n", c.Number, c.Name, c.Count, c.Values.Min, c.Values.Max, joinInts(c.Channels))
+		}
+	}
+	if len(r.SysEx) > 0 {
+		fmt.Fprintf(tw, "\nSysEx ID\tName\tCount\tLength\n")
+		for _, s := range r.SysEx {
+			id := fmt.Sprintf("% X", s.ID)
+			if len(s.SubID) > 0 {
+				id += fmt.Sprintf(" (% X)", s.SubID)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d-%d\n", id, s.Name, s.Count, s.Length.Min, s.Length.Max)
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+// joinInts formats numbers as a comma-separated list, or "-" if there are none.
+func joinInts(values []int) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+// yesNo formats a boolean for the chart.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// countingWriter counts the bytes written and remembers the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// Write writes p unless a previous write failed.
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,244 @@
+// Package keyboard turns a computer keyboard into a MIDI controller. Keys are laid out
+// like a piano over two rows, as in music trackers, and every keypress is sent as a note
+// to a Sender such as the loopback backend, so applications can be exercised without
+// hardware. See cmd/vkeyboard for a terminal front end.
+package keyboard
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Sender receives the events played on the keyboard. The loopback backend client
+// implements it.
+type Sender interface {
+	Send(event contracts.MIDI) error
+}
+
+// Control keys besides the note keys.
+const (
+	keyOctaveDown   = '-'
+	keyOctaveUp     = '='
+	keyVelocityDown = '['
+	keyVelocityUp   = ']'
+	keyAllNotesOff  = ' '
+	keyInterrupt    = 0x03 // Ctrl+C
+	keyEndOfInput   = 0x04 // Ctrl+D
+	keyEscape       = 0x1B
+)
+
+// Octave and velocity limits of the keyboard.
+const (
+	minOctave    = -1
+	maxOctave    = 7
+	velocityStep = 10
+)
+
+// ccAllNotesOff is the control change sent by the all notes off key.
+const ccAllNotesOff = 123
+
+// layout maps keys to semitones above C of the lower row's octave: the bottom letter row
+// plays the lower octave with the sharps on the row above it, the top letter row plays the
+// next octave with the sharps on the digit row.
+var layout = map[rune]int{
+	'z': 0, 's': 1, 'x': 2, 'd': 3, 'c': 4, 'v': 5, 'g': 6, 'b': 7, 'h': 8, 'n': 9, 'j': 10, 'm': 11,
+	',': 12, 'l': 13, '.': 14, ';': 15, '/': 16,
+	'q': 12, '2': 13, 'w': 14, '3': 15, 'e': 16, 'r': 17, '5': 18, 't': 19, '6': 20, 'y': 21, '7': 22, 'u': 23,
+	'i': 24, '9': 25, 'o': 26, '0': 27, 'p': 28,
+}
+
+// noteNames are the names of the notes within an octave.
+var noteNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// Keyboard plays notes on a Sender from keypresses. It is safe for concurrent use.
+type Keyboard struct {
+	sender   Sender
+	options  Options
+	start    time.Time
+	mu       sync.Mutex
+	octave   int
+	velocity byte
+	sounding map[byte]*time.Timer // Release timers of the sounding notes.
+	last     string               // Description of the last action, shown in the status line.
+}
+
+// New creates a keyboard playing on sender.
+//
+// sender Sender: Receiver of the played events, e.g. a loopback backend client.
+// opts ...Option: A variadic list of option functions to customize the keyboard.
+//
+// Returns:
+//   - *Keyboard: The keyboard, ready to receive keypresses.
+func New(sender Sender, opts ...Option) *Keyboard {
+	options := applyDefaultOptions(opts...)
+	return &Keyboard{
+		sender:   sender,
+		options:  options,
+		start:    time.Now(),
+		octave:   options.Octave,
+		velocity: options.Velocity,
+		sounding: map[byte]*time.Timer{},
+	}
+}
+
+// Press handles a keypress: a note key plays a note that is released after the note
+// length, and the control keys change the octave or velocity or silence every note.
+// It reports whether the key was handled.
+func (k *Keyboard) Press(key rune) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if offset, ok := layout[key]; ok {
+		return true, k.play(offset)
+	}
+
+	switch key {
+	case keyOctaveDown:
+		k.octave = max(k.octave-1, minOctave)
+		k.last = fmt.Sprintf("octave %d", k.octave)
+	case keyOctaveUp:
+		k.octave = min(k.octave+1, maxOctave)
+		k.last = fmt.Sprintf("octave %d", k.octave)
+	case keyVelocityDown:
+		k.velocity = byte(max(int(k.velocity)-velocityStep, 1))
+		k.last = fmt.Sprintf("velocity %d", k.velocity)
+	case keyVelocityUp:
+		k.velocity = byte(min(int(k.velocity)+velocityStep, 127))
+		k.last = fmt.Sprintf("velocity %d", k.velocity)
+	case keyAllNotesOff:
+		k.last = "all notes off"
+		return true, k.allNotesOff()
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// Run reads keypresses from in until Escape, Ctrl+C, Ctrl+D or the end of the input,
+// drawing the key layout and a status line to out. in is expected to deliver single
+// keypresses, e.g. a terminal in raw mode. Send errors are shown in the status line.
+// Sounding notes are released before returning.
+func (k *Keyboard) Run(in io.Reader, out io.Writer) error {
+	defer k.Release()
+
+	fmt.Fprint(out, Help())
+	k.drawStatus(out)
+
+	buf := make([]byte, 1)
+	for {
+		if _, err := in.Read(buf); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("error reading keypress: %w", err)
+		}
+
+		switch buf[0] {
+		case keyEscape, keyInterrupt, keyEndOfInput:
+			fmt.Fprint(out, "\r\n")
+			return nil
+		}
+		if _, err := k.Press(rune(buf[0])); err != nil {
+			// Keep playing: the receiver may only be temporarily unavailable.
+			k.mu.Lock()
+			k.last = fmt.Sprintf("not sent: %v", err)
+			k.mu.Unlock()
+		}
+		k.drawStatus(out)
+	}
+}
+
+// Release releases every sounding note.
+func (k *Keyboard) Release() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for note, timer := range k.sounding {
+		timer.Stop()
+		delete(k.sounding, note)
+		k.send(0x80, note, 0)
+	}
+}
+
+// Help describes the key layout and the control keys.
+func Help() string {
+	lines := []string{
+		"  2 3   5 6 7   9 0       upper octave (sharps)",
+		" q w e r t y u i o p      upper octave",
+		"  s d   g h j   l ;       lower octave (sharps)",
+		" z x c v b n m , . /      lower octave",
+		"",
+		" -/= octave   [/] velocity   space all notes off   Esc quit",
+		"",
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// play sends a note on for the key at offset and schedules its release, releasing the
+// note first if it is still sounding.
+func (k *Keyboard) play(offset int) error {
+	value := (k.octave+1)*12 + offset
+	if value < 0 || value > 127 {
+		k.last = "out of range"
+		return nil
+	}
+	note := byte(value)
+
+	if timer, ok := k.sounding[note]; ok {
+		timer.Stop()
+		delete(k.sounding, note)
+		if err := k.send(0x80, note, 0); err != nil {
+			return err
+		}
+	}
+	if err := k.send(0x90, note, k.velocity); err != nil {
+		return err
+	}
+	k.last = fmt.Sprintf("%s%d", noteNames[note%12], int(note)/12-1)
+
+	var timer *time.Timer
+	timer = time.AfterFunc(k.options.NoteLength, func() {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		// A retriggered or already released note is no longer owned by this timer.
+		if k.sounding[note] == timer {
+			delete(k.sounding, note)
+			k.send(0x80, note, 0)
+		}
+	})
+	k.sounding[note] = timer
+	return nil
+}
+
+// allNotesOff cancels the pending releases and sends the all notes off controller.
+func (k *Keyboard) allNotesOff() error {
+	for note, timer := range k.sounding {
+		timer.Stop()
+		delete(k.sounding, note)
+	}
+	return k.send(0xB0, ccAllNotesOff, 0)
+}
+
+// send sends a channel message on the keyboard's channel.
+func (k *Keyboard) send(status, data1, data2 byte) error {
+	return k.sender.Send(contracts.MIDI{
+		Timestamp: uint64(time.Since(k.start).Milliseconds()),
+		Command:   status | k.options.Channel,
+		Note:      data1,
+		Velocity:  data2,
+	})
+}
+
+// drawStatus rewrites the status line with the octave, velocity, channel and last action.
+func (k *Keyboard) drawStatus(out io.Writer) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	fmt.Fprintf(out, "\r\x1b[Koctave %d  velocity %d  channel %d  %s", k.octave, k.velocity, k.options.Channel+1, k.last)
+}
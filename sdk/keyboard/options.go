@@ -0,0 +1,58 @@
+package keyboard
+
+import "time"
+
+// Options holds the configuration of a Keyboard.
+type Options struct {
+	Channel    byte          // Zero-based MIDI channel of the notes.
+	Octave     int           // Octave of the lower key row; the upper row plays one octave higher.
+	Velocity   byte          // Velocity of the notes.
+	NoteLength time.Duration // Time after which a note is released, since terminals report no key releases.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithChannel sets the zero-based MIDI channel of the notes.
+func WithChannel(channel byte) Option {
+	return func(opts *Options) {
+		opts.Channel = channel
+	}
+}
+
+// WithOctave sets the octave of the lower key row, where octave 4 starts at middle C.
+func WithOctave(octave int) Option {
+	return func(opts *Options) {
+		opts.Octave = octave
+	}
+}
+
+// WithVelocity sets the velocity of the notes.
+func WithVelocity(velocity byte) Option {
+	return func(opts *Options) {
+		opts.Velocity = velocity
+	}
+}
+
+// WithNoteLength sets how long notes sound before they are released.
+func WithNoteLength(length time.Duration) Option {
+	return func(opts *Options) {
+		opts.NoteLength = length
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{Octave: 3, Velocity: 100}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	options.Channel &= 0x0F
+	options.Octave = min(max(options.Octave, minOctave), maxOctave)
+	options.Velocity = min(max(options.Velocity, 1), 127)
+	if options.NoteLength <= 0 {
+		options.NoteLength = 300 * time.Millisecond
+	}
+	return options
+}
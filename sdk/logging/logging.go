@@ -0,0 +1,96 @@
+// Package logging helps route the logs of the library into the logger of the application.
+// The logrusadapter and zerologadapter subpackages adapt logrus and zerolog; other logging
+// libraries are adapted by implementing contracts.Logger, typically with Field as the
+// field builder returned by Logger.Field and Pairs to read the fields of a message:
+//
+//	func (l *myLogger) Field() contracts.Field {
+//		return logging.Field{}
+//	}
+//
+//	func (l *myLogger) Info(msg string, fields ...contracts.Field) {
+//		for _, pair := range logging.Pairs(fields) {
+//			// Attach pair.Key and pair.Value to the message.
+//		}
+//		// Write msg.
+//	}
+//
+// The adapter is installed with contracts.WithLogger; contracts.WithLogLevel is then
+// applied to it with SetLevel.
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Field is a key-value pair of a log message. Its methods create new fields, so the zero
+// Field can be returned by contracts.Logger.Field.
+type Field struct {
+	Key   string // Name of the field.
+	Value any    // Value of the field, of the type of the method that created it.
+}
+
+// Bool creates a boolean field.
+func (Field) Bool(key string, val bool) contracts.Field { return Field{key, val} }
+
+// Int creates an integer field.
+func (Field) Int(key string, val int) contracts.Field { return Field{key, val} }
+
+// Float64 creates a floating-point field.
+func (Field) Float64(key string, val float64) contracts.Field { return Field{key, val} }
+
+// String creates a string field.
+func (Field) String(key string, val string) contracts.Field { return Field{key, val} }
+
+// Time creates a time field.
+func (Field) Time(key string, val time.Time) contracts.Field { return Field{key, val} }
+
+// Int64 creates a 64-bit integer field.
+func (Field) Int64(key string, val int64) contracts.Field { return Field{key, val} }
+
+// Error creates an error field.
+func (Field) Error(key string, val error) contracts.Field { return Field{key, val} }
+
+// Uint64 creates an unsigned 64-bit integer field.
+func (Field) Uint64(key string, val uint64) contracts.Field { return Field{key, val} }
+
+// Uint8 creates a byte field.
+func (Field) Uint8(key string, val uint8) contracts.Field { return Field{key, val} }
+
+// Pairs returns the fields created with Field, in order, skipping fields created by the
+// builders of other loggers.
+func Pairs(fields []contracts.Field) []Field {
+	pairs := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		if pair, ok := field.(Field); ok {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// OpenDestination returns the writer of a destination set with
+// contracts.Logger.SetDestination: standard error for ConsoleLog, or the file at path,
+// opened for appending and created if needed, for FileLog.
+//
+// dest contracts.LogDestination: The destination.
+// filePath ...string: The path of the file, for FileLog.
+//
+// Returns:
+//   - *os.File: The writer; the caller closes files it no longer writes to.
+//   - error: An error if the destination is unknown or the file cannot be opened.
+func OpenDestination(dest contracts.LogDestination, filePath ...string) (*os.File, error) {
+	switch {
+	case dest == contracts.ConsoleLog:
+		return os.Stderr, nil
+	case dest == contracts.FileLog && len(filePath) > 0 && filePath[0] != "":
+		return os.OpenFile(filePath[0], os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	case dest == contracts.FileLog:
+		return nil, errors.New("logging: file destination without a path")
+	}
+	return nil, fmt.Errorf("logging: unknown destination %q", dest)
+}
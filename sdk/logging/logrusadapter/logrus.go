@@ -0,0 +1,98 @@
+// Package logrusadapter sends the logs of the library to a logrus logger.
+package logrusadapter
+
+import (
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// levels maps the levels of the library to those of logrus.
+var levels = map[contracts.LogLevel]logrus.Level{
+	contracts.DebugLevel: logrus.DebugLevel,
+	contracts.InfoLevel:  logrus.InfoLevel,
+	contracts.WarnLevel:  logrus.WarnLevel,
+	contracts.ErrorLevel: logrus.ErrorLevel,
+	contracts.FatalLevel: logrus.FatalLevel,
+}
+
+// Logger is a contracts.Logger writing to a logrus logger.
+type Logger struct {
+	logger   *logrus.Logger
+	rotation contracts.LogRotation // Rotation of the files of SetDestination.
+}
+
+// New creates a logger writing to logger. Its level and output are changed by SetLevel
+// and SetDestination.
+//
+// logger *logrus.Logger: The logrus logger, such as logrus.StandardLogger().
+//
+// Returns:
+//   - *Logger: The logger, to install with contracts.WithLogger.
+func New(logger *logrus.Logger) *Logger {
+	return &Logger{logger: logger}
+}
+
+// Info logs a message at the info level.
+func (l *Logger) Info(msg string, fields ...contracts.Field) {
+	l.entry(fields).Info(msg)
+}
+
+// Error logs a message at the error level.
+func (l *Logger) Error(msg string, fields ...contracts.Field) {
+	l.entry(fields).Error(msg)
+}
+
+// Debug logs a message at the debug level.
+func (l *Logger) Debug(msg string, fields ...contracts.Field) {
+	l.entry(fields).Debug(msg)
+}
+
+// Warn logs a message at the warning level.
+func (l *Logger) Warn(msg string, fields ...contracts.Field) {
+	l.entry(fields).Warn(msg)
+}
+
+// Fatal logs a message at the fatal level; logrus then terminates the application.
+func (l *Logger) Fatal(msg string, fields ...contracts.Field) {
+	l.entry(fields).Fatal(msg)
+}
+
+// Field returns a field builder.
+func (l *Logger) Field() contracts.Field {
+	return logging.Field{}
+}
+
+// SetLevel sets the level of the logrus logger.
+func (l *Logger) SetLevel(level contracts.LogLevel) {
+	if level, ok := levels[level]; ok {
+		l.logger.SetLevel(level)
+	}
+}
+
+// SetRotation sets the rotation of the files of the next call to SetDestination.
+func (l *Logger) SetRotation(rotation contracts.LogRotation) {
+	l.rotation = rotation
+}
+
+// SetDestination sets the output of the logrus logger: standard error for ConsoleLog, the
+// file at filePath for FileLog, or both for ConsoleAndFileLog. The output is left
+// unchanged if the file cannot be opened, and the error is logged.
+func (l *Logger) SetDestination(dest contracts.LogDestination, filePath ...string) {
+	output, err := logging.OpenWriter(dest, l.rotation, filePath...)
+	if err != nil {
+		l.logger.WithError(err).Error("Failed to set the log destination")
+		return
+	}
+	l.logger.SetOutput(output)
+}
+
+// entry returns an entry with the fields.
+func (l *Logger) entry(fields []contracts.Field) *logrus.Entry {
+	pairs := logging.Pairs(fields)
+	data := make(logrus.Fields, len(pairs))
+	for _, pair := range pairs {
+		data[pair.Key] = pair.Value
+	}
+	return l.logger.WithFields(data)
+}
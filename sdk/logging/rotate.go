@@ -0,0 +1,275 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// backupTime is the layout of the time of rotation in the names of backups.
+const backupTime = "2006-01-02T15-04-05.000"
+
+// RotatingFile is a log file replaced by a new one when it reaches the size or the age of
+// its contracts.LogRotation. Rotated files are renamed name-<time>.ext next to it, the time
+// of rotation being UTC, and removed beyond the number and age of backups to keep. It is
+// safe for concurrent use.
+type RotatingFile struct {
+	path     string
+	rotation contracts.LogRotation
+
+	mu     sync.Mutex
+	file   *os.File  // Current file; nil once closed.
+	size   int64     // Bytes in the current file.
+	period time.Time // Start of the interval the current file belongs to.
+	now    func() time.Time
+}
+
+// OpenRotatingFile opens the file at path for appending, creating it and its directory if
+// needed. A file left by a previous run is rotated on the first write if it is already
+// too large or belongs to an earlier interval.
+//
+// path string: The path of the log file.
+// rotation contracts.LogRotation: When to rotate the file and which backups to keep.
+//
+// Returns:
+//   - *RotatingFile: The file, to close once no longer written to.
+//   - error: An error if the file cannot be opened.
+func OpenRotatingFile(path string, rotation contracts.LogRotation) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, rotation: rotation, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the file at f.path for appending and takes its size and interval. The caller
+// must hold f.mu, if f is shared.
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	f.period = f.periodOf(f.now())
+	if f.size > 0 {
+		f.period = f.periodOf(info.ModTime())
+	}
+	return nil
+}
+
+// periodOf returns the start of the rotation interval t belongs to.
+func (f *RotatingFile) periodOf(t time.Time) time.Time {
+	if f.rotation.Interval <= 0 {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(f.rotation.Interval)
+}
+
+// Write appends p to the file, rotating it first if p would take it past the maximum size
+// or the interval of the file is over. A message longer than the maximum size is written
+// whole to a new file. If the file cannot be renamed, messages keep being appended to it.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	now := f.now()
+	full := f.rotation.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.rotation.MaxSize
+	if full || !f.periodOf(now).Equal(f.period) {
+		if err := f.rotate(now); err != nil && f.file == nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate replaces the file by a new one at once, keeping it as a backup.
+//
+// Returns:
+//   - error: An error if the file cannot be renamed or the new one created.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate(f.now())
+}
+
+// rotate renames the current file to a backup named after now, opens a new one and removes
+// the backups beyond those to keep. The caller must hold f.mu.
+func (f *RotatingFile) rotate(now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if err := os.Rename(f.path, f.backupName(now)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// Keep writing to the current file rather than losing messages.
+		if openErr := f.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		f.period = f.periodOf(now)
+		return fmt.Errorf("logging: rotating %s: %w", f.path, err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.period = f.periodOf(now)
+	f.prune(now)
+	return nil
+}
+
+// backupName returns the name of the backup of the file rotated at now.
+func (f *RotatingFile) backupName(now time.Time) string {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-" + now.UTC().Format(backupTime) + ext
+}
+
+// prune removes the backups beyond MaxBackups, the oldest first, and those older than
+// MaxAge. Failures are ignored; the backups are removed on a later rotation.
+func (f *RotatingFile) prune(now time.Time) {
+	if f.rotation.MaxBackups <= 0 && f.rotation.MaxAge <= 0 {
+		return
+	}
+	backups := f.backups()
+	for i, backup := range backups {
+		if (f.rotation.MaxBackups > 0 && i >= f.rotation.MaxBackups) ||
+			(f.rotation.MaxAge > 0 && now.Sub(backup.rotated) > f.rotation.MaxAge) {
+			os.Remove(backup.path)
+		}
+	}
+}
+
+// backup is a rotated file.
+type backup struct {
+	path    string
+	rotated time.Time // Time the file was rotated, from its name.
+}
+
+// backups returns the backups of the file, the most recent first.
+func (f *RotatingFile) backups() []backup {
+	dir, name := filepath.Split(f.path)
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext) + "-"
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return nil
+	}
+
+	var backups []backup
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		rotated, err := time.Parse(backupTime, strings.TrimSuffix(stamp, ext))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, entry.Name()), rotated: rotated})
+	}
+	slices.SortFunc(backups, func(a, b backup) int { return b.rotated.Compare(a.rotated) })
+	return backups
+}
+
+// Sync commits the written messages to stable storage.
+func (f *RotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.file.Sync()
+}
+
+// Close closes the file; later writes fail with os.ErrClosed.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// OpenWriter returns the writer of a destination set with contracts.Logger.SetDestination,
+// like OpenDestination, with files rotated according to rotation: standard error for
+// ConsoleLog, a RotatingFile at path for FileLog, or both for ConsoleAndFileLog.
+//
+// dest contracts.LogDestination: The destination.
+// rotation contracts.LogRotation: The rotation of the file, for FileLog and ConsoleAndFileLog.
+// filePath ...string: The path of the file, for FileLog and ConsoleAndFileLog.
+//
+// Returns:
+//   - io.WriteCloser: The writer, whose Close closes the file but not standard error.
+//   - error: An error if the destination is unknown or the file cannot be opened.
+func OpenWriter(dest contracts.LogDestination, rotation contracts.LogRotation, filePath ...string) (io.WriteCloser, error) {
+	switch dest {
+	case contracts.ConsoleLog:
+		return nopCloser{os.Stderr}, nil
+	case contracts.FileLog, contracts.ConsoleAndFileLog:
+		if len(filePath) == 0 || filePath[0] == "" {
+			return nil, fmt.Errorf("logging: %s destination without a path", dest)
+		}
+		file, err := OpenRotatingFile(filePath[0], rotation)
+		if err != nil {
+			return nil, err
+		}
+		if dest == contracts.FileLog {
+			return file, nil
+		}
+		return &tee{console: os.Stderr, file: file}, nil
+	}
+	return nil, fmt.Errorf("logging: unknown destination %q", dest)
+}
+
+// nopCloser is a writer whose Close does nothing.
+type nopCloser struct{ io.Writer }
+
+// Close does nothing.
+func (nopCloser) Close() error { return nil }
+
+// tee writes to the console and to a file.
+type tee struct {
+	console io.Writer
+	file    *RotatingFile
+}
+
+// Write writes p to the console and to the file, returning the error of the file: the
+// file is the record of the session, while a closed console is common for services.
+func (t *tee) Write(p []byte) (int, error) {
+	t.console.Write(p)
+	return t.file.Write(p)
+}
+
+// Sync commits the file to stable storage.
+func (t *tee) Sync() error {
+	return t.file.Sync()
+}
+
+// Close closes the file.
+func (t *tee) Close() error {
+	return t.file.Close()
+}
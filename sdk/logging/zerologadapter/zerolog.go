@@ -0,0 +1,131 @@
+// Package zerologadapter sends the logs of the library to a zerolog logger.
+package zerologadapter
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/logging"
+	"github.com/rs/zerolog"
+)
+
+// levels maps the levels of the library to those of zerolog.
+var levels = map[contracts.LogLevel]zerolog.Level{
+	contracts.DebugLevel: zerolog.DebugLevel,
+	contracts.InfoLevel:  zerolog.InfoLevel,
+	contracts.WarnLevel:  zerolog.WarnLevel,
+	contracts.ErrorLevel: zerolog.ErrorLevel,
+	contracts.FatalLevel: zerolog.FatalLevel,
+}
+
+// Logger is a contracts.Logger writing to a zerolog logger.
+type Logger struct {
+	logger   atomic.Pointer[zerolog.Logger]        // Replaced by SetLevel and SetDestination.
+	rotation atomic.Pointer[contracts.LogRotation] // Rotation of the files of SetDestination; nil never rotates.
+}
+
+// New creates a logger writing to logger. SetLevel and SetDestination derive new zerolog
+// loggers from it, leaving logger itself unchanged.
+//
+// logger zerolog.Logger: The zerolog logger, such as log.Logger.
+//
+// Returns:
+//   - *Logger: The logger, to install with contracts.WithLogger.
+func New(logger zerolog.Logger) *Logger {
+	l := &Logger{}
+	l.logger.Store(&logger)
+	return l
+}
+
+// Info logs a message at the info level.
+func (l *Logger) Info(msg string, fields ...contracts.Field) {
+	send(l.logger.Load().Info(), msg, fields)
+}
+
+// Error logs a message at the error level.
+func (l *Logger) Error(msg string, fields ...contracts.Field) {
+	send(l.logger.Load().Error(), msg, fields)
+}
+
+// Debug logs a message at the debug level.
+func (l *Logger) Debug(msg string, fields ...contracts.Field) {
+	send(l.logger.Load().Debug(), msg, fields)
+}
+
+// Warn logs a message at the warning level.
+func (l *Logger) Warn(msg string, fields ...contracts.Field) {
+	send(l.logger.Load().Warn(), msg, fields)
+}
+
+// Fatal logs a message at the fatal level; zerolog then terminates the application.
+func (l *Logger) Fatal(msg string, fields ...contracts.Field) {
+	send(l.logger.Load().Fatal(), msg, fields)
+}
+
+// Field returns a field builder.
+func (l *Logger) Field() contracts.Field {
+	return logging.Field{}
+}
+
+// SetLevel sets the minimum level of the messages written.
+func (l *Logger) SetLevel(level contracts.LogLevel) {
+	if level, ok := levels[level]; ok {
+		logger := l.logger.Load().Level(level)
+		l.logger.Store(&logger)
+	}
+}
+
+// SetRotation sets the rotation of the files of the next call to SetDestination.
+func (l *Logger) SetRotation(rotation contracts.LogRotation) {
+	l.rotation.Store(&rotation)
+}
+
+// SetDestination sets the output of the logger: standard error for ConsoleLog, the file at
+// filePath for FileLog, or both for ConsoleAndFileLog. The output is left unchanged if the
+// file cannot be opened, and the error is logged.
+func (l *Logger) SetDestination(dest contracts.LogDestination, filePath ...string) {
+	var rotation contracts.LogRotation
+	if r := l.rotation.Load(); r != nil {
+		rotation = *r
+	}
+	output, err := logging.OpenWriter(dest, rotation, filePath...)
+	if err != nil {
+		l.logger.Load().Error().Err(err).Msg("Failed to set the log destination")
+		return
+	}
+	logger := l.logger.Load().Output(output)
+	l.logger.Store(&logger)
+}
+
+// send adds the fields to event and writes it. event is nil when its level is disabled.
+func send(event *zerolog.Event, msg string, fields []contracts.Field) {
+	if event == nil {
+		return
+	}
+	for _, pair := range logging.Pairs(fields) {
+		switch value := pair.Value.(type) {
+		case bool:
+			event.Bool(pair.Key, value)
+		case int:
+			event.Int(pair.Key, value)
+		case int64:
+			event.Int64(pair.Key, value)
+		case uint64:
+			event.Uint64(pair.Key, value)
+		case uint8:
+			event.Uint8(pair.Key, value)
+		case float64:
+			event.Float64(pair.Key, value)
+		case string:
+			event.Str(pair.Key, value)
+		case time.Time:
+			event.Time(pair.Key, value)
+		case error:
+			event.AnErr(pair.Key, value)
+		default:
+			event.Interface(pair.Key, value)
+		}
+	}
+	event.Msg(msg)
+}
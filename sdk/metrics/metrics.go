@@ -0,0 +1,77 @@
+// Package metrics exports the statistics and health of a MIDI client to monitoring
+// systems, so services embedding the client can watch the event traffic, the events
+// dropped or filtered, the occupancy of the event channel and the time the capture
+// handler takes. Publish registers them with the standard expvar package, served at
+// /debug/vars by its HTTP handler, and Handler serves them in the Prometheus text
+// exposition format without depending on the Prometheus client library:
+//
+//	if err := metrics.Publish("midi", client); err != nil {
+//		log.Fatal(err)
+//	}
+//	http.Handle("/metrics", metrics.Handler(client, metrics.WithLabels(map[string]string{"client": "keys"})))
+//	go http.ListenAndServe(":9100", nil)
+//
+// The values are read from the client when they are collected, so nothing is recorded
+// between scrapes.
+package metrics
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// ErrDuplicateName is returned by Publish when the name is already published.
+var ErrDuplicateName = errors.New("expvar name already published")
+
+// Source is what the metrics are read from: a contracts.ClientMIDI, or an InputGroup of
+// sdk/midi. The health counters are exported too when the source also reports its health.
+type Source interface {
+	Stats() contracts.Stats
+}
+
+// healthSource is a Source that also reports its health, as every contracts.ClientMIDI.
+type healthSource interface {
+	Health() contracts.Health
+}
+
+// Snapshot is the value published by Publish, read from the source on every collection.
+type Snapshot struct {
+	Stats  contracts.Stats   // Statistics of the source.
+	Health *contracts.Health `json:",omitempty"` // Health of the source; nil when it does not report one.
+}
+
+// Collect reads the statistics and, if available, the health of source.
+//
+// source Source: The client or group to read.
+//
+// Returns:
+//   - Snapshot: The current statistics and health.
+func Collect(source Source) Snapshot {
+	snapshot := Snapshot{Stats: source.Stats()}
+	if client, ok := source.(healthSource); ok {
+		health := client.Health()
+		snapshot.Health = &health
+	}
+	return snapshot
+}
+
+// Publish registers the statistics and health of source with the expvar package under
+// name, as a JSON object with the fields of Snapshot. Durations are in nanoseconds. Names
+// are global to the process and cannot be unpublished, so a service publishing several
+// clients gives each a name of its own.
+//
+// name string: The name of the variable, a key of the /debug/vars object.
+// source Source: The client or group to publish.
+//
+// Returns:
+//   - error: ErrDuplicateName if a variable with that name is already published.
+func Publish(name string, source Source) error {
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("%w: %q", ErrDuplicateName, name)
+	}
+	expvar.Publish(name, expvar.Func(func() any { return Collect(source) }))
+	return nil
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import "maps"
+
+// Options holds the configuration of the Prometheus exposition.
+type Options struct {
+	Namespace string            // Prefix of the metric names, followed by an underscore.
+	Labels    map[string]string // Labels added to every metric, such as the name of the client.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithNamespace sets the prefix of the metric names, "midi" by default, giving names such
+// as midi_events_received_total.
+func WithNamespace(namespace string) Option {
+	return func(opts *Options) {
+		opts.Namespace = namespace
+	}
+}
+
+// WithLabels adds labels to every metric, to tell apart the clients of a process exposing
+// several, e.g. WithLabels(map[string]string{"client": "keys"}).
+func WithLabels(labels map[string]string) Option {
+	return func(opts *Options) {
+		if opts.Labels == nil {
+			opts.Labels = make(map[string]string, len(labels))
+		}
+		maps.Copy(opts.Labels, labels)
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{Namespace: "midi"}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// contentType is the media type of the Prometheus text exposition format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// quantiles are the quantile labels of the summarised durations, from the minimum to the
+// maximum.
+var quantiles = []string{"0", "0.5", "0.95", "0.99", "1"}
+
+// Handler serves the metrics of source in the Prometheus text exposition format.
+//
+// source Source: The client or group to expose.
+// opts ...Option: Optional settings such as WithNamespace and WithLabels.
+//
+// Returns:
+//   - http.Handler: The handler, to mount at the path scraped by Prometheus, usually /metrics.
+func Handler(source Source, opts ...Option) http.Handler {
+	options := applyDefaultOptions(opts...)
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_ = write(w, Collect(source), options)
+	})
+}
+
+// WritePrometheus writes the metrics of source to w in the Prometheus text exposition
+// format, for services that serve them themselves or push them to a gateway.
+//
+// w io.Writer: The destination of the metrics.
+// source Source: The client or group to expose.
+// opts ...Option: Optional settings such as WithNamespace and WithLabels.
+//
+// Returns:
+//   - error: The error writing to w, if any.
+func WritePrometheus(w io.Writer, source Source, opts ...Option) error {
+	return write(w, Collect(source), applyDefaultOptions(opts...))
+}
+
+// sample is one value of a metric, with the labels telling it apart from the others.
+type sample struct {
+	labels []string // Label names and values, alternating.
+	value  float64
+}
+
+// exposition writes metrics in the text exposition format. The writer is buffered, so the
+// first error writing is returned by its Flush.
+type exposition struct {
+	w      *bufio.Writer
+	prefix string   // Namespace and underscore, prepended to the metric names.
+	labels []string // Names and values of the labels of WithLabels, sorted by name.
+}
+
+// write writes the metrics of snapshot to w.
+func write(w io.Writer, snapshot Snapshot, options Options) error {
+	e := &exposition{w: bufio.NewWriter(w)}
+	if options.Namespace != "" {
+		e.prefix = options.Namespace + "_"
+	}
+	for _, name := range slices.Sorted(maps.Keys(options.Labels)) {
+		e.labels = append(e.labels, name, options.Labels[name])
+	}
+
+	stats := snapshot.Stats
+	e.counter("events_received_total", "Events received from every device, before filtering.", float64(stats.Received))
+	e.counter("events_filtered_total", "Events discarded by the event filter, the event predicate or a transform.", float64(stats.Filtered))
+	e.counter("events_dropped_total", "Events discarded because the event channel was full.", float64(stats.Dropped))
+	e.counter("events_coalesced_total", "Events replaced by a later value of the same control.", float64(stats.Coalesced))
+	e.counter("sysex_bytes_total", "Bytes of the SysEx messages received.", float64(stats.SysExBytes))
+	e.gauge("channel_queued_events", "Events waiting in the event channel.", sample{value: float64(stats.Queued)})
+	e.gauge("channel_capacity_events", "Capacity of the event channel.", sample{value: float64(stats.Capacity)})
+	e.gauge("channel_occupancy_ratio", "Fraction of the event channel in use.", sample{value: stats.Occupancy()})
+	if callbacks := stats.Callbacks; callbacks.Count > 0 {
+		e.gauge("callback_duration_seconds", "Time the capture handler takes for each event, over the recent events.",
+			durations(callbacks.Min, callbacks.P50, callbacks.P95, callbacks.P99, callbacks.Max)...)
+	}
+
+	if health := snapshot.Health; health != nil {
+		e.gauge("connected", "Whether a device is selected and connected.", sample{value: boolValue(health.Connected)})
+		e.gauge("capturing", "Whether events are being delivered.", sample{value: boolValue(health.Capturing)})
+		if !health.LastEvent.IsZero() {
+			e.gauge("last_event_timestamp_seconds", "Time the last event was received.", sample{value: float64(health.LastEvent.UnixNano()) / 1e9})
+		}
+		e.counter("duplicates_total", "Events suppressed as duplicates of another device's.", float64(health.Duplicates))
+		e.counter("malformed_total", "Malformed byte sequences discarded while parsing.", float64(health.Malformed))
+		e.counter("sysex_received_total", "SysEx messages received.", float64(health.SysExReceived))
+		e.counter("sysex_dropped_total", "SysEx messages discarded because their channel or queue was full.", float64(health.SysExDropped))
+		e.counter("midi2_received_total", "MIDI 2.0 events received.", float64(health.MIDI2Received))
+		e.counter("midi2_dropped_total", "MIDI 2.0 events discarded because their channel was full.", float64(health.MIDI2Dropped))
+		e.counter("panics_total", "Panics recovered in callbacks and capture paths.", float64(health.Panics))
+	}
+
+	e.devices(stats.Devices)
+	return e.w.Flush()
+}
+
+// devices writes the metrics of every device, grouped by metric as the format requires.
+func (e *exposition) devices(devices []contracts.DeviceStats) {
+	if len(devices) == 0 {
+		return
+	}
+	var received, queueDropped, rate, intervals, commandsReceived, commandsFiltered []sample
+	for _, device := range devices {
+		labels := []string{"device_id", strconv.Itoa(device.DeviceID), "device", device.Device.Name}
+		received = append(received, sample{labels: labels, value: float64(device.Received)})
+		queueDropped = append(queueDropped, sample{labels: labels, value: float64(device.QueueDropped)})
+		rate = append(rate, sample{labels: labels, value: device.Intervals.EventsPerSecond})
+		if iv := device.Intervals; iv.Count > 0 {
+			for _, s := range durations(iv.Min, iv.P50, iv.P95, iv.P99, iv.Max) {
+				intervals = append(intervals, sample{labels: append(slices.Clip(labels), s.labels...), value: s.value})
+			}
+		}
+		for _, command := range device.Commands {
+			commandLabels := append(slices.Clip(labels), "command", command.String())
+			commandsReceived = append(commandsReceived, sample{labels: commandLabels, value: float64(command.Received)})
+			commandsFiltered = append(commandsFiltered, sample{labels: commandLabels, value: float64(command.Filtered)})
+		}
+	}
+	e.metric("device_events_received_total", "counter", "Events received from the device, before filtering.", received)
+	e.metric("device_queue_dropped_total", "counter", "Events discarded because the queue of the device was full.", queueDropped)
+	e.metric("device_events_per_second", "gauge", "Event rate of the device over the recent events.", rate)
+	e.metric("device_event_interval_seconds", "gauge", "Time between consecutive events of the device, over the recent events.", intervals)
+	e.metric("device_commands_received_total", "counter", "Events received from the device by kind, before filtering.", commandsReceived)
+	e.metric("device_commands_filtered_total", "counter", "Events of the device discarded by the event filter, by kind.", commandsFiltered)
+}
+
+// counter writes a counter with a single value.
+func (e *exposition) counter(name, help string, value float64) {
+	e.metric(name, "counter", help, []sample{{value: value}})
+}
+
+// gauge writes a gauge with the given values.
+func (e *exposition) gauge(name, help string, samples ...sample) {
+	e.metric(name, "gauge", help, samples)
+}
+
+// metric writes the help and type lines of a metric followed by its samples. Metrics
+// without samples are omitted.
+func (e *exposition) metric(name, kind, help string, samples []sample) {
+	if len(samples) == 0 {
+		return
+	}
+	name = e.prefix + name
+	e.w.WriteString("# HELP " + name + " " + help + "\n")
+	e.w.WriteString("# TYPE " + name + " " + kind + "\n")
+	for _, s := range samples {
+		e.w.WriteString(name)
+		e.writeLabels(append(slices.Clip(e.labels), s.labels...))
+		e.w.WriteString(" " + strconv.FormatFloat(s.value, 'g', -1, 64) + "\n")
+	}
+}
+
+// writeLabels writes the label set of a sample, if it has labels.
+func (e *exposition) writeLabels(labels []string) {
+	if len(labels) == 0 {
+		return
+	}
+	e.w.WriteByte('{')
+	for i := 0; i < len(labels); i += 2 {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.w.WriteString(labels[i] + `="` + labelEscaper.Replace(labels[i+1]) + `"`)
+	}
+	e.w.WriteByte('}')
+}
+
+// labelEscaper escapes label values as the text exposition format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// durations returns the samples of summarised durations in seconds, labelled with the
+// quantiles from the minimum to the maximum.
+func durations(values ...time.Duration) []sample {
+	samples := make([]sample, len(values))
+	for i, value := range values {
+		samples[i] = sample{labels: []string{"quantile", quantiles[i]}, value: value.Seconds()}
+	}
+	return samples
+}
+
+// boolValue returns 1 for true and 0 for false.
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
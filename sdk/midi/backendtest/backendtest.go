@@ -0,0 +1,458 @@
+// Package backendtest is a conformance suite for contracts.ClientMIDI implementations. It
+// checks the behavior every backend of this module shares — device selection and errors,
+// lifecycle ordering and hooks, health reporting, event filtering, restarting on a new
+// client and the overflow policy of the event channel — so new built-in and third-party
+// backends can prove they behave like the others.
+//
+// Run it from a test of the backend, as the loopback backend does:
+//
+//	func TestConformance(t *testing.T) {
+//		backendtest.Run(t, backendtest.Config{
+//			New: func(t *testing.T, opts ...contracts.Option) contracts.ClientMIDI {
+//				opts = append(opts, contracts.WithBackend(contracts.BackendLoopback))
+//				client, err := midi.NewMIDIClient(opts...)
+//				if err != nil {
+//					t.Fatal(err)
+//				}
+//				return client
+//			},
+//			Inject: func(client contracts.ClientMIDI, event contracts.MIDI) error {
+//				return client.Send(event)
+//			},
+//		})
+//	}
+package backendtest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Timeout bounds every wait of the suite, such as for an injected event to be delivered.
+const Timeout = 2 * time.Second
+
+// Config describes the backend under test.
+type Config struct {
+	// New creates a client of the backend with opts appended to its own options. The
+	// suite calls it once per check and stops every client it creates. It must fail t
+	// when the client cannot be created.
+	New func(t *testing.T, opts ...contracts.Option) contracts.ClientMIDI
+	// DeviceID is the device the suite selects and captures from.
+	DeviceID int
+	// Inject makes the selected device of client send event, e.g. through a loopback or
+	// a device under the control of the test. Checks of captured events are skipped
+	// when it is nil.
+	Inject func(client contracts.ClientMIDI, event contracts.MIDI) error
+}
+
+// Run runs the conformance checks as subtests of t.
+func Run(t *testing.T, config Config) {
+	t.Helper()
+	if config.New == nil {
+		t.Fatal("backendtest: Config.New is nil")
+	}
+
+	s := &suite{config: config}
+	t.Run("ListDevices", s.listDevices)
+	t.Run("InvalidDevice", s.invalidDevice)
+	t.Run("DeviceCapabilities", s.deviceCapabilities)
+	t.Run("HealthBeforeSelect", s.healthBeforeSelect)
+	t.Run("StartCaptureWithoutDevice", s.startCaptureWithoutDevice)
+	t.Run("StartCaptureNilChannel", s.startCaptureNilChannel)
+	t.Run("StartCaptureTwice", s.startCaptureTwice)
+	t.Run("Lifecycle", s.lifecycle)
+	t.Run("StopTwice", s.stopTwice)
+	t.Run("CaptureContext", s.captureContext)
+	t.Run("Delivery", s.delivery)
+	t.Run("DeliveryFunc", s.deliveryFunc)
+	t.Run("Filter", s.filter)
+	t.Run("Restart", s.restart)
+	t.Run("Overflow", s.overflow)
+}
+
+// suite holds the configuration shared by the checks.
+type suite struct {
+	config Config
+}
+
+// client creates a client with opts that is stopped when the check ends.
+func (s *suite) client(t *testing.T, opts ...contracts.Option) contracts.ClientMIDI {
+	t.Helper()
+	client := s.config.New(t, append([]contracts.Option{contracts.WithoutLogging()}, opts...)...)
+	t.Cleanup(func() { client.Stop() })
+	return client
+}
+
+// selected creates a client with opts and selects the device under test.
+func (s *suite) selected(t *testing.T, opts ...contracts.Option) contracts.ClientMIDI {
+	t.Helper()
+	client := s.client(t, opts...)
+	if err := client.SelectDevice(s.config.DeviceID); err != nil {
+		t.Fatalf("SelectDevice(%d): %v", s.config.DeviceID, err)
+	}
+	return client
+}
+
+// requireInject skips the check when the backend cannot inject events.
+func (s *suite) requireInject(t *testing.T) {
+	t.Helper()
+	if s.config.Inject == nil {
+		t.Skip("Config.Inject is nil")
+	}
+}
+
+// inject makes the device send event.
+func (s *suite) inject(t *testing.T, client contracts.ClientMIDI, event contracts.MIDI) {
+	t.Helper()
+	if err := s.config.Inject(client, event); err != nil {
+		t.Fatalf("Inject(%+v): %v", event, err)
+	}
+}
+
+// listDevices checks that the device under test is listed.
+func (s *suite) listDevices(t *testing.T) {
+	devices, err := s.client(t).ListDevices()
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	if s.config.DeviceID < 0 || s.config.DeviceID >= len(devices) {
+		t.Fatalf("ListDevices returned %d devices; device %d is missing", len(devices), s.config.DeviceID)
+	}
+}
+
+// invalidDevice checks that selecting a device that does not exist fails with
+// contracts.ErrInvalidDevice.
+func (s *suite) invalidDevice(t *testing.T) {
+	client := s.client(t)
+	devices, err := client.ListDevices()
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	for _, id := range []int{-1, len(devices) + 100} {
+		if err := client.SelectDevice(id); !errors.Is(err, contracts.ErrInvalidDevice) {
+			t.Errorf("SelectDevice(%d) = %v; want an error wrapping contracts.ErrInvalidDevice", id, err)
+		}
+	}
+}
+
+// deviceCapabilities checks that the device under test reports its capabilities and that
+// devices that do not exist are rejected.
+func (s *suite) deviceCapabilities(t *testing.T) {
+	client := s.client(t)
+	caps, err := client.DeviceCapabilities(s.config.DeviceID)
+	if err != nil {
+		t.Fatalf("DeviceCapabilities(%d): %v", s.config.DeviceID, err)
+	}
+	if caps.InputPorts < 1 {
+		t.Errorf("DeviceCapabilities(%d).InputPorts = %d; want at least 1", s.config.DeviceID, caps.InputPorts)
+	}
+	if _, err := client.DeviceCapabilities(-1); err == nil {
+		t.Error("DeviceCapabilities(-1) succeeded; want an error")
+	}
+}
+
+// healthBeforeSelect checks the health of a client without a selected device.
+func (s *suite) healthBeforeSelect(t *testing.T) {
+	health := s.client(t).Health()
+	if health.Backend == "" {
+		t.Error("Health().Backend is empty")
+	}
+	if health.DeviceID != -1 || health.Connected || health.Capturing {
+		t.Errorf("Health() = {DeviceID: %d, Connected: %v, Capturing: %v}; want {-1, false, false}",
+			health.DeviceID, health.Connected, health.Capturing)
+	}
+}
+
+// startCaptureWithoutDevice checks that capture does not start before a device is selected.
+func (s *suite) startCaptureWithoutDevice(t *testing.T) {
+	client := s.client(t)
+	if err := client.StartCapture(make(chan contracts.MIDI, 1)); !errors.Is(err, contracts.ErrNoDeviceSelected) {
+		t.Errorf("StartCapture without a selected device = %v; want %v", err, contracts.ErrNoDeviceSelected)
+	}
+	if client.Health().Capturing {
+		t.Error("capture started without a selected device")
+	}
+}
+
+// startCaptureNilChannel checks that a nil channel is rejected.
+func (s *suite) startCaptureNilChannel(t *testing.T) {
+	client := s.selected(t)
+	if err := client.StartCapture(nil); !errors.Is(err, contracts.ErrNilChannel) {
+		t.Errorf("StartCapture(nil) = %v; want %v", err, contracts.ErrNilChannel)
+	}
+	if client.Health().Capturing {
+		t.Error("capture started with a nil channel")
+	}
+}
+
+// lifecycle checks the health and the order of the hooks through selection, capture and Stop.
+func (s *suite) lifecycle(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	record := func(call string) {
+		mu.Lock()
+		calls = append(calls, call)
+		mu.Unlock()
+	}
+	client := s.selected(t, contracts.WithHooks(contracts.Hooks{
+		OnDeviceSelected: func(id int, _ contracts.DeviceInfo) {
+			if id != s.config.DeviceID {
+				t.Errorf("OnDeviceSelected(%d); want device %d", id, s.config.DeviceID)
+			}
+			record("selected")
+		},
+		OnCaptureStarted: func() { record("started") },
+		OnCaptureStopped: func() { record("stopped") },
+	}))
+
+	health := client.Health()
+	if !health.Connected || health.DeviceID != s.config.DeviceID || health.Capturing {
+		t.Errorf("after SelectDevice: Health() = {DeviceID: %d, Connected: %v, Capturing: %v}; want {%d, true, false}",
+			health.DeviceID, health.Connected, health.Capturing, s.config.DeviceID)
+	}
+
+	start(t, client, make(chan contracts.MIDI, 16))
+	if !client.Health().Capturing {
+		t.Error("after StartCapture: Health().Capturing = false")
+	}
+
+	if err := client.Stop(); err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+	if client.Health().Capturing {
+		t.Error("after Stop: Health().Capturing = true")
+	}
+
+	want := []string{"selected", "started", "stopped"}
+	waitFor(t, "hooks "+strings.Join(want, ", "), func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(calls) >= len(want)
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(calls, want) {
+		t.Errorf("hooks called in order %q; want %q", calls, want)
+	}
+}
+
+// startCaptureTwice checks that a running capture is not replaced.
+func (s *suite) startCaptureTwice(t *testing.T) {
+	client := s.selected(t)
+	start(t, client, make(chan contracts.MIDI, 1))
+	if err := client.StartCapture(make(chan contracts.MIDI, 1)); !errors.Is(err, contracts.ErrCaptureStarted) {
+		t.Errorf("second StartCapture = %v; want %v", err, contracts.ErrCaptureStarted)
+	}
+	if !client.Health().Capturing {
+		t.Error("after a second StartCapture: Health().Capturing = false")
+	}
+}
+
+// stopTwice checks that Stop can be called again.
+func (s *suite) stopTwice(t *testing.T) {
+	client := s.selected(t)
+	start(t, client, make(chan contracts.MIDI, 1))
+	if err := client.Stop(); err != nil {
+		t.Errorf("first Stop: %v", err)
+	}
+	if err := client.Stop(); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+}
+
+// delivery checks that captured events arrive in order and are counted.
+func (s *suite) delivery(t *testing.T) {
+	s.requireInject(t)
+	client := s.selected(t)
+	events := make(chan contracts.MIDI, 16)
+	start(t, client, events)
+
+	sent := []contracts.MIDI{
+		{Command: 0x90, Note: 60, Velocity: 100},
+		{Command: 0xB0, Note: 7, Velocity: 90},
+		{Command: 0x80, Note: 60, Velocity: 0},
+	}
+	for _, event := range sent {
+		s.inject(t, client, event)
+	}
+	for i, want := range sent {
+		got := receive(t, events)
+		if got.Command != want.Command || got.Note != want.Note || got.Velocity != want.Velocity {
+			t.Errorf("event %d = %+v; want %+v", i, got, want)
+		}
+	}
+	if received := client.Health().EventsReceived; received < uint64(len(sent)) {
+		t.Errorf("Health().EventsReceived = %d; want at least %d", received, len(sent))
+	}
+}
+
+// captureContext checks that a capture started with StartCaptureContext stops the client
+// when its context is cancelled.
+func (s *suite) captureContext(t *testing.T) {
+	client := s.selected(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := client.StartCaptureContext(ctx, make(chan contracts.MIDI, 16)); err != nil {
+		t.Fatalf("StartCaptureContext: %v", err)
+	}
+	if !client.Health().Capturing {
+		t.Error("after StartCaptureContext: Health().Capturing = false")
+	}
+
+	cancel()
+	waitFor(t, "the client to stop after cancelling the context", func() bool {
+		return !client.Health().Capturing
+	})
+}
+
+// deliveryFunc checks that injected events reach the handler of StartCaptureFunc in order.
+func (s *suite) deliveryFunc(t *testing.T) {
+	s.requireInject(t)
+	client := s.selected(t)
+	events := make(chan contracts.MIDI, 16)
+	if err := client.StartCaptureFunc(func(event contracts.MIDI) { events <- event }); err != nil {
+		t.Fatalf("StartCaptureFunc: %v", err)
+	}
+
+	sent := []contracts.MIDI{
+		{Command: 0x90, Note: 64, Velocity: 80},
+		{Command: 0x80, Note: 64, Velocity: 0},
+	}
+	for _, event := range sent {
+		s.inject(t, client, event)
+	}
+	for i, want := range sent {
+		got := receive(t, events)
+		if got.Command != want.Command || got.Note != want.Note || got.Velocity != want.Velocity {
+			t.Errorf("handled event %d = %+v; want %+v", i, got, want)
+		}
+	}
+}
+
+// filter checks the initial filter, its replacement during capture and its counters.
+func (s *suite) filter(t *testing.T) {
+	s.requireInject(t)
+	client := s.selected(t, contracts.WithMIDIEventFilter(contracts.MIDIEventFilter{
+		Commands: []contracts.MIDICommand{contracts.NoteOn},
+	}))
+	events := make(chan contracts.MIDI, 16)
+	start(t, client, events)
+
+	s.inject(t, client, contracts.MIDI{Command: 0xB0, Note: 1, Velocity: 1})
+	s.inject(t, client, contracts.MIDI{Command: 0x90, Note: 61, Velocity: 100})
+	if got := receive(t, events); got.Command != 0x90 || got.Note != 61 {
+		t.Errorf("with a note on filter: received %+v; want the note on", got)
+	}
+	s.inject(t, client, contracts.MIDI{Command: 0x93, Note: 62, Velocity: 100})
+	if got := receive(t, events); got.Command != 0x93 || got.Note != 62 {
+		t.Errorf("with a note on filter: received %+v; want the note on of channel 4 with its channel", got)
+	}
+	if filtered := client.Health().EventsFiltered; filtered != 1 {
+		t.Errorf("Health().EventsFiltered = %d; want 1", filtered)
+	}
+
+	client.SetMIDIEventFilter(nil)
+	s.inject(t, client, contracts.MIDI{Command: 0xB0, Note: 2, Velocity: 2})
+	if got := receive(t, events); got.Command != 0xB0 || got.Note != 2 {
+		t.Errorf("without filter: received %+v; want the control change", got)
+	}
+}
+
+// restart checks that the device can be captured again by a new client once the previous
+// one stopped, which requires Stop to release it.
+func (s *suite) restart(t *testing.T) {
+	for round := range 2 {
+		client := s.selected(t)
+		events := make(chan contracts.MIDI, 16)
+		start(t, client, events)
+		if !client.Health().Capturing {
+			t.Fatalf("round %d: capture did not start", round)
+		}
+		if s.config.Inject != nil {
+			s.inject(t, client, contracts.MIDI{Command: 0x90, Note: byte(64 + round), Velocity: 100})
+			if got := receive(t, events); got.Note != byte(64+round) {
+				t.Errorf("round %d: received %+v", round, got)
+			}
+		}
+		if err := client.Stop(); err != nil {
+			t.Fatalf("round %d: Stop: %v", round, err)
+		}
+	}
+}
+
+// overflow checks the overflow policy: capture never blocks on a full channel, and the
+// events that do not fit are dropped and counted.
+func (s *suite) overflow(t *testing.T) {
+	s.requireInject(t)
+	client := s.selected(t)
+	events := make(chan contracts.MIDI, 1)
+	start(t, client, events)
+
+	const sent = 32
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range sent {
+			event := contracts.MIDI{Command: 0x90, Note: byte(i), Velocity: 100}
+			if err := s.config.Inject(client, event); err != nil {
+				t.Errorf("Inject(%+v): %v", event, err)
+				return
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(Timeout):
+		t.Fatal("capture blocked on a full event channel")
+	}
+
+	waitFor(t, "all events to be received or dropped", func() bool {
+		health := client.Health()
+		return health.EventsReceived >= sent && health.EventsDropped+uint64(len(events)) >= sent
+	})
+	if dropped := client.Health().EventsDropped; dropped != sent-1 {
+		t.Errorf("Health().EventsDropped = %d; want %d", dropped, sent-1)
+	}
+	if got := receive(t, events); got.Note != 0 {
+		t.Errorf("kept event %+v; want the first one, since newer events are dropped", got)
+	}
+}
+
+// start starts capturing into events, failing t if the capture does not start.
+func start(t *testing.T, client contracts.ClientMIDI, events chan contracts.MIDI) {
+	t.Helper()
+	if err := client.StartCapture(events); err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+}
+
+// receive returns the next event of events, failing t after Timeout.
+func receive(t *testing.T, events chan contracts.MIDI) contracts.MIDI {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(Timeout):
+		t.Fatal("no event received")
+		return contracts.MIDI{}
+	}
+}
+
+// waitFor polls condition until it holds, failing t after Timeout.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(Timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
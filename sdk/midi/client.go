@@ -1,6 +1,8 @@
 package midi
 
 import (
+	"context"
+
 	"github.com/leandrodaf/midi/sdk/contracts"
 )
 
@@ -18,10 +20,55 @@ func NewMIDIClient(opts ...contracts.Option) (contracts.ClientMIDI, error) {
 		return nil, err
 	}
 
+	var reconnect *reconnectClient
+	if options.AutoReconnect != nil {
+		reconnect = newReconnectClient(&options)
+	}
+	thru := newThruOutput(&options)
+
 	client, err := NewClient(&options)
 	if err != nil {
 		return nil, err
 	}
+	if len(options.SoftwareOutputs) > 0 {
+		client = newSoftwareOutputClient(client, &options)
+	}
+	if thru != nil {
+		if err := thru.open(client, options.Thru.DeviceID); err != nil {
+			client.Stop()
+			return nil, err
+		}
+	}
+
+	if options.Discovery != nil {
+		client = newDiscoveryClient(client, &options)
+	}
+	if reconnect != nil {
+		client = reconnect.start(client)
+	}
+
+	return client, nil
+}
 
+// NewMIDIClientContext creates a new MIDI client like NewMIDIClient, tied to ctx: the
+// client is stopped when ctx is done, and calls to network peers, such as remote servers,
+// are cancelled with it.
+//
+// ctx context.Context: The lifetime of the client.
+// opts ...contracts.Option: A variadic list of option functions to customize the client configuration.
+//
+// Returns:
+//   - contracts.ClientMIDI: An instance of the MIDI client.
+//   - error: An error, if any occurred during the creation of the client, or the error of ctx if it is done.
+func NewMIDIClientContext(ctx context.Context, opts ...contracts.Option) (contracts.ClientMIDI, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	opts = append(opts, func(options *contracts.ClientOptions) { options.Context = ctx })
+	client, err := NewMIDIClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	context.AfterFunc(ctx, func() { client.Stop() })
 	return client, nil
 }
@@ -0,0 +1,55 @@
+package midi
+
+import (
+	"errors"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Endpoint is a device listed by ListAllDevices, together with the ID selecting it.
+type Endpoint struct {
+	ID     int                  // ID for SelectDevice when Device.Direction is contracts.DirectionInput, for SelectOutputDevice otherwise.
+	Device contracts.DeviceInfo // Information about the device.
+}
+
+// ListAllDevices lists the devices of client in both directions: its inputs, as listed by
+// ListDevices, followed by its outputs, as listed by ListOutputDevices. Every device is
+// refreshed from the backend and carries its direction, so tools can show the whole setup
+// at once.
+//
+// client contracts.ClientMIDI: The client whose devices are listed.
+//
+// Returns:
+//   - []Endpoint: The input devices, then the output devices, with their IDs.
+//   - error: contracts.ErrNoDevices if the client has no device in either direction, or
+//     the first error listing them other than contracts.ErrNoDevices and
+//     contracts.ErrOutputUnsupported.
+func ListAllDevices(client contracts.ClientMIDI) ([]Endpoint, error) {
+	inputs, err := client.ListDevices()
+	if err != nil && !errors.Is(err, contracts.ErrNoDevices) {
+		return nil, err
+	}
+	outputs, err := client.ListOutputDevices()
+	if err != nil && !errors.Is(err, contracts.ErrNoDevices) && !errors.Is(err, contracts.ErrOutputUnsupported) {
+		return nil, err
+	}
+	if len(inputs) == 0 && len(outputs) == 0 {
+		return nil, contracts.ErrNoDevices
+	}
+
+	endpoints := make([]Endpoint, 0, len(inputs)+len(outputs))
+	endpoints = appendEndpoints(endpoints, inputs, contracts.DirectionInput)
+	return appendEndpoints(endpoints, outputs, contracts.DirectionOutput), nil
+}
+
+// appendEndpoints appends devices to endpoints with their IDs, marking those without
+// direction with direction.
+func appendEndpoints(endpoints []Endpoint, devices []contracts.DeviceInfo, direction contracts.DeviceDirection) []Endpoint {
+	for id, device := range devices {
+		if device.Direction == 0 {
+			device.Direction = direction
+		}
+		endpoints = append(endpoints, Endpoint{ID: id, Device: device})
+	}
+	return endpoints
+}
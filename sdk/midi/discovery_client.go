@@ -0,0 +1,236 @@
+package midi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midiremote"
+	"github.com/leandrodaf/midi/internal/midi/midirtp"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/discovery"
+)
+
+// ErrUnsupportedEndpoint is returned when a discovered network endpoint is selected
+// but no backend in this build can connect to it.
+var ErrUnsupportedEndpoint = errors.New("unsupported network MIDI endpoint")
+
+// discoveryClient extends a backend with network endpoints found via mDNS. Devices of the
+// backend keep their IDs; discovered endpoints are listed after them.
+type discoveryClient struct {
+	contracts.ClientMIDI // Backend serving the hardware devices.
+
+	options   *contracts.ClientOptions
+	mu        sync.Mutex
+	hardware  int                  // Number of backend devices in the last listing.
+	endpoints []discovery.Endpoint // Network endpoints of the last listing.
+	network   contracts.ClientMIDI // Client of the selected network endpoint, if any.
+}
+
+// newDiscoveryClient wraps client so that ListDevices also reports network endpoints.
+func newDiscoveryClient(client contracts.ClientMIDI, options *contracts.ClientOptions) contracts.ClientMIDI {
+	return &discoveryClient{ClientMIDI: client, options: options}
+}
+
+// ListDevices lists the backend devices followed by the network endpoints found during
+// the configured discovery timeout.
+func (c *discoveryClient) ListDevices() ([]contracts.DeviceInfo, error) {
+	devices, err := c.ClientMIDI.ListDevices()
+
+	ctx, cancel := context.WithTimeout(c.options.Context, c.options.Discovery.Timeout)
+	defer cancel()
+	endpoints, browseErr := discovery.Browse(ctx)
+	if browseErr != nil {
+		c.options.Logger.Warn("Network MIDI discovery failed", c.options.Logger.Field().Error("error", browseErr))
+	}
+
+	c.mu.Lock()
+	c.hardware = len(devices)
+	c.endpoints = endpoints
+	c.mu.Unlock()
+
+	if len(endpoints) == 0 {
+		return devices, err
+	}
+	for _, endpoint := range endpoints {
+		devices = append(devices, contracts.DeviceInfo{
+			Name:         endpoint.Instance,
+			Manufacturer: endpointKind(endpoint.Service),
+			EntityName:   endpoint.Address(),
+		})
+	}
+	return devices, nil
+}
+
+// DeviceCapabilities reports the capabilities of a backend device. For a network endpoint
+// only what is known before connecting is reported: a single input port.
+func (c *discoveryClient) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	c.mu.Lock()
+	index := deviceID - c.hardware
+	isEndpoint := deviceID >= c.hardware && index < len(c.endpoints)
+	c.mu.Unlock()
+
+	if !isEndpoint {
+		return c.ClientMIDI.DeviceCapabilities(deviceID)
+	}
+	return contracts.DeviceCapabilities{InputPorts: 1}, nil
+}
+
+// SelectDevice selects a backend device or connects to a discovered network endpoint.
+// Selecting a remote MIDI server uses the device currently selected on that server.
+func (c *discoveryClient) SelectDevice(deviceID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	index := deviceID - c.hardware
+	if deviceID < c.hardware || index >= len(c.endpoints) {
+		if err := c.stopNetworkLocked(); err != nil {
+			return err
+		}
+		return c.ClientMIDI.SelectDevice(deviceID)
+	}
+
+	// The previous endpoint is left first, as an RTP-MIDI session holds its ports.
+	if err := c.stopNetworkLocked(); err != nil {
+		return err
+	}
+	endpoint := c.endpoints[index]
+	network, err := c.connect(endpoint)
+	if err != nil {
+		return err
+	}
+	c.network = network
+
+	c.options.Logger.Info("Network MIDI endpoint selected",
+		c.options.Logger.Field().String("instance", endpoint.Instance),
+		c.options.Logger.Field().String("address", endpoint.Address()))
+	return nil
+}
+
+// connect creates a client of a discovered endpoint: a remote MIDI server is reached with
+// the TLS, token and keys of the remote configuration, and an AppleMIDI session is invited
+// by an RTP-MIDI client with the session name and port of the RTP-MIDI configuration.
+func (c *discoveryClient) connect(endpoint discovery.Endpoint) (contracts.ClientMIDI, error) {
+	options := *c.options
+	switch endpoint.Service {
+	case discovery.RemoteService:
+		config := contracts.RemoteConfig{}
+		if options.RemoteConfig != nil {
+			config = *options.RemoteConfig
+		}
+		config.Address = endpoint.Address()
+		options.RemoteConfig = &config
+		return midiremote.NewMIDIClient(&options)
+	case discovery.AppleMIDIService:
+		config := contracts.RTPConfig{}
+		if options.RTPConfig != nil {
+			config = *options.RTPConfig
+		}
+		config.Peers, config.BrowseTimeout = []string{endpoint.Address()}, -1
+		options.RTPConfig = &config
+		network, err := midirtp.NewMIDIClient(&options)
+		if err != nil {
+			return nil, err
+		}
+		if err := network.SelectDevice(0); err != nil {
+			return nil, errors.Join(err, network.Stop())
+		}
+		return network, nil
+	default:
+		return nil, fmt.Errorf("%w: %s (%s)", ErrUnsupportedEndpoint, endpoint.Instance, endpoint.Service)
+	}
+}
+
+// StartCapture starts capturing from the selected network endpoint or, if none is selected, from the backend.
+func (c *discoveryClient) StartCapture(eventChannel chan contracts.MIDI) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.network != nil {
+		return c.network.StartCapture(eventChannel)
+	}
+	return c.ClientMIDI.StartCapture(eventChannel)
+}
+
+// StartCaptureFunc starts capturing from the selected network endpoint or, if none is
+// selected, from the backend, calling handler with each event.
+func (c *discoveryClient) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.network != nil {
+		return c.network.StartCaptureFunc(handler)
+	}
+	return c.ClientMIDI.StartCaptureFunc(handler)
+}
+
+// StartCaptureContext starts capturing like StartCapture and stops the backend and the
+// selected network endpoint once ctx is done.
+func (c *discoveryClient) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, c.StartCapture, c.Stop)
+}
+
+// SetMIDIEventFilter replaces the event filter of the backend and of the selected network endpoint.
+func (c *discoveryClient) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.options.MIDIEventFilter = filter
+	if c.network != nil {
+		c.network.SetMIDIEventFilter(filter)
+	}
+	c.ClientMIDI.SetMIDIEventFilter(filter)
+}
+
+// Health reports the health of the selected network endpoint or, if none is selected, of the backend.
+func (c *discoveryClient) Health() contracts.Health {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.network != nil {
+		return c.network.Health()
+	}
+	return c.ClientMIDI.Health()
+}
+
+// Stats reports the statistics of the selected network endpoint or, if none is selected, of the backend.
+func (c *discoveryClient) Stats() contracts.Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.network != nil {
+		return c.network.Stats()
+	}
+	return c.ClientMIDI.Stats()
+}
+
+// Stop stops the network endpoint client, if any, and the backend.
+func (c *discoveryClient) Stop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return errors.Join(c.stopNetworkLocked(), c.ClientMIDI.Stop())
+}
+
+// stopNetworkLocked stops the client of the selected network endpoint. The caller must hold c.mu.
+func (c *discoveryClient) stopNetworkLocked() error {
+	if c.network == nil {
+		return nil
+	}
+	err := c.network.Stop()
+	c.network = nil
+	return err
+}
+
+// endpointKind describes a discovered service type in DeviceInfo.Manufacturer.
+func endpointKind(service string) string {
+	switch service {
+	case discovery.AppleMIDIService:
+		return "AppleMIDI (RTP-MIDI)"
+	case discovery.RemoteService:
+		return "Remote MIDI server"
+	default:
+		return service
+	}
+}
@@ -0,0 +1,215 @@
+package midi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// InputGroup captures several devices at once and merges their events into one channel,
+// such as a keyboard and a pad controller played together. Every event carries the ID of
+// the device it came from in SourceDeviceID. Each device is opened by a client of its
+// own, created with the options of the group, so the group works with every backend. With
+// contracts.WithDeduplication, the clients share one deduplication stage, so identical
+// events arriving from different devices of the group are delivered once.
+type InputGroup struct {
+	deviceIDs []int
+	clients   []contracts.ClientMIDI // Client of each device, in the order of deviceIDs.
+
+	mu       sync.Mutex
+	stopOnce sync.Once
+}
+
+// NewInputGroup creates a client for each device with opts, as NewMIDIClient does, and
+// selects the device on it.
+//
+// deviceIDs []int: The IDs of the devices to capture, as listed by ListDevices.
+// opts ...contracts.Option: A variadic list of option functions applied to every client of the group.
+//
+// Returns:
+//   - *InputGroup: The group, ready to capture.
+//   - error: The error creating a client or selecting a device, if any; the clients already created are stopped.
+func NewInputGroup(deviceIDs []int, opts ...contracts.Option) (*InputGroup, error) {
+	return newInputGroup(deviceIDs, opts, NewMIDIClient)
+}
+
+// NewInputGroupContext creates a group like NewInputGroup, whose clients are tied to ctx
+// as by NewMIDIClientContext.
+//
+// ctx context.Context: The lifetime of the group.
+// deviceIDs []int: The IDs of the devices to capture, as listed by ListDevices.
+// opts ...contracts.Option: A variadic list of option functions applied to every client of the group.
+//
+// Returns:
+//   - *InputGroup: The group, ready to capture.
+//   - error: The error creating a client or selecting a device, if any, or the error of ctx if it is done.
+func NewInputGroupContext(ctx context.Context, deviceIDs []int, opts ...contracts.Option) (*InputGroup, error) {
+	return newInputGroup(deviceIDs, opts, func(opts ...contracts.Option) (contracts.ClientMIDI, error) {
+		return NewMIDIClientContext(ctx, opts...)
+	})
+}
+
+// newInputGroup creates a group whose clients are created by newClient with opts. With a
+// deduplication window, the clients share the Dedup comparing the events of their devices.
+func newInputGroup(deviceIDs []int, opts []contracts.Option, newClient func(opts ...contracts.Option) (contracts.ClientMIDI, error)) (*InputGroup, error) {
+	if len(deviceIDs) == 0 {
+		return nil, fmt.Errorf("%w: no device IDs given", contracts.ErrInvalidDevice)
+	}
+	seen := make(map[int]bool, len(deviceIDs))
+	for _, id := range deviceIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: device %d given more than once", contracts.ErrInvalidDevice, id)
+		}
+		seen[id] = true
+	}
+
+	var options contracts.ClientOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if dedup := dispatch.NewDedup(options.DedupWindow); dedup != nil {
+		opts = append(slices.Clip(opts), func(options *contracts.ClientOptions) {
+			options.Duplicate = dedup.Duplicate
+		})
+	}
+
+	g := &InputGroup{deviceIDs: append([]int(nil), deviceIDs...)}
+	for _, id := range deviceIDs {
+		client, err := newClient(opts...)
+		if err != nil {
+			return nil, errors.Join(err, g.Stop())
+		}
+		g.clients = append(g.clients, client)
+		if err := client.SelectDevice(id); err != nil {
+			return nil, errors.Join(fmt.Errorf("error selecting device %d: %w", id, err), g.Stop())
+		}
+	}
+	return g, nil
+}
+
+// DeviceIDs returns the IDs of the devices of the group.
+func (g *InputGroup) DeviceIDs() []int {
+	return append([]int(nil), g.deviceIDs...)
+}
+
+// Client returns the client capturing the device with the given ID, for its health, its
+// capabilities or its hooks, or nil if the device is not in the group.
+func (g *InputGroup) Client(deviceID int) contracts.ClientMIDI {
+	for i, id := range g.deviceIDs {
+		if id == deviceID {
+			return g.clients[i]
+		}
+	}
+	return nil
+}
+
+// StartCapture starts capturing every device of the group into eventChannel. Events that
+// do not fit in the channel are handled by the overflow policy of the options, device by
+// device, and counted in Stats. Devices that fail to start do not prevent the others from
+// capturing; their errors are joined in the returned error.
+func (g *InputGroup) StartCapture(eventChannel chan contracts.MIDI) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var errs []error
+	for i, client := range g.clients {
+		if err := client.StartCapture(eventChannel); err != nil {
+			errs = append(errs, fmt.Errorf("device %d: %w", g.deviceIDs[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StartCaptureFunc starts capturing every device of the group and calls handler with each
+// event. The clients call it from their own workers, so handler must be safe for
+// concurrent use. Errors are reported as by StartCapture.
+func (g *InputGroup) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var errs []error
+	for i, client := range g.clients {
+		if err := client.StartCaptureFunc(handler); err != nil {
+			errs = append(errs, fmt.Errorf("device %d: %w", g.deviceIDs[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetMIDIEventFilter replaces the event filter of every device of the group.
+func (g *InputGroup) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, client := range g.clients {
+		client.SetMIDIEventFilter(filter)
+	}
+}
+
+// Stats reports the event traffic statistics of every device of the group. The counters
+// are summed; the channel occupancy is that of the fullest channel, and the handler times
+// are the slowest of the devices, whose clients run the handler on workers of their own.
+func (g *InputGroup) Stats() contracts.Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var stats contracts.Stats
+	for _, client := range g.clients {
+		clientStats := client.Stats()
+		stats.Devices = append(stats.Devices, clientStats.Devices...)
+		stats.Received += clientStats.Received
+		stats.Filtered += clientStats.Filtered
+		stats.Dropped += clientStats.Dropped
+		stats.Coalesced += clientStats.Coalesced
+		stats.SysExBytes += clientStats.SysExBytes
+		if clientStats.Occupancy() >= stats.Occupancy() {
+			stats.Queued, stats.Capacity = clientStats.Queued, clientStats.Capacity
+		}
+		stats.Callbacks = slowest(stats.Callbacks, clientStats.Callbacks)
+	}
+	return stats
+}
+
+// slowest merges the handler times of two clients into their worst case: the counts are
+// summed, the mean is weighted by them and the percentiles are the highest.
+func slowest(a, b contracts.LatencyStats) contracts.LatencyStats {
+	if a.Count == 0 || b.Count == 0 {
+		if a.Count == 0 {
+			return b
+		}
+		return a
+	}
+	count := a.Count + b.Count
+	return contracts.LatencyStats{
+		Count: count,
+		Min:   min(a.Min, b.Min),
+		Max:   max(a.Max, b.Max),
+		Mean:  (a.Mean*time.Duration(a.Count) + b.Mean*time.Duration(b.Count)) / time.Duration(count),
+		P50:   max(a.P50, b.P50),
+		P95:   max(a.P95, b.P95),
+		P99:   max(a.P99, b.P99),
+	}
+}
+
+// Stop stops the clients of the group. No event is delivered once it returns, so the
+// channel passed to StartCapture can then be closed.
+//
+// Returns:
+//   - error: The errors stopping the clients, joined, or nil.
+func (g *InputGroup) Stop() error {
+	var err error
+	g.stopOnce.Do(func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		errs := make([]error, 0, len(g.clients))
+		for _, client := range g.clients {
+			errs = append(errs, client.Stop())
+		}
+		err = errors.Join(errs...)
+	})
+	return err
+}
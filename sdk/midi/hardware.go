@@ -0,0 +1,25 @@
+//go:build !nomidihw && !js
+// +build !nomidihw,!js
+
+package midi
+
+import (
+	"github.com/leandrodaf/midi/internal/midi/midible"
+	"github.com/leandrodaf/midi/internal/midi/mididarwin"
+	"github.com/leandrodaf/midi/internal/midi/midijack"
+	"github.com/leandrodaf/midi/internal/midi/midiserial"
+	"github.com/leandrodaf/midi/internal/midi/midiusb"
+	"github.com/leandrodaf/midi/internal/midi/midiwindows"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// init registers the backends accessing MIDI hardware, which builds with the nomidihw tag
+// leave out.
+func init() {
+	clientInitializers["darwin"] = mididarwin.NewMIDIClient                 // macOS (Darwin) MIDI client initializer.
+	clientInitializers["windows"] = midiwindows.NewMIDIClient               // Windows MIDI client initializer.
+	backendInitializers[contracts.BackendSerial] = midiserial.NewMIDIClient // MIDI byte stream on serial ports.
+	backendInitializers[contracts.BackendUSB] = midiusb.NewMIDIClient       // USB MIDI class devices through libusb.
+	backendInitializers[contracts.BackendBLE] = midible.NewMIDIClient       // Bluetooth LE MIDI peripherals.
+	backendInitializers[contracts.BackendJACK] = midijack.NewMIDIClient     // MIDI ports of a JACK or PipeWire graph.
+}
@@ -0,0 +1,30 @@
+//go:build nomidihw
+// +build nomidihw
+
+package midi
+
+import (
+	"fmt"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// init registers placeholders for the backends accessing MIDI hardware, so that selecting
+// them reports ErrHardwareExcluded rather than an unknown backend or operating system.
+// Leaving their packages out lets the module build without cgo on every platform.
+func init() {
+	clientInitializers["darwin"] = excluded("darwin")
+	clientInitializers["windows"] = excluded("windows")
+	clientInitializers["js"] = excluded("js")
+	backendInitializers[contracts.BackendSerial] = excluded(contracts.BackendSerial)
+	backendInitializers[contracts.BackendUSB] = excluded(contracts.BackendUSB)
+	backendInitializers[contracts.BackendBLE] = excluded(contracts.BackendBLE)
+	backendInitializers[contracts.BackendJACK] = excluded(contracts.BackendJACK)
+}
+
+// excluded returns an initializer reporting that the backend is excluded from the build.
+func excluded(backend string) func(*contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	return func(*contracts.ClientOptions) (contracts.ClientMIDI, error) {
+		return nil, fmt.Errorf("%w: %s", ErrHardwareExcluded, backend)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build js && !nomidihw
+// +build js,!nomidihw
+
+package midi
+
+import (
+	"fmt"
+
+	"github.com/leandrodaf/midi/internal/midi/midiweb"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// init registers the Web MIDI API of the browser as the native backend of js/wasm builds.
+// The serial, USB, BLE and JACK backends need system access browsers do not give.
+func init() {
+	clientInitializers["js"] = midiweb.NewMIDIClient // Web MIDI API of the browser.
+	for _, backend := range []string{contracts.BackendSerial, contracts.BackendUSB, contracts.BackendBLE, contracts.BackendJACK} {
+		backendInitializers[backend] = unsupportedInBrowser(backend)
+	}
+}
+
+// unsupportedInBrowser returns an initializer reporting that the backend does not run in
+// a browser.
+func unsupportedInBrowser(backend string) func(*contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	return func(*contracts.ClientOptions) (contracts.ClientMIDI, error) {
+		return nil, fmt.Errorf("%w: the %s backend does not run on js", ErrUnsupportedOS, backend)
+	}
+}
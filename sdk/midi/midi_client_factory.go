@@ -5,29 +5,55 @@ import (
 	"fmt"
 	"runtime"
 
-	"github.com/leandrodaf/midi/internal/midi/mididarwin"
-	"github.com/leandrodaf/midi/internal/midi/midiwindows"
+	"github.com/leandrodaf/midi/internal/midi/midiloopback"
+	"github.com/leandrodaf/midi/internal/midi/midiremote"
+	"github.com/leandrodaf/midi/internal/midi/midireplay"
+	"github.com/leandrodaf/midi/internal/midi/midirtp"
 	"github.com/leandrodaf/midi/sdk/contracts"
 )
 
 // ErrUnsupportedOS is returned when the operating system is not supported by the MIDI client.
 var ErrUnsupportedOS = errors.New("unsupported operating system")
 
-// clientInitializers maps OS names to corresponding MIDI client initializers.
-var clientInitializers = map[string]func(*contracts.ClientOptions) (contracts.ClientMIDI, error){
-	"darwin":  mididarwin.NewMIDIClient,  // macOS (Darwin) MIDI client initializer.
-	"windows": midiwindows.NewMIDIClient, // Windows MIDI client initializer.
+// ErrUnknownBackend is returned when the backend selected with contracts.WithBackend does not exist.
+var ErrUnknownBackend = errors.New("unknown MIDI backend")
+
+// ErrHardwareExcluded is returned when the selected backend accesses MIDI hardware and the
+// module was built with the nomidihw tag, which excludes those backends.
+var ErrHardwareExcluded = errors.New("hardware MIDI backends are excluded from this build (nomidihw tag)")
+
+// clientInitializers maps OS names to corresponding MIDI client initializers. The native
+// backends are registered by hardware.go.
+var clientInitializers = map[string]func(*contracts.ClientOptions) (contracts.ClientMIDI, error){}
+
+// backendInitializers maps backend names to MIDI client initializers that do not depend on
+// the OS. The serial, USB and BLE backends are registered by hardware.go.
+var backendInitializers = map[string]func(*contracts.ClientOptions) (contracts.ClientMIDI, error){
+	contracts.BackendRemote:   midiremote.NewMIDIClient,   // gRPC client for a remote MIDI server.
+	contracts.BackendLoopback: midiloopback.NewMIDIClient, // Device fed by the application itself.
+	contracts.BackendReplay:   midireplay.NewMIDIClient,   // Recorded events played back as a device.
+	contracts.BackendRTP:      midirtp.NewMIDIClient,      // RTP-MIDI (AppleMIDI) sessions on the network.
 }
 
-// NewClient initializes a MIDI client based on the current operating system.
-// It supports macOS (Darwin) and Windows, returning ErrUnsupportedOS if the OS is unsupported.
+// NewClient initializes a MIDI client based on the selected backend or, when none is
+// selected, on the current operating system.
+// It supports macOS (Darwin), Windows and browsers (js/wasm, through the Web MIDI API),
+// returning ErrUnsupportedOS if the OS is unsupported,
+// and ErrHardwareExcluded for the native, serial, USB and BLE backends in nomidihw builds.
 //
 // opts *contracts.ClientOptions: Configuration options for the MIDI client.
 //
 // Returns:
 //   - contracts.ClientMIDI: An instance of the MIDI client.
-//   - error: An error if the operating system is unsupported or if initialization fails.
+//   - error: An error if the backend or operating system is unsupported or if initialization fails.
 func NewClient(opts *contracts.ClientOptions) (contracts.ClientMIDI, error) {
+	if opts.Backend != "" {
+		if initializer, exists := backendInitializers[opts.Backend]; exists {
+			return initializer(opts)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, opts.Backend)
+	}
+
 	if initializer, exists := clientInitializers[runtime.GOOS]; exists {
 		return initializer(opts)
 	}
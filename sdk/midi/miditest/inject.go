@@ -0,0 +1,179 @@
+package miditest
+
+import (
+	"slices"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Inject delivers event as if the selected device had sent it, tagged with the device ID.
+// The timestamp is kept as given, so tests control it. It returns
+// contracts.ErrNotCapturing when capture is not running.
+func (c *Client) Inject(event contracts.MIDI) error {
+	source, err := c.capturingSource()
+	if err != nil {
+		return err
+	}
+	c.injectMu.Lock()
+	defer c.injectMu.Unlock()
+	source.Dispatch(event)
+	return nil
+}
+
+// InjectAll injects events in order, stopping at the first error.
+func (c *Client) InjectAll(events ...contracts.MIDI) error {
+	for _, event := range events {
+		if err := c.Inject(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InjectSysEx delivers a SysEx message, from its 0xF0 to its 0xF7 byte, as if the selected
+// device had sent it, to the SysEx channel or handler of the client options. It returns
+// contracts.ErrNotCapturing when capture is not running.
+func (c *Client) InjectSysEx(timestamp uint64, data []byte) error {
+	source, err := c.capturingSource()
+	if err != nil {
+		return err
+	}
+	source.DispatchSysEx(contracts.SysExEvent{Timestamp: timestamp, Data: slices.Clone(data)})
+	return nil
+}
+
+// InjectMIDI2 delivers a MIDI 2.0 event as if the selected device had sent it, like the
+// CoreMIDI backend receiving the MIDI 2.0 protocol: to the MIDI 2.0 channel, and converted
+// to MIDI 1.0 to the event channel. It returns contracts.ErrNotCapturing when capture is
+// not running.
+func (c *Client) InjectMIDI2(event contracts.MIDI2Event) error {
+	source, err := c.capturingSource()
+	if err != nil {
+		return err
+	}
+	c.injectMu.Lock()
+	defer c.injectMu.Unlock()
+	source.DispatchMIDI2(event)
+	return nil
+}
+
+// capturingSource returns the source of the selected device, or contracts.ErrNotCapturing
+// when capture is not running.
+func (c *Client) capturingSource() (*dispatch.Source, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.capturing || c.source == nil {
+		return nil, contracts.ErrNotCapturing
+	}
+	return c.source, nil
+}
+
+// Disconnect simulates the selected device going away: the hooks and automatic
+// reconnection are told it was lost for the reason err, contracts.ErrDeviceDisconnected
+// when nil, and injected events are rejected until a device is selected again.
+func (c *Client) Disconnect(err error) {
+	if err == nil {
+		err = contracts.ErrDeviceDisconnected
+	}
+	c.mu.Lock()
+	source := c.source
+	c.source = nil
+	c.deviceID = -1
+	c.mu.Unlock()
+
+	if source != nil {
+		source.Lost(err)
+		source.Close()
+	}
+}
+
+// SetDevices replaces the devices listed by ListDevices, simulating devices being plugged
+// in or removed, and notifies the functions registered with NotifyDeviceChanges. The
+// selected device stays selected.
+func (c *Client) SetDevices(devices ...contracts.DeviceInfo) {
+	c.mu.Lock()
+	c.devices = slices.Clone(devices)
+	notifiers := c.notifiersLocked()
+	c.mu.Unlock()
+
+	for _, notify := range notifiers {
+		notify()
+	}
+}
+
+// SetOutputDevices replaces the devices listed by ListOutputDevices and notifies the
+// functions registered with NotifyDeviceChanges.
+func (c *Client) SetOutputDevices(devices ...contracts.DeviceInfo) {
+	c.mu.Lock()
+	c.outputs = slices.Clone(devices)
+	notifiers := c.notifiersLocked()
+	c.mu.Unlock()
+
+	for _, notify := range notifiers {
+		notify()
+	}
+}
+
+// notifiersLocked returns the functions registered with NotifyDeviceChanges. The caller
+// must hold c.mu.
+func (c *Client) notifiersLocked() []func() {
+	notifiers := make([]func(), 0, len(c.notifiers))
+	for _, notify := range c.notifiers {
+		notifiers = append(notifiers, notify)
+	}
+	return notifiers
+}
+
+// SetSendError makes Send and SendSysEx return err instead of recording messages,
+// simulating a failing output; nil restores recording.
+func (c *Client) SetSendError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendErr = err
+}
+
+// SetSelectError makes SelectDevice and SelectOutputDevice return err, simulating a device
+// held by another application or a failing driver; nil restores selection.
+func (c *Client) SetSelectError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.selectErr = err
+}
+
+// Sent returns the messages passed to Send since the client was created or ClearSent was
+// called, in order.
+func (c *Client) Sent() []contracts.MIDI {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.sent)
+}
+
+// WaitSent waits up to timeout for at least n messages to be sent, for applications
+// sending from their own goroutines. It returns the messages sent so far and whether
+// there were n of them.
+func (c *Client) WaitSent(n int, timeout time.Duration) ([]contracts.MIDI, bool) {
+	ok := c.wait(timeout, func() bool { return len(c.sent) >= n })
+	return c.Sent(), ok
+}
+
+// SentSysEx returns the SysEx messages passed to SendSysEx since the client was created
+// or ClearSent was called, in order.
+func (c *Client) SentSysEx() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sent := make([][]byte, len(c.sentSysEx))
+	for i, data := range c.sentSysEx {
+		sent[i] = slices.Clone(data)
+	}
+	return sent
+}
+
+// ClearSent forgets the messages and SysEx messages sent so far.
+func (c *Client) ClearSent() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = nil
+	c.sentSysEx = nil
+}
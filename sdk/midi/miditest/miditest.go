@@ -0,0 +1,339 @@
+// Package miditest provides an in-memory contracts.ClientMIDI for unit tests of
+// applications, so they run without MIDI hardware. Tests choose the devices the client
+// lists, inject the events and SysEx messages a device would send, simulate unplugging it,
+// and check the messages the application sent:
+//
+//	client, err := miditest.NewClient(miditest.WithDevices(contracts.DeviceInfo{Name: "KeyStep"}))
+//	go app.Run(client) // Selects the device, starts capture and answers notes.
+//	client.WaitCapturing(time.Second)
+//	client.Inject(contracts.MIDI{Command: 0x90, Note: 60, Velocity: 100})
+//	sent, ok := client.WaitSent(1, time.Second)
+//
+// Injected events go through the same dispatcher as those of the real backends, so event
+// filters, the overflow policy, hooks and health counters behave as in production.
+package miditest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/logger"
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/midi"
+)
+
+// backendName identifies the client in health reports and profiles.
+const backendName = "miditest"
+
+// Client is an in-memory contracts.ClientMIDI. Its methods are safe for concurrent use, so
+// a test can inject events while the application under test captures them.
+type Client struct {
+	dispatcher   *dispatch.Dispatcher                 // Filters injected events and delivers them to the event channel.
+	mu           sync.Mutex                           // Protects the fields below.
+	changed      *sync.Cond                           // Signaled when sent or capturing change.
+	devices      []contracts.DeviceInfo               // Devices listed by ListDevices.
+	outputs      []contracts.DeviceInfo               // Devices listed by ListOutputDevices.
+	capabilities map[int]contracts.DeviceCapabilities // Capabilities by device ID.
+	deviceID     int                                  // ID of the selected device, or -1 when none is selected.
+	source       *dispatch.Source                     // Dispatcher entry of the selected device; nil when none is selected.
+	capturing    bool                                 // Whether a consumer is attached.
+	outputID     int                                  // ID of the selected output device, or -1 when none is selected.
+	sent         []contracts.MIDI                     // Messages passed to Send.
+	sentSysEx    [][]byte                             // Messages passed to SendSysEx.
+	sendErr      error                                // Error returned by Send instead of recording; nil records.
+	selectErr    error                                // Error returned by SelectDevice and SelectOutputDevice; nil selects.
+	notifiers    map[int]func()                       // Functions registered with NotifyDeviceChanges, by registration.
+	lastNotifier int                                  // Key of the last registered notifier.
+	virtuals     map[string]*virtualEndpoint          // Virtual endpoints by name.
+	stopped      bool                                 // Whether Stop was called.
+	injectMu     sync.Mutex                           // Serializes the events passed to the source, which has a single producer.
+}
+
+// NewClient creates an in-memory client.
+//
+// opts ...Option: A variadic list of option functions to customize the client.
+//
+// Returns:
+//   - *Client: The client, without selected device.
+//   - error: A *midi.OptionsError if the client options are invalid.
+func NewClient(opts ...Option) (*Client, error) {
+	options := applyDefaultOptions(opts...)
+	clientOptions, err := midi.ApplyOptions(append([]contracts.Option{contracts.WithLogger(logger.NewNopLogger())}, options.Client...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		dispatcher:   dispatch.New(backendName, &clientOptions),
+		devices:      slices.Clone(options.Devices),
+		outputs:      slices.Clone(options.OutputDevices),
+		capabilities: options.Capabilities,
+		deviceID:     -1,
+		outputID:     -1,
+		notifiers:    make(map[int]func()),
+		virtuals:     make(map[string]*virtualEndpoint),
+	}
+	c.changed = sync.NewCond(&c.mu)
+	return c, nil
+}
+
+// ListDevices lists the devices set with WithDevices or SetDevices.
+func (c *Client) ListDevices() ([]contracts.DeviceInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.devices) == 0 {
+		return nil, contracts.ErrNoDevices
+	}
+	return slices.Clone(c.devices), nil
+}
+
+// DeviceCapabilities reports the capabilities set with WithCapabilities, or one input
+// port with SysEx input for other devices.
+func (c *Client) DeviceCapabilities(deviceID int) (contracts.DeviceCapabilities, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if deviceID < 0 || deviceID >= len(c.devices) {
+		return contracts.DeviceCapabilities{}, fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	if capabilities, ok := c.capabilities[deviceID]; ok {
+		return capabilities, nil
+	}
+	return contracts.DeviceCapabilities{SysExIn: true, InputPorts: 1}, nil
+}
+
+// SelectDevice selects a listed device, whose events Inject then delivers. It returns the
+// error set with SetSelectError, if any.
+func (c *Client) SelectDevice(deviceID int) error {
+	c.mu.Lock()
+	if c.selectErr != nil {
+		err := c.selectErr
+		c.mu.Unlock()
+		return err
+	}
+	if deviceID < 0 || deviceID >= len(c.devices) {
+		c.mu.Unlock()
+		return fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	if c.source != nil {
+		c.source.Close()
+	}
+	device := c.devices[deviceID]
+	c.deviceID = deviceID
+	c.source = c.dispatcher.AddSource(deviceID, device)
+	c.mu.Unlock()
+
+	c.dispatcher.DeviceSelected(deviceID, device)
+	return nil
+}
+
+// SelectedDevice returns the ID of the selected device, or -1 when none is selected.
+func (c *Client) SelectedDevice() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deviceID
+}
+
+// StartCapture delivers the events injected from then on to eventChannel. Like the real
+// backends, it fails with contracts.ErrNoDeviceSelected without a selected device or after
+// Stop, and with contracts.ErrCaptureStarted when capture already started.
+func (c *Client) StartCapture(eventChannel chan contracts.MIDI) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.dispatcher.CheckChannel(eventChannel); err != nil {
+		return err
+	}
+	switch {
+	case c.source == nil || c.stopped:
+		return contracts.ErrNoDeviceSelected
+	case c.capturing:
+		return contracts.ErrCaptureStarted
+	}
+	c.dispatcher.Attach(eventChannel)
+	c.capturing = true
+	c.changed.Broadcast()
+	return nil
+}
+
+// StartCaptureFunc starts capturing MIDI events and calls handler with each of them, on
+// the workers set with contracts.WithCaptureWorkers.
+func (c *Client) StartCaptureFunc(handler func(contracts.MIDI)) error {
+	return c.StartCapture(c.dispatcher.Callback(handler))
+}
+
+// StartCaptureContext starts capturing MIDI events into eventChannel and stops the client
+// once ctx is done.
+func (c *Client) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, c.StartCapture, c.Stop)
+}
+
+// Capturing reports whether the application started capture and did not stop the client.
+func (c *Client) Capturing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.capturing
+}
+
+// WaitCapturing waits up to timeout for the application to start capture, and reports
+// whether it did.
+func (c *Client) WaitCapturing(timeout time.Duration) bool {
+	return c.wait(timeout, func() bool { return c.capturing })
+}
+
+// SetMIDIEventFilter replaces the event filter applied to injected events.
+func (c *Client) SetMIDIEventFilter(filter *contracts.MIDIEventFilter) {
+	c.dispatcher.SetFilter(filter)
+}
+
+// Health reports the dispatcher counters together with the selected device.
+func (c *Client) Health() contracts.Health {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	health := c.dispatcher.Health()
+	health.Connected = c.source != nil
+	health.DeviceID = c.deviceID
+	if c.deviceID >= 0 && c.deviceID < len(c.devices) {
+		health.Device = c.devices[c.deviceID]
+	}
+	return health
+}
+
+// Stats reports the traffic statistics of the injected events.
+func (c *Client) Stats() contracts.Stats {
+	return c.dispatcher.Stats()
+}
+
+// ListOutputDevices lists the devices set with WithOutputDevices or SetOutputDevices.
+func (c *Client) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.outputs) == 0 {
+		return nil, contracts.ErrNoDevices
+	}
+	return slices.Clone(c.outputs), nil
+}
+
+// SelectOutputDevice selects a listed output device for Send. It returns the error set
+// with SetSelectError, if any.
+func (c *Client) SelectOutputDevice(deviceID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.selectErr != nil {
+		return c.selectErr
+	}
+	if deviceID < 0 || deviceID >= len(c.outputs) {
+		return fmt.Errorf("%w: %d", contracts.ErrInvalidDevice, deviceID)
+	}
+	c.outputID = deviceID
+	return nil
+}
+
+// Send records a message for Sent. Like the real backends, it returns
+// contracts.ErrNoOutputDevice before SelectOutputDevice and contracts.ErrMalformedMessage
+// for messages that cannot be encoded; otherwise it returns the error set with
+// SetSendError, if any, without recording.
+func (c *Client) Send(event contracts.MIDI) error {
+	if _, err := midistream.Encode(event); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.outputID < 0 {
+		return contracts.ErrNoOutputDevice
+	}
+	if c.sendErr != nil {
+		return c.sendErr
+	}
+	c.sent = append(c.sent, event)
+	c.changed.Broadcast()
+	return nil
+}
+
+// SendSysEx records a SysEx message, returned by SentSysEx, like Send records messages:
+// it returns contracts.ErrNoOutputDevice before SelectOutputDevice and
+// contracts.ErrMalformedMessage for data that is not a complete SysEx message; otherwise
+// it returns the error set with SetSendError, if any, without recording.
+func (c *Client) SendSysEx(data []byte) error {
+	if err := midistream.CheckSysEx(data); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.outputID < 0 {
+		return contracts.ErrNoOutputDevice
+	}
+	if c.sendErr != nil {
+		return c.sendErr
+	}
+	c.sentSysEx = append(c.sentSysEx, slices.Clone(data))
+	c.changed.Broadcast()
+	return nil
+}
+
+// Stop ends capture and closes the virtual endpoints. Injected events are rejected
+// afterwards.
+func (c *Client) Stop() error {
+	c.mu.Lock()
+	source := c.source
+	c.source = nil
+	c.capturing = false
+	c.stopped = true
+	c.deviceID, c.outputID = -1, -1
+	virtuals := c.virtuals
+	c.virtuals = make(map[string]*virtualEndpoint)
+	c.changed.Broadcast()
+	c.mu.Unlock()
+
+	c.dispatcher.Detach()
+	if source != nil {
+		source.Close()
+	}
+	for _, virtual := range virtuals {
+		virtual.Close()
+	}
+	return nil
+}
+
+// NotifyDeviceChanges calls changed whenever SetDevices or SetOutputDevices changes the
+// devices, until stop is called.
+func (c *Client) NotifyDeviceChanges(changed func()) (stop func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastNotifier++
+	key := c.lastNotifier
+	c.notifiers[key] = changed
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.notifiers, key)
+	}
+}
+
+// wait waits up to timeout for done, called with c.mu held, to return true, and reports
+// whether it did.
+func (c *Client) wait(timeout time.Duration, done func() bool) bool {
+	timer := time.AfterFunc(timeout, func() {
+		c.mu.Lock()
+		c.changed.Broadcast()
+		c.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(timeout)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for !done() {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		c.changed.Wait()
+	}
+	return true
+}
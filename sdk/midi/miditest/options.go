@@ -0,0 +1,63 @@
+package miditest
+
+import "github.com/leandrodaf/midi/sdk/contracts"
+
+// Options holds the configuration of a Client.
+type Options struct {
+	Devices       []contracts.DeviceInfo               // Devices listed by ListDevices; one named "Test Device" by default.
+	OutputDevices []contracts.DeviceInfo               // Devices listed by ListOutputDevices; one named "Test Output" by default.
+	Capabilities  map[int]contracts.DeviceCapabilities // Capabilities reported by DeviceCapabilities, by device ID.
+	Client        []contracts.Option                   // Client options, such as filters, hooks and the overflow policy.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithDevices sets the devices listed by ListDevices, whose IDs are their indexes. An empty
+// list makes ListDevices fail with contracts.ErrNoDevices.
+func WithDevices(devices ...contracts.DeviceInfo) Option {
+	return func(opts *Options) {
+		opts.Devices = devices
+	}
+}
+
+// WithOutputDevices sets the devices listed by ListOutputDevices, whose IDs are their
+// indexes. An empty list makes ListOutputDevices fail with contracts.ErrNoDevices.
+func WithOutputDevices(devices ...contracts.DeviceInfo) Option {
+	return func(opts *Options) {
+		opts.OutputDevices = devices
+	}
+}
+
+// WithCapabilities sets what DeviceCapabilities reports for the device with the given ID.
+// Devices without capabilities report one input port and SysEx input.
+func WithCapabilities(deviceID int, capabilities contracts.DeviceCapabilities) Option {
+	return func(opts *Options) {
+		if opts.Capabilities == nil {
+			opts.Capabilities = make(map[int]contracts.DeviceCapabilities)
+		}
+		opts.Capabilities[deviceID] = capabilities
+	}
+}
+
+// WithClientOptions applies client options, as passed to midi.NewMIDIClient, to the
+// delivery of injected events: event filters and predicates, the overflow policy, SysEx
+// and MIDI 2.0 channels, error channels and hooks behave as with the real backends. The
+// client logs nothing unless a logger is set.
+func WithClientOptions(opts ...contracts.Option) Option {
+	return func(options *Options) {
+		options.Client = append(options.Client, opts...)
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{
+		Devices:       []contracts.DeviceInfo{{Name: "Test Device"}},
+		OutputDevices: []contracts.DeviceInfo{{Name: "Test Output"}},
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
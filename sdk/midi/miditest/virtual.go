@@ -0,0 +1,114 @@
+package miditest
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// virtualEndpoint is a virtual source or destination published by the client.
+type virtualEndpoint struct {
+	client      *Client
+	name        string
+	destination chan contracts.MIDI // Channel of a destination; nil for a source.
+	mu          sync.Mutex          // Protects sent and closed.
+	sent        []contracts.MIDI    // Messages sent through a source.
+	closed      bool
+}
+
+// CreateVirtualSource publishes a source named name, whose sent messages VirtualSent
+// returns. It fails with contracts.ErrInvalidDevice if an endpoint with that name exists.
+func (c *Client) CreateVirtualSource(name string) (contracts.VirtualSource, error) {
+	return c.addVirtual(name, nil)
+}
+
+// CreateVirtualDestination publishes a destination named name delivering the messages
+// passed to SendToVirtual to eventChannel without blocking. It fails with
+// contracts.ErrInvalidDevice if an endpoint with that name exists.
+func (c *Client) CreateVirtualDestination(name string, eventChannel chan contracts.MIDI) (contracts.VirtualDestination, error) {
+	if eventChannel == nil {
+		return nil, fmt.Errorf("%w: nil event channel for virtual destination %q", contracts.ErrInvalidDevice, name)
+	}
+	return c.addVirtual(name, eventChannel)
+}
+
+// addVirtual registers a virtual endpoint.
+func (c *Client) addVirtual(name string, destination chan contracts.MIDI) (*virtualEndpoint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.virtuals[name]; exists {
+		return nil, fmt.Errorf("%w: virtual endpoint %q already exists", contracts.ErrInvalidDevice, name)
+	}
+	virtual := &virtualEndpoint{client: c, name: name, destination: destination}
+	c.virtuals[name] = virtual
+	return virtual, nil
+}
+
+// VirtualSent returns the messages sent through the virtual source named name, or nil if
+// there is none.
+func (c *Client) VirtualSent(name string) []contracts.MIDI {
+	c.mu.Lock()
+	virtual := c.virtuals[name]
+	c.mu.Unlock()
+	if virtual == nil {
+		return nil
+	}
+
+	virtual.mu.Lock()
+	defer virtual.mu.Unlock()
+	return slices.Clone(virtual.sent)
+}
+
+// SendToVirtual delivers event to the virtual destination named name, as if another
+// application had sent it. The event is dropped when the channel of the destination is
+// full. It fails with contracts.ErrInvalidDevice when there is no such destination.
+func (c *Client) SendToVirtual(name string, event contracts.MIDI) error {
+	c.mu.Lock()
+	virtual := c.virtuals[name]
+	c.mu.Unlock()
+	if virtual == nil || virtual.destination == nil {
+		return fmt.Errorf("%w: no virtual destination %q", contracts.ErrInvalidDevice, name)
+	}
+
+	virtual.mu.Lock()
+	defer virtual.mu.Unlock()
+	if virtual.closed {
+		return fmt.Errorf("%w: no virtual destination %q", contracts.ErrInvalidDevice, name)
+	}
+	select {
+	case virtual.destination <- event:
+	default:
+	}
+	return nil
+}
+
+// Send records a message sent through a virtual source.
+func (v *virtualEndpoint) Send(event contracts.MIDI) error {
+	if _, err := midistream.Encode(event); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed {
+		return contracts.ErrDeviceDisconnected
+	}
+	v.sent = append(v.sent, event)
+	return nil
+}
+
+// Close removes the endpoint.
+func (v *virtualEndpoint) Close() error {
+	v.mu.Lock()
+	v.closed = true
+	v.mu.Unlock()
+
+	v.client.mu.Lock()
+	defer v.client.mu.Unlock()
+	if v.client.virtuals[v.name] == v {
+		delete(v.client.virtuals, v.name)
+	}
+	return nil
+}
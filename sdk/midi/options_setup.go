@@ -1,24 +1,46 @@
 package midi
 
 import (
+	"context"
+	"time"
+
 	"github.com/leandrodaf/midi/internal/logger"
 	"github.com/leandrodaf/midi/sdk/contracts"
 )
 
+// ApplyOptions applies opts and the defaults NewMIDIClient uses, for clients created
+// outside this package, such as the in-memory client of sdk/midi/miditest, to behave like
+// those it creates.
+//
+// opts ...contracts.Option: A variadic list of option functions that can modify ClientOptions.
+//
+// Returns:
+//   - contracts.ClientOptions: The client options with defaults applied.
+//   - error: An *OptionsError if the options are invalid.
+func ApplyOptions(opts ...contracts.Option) (contracts.ClientOptions, error) {
+	return applyDefaultOptions(opts...)
+}
+
 // applyDefaultOptions sets default values for ClientOptions if not explicitly provided.
 //
 // opts ...contracts.Option: A variadic list of option functions that can modify ClientOptions.
 //
 // Returns:
 //   - contracts.ClientOptions: A structure containing the finalized client options with defaults applied.
-//   - error: An error if there was an issue applying the options.
+//   - error: An *OptionsError if the options are invalid.
 func applyDefaultOptions(opts ...contracts.Option) (contracts.ClientOptions, error) {
 	options := &contracts.ClientOptions{}
 	for _, opt := range opts {
 		opt(options)
 	}
+	if err := validateOptions(options); err != nil {
+		return contracts.ClientOptions{}, err
+	}
 
 	// Set defaults if options are not provided
+	if options.DisableLogging {
+		options.Logger = logger.NewNopLogger() // Discard messages from outside the capture path
+	}
 	if options.Logger == nil {
 		options.Logger = logger.NewZapLogger() // Default to a standard logger
 	}
@@ -30,6 +52,80 @@ func applyDefaultOptions(opts ...contracts.Option) (contracts.ClientOptions, err
 		options.CoreMIDIConfig = &contracts.CoreMIDIConfig{ClientName: "GO MIDI Client"} // Default CoreMIDI config
 	}
 
+	if options.Backend == contracts.BackendSerial && options.SerialConfig == nil {
+		options.SerialConfig = &contracts.SerialConfig{}
+	}
+	if options.SerialConfig != nil && options.SerialConfig.BaudRate <= 0 {
+		options.SerialConfig.BaudRate = 31250 // MIDI 1.0 DIN rate
+	}
+
+	if options.Backend == contracts.BackendJACK && options.JACKConfig == nil {
+		options.JACKConfig = &contracts.JACKConfig{}
+	}
+	if options.JACKConfig != nil && options.JACKConfig.ClientName == "" {
+		options.JACKConfig.ClientName = "Go MIDI" // Default name of the client in the JACK graph
+	}
+
+	if options.Backend == contracts.BackendReplay && options.ReplayConfig == nil {
+		options.ReplayConfig = &contracts.ReplayConfig{}
+	}
+	if options.ReplayConfig != nil && options.ReplayConfig.TimestampUnit <= 0 {
+		options.ReplayConfig.TimestampUnit = time.Nanosecond // Unit of the native backends' timestamps
+	}
+	if options.ReplayTiming.Mode == contracts.ReplayScaled && options.ReplayTiming.Speed <= 0 {
+		options.ReplayTiming.Speed = 1
+	}
+
+	if options.Discovery != nil && options.Discovery.Timeout <= 0 {
+		options.Discovery.Timeout = time.Second // Default mDNS browse duration
+	}
+
+	if options.SysEx != nil && options.SysEx.Handler != nil && options.SysEx.Buffer <= 0 {
+		options.SysEx.Buffer = 16 // Default SysEx handler queue length
+	}
+	if options.SysEx != nil {
+		if options.SysEx.MaxSize <= 0 {
+			options.SysEx.MaxSize = 64 * 1024 // Default longest SysEx message
+		}
+		if options.SysEx.DriverBuffers <= 0 {
+			options.SysEx.DriverBuffers = 4 // Default number of winmm SysEx buffers
+		}
+		if options.SysEx.DriverBufferSize <= 0 {
+			options.SysEx.DriverBufferSize = 1024 // Default size of winmm SysEx buffers
+		}
+	}
+
+	if options.AutoReconnect != nil && options.AutoReconnect.Interval <= 0 {
+		options.AutoReconnect.Interval = time.Second // Default delay between reconnection attempts
+	}
+
+	if options.Context == nil {
+		options.Context = context.Background() // The client lives until Stop
+	}
+
+	if options.CaptureWorkers <= 0 {
+		options.CaptureWorkers = 1 // Handle events in order
+	}
+	if options.CaptureBuffer <= 0 {
+		options.CaptureBuffer = 1024 // Default queue of the StartCaptureFunc handler
+	}
+
+	if options.RealtimeDispatch && options.SourceQueue <= 0 {
+		options.SourceQueue = 256 // Realtime dispatch runs on the per-source goroutines
+	}
+	if options.Overflow == contracts.OverflowBlock && options.SourceQueue <= 0 {
+		options.SourceQueue = 256 // Blocking waits on the per-source goroutines, not the driver callbacks
+	}
+
 	options.Logger.SetLevel(options.LogLevel) // Set the logger to the specified log level
+	if options.LogFilePath != "" && options.LogDestination == "" {
+		options.LogDestination = contracts.FileLog // A file path alone directs the logs to it
+	}
+	if options.LogDestination != "" && !options.DisableLogging {
+		if logger, ok := options.Logger.(contracts.RotatingLogger); ok && options.LogRotation != nil {
+			logger.SetRotation(*options.LogRotation)
+		}
+		options.Logger.SetDestination(options.LogDestination, options.LogFilePath)
+	}
 	return *options, nil
 }
@@ -0,0 +1,278 @@
+package midi
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// ErrInvalidOptions is matched by the *OptionsError returned by NewMIDIClient when the
+// options are invalid or contradict each other.
+var ErrInvalidOptions = errors.New("invalid MIDI client options")
+
+// OptionsError lists every problem found in the options given to NewMIDIClient, so they
+// can all be fixed at once.
+type OptionsError struct {
+	Problems []string // Descriptions of the problems, naming the options involved.
+}
+
+// Error lists the problems.
+func (e *OptionsError) Error() string {
+	return ErrInvalidOptions.Error() + ": " + strings.Join(e.Problems, "; ")
+}
+
+// Is reports whether target is ErrInvalidOptions.
+func (e *OptionsError) Is(target error) bool {
+	return target == ErrInvalidOptions
+}
+
+// validateOptions checks the options as set by the caller, before defaults are applied,
+// so that values the defaults would silently replace are reported too. Unknown backends
+// and missing remote addresses are left to NewClient, which reports them with
+// ErrUnknownBackend and the errors of the backends.
+//
+// options *contracts.ClientOptions: The options to check.
+//
+// Returns:
+//   - error: An *OptionsError listing every problem, or nil if there is none.
+func validateOptions(options *contracts.ClientOptions) error {
+	var problems []string
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if options.LogLevel < contracts.InfoLevel || options.LogLevel > contracts.FatalLevel {
+		problem("WithLogLevel: unknown log level %d", options.LogLevel)
+	}
+	if options.LogFilePath != "" {
+		if info, err := os.Stat(filepath.Dir(options.LogFilePath)); err != nil || !info.IsDir() {
+			problem("log file %q: directory %q does not exist", options.LogFilePath, filepath.Dir(options.LogFilePath))
+		} else if info, err := os.Stat(options.LogFilePath); err == nil && info.IsDir() {
+			problem("log file %q is a directory", options.LogFilePath)
+		}
+	}
+	switch options.LogDestination {
+	case "", contracts.ConsoleLog:
+	case contracts.FileLog, contracts.ConsoleAndFileLog:
+		if options.LogFilePath == "" {
+			problem("WithLogDestination: %s destination without a file path", options.LogDestination)
+		}
+	default:
+		problem("WithLogDestination: unknown destination %q", options.LogDestination)
+	}
+	if rotation := options.LogRotation; rotation != nil {
+		if options.LogFilePath == "" {
+			problem("WithLogRotation is set but no log file is; set it with WithLogDestination")
+		}
+		if rotation.MaxSize < 0 || rotation.Interval < 0 || rotation.MaxBackups < 0 || rotation.MaxAge < 0 {
+			problem("WithLogRotation: negative limit in %+v", *rotation)
+		}
+	}
+
+	if options.MIDIEventFilter != nil {
+		for _, command := range options.MIDIEventFilter.Commands {
+			if command < 0x80 {
+				problem("WithMIDIEventFilter: 0x%02X is not a status byte (0x80-0xFF)", byte(command))
+			}
+		}
+	}
+	for i, transform := range options.Transforms {
+		if transform == nil {
+			problem("WithTransforms: transform %d is nil", i)
+		}
+	}
+	if curves := options.VelocityCurves; curves != nil {
+		if curves.Default != nil {
+			if v, ok := invalidVelocity(*curves.Default); ok {
+				problem("WithVelocityCurve: velocity %d becomes %d, above 127", v, curves.Default[v])
+			}
+		}
+		for _, device := range slices.Sorted(maps.Keys(curves.Devices)) {
+			if strings.TrimSpace(device) == "" {
+				problem("WithDeviceVelocityCurve: the device name is empty")
+			}
+			table := curves.Devices[device]
+			if v, ok := invalidVelocity(table); ok {
+				problem("WithDeviceVelocityCurve: velocity %d of %q becomes %d, above 127", v, device, table[v])
+			}
+		}
+	}
+	if options.CoreMIDIConfig != nil && strings.TrimSpace(options.CoreMIDIConfig.ClientName) == "" {
+		problem("WithCoreMIDIConfig: the client name is empty")
+	}
+
+	// The remote configuration also applies to the servers found by network discovery.
+	if options.RemoteConfig != nil && options.Backend != contracts.BackendRemote && options.Discovery == nil {
+		problem("WithRemoteConfig is set but the backend is %s; select it with WithBackend(%q)", backendName(options.Backend), contracts.BackendRemote)
+	}
+	if options.RemoteConfig != nil {
+		if options.RemoteConfig.Keyring != nil && len(options.RemoteConfig.Keys) > 0 {
+			problem("WithRemoteConfig: set either Keys or Keyring, not both")
+		}
+		for _, key := range options.RemoteConfig.Keys {
+			if key.ID == "" || len(key.ID) > 255 {
+				problem("WithRemoteConfig: key IDs must have 1 to 255 bytes")
+			}
+			if n := len(key.Secret); n != 16 && n != 24 && n != 32 {
+				problem("WithRemoteConfig: key %q has %d bytes; AES keys have 16, 24 or 32", key.ID, n)
+			}
+		}
+	}
+	if options.SerialConfig != nil {
+		if options.Backend != contracts.BackendSerial {
+			problem("WithSerialConfig is set but the backend is %s; select it with WithBackend(%q)", backendName(options.Backend), contracts.BackendSerial)
+		}
+		if options.SerialConfig.BaudRate < 0 {
+			problem("WithSerialConfig: negative baud rate %d", options.SerialConfig.BaudRate)
+		}
+		for _, port := range options.SerialConfig.Ports {
+			if strings.TrimSpace(port) == "" {
+				problem("WithSerialConfig: empty port name")
+			}
+		}
+	}
+	if options.ReplayConfig != nil {
+		if options.Backend != contracts.BackendReplay {
+			problem("WithReplayConfig is set but the backend is %s; select it with WithBackend(%q)", backendName(options.Backend), contracts.BackendReplay)
+		}
+		if options.ReplayConfig.TimestampUnit < 0 {
+			problem("WithReplayConfig: negative timestamp unit %s", options.ReplayConfig.TimestampUnit)
+		}
+	}
+	// The RTP-MIDI configuration also applies to the sessions found by network discovery.
+	if options.RTPConfig != nil {
+		if options.Backend != contracts.BackendRTP && options.Discovery == nil {
+			problem("WithRTPConfig is set but the backend is %s; select it with WithBackend(%q)", backendName(options.Backend), contracts.BackendRTP)
+		}
+		if port := options.RTPConfig.Port; port < 0 || port > 65534 {
+			problem("WithRTPConfig: port %d out of range; the data port is the next one", port)
+		}
+		for _, peer := range options.RTPConfig.Peers {
+			if _, _, err := net.SplitHostPort(peer); err != nil {
+				problem("WithRTPConfig: peer %q is not a host:port address", peer)
+			}
+		}
+	}
+	if options.BLEConfig != nil {
+		if options.Backend != contracts.BackendBLE {
+			problem("WithBLEConfig is set but the backend is %s; select it with WithBackend(%q)", backendName(options.Backend), contracts.BackendBLE)
+		}
+		if options.BLEConfig.ScanTimeout < 0 {
+			problem("WithBLEConfig: negative scan timeout %s", options.BLEConfig.ScanTimeout)
+		}
+	}
+	if options.JACKConfig != nil {
+		if options.Backend != contracts.BackendJACK {
+			problem("WithJACKConfig is set but the backend is %s; select it with WithBackend(%q)", backendName(options.Backend), contracts.BackendJACK)
+		}
+		if strings.Contains(options.JACKConfig.ClientName, ":") {
+			problem("WithJACKConfig: client name %q contains a colon, which separates clients from ports in JACK", options.JACKConfig.ClientName)
+		}
+	}
+	switch options.ReplayTiming.Mode {
+	case contracts.ReplayRealtime, contracts.ReplayScaled, contracts.ReplayAsFastAsPossible:
+	default:
+		problem("WithReplayTiming: unknown replay mode %d", options.ReplayTiming.Mode)
+	}
+	if options.ReplayTiming.Mode == contracts.ReplayScaled && options.ReplayTiming.Speed < 0 {
+		problem("WithReplayTiming: negative speed %g", options.ReplayTiming.Speed)
+	}
+
+	if options.Discovery != nil && options.Discovery.Timeout < 0 {
+		problem("WithNetworkDiscovery: negative timeout %s", options.Discovery.Timeout)
+	}
+	if options.InactivityWatchdog != nil {
+		if options.InactivityWatchdog.Timeout <= 0 {
+			problem("WithInactivityWatchdog: the timeout must be positive, not %s", options.InactivityWatchdog.Timeout)
+		}
+		if options.InactivityWatchdog.Callback == nil {
+			problem("WithInactivityWatchdog: the callback is nil")
+		}
+	}
+	if options.OpenRetry != nil {
+		if options.OpenRetry.Attempts < 1 {
+			problem("WithOpenRetry: at least one attempt is needed, not %d", options.OpenRetry.Attempts)
+		}
+		if options.OpenRetry.Backoff < 0 {
+			problem("WithOpenRetry: negative backoff %s", options.OpenRetry.Backoff)
+		}
+	}
+	if options.AutoReconnect != nil && options.AutoReconnect.Interval < 0 {
+		problem("WithAutoReconnect: negative interval %s", options.AutoReconnect.Interval)
+	}
+	if options.Thru != nil {
+		if options.Thru.DeviceID < 0 {
+			problem("WithThru: invalid output device ID %d", options.Thru.DeviceID)
+		}
+		if options.Backend == contracts.BackendLoopback {
+			problem("WithThru: the loopback backend captures what it sends, so thru would echo every event forever")
+		}
+	}
+	for _, output := range options.SoftwareOutputs {
+		if strings.TrimSpace(output.Name) == "" || output.Write == nil {
+			problem("WithSoftwareOutput: an output needs a name and a write function")
+		}
+	}
+	if options.SysEx != nil {
+		if options.SysEx.Channel == nil && options.SysEx.Handler == nil {
+			problem("SysEx delivery needs a channel (WithSysExChannel) or a handler (WithSysExHandler)")
+		}
+		if options.SysEx.Buffer < 0 {
+			problem("WithSysExHandler: negative buffer %d", options.SysEx.Buffer)
+		}
+		if options.SysEx.MaxSize < 0 {
+			problem("WithSysExMaxSize: negative size %d", options.SysEx.MaxSize)
+		}
+		if options.SysEx.DriverBuffers < 0 || options.SysEx.DriverBufferSize < 0 {
+			problem("WithSysExDriverBuffers: negative count %d or size %d", options.SysEx.DriverBuffers, options.SysEx.DriverBufferSize)
+		}
+	}
+	if options.SourceQueue < 0 {
+		problem("WithSourceQueues: negative queue size %d", options.SourceQueue)
+	}
+	if options.CaptureWorkers < 0 || options.CaptureBuffer < 0 {
+		problem("WithCaptureWorkers: negative count %d or buffer %d", options.CaptureWorkers, options.CaptureBuffer)
+	}
+	if options.Overflow < contracts.OverflowDrop || options.Overflow > contracts.OverflowCoalesce {
+		problem("WithOverflowPolicy: unknown policy %d", options.Overflow)
+	}
+	if options.Clock < contracts.DefaultClock || options.Clock > contracts.HardwareClock {
+		problem("WithTimestampClock: unknown clock %d", options.Clock)
+	}
+	if options.ParsingMode != contracts.LenientParsing && options.ParsingMode != contracts.StrictParsing {
+		problem("WithParsingMode: unknown parsing mode %d", options.ParsingMode)
+	}
+	if options.DedupWindow < 0 {
+		problem("WithDeduplication: negative window %s", options.DedupWindow)
+	}
+
+	if len(problems) > 0 {
+		return &OptionsError{Problems: problems}
+	}
+	return nil
+}
+
+// backendName describes a backend in problems, the empty name being the native backend.
+func backendName(backend string) string {
+	if backend == "" {
+		return "the native one"
+	}
+	return fmt.Sprintf("%q", backend)
+}
+
+// invalidVelocity returns the first velocity a velocity table maps above 127.
+func invalidVelocity(table [128]byte) (int, bool) {
+	for v, value := range table {
+		if value > 127 {
+			return v, true
+		}
+	}
+	return 0, false
+}
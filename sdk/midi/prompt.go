@@ -0,0 +1,104 @@
+package midi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// PromptSelectDevice lists the devices of client on w, asks the user to choose one and
+// selects it. The choice is read from r as a device ID or a part of a device name that
+// matches a single device; invalid choices and devices that fail to open are reported
+// and asked again.
+//
+// client contracts.ClientMIDI: The client whose device to select.
+// r io.Reader: The input of the user, e.g. os.Stdin. It is read ahead of the chosen line.
+// w io.Writer: The output for the list and the prompts, e.g. os.Stdout.
+//
+// Returns:
+//   - int: The ID of the selected device.
+//   - error: contracts.ErrNoDevices if there are no devices, io.EOF if the input ended
+//     before a device was selected, or the error listing the devices or reading r.
+func PromptSelectDevice(client contracts.ClientMIDI, r io.Reader, w io.Writer) (int, error) {
+	devices, err := client.ListDevices()
+	if err != nil {
+		return -1, err
+	}
+	if len(devices) == 0 {
+		return -1, contracts.ErrNoDevices
+	}
+
+	fmt.Fprintln(w, "MIDI devices:")
+	for id, device := range devices {
+		fmt.Fprintf(w, "  %d: %s\n", id, deviceLabel(device))
+	}
+
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprintf(w, "Select a device [0-%d]: ", len(devices)-1)
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			if err := scanner.Err(); err != nil {
+				return -1, err
+			}
+			return -1, io.EOF
+		}
+
+		id, problem := parseChoice(strings.TrimSpace(scanner.Text()), devices)
+		if problem != "" {
+			fmt.Fprintln(w, problem)
+			continue
+		}
+		if err := client.SelectDevice(id); err != nil {
+			fmt.Fprintf(w, "Cannot select %s: %v\n", devices[id].Name, err)
+			continue
+		}
+		return id, nil
+	}
+}
+
+// parseChoice returns the ID of the device chosen with choice: an ID, or a part of a
+// device name, ignoring case, that matches a single device. For invalid choices, it
+// returns the problem to show the user instead.
+func parseChoice(choice string, devices []contracts.DeviceInfo) (int, string) {
+	if choice == "" {
+		return -1, fmt.Sprintf("Enter a device ID from 0 to %d.", len(devices)-1)
+	}
+	if id, err := strconv.Atoi(choice); err == nil {
+		if id < 0 || id >= len(devices) {
+			return -1, fmt.Sprintf("There is no device %d; enter an ID from 0 to %d.", id, len(devices)-1)
+		}
+		return id, ""
+	}
+
+	match := -1
+	for id, device := range devices {
+		if strings.Contains(strings.ToLower(device.Name), strings.ToLower(choice)) {
+			if match >= 0 {
+				return -1, fmt.Sprintf("Several devices match %q; enter a device ID.", choice)
+			}
+			match = id
+		}
+	}
+	if match < 0 {
+		return -1, fmt.Sprintf("No device matches %q.", choice)
+	}
+	return match, ""
+}
+
+// deviceLabel describes a device in the list, with its manufacturer when known, marking
+// offline devices.
+func deviceLabel(device contracts.DeviceInfo) string {
+	label := device.Name
+	if device.Manufacturer != "" {
+		label = fmt.Sprintf("%s (%s)", device.Name, device.Manufacturer)
+	}
+	if device.Offline {
+		label += " [offline]"
+	}
+	return label
+}
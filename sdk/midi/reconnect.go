@@ -0,0 +1,206 @@
+package midi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/midi/dispatch"
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// reconnectClient reopens the selected device of a backend when it is lost. The device is
+// identified by its DeviceInfo, since its ID may change while it is away; the backends
+// resume a running capture when the device is selected again.
+type reconnectClient struct {
+	contracts.ClientMIDI // Backend whose device is reopened.
+
+	logger   contracts.Logger
+	config   contracts.AutoReconnect
+	mu       sync.Mutex
+	target   *contracts.DeviceInfo // Device to reopen; nil when none was selected.
+	deviceID int                   // ID of the selected device.
+	lostErr  error                 // Why the device was lost; nil when it is connected.
+	lost     chan struct{}         // Signalled when the device is lost.
+	done     chan struct{}         // Closed by Stop.
+	wg       sync.WaitGroup        // Waits for the reconnection goroutine.
+	stopOnce sync.Once
+}
+
+// newReconnectClient prepares automatic reconnection for the client created from options,
+// chaining its loss detection into the OnDeviceLost hook of options. The backend is set
+// with start once created.
+func newReconnectClient(options *contracts.ClientOptions) *reconnectClient {
+	r := &reconnectClient{
+		logger:   options.Logger,
+		config:   *options.AutoReconnect,
+		deviceID: -1,
+		lost:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	var hooks contracts.Hooks
+	if options.Hooks != nil {
+		hooks = *options.Hooks
+	}
+	onDeviceLost := hooks.OnDeviceLost
+	hooks.OnDeviceLost = func(deviceID int, device contracts.DeviceInfo, err error) {
+		r.deviceLost(err)
+		if onDeviceLost != nil {
+			onDeviceLost(deviceID, device, err)
+		}
+	}
+	options.Hooks = &hooks
+	return r
+}
+
+// start wraps client and starts watching for lost devices.
+func (r *reconnectClient) start(client contracts.ClientMIDI) contracts.ClientMIDI {
+	r.ClientMIDI = client
+	r.wg.Add(1)
+	profiling.Go(profiling.RoleWatchdog, "reconnect", "", r.watch)
+	return r
+}
+
+// SelectDevice selects a device of the backend and remembers it for reconnection.
+func (r *reconnectClient) SelectDevice(deviceID int) error {
+	devices, _ := r.ClientMIDI.ListDevices()
+	if err := r.ClientMIDI.SelectDevice(deviceID); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.target = nil
+	if deviceID >= 0 && deviceID < len(devices) {
+		r.target = &devices[deviceID]
+	}
+	r.deviceID = deviceID
+	r.lostErr = nil
+	device := r.device()
+	r.mu.Unlock()
+
+	r.emit(contracts.Connected, deviceID, device, nil)
+	return nil
+}
+
+// StartCaptureContext starts capturing into eventChannel and stops reconnecting and the
+// backend once ctx is done.
+func (r *reconnectClient) StartCaptureContext(ctx context.Context, eventChannel chan contracts.MIDI) error {
+	return dispatch.StartContext(ctx, eventChannel, r.StartCapture, r.Stop)
+}
+
+// Stop stops reconnecting and stops the backend.
+func (r *reconnectClient) Stop() error {
+	r.stopOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+	})
+	return r.ClientMIDI.Stop()
+}
+
+// deviceLost records the loss of the selected device, reported by the backend to the
+// OnDeviceLost hook.
+func (r *reconnectClient) deviceLost(err error) {
+	r.mu.Lock()
+	if r.target == nil || r.lostErr != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.lostErr = err
+	deviceID, device := r.deviceID, r.device()
+	r.mu.Unlock()
+
+	r.logger.Warn("MIDI device lost; reconnecting", r.logger.Field().String("device", device.Name), r.logger.Field().Error("error", err))
+	r.emit(contracts.Disconnected, deviceID, device, err)
+	select {
+	case r.lost <- struct{}{}:
+	default:
+	}
+}
+
+// watch tries to reopen a lost device every interval until it is back, until Stop.
+func (r *reconnectClient) watch() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.lost:
+		}
+
+		ticker := time.NewTicker(r.config.Interval)
+		for !r.reopen() {
+			select {
+			case <-r.done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+			}
+		}
+		ticker.Stop()
+	}
+}
+
+// reopen looks the lost device up and selects it again. It reports whether no more
+// attempts are needed, because the device is back or another device was selected.
+func (r *reconnectClient) reopen() bool {
+	r.mu.Lock()
+	if r.lostErr == nil {
+		r.mu.Unlock()
+		return true
+	}
+	target, previousID := *r.target, r.deviceID
+	r.mu.Unlock()
+
+	deviceID := -1
+	devices, err := r.ClientMIDI.ListDevices()
+	for i, device := range devices {
+		if device == target {
+			deviceID = i
+			break
+		}
+	}
+	if deviceID < 0 {
+		if err == nil || errors.Is(err, contracts.ErrNoDevices) {
+			err = fmt.Errorf("%w: %q is not connected", contracts.ErrDeviceDisconnected, target.Name)
+		}
+		r.emit(contracts.Reconnecting, previousID, target, err)
+		return false
+	}
+	if err := r.ClientMIDI.SelectDevice(deviceID); err != nil {
+		r.emit(contracts.Reconnecting, previousID, target, err)
+		return false
+	}
+
+	r.mu.Lock()
+	r.deviceID = deviceID
+	r.lostErr = nil
+	r.mu.Unlock()
+
+	r.logger.Info("MIDI device reconnected", r.logger.Field().String("device", target.Name), r.logger.Field().Int("deviceID", deviceID))
+	r.emit(contracts.Reconnected, deviceID, target, nil)
+	return true
+}
+
+// device returns the selected device. The caller must hold r.mu.
+func (r *reconnectClient) device() contracts.DeviceInfo {
+	if r.target == nil {
+		return contracts.DeviceInfo{}
+	}
+	return *r.target
+}
+
+// emit sends a connection event to the configured channel, dropping it when the channel
+// is full.
+func (r *reconnectClient) emit(state contracts.ConnectionState, deviceID int, device contracts.DeviceInfo, err error) {
+	if r.config.Events == nil {
+		return
+	}
+	select {
+	case r.config.Events <- contracts.ConnectionEvent{Time: time.Now(), State: state, DeviceID: deviceID, Device: device, Err: err}:
+	default:
+	}
+}
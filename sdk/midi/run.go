@@ -0,0 +1,119 @@
+package midi
+
+import (
+	"context"
+	"iter"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// runBuffer is the size of the event channel Run captures into.
+const runBuffer = 256
+
+// Run captures events from the selected device of client and calls handler for each of
+// them until ctx is done, then stops client and returns. Events captured before the stop
+// are still handed to handler, so none is lost on shutdown.
+//
+// handler runs on the goroutine calling Run, one event at a time, so it needs no
+// synchronization of its own; while it runs, events queue up in a buffer of 256 and are
+// dropped when it is full, as with any event channel.
+//
+// ctx context.Context: Ends the capture when done.
+// client contracts.ClientMIDI: The client to capture from, with a device selected. Run stops it.
+// handler func(contracts.MIDI): Called for every captured event.
+//
+// Returns:
+//   - error: The error starting the capture, after which client is stopped, or the error
+//     stopping client, if any.
+func Run(ctx context.Context, client contracts.ClientMIDI, handler func(contracts.MIDI)) error {
+	events := make(chan contracts.MIDI, runBuffer)
+	if err := client.StartCapture(events); err != nil {
+		client.Stop()
+		return err
+	}
+
+	for {
+		select {
+		case event := <-events:
+			handler(event)
+		case <-ctx.Done():
+			err := client.Stop()
+			// The channel is not closed: backends stop sending when Stop returns, and the
+			// events already buffered are delivered.
+			for {
+				select {
+				case event := <-events:
+					handler(event)
+				default:
+					return err
+				}
+			}
+		}
+	}
+}
+
+// Events captures events from the selected device of client and yields them to a range
+// loop until ctx is done, as Run calls its handler, so consumers can iterate instead of
+// receiving from a channel:
+//
+//	for event := range midi.Events(ctx, client) {
+//		...
+//	}
+//
+// client is stopped when ctx is done, after which the events already captured are still
+// yielded, or when the loop ends early. When the capture cannot start, client is stopped
+// and nothing is yielded. The errors starting and stopping it are discarded; use Run to
+// get them.
+//
+// ctx context.Context: Ends the capture when done.
+// client contracts.ClientMIDI: The client to capture from, with a device selected. It is stopped when the iteration ends.
+//
+// Returns:
+//   - iter.Seq[contracts.MIDI]: The captured events, to be iterated once.
+func Events(ctx context.Context, client contracts.ClientMIDI) iter.Seq[contracts.MIDI] {
+	return func(yield func(contracts.MIDI) bool) {
+		events := make(chan contracts.MIDI, runBuffer)
+		defer client.Stop()
+		if client.StartCapture(events) != nil {
+			return
+		}
+
+		for {
+			select {
+			case event := <-events:
+				if !yield(event) {
+					return
+				}
+			case <-ctx.Done():
+				client.Stop()
+				for {
+					select {
+					case event := <-events:
+						if !yield(event) {
+							return
+						}
+					default:
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// RunUntilInterrupt is Run until the process receives an interrupt (Ctrl+C) or SIGTERM, so
+// command-line tools and services shut down cleanly when asked to.
+//
+// client contracts.ClientMIDI: The client to capture from, with a device selected. It is stopped on return.
+// handler func(contracts.MIDI): Called for every captured event, on the calling goroutine.
+//
+// Returns:
+//   - error: The error stopping client, if any.
+func RunUntilInterrupt(client contracts.ClientMIDI, handler func(contracts.MIDI)) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return Run(ctx, client, handler)
+}
@@ -0,0 +1,113 @@
+package midi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// ErrDeviceNotFound is returned when no device matches the name or pattern looked for. It
+// is contracts.ErrDeviceNotFound, also returned by the backends.
+var ErrDeviceNotFound = contracts.ErrDeviceNotFound
+
+// DeviceMatcher reports whether a device is the one looked for.
+type DeviceMatcher func(device contracts.DeviceInfo) bool
+
+// MatchName matches the devices named name, ignoring case.
+func MatchName(name string) DeviceMatcher {
+	return func(device contracts.DeviceInfo) bool {
+		return strings.EqualFold(device.Name, name)
+	}
+}
+
+// MatchSubstring matches the devices whose name contains part, ignoring case. A blank part
+// matches no device.
+func MatchSubstring(part string) DeviceMatcher {
+	part = strings.ToLower(strings.TrimSpace(part))
+	return func(device contracts.DeviceInfo) bool {
+		return part != "" && strings.Contains(strings.ToLower(device.Name), part)
+	}
+}
+
+// MatchRegexp matches the devices whose name matches re, e.g.
+// regexp.MustCompile(`(?i)^arturia keystep`).
+func MatchRegexp(re *regexp.Regexp) DeviceMatcher {
+	return func(device contracts.DeviceInfo) bool {
+		return re.MatchString(device.Name)
+	}
+}
+
+// MatchUniqueID matches the device whose DeviceInfo.UniqueID is id, on backends reporting
+// one, so a device is found again even when it is renamed or another unit with the same
+// name is plugged in.
+func MatchUniqueID(id string) DeviceMatcher {
+	return func(device contracts.DeviceInfo) bool {
+		return id != "" && device.UniqueID == id
+	}
+}
+
+// FindDevice returns the ID of the first of devices matched by match, or -1.
+//
+// devices []contracts.DeviceInfo: The devices, as listed by ListDevices.
+// match DeviceMatcher: Reports whether a device is the one looked for.
+//
+// Returns:
+//   - int: The ID of the first matching device, or -1 if none matches.
+func FindDevice(devices []contracts.DeviceInfo, match DeviceMatcher) int {
+	for id, device := range devices {
+		if match(device) {
+			return id
+		}
+	}
+	return -1
+}
+
+// SelectDeviceByName selects the device named name, ignoring case, or else the first
+// device whose name contains name, so configuration files can refer to a device such as
+// "Arturia KeyStep" whatever its ID is after a reboot.
+//
+// client contracts.ClientMIDI: The client whose device to select.
+// name string: The name, or part of the name, of the device.
+//
+// Returns:
+//   - int: The ID of the selected device.
+//   - error: ErrDeviceNotFound if name is blank or no device matches, or the error listing or selecting the device.
+func SelectDeviceByName(client contracts.ClientMIDI, name string) (int, error) {
+	if strings.TrimSpace(name) == "" {
+		return -1, fmt.Errorf("%w: empty device name", ErrDeviceNotFound)
+	}
+	devices, err := client.ListDevices()
+	if err != nil {
+		return -1, err
+	}
+	id := FindDevice(devices, MatchName(name))
+	if id < 0 {
+		id = FindDevice(devices, MatchSubstring(name))
+	}
+	if id < 0 {
+		return -1, fmt.Errorf("%w: %s", ErrDeviceNotFound, name)
+	}
+	return id, client.SelectDevice(id)
+}
+
+// SelectDeviceMatching selects the first device matched by match.
+//
+// client contracts.ClientMIDI: The client whose device to select.
+// match DeviceMatcher: Reports whether a device is the one looked for, e.g. MatchRegexp(re).
+//
+// Returns:
+//   - int: The ID of the selected device.
+//   - error: ErrDeviceNotFound if no device matches, or the error listing or selecting the device.
+func SelectDeviceMatching(client contracts.ClientMIDI, match DeviceMatcher) (int, error) {
+	devices, err := client.ListDevices()
+	if err != nil {
+		return -1, err
+	}
+	id := FindDevice(devices, match)
+	if id < 0 {
+		return -1, ErrDeviceNotFound
+	}
+	return id, client.SelectDevice(id)
+}
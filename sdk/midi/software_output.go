@@ -0,0 +1,103 @@
+package midi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// softwareOutputClient extends a backend with the output devices set with
+// contracts.WithSoftwareOutput. Output devices of the backend keep their IDs; software
+// outputs are listed after them.
+type softwareOutputClient struct {
+	contracts.ClientMIDI // Backend serving the hardware devices.
+
+	outputs  []contracts.SoftwareOutput
+	mu       sync.Mutex
+	selected *contracts.SoftwareOutput // Software output receiving Send; nil sends to the backend.
+}
+
+// newSoftwareOutputClient wraps client so that its output devices include the software
+// outputs of options.
+func newSoftwareOutputClient(client contracts.ClientMIDI, options *contracts.ClientOptions) contracts.ClientMIDI {
+	return &softwareOutputClient{ClientMIDI: client, outputs: options.SoftwareOutputs}
+}
+
+// ListOutputDevices lists the output devices of the backend followed by the software
+// outputs. Backends without output only list the software outputs.
+func (c *softwareOutputClient) ListOutputDevices() ([]contracts.DeviceInfo, error) {
+	devices, err := c.backendOutputs()
+	if err != nil {
+		return nil, err
+	}
+	for _, output := range c.outputs {
+		devices = append(devices, contracts.DeviceInfo{
+			Name:         output.Name,
+			Manufacturer: "software",
+			Direction:    contracts.DirectionOutput,
+		})
+	}
+	return devices, nil
+}
+
+// SelectOutputDevice selects an output device of the backend or a software output.
+func (c *softwareOutputClient) SelectOutputDevice(deviceID int) error {
+	devices, err := c.backendOutputs()
+	if err != nil {
+		return err
+	}
+	index := deviceID - len(devices)
+	if deviceID < len(devices) || index >= len(c.outputs) {
+		if err := c.ClientMIDI.SelectOutputDevice(deviceID); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.selected = nil
+		c.mu.Unlock()
+		return nil
+	}
+
+	c.mu.Lock()
+	c.selected = &c.outputs[index]
+	c.mu.Unlock()
+	return nil
+}
+
+// Send passes event to the selected software output or, if none is selected, sends it
+// through the backend.
+func (c *softwareOutputClient) Send(event contracts.MIDI) error {
+	c.mu.Lock()
+	selected := c.selected
+	c.mu.Unlock()
+
+	if selected != nil {
+		return selected.Write(event)
+	}
+	return c.ClientMIDI.Send(event)
+}
+
+// SendSysEx sends a SysEx message through the backend. Software outputs only take
+// channel and system messages, so it fails with contracts.ErrSysExUnsupported while one
+// is selected.
+func (c *softwareOutputClient) SendSysEx(data []byte) error {
+	c.mu.Lock()
+	selected := c.selected
+	c.mu.Unlock()
+
+	if selected != nil {
+		return fmt.Errorf("%w: software output %q", contracts.ErrSysExUnsupported, selected.Name)
+	}
+	return c.ClientMIDI.SendSysEx(data)
+}
+
+// backendOutputs lists the output devices of the backend, none for backends without
+// output.
+func (c *softwareOutputClient) backendOutputs() ([]contracts.DeviceInfo, error) {
+	devices, err := c.ClientMIDI.ListOutputDevices()
+	if errors.Is(err, contracts.ErrOutputUnsupported) {
+		return nil, nil
+	}
+	return devices, err
+}
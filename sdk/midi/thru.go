@@ -0,0 +1,45 @@
+package midi
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// thruOutput sends the events echoed by contracts.WithThru to the output device of a client.
+type thruOutput struct {
+	client atomic.Pointer[contracts.ClientMIDI] // Client sending the events; nil until opened.
+}
+
+// newThruOutput sets the sender of the thru configuration of options, if any, to a
+// thruOutput, and returns it; it returns nil without thru configuration.
+func newThruOutput(options *contracts.ClientOptions) *thruOutput {
+	if options.Thru == nil {
+		return nil
+	}
+	thru := &thruOutput{}
+	config := *options.Thru
+	config.Send = thru.send
+	options.Thru = &config
+	return thru
+}
+
+// open selects the output device of the thru configuration on client and starts sending
+// the echoed events to it.
+func (t *thruOutput) open(client contracts.ClientMIDI, deviceID int) error {
+	if err := client.SelectOutputDevice(deviceID); err != nil {
+		return fmt.Errorf("failed to open MIDI thru output device %d: %w", deviceID, err)
+	}
+	t.client.Store(&client)
+	return nil
+}
+
+// send sends event to the output device, discarding it until the output is open.
+func (t *thruOutput) send(event contracts.MIDI) error {
+	client := t.client.Load()
+	if client == nil {
+		return nil
+	}
+	return (*client).Send(event)
+}
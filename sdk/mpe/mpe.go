@@ -0,0 +1,370 @@
+// Package mpe interprets MIDI Polyphonic Expression (MPE), the way controllers such as the
+// Roli Seaboard, the LinnStrument and the Osmose give every note its own pitch bend,
+// pressure and timbre: each note sounds on a member channel of a zone of its own, so the
+// channel-wide pitch bend, channel pressure and controller 74 of that channel shape the
+// note alone, while the manager channel of the zone bends every note of the zone.
+//
+// A Tracker follows the zones, configured with WithZones or by the MPE configuration
+// messages of the devices, and turns the captured events into per-note expression events
+// identified by a NoteID from note on to note off:
+//
+//	tracker := mpe.NewTracker()
+//	var events []mpe.Event
+//	client.StartCaptureFunc(func(event contracts.MIDI) {
+//		events = tracker.Decode(events[:0], event)
+//		for _, note := range events {
+//			voice(note.NoteID).Set(note.Pitch(), note.Pressure, note.Timbre)
+//		}
+//	})
+package mpe
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/controller"
+)
+
+const (
+	MaxMembers              = 15   // Highest number of member channels of a zone.
+	DefaultMemberBendRange  = 48.0 // Pitch bend range of member channels, in semitones, until set otherwise.
+	DefaultManagerBendRange = 2.0  // Pitch bend range of manager channels, in semitones, until set otherwise.
+)
+
+// ccTimbre is the controller carrying the timbre of the notes of a member channel.
+const ccTimbre = 74
+
+// defaultTimbre is the value of the timbre controller until a device sends one, the
+// center of its range.
+const defaultTimbre = 64
+
+// Zone is a group of member channels whose notes are expressed separately, managed on
+// channel 1 for the lower zone and on channel 16 for the upper zone.
+type Zone struct {
+	Upper            bool    // Whether the zone is the upper zone, with the highest member channels.
+	Members          int     // Number of member channels, from 1 to 15; zero disables the zone.
+	MemberBendRange  float64 // Pitch bend range of the member channels, in semitones; DefaultMemberBendRange when zero.
+	ManagerBendRange float64 // Pitch bend range of the manager channel, in semitones; DefaultManagerBendRange when zero.
+}
+
+// LowerZone returns a lower zone, managed on channel 1, with member channels from 2 on.
+func LowerZone(members int) Zone {
+	return Zone{Members: members}
+}
+
+// UpperZone returns an upper zone, managed on channel 16, with member channels from 15
+// down.
+func UpperZone(members int) Zone {
+	return Zone{Upper: true, Members: members}
+}
+
+// Manager returns the zero-based manager channel of the zone: 0 for the lower zone and
+// 15 for the upper zone.
+func (z Zone) Manager() byte {
+	if z.Upper {
+		return 15
+	}
+	return 0
+}
+
+// Contains reports whether the zero-based channel is a member channel of the zone.
+func (z Zone) Contains(channel byte) bool {
+	if z.Upper {
+		return channel < 15 && int(channel) >= 15-z.Members
+	}
+	return channel > 0 && int(channel) <= z.Members
+}
+
+// withDefaults returns the zone with the default pitch bend ranges where unset.
+func (z Zone) withDefaults() Zone {
+	if z.MemberBendRange == 0 {
+		z.MemberBendRange = DefaultMemberBendRange
+	}
+	if z.ManagerBendRange == 0 {
+		z.ManagerBendRange = DefaultManagerBendRange
+	}
+	return z
+}
+
+// Kind is the kind of change an Event reports.
+type Kind uint8
+
+const (
+	NoteOn    Kind = iota + 1 // A note started.
+	NoteOff                   // A note ended; its NoteID is not used again.
+	PitchBend                 // The pitch bend of the note changed.
+	Pressure                  // The pressure of the note changed.
+	Timbre                    // The timbre of the note, controller 74, changed.
+)
+
+// String names the kind, e.g. "NoteOn" or "PitchBend".
+func (k Kind) String() string {
+	switch k {
+	case NoteOn:
+		return "NoteOn"
+	case NoteOff:
+		return "NoteOff"
+	case PitchBend:
+		return "PitchBend"
+	case Pressure:
+		return "Pressure"
+	case Timbre:
+		return "Timbre"
+	default:
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Event is a change of a note, carrying the whole expression of the note after the
+// change.
+type Event struct {
+	Timestamp      uint64  // Timestamp of the message causing the change.
+	Kind           Kind    // What changed.
+	NoteID         uint64  // Identifies the note from its note on to its note off; unique for the Tracker.
+	Note           byte    // Note number.
+	Channel        byte    // Zero-based channel the note sounds on.
+	Velocity       byte    // Velocity of the note on, or release velocity for NoteOff.
+	PitchBend      float64 // Pitch offset in semitones: the bend of the channel of the note plus the bend of the manager channel.
+	Pressure       float64 // Pressure of the note, from 0 to 1.
+	Timbre         float64 // Timbre of the note, from 0 to 1; about 0.5 until the device sends one.
+	SourceDeviceID int     // ID of the device the note came from.
+}
+
+// Pitch returns the pitch of the note as a fractional note number: the note number plus
+// the pitch bend, e.g. for sdk/tuning.
+func (e Event) Pitch() float64 {
+	return float64(e.Note) + e.PitchBend
+}
+
+// channelState is the expression of a channel.
+type channelState struct {
+	bend     int  // Pitch bend position, from -8192 to 8191.
+	pressure byte // Channel pressure.
+	timbre   byte // Value of the timbre controller.
+}
+
+// note is a sounding note.
+type note struct {
+	id       uint64
+	number   byte
+	velocity byte
+}
+
+// device holds the zones, the expression of every channel and the sounding notes of a
+// device.
+type device struct {
+	zones    [2]Zone // Lower and upper zones.
+	channels [16]channelState
+	notes    [16][]note // Sounding notes by channel.
+}
+
+// Tracker follows the zones and the notes of every device and reports the expression of
+// each note. It is safe for concurrent use.
+type Tracker struct {
+	options    Options
+	parameters *controller.Decoder // Decodes MPE configuration and pitch bend range messages.
+
+	mu      sync.Mutex
+	devices map[int]*device
+	lastID  uint64 // NoteID of the last note started.
+}
+
+// NewTracker creates a tracker without sounding notes.
+//
+// opts ...Option: Optional settings such as WithZones.
+//
+// Returns:
+//   - *Tracker: The tracker, ready to decode events.
+func NewTracker(opts ...Option) *Tracker {
+	return &Tracker{
+		options:    applyDefaultOptions(opts...),
+		parameters: controller.NewDecoder(),
+		devices:    make(map[int]*device),
+	}
+}
+
+// Decode follows event and appends the events of the notes it changes to dst, returning
+// the extended slice: a note on or off, or the pitch bend, channel pressure or timbre
+// controller of a member channel, which change the notes of that channel, or the pitch
+// bend of a manager channel, which changes every note of its zone. Notes played on the
+// manager channel are reported too, bent by the manager channel only. MPE configuration
+// messages (registered parameter 6 on a manager channel) and pitch bend range messages
+// (registered parameter 0) reconfigure the zones of the device; events of channels
+// outside the zones are ignored.
+func (t *Tracker) Decode(dst []Event, event contracts.MIDI) []Event {
+	parameter, isParameter := t.parameters.Decode(event)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d := t.device(event.SourceDeviceID)
+	if isParameter && parameter.Kind == controller.RPN {
+		if parameter.HasValue {
+			d.configure(parameter)
+		}
+		return dst
+	}
+
+	channel := event.Channel()
+	zone, ok := d.zone(channel)
+	if !ok {
+		return dst
+	}
+	state := &d.channels[channel]
+	base := Event{Timestamp: event.Timestamp, Channel: channel, SourceDeviceID: event.SourceDeviceID}
+
+	switch event.Type() {
+	case contracts.MessageNoteOn:
+		t.lastID++
+		started := note{id: t.lastID, number: event.Note, velocity: event.Velocity}
+		d.notes[channel] = append(d.notes[channel], started)
+		return append(dst, d.event(base, NoteOn, zone, started))
+	case contracts.MessageNoteOff:
+		notes := d.notes[channel]
+		i := slices.IndexFunc(notes, func(n note) bool { return n.number == event.Note })
+		if i < 0 {
+			return dst
+		}
+		ended := notes[i]
+		ended.velocity = event.Velocity
+		d.notes[channel] = slices.Delete(notes, i, i+1)
+		return append(dst, d.event(base, NoteOff, zone, ended))
+	case contracts.MessagePitchBend:
+		state.bend = event.PitchBend()
+		if channel != zone.Manager() {
+			return d.events(dst, base, PitchBend, zone, channel)
+		}
+		for member := range d.channels {
+			if byte(member) == channel || zone.Contains(byte(member)) {
+				dst = d.events(dst, base, PitchBend, zone, byte(member))
+			}
+		}
+		return dst
+	case contracts.MessageChannelAftertouch:
+		state.pressure = event.Pressure() & 0x7F
+		return d.events(dst, base, Pressure, zone, channel)
+	case contracts.MessageControlChange:
+		if event.Controller() != ccTimbre {
+			return dst
+		}
+		state.timbre = event.Value() & 0x7F
+		return d.events(dst, base, Timbre, zone, channel)
+	}
+	return dst
+}
+
+// Zones returns the lower and upper zones of the device with the given ID, as configured
+// with WithZones or by the last MPE configuration messages of the device. A zone with
+// zero members is disabled.
+func (t *Tracker) Zones(deviceID int) (lower, upper Zone) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d := t.device(deviceID)
+	return d.zones[0], d.zones[1]
+}
+
+// Reset forgets the sounding notes, the expression of the channels and the zones
+// configured by the devices.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.devices)
+	t.parameters.Reset()
+}
+
+// device returns the state of the device with the given ID, creating it with the zones of
+// the options. The caller must hold t.mu.
+func (t *Tracker) device(id int) *device {
+	if d, ok := t.devices[id]; ok {
+		return d
+	}
+	d := &device{zones: [2]Zone{LowerZone(0), UpperZone(0)}}
+	for _, zone := range t.options.Zones {
+		d.setZone(zone)
+	}
+	for channel := range d.channels {
+		d.channels[channel].timbre = defaultTimbre
+	}
+	t.devices[id] = d
+	return d
+}
+
+// setZone replaces the zone of the same kind as zone, shrinking the other zone if they
+// overlap, as the MPE specification requires.
+func (d *device) setZone(zone Zone) {
+	zone.Members = min(max(zone.Members, 0), MaxMembers)
+	index, other := 0, 1
+	if zone.Upper {
+		index, other = 1, 0
+	}
+	d.zones[index] = zone.withDefaults()
+	if free := MaxMembers - 1 - zone.Members; d.zones[other].Members > free {
+		d.zones[other].Members = max(free, 0)
+	}
+}
+
+// configure applies an MPE configuration or pitch bend range message.
+func (d *device) configure(parameter controller.Parameter) {
+	switch parameter.Number {
+	case controller.MPEConfiguration:
+		switch parameter.Channel {
+		case 0:
+			d.setZone(LowerZone(int(parameter.MSB())))
+		case 15:
+			d.setZone(UpperZone(int(parameter.MSB())))
+		}
+	case controller.PitchBendSensitivity:
+		zone, ok := d.zone(parameter.Channel)
+		if !ok {
+			return
+		}
+		index := 0
+		if zone.Upper {
+			index = 1
+		}
+		semitones := float64(parameter.MSB()) + float64(parameter.LSB())/100
+		if parameter.Channel == zone.Manager() {
+			d.zones[index].ManagerBendRange = semitones
+		} else {
+			d.zones[index].MemberBendRange = semitones
+		}
+	}
+}
+
+// zone returns the zone channel is the manager or a member channel of, and whether there
+// is one.
+func (d *device) zone(channel byte) (Zone, bool) {
+	for _, zone := range d.zones {
+		if zone.Members > 0 && (channel == zone.Manager() || zone.Contains(channel)) {
+			return zone, true
+		}
+	}
+	return Zone{}, false
+}
+
+// events appends an event of kind for every note sounding on channel to dst.
+func (d *device) events(dst []Event, base Event, kind Kind, zone Zone, channel byte) []Event {
+	base.Channel = channel
+	for _, sounding := range d.notes[channel] {
+		dst = append(dst, d.event(base, kind, zone, sounding))
+	}
+	return dst
+}
+
+// event completes base with kind and the note and expression of n, sounding on the
+// channel of base in zone.
+func (d *device) event(base Event, kind Kind, zone Zone, n note) Event {
+	state := d.channels[base.Channel]
+	manager := d.channels[zone.Manager()]
+
+	base.Kind, base.NoteID, base.Note, base.Velocity = kind, n.id, n.number, n.velocity
+	base.PitchBend = float64(manager.bend) / 8192 * zone.ManagerBendRange
+	if base.Channel != zone.Manager() {
+		base.PitchBend += float64(state.bend) / 8192 * zone.MemberBendRange
+	}
+	base.Pressure = float64(state.pressure) / 127
+	base.Timbre = float64(state.timbre) / 127
+	return base
+}
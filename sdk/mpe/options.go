@@ -0,0 +1,27 @@
+package mpe
+
+// Options holds the configuration of a Tracker.
+type Options struct {
+	Zones []Zone // Zones of every device until it sends an MPE configuration message.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithZones sets the zones of the devices until they send an MPE configuration message,
+// for controllers that do not send one. A lower zone with 15 member channels, the setup
+// of most controllers, is assumed by default.
+func WithZones(zones ...Zone) Option {
+	return func(opts *Options) {
+		opts.Zones = append([]Zone{}, zones...)
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{Zones: []Zone{LowerZone(MaxMembers)}}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
@@ -0,0 +1,106 @@
+package notation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WriteABC writes the sequence to w as an ABC 2.1 tune, with a voice for each channel,
+// laid out like WriteMusicXML. The unit note length is one division, so lengths are
+// multiples of the grid of the sequence.
+//
+// w io.Writer: The destination, typically a .abc file.
+// s Sequence: The notes to write, with their tempo and time signature.
+//
+// Returns:
+//   - error: ErrInvalidDivisions, ErrInvalidTimeSignature or the error of w, if any.
+func WriteABC(w io.Writer, s Sequence) error {
+	l, err := s.layout()
+	if err != nil {
+		return err
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "X:1\n")
+	if l.title != "" {
+		fmt.Fprintf(b, "T:%s\n", strings.ReplaceAll(l.title, "\n", " "))
+	}
+	fmt.Fprintf(b, "M:%d/%d\nL:1/%d\nQ:1/4=%d\n", l.timeSignature.Beats, l.timeSignature.BeatType, 4*l.divisions, int(math.Round(l.tempo)))
+	for _, p := range l.parts {
+		clef := "treble"
+		if p.bass {
+			clef = "bass"
+		}
+		fmt.Fprintf(b, "V:%d name=\"Channel %d\" clef=%s\n", p.channel+1, p.channel+1, clef)
+	}
+	fmt.Fprintf(b, "K:C\n")
+	for _, p := range l.parts {
+		fmt.Fprintf(b, "V:%d\n", p.channel+1)
+		for i, measure := range p.measures {
+			// Accidentals last until the end of the measure, so naturals after sharps of
+			// the same pitch are written explicitly.
+			sharpened := make(map[byte]bool)
+			words := make([]string, 0, len(measure))
+			for _, e := range measure {
+				words = append(words, abcElement(e, sharpened))
+			}
+			bar := "|"
+			if i == len(p.measures)-1 {
+				bar = "|]"
+			}
+			fmt.Fprintf(b, "%s %s\n", strings.Join(words, " "), bar)
+		}
+	}
+	return b.Flush()
+}
+
+// abcElement writes a note, chord or rest, recording the sharpened pitches of the measure.
+func abcElement(e element, sharpened map[byte]bool) string {
+	var word strings.Builder
+	switch len(e.keys) {
+	case 0:
+		word.WriteString("z")
+	case 1:
+		word.WriteString(abcPitch(e.keys[0], sharpened))
+	default:
+		word.WriteString("[")
+		for _, key := range e.keys {
+			word.WriteString(abcPitch(key, sharpened))
+		}
+		word.WriteString("]")
+	}
+	if e.duration != 1 {
+		word.WriteString(strconv.Itoa(e.duration))
+	}
+	if e.tieStart {
+		word.WriteString("-")
+	}
+	return word.String()
+}
+
+// abcPitch writes a pitch: C is middle C (60), c the octave above.
+func abcPitch(key byte, sharpened map[byte]bool) string {
+	step := steps[key%12]
+	natural := key - byte(step.alter) // Key of the unaltered step, which accidentals apply to.
+	accidental := ""
+	switch {
+	case step.alter > 0:
+		accidental = "^"
+		sharpened[natural] = true
+	case sharpened[natural]:
+		accidental = "="
+		delete(sharpened, natural)
+	}
+
+	octave := int(key)/12 - 1
+	switch {
+	case octave >= 5:
+		return accidental + strings.ToLower(step.step) + strings.Repeat("'", octave-5)
+	default:
+		return accidental + step.step + strings.Repeat(",", max(4-octave, 0))
+	}
+}
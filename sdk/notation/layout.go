@@ -0,0 +1,164 @@
+package notation
+
+import (
+	"math"
+	"math/bits"
+	"slices"
+	"time"
+)
+
+// maxDivisions is the finest grid, in divisions per quarter note: 1024th notes.
+const maxDivisions = 256
+
+// element is a note, chord or rest laid out in a measure, with a single written value.
+type element struct {
+	keys     []byte // Keys of the note or chord, ascending; empty for a rest.
+	duration int    // Length in divisions.
+	value    int    // Written value: 0 for a whole note, 1 for a half, 2 for a quarter and so on.
+	dotted   bool   // Whether the value is dotted.
+	tieStop  bool   // Whether the element continues the previous one.
+	tieStart bool   // Whether the element continues in the next one.
+}
+
+// part is the layout of the notes of one channel.
+type part struct {
+	channel  byte
+	bass     bool        // Whether the notes are mostly below middle C.
+	measures [][]element // Measures, each filled with elements.
+}
+
+// layout is a Sequence quantized and split into measures.
+type layout struct {
+	title         string
+	tempo         float64
+	timeSignature TimeSignature
+	divisions     int // Divisions per quarter note.
+	measure       int // Length of a measure in divisions.
+	parts         []part
+}
+
+// layout quantizes the notes of the sequence and splits them into measures.
+func (s Sequence) layout() (layout, error) {
+	l := layout{title: s.Title, tempo: s.Tempo, timeSignature: s.TimeSignature, divisions: s.Divisions}
+	if l.tempo <= 0 {
+		l.tempo = DefaultTempo
+	}
+	if l.timeSignature == (TimeSignature{}) {
+		l.timeSignature = TimeSignature{Beats: 4, BeatType: 4}
+	}
+	if l.divisions == 0 {
+		l.divisions = DefaultDivisions
+	}
+	if l.divisions < 0 || l.divisions > maxDivisions || bits.OnesCount(uint(l.divisions)) != 1 {
+		return layout{}, ErrInvalidDivisions
+	}
+	beat := 4 * l.divisions / max(l.timeSignature.BeatType, 1)
+	if l.timeSignature.Beats <= 0 || l.timeSignature.BeatType <= 0 || bits.OnesCount(uint(l.timeSignature.BeatType)) != 1 ||
+		beat == 0 || beat*l.timeSignature.BeatType != 4*l.divisions {
+		return layout{}, ErrInvalidTimeSignature
+	}
+	l.measure = l.timeSignature.Beats * beat
+
+	tick := time.Duration(float64(time.Minute) / l.tempo / float64(l.divisions))
+	quantize := func(d time.Duration) int {
+		return int(math.Round(float64(d) / float64(tick)))
+	}
+
+	var channels [16][]Note
+	for _, note := range s.Notes {
+		channels[note.Channel&0x0F] = append(channels[note.Channel&0x0F], note)
+	}
+	for channel, notes := range channels {
+		if len(notes) == 0 {
+			continue
+		}
+		l.parts = append(l.parts, l.part(byte(channel), notes, quantize))
+	}
+	return l, nil
+}
+
+// part lays out the notes of a channel.
+func (l layout) part(channel byte, notes []Note, quantize func(time.Duration) int) part {
+	// Group the notes starting on the same division into chords, lasting as long as their
+	// longest note but no further than the next chord.
+	type chord struct {
+		start, end int
+		keys       []byte
+	}
+	var chords []chord
+	sum := 0
+	for _, note := range notes {
+		sum += int(note.Key)
+		start := quantize(note.Start)
+		end := max(quantize(note.Start+note.Duration), start+1)
+		i := slices.IndexFunc(chords, func(c chord) bool { return c.start == start })
+		if i < 0 {
+			chords = append(chords, chord{start: start, end: end})
+			i = len(chords) - 1
+		}
+		chords[i].end = max(chords[i].end, end)
+		if !slices.Contains(chords[i].keys, note.Key) {
+			chords[i].keys = append(chords[i].keys, note.Key)
+		}
+	}
+	slices.SortFunc(chords, func(x, y chord) int { return x.start - y.start })
+
+	p := part{channel: channel, bass: sum < 60*len(notes)}
+	var current []element
+	position := 0 // Position in divisions of the end of the elements laid out.
+	tied := false // Whether the last element laid out continues in the next one.
+	place := func(keys []byte, length int) {
+		for length > 0 {
+			piece := min(length, l.measure-position%l.measure)
+			for _, value := range l.split(piece) {
+				length -= value.duration
+				value.keys = keys
+				value.tieStop = tied
+				value.tieStart = len(keys) > 0 && length > 0
+				tied = value.tieStart
+				current = append(current, value)
+				position += value.duration
+				if position%l.measure == 0 {
+					p.measures = append(p.measures, current)
+					current = nil
+				}
+			}
+		}
+	}
+	for i, c := range chords {
+		slices.Sort(c.keys)
+		if c.start > position {
+			place(nil, c.start-position)
+		}
+		end := c.end
+		if i+1 < len(chords) {
+			end = min(end, chords[i+1].start)
+		}
+		place(c.keys, end-position)
+	}
+	if position%l.measure != 0 {
+		place(nil, l.measure-position%l.measure)
+	}
+	return p
+}
+
+// split splits a length in divisions into written values, longest first.
+func (l layout) split(length int) []element {
+	var elements []element
+	for length > 0 {
+		for value := 0; ; value++ {
+			base := 4 * l.divisions >> value
+			if dotted := base + base/2; base%2 == 0 && dotted <= length {
+				elements = append(elements, element{duration: dotted, value: value, dotted: true})
+				length -= dotted
+				break
+			}
+			if base <= length {
+				elements = append(elements, element{duration: base, value: value})
+				length -= base
+				break
+			}
+		}
+	}
+	return elements
+}
@@ -0,0 +1,89 @@
+package notation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// lilypondNames are the Lilypond names of the notes of an octave, spelled with sharps.
+var lilypondNames = [12]string{"c", "cis", "d", "dis", "e", "f", "fis", "g", "gis", "a", "ais", "b"}
+
+// WriteLilypond writes the sequence to w as a Lilypond score, with a staff for each channel
+// and absolute pitches, laid out like WriteMusicXML. The score has \layout and \midi blocks,
+// so Lilypond engraves it and renders it back to MIDI.
+//
+// w io.Writer: The destination, typically a .ly file.
+// s Sequence: The notes to write, with their tempo and time signature.
+//
+// Returns:
+//   - error: ErrInvalidDivisions, ErrInvalidTimeSignature or the error of w, if any.
+func WriteLilypond(w io.Writer, s Sequence) error {
+	l, err := s.layout()
+	if err != nil {
+		return err
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "\\version \"2.24.0\"\n\n")
+	if l.title != "" {
+		fmt.Fprintf(b, "\\header {\n  title = %s\n}\n\n", strconv.Quote(l.title))
+	}
+	fmt.Fprintf(b, "\\score {\n  <<\n")
+	for _, p := range l.parts {
+		clef := "treble"
+		if p.bass {
+			clef = "bass"
+		}
+		fmt.Fprintf(b, "    \\new Staff \\with { instrumentName = \"Channel %d\" } {\n", p.channel+1)
+		fmt.Fprintf(b, "      \\clef %s\n      \\time %d/%d\n      \\tempo 4 = %d\n",
+			clef, l.timeSignature.Beats, l.timeSignature.BeatType, int(math.Round(l.tempo)))
+		for _, measure := range p.measures {
+			words := make([]string, 0, len(measure))
+			for _, e := range measure {
+				words = append(words, lilypondElement(e))
+			}
+			fmt.Fprintf(b, "      %s |\n", strings.Join(words, " "))
+		}
+		fmt.Fprintf(b, "    }\n")
+	}
+	fmt.Fprintf(b, "  >>\n  \\layout { }\n  \\midi { }\n}\n")
+	return b.Flush()
+}
+
+// lilypondElement writes a note, chord or rest.
+func lilypondElement(e element) string {
+	var word strings.Builder
+	switch len(e.keys) {
+	case 0:
+		word.WriteString("r")
+	case 1:
+		word.WriteString(lilypondPitch(e.keys[0]))
+	default:
+		pitches := make([]string, len(e.keys))
+		for i, key := range e.keys {
+			pitches[i] = lilypondPitch(key)
+		}
+		word.WriteString("<" + strings.Join(pitches, " ") + ">")
+	}
+	word.WriteString(strconv.Itoa(1 << e.value))
+	if e.dotted {
+		word.WriteString(".")
+	}
+	if e.tieStart {
+		word.WriteString("~")
+	}
+	return word.String()
+}
+
+// lilypondPitch writes an absolute pitch: c is C3 (48) and c' middle C.
+func lilypondPitch(key byte) string {
+	octave := int(key)/12 - 4
+	if octave >= 0 {
+		return lilypondNames[key%12] + strings.Repeat("'", octave)
+	}
+	return lilypondNames[key%12] + strings.Repeat(",", -octave)
+}
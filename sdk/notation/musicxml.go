@@ -0,0 +1,202 @@
+package notation
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// musicXMLHeader starts MusicXML documents.
+const musicXMLHeader = xml.Header + `<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 4.0 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">` + "\n"
+
+// musicXMLTypes are the MusicXML names of the written values, by value.
+var musicXMLTypes = []string{"whole", "half", "quarter", "eighth", "16th", "32nd", "64th", "128th", "256th", "512th", "1024th"}
+
+// steps spell the notes of an octave, with the alteration of each.
+var steps = [12]struct {
+	step  string
+	alter int
+}{{"C", 0}, {"C", 1}, {"D", 0}, {"D", 1}, {"E", 0}, {"F", 0}, {"F", 1}, {"G", 0}, {"G", 1}, {"A", 0}, {"A", 1}, {"B", 0}}
+
+type xmlScore struct {
+	XMLName  xml.Name       `xml:"score-partwise"`
+	Version  string         `xml:"version,attr"`
+	Work     *xmlWork       `xml:"work,omitempty"`
+	Encoding xmlEncoding    `xml:"identification>encoding"`
+	Parts    []xmlScorePart `xml:"part-list>score-part"`
+	Music    []xmlPart      `xml:"part"`
+}
+
+type xmlWork struct {
+	Title string `xml:"work-title"`
+}
+
+type xmlEncoding struct {
+	Software string `xml:"software"`
+}
+
+type xmlScorePart struct {
+	ID         string `xml:"id,attr"`
+	Name       string `xml:"part-name"`
+	Instrument struct {
+		ID   string `xml:"id,attr"`
+		Name string `xml:"instrument-name"`
+	} `xml:"score-instrument"`
+	MIDI struct {
+		ID      string `xml:"id,attr"`
+		Channel int    `xml:"midi-channel"`
+	} `xml:"midi-instrument"`
+}
+
+type xmlPart struct {
+	ID       string       `xml:"id,attr"`
+	Measures []xmlMeasure `xml:"measure"`
+}
+
+type xmlMeasure struct {
+	Number     int            `xml:"number,attr"`
+	Attributes *xmlAttributes `xml:"attributes,omitempty"`
+	Direction  *xmlDirection  `xml:"direction,omitempty"`
+	Notes      []xmlNote      `xml:"note"`
+}
+
+type xmlAttributes struct {
+	Divisions int    `xml:"divisions"`
+	Fifths    int    `xml:"key>fifths"`
+	Beats     int    `xml:"time>beats"`
+	BeatType  int    `xml:"time>beat-type"`
+	ClefSign  string `xml:"clef>sign"`
+	ClefLine  int    `xml:"clef>line"`
+}
+
+type xmlDirection struct {
+	Placement string `xml:"placement,attr"`
+	BeatUnit  string `xml:"direction-type>metronome>beat-unit"`
+	PerMinute string `xml:"direction-type>metronome>per-minute"`
+	Sound     struct {
+		Tempo string `xml:"tempo,attr"`
+	} `xml:"sound"`
+}
+
+type xmlNote struct {
+	Chord     *struct{}     `xml:"chord,omitempty"`
+	Rest      *struct{}     `xml:"rest,omitempty"`
+	Pitch     *xmlPitch     `xml:"pitch,omitempty"`
+	Duration  int           `xml:"duration"`
+	Ties      []xmlTie      `xml:"tie"`
+	Voice     int           `xml:"voice"`
+	Type      string        `xml:"type"`
+	Dot       *struct{}     `xml:"dot,omitempty"`
+	Notations *xmlNotations `xml:"notations,omitempty"`
+}
+
+type xmlPitch struct {
+	Step   string `xml:"step"`
+	Alter  int    `xml:"alter,omitempty"`
+	Octave int    `xml:"octave"`
+}
+
+type xmlTie struct {
+	Type string `xml:"type,attr"`
+}
+
+type xmlNotations struct {
+	Tied []xmlTie `xml:"tied"`
+}
+
+// WriteMusicXML writes the sequence to w as a MusicXML 4.0 partwise score, with a part for
+// each channel. Notes are quantized to the divisions of the sequence and spelled with
+// sharps; notes starting together form chords, and each chord lasts until the next one at
+// most, so every part has a single voice.
+//
+// w io.Writer: The destination, typically a .musicxml file.
+// s Sequence: The notes to write, with their tempo and time signature.
+//
+// Returns:
+//   - error: ErrInvalidDivisions, ErrInvalidTimeSignature or the error of w, if any.
+func WriteMusicXML(w io.Writer, s Sequence) error {
+	l, err := s.layout()
+	if err != nil {
+		return err
+	}
+
+	score := xmlScore{Version: "4.0", Encoding: xmlEncoding{Software: "github.com/leandrodaf/midi"}}
+	if l.title != "" {
+		score.Work = &xmlWork{Title: l.title}
+	}
+	for i, p := range l.parts {
+		id := "P" + strconv.Itoa(i+1)
+		scorePart := xmlScorePart{ID: id, Name: fmt.Sprintf("Channel %d", p.channel+1)}
+		scorePart.Instrument.ID, scorePart.Instrument.Name = id+"-I1", scorePart.Name
+		scorePart.MIDI.ID, scorePart.MIDI.Channel = id+"-I1", int(p.channel)+1
+		score.Parts = append(score.Parts, scorePart)
+		score.Music = append(score.Music, l.musicXMLPart(id, p))
+	}
+
+	if _, err := io.WriteString(w, musicXMLHeader); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(score); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// musicXMLPart converts the layout of a part.
+func (l layout) musicXMLPart(id string, p part) xmlPart {
+	part := xmlPart{ID: id}
+	for i, elements := range p.measures {
+		measure := xmlMeasure{Number: i + 1}
+		if i == 0 {
+			measure.Attributes = &xmlAttributes{
+				Divisions: l.divisions,
+				Beats:     l.timeSignature.Beats,
+				BeatType:  l.timeSignature.BeatType,
+				ClefSign:  "G",
+				ClefLine:  2,
+			}
+			if p.bass {
+				measure.Attributes.ClefSign, measure.Attributes.ClefLine = "F", 4
+			}
+			tempo := strconv.FormatFloat(l.tempo, 'f', -1, 64)
+			measure.Direction = &xmlDirection{Placement: "above", BeatUnit: "quarter", PerMinute: tempo}
+			measure.Direction.Sound.Tempo = tempo
+		}
+		for _, e := range elements {
+			note := xmlNote{Duration: e.duration, Voice: 1, Type: musicXMLTypes[e.value]}
+			if e.dotted {
+				note.Dot = &struct{}{}
+			}
+			var ties []xmlTie
+			if e.tieStop {
+				ties = append(ties, xmlTie{Type: "stop"})
+			}
+			if e.tieStart {
+				ties = append(ties, xmlTie{Type: "start"})
+			}
+			if len(e.keys) == 0 {
+				note.Rest = &struct{}{}
+				measure.Notes = append(measure.Notes, note)
+				continue
+			}
+			for j, key := range e.keys {
+				note := note
+				if j > 0 {
+					note.Chord = &struct{}{}
+				}
+				note.Pitch = &xmlPitch{Step: steps[key%12].step, Alter: steps[key%12].alter, Octave: int(key)/12 - 1}
+				note.Ties = ties
+				if len(ties) > 0 {
+					note.Notations = &xmlNotations{Tied: ties}
+				}
+				measure.Notes = append(measure.Notes, note)
+			}
+		}
+		part.Measures = append(part.Measures, measure)
+	}
+	return part
+}
@@ -0,0 +1,100 @@
+// Package notation converts recorded events to music notation, so recordings can be
+// reviewed and printed in notation software: MusicXML, Lilypond and ABC. Events are paired into notes with Pair, laid
+// out in a Sequence with its tempo and time signature, quantized to a grid and split into
+// measures, with notes crossing barlines or lasting irregular lengths tied.
+package notation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Defaults of a Sequence.
+const (
+	DefaultTempo     = 120 // Quarter notes per minute.
+	DefaultDivisions = 4   // Sixteenth notes.
+)
+
+var (
+	// ErrInvalidDivisions is returned for a Sequence whose divisions are not a power of two
+	// up to 256.
+	ErrInvalidDivisions = errors.New("notation: divisions must be a power of two up to 256")
+	// ErrInvalidTimeSignature is returned for a Sequence whose time signature does not
+	// divide into the grid.
+	ErrInvalidTimeSignature = errors.New("notation: invalid time signature")
+)
+
+// Note is a played note, paired from its note on and note off messages.
+type Note struct {
+	Start    time.Duration // Time from the start of the recording.
+	Duration time.Duration // Time until the note was released.
+	Channel  byte          // Zero-based channel.
+	Key      byte          // Note number, 0 to 127; middle C is 60.
+	Velocity byte          // Velocity of the note on message.
+}
+
+// TimeSignature is the meter of a Sequence, such as 3/4.
+type TimeSignature struct {
+	Beats    int // Beats per measure.
+	BeatType int // Note value of a beat: 4 for quarter notes, 8 for eighth notes.
+}
+
+// Sequence is a recording to export.
+type Sequence struct {
+	Title         string        // Title of the piece; optional.
+	Tempo         float64       // Quarter notes per minute; DefaultTempo if zero.
+	TimeSignature TimeSignature // Meter; 4/4 if zero.
+	Divisions     int           // Subdivisions of a quarter note the notes are quantized to, a power of two; DefaultDivisions if zero.
+	Notes         []Note        // Notes, on any channels; each channel is exported as a part of its own.
+}
+
+// Pair pairs the note on and note off messages of events into notes, ordered by start.
+// Timestamps are taken as nanoseconds, the unit of the native backends, and starts are
+// measured from the first event. A note on with velocity zero ends a note like a note off,
+// a note struck again before being released ends the previous one, and notes still held
+// after the last event end with it.
+//
+// events []contracts.MIDI: The recorded events, in order.
+//
+// Returns:
+//   - []Note: The notes.
+func Pair(events []contracts.MIDI) []Note {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var notes []Note
+	var held [16][128]int // Index in notes plus one of the held notes; zero if released.
+	origin := events[0].Timestamp
+	at := func(event contracts.MIDI) time.Duration {
+		return time.Duration(event.Timestamp - origin)
+	}
+	release := func(channel, key byte, end time.Duration) {
+		if i := held[channel][key]; i > 0 {
+			notes[i-1].Duration = end - notes[i-1].Start
+			held[channel][key] = 0
+		}
+	}
+
+	for _, event := range events {
+		channel, key := event.Command&0x0F, event.Note&0x7F
+		switch {
+		case event.Command&0xF0 == 0x90 && event.Velocity > 0:
+			release(channel, key, at(event))
+			notes = append(notes, Note{Start: at(event), Channel: channel, Key: key, Velocity: event.Velocity})
+			held[channel][key] = len(notes)
+		case event.Command&0xF0 == 0x80, event.Command&0xF0 == 0x90:
+			release(channel, key, at(event))
+		}
+	}
+
+	end := at(events[len(events)-1])
+	for channel := range held {
+		for key := range held[channel] {
+			release(byte(channel), byte(key), end)
+		}
+	}
+	return notes
+}
@@ -0,0 +1,125 @@
+package notes
+
+import "slices"
+
+// Chord is a chord recognised in a set of notes.
+type Chord struct {
+	Root    PitchClass // Root of the chord.
+	Bass    PitchClass // Lowest note; differs from Root for inversions and slash chords.
+	Quality string     // Name of the quality, such as "major" or "minor seventh".
+	Symbol  string     // Suffix of the quality in chord symbols, such as "" for major or "m7".
+	Notes   []byte     // The notes forming the chord, in ascending order.
+}
+
+// String returns the chord symbol, such as "C", "F#m7" or "G7/B" when the bass is not the root.
+func (c Chord) String() string {
+	symbol := c.Root.String() + c.Symbol
+	if c.Bass != c.Root {
+		symbol += "/" + c.Bass.String()
+	}
+	return symbol
+}
+
+// Intervals returns the intervals of the notes of the chord above its lowest note.
+func (c Chord) Intervals() []Interval {
+	if len(c.Notes) == 0 {
+		return nil
+	}
+	intervals := make([]Interval, 0, len(c.Notes)-1)
+	for _, note := range c.Notes[1:] {
+		intervals = append(intervals, IntervalBetween(c.Notes[0], note))
+	}
+	return intervals
+}
+
+// chordQuality is a chord quality recognised by DetectChord.
+type chordQuality struct {
+	name      string
+	symbol    string
+	intervals []Interval // Pitch classes above the root, in semitones.
+	mask      uint16     // Pitch classes of the quality relative to the root, as bits.
+}
+
+// chordQualities lists the recognised qualities. When notes form several chords, none of
+// them on the bass, the first in this order is chosen.
+var chordQualities = []chordQuality{
+	{name: "major", symbol: "", intervals: []Interval{MajorThird, PerfectFifth}},
+	{name: "minor", symbol: "m", intervals: []Interval{MinorThird, PerfectFifth}},
+	{name: "dominant seventh", symbol: "7", intervals: []Interval{MajorThird, PerfectFifth, MinorSeventh}},
+	{name: "major seventh", symbol: "maj7", intervals: []Interval{MajorThird, PerfectFifth, MajorSeventh}},
+	{name: "minor seventh", symbol: "m7", intervals: []Interval{MinorThird, PerfectFifth, MinorSeventh}},
+	{name: "half-diminished seventh", symbol: "m7b5", intervals: []Interval{MinorThird, Tritone, MinorSeventh}},
+	{name: "diminished", symbol: "dim", intervals: []Interval{MinorThird, Tritone}},
+	{name: "diminished seventh", symbol: "dim7", intervals: []Interval{MinorThird, Tritone, MajorSixth}},
+	{name: "augmented", symbol: "aug", intervals: []Interval{MajorThird, MinorSixth}},
+	{name: "augmented seventh", symbol: "aug7", intervals: []Interval{MajorThird, MinorSixth, MinorSeventh}},
+	{name: "minor major seventh", symbol: "mMaj7", intervals: []Interval{MinorThird, PerfectFifth, MajorSeventh}},
+	{name: "suspended fourth", symbol: "sus4", intervals: []Interval{PerfectFourth, PerfectFifth}},
+	{name: "suspended second", symbol: "sus2", intervals: []Interval{MajorSecond, PerfectFifth}},
+	{name: "seventh suspended fourth", symbol: "7sus4", intervals: []Interval{PerfectFourth, PerfectFifth, MinorSeventh}},
+	{name: "sixth", symbol: "6", intervals: []Interval{MajorThird, PerfectFifth, MajorSixth}},
+	{name: "minor sixth", symbol: "m6", intervals: []Interval{MinorThird, PerfectFifth, MajorSixth}},
+	{name: "added ninth", symbol: "add9", intervals: []Interval{MajorSecond, MajorThird, PerfectFifth}},
+	{name: "minor added ninth", symbol: "madd9", intervals: []Interval{MajorSecond, MinorThird, PerfectFifth}},
+	{name: "dominant ninth", symbol: "9", intervals: []Interval{MajorSecond, MajorThird, PerfectFifth, MinorSeventh}},
+	{name: "major ninth", symbol: "maj9", intervals: []Interval{MajorSecond, MajorThird, PerfectFifth, MajorSeventh}},
+	{name: "minor ninth", symbol: "m9", intervals: []Interval{MajorSecond, MinorThird, PerfectFifth, MinorSeventh}},
+	{name: "dominant seventh without fifth", symbol: "7", intervals: []Interval{MajorThird, MinorSeventh}},
+	{name: "power", symbol: "5", intervals: []Interval{PerfectFifth}},
+}
+
+func init() {
+	for i := range chordQualities {
+		chordQualities[i].mask = 1
+		for _, interval := range chordQualities[i].intervals {
+			chordQualities[i].mask |= 1 << interval
+		}
+	}
+}
+
+// DetectChord names the chord formed by notes, in any order and octave, doublings
+// included. The root is taken on the bass whenever the notes form a chord on it, so C, E,
+// G and A in the bass make C6 while A in the bass makes Am7.
+//
+// notes []byte: The notes sounding together.
+//
+// Returns:
+//   - Chord: The recognised chord.
+//   - bool: false if the notes form no recognised chord, e.g. fewer than two pitch classes.
+func DetectChord(notes []byte) (Chord, bool) {
+	if len(notes) == 0 {
+		return Chord{}, false
+	}
+	sorted := slices.Sorted(slices.Values(notes))
+	sorted = slices.Compact(sorted)
+	var classes uint16
+	for _, note := range sorted {
+		classes |= 1 << (note % 12)
+	}
+	bass := PitchClassOf(sorted[0])
+
+	var found *chordQuality
+	var root PitchClass
+	for _, quality := range chordQualities {
+		for candidate := PitchClass(0); candidate < 12; candidate++ {
+			if rotate(classes, candidate) != quality.mask {
+				continue
+			}
+			if candidate == bass {
+				return Chord{Root: bass, Bass: bass, Quality: quality.name, Symbol: quality.symbol, Notes: sorted}, true
+			}
+			if found == nil {
+				found, root = &quality, candidate
+			}
+		}
+	}
+	if found == nil {
+		return Chord{}, false
+	}
+	return Chord{Root: root, Bass: bass, Quality: found.name, Symbol: found.symbol, Notes: sorted}, true
+}
+
+// rotate returns the pitch classes of classes relative to root.
+func rotate(classes uint16, root PitchClass) uint16 {
+	return (classes>>root | classes<<(12-root)) & 0xFFF
+}
@@ -0,0 +1,99 @@
+package notes
+
+import "strconv"
+
+// Interval is the distance between two notes, in semitones.
+type Interval int
+
+// Intervals within an octave.
+const (
+	Unison Interval = iota
+	MinorSecond
+	MajorSecond
+	MinorThird
+	MajorThird
+	PerfectFourth
+	Tritone
+	PerfectFifth
+	MinorSixth
+	MajorSixth
+	MinorSeventh
+	MajorSeventh
+	Octave
+)
+
+// intervalNames are the names of the intervals up to two octaves.
+var intervalNames = [...]string{
+	"unison", "minor second", "major second", "minor third", "major third", "perfect fourth",
+	"tritone", "perfect fifth", "minor sixth", "major sixth", "minor seventh", "major seventh",
+	"octave", "minor ninth", "major ninth", "minor tenth", "major tenth", "perfect eleventh",
+	"augmented eleventh", "perfect twelfth", "minor thirteenth", "major thirteenth",
+	"minor fourteenth", "major fourteenth", "double octave",
+}
+
+// intervalQualities and intervalNumbers make up the short names of the intervals within
+// an octave, such as "m3" or "P5".
+var (
+	intervalQualities = [12]string{"P", "m", "M", "m", "M", "P", "TT", "P", "m", "M", "m", "M"}
+	intervalNumbers   = [12]int{1, 2, 2, 3, 3, 4, 0, 5, 6, 6, 7, 7}
+)
+
+// IntervalBetween returns the interval between two notes, whichever is higher.
+func IntervalBetween(a, b byte) Interval {
+	if a > b {
+		a, b = b, a
+	}
+	return Interval(b - a)
+}
+
+// Simple returns the interval reduced to within an octave, an octave itself being kept.
+func (i Interval) Simple() Interval {
+	i = i.abs()
+	if i > Octave && i%12 == 0 {
+		return Octave
+	}
+	if i > Octave {
+		return i % 12
+	}
+	return i
+}
+
+// Compound reports whether the interval is larger than an octave.
+func (i Interval) Compound() bool {
+	return i.abs() > Octave
+}
+
+// String names the interval, such as "major third" or "minor ninth". Intervals beyond two
+// octaves are named after their simple interval and the octaves added.
+func (i Interval) String() string {
+	i = i.abs()
+	if int(i) < len(intervalNames) {
+		return intervalNames[i]
+	}
+	octaves := int(i) / 12
+	if i%12 == 0 {
+		return strconv.Itoa(octaves) + " octaves"
+	}
+	return intervalNames[i%12] + " plus " + strconv.Itoa(octaves) + " octaves"
+}
+
+// Short names the interval in the usual abbreviation, such as "M3", "P5", "TT" or "m9".
+func (i Interval) Short() string {
+	i = i.abs()
+	if i%12 == 0 && i > 0 {
+		return "P" + strconv.Itoa(int(i)/12*7+1)
+	}
+	semitones := int(i) % 12
+	if semitones == int(Tritone) {
+		return "TT"
+	}
+	return intervalQualities[semitones] + strconv.Itoa(intervalNumbers[semitones]+int(i)/12*7)
+}
+
+// abs returns the size of the interval regardless of its direction.
+func (i Interval) abs() Interval {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,162 @@
+// Package notes names MIDI notes and their frequencies, detects the intervals and chords
+// they form, and quantizes them to scales. Trackers follow the notes held on a keyboard
+// from captured events, and Quantize plugs into the transformation pipeline of the
+// client set with contracts.WithTransforms:
+//
+//	client, err := midi.NewMIDIClient(contracts.WithTransforms(
+//		notes.Quantize(notes.NewScale(notes.D, notes.Dorian), notes.Nearest),
+//	))
+//
+//	tracker := notes.NewTracker(notes.WithWindow(300 * time.Millisecond))
+//	go sink.Drain(eventChannel, tracker)
+//	chord, ok := tracker.Chord() // e.g. "Dm7" while D, F, A and C are held.
+//
+// Octaves follow scientific pitch notation, middle C (60) being C4, and frequencies are
+// those of twelve-tone equal temperament with A4 at 440 Hz; sdk/tuning covers other
+// temperaments.
+package notes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/tuning"
+)
+
+// ErrInvalidName is returned by Parse for text that is not a note name within the MIDI range.
+var ErrInvalidName = errors.New("invalid note name")
+
+// MiddleC is the note number of middle C, C4.
+const MiddleC = 60
+
+// PitchClass is a note regardless of its octave, from C (0) to B (11).
+type PitchClass int
+
+// The twelve pitch classes, named with sharps.
+const (
+	C PitchClass = iota
+	CSharp
+	D
+	DSharp
+	E
+	F
+	FSharp
+	G
+	GSharp
+	A
+	ASharp
+	B
+)
+
+// sharpNames and flatNames are the names of the pitch classes with sharps and with flats.
+var (
+	sharpNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	flatNames  = [12]string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+)
+
+// letters are the pitch classes of the note letters.
+var letters = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// PitchClassOf returns the pitch class of note.
+func PitchClassOf(note byte) PitchClass {
+	return PitchClass(note % 12)
+}
+
+// String names the pitch class with a sharp if it has an accidental, such as "F#".
+func (p PitchClass) String() string {
+	return sharpNames[p.normalize()]
+}
+
+// Flat names the pitch class with a flat if it has an accidental, such as "Gb".
+func (p PitchClass) Flat() string {
+	return flatNames[p.normalize()]
+}
+
+// normalize returns p within 0-11.
+func (p PitchClass) normalize() int {
+	return (int(p)%12 + 12) % 12
+}
+
+// OctaveOf returns the octave of note, middle C starting octave 4 and note 0 octave -1.
+func OctaveOf(note byte) int {
+	return int(note)/12 - 1
+}
+
+// Name returns the name of note with its octave, using sharps, such as "C#4" for 61.
+func Name(note byte) string {
+	return PitchClassOf(note).String() + strconv.Itoa(OctaveOf(note))
+}
+
+// FlatName returns the name of note with its octave, using flats, such as "Db4" for 61.
+func FlatName(note byte) string {
+	return PitchClassOf(note).Flat() + strconv.Itoa(OctaveOf(note))
+}
+
+// Parse returns the note number of a note name such as "C4", "f#3", "Bb-1" or "E♭5": a
+// letter, any number of sharps (#, ♯) or flats (b, ♭), and an octave, middle C being C4.
+//
+// name string: The note name.
+//
+// Returns:
+//   - byte: The note number.
+//   - error: ErrInvalidName if name is not a note or is outside the MIDI range.
+func Parse(name string) (byte, error) {
+	text := strings.TrimSpace(name)
+	if text == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	pitch, ok := letters[strings.ToUpper(text[:1])[0]]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	rest := text[1:]
+	for {
+		switch {
+		case strings.HasPrefix(rest, "#"):
+			pitch, rest = pitch+1, rest[1:]
+		case strings.HasPrefix(rest, "♯"):
+			pitch, rest = pitch+1, rest[len("♯"):]
+		case strings.HasPrefix(rest, "b"):
+			pitch, rest = pitch-1, rest[1:]
+		case strings.HasPrefix(rest, "♭"):
+			pitch, rest = pitch-1, rest[len("♭"):]
+		default:
+			octave, err := strconv.Atoi(rest)
+			note := (octave+1)*12 + pitch
+			if err != nil || note < 0 || note > 127 {
+				return 0, fmt.Errorf("%w: %q", ErrInvalidName, name)
+			}
+			return byte(note), nil
+		}
+	}
+}
+
+// Frequency returns the frequency of note in Hz, in twelve-tone equal temperament with A4
+// at 440 Hz.
+func Frequency(note byte) float64 {
+	return tuning.Frequency(float64(note))
+}
+
+// FromFrequency returns the note closest to a frequency and how far the frequency is from
+// it, in cents between -50 and 50, such as for the pitch reported by a tuner.
+//
+// frequency float64: The frequency in Hz.
+//
+// Returns:
+//   - byte: The closest note.
+//   - float64: The deviation of frequency from the note, in cents.
+//   - bool: false if the closest note is outside the MIDI range.
+func FromFrequency(frequency float64) (byte, float64, bool) {
+	if !(frequency > 0) {
+		return 0, 0, false
+	}
+	exact := tuning.Note(frequency)
+	note := math.Round(exact)
+	if note < 0 || note > 127 {
+		return 0, 0, false
+	}
+	return byte(note), (exact - note) * 100, true
+}
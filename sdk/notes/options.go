@@ -0,0 +1,43 @@
+package notes
+
+import "time"
+
+// DefaultWindow is how long released notes still count towards the chord of a Tracker
+// unless set otherwise with WithWindow.
+const DefaultWindow = 250 * time.Millisecond
+
+// Options holds the configuration of a Tracker.
+type Options struct {
+	Window  time.Duration // How long released notes still count towards the chord.
+	OnChord func(Chord)   // Called when the chord formed by the notes changes, if set.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithWindow sets how long released notes still count towards the chord, so chords
+// played as arpeggios or with detached notes are recognised. Zero only counts the notes
+// held. DefaultWindow is used by default.
+func WithWindow(window time.Duration) Option {
+	return func(opts *Options) {
+		opts.Window = max(window, 0)
+	}
+}
+
+// WithOnChord sets a function called with every new chord the notes form, e.g. to show
+// the chord being played. It is called outside the lock of the Tracker, from the
+// goroutine writing events.
+func WithOnChord(onChord func(Chord)) Option {
+	return func(opts *Options) {
+		opts.OnChord = onChord
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{Window: DefaultWindow}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
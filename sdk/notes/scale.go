@@ -0,0 +1,154 @@
+package notes
+
+import (
+	"slices"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Mode is the pattern of a scale: the pitch classes it contains above its root, in
+// ascending order and starting with 0.
+type Mode []Interval
+
+// Common modes.
+var (
+	Major           = Mode{0, 2, 4, 5, 7, 9, 11}
+	NaturalMinor    = Mode{0, 2, 3, 5, 7, 8, 10}
+	HarmonicMinor   = Mode{0, 2, 3, 5, 7, 8, 11}
+	MelodicMinor    = Mode{0, 2, 3, 5, 7, 9, 11}
+	Dorian          = Mode{0, 2, 3, 5, 7, 9, 10}
+	Phrygian        = Mode{0, 1, 3, 5, 7, 8, 10}
+	Lydian          = Mode{0, 2, 4, 6, 7, 9, 11}
+	Mixolydian      = Mode{0, 2, 4, 5, 7, 9, 10}
+	Locrian         = Mode{0, 1, 3, 5, 6, 8, 10}
+	MajorPentatonic = Mode{0, 2, 4, 7, 9}
+	MinorPentatonic = Mode{0, 3, 5, 7, 10}
+	Blues           = Mode{0, 3, 5, 6, 7, 10}
+	WholeTone       = Mode{0, 2, 4, 6, 8, 10}
+	Chromatic       = Mode{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+)
+
+// Direction is the way Quantize moves notes outside a scale.
+type Direction int
+
+const (
+	Nearest Direction = iota // To the closest note of the scale, the lower one on a tie.
+	Up                       // To the next note of the scale above.
+	Down                     // To the next note of the scale below.
+)
+
+// Scale is a mode played from a root, such as D Dorian. Scales are created with NewScale.
+type Scale struct {
+	Root    PitchClass // Root of the scale.
+	Mode    Mode       // Pitch classes of the scale above its root.
+	classes uint16     // Pitch classes of the scale, as bits.
+}
+
+// NewScale creates the scale of mode played from root.
+//
+// root PitchClass: The root of the scale.
+// mode Mode: The pattern of the scale, such as Major or Dorian.
+//
+// Returns:
+//   - Scale: The scale.
+func NewScale(root PitchClass, mode Mode) Scale {
+	s := Scale{Root: PitchClass(root.normalize()), Mode: mode}
+	for _, interval := range mode {
+		s.classes |= 1 << ((int(s.Root) + int(interval)%12 + 12) % 12)
+	}
+	return s
+}
+
+// Contains reports whether note belongs to the scale, in any octave.
+func (s Scale) Contains(note byte) bool {
+	return s.classes&(1<<(note%12)) != 0
+}
+
+// Degree returns the position of note in the scale, the root being degree 1.
+//
+// note byte: The note.
+//
+// Returns:
+//   - int: The degree of note, from 1.
+//   - bool: false if note is not in the scale.
+func (s Scale) Degree(note byte) (int, bool) {
+	interval := Interval((int(note%12) - int(s.Root) + 12) % 12)
+	index := slices.Index(s.Mode, interval)
+	return index + 1, index >= 0
+}
+
+// Notes returns the notes of the scale from low to high, both included.
+func (s Scale) Notes(low, high byte) []byte {
+	var notes []byte
+	for note := int(low); note <= int(high); note++ {
+		if s.Contains(byte(note)) {
+			notes = append(notes, byte(note))
+		}
+	}
+	return notes
+}
+
+// Quantize returns the note of the scale note moves to in direction, note itself if it
+// belongs to the scale. Notes are kept within the MIDI range, moving the other way at its
+// ends.
+//
+// note byte: The note to quantize.
+// direction Direction: The way to move notes outside the scale.
+//
+// Returns:
+//   - byte: The quantized note.
+//   - bool: false if the scale is empty.
+func (s Scale) Quantize(note byte, direction Direction) (byte, bool) {
+	if s.classes == 0 {
+		return note, false
+	}
+	if s.Contains(note) {
+		return note, true
+	}
+	up, upOK := s.search(note, 1)
+	down, downOK := s.search(note, -1)
+	switch {
+	case !upOK:
+		return down, true
+	case !downOK:
+		return up, true
+	case direction == Up:
+		return up, true
+	case direction == Down:
+		return down, true
+	case up-note < note-down:
+		return up, true
+	}
+	return down, true
+}
+
+// search returns the closest note of the scale from note in steps of step, within the
+// MIDI range.
+func (s Scale) search(note byte, step int) (byte, bool) {
+	for n := int(note) + step; n >= 0 && n <= 127; n += step {
+		if s.Contains(byte(n)) {
+			return byte(n), true
+		}
+	}
+	return 0, false
+}
+
+// Quantize returns a transform moving the notes of note on, note off and poly aftertouch
+// messages into scale, e.g. so a performance only plays the notes of a key. Quantizing
+// maps each note the same way, so the note offs of moved notes stay paired with their
+// note ons as long as the scale is unchanged. Other messages pass unchanged.
+//
+// scale Scale: The scale notes are moved into.
+// direction Direction: The way to move notes outside the scale.
+//
+// Returns:
+//   - contracts.Transform: The transform, to add with contracts.WithTransforms.
+func Quantize(scale Scale, direction Direction) contracts.Transform {
+	return func(event contracts.MIDI) (contracts.MIDI, bool) {
+		switch event.Command & 0xF0 {
+		case 0x80, 0x90, 0xA0:
+			event.Note, _ = scale.Quantize(event.Note, direction)
+		}
+		return event, true
+	}
+}
@@ -0,0 +1,172 @@
+package notes
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+const (
+	ccAllSoundOff = 120 // Control change silencing every note of a channel.
+	ccAllNotesOff = 123 // Control change releasing every note of a channel.
+)
+
+// key identifies a sounding note.
+type key struct {
+	device  int
+	channel byte
+	note    byte
+}
+
+// Tracker follows the notes played on every device and channel and the chord and
+// interval they form. It implements sink.Sink, to be fed with sink.Drain or written to
+// from a capture callback, and is safe for concurrent use.
+type Tracker struct {
+	options Options
+	now     func() time.Time // Returns the time events are written at.
+
+	mu       sync.Mutex
+	held     map[key]struct{}  // Notes held down.
+	released map[key]time.Time // Notes released within the window, with the time of their release.
+	chord    string            // Symbol of the last chord reported to OnChord.
+}
+
+// NewTracker creates a tracker without notes.
+//
+// opts ...Option: Optional settings such as WithWindow and WithOnChord.
+//
+// Returns:
+//   - *Tracker: The tracker, ready to be written events.
+func NewTracker(opts ...Option) *Tracker {
+	return &Tracker{
+		options:  applyDefaultOptions(opts...),
+		now:      time.Now,
+		held:     make(map[key]struct{}),
+		released: make(map[key]time.Time),
+	}
+}
+
+// Write follows event: note ons add notes, note offs release them, and all sound off and
+// all notes off controllers release every note of their channel at once, without the
+// window. Other messages are ignored. OnChord is called when the notes then form a chord
+// other than the last one reported.
+func (t *Tracker) Write(event contracts.MIDI) error {
+	if event.Command < 0x80 || event.Command >= 0xF0 {
+		return nil
+	}
+	k := key{device: event.SourceDeviceID, channel: event.Command & 0x0F, note: event.Note}
+
+	t.mu.Lock()
+	now := t.now()
+	switch event.Command & 0xF0 {
+	case 0x90:
+		if event.Velocity > 0 {
+			t.held[k] = struct{}{}
+			delete(t.released, k)
+			break
+		}
+		fallthrough
+	case 0x80:
+		if _, ok := t.held[k]; ok {
+			delete(t.held, k)
+			t.released[k] = now
+		}
+	case 0xB0:
+		if event.Note != ccAllSoundOff && event.Note != ccAllNotesOff {
+			t.mu.Unlock()
+			return nil
+		}
+		for held := range t.held {
+			if held.device == k.device && held.channel == k.channel {
+				delete(t.held, held)
+			}
+		}
+		for released := range t.released {
+			if released.device == k.device && released.channel == k.channel {
+				delete(t.released, released)
+			}
+		}
+	default:
+		t.mu.Unlock()
+		return nil
+	}
+	notes := t.notes(now)
+	chord, ok := DetectChord(notes)
+	symbol := ""
+	if ok {
+		symbol = chord.String()
+	}
+	changed := ok && symbol != t.chord
+	t.chord = symbol
+	t.mu.Unlock()
+
+	if changed && t.options.OnChord != nil {
+		t.options.OnChord(chord)
+	}
+	return nil
+}
+
+// Close implements sink.Sink; the tracker holds no resources.
+func (t *Tracker) Close() error {
+	return nil
+}
+
+// Reset forgets every note, e.g. after the capture stopped with notes held.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.held)
+	clear(t.released)
+	t.chord = ""
+}
+
+// Notes returns the notes held or released within the window, in ascending order and
+// without duplicates across devices and channels.
+func (t *Tracker) Notes() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.notes(t.now())
+}
+
+// Chord returns the chord formed by the notes held or released within the window.
+//
+// Returns:
+//   - Chord: The chord.
+//   - bool: false if the notes form no recognised chord.
+func (t *Tracker) Chord() (Chord, bool) {
+	return DetectChord(t.Notes())
+}
+
+// Interval returns the interval between the notes held or released within the window
+// when there are exactly two of them.
+//
+// Returns:
+//   - Interval: The interval between the two notes.
+//   - bool: false if there are not exactly two notes.
+func (t *Tracker) Interval() (Interval, bool) {
+	notes := t.Notes()
+	if len(notes) != 2 {
+		return 0, false
+	}
+	return IntervalBetween(notes[0], notes[1]), true
+}
+
+// notes forgets the notes released before the window and returns the others, sorted.
+// t.mu must be held.
+func (t *Tracker) notes(now time.Time) []byte {
+	notes := make([]byte, 0, len(t.held)+len(t.released))
+	for k := range t.held {
+		notes = append(notes, k.note)
+	}
+	for k, at := range t.released {
+		if now.Sub(at) > t.options.Window {
+			delete(t.released, k)
+			continue
+		}
+		notes = append(notes, k.note)
+	}
+	slices.Sort(notes)
+	return slices.Compact(notes)
+}
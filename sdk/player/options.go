@@ -0,0 +1,94 @@
+package player
+
+import "time"
+
+// DefaultSpin is how long before an event is due the player stops sleeping and polls the
+// clock instead, unless WithSpin is given.
+const DefaultSpin = time.Millisecond
+
+// Options holds the configuration of a Player.
+type Options struct {
+	Speed         float64         // Playback speed; 2 plays twice as fast, 0.5 at half speed.
+	TimestampUnit time.Duration   // Duration of one unit of the event timestamps.
+	Origin        *uint64         // Timestamp of the start of the sequence; the first event by default.
+	Loop          bool            // Whether playback restarts from the start after the end.
+	Spin          time.Duration   // How long before an event is due the player polls the clock.
+	OnError       func(err error) // Called with the errors sending events; none by default.
+	OnEnd         func()          // Called when playback reaches the end; none by default.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithSpeed sets the playback speed, which can be changed while playing with SetSpeed.
+func WithSpeed(speed float64) Option {
+	return func(opts *Options) {
+		opts.Speed = speed
+	}
+}
+
+// WithTimestampUnit sets the duration of one unit of the event timestamps, which is a
+// nanosecond for the events of the native backends and of smf.File.Events.
+func WithTimestampUnit(unit time.Duration) Option {
+	return func(opts *Options) {
+		opts.TimestampUnit = unit
+	}
+}
+
+// WithOrigin sets the timestamp the sequence starts at instead of the first event,
+// keeping the silence before it.
+func WithOrigin(timestamp uint64) Option {
+	return func(opts *Options) {
+		opts.Origin = &timestamp
+	}
+}
+
+// WithLoop restarts playback from the start each time it reaches the end.
+func WithLoop() Option {
+	return func(opts *Options) {
+		opts.Loop = true
+	}
+}
+
+// WithSpin sets how long before an event is due the player stops sleeping and polls the
+// clock instead. Sleeping wakes up late by up to the timer resolution of the system;
+// polling keeps a CPU busy for up to spin per event but sends events on time. Zero turns
+// polling off.
+func WithSpin(spin time.Duration) Option {
+	return func(opts *Options) {
+		opts.Spin = spin
+	}
+}
+
+// WithOnError calls fn with the errors sending events. Playback goes on after them.
+func WithOnError(fn func(err error)) Option {
+	return func(opts *Options) {
+		opts.OnError = fn
+	}
+}
+
+// WithOnEnd calls fn each time playback reaches the end of the sequence without looping.
+func WithOnEnd(fn func()) Option {
+	return func(opts *Options) {
+		opts.OnEnd = fn
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{Spin: -1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Speed <= 0 {
+		options.Speed = 1
+	}
+	if options.TimestampUnit <= 0 {
+		options.TimestampUnit = time.Nanosecond
+	}
+	if options.Spin < 0 {
+		options.Spin = DefaultSpin
+	}
+	return options
+}
@@ -0,0 +1,398 @@
+// Package player plays sequences of timestamped events, recorded or read from Standard
+// MIDI Files, to an output device, with transport controls: play, pause, seek and speed.
+//
+// Events are scheduled on a dedicated OS thread with raised priority where the platform
+// supports it. The player sleeps until shortly before each event and polls the clock for
+// the rest of the wait, so events are sent within microseconds of their time instead of
+// the millisecond resolution of system timers.
+package player
+
+import (
+	"cmp"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/internal/threadprio"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/smf"
+)
+
+// Sender sends messages to an output device. contracts.ClientMIDI implements it once an
+// output device is selected with SelectOutputDevice.
+type Sender interface {
+	Send(event contracts.MIDI) error
+}
+
+// State is the transport state of a Player.
+type State int
+
+const (
+	Stopped State = iota // Not playing; Play starts from the position, or from the start at the end.
+	Playing              // Sending events as they are due.
+	Paused               // Holding its position.
+)
+
+// String names the state.
+func (s State) String() string {
+	switch s {
+	case Playing:
+		return "playing"
+	case Paused:
+		return "paused"
+	default:
+		return "stopped"
+	}
+}
+
+// Player plays a sequence of events to a Sender. Notes still sounding are turned off when
+// playback is paused, stopped or moved with Seek. It is safe for concurrent use.
+type Player struct {
+	out     Sender
+	options Options
+	events  []contracts.MIDI // Events of the sequence, ordered by time.
+	times   []time.Duration  // Time of each event from the start of the sequence.
+
+	sendMu sync.Mutex // Orders the events sent by the scheduler and the notes turned off.
+
+	mu         sync.Mutex
+	state      State
+	next       int           // Index of the next event to send.
+	anchorPos  time.Duration // Position at anchorTime.
+	anchorTime time.Time     // When playback was at anchorPos; set while playing.
+	speed      float64
+	generation uint64               // Incremented on every change of the transport.
+	sounding   map[[2]byte]struct{} // Status of the note on and note number of sounding notes.
+	wake       chan struct{}        // Signals the scheduler of a change of the transport.
+	done       chan struct{}        // Closed by Close.
+	wg         sync.WaitGroup       // Waits for the scheduler.
+	closeOnce  sync.Once
+}
+
+// New creates a stopped player of events, timed by their timestamps from the first event,
+// or the origin of the options.
+//
+// out Sender: The destination of the events, such as a client with an output device selected.
+// events []contracts.MIDI: The events to play; sorted by timestamp if they are not.
+// opts ...Option: A variadic list of option functions to customize the player.
+//
+// Returns:
+//   - *Player: The player, ready to Play.
+func New(out Sender, events []contracts.MIDI, opts ...Option) *Player {
+	p := &Player{
+		out:      out,
+		options:  applyDefaultOptions(opts...),
+		events:   slices.Clone(events),
+		sounding: make(map[[2]byte]struct{}),
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	p.speed = p.options.Speed
+	slices.SortStableFunc(p.events, func(x, y contracts.MIDI) int { return cmp.Compare(x.Timestamp, y.Timestamp) })
+
+	var origin uint64
+	if len(p.events) > 0 {
+		origin = p.events[0].Timestamp
+	}
+	if p.options.Origin != nil {
+		origin = *p.options.Origin
+	}
+	p.times = make([]time.Duration, len(p.events))
+	for i, event := range p.events {
+		p.times[i] = time.Duration(max(event.Timestamp, origin)-origin) * p.options.TimestampUnit
+	}
+
+	p.wg.Add(1)
+	profiling.Go(profiling.RoleScheduler, "player", "", p.schedule)
+	return p
+}
+
+// FromFile creates a stopped player of the messages of a Standard MIDI File, timed along
+// its tempo map from the start of the file.
+//
+// out Sender: The destination of the events, such as a client with an output device selected.
+// file *smf.File: The file to play, such as one read with smf.ReadFile.
+// opts ...Option: A variadic list of option functions to customize the player.
+//
+// Returns:
+//   - *Player: The player, ready to Play.
+func FromFile(out Sender, file *smf.File, opts ...Option) *Player {
+	opts = append([]Option{WithOrigin(0)}, opts...)
+	return New(out, file.Events(), append(opts, WithTimestampUnit(time.Nanosecond))...)
+}
+
+// Play starts or resumes playback from the current position, or from the start when the
+// player is at the end of the sequence.
+func (p *Player) Play() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == Playing {
+		return
+	}
+	if p.next >= len(p.events) {
+		p.moveLocked(0)
+	}
+	p.state = Playing
+	p.anchorTime = time.Now()
+	p.changedLocked()
+}
+
+// Pause holds the current position, turning off the sounding notes. Play resumes from it.
+func (p *Player) Pause() {
+	p.mu.Lock()
+	if p.state != Playing {
+		p.mu.Unlock()
+		return
+	}
+	p.anchorPos = p.positionLocked()
+	p.state = Paused
+	p.changedLocked()
+	notes := p.silenceLocked()
+	p.mu.Unlock()
+
+	p.send(notes)
+}
+
+// Stop stops playback and goes back to the start, turning off the sounding notes.
+func (p *Player) Stop() {
+	p.mu.Lock()
+	p.state = Stopped
+	p.moveLocked(0)
+	p.changedLocked()
+	notes := p.silenceLocked()
+	p.mu.Unlock()
+
+	p.send(notes)
+}
+
+// Seek moves playback to position from the start of the sequence, turning off the sounding
+// notes. Playback goes on from there if the player is playing.
+func (p *Player) Seek(position time.Duration) {
+	p.mu.Lock()
+	p.moveLocked(min(max(position, 0), p.durationLocked()))
+	if p.state == Playing {
+		p.anchorTime = time.Now()
+	}
+	p.changedLocked()
+	notes := p.silenceLocked()
+	p.mu.Unlock()
+
+	p.send(notes)
+}
+
+// SetSpeed changes the playback speed from the current position on; 2 plays twice as
+// fast, 0.5 at half speed. Speeds of zero or less are ignored.
+func (p *Player) SetSpeed(speed float64) {
+	if speed <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == Playing {
+		p.anchorPos, p.anchorTime = p.positionLocked(), time.Now()
+	}
+	p.speed = speed
+	p.changedLocked()
+}
+
+// State returns the transport state of the player.
+func (p *Player) State() State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// Position returns the current position from the start of the sequence.
+func (p *Player) Position() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.positionLocked()
+}
+
+// Duration returns the time of the last event from the start of the sequence.
+func (p *Player) Duration() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.durationLocked()
+}
+
+// Close stops playback, turning off the sounding notes, and stops the scheduler. The
+// player cannot be used afterwards.
+func (p *Player) Close() error {
+	p.Stop()
+	p.closeOnce.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+	})
+	return nil
+}
+
+// schedule sends the events as they are due, until Close.
+func (p *Player) schedule() {
+	defer p.wg.Done()
+
+	// The thread is not unlocked, so it exits with the goroutine instead of going back to
+	// the scheduler with a raised priority.
+	runtime.LockOSThread()
+	_ = threadprio.Raise() // Best effort; timing is still accurate with polling
+
+	for {
+		p.mu.Lock()
+		if p.state == Playing && p.next >= len(p.events) {
+			p.endLocked()
+		}
+		if p.state != Playing {
+			p.mu.Unlock()
+			select {
+			case <-p.done:
+				return
+			case <-p.wake:
+			}
+			continue
+		}
+		generation := p.generation
+		due := p.anchorTime.Add(time.Duration(float64(p.times[p.next]-p.anchorPos) / p.speed))
+		p.mu.Unlock()
+
+		if !p.wait(due) {
+			return
+		}
+
+		p.sendMu.Lock()
+		p.mu.Lock()
+		if p.generation != generation || time.Now().Before(due) {
+			p.mu.Unlock()
+			p.sendMu.Unlock()
+			continue
+		}
+		event := p.events[p.next]
+		p.next++
+		p.trackLocked(event)
+		p.mu.Unlock()
+		if err := p.out.Send(event); err != nil && p.options.OnError != nil {
+			p.options.OnError(err)
+		}
+		p.sendMu.Unlock()
+	}
+}
+
+// wait waits until due, sleeping until the spin time before it and polling the clock for
+// the rest. It returns early when woken up by a change of the transport, and reports false
+// after Close.
+func (p *Player) wait(due time.Time) bool {
+	if sleep := time.Until(due) - p.options.Spin; sleep > 0 {
+		timer := time.NewTimer(sleep)
+		defer timer.Stop()
+		select {
+		case <-p.done:
+			return false
+		case <-p.wake:
+			return true // The caller checks whether the event is still due
+		case <-timer.C:
+		}
+	}
+	for time.Now().Before(due) {
+		select {
+		case <-p.done:
+			return false
+		case <-p.wake:
+			return true
+		default:
+			runtime.Gosched()
+		}
+	}
+	return true
+}
+
+// wakeUp signals the scheduler without blocking.
+func (p *Player) wakeUp() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// endLocked handles the end of the sequence while playing: playback restarts from the
+// start when looping, or stops at the end. The caller must hold p.mu.
+func (p *Player) endLocked() {
+	if p.options.Loop && p.durationLocked() > 0 {
+		p.anchorTime = p.anchorTime.Add(time.Duration(float64(p.durationLocked()-p.anchorPos) / p.speed))
+		p.moveLocked(0)
+		return
+	}
+	p.anchorPos = p.durationLocked()
+	p.state = Stopped
+	p.generation++
+	if p.options.OnEnd != nil {
+		go p.options.OnEnd()
+	}
+}
+
+// moveLocked sets the position, and the next event to the first one at or after it. The
+// caller must hold p.mu.
+func (p *Player) moveLocked(position time.Duration) {
+	p.anchorPos = position
+	p.next, _ = slices.BinarySearch(p.times, position)
+}
+
+// changedLocked invalidates the event the scheduler waits for and wakes it up. The caller
+// must hold p.mu.
+func (p *Player) changedLocked() {
+	p.generation++
+	p.wakeUp()
+}
+
+// positionLocked returns the current position. The caller must hold p.mu.
+func (p *Player) positionLocked() time.Duration {
+	if p.state != Playing {
+		return p.anchorPos
+	}
+	position := p.anchorPos + time.Duration(float64(time.Since(p.anchorTime))*p.speed)
+	return min(position, p.durationLocked())
+}
+
+// durationLocked returns the time of the last event. The caller must hold p.mu.
+func (p *Player) durationLocked() time.Duration {
+	if len(p.times) == 0 {
+		return 0
+	}
+	return p.times[len(p.times)-1]
+}
+
+// trackLocked records the notes turned on and off by an event about to be sent. The
+// caller must hold p.mu.
+func (p *Player) trackLocked(event contracts.MIDI) {
+	switch event.Type() {
+	case contracts.MessageNoteOn:
+		p.sounding[[2]byte{event.Command, event.Note}] = struct{}{}
+	case contracts.MessageNoteOff:
+		delete(p.sounding, [2]byte{0x90 | event.Channel(), event.Note})
+	}
+}
+
+// silenceLocked returns note offs for the sounding notes and forgets them. The caller
+// must hold p.mu.
+func (p *Player) silenceLocked() []contracts.MIDI {
+	notes := make([]contracts.MIDI, 0, len(p.sounding))
+	for note := range p.sounding {
+		notes = append(notes, contracts.MIDI{Command: 0x80 | note[0]&0x0F, Note: note[1]})
+	}
+	clear(p.sounding)
+	return notes
+}
+
+// send sends events after any event the scheduler is sending.
+func (p *Player) send(events []contracts.MIDI) {
+	if len(events) == 0 {
+		return
+	}
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	for _, event := range events {
+		if err := p.out.Send(event); err != nil && p.options.OnError != nil {
+			p.options.OnError(err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package practice compares recorded performances with reference pieces, for practice and
+// training applications: timing deviations, wrong and missed notes and the evenness of
+// velocities, for the whole piece and for each of its sections.
+package practice
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/notation"
+)
+
+// SectionReport describes the performance of a section, or of the whole piece.
+type SectionReport struct {
+	Name                  string          // Name of the section; empty for the whole piece and the notes before the first marker.
+	Start                 time.Duration   // Start of the section.
+	End                   time.Duration   // End of the section, which is the start of the next one.
+	Notes                 int             // Reference notes of the section.
+	Played                int             // Reference notes matched by a played note.
+	Missed                []notation.Note // Reference notes that were not played.
+	Wrong                 []notation.Note // Played notes matching no reference note, with aligned starts.
+	MeanDeviation         time.Duration   // Mean timing deviation of the matched notes; negative when rushing, positive when dragging.
+	MeanAbsoluteDeviation time.Duration   // Mean of the absolute timing deviations.
+	MaxDeviation          time.Duration   // Largest absolute timing deviation.
+	MeanVelocity          float64         // Mean velocity of the matched notes.
+	VelocityDeviation     float64         // Standard deviation of the velocities of the matched notes; lower is more even.
+}
+
+// Accuracy returns the share of reference notes played, from 0 to 1; 1 for a section
+// without notes.
+func (s SectionReport) Accuracy() float64 {
+	if s.Notes == 0 {
+		return 1
+	}
+	return float64(s.Played) / float64(s.Notes)
+}
+
+// Report describes a performance compared with a reference.
+type Report struct {
+	Offset   time.Duration   // Shift subtracted from the played notes to align them with the reference.
+	Total    SectionReport   // The whole piece.
+	Sections []SectionReport // Each section of the reference, in order.
+}
+
+// accumulator sums the matched notes of a section.
+type accumulator struct {
+	report              *SectionReport
+	deviation, absolute time.Duration
+	velocity, squares   float64
+}
+
+// add records a reference note played with deviation and velocity.
+func (a *accumulator) add(deviation time.Duration, velocity byte) {
+	a.report.Played++
+	a.deviation += deviation
+	a.absolute += deviation.Abs()
+	a.report.MaxDeviation = max(a.report.MaxDeviation, deviation.Abs())
+	a.velocity += float64(velocity)
+	a.squares += float64(velocity) * float64(velocity)
+}
+
+// finish computes the means.
+func (a *accumulator) finish() {
+	if n := a.report.Played; n > 0 {
+		a.report.MeanDeviation = a.deviation / time.Duration(n)
+		a.report.MeanAbsoluteDeviation = a.absolute / time.Duration(n)
+		a.report.MeanVelocity = a.velocity / float64(n)
+		a.report.VelocityDeviation = math.Sqrt(max(a.squares/float64(n)-a.report.MeanVelocity*a.report.MeanVelocity, 0))
+	}
+}
+
+// Compare compares a performance with a reference. Each reference note is matched with the
+// closest unmatched played note of the same key within the tolerance, on any channel.
+//
+// reference Reference: The piece, typically read with ReadReference.
+// performance []notation.Note: The played notes, typically paired with notation.Pair.
+// opts ...Option: A variadic list of option functions to customize the comparison.
+//
+// Returns:
+//   - Report: The comparison, for the whole piece and each section.
+func Compare(reference Reference, performance []notation.Note, opts ...Option) Report {
+	options := applyDefaultOptions(opts...)
+	expected := slices.Clone(reference.Notes)
+	played := slices.Clone(performance)
+	byStart := func(x, y notation.Note) int { return cmp.Compare(x.Start, y.Start) }
+	slices.SortStableFunc(expected, byStart)
+	slices.SortStableFunc(played, byStart)
+
+	var report Report
+	if !options.Aligned && len(expected) > 0 && len(played) > 0 {
+		report.Offset = played[0].Start - expected[0].Start
+	}
+	for i := range played {
+		played[i].Start -= report.Offset
+	}
+
+	// Sections cover the piece from their start to the start of the next one, notes before
+	// the first marker forming an unnamed section.
+	var end time.Duration
+	for _, note := range slices.Concat(expected, played) {
+		end = max(end, note.Start+note.Duration)
+	}
+	sections := slices.Clone(reference.Sections)
+	if len(sections) == 0 || sections[0].Start > 0 {
+		sections = slices.Insert(sections, 0, Section{})
+	}
+	report.Total = SectionReport{End: end}
+	report.Sections = make([]SectionReport, len(sections))
+	for i, section := range sections {
+		report.Sections[i] = SectionReport{Name: section.Name, Start: section.Start, End: end}
+		if i+1 < len(sections) {
+			report.Sections[i].End = sections[i+1].Start
+		}
+	}
+	section := func(at time.Duration) int {
+		i, found := slices.BinarySearchFunc(report.Sections, at, func(s SectionReport, at time.Duration) int {
+			return cmp.Compare(s.Start, at)
+		})
+		if !found {
+			i--
+		}
+		return max(i, 0)
+	}
+
+	total := accumulator{report: &report.Total}
+	accumulators := make([]accumulator, len(report.Sections))
+	for i := range accumulators {
+		accumulators[i].report = &report.Sections[i]
+	}
+	matched := make([]bool, len(played))
+	for _, note := range expected {
+		s := section(note.Start)
+		report.Total.Notes++
+		report.Sections[s].Notes++
+
+		best := -1
+		first, _ := slices.BinarySearchFunc(played, note.Start-options.Tolerance, func(n notation.Note, at time.Duration) int {
+			return cmp.Compare(n.Start, at)
+		})
+		for i := first; i < len(played) && played[i].Start <= note.Start+options.Tolerance; i++ {
+			if !matched[i] && played[i].Key == note.Key &&
+				(best < 0 || (played[i].Start-note.Start).Abs() < (played[best].Start-note.Start).Abs()) {
+				best = i
+			}
+		}
+		if best < 0 {
+			report.Total.Missed = append(report.Total.Missed, note)
+			report.Sections[s].Missed = append(report.Sections[s].Missed, note)
+			continue
+		}
+		matched[best] = true
+		total.add(played[best].Start-note.Start, played[best].Velocity)
+		accumulators[s].add(played[best].Start-note.Start, played[best].Velocity)
+	}
+	for i, note := range played {
+		if !matched[i] {
+			s := section(note.Start)
+			report.Total.Wrong = append(report.Total.Wrong, note)
+			report.Sections[s].Wrong = append(report.Sections[s].Wrong, note)
+		}
+	}
+
+	total.finish()
+	for i := range accumulators {
+		accumulators[i].finish()
+	}
+	return report
+}
@@ -0,0 +1,46 @@
+package practice
+
+import "time"
+
+// DefaultTolerance is the largest timing deviation of a matched note unless
+// WithTolerance is given.
+const DefaultTolerance = 150 * time.Millisecond
+
+// Options holds the configuration of a comparison.
+type Options struct {
+	Tolerance time.Duration // Largest timing deviation of a played note matching a reference note.
+	Aligned   bool          // Whether the performance is already aligned with the reference.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithTolerance sets the largest timing deviation of a played note still counted as the
+// reference note; later or earlier notes count as wrong, and the reference note as missed.
+func WithTolerance(tolerance time.Duration) Option {
+	return func(opts *Options) {
+		opts.Tolerance = tolerance
+	}
+}
+
+// WithAlignedStart compares the performance with the reference as is, for performances
+// recorded against a count-in or a backing track, instead of aligning the first played
+// note with the first reference note.
+func WithAlignedStart() Option {
+	return func(opts *Options) {
+		opts.Aligned = true
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Tolerance <= 0 {
+		options.Tolerance = DefaultTolerance
+	}
+	return options
+}
@@ -0,0 +1,56 @@
+package practice
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/notation"
+	"github.com/leandrodaf/midi/sdk/smf"
+)
+
+// Section is a named part of a piece, such as a verse or an exercise.
+type Section struct {
+	Name  string        // Name of the section.
+	Start time.Duration // Start of the section from the start of the piece.
+}
+
+// Reference is the piece a performance is compared against.
+type Reference struct {
+	Notes    []notation.Note // Notes of the piece, ordered by start.
+	Sections []Section       // Sections, ordered by start; the whole piece is one section if empty.
+}
+
+// ReadReference reads a reference from a Standard MIDI File. The marker events of the file
+// start its sections.
+//
+// r io.Reader: The source, typically a .mid file.
+//
+// Returns:
+//   - Reference: The notes and sections of the file.
+//   - error: The error of smf.Read, if any.
+func ReadReference(r io.Reader) (Reference, error) {
+	file, err := smf.Read(r)
+	if err != nil {
+		return Reference{}, err
+	}
+
+	var reference Reference
+	if events := file.Events(); len(events) > 0 {
+		// Pair measures starts from the first event rather than from the start of the file.
+		reference.Notes = notation.Pair(events)
+		for i := range reference.Notes {
+			reference.Notes[i].Start += time.Duration(events[0].Timestamp)
+		}
+	}
+	for _, track := range file.Tracks {
+		for _, event := range track.Events {
+			if len(event.Data) >= 2 && event.Data[0] == 0xFF && event.Data[1] == smf.MetaMarker {
+				reference.Sections = append(reference.Sections, Section{Name: string(event.Data[2:]), Start: file.Time(event.Tick)})
+			}
+		}
+	}
+	slices.SortStableFunc(reference.Sections, func(x, y Section) int { return cmp.Compare(x.Start, y.Start) })
+	return reference, nil
+}
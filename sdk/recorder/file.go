@@ -0,0 +1,315 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/sink"
+	"github.com/leandrodaf/midi/sdk/smf"
+)
+
+// ErrRecorderClosed is returned when writing to a FileRecorder that has already been
+// closed.
+var ErrRecorderClosed = errors.New("recorder closed")
+
+// FileRecorder streams the events written to it to disk as they arrive, timed from the
+// start of each file, so sessions of any length are recorded without being held in memory.
+// With rotation, files are numbered after the path: "practice.mid" is recorded as
+// "practice-0001.mid", "practice-0002.mid" and so on. It implements sink.Sink and is safe
+// for concurrent use.
+type FileRecorder struct {
+	options Options
+	path    string
+	format  Format
+
+	mu     sync.Mutex
+	index  int        // Number of the current file; zero without rotation.
+	name   string     // Path of the current file.
+	file   *os.File   // Current file.
+	out    fileWriter // Encoder of the current file.
+	opened time.Time  // When the current file was started.
+	closed bool
+}
+
+// fileWriter encodes the recording of one file.
+type fileWriter interface {
+	event(now time.Time, event contracts.MIDI) error
+	marker(now time.Time, label string) error
+	size() int64
+	close() error // Completes the file, without closing it.
+}
+
+// Create creates the first file of a recording at path and returns a recorder streaming to
+// it. Its recording starts now.
+//
+// path string: The path of the recording, such as "practice.mid" or "practice.jsonl".
+// opts ...Option: A variadic list of option functions to customize the recorder.
+//
+// Returns:
+//   - *FileRecorder: The recorder, ready to receive events.
+//   - error: The error creating the file, if any.
+func Create(path string, opts ...Option) (*FileRecorder, error) {
+	r := &FileRecorder{options: applyDefaultOptions(opts...), path: path, format: FormatSMF}
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case r.options.Format != FormatAuto:
+		r.format = r.options.Format
+	case ext == ".jsonl" || ext == ".ndjson":
+		r.format = FormatJSONL
+	}
+	if err := r.open(time.Now()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record writes the events of eventChannel to the recorder until the channel is closed,
+// then closes the recorder, as sink.Drain does.
+//
+// eventChannel <-chan contracts.MIDI: The channel passed to ClientMIDI.StartCapture.
+//
+// Returns:
+//   - error: The first write error and the close error, joined, or nil.
+func (r *FileRecorder) Record(eventChannel <-chan contracts.MIDI) error {
+	return sink.Drain(eventChannel, r)
+}
+
+// Write records event at the current time, or adds a marker if it matches the trigger of
+// WithMarkTrigger, starting a new file first when rotation is due.
+func (r *FileRecorder) Write(event contracts.MIDI) error {
+	if r.options.MarkTrigger != nil && r.options.MarkTrigger(event) {
+		return r.Mark("")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if err := r.rotate(now); err != nil {
+		return err
+	}
+	return r.out.event(now, event)
+}
+
+// Mark adds a marker labeled label at the current time. An empty label is replaced with
+// "Mark" followed by the time of the marker.
+func (r *FileRecorder) Mark(label string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if err := r.rotate(now); err != nil {
+		return err
+	}
+	if label == "" {
+		label = "Mark " + now.Sub(r.opened).Round(time.Second).String()
+	}
+	return r.out.marker(now, label)
+}
+
+// Path returns the path of the file being recorded.
+func (r *FileRecorder) Path() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.name
+}
+
+// Close completes and closes the file being recorded.
+func (r *FileRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.finish()
+}
+
+// rotate starts a new file if the current one is due for rotation. The caller must hold
+// r.mu.
+func (r *FileRecorder) rotate(now time.Time) error {
+	if r.closed {
+		return ErrRecorderClosed
+	}
+	due := r.options.RotateEvery > 0 && now.Sub(r.opened) >= r.options.RotateEvery ||
+		r.options.RotateSize > 0 && r.out.size() >= r.options.RotateSize
+	if !due {
+		return nil
+	}
+	if err := r.finish(); err != nil {
+		r.closed = true
+		return err
+	}
+	if err := r.open(now); err != nil {
+		r.closed = true
+		return err
+	}
+	return nil
+}
+
+// open creates the next file of the recording. The caller must hold r.mu, except from
+// Create.
+func (r *FileRecorder) open(now time.Time) error {
+	r.name = r.path
+	if r.options.RotateEvery > 0 || r.options.RotateSize > 0 {
+		r.index++
+		ext := filepath.Ext(r.path)
+		r.name = fmt.Sprintf("%s-%04d%s", strings.TrimSuffix(r.path, ext), r.index, ext)
+	}
+
+	file, err := os.Create(r.name)
+	if err != nil {
+		return fmt.Errorf("error creating recording file: %w", err)
+	}
+	r.file, r.opened = file, now
+	if r.format == FormatJSONL {
+		r.out = newJSONLWriter(file, now)
+		return nil
+	}
+	name := r.options.Name
+	if r.index > 1 {
+		name += " (" + strconv.Itoa(r.index) + ")"
+	}
+	stream, err := smf.NewStream(file,
+		smf.WithName(strings.TrimSpace(name)),
+		smf.WithTempo(r.options.Tempo),
+		smf.WithDivision(r.options.Division),
+		smf.WithOrigin(0))
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error writing recording file: %w", err)
+	}
+	r.out = &smfWriter{stream: stream, options: r.options, opened: now}
+	return nil
+}
+
+// finish completes and closes the current file, and reports it to the OnRotate function.
+// The caller must hold r.mu.
+func (r *FileRecorder) finish() error {
+	err := errors.Join(r.out.close(), r.file.Close())
+	if err != nil {
+		return fmt.Errorf("error completing recording file %s: %w", r.name, err)
+	}
+	if r.options.OnRotate != nil {
+		r.options.OnRotate(r.name)
+	}
+	return nil
+}
+
+// smfWriter records to a SingleTrack Standard MIDI File.
+type smfWriter struct {
+	stream  *smf.Stream
+	options Options
+	opened  time.Time
+}
+
+func (w *smfWriter) event(now time.Time, event contracts.MIDI) error {
+	event.Timestamp = uint64(now.Sub(w.opened))
+	return w.stream.Write(event)
+}
+
+func (w *smfWriter) marker(now time.Time, label string) error {
+	tick := smf.Ticks(now.Sub(w.opened), w.options.Division, w.options.Tempo)
+	return w.stream.WriteEvent(smf.Marker(tick, label))
+}
+
+func (w *smfWriter) size() int64 {
+	return w.stream.Len()
+}
+
+func (w *smfWriter) close() error {
+	return w.stream.Close()
+}
+
+// jsonlEvent is a line of a JSONL recording for an event.
+type jsonlEvent struct {
+	Time     time.Time `json:"time"`      // Time of arrival.
+	Offset   int64     `json:"offset_ns"` // Time from the start of the file, in nanoseconds.
+	Delta    int64     `json:"delta_ns"`  // Time from the previous event, in nanoseconds.
+	Status   byte      `json:"status"`
+	Type     string    `json:"type"`
+	Channel  byte      `json:"channel"`
+	Note     byte      `json:"note"`
+	Velocity byte      `json:"velocity"`
+}
+
+// jsonlMarker is a line of a JSONL recording for a marker.
+type jsonlMarker struct {
+	Time   time.Time `json:"time"`
+	Offset int64     `json:"offset_ns"`
+	Marker string    `json:"marker"`
+}
+
+// jsonlWriter records one JSON object per line. Lines are buffered and written out every
+// second, so the file can be followed while it is recorded.
+type jsonlWriter struct {
+	buf     *bufio.Writer
+	encoder *json.Encoder
+	opened  time.Time
+	last    time.Time // Arrival of the previous event.
+	flushed time.Time // Last time the buffer was written out.
+	written int64     // Bytes written out.
+}
+
+func newJSONLWriter(file *os.File, opened time.Time) *jsonlWriter {
+	w := &jsonlWriter{opened: opened, last: opened, flushed: opened}
+	w.buf = bufio.NewWriter(writerFunc(func(p []byte) (int, error) {
+		n, err := file.Write(p)
+		w.written += int64(n)
+		return n, err
+	}))
+	w.encoder = json.NewEncoder(w.buf)
+	return w
+}
+
+func (w *jsonlWriter) event(now time.Time, event contracts.MIDI) error {
+	line := jsonlEvent{
+		Time:     now.UTC(),
+		Offset:   int64(now.Sub(w.opened)),
+		Delta:    int64(now.Sub(w.last)),
+		Status:   event.Command,
+		Type:     sink.MessageType(event),
+		Channel:  sink.Channel(event),
+		Note:     event.Note,
+		Velocity: event.Velocity,
+	}
+	w.last = now
+	return w.write(now, line)
+}
+
+func (w *jsonlWriter) marker(now time.Time, label string) error {
+	return w.write(now, jsonlMarker{Time: now.UTC(), Offset: int64(now.Sub(w.opened)), Marker: label})
+}
+
+// write encodes a line, writing the buffer out if it was last written a second ago.
+func (w *jsonlWriter) write(now time.Time, line any) error {
+	if err := w.encoder.Encode(line); err != nil {
+		return err
+	}
+	if now.Sub(w.flushed) < time.Second {
+		return nil
+	}
+	w.flushed = now
+	return w.buf.Flush()
+}
+
+func (w *jsonlWriter) size() int64 {
+	return w.written + int64(w.buf.Buffered())
+}
+
+func (w *jsonlWriter) close() error {
+	return w.buf.Flush()
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
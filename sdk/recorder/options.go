@@ -0,0 +1,127 @@
+package recorder
+
+import (
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/smf"
+)
+
+// Format is the format of the files written by a FileRecorder.
+type Format int
+
+const (
+	// FormatAuto picks the format from the extension of the path: JSONL for .jsonl and
+	// .ndjson, SMF otherwise.
+	FormatAuto Format = iota
+	// FormatSMF writes SingleTrack Standard MIDI Files, with the markers as marker events.
+	FormatSMF
+	// FormatJSONL writes one JSON object per line and event, readable while it is written.
+	FormatJSONL
+)
+
+// Options holds the configuration of a Recorder.
+type Options struct {
+	Name        string                    // Name of the session, written as the name of the first track.
+	Tempo       float64                   // Tempo written to files; smf.DefaultTempo if zero.
+	Division    uint16                    // Ticks per quarter note of files; smf.DefaultDivision if zero.
+	Split       smf.Split                 // Track of each event, such as smf.ByDevice(devices); smf.ByChannel by default.
+	MarkTrigger func(contracts.MIDI) bool // Events adding a marker instead of being recorded; none by default.
+	Format      Format                    // Format of the files of a FileRecorder; picked from the path by default.
+	RotateEvery time.Duration             // Duration after which a FileRecorder starts a new file; never by default.
+	RotateSize  int64                     // Size in bytes after which a FileRecorder starts a new file; never by default.
+	OnRotate    func(path string)         // Called with the path of each file a FileRecorder completes; none by default.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithName names the session.
+func WithName(name string) Option {
+	return func(opts *Options) {
+		opts.Name = name
+	}
+}
+
+// WithTempo sets the tempo written to files, which places the beats and bars in a DAW.
+func WithTempo(bpm float64) Option {
+	return func(opts *Options) {
+		opts.Tempo = bpm
+	}
+}
+
+// WithDivision sets the number of ticks per quarter note of files.
+func WithDivision(ticks uint16) Option {
+	return func(opts *Options) {
+		opts.Division = ticks
+	}
+}
+
+// WithSplit sets how events are split into tracks: smf.ByChannel, the default,
+// smf.ByDevice(devices) for one track per source device, smf.ByDeviceAndChannel(devices)
+// for one per channel of each device, or any other smf.Split.
+func WithSplit(split smf.Split) Option {
+	return func(opts *Options) {
+		opts.Split = split
+	}
+}
+
+// WithMarkTrigger adds a marker, instead of recording the event, for each event trigger
+// returns true for, such as a spare pad or footswitch, so takes can be marked without
+// leaving the instrument.
+func WithMarkTrigger(trigger func(contracts.MIDI) bool) Option {
+	return func(opts *Options) {
+		opts.MarkTrigger = trigger
+	}
+}
+
+// WithFormat sets the format of the files of a FileRecorder.
+func WithFormat(format Format) Option {
+	return func(opts *Options) {
+		opts.Format = format
+	}
+}
+
+// WithRotateEvery makes a FileRecorder start a new file every interval, such as one file
+// per hour of a long session.
+func WithRotateEvery(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.RotateEvery = interval
+	}
+}
+
+// WithRotateSize makes a FileRecorder start a new file once the current one reaches size
+// bytes.
+func WithRotateSize(size int64) Option {
+	return func(opts *Options) {
+		opts.RotateSize = size
+	}
+}
+
+// WithOnRotate sets a function called with the path of each file a FileRecorder
+// completes, when it rotates and when it is closed, such as to upload or archive it. It
+// is called by the goroutine writing to the recorder and should not block it for long.
+func WithOnRotate(onRotate func(path string)) Option {
+	return func(opts *Options) {
+		opts.OnRotate = onRotate
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Tempo <= 0 {
+		options.Tempo = smf.DefaultTempo
+	}
+	if options.Division == 0 {
+		options.Division = smf.DefaultDivision
+	}
+	if options.Split == nil {
+		options.Split = smf.ByChannel
+	}
+	return options
+}
@@ -0,0 +1,127 @@
+// Package recorder records capture sessions as Standard MIDI Files, with markers
+// annotating them ("take 3 starts here") so long sessions can be navigated later.
+// Recorder holds a session in memory and writes it as a multi-track file; FileRecorder
+// streams it to disk as it is played, as a MIDI or JSONL file, optionally rotated.
+package recorder
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/smf"
+)
+
+// Marker is a point of a recording.
+type Marker struct {
+	Time  time.Duration // Time from the start of the recording.
+	Label string        // Label of the marker.
+}
+
+// Recorder records the events written to it with their time of arrival. It implements
+// sink.Sink, so it can be fed with sink.Drain. It is safe for concurrent use.
+type Recorder struct {
+	options Options
+	start   time.Time
+
+	mu      sync.Mutex
+	events  []contracts.MIDI // Recorded events, timestamped in nanoseconds from start.
+	markers []Marker
+}
+
+// New creates a recorder, whose recording starts now.
+//
+// opts ...Option: A variadic list of option functions to customize the recorder.
+//
+// Returns:
+//   - *Recorder: The recorder.
+func New(opts ...Option) *Recorder {
+	return &Recorder{options: applyDefaultOptions(opts...), start: time.Now()}
+}
+
+// Write records event at the current time, or adds a marker if it matches the trigger of
+// WithMarkTrigger. It never fails.
+func (r *Recorder) Write(event contracts.MIDI) error {
+	if r.options.MarkTrigger != nil && r.options.MarkTrigger(event) {
+		r.Mark("")
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.Timestamp = uint64(time.Since(r.start))
+	r.events = append(r.events, event)
+	return nil
+}
+
+// Close does nothing; the recording can still be written out after it.
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// Mark adds a marker labeled label at the current time. An empty label is replaced with
+// "Mark" and the number of the marker, starting at 1.
+func (r *Recorder) Mark(label string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if label == "" {
+		label = "Mark " + strconv.Itoa(len(r.markers)+1)
+	}
+	r.markers = append(r.markers, Marker{Time: time.Since(r.start), Label: label})
+}
+
+// MarkLines adds a marker for each line read from input, labeled with the line, until
+// input ends. With os.Stdin, pressing Enter marks the recording and typing a label first
+// names the marker. It blocks, so it is typically run on a goroutine of its own.
+//
+// input io.Reader: The source of the lines, such as os.Stdin.
+//
+// Returns:
+//   - error: The error reading input, or nil when it ended.
+func (r *Recorder) MarkLines(input io.Reader) error {
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		r.Mark(strings.TrimSpace(scanner.Text()))
+	}
+	return scanner.Err()
+}
+
+// Markers returns the markers added so far, in order.
+func (r *Recorder) Markers() []Marker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Marker(nil), r.markers...)
+}
+
+// File returns the recording as a MultiTrack Standard MIDI File, starting when the
+// recorder was created, with the markers on the first track.
+func (r *Recorder) File() *smf.File {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file := smf.NewMultiTrack(r.events, r.options.Split,
+		smf.WithName(r.options.Name),
+		smf.WithTempo(r.options.Tempo),
+		smf.WithDivision(r.options.Division),
+		smf.WithOrigin(0))
+	for _, marker := range r.markers {
+		tick := smf.Ticks(marker.Time, file.Division, r.options.Tempo)
+		file.Tracks[0].Events = append(file.Tracks[0].Events, smf.Marker(tick, marker.Label))
+	}
+	return file
+}
+
+// WriteTo writes the recording to w as a Standard MIDI File, as returned by File.
+//
+// w io.Writer: The destination, typically a .mid file.
+//
+// Returns:
+//   - int64: The number of bytes written.
+//   - error: The error of w, if any.
+func (r *Recorder) WriteTo(w io.Writer) (int64, error) {
+	return r.File().WriteTo(w)
+}
@@ -0,0 +1,34 @@
+package remote
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// CodecName is the gRPC content subtype used by the MIDI service. Messages are
+// plain JSON, so the service needs no generated protobuf code and can be inspected
+// with ordinary tooling.
+const CodecName = "json"
+
+// jsonCodec marshals gRPC messages as JSON.
+type jsonCodec struct{}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// Marshal encodes v as JSON.
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON data into v.
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// Name returns the content subtype the codec is registered under.
+func (jsonCodec) Name() string {
+	return CodecName
+}
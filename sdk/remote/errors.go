@@ -0,0 +1,84 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnauthenticated is returned by calls the server rejected for a missing or invalid token.
+var ErrUnauthenticated = errors.New("remote MIDI server rejected the credentials")
+
+// errorCodes maps the contracts errors, and the errors of the server itself, to the
+// status codes they are sent with.
+var errorCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{contracts.ErrNoDevices, codes.NotFound},
+	{contracts.ErrInvalidDevice, codes.InvalidArgument},
+	{contracts.ErrDeviceBusy, codes.ResourceExhausted},
+	{contracts.ErrDeviceDisconnected, codes.Unavailable},
+	{contracts.ErrNotCapturing, codes.FailedPrecondition},
+	{contracts.ErrNoOutputDevice, codes.FailedPrecondition},
+	{contracts.ErrOutputUnsupported, codes.Unimplemented},
+	{contracts.ErrSysExUnsupported, codes.Unimplemented},
+	{contracts.ErrMalformedMessage, codes.InvalidArgument},
+	{contracts.ErrDeviceNotFound, codes.NotFound},
+	{contracts.ErrCaptureRunning, codes.FailedPrecondition},
+	{contracts.ErrDriverFailure, codes.Internal},
+	{contracts.ErrNoDeviceSelected, codes.FailedPrecondition},
+	{contracts.ErrCaptureStarted, codes.FailedPrecondition},
+	{contracts.ErrNilChannel, codes.InvalidArgument},
+	{contracts.ErrUnbufferedChannel, codes.InvalidArgument},
+	{ErrUnauthenticated, codes.Unauthenticated},
+}
+
+// toStatus converts an error of the local client into a gRPC status error, so that
+// the contracts error survives the trip to the remote client.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, mapping := range errorCodes {
+		if errors.Is(err, mapping.err) {
+			return status.Error(mapping.code, err.Error())
+		}
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// fromStatus converts a gRPC status error back into an error wrapping the matching
+// contracts error. An unreachable server is reported as a disconnected device. Errors
+// sharing a code are told apart by their text in the message, falling back to the first
+// error mapped to the code.
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	var match error
+	for _, mapping := range errorCodes {
+		if st.Code() != mapping.code {
+			continue
+		}
+		if strings.Contains(st.Message(), mapping.err.Error()) {
+			match = mapping.err
+			break
+		}
+		if match == nil {
+			match = mapping.err
+		}
+	}
+	if match == nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", match, st.Message())
+}
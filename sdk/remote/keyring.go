@@ -0,0 +1,296 @@
+package remote
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Errors of payload encryption.
+var (
+	ErrInvalidKey      = errors.New("invalid remote MIDI key")
+	ErrUnknownKey      = errors.New("unknown remote MIDI key")
+	ErrForgedPayload   = errors.New("remote MIDI payload failed authentication")
+	ErrReplayedPayload = errors.New("remote MIDI payload was replayed")
+	errShortPayload    = fmt.Errorf("%w: payload too short", ErrForgedPayload)
+)
+
+// Keyring holds the keys encrypting the payloads of the MIDI service with AES-GCM, which
+// also detects payloads that were altered or not sent by a key holder. The current key
+// encrypts outgoing payloads and every key decrypts incoming ones, so keys can be rotated
+// without interrupting peers: add the new key to every peer, make it current everywhere,
+// then remove the old one.
+//
+// Every payload also carries the random ID of the keyring that sealed it, a counter that
+// keyring increments and the time it was sealed, all authenticated, so payloads captured on
+// the network and sent again are rejected with ErrReplayedPayload: those sealed more than
+// MaxClockSkew before or after the time of the receiver, and the others when their counter
+// was already received from their sender. Payloads may arrive out of order, as concurrent
+// calls do, by up to replayWindowSize payloads. The clocks of peers must therefore agree
+// within MaxClockSkew; a payload can be replayed within that time only to a peer that did
+// not receive it yet, such as one that restarted or another peer holding the same keys.
+// Clients and servers also seal the direction of their payloads, so a payload reflected
+// back to the peer that sent it is rejected too. A keyring can be shared by several
+// clients and a server of the same process. It is safe for concurrent use.
+type Keyring struct {
+	mu      sync.RWMutex
+	current string                 // ID of the key encrypting outgoing payloads.
+	keys    map[string]cipher.AEAD // Keys by ID.
+	sender  [senderSize]byte       // Random ID of the keyring, sent with every payload.
+	counter atomic.Uint64          // Counter of the last payload sealed.
+
+	replayMu sync.Mutex
+	received map[[senderSize]byte]*replayWindow // Counters received from every sender.
+}
+
+// MaxClockSkew is how far the time a payload was sealed at may be from the time of the
+// keyring opening it, in either direction, for the payload to be accepted.
+const MaxClockSkew = 30 * time.Second
+
+const (
+	// senderSize is the length of the random ID of a keyring.
+	senderSize = 8
+	// headerSize is the length of the authenticated header after the key ID: the sender,
+	// the counter and the time of sealing.
+	headerSize = senderSize + 8 + 8
+	// replayWindowSize is how many payloads of a sender can arrive before one sealed earlier
+	// that is still accepted.
+	replayWindowSize = 64
+	// replayPruneSize is the number of senders above which those not heard from within
+	// twice MaxClockSkew are forgotten; their payloads are rejected as stale anyway.
+	replayPruneSize = 1024
+)
+
+// replayWindow records the counters received from a sender: the highest one and, in
+// bit i of seen, whether highest-i was received.
+type replayWindow struct {
+	highest uint64
+	seen    uint64
+	last    time.Time // Time the last payload of the sender was received.
+}
+
+// accept reports whether counter was not received before and is recent enough to be
+// told apart from a replay, and records it.
+func (w *replayWindow) accept(counter uint64) bool {
+	switch {
+	case counter > w.highest:
+		if shift := counter - w.highest; shift < replayWindowSize {
+			w.seen = w.seen<<shift | 1
+		} else {
+			w.seen = 1
+		}
+		w.highest = counter
+		return true
+	case w.highest-counter >= replayWindowSize:
+		return false
+	default:
+		bit := uint64(1) << (w.highest - counter)
+		if w.seen&bit != 0 {
+			return false
+		}
+		w.seen |= bit
+		return true
+	}
+}
+
+// NewKeyring creates a keyring holding keys, the first of which is current.
+//
+// keys ...contracts.RemoteKey: The keys, at least one.
+//
+// Returns:
+//   - *Keyring: The keyring.
+//   - error: ErrInvalidKey if no key is given or a key is invalid.
+func NewKeyring(keys ...contracts.RemoteKey) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no keys", ErrInvalidKey)
+	}
+	k := &Keyring{
+		current:  keys[0].ID,
+		keys:     make(map[string]cipher.AEAD, len(keys)),
+		received: make(map[[senderSize]byte]*replayWindow),
+	}
+	if _, err := rand.Read(k.sender[:]); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if err := k.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Add adds a key decrypting incoming payloads, replacing any key with the same ID.
+func (k *Keyring) Add(key contracts.RemoteKey) error {
+	if key.ID == "" || len(key.ID) > 255 {
+		return fmt.Errorf("%w: the ID must have 1 to 255 bytes", ErrInvalidKey)
+	}
+	block, err := aes.NewCipher(key.Secret)
+	if err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidKey, key.ID, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidKey, key.ID, err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[key.ID] = aead
+	return nil
+}
+
+// Rotate adds key and makes it current.
+func (k *Keyring) Rotate(key contracts.RemoteKey) error {
+	if err := k.Add(key); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.current = key.ID
+	return nil
+}
+
+// Remove removes the key with the given ID, so payloads encrypted with it are rejected.
+// The current key cannot be removed.
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.keys[id]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	if id == k.current {
+		return fmt.Errorf("%w: %q is the current key; rotate to another one first", ErrInvalidKey, id)
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// Seal encrypts plaintext with the current key. The result holds the length and ID of the
+// key, the ID of the keyring, the next counter, the current time in Unix nanoseconds, a
+// random nonce and the ciphertext, which authenticates everything before the nonce too.
+func (k *Keyring) Seal(plaintext []byte) ([]byte, error) {
+	k.mu.RLock()
+	id, aead := k.current, k.keys[k.current]
+	k.mu.RUnlock()
+
+	size := 1 + len(id) + headerSize
+	out := make([]byte, size+aead.NonceSize(), size+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out[0] = byte(len(id))
+	copy(out[1:], id)
+	copy(out[1+len(id):], k.sender[:])
+	binary.BigEndian.PutUint64(out[1+len(id)+senderSize:], k.counter.Add(1))
+	binary.BigEndian.PutUint64(out[1+len(id)+senderSize+8:], uint64(time.Now().UnixNano()))
+	nonce := out[size:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, plaintext, out[:size]), nil
+}
+
+// Open decrypts a payload produced by Seal with any key of the keyring. Payloads sealed
+// more than MaxClockSkew away from now, and those whose counter was already received from
+// their sender or is too old to tell, are rejected.
+func (k *Keyring) Open(payload []byte) ([]byte, error) {
+	if len(payload) < 1 || len(payload) < 1+int(payload[0])+headerSize {
+		return nil, errShortPayload
+	}
+	idSize := int(payload[0])
+	header := payload[:1+idSize+headerSize]
+	id := string(header[1 : 1+idSize])
+
+	k.mu.RLock()
+	aead, ok := k.keys[id]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+
+	rest := payload[len(header):]
+	if len(rest) < aead.NonceSize() {
+		return nil, errShortPayload
+	}
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrForgedPayload, err)
+	}
+
+	now := time.Now()
+	sealed := time.Unix(0, int64(binary.BigEndian.Uint64(header[1+idSize+senderSize+8:])))
+	if skew := now.Sub(sealed).Abs(); skew > MaxClockSkew {
+		return nil, fmt.Errorf("%w: sealed %s away from now", ErrReplayedPayload, skew)
+	}
+
+	// Only authenticated payloads are recorded, so forged senders cannot fill the map.
+	sender := [senderSize]byte(header[1+idSize:])
+	counter := binary.BigEndian.Uint64(header[1+idSize+senderSize:])
+	k.replayMu.Lock()
+	defer k.replayMu.Unlock()
+	window, ok := k.received[sender]
+	if !ok {
+		if len(k.received) >= replayPruneSize {
+			for s, w := range k.received {
+				if now.Sub(w.last) > 2*MaxClockSkew {
+					delete(k.received, s)
+				}
+			}
+		}
+		window = &replayWindow{seen: 1} // Counters start at 1.
+		k.received[sender] = window
+	}
+	if !window.accept(counter) {
+		return nil, fmt.Errorf("%w: counter %d", ErrReplayedPayload, counter)
+	}
+	window.last = now
+	return plaintext, nil
+}
+
+// Directions of the payloads, sealed with them so that a payload reflected back to the
+// peer that sent it is rejected.
+const (
+	toServer byte = 1 // Requests of clients.
+	toClient byte = 2 // Responses and events of servers.
+)
+
+// encryptedCodec marshals gRPC messages as JSON encrypted with a keyring. It replaces the
+// JSON codec on the connections of clients and servers configured with keys.
+type encryptedCodec struct {
+	keyring contracts.PayloadKeyring
+	sends   byte // Direction of the payloads it seals; it only opens those of the other one.
+}
+
+// Marshal encodes v as JSON and encrypts it, preceded by its direction, with the current key.
+func (c encryptedCodec) Marshal(v any) ([]byte, error) {
+	data, err := jsonCodec{}.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.keyring.Seal(append([]byte{c.sends}, data...))
+}
+
+// Unmarshal decrypts data and decodes the JSON it holds into v. Payloads sent in the
+// direction of those the codec seals are rejected with ErrReplayedPayload.
+func (c encryptedCodec) Unmarshal(data []byte, v any) error {
+	plaintext, err := c.keyring.Open(data)
+	if err != nil {
+		return err
+	}
+	if len(plaintext) == 0 || plaintext[0] == c.sends {
+		return fmt.Errorf("%w: payload reflected to its sender", ErrReplayedPayload)
+	}
+	return jsonCodec{}.Unmarshal(plaintext[1:], v)
+}
+
+// Name returns the content subtype of the JSON codec, which the calls of the service name.
+func (encryptedCodec) Name() string {
+	return CodecName
+}
@@ -0,0 +1,112 @@
+package remote
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationKey is the metadata key carrying the token of a call, as "Bearer <token>".
+const authorizationKey = "authorization"
+
+// DialOptions returns the gRPC dial options securing a connection to a Server as
+// configured: TLS, or a plaintext connection if config.TLS is nil, the token sent with
+// every call, and the encryption of payloads with config.Keyring or, if it is nil,
+// config.Keys.
+//
+// config contracts.RemoteConfig: The configuration of the remote backend; Address is not used.
+//
+// Returns:
+//   - []grpc.DialOption: The options to pass to grpc.NewClient.
+//   - error: ErrInvalidKey if a key is invalid.
+func DialOptions(config contracts.RemoteConfig) ([]grpc.DialOption, error) {
+	transport := insecure.NewCredentials()
+	if config.TLS != nil {
+		transport = credentials.NewTLS(config.TLS)
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(transport)}
+	if config.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(config.Token)))
+	}
+	if config.Keyring != nil {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.ForceCodec(encryptedCodec{config.Keyring, toServer})))
+	} else if len(config.Keys) > 0 {
+		keyring, err := NewKeyring(config.Keys...)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.ForceCodec(encryptedCodec{keyring, toServer})))
+	}
+	return opts, nil
+}
+
+// tokenCredentials sends a token with every call.
+type tokenCredentials string
+
+// GetRequestMetadata returns the authorization metadata of a call.
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{authorizationKey: "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity allows plaintext connections, for networks trusted not to be
+// eavesdropped; RemoteConfig documents the need for TLS elsewhere.
+func (tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// authenticate checks the token of an incoming call against the accepted tokens. Every
+// call is accepted when there are none.
+func (s *Server) authenticate(ctx context.Context) error {
+	s.authMu.RLock()
+	tokens := s.tokens
+	s.authMu.RUnlock()
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(authorizationKey) {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		if !ok {
+			continue
+		}
+		for _, accepted := range tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(accepted)) == 1 {
+				return nil
+			}
+		}
+	}
+
+	address := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		address = p.Addr.String()
+	}
+	s.options.Logger.Warn("Rejected remote MIDI client with missing or invalid token",
+		s.options.Logger.Field().String("peer", address))
+	return status.Error(codes.Unauthenticated, "missing or invalid token")
+}
+
+// unaryAuth authenticates unary calls.
+func (s *Server) unaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authenticate(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamAuth authenticates streaming calls.
+func (s *Server) streamAuth(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authenticate(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
@@ -0,0 +1,333 @@
+// Package remote exposes a MIDI client over gRPC so that devices attached to one machine
+// can be listed, selected and captured from another. The matching client side is the
+// "remote" backend, selected with contracts.WithBackend(contracts.BackendRemote).
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+
+	"github.com/leandrodaf/midi/internal/logger"
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/discovery"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// ServerOptions holds the configuration of a Server.
+type ServerOptions struct {
+	Logger           contracts.Logger    // Logger for server events and errors.
+	SubscriberBuffer int                 // Number of events buffered per connected client before dropping.
+	GRPCOptions      []grpc.ServerOption // Extra options for the gRPC server created by Serve.
+	Advertise        string              // mDNS instance name announced by Serve; empty disables announcing.
+	TLS              *tls.Config         // TLS configuration of the connections; nil serves without TLS.
+	Tokens           []string            // Tokens accepted from clients; empty accepts every client.
+	Keyring          *Keyring            // Keys encrypting the payloads; nil sends them unencrypted.
+}
+
+// ServerOption is a function that modifies ServerOptions.
+type ServerOption func(*ServerOptions)
+
+// WithServerLogger sets the logger used by the server.
+func WithServerLogger(l contracts.Logger) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.Logger = l
+	}
+}
+
+// WithSubscriberBuffer sets how many events are buffered for each connected client.
+func WithSubscriberBuffer(size int) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.SubscriberBuffer = size
+	}
+}
+
+// WithGRPCOptions appends options for the gRPC server created by Serve.
+func WithGRPCOptions(grpcOpts ...grpc.ServerOption) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.GRPCOptions = append(opts.GRPCOptions, grpcOpts...)
+	}
+}
+
+// WithAdvertisement makes Serve announce the server on the local network via mDNS under
+// the given instance name, so clients using contracts.WithNetworkDiscovery can find it.
+func WithAdvertisement(instance string) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.Advertise = instance
+	}
+}
+
+// WithTLS serves over TLS with the given configuration, which must hold the certificate
+// of the server. Set ClientAuth and ClientCAs to require client certificates as well.
+func WithTLS(config *tls.Config) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.TLS = config
+	}
+}
+
+// WithTokens makes the server reject calls without one of the tokens, which clients set
+// in contracts.RemoteConfig. Several tokens can be accepted at once, so they can be
+// rotated with SetTokens without disconnecting clients. Use TLS to keep them secret.
+func WithTokens(tokens ...string) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.Tokens = append(opts.Tokens, tokens...)
+	}
+}
+
+// WithKeyring encrypts the payloads exchanged with clients with the keys of keyring,
+// which clients configure with the same keys in contracts.RemoteConfig. Keys rotated in
+// keyring take effect immediately. Clients without the keys cannot decode the events.
+func WithKeyring(keyring *Keyring) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.Keyring = keyring
+	}
+}
+
+// Server shares a local MIDI client with remote clients. Events captured from the
+// selected device are broadcast to every connected Capture stream.
+type Server struct {
+	client      contracts.ClientMIDI
+	options     ServerOptions
+	mu          sync.Mutex                       // Protects subscribers, capturing and grpcServer.
+	subscribers map[chan contracts.MIDI]struct{} // Event channels of the connected Capture streams.
+	capturing   bool                             // Indicates if the local capture has been started.
+	events      chan contracts.MIDI              // Channel the local client captures into.
+	grpcServer  *grpc.Server
+	advert      *discovery.Advertisement // mDNS announcement started by Serve, if any.
+	done        chan struct{}
+	stopOnce    sync.Once
+	authMu      sync.RWMutex // Protects tokens.
+	tokens      []string     // Tokens accepted from clients; empty accepts every client.
+}
+
+// NewServer creates a server exposing client. The server starts capturing on the local
+// client when the first remote client opens a Capture stream.
+//
+// client contracts.ClientMIDI: The local client whose devices are shared.
+// opts ...ServerOption: A variadic list of option functions to customize the server.
+//
+// Returns:
+//   - *Server: The server, ready to be registered or served.
+func NewServer(client contracts.ClientMIDI, opts ...ServerOption) *Server {
+	options := ServerOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.Logger == nil {
+		options.Logger = logger.NewZapLogger()
+	}
+	if options.SubscriberBuffer <= 0 {
+		options.SubscriberBuffer = 256
+	}
+
+	return &Server{
+		client:      client,
+		options:     options,
+		subscribers: make(map[chan contracts.MIDI]struct{}),
+		events:      make(chan contracts.MIDI, options.SubscriberBuffer),
+		done:        make(chan struct{}),
+		tokens:      options.Tokens,
+	}
+}
+
+// SetTokens replaces the tokens accepted from clients; calls already accepted keep
+// running. Without tokens, every client is accepted.
+func (s *Server) SetTokens(tokens ...string) {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	s.tokens = append([]string(nil), tokens...)
+}
+
+// GRPCServerOptions returns the options of the gRPC server created by Serve: the TLS
+// credentials, token authentication and payload encryption configured for the server,
+// followed by those added with WithGRPCOptions. Pass them to grpc.NewServer when
+// registering the service on a server of your own with Register.
+func (s *Server) GRPCServerOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryAuth),
+		grpc.ChainStreamInterceptor(s.streamAuth),
+	}
+	if s.options.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.options.TLS)))
+	}
+	if s.options.Keyring != nil {
+		opts = append(opts, grpc.ForceServerCodec(encryptedCodec{s.options.Keyring, toClient}))
+	}
+	return append(opts, s.options.GRPCOptions...)
+}
+
+// Register registers the MIDI service on an existing gRPC server.
+func (s *Server) Register(registrar grpc.ServiceRegistrar) {
+	registrar.RegisterService(&serviceDesc, s)
+}
+
+// Serve creates a gRPC server, registers the MIDI service and serves it on lis.
+// It blocks until Stop is called or lis fails.
+func (s *Server) Serve(lis net.Listener) error {
+	s.mu.Lock()
+	s.grpcServer = grpc.NewServer(s.GRPCServerOptions()...)
+	s.Register(s.grpcServer)
+	grpcServer := s.grpcServer
+	if s.options.Advertise != "" {
+		if addr, ok := lis.Addr().(*net.TCPAddr); ok {
+			advert, err := discovery.Advertise(s.options.Advertise, discovery.RemoteService, addr.Port)
+			if err != nil {
+				s.options.Logger.Warn("Failed to announce MIDI server via mDNS", s.options.Logger.Field().Error("error", err))
+			}
+			s.advert = advert
+		}
+	}
+	s.mu.Unlock()
+
+	s.options.Logger.Info("MIDI gRPC server listening", s.options.Logger.Field().String("address", lis.Addr().String()))
+	return grpcServer.Serve(lis)
+}
+
+// Stop stops the gRPC server started by Serve and the local capture.
+// The local client itself is stopped too, since the server owns its capture.
+func (s *Server) Stop() error {
+	var err error
+	s.stopOnce.Do(func() {
+		s.mu.Lock()
+		grpcServer := s.grpcServer
+		advert := s.advert
+		s.mu.Unlock()
+
+		if advert != nil {
+			_ = advert.Close()
+		}
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+		close(s.done)
+		err = s.client.Stop()
+	})
+	return err
+}
+
+func (s *Server) listDevices(ctx context.Context) (*DeviceList, error) {
+	devices, err := s.client.ListDevices()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &DeviceList{Devices: devices}, nil
+}
+
+func (s *Server) selectDevice(ctx context.Context, req *SelectDeviceRequest) (*Empty, error) {
+	if err := s.client.SelectDevice(req.DeviceID); err != nil {
+		return nil, toStatus(err)
+	}
+	return &Empty{}, nil
+}
+
+func (s *Server) deviceCapabilities(ctx context.Context, req *DeviceRequest) (*contracts.DeviceCapabilities, error) {
+	capabilities, err := s.client.DeviceCapabilities(req.DeviceID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &capabilities, nil
+}
+
+func (s *Server) listOutputDevices(ctx context.Context) (*DeviceList, error) {
+	devices, err := s.client.ListOutputDevices()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &DeviceList{Devices: devices}, nil
+}
+
+func (s *Server) selectOutputDevice(ctx context.Context, req *SelectDeviceRequest) (*Empty, error) {
+	if err := s.client.SelectOutputDevice(req.DeviceID); err != nil {
+		return nil, toStatus(err)
+	}
+	return &Empty{}, nil
+}
+
+func (s *Server) send(ctx context.Context, req *contracts.MIDI) (*Empty, error) {
+	if err := s.client.Send(*req); err != nil {
+		return nil, toStatus(err)
+	}
+	return &Empty{}, nil
+}
+
+func (s *Server) sendSysEx(ctx context.Context, req *SysExRequest) (*Empty, error) {
+	if err := s.client.SendSysEx(req.Data); err != nil {
+		return nil, toStatus(err)
+	}
+	return &Empty{}, nil
+}
+
+func (s *Server) capture(req *Empty, stream grpc.ServerStream) error {
+	sub, err := s.subscribe()
+	if err != nil {
+		return toStatus(err)
+	}
+	defer s.unsubscribe(sub)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return nil
+		case event := <-sub:
+			if err := stream.SendMsg(&event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// subscribe registers a new Capture stream, starting the local capture on first use. It
+// fails with the error of the local client when the capture cannot start.
+func (s *Server) subscribe() (chan contracts.MIDI, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.capturing {
+		if err := s.client.StartCapture(s.events); err != nil {
+			s.options.Logger.Error("Failed to start local MIDI capture", s.options.Logger.Field().Error("error", err))
+			return nil, err
+		}
+		s.capturing = true
+		profiling.Go(profiling.RoleDispatch, "remote-server", "", s.broadcast)
+	}
+
+	sub := make(chan contracts.MIDI, s.options.SubscriberBuffer)
+	s.subscribers[sub] = struct{}{}
+	s.options.Logger.Info("Remote MIDI client subscribed", s.options.Logger.Field().Int("subscribers", len(s.subscribers)))
+	return sub, nil
+}
+
+// unsubscribe removes a Capture stream.
+func (s *Server) unsubscribe(sub chan contracts.MIDI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subscribers, sub)
+	s.options.Logger.Info("Remote MIDI client unsubscribed", s.options.Logger.Field().Int("subscribers", len(s.subscribers)))
+}
+
+// broadcast forwards locally captured events to every subscriber. A subscriber that
+// cannot keep up loses events instead of stalling the others.
+func (s *Server) broadcast() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case event := <-s.events:
+			s.mu.Lock()
+			for sub := range s.subscribers {
+				select {
+				case sub <- event:
+				default:
+					s.options.Logger.Warn("Remote subscriber buffer full; dropping MIDI event")
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
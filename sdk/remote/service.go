@@ -0,0 +1,263 @@
+package remote
+
+import (
+	"context"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"google.golang.org/grpc"
+)
+
+// ServiceName is the fully qualified name of the MIDI gRPC service.
+const ServiceName = "midi.v1.MIDI"
+
+// Empty is the message used by calls that carry no data.
+type Empty struct{}
+
+// DeviceList is the response of ListDevices.
+type DeviceList struct {
+	Devices []contracts.DeviceInfo `json:"devices"`
+}
+
+// SelectDeviceRequest is the request of SelectDevice.
+type SelectDeviceRequest struct {
+	DeviceID int `json:"device_id"`
+}
+
+// SysExRequest is the request of SendSysEx.
+type SysExRequest struct {
+	Data []byte `json:"data"` // Complete SysEx message, including F0 and F7.
+}
+
+// DeviceRequest is the request of calls about one device.
+type DeviceRequest struct {
+	DeviceID int `json:"device_id"`
+}
+
+// service is implemented by Server and describes the handlers of serviceDesc.
+type service interface {
+	listDevices(ctx context.Context) (*DeviceList, error)
+	selectDevice(ctx context.Context, req *SelectDeviceRequest) (*Empty, error)
+	deviceCapabilities(ctx context.Context, req *DeviceRequest) (*contracts.DeviceCapabilities, error)
+	listOutputDevices(ctx context.Context) (*DeviceList, error)
+	selectOutputDevice(ctx context.Context, req *SelectDeviceRequest) (*Empty, error)
+	send(ctx context.Context, req *contracts.MIDI) (*Empty, error)
+	sendSysEx(ctx context.Context, req *SysExRequest) (*Empty, error)
+	capture(req *Empty, stream grpc.ServerStream) error
+}
+
+// serviceDesc describes the MIDI service to gRPC.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*service)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "ListDevices", Handler: listDevicesHandler},
+		{MethodName: "SelectDevice", Handler: selectDeviceHandler},
+		{MethodName: "DeviceCapabilities", Handler: deviceCapabilitiesHandler},
+		{MethodName: "ListOutputDevices", Handler: listOutputDevicesHandler},
+		{MethodName: "SelectOutputDevice", Handler: selectOutputDeviceHandler},
+		{MethodName: "Send", Handler: sendHandler},
+		{MethodName: "SendSysEx", Handler: sendSysExHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Capture", Handler: captureHandler, ServerStreams: true},
+	},
+}
+
+func listDevicesHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(service).listDevices(ctx)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/ListDevices"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(service).listDevices(ctx)
+	})
+}
+
+func selectDeviceHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(SelectDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(service).selectDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/SelectDevice"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(service).selectDevice(ctx, req.(*SelectDeviceRequest))
+	})
+}
+
+func deviceCapabilitiesHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(DeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(service).deviceCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/DeviceCapabilities"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(service).deviceCapabilities(ctx, req.(*DeviceRequest))
+	})
+}
+
+func listOutputDevicesHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(service).listOutputDevices(ctx)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/ListOutputDevices"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(service).listOutputDevices(ctx)
+	})
+}
+
+func selectOutputDeviceHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(SelectDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(service).selectOutputDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/SelectOutputDevice"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(service).selectOutputDevice(ctx, req.(*SelectDeviceRequest))
+	})
+}
+
+func sendHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(contracts.MIDI)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(service).send(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/Send"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(service).send(ctx, req.(*contracts.MIDI))
+	})
+}
+
+func sendSysExHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(SysExRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(service).sendSysEx(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/SendSysEx"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(service).sendSysEx(ctx, req.(*SysExRequest))
+	})
+}
+
+func captureHandler(srv any, stream grpc.ServerStream) error {
+	in := new(Empty)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(service).capture(in, stream)
+}
+
+// ServiceClient calls the MIDI service exposed by a remote Server.
+type ServiceClient struct {
+	conn grpc.ClientConnInterface
+}
+
+// NewServiceClient returns a client for the MIDI service reachable through conn.
+func NewServiceClient(conn grpc.ClientConnInterface) *ServiceClient {
+	return &ServiceClient{conn: conn}
+}
+
+// ListDevices lists the devices available on the remote host.
+// Errors wrap the contracts error reported by the server, if any.
+func (c *ServiceClient) ListDevices(ctx context.Context) ([]contracts.DeviceInfo, error) {
+	out := new(DeviceList)
+	if err := c.conn.Invoke(ctx, "/"+ServiceName+"/ListDevices", &Empty{}, out, grpc.CallContentSubtype(CodecName)); err != nil {
+		return nil, fromStatus(err)
+	}
+	return out.Devices, nil
+}
+
+// SelectDevice selects a device on the remote host.
+// Errors wrap the contracts error reported by the server, if any.
+func (c *ServiceClient) SelectDevice(ctx context.Context, deviceID int) error {
+	return fromStatus(c.conn.Invoke(ctx, "/"+ServiceName+"/SelectDevice", &SelectDeviceRequest{DeviceID: deviceID}, &Empty{}, grpc.CallContentSubtype(CodecName)))
+}
+
+// DeviceCapabilities reports what a device on the remote host supports.
+// Errors wrap the contracts error reported by the server, if any.
+func (c *ServiceClient) DeviceCapabilities(ctx context.Context, deviceID int) (contracts.DeviceCapabilities, error) {
+	out := new(contracts.DeviceCapabilities)
+	if err := c.conn.Invoke(ctx, "/"+ServiceName+"/DeviceCapabilities", &DeviceRequest{DeviceID: deviceID}, out, grpc.CallContentSubtype(CodecName)); err != nil {
+		return contracts.DeviceCapabilities{}, fromStatus(err)
+	}
+	return *out, nil
+}
+
+// ListOutputDevices lists the output devices available on the remote host.
+// Errors wrap the contracts error reported by the server, if any.
+func (c *ServiceClient) ListOutputDevices(ctx context.Context) ([]contracts.DeviceInfo, error) {
+	out := new(DeviceList)
+	if err := c.conn.Invoke(ctx, "/"+ServiceName+"/ListOutputDevices", &Empty{}, out, grpc.CallContentSubtype(CodecName)); err != nil {
+		return nil, fromStatus(err)
+	}
+	return out.Devices, nil
+}
+
+// SelectOutputDevice selects the output device of the remote host that Send writes to.
+// Errors wrap the contracts error reported by the server, if any.
+func (c *ServiceClient) SelectOutputDevice(ctx context.Context, deviceID int) error {
+	return fromStatus(c.conn.Invoke(ctx, "/"+ServiceName+"/SelectOutputDevice", &SelectDeviceRequest{DeviceID: deviceID}, &Empty{}, grpc.CallContentSubtype(CodecName)))
+}
+
+// Send sends an event to the output device selected on the remote host.
+// Errors wrap the contracts error reported by the server, if any.
+func (c *ServiceClient) Send(ctx context.Context, event contracts.MIDI) error {
+	return fromStatus(c.conn.Invoke(ctx, "/"+ServiceName+"/Send", &event, &Empty{}, grpc.CallContentSubtype(CodecName)))
+}
+
+// SendSysEx sends a complete SysEx message, including F0 and F7, to the output device
+// selected on the remote host.
+// Errors wrap the contracts error reported by the server, if any.
+func (c *ServiceClient) SendSysEx(ctx context.Context, data []byte) error {
+	return fromStatus(c.conn.Invoke(ctx, "/"+ServiceName+"/SendSysEx", &SysExRequest{Data: data}, &Empty{}, grpc.CallContentSubtype(CodecName)))
+}
+
+// Capture opens a stream of the events captured on the remote host.
+// The stream ends when ctx is cancelled.
+func (c *ServiceClient) Capture(ctx context.Context) (*EventStream, error) {
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], "/"+ServiceName+"/Capture", grpc.CallContentSubtype(CodecName))
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	if err := stream.SendMsg(&Empty{}); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &EventStream{stream: stream}, nil
+}
+
+// EventStream receives the events of a Capture call.
+type EventStream struct {
+	stream grpc.ClientStream
+}
+
+// Recv blocks until the next event arrives or the stream ends.
+func (s *EventStream) Recv() (contracts.MIDI, error) {
+	var event contracts.MIDI
+	err := s.stream.RecvMsg(&event)
+	return event, err
+}
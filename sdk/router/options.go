@@ -0,0 +1,27 @@
+package router
+
+// Options holds the configuration of a Router.
+type Options struct {
+	OnError func(route int, err error) // Optional handler of errors sending routed events, with the ID of the route.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithErrorHandler calls handler with the errors returned by the outputs, and with
+// ErrUnknownOutput for routes to outputs not added, together with the ID of the route.
+// They are discarded otherwise.
+func WithErrorHandler(handler func(route int, err error)) Option {
+	return func(opts *Options) {
+		opts.OnError = handler
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
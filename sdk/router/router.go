@@ -0,0 +1,214 @@
+// Package router connects MIDI inputs to outputs, like a programmable patchbay. Inputs are
+// clients capturing from a device, or any other producer of events; outputs are clients
+// with a selected output device, or anything else with a Send method, such as a throttle.
+// Routes connect an input to an output, keep the events matching their filter and pass
+// them through transforms, such as the channel remapping, transposition and velocity
+// curves of sdk/transform. Routes and outputs are added and removed while events flow:
+//
+//	r := router.New()
+//	r.AddOutput("synth", synth) // A client with a selected output device.
+//	id, err := r.AddRoute(router.Route{
+//		Input:      "keys",
+//		Output:     "synth",
+//		Filter:     &contracts.MIDIEventFilter{Commands: []contracts.MIDICommand{contracts.NoteOn, contracts.NoteOff}},
+//		Transforms: []contracts.Transform{transform.Transpose(12), transform.SetChannel(9)},
+//	})
+//	err = r.AddInput("keys", keys) // Starts capture on a client with a selected device.
+//	...
+//	r.RemoveRoute(id)
+package router
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Errors returned by a Router.
+var (
+	ErrInvalidRoute  = errors.New("route without output")
+	ErrUnknownOutput = errors.New("router output not found")
+)
+
+// Sender sends messages to a MIDI output.
+type Sender interface {
+	Send(event contracts.MIDI) error // Sends a channel or system message.
+}
+
+// Route connects an input to an output.
+type Route struct {
+	Input      string                     // Name of the input whose events are routed; empty routes the events of every input.
+	Output     string                     // Name of the output the events are sent to.
+	Filter     *contracts.MIDIEventFilter // Optional filter of the routed commands; nil routes every command.
+	Predicate  func(contracts.MIDI) bool  // Optional filter applied after Filter; events it returns false for are not routed.
+	Transforms []contracts.Transform      // Transforms applied in order to the events routed.
+}
+
+// table holds the routes and outputs of a Router. It is never modified once published.
+type table struct {
+	ids     []int             // IDs of the routes, in the order they were added.
+	routes  map[int]Route     // Routes by ID.
+	outputs map[string]Sender // Outputs by name.
+}
+
+// Router sends the events of its inputs to its outputs along its routes. It is safe for
+// concurrent use: events are routed without locking, while routes and outputs change.
+type Router struct {
+	options Options
+	table   atomic.Pointer[table] // Current routes and outputs, replaced on every change.
+	mu      sync.Mutex            // Serializes changes of the table.
+	lastID  int                   // ID of the last route added.
+}
+
+// New creates a router without routes or outputs.
+//
+// opts ...Option: Optional settings such as WithErrorHandler.
+//
+// Returns:
+//   - *Router: The router.
+func New(opts ...Option) *Router {
+	r := &Router{options: applyDefaultOptions(opts...)}
+	r.table.Store(&table{routes: make(map[int]Route), outputs: make(map[string]Sender)})
+	return r
+}
+
+// AddOutput adds an output named name, replacing the output of that name if any. Routes
+// to the name send to out from then on.
+func (r *Router) AddOutput(name string, out Sender) {
+	r.update(func(t *table) {
+		t.outputs[name] = out
+	})
+}
+
+// RemoveOutput removes the output named name. Routes to it are kept, and report
+// ErrUnknownOutput to the error handler until an output of that name is added again.
+func (r *Router) RemoveOutput(name string) {
+	r.update(func(t *table) {
+		delete(t.outputs, name)
+	})
+}
+
+// AddRoute adds a route, effective for the next event of its input.
+//
+// route Route: The route; its output need not be added yet.
+//
+// Returns:
+//   - int: The ID of the route, for RemoveRoute.
+//   - error: ErrInvalidRoute if the route has no output.
+func (r *Router) AddRoute(route Route) (int, error) {
+	if route.Output == "" {
+		return 0, ErrInvalidRoute
+	}
+	route.Transforms = slices.Clone(route.Transforms)
+
+	var id int
+	r.update(func(t *table) {
+		r.lastID++
+		id = r.lastID
+		t.ids = append(t.ids, id)
+		t.routes[id] = route
+	})
+	return id, nil
+}
+
+// RemoveRoute removes the route with the given ID, and reports whether it existed. Events
+// being routed along it may still be sent; notes it sent are not released.
+func (r *Router) RemoveRoute(id int) bool {
+	removed := false
+	r.update(func(t *table) {
+		if _, removed = t.routes[id]; removed {
+			delete(t.routes, id)
+			t.ids = slices.DeleteFunc(t.ids, func(other int) bool { return other == id })
+		}
+	})
+	return removed
+}
+
+// Routes returns the routes by ID.
+func (r *Router) Routes() map[int]Route {
+	return maps.Clone(r.table.Load().routes)
+}
+
+// AddInput routes the events client captures as the input named name, by starting capture
+// on it with StartCaptureFunc. A device must be selected on client, which the router does
+// not stop.
+func (r *Router) AddInput(name string, client contracts.ClientMIDI) error {
+	return client.StartCaptureFunc(r.Input(name))
+}
+
+// Input returns a handler routing the events it is called with as the input named name,
+// for StartCaptureFunc or any other producer of events.
+func (r *Router) Input(name string) func(contracts.MIDI) {
+	return func(event contracts.MIDI) {
+		r.Dispatch(name, event)
+	}
+}
+
+// Dispatch routes event as an event of the input named name: it is sent to the output of
+// every route of the input, in the order the routes were added, unless the route filters
+// it out.
+func (r *Router) Dispatch(input string, event contracts.MIDI) {
+	t := r.table.Load()
+	for _, id := range t.ids {
+		route := t.routes[id]
+		if route.Input != "" && route.Input != input {
+			continue
+		}
+		routed, ok := route.apply(event)
+		if !ok {
+			continue
+		}
+		out, ok := t.outputs[route.Output]
+		if !ok {
+			r.error(id, ErrUnknownOutput)
+			continue
+		}
+		if err := out.Send(routed); err != nil {
+			r.error(id, err)
+		}
+	}
+}
+
+// update applies change to a copy of the table and publishes the copy.
+func (r *Router) update(change func(t *table)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.table.Load()
+	next := &table{
+		ids:     slices.Clone(current.ids),
+		routes:  maps.Clone(current.routes),
+		outputs: maps.Clone(current.outputs),
+	}
+	change(next)
+	r.table.Store(next)
+}
+
+// error reports err, sending along the route with the given ID, to the error handler.
+func (r *Router) error(id int, err error) {
+	if r.options.OnError != nil {
+		r.options.OnError(id, err)
+	}
+}
+
+// apply filters event and passes it through the transforms of the route, and reports
+// whether it is routed.
+func (route Route) apply(event contracts.MIDI) (contracts.MIDI, bool) {
+	if route.Filter != nil && !route.Filter.Matches(event) {
+		return event, false
+	}
+	if route.Predicate != nil && !route.Predicate(event) {
+		return event, false
+	}
+	for _, transform := range route.Transforms {
+		var ok bool
+		if event, ok = transform(event); !ok {
+			return event, false
+		}
+	}
+	return event, true
+}
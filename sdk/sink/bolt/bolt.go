@@ -0,0 +1,206 @@
+//go:build !js
+// +build !js
+
+// Package bolt provides a sink that persists captured MIDI events into a bbolt database,
+// an embedded key-value store needing neither cgo nor a database driver, so practice and
+// analytics applications can keep their sessions in a single file. It is not available in
+// js/wasm builds, which cannot map files into memory.
+//
+// Events are kept per session, ordered by the wall time they were written at, with an
+// index by note, so Events answers queries by time range and note without scanning the
+// whole session, whatever clock the timestamps of the events are on:
+//
+//	db, err := bbolt.Open("sessions.db", 0o600, nil)
+//	store, err := bolt.New(db, bolt.WithDevice("Arturia KeyStep"))
+//	go sink.Drain(eventChannel, store)
+package bolt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"go.etcd.io/bbolt"
+)
+
+// ErrSinkClosed is returned when writing to a sink that has already been closed.
+var ErrSinkClosed = errors.New("bolt sink closed")
+
+// Sink buffers MIDI events and writes them to bbolt in batched transactions.
+type Sink struct {
+	db       *bbolt.DB
+	options  Options
+	mu       sync.Mutex // Protects pending, closed and flushErr.
+	pending  []pendingEvent
+	closed   bool
+	flushErr error          // Error from a background flush, reported on the next Write or Close.
+	done     chan struct{}  // Closed to stop the background flusher.
+	wg       sync.WaitGroup // Tracks the background flusher.
+}
+
+// pendingEvent is a buffered event with the Unix nanoseconds it was written at.
+type pendingEvent struct {
+	event contracts.MIDI
+	time  uint64
+}
+
+// New creates the buckets of the session if needed and returns a sink writing to db.
+// Events written to an existing session are added to it.
+//
+// db *bbolt.DB: A database opened with bbolt.Open, owned by the caller.
+// opts ...Option: A variadic list of option functions to customize the sink.
+//
+// Returns:
+//   - *Sink: The sink, ready to receive events.
+//   - error: An error if the buckets could not be created.
+func New(db *bbolt.DB, opts ...Option) (*Sink, error) {
+	options := applyDefaultOptions(opts...)
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := sessionBucket(tx, options.Session)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s buckets: %w", BucketName, err)
+	}
+
+	s := &Sink{
+		db:      db,
+		options: options,
+		pending: make([]pendingEvent, 0, options.BatchSize),
+		done:    make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	profiling.Go(profiling.RoleScheduler, "bolt", options.Device, s.flushPeriodically)
+	return s, nil
+}
+
+// sessionBucket returns the bucket of session, creating it with its events and notes
+// buckets if needed.
+func sessionBucket(tx *bbolt.Tx, session string) (*bbolt.Bucket, error) {
+	root, err := tx.CreateBucketIfNotExists([]byte(BucketName))
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := root.CreateBucketIfNotExists([]byte(session))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := bucket.CreateBucketIfNotExists(eventsBucket); err != nil {
+		return nil, err
+	}
+	if _, err := bucket.CreateBucketIfNotExists(notesBucket); err != nil {
+		return nil, err
+	}
+	return bucket, nil
+}
+
+// Session returns the session identifier stored with the events of this sink.
+func (s *Sink) Session() string {
+	return s.options.Session
+}
+
+// Write buffers an event stamped with the current wall time, writing the whole batch once it reaches the configured size.
+func (s *Sink) Write(event contracts.MIDI) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+	if err := s.flushErr; err != nil {
+		s.flushErr = nil
+		return err
+	}
+
+	s.pending = append(s.pending, pendingEvent{event: event, time: uint64(time.Now().UnixNano())})
+	if len(s.pending) >= s.options.BatchSize {
+		return s.flushLocked()
+	}
+	return nil
+}
+
+// Flush writes all buffered events immediately.
+func (s *Sink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+// Close stops the background flusher and writes any buffered events.
+// The database itself is left open and remains owned by the caller.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.flushErr, s.flushLocked())
+}
+
+// flushPeriodically writes buffered events every FlushInterval until the sink is closed.
+func (s *Sink) flushPeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if err := s.flushLocked(); err != nil && s.flushErr == nil {
+				s.flushErr = err
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// flushLocked writes the pending batch in a single transaction. The caller must hold s.mu.
+// On failure the batch is kept so the next flush retries it.
+func (s *Sink) flushLocked() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := sessionBucket(tx, s.options.Session)
+		if err != nil {
+			return err
+		}
+		events, notes := bucket.Bucket(eventsBucket), bucket.Bucket(notesBucket)
+		for _, pending := range s.pending {
+			id, err := events.NextSequence()
+			if err != nil {
+				return err
+			}
+			key := eventKey(pending.time, id)
+			if err := events.Put(key, encodeEvent(pending.event, s.options.Device)); err != nil {
+				return err
+			}
+			if err := notes.Put(noteKey(pending.event.Note, key), []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error writing MIDI events to bbolt: %w", err)
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
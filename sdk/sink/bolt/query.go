@@ -0,0 +1,233 @@
+//go:build !js
+// +build !js
+
+package bolt
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/sink"
+	"go.etcd.io/bbolt"
+)
+
+// Record is a persisted MIDI event together with the metadata stored alongside it.
+type Record struct {
+	contracts.MIDI           // The event, with its timestamp as captured.
+	ID             uint64    // Identifier of the event within its session, in order of writing.
+	Time           time.Time // Wall time the event was written to the sink at, which queries and sessions are based on.
+	Session        string    // Session the event was captured in.
+	Device         string    // Device the event was captured from.
+	MessageType    string    // Decoded message type (note_on, control_change, ...).
+	Channel        byte      // Zero-based MIDI channel.
+}
+
+// Query narrows the events returned by Events. Zero values match everything.
+type Query struct {
+	Session     string    // Only events of this session.
+	Device      string    // Only events of this device.
+	From        time.Time // Only events written at or after this time.
+	To          time.Time // Only events written before this time.
+	MessageType string    // Only events of this decoded message type.
+	Channels    []byte    // Only events on these zero-based channels.
+	Notes       []byte    // Only events with these note numbers, read through the notes index.
+	Limit       int       // Maximum number of events returned.
+}
+
+// SessionSummary describes one recorded session.
+type SessionSummary struct {
+	Session string    // Session identifier.
+	Device  string    // Device the first event of the session was captured from.
+	Start   time.Time // Time the first event was written at.
+	End     time.Time // Time the last event was written at.
+	Events  int64     // Number of events recorded.
+}
+
+// checkEvery is the number of stored entries read between checks of the context.
+const checkEvery = 1024
+
+// Events returns the persisted events matching q, in the order they were written. Each session is
+// read from the first event at q.From on, through the notes index when q.Notes is set.
+func Events(ctx context.Context, db *bbolt.DB, q Query) ([]Record, error) {
+	var records []Record
+	err := db.View(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(BucketName))
+		if root == nil {
+			return nil
+		}
+		sessions := [][]byte{[]byte(q.Session)}
+		if q.Session == "" {
+			sessions = sessionNames(root)
+		}
+		for _, session := range sessions {
+			bucket := root.Bucket(session)
+			if bucket == nil {
+				continue
+			}
+			found, err := q.events(ctx, string(session), bucket)
+			if err != nil {
+				return err
+			}
+			records = append(records, found...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying MIDI events: %w", err)
+	}
+
+	slices.SortStableFunc(records, func(a, b Record) int {
+		return cmp.Or(a.Time.Compare(b.Time), cmp.Compare(a.ID, b.ID))
+	})
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[:q.Limit]
+	}
+	return records, nil
+}
+
+// sessionNames returns the names of the session buckets under root.
+func sessionNames(root *bbolt.Bucket) [][]byte {
+	var names [][]byte
+	root.ForEachBucket(func(name []byte) error {
+		names = append(names, slices.Clone(name))
+		return nil
+	})
+	return names
+}
+
+// bounds returns the range of write times of q, in Unix nanoseconds: from is inclusive and to exclusive, and
+// bounded reports whether there is an upper bound.
+func (q Query) bounds() (from, to uint64, bounded bool) {
+	if !q.From.IsZero() && q.From.UnixNano() > 0 {
+		from = uint64(q.From.UnixNano())
+	}
+	if !q.To.IsZero() {
+		to, bounded = uint64(max(q.To.UnixNano(), 0)), true
+	}
+	return from, to, bounded
+}
+
+// events returns the events of a session matching q, up to its limit, in key order.
+func (q Query) events(ctx context.Context, session string, bucket *bbolt.Bucket) ([]Record, error) {
+	events, notes := bucket.Bucket(eventsBucket), bucket.Bucket(notesBucket)
+	if events == nil || notes == nil {
+		return nil, nil
+	}
+	from, to, bounded := q.bounds()
+	before := func(key []byte) bool {
+		return !bounded || binary.BigEndian.Uint64(key) < to
+	}
+
+	var keys [][]byte
+	if len(q.Notes) > 0 {
+		cursor := notes.Cursor()
+		read := 0
+		for _, note := range slices.Compact(slices.Sorted(slices.Values(q.Notes))) {
+			prefix := []byte{note}
+			for k, _ := cursor.Seek(noteKey(note, eventKey(from, 0))); k != nil && bytes.HasPrefix(k, prefix) && before(k[1:]); k, _ = cursor.Next() {
+				if read++; read%checkEvery == 0 && ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				keys = append(keys, k[1:])
+			}
+		}
+		slices.SortFunc(keys, bytes.Compare)
+	}
+
+	var records []Record
+	match := func(key, value []byte) (bool, error) {
+		r, err := decodeRecord(session, key, value)
+		if err != nil {
+			return false, err
+		}
+		r.MessageType, r.Channel = sink.MessageType(r.MIDI), sink.Channel(r.MIDI)
+		if q.matches(r) {
+			records = append(records, r)
+		}
+		return q.Limit > 0 && len(records) >= q.Limit, nil
+	}
+
+	if len(q.Notes) > 0 {
+		for i, key := range keys {
+			if i%checkEvery == checkEvery-1 && ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			value := events.Get(key)
+			if value == nil {
+				continue
+			}
+			if done, err := match(key, value); err != nil || done {
+				return records, err
+			}
+		}
+		return records, nil
+	}
+
+	cursor := events.Cursor()
+	read := 0
+	for k, v := cursor.Seek(eventKey(from, 0)); k != nil && before(k); k, v = cursor.Next() {
+		if read++; read%checkEvery == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if done, err := match(k, v); err != nil || done {
+			return records, err
+		}
+	}
+	return records, nil
+}
+
+// matches reports whether r passes the filters of q not answered by the keys.
+func (q Query) matches(r Record) bool {
+	return (q.Device == "" || r.Device == q.Device) &&
+		(q.MessageType == "" || r.MessageType == q.MessageType) &&
+		(len(q.Channels) == 0 || slices.Contains(q.Channels, r.Channel))
+}
+
+// Sessions returns a summary of every session stored in db, oldest first.
+func Sessions(ctx context.Context, db *bbolt.DB) ([]SessionSummary, error) {
+	var sessions []SessionSummary
+	err := db.View(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(BucketName))
+		if root == nil {
+			return nil
+		}
+		for _, name := range sessionNames(root) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			events := root.Bucket(name).Bucket(eventsBucket)
+			if events == nil {
+				continue
+			}
+			cursor := events.Cursor()
+			firstKey, firstValue := cursor.First()
+			lastKey, _ := cursor.Last()
+			if firstKey == nil {
+				continue
+			}
+			first, err := decodeRecord(string(name), firstKey, firstValue)
+			if err != nil {
+				return err
+			}
+			sessions = append(sessions, SessionSummary{
+				Session: string(name),
+				Device:  first.Device,
+				Start:   first.Time,
+				End:     keyTime(lastKey),
+				Events:  int64(events.Sequence()),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying MIDI sessions: %w", err)
+	}
+	slices.SortStableFunc(sessions, func(a, b SessionSummary) int { return a.Start.Compare(b.Start) })
+	return sessions, nil
+}
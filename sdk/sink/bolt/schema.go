@@ -0,0 +1,81 @@
+//go:build !js
+// +build !js
+
+package bolt
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// BucketName is the top-level bucket events are written to. It holds a bucket per
+// session, which holds the events bucket and the notes index bucket.
+const BucketName = "midi_events"
+
+var (
+	eventsBucket = []byte("events") // Events of the session by key, in timestamp order.
+	notesBucket  = []byte("notes")  // Index of the events by note: the note byte followed by the event key, with empty values.
+)
+
+// keySize is the size of an event key: the Unix nanoseconds the event was written at
+// followed by the ID of the event, both big-endian so keys sort by time, then by order of
+// arrival. The wall time is used rather than the timestamp of the event, whose clock
+// depends on the backend and on contracts.WithTimestampClock.
+const keySize = 16
+
+// headerSize is the size of a stored value before the name of the device: the timestamp
+// of the event, big-endian, followed by its status and data bytes.
+const headerSize = 11
+
+// errCorruptRecord is returned when a stored value cannot be decoded.
+var errCorruptRecord = errors.New("corrupt MIDI event record")
+
+// eventKey returns the key of the event written at time, in Unix nanoseconds, with the
+// given ID.
+func eventKey(time, id uint64) []byte {
+	key := make([]byte, keySize)
+	binary.BigEndian.PutUint64(key, time)
+	binary.BigEndian.PutUint64(key[8:], id)
+	return key
+}
+
+// noteKey returns the key of the notes index for note and an event key.
+func noteKey(note byte, key []byte) []byte {
+	return append([]byte{note}, key...)
+}
+
+// encodeEvent returns the value stored for an event: its timestamp, status and data bytes
+// followed by the name of the device.
+func encodeEvent(event contracts.MIDI, device string) []byte {
+	value := binary.BigEndian.AppendUint64(make([]byte, 0, headerSize+len(device)), event.Timestamp)
+	value = append(value, event.Command, event.Note, event.Velocity)
+	return append(value, device...)
+}
+
+// decodeRecord decodes the event stored under key in session.
+func decodeRecord(session string, key, value []byte) (Record, error) {
+	if len(key) != keySize || len(value) < headerSize {
+		return Record{}, errCorruptRecord
+	}
+	r := Record{
+		MIDI: contracts.MIDI{
+			Timestamp: binary.BigEndian.Uint64(value),
+			Command:   value[8],
+			Note:      value[9],
+			Velocity:  value[10],
+		},
+		ID:      binary.BigEndian.Uint64(key[8:]),
+		Time:    keyTime(key),
+		Session: session,
+		Device:  string(value[headerSize:]),
+	}
+	return r, nil
+}
+
+// keyTime returns the time an event was written at from its key.
+func keyTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key))).UTC()
+}
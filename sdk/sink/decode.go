@@ -0,0 +1,38 @@
+package sink
+
+import "github.com/leandrodaf/midi/sdk/contracts"
+
+// MessageType returns a stable, lower-case name for the kind of message an event carries,
+// suitable for storing in columnar or relational outputs. A Note On with velocity zero is
+// reported as note_off, matching how devices use it.
+func MessageType(event contracts.MIDI) string {
+	switch event.Command & 0xF0 {
+	case 0x80:
+		return "note_off"
+	case 0x90:
+		if event.Velocity == 0 {
+			return "note_off"
+		}
+		return "note_on"
+	case 0xA0:
+		return "poly_aftertouch"
+	case 0xB0:
+		return "control_change"
+	case 0xC0:
+		return "program_change"
+	case 0xD0:
+		return "channel_aftertouch"
+	case 0xE0:
+		return "pitch_bend"
+	case 0xF0:
+		return "system"
+	default:
+		return "unknown"
+	}
+}
+
+// Channel returns the zero-based MIDI channel encoded in the event's status byte,
+// or zero for system messages.
+func Channel(event contracts.MIDI) byte {
+	return event.Channel()
+}
@@ -0,0 +1,62 @@
+package parquet
+
+import "time"
+
+// Options holds the configuration of a Parquet exporter.
+type Options struct {
+	Session      string // Identifier stored with every event; defaults to the exporter start time.
+	Device       string // Name of the device the events come from.
+	BatchSize    int    // Number of events buffered before they are handed to the Parquet writer.
+	RowGroupSize int64  // Maximum number of rows per Parquet row group.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithSession sets the session identifier stored with every event.
+func WithSession(session string) Option {
+	return func(opts *Options) {
+		opts.Session = session
+	}
+}
+
+// WithDevice sets the device name stored with every event.
+func WithDevice(device string) Option {
+	return func(opts *Options) {
+		opts.Device = device
+	}
+}
+
+// WithBatchSize sets how many events are buffered before they are handed to the writer.
+func WithBatchSize(size int) Option {
+	return func(opts *Options) {
+		opts.BatchSize = size
+	}
+}
+
+// WithRowGroupSize sets the maximum number of rows per Parquet row group.
+// Smaller row groups use less memory while writing; larger ones compress better.
+func WithRowGroupSize(rows int64) Option {
+	return func(opts *Options) {
+		opts.RowGroupSize = rows
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Session == "" {
+		options.Session = time.Now().UTC().Format("20060102T150405Z")
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 1024
+	}
+	if options.RowGroupSize <= 0 {
+		options.RowGroupSize = 128 * 1024
+	}
+	return options
+}
@@ -0,0 +1,139 @@
+// Package parquet exports captured MIDI events to Parquet files, a columnar format that
+// pandas, polars, duckdb and Arrow-based tools load directly for velocity and timing analysis.
+//
+// The columns mirror the SQLite sink: session, device, timestamp (nanoseconds, UTC),
+// status, message_type, channel, note and velocity.
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/sink"
+	goparquet "github.com/parquet-go/parquet-go"
+)
+
+// ErrExporterClosed is returned when writing to an exporter that has already been closed.
+var ErrExporterClosed = errors.New("parquet exporter closed")
+
+// row is the Parquet schema of an exported event.
+type row struct {
+	Session     string `parquet:"session,dict"`
+	Device      string `parquet:"device,dict"`
+	Timestamp   int64  `parquet:"timestamp,timestamp(nanosecond)"`
+	Status      int32  `parquet:"status"`
+	MessageType string `parquet:"message_type,dict"`
+	Channel     int32  `parquet:"channel"`
+	Note        int32  `parquet:"note"`
+	Velocity    int32  `parquet:"velocity"`
+}
+
+// Exporter streams MIDI events into a Parquet file.
+type Exporter struct {
+	options Options
+	writer  *goparquet.GenericWriter[row]
+	closer  io.Closer // Underlying file when created through Create; nil otherwise.
+	mu      sync.Mutex
+	pending []row
+	closed  bool
+}
+
+// New returns an exporter writing Parquet data to w. The file footer is only
+// written by Close, so the output is not readable until the exporter is closed.
+//
+// w io.Writer: The destination of the Parquet data.
+// opts ...Option: A variadic list of option functions to customize the exporter.
+//
+// Returns:
+//   - *Exporter: The exporter, ready to receive events.
+func New(w io.Writer, opts ...Option) *Exporter {
+	options := applyDefaultOptions(opts...)
+	return &Exporter{
+		options: options,
+		writer: goparquet.NewGenericWriter[row](w,
+			goparquet.Compression(&goparquet.Zstd),
+			goparquet.MaxRowsPerRowGroup(options.RowGroupSize),
+		),
+		pending: make([]row, 0, options.BatchSize),
+	}
+}
+
+// Create creates (or truncates) the file at path and returns an exporter writing to it.
+// Closing the exporter also closes the file.
+func Create(path string, opts ...Option) (*Exporter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating parquet file: %w", err)
+	}
+
+	e := New(file, opts...)
+	e.closer = file
+	return e, nil
+}
+
+// Session returns the session identifier stored with the exported events.
+func (e *Exporter) Session() string {
+	return e.options.Session
+}
+
+// Write buffers an event, handing the batch to the Parquet writer once it reaches the configured size.
+func (e *Exporter) Write(event contracts.MIDI) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return ErrExporterClosed
+	}
+
+	e.pending = append(e.pending, row{
+		Session:     e.options.Session,
+		Device:      e.options.Device,
+		Timestamp:   int64(event.Timestamp),
+		Status:      int32(event.Command),
+		MessageType: sink.MessageType(event),
+		Channel:     int32(sink.Channel(event)),
+		Note:        int32(event.Note),
+		Velocity:    int32(event.Velocity),
+	})
+	if len(e.pending) >= e.options.BatchSize {
+		return e.flushLocked()
+	}
+	return nil
+}
+
+// Close writes any buffered events and the Parquet footer, then closes the file if the
+// exporter was created with Create.
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	err := e.flushLocked()
+	if closeErr := e.writer.Close(); closeErr != nil {
+		err = errors.Join(err, fmt.Errorf("error finalizing parquet file: %w", closeErr))
+	}
+	if e.closer != nil {
+		err = errors.Join(err, e.closer.Close())
+	}
+	return err
+}
+
+// flushLocked hands the pending rows to the Parquet writer. The caller must hold e.mu.
+func (e *Exporter) flushLocked() error {
+	if len(e.pending) == 0 {
+		return nil
+	}
+	if _, err := e.writer.Write(e.pending); err != nil {
+		return fmt.Errorf("error writing parquet rows: %w", err)
+	}
+	e.pending = e.pending[:0]
+	return nil
+}
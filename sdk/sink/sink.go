@@ -0,0 +1,32 @@
+package sink
+
+import (
+	"errors"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Sink consumes captured MIDI events, typically persisting or forwarding them elsewhere.
+type Sink interface {
+	Write(event contracts.MIDI) error // Write hands a single event to the sink.
+	Close() error                     // Close flushes pending data and releases resources.
+}
+
+// Drain reads events from eventChannel and writes them to s until the channel is closed,
+// then closes the sink. A failing Write does not stop the drain, so the capture side is
+// never blocked; the first write error is returned together with any error from Close.
+//
+// eventChannel <-chan contracts.MIDI: The channel passed to ClientMIDI.StartCapture.
+// s Sink: The destination for the events.
+//
+// Returns:
+//   - error: The first write error and the close error, joined, or nil.
+func Drain(eventChannel <-chan contracts.MIDI, s Sink) error {
+	var writeErr error
+	for event := range eventChannel {
+		if err := s.Write(event); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+	return errors.Join(writeErr, s.Close())
+}
@@ -0,0 +1,64 @@
+package soundfont
+
+import "github.com/leandrodaf/midi/sdk/velocity"
+
+// Options holds the configuration of a Synth.
+type Options struct {
+	SampleRate int            // Samples per second of the rendered audio.
+	Polyphony  int            // Maximum number of samples sounding at once; the oldest is cut beyond it.
+	Gain       float64        // Master gain, to leave headroom for chords.
+	Velocity   velocity.Curve // Curve used to convert velocities to gain.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithSampleRate sets the sample rate of the rendered audio.
+func WithSampleRate(rate int) Option {
+	return func(opts *Options) {
+		opts.SampleRate = rate
+	}
+}
+
+// WithPolyphony sets the maximum number of samples sounding at once.
+func WithPolyphony(voices int) Option {
+	return func(opts *Options) {
+		opts.Polyphony = voices
+	}
+}
+
+// WithGain sets the master gain.
+func WithGain(gain float64) Option {
+	return func(opts *Options) {
+		opts.Gain = gain
+	}
+}
+
+// WithVelocityCurve sets the curve used to convert velocities to gain.
+func WithVelocityCurve(curve velocity.Curve) Option {
+	return func(opts *Options) {
+		opts.Velocity = curve
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.SampleRate <= 0 {
+		options.SampleRate = 44100
+	}
+	if options.Polyphony <= 0 {
+		options.Polyphony = 64
+	}
+	if options.Gain <= 0 {
+		options.Gain = 0.5
+	}
+	if options.Velocity == nil {
+		options.Velocity = velocity.GM
+	}
+	return options
+}
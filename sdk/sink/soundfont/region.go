@@ -0,0 +1,164 @@
+package soundfont
+
+import "math"
+
+// region is a sample with the playback parameters resolved for a key and velocity.
+type region struct {
+	data             []float32
+	start, end       int
+	loopStart        int
+	loopEnd          int
+	loop             bool // Whether the sample loops while the note is held.
+	loopAfterRelease bool // Whether the sample keeps looping after the note off.
+	sampleRate       int
+	rootKey          int
+	tune             float64 // Tuning offset, in cents.
+	scaleTuning      float64 // Cents per key.
+	attenuation      float64 // Initial attenuation, in centibels.
+	delay, attack    float64 // Volume envelope stages, in seconds.
+	hold, decay      float64
+	sustain          float64 // Sustain level, from 0 to 1.
+	release          float64
+}
+
+// regions resolves the samples a preset plays for a key and velocity. Preset generators
+// are added to the instrument ones, as the SoundFont specification requires.
+func (f *SoundFont) regions(preset *Preset, key, velocity int) []region {
+	var result []region
+	presetGlobal := globalZone(preset.zones, genInstrument)
+	for i := range preset.zones {
+		presetZone := &preset.zones[i]
+		if !presetZone.has(genInstrument) || !inRange(presetZone, presetGlobal, key, velocity) {
+			continue
+		}
+		instrument := presetZone.value(genInstrument, -1)
+		if instrument < 0 || instrument >= len(f.instruments) {
+			continue
+		}
+		zones := f.instruments[instrument]
+		instrumentGlobal := globalZone(zones, genSampleID)
+		for j := range zones {
+			instrumentZone := &zones[j]
+			if !instrumentZone.has(genSampleID) || !inRange(instrumentZone, instrumentGlobal, key, velocity) {
+				continue
+			}
+			sampleID := instrumentZone.value(genSampleID, -1)
+			if sampleID < 0 || sampleID >= len(f.Samples) {
+				continue
+			}
+			if r, ok := f.region(&f.Samples[sampleID], instrumentZone, instrumentGlobal, presetZone, presetGlobal); ok {
+				result = append(result, r)
+			}
+		}
+	}
+	return result
+}
+
+// region combines the generators of an instrument zone and a preset zone for a sample.
+func (f *SoundFont) region(sample *Sample, instrumentZone, instrumentGlobal, presetZone, presetGlobal *zone) (region, bool) {
+	gen := func(operator int, def int) float64 {
+		value := generator(instrumentZone, instrumentGlobal, operator, def)
+		return float64(value + generator(presetZone, presetGlobal, operator, 0))
+	}
+	offset := func(fine, coarse int) int {
+		return generator(instrumentZone, instrumentGlobal, fine, 0) + generator(instrumentZone, instrumentGlobal, coarse, 0)*32768
+	}
+
+	r := region{
+		data:        f.data,
+		start:       sample.Start + offset(genStartAddrsOffset, genStartAddrsCoarseOffset),
+		end:         sample.End + offset(genEndAddrsOffset, genEndAddrsCoarseOffset),
+		loopStart:   sample.LoopStart + offset(genStartloopAddrsOffset, genStartloopAddrsCoarse),
+		loopEnd:     sample.LoopEnd + offset(genEndloopAddrsOffset, genEndloopAddrsCoarse),
+		sampleRate:  sample.SampleRate,
+		rootKey:     generator(instrumentZone, instrumentGlobal, genOverridingRootKey, -1),
+		tune:        gen(genCoarseTune, 0)*100 + gen(genFineTune, 0) + float64(sample.PitchCorrection),
+		scaleTuning: gen(genScaleTuning, 100),
+		attenuation: max(gen(genInitialAttenuation, 0), 0),
+		delay:       timecents(gen(genDelayVolEnv, -12000)),
+		attack:      timecents(gen(genAttackVolEnv, -12000)),
+		hold:        timecents(gen(genHoldVolEnv, -12000)),
+		decay:       timecents(gen(genDecayVolEnv, -12000)),
+		sustain:     centibels(max(gen(genSustainVolEnv, 0), 0)),
+		release:     timecents(gen(genReleaseVolEnv, -12000)),
+	}
+	if r.rootKey < 0 || r.rootKey > 127 {
+		r.rootKey = sample.OriginalPitch
+		if r.rootKey > 127 {
+			r.rootKey = 60
+		}
+	}
+
+	r.start = max(r.start, 0)
+	r.end = min(r.end, len(f.data)-1)
+	if r.start >= r.end || r.sampleRate <= 0 {
+		return region{}, false
+	}
+
+	switch generator(instrumentZone, instrumentGlobal, genSampleModes, 0) & 3 {
+	case 1:
+		r.loop, r.loopAfterRelease = true, true
+	case 3:
+		r.loop = true
+	}
+	if r.loopStart < r.start || r.loopEnd > r.end || r.loopStart >= r.loopEnd {
+		r.loop, r.loopAfterRelease = false, false
+	}
+	return r, true
+}
+
+// step returns how far the sample advances per output sample when playing key.
+func (r *region) step(key int, outputRate int) float64 {
+	cents := float64(key-r.rootKey)*r.scaleTuning + r.tune
+	return math.Exp2(cents/1200) * float64(r.sampleRate) / float64(outputRate)
+}
+
+// globalZone returns the global zone of a zone list: a first zone without the generator
+// that terminates local zones.
+func globalZone(zones []zone, terminal int) *zone {
+	if len(zones) > 0 && !zones[0].has(terminal) {
+		return &zones[0]
+	}
+	return nil
+}
+
+// generator returns the value of a generator from a local zone, falling back to the
+// global zone and then to def.
+func generator(local, global *zone, operator int, def int) int {
+	if local != nil && local.has(operator) {
+		return local.value(operator, def)
+	}
+	if global != nil {
+		return global.value(operator, def)
+	}
+	return def
+}
+
+// inRange reports whether key and velocity fall into the ranges of a zone.
+func inRange(local, global *zone, key, velocity int) bool {
+	for _, operator := range []int{genKeyRange, genVelRange} {
+		z := local
+		if !z.has(operator) && global != nil {
+			z = global
+		}
+		low, high := z.span(operator)
+		value := key
+		if operator == genVelRange {
+			value = velocity
+		}
+		if value < low || value > high {
+			return false
+		}
+	}
+	return true
+}
+
+// timecents converts a SoundFont time, in timecents, to seconds.
+func timecents(value float64) float64 {
+	return math.Exp2(value / 1200)
+}
+
+// centibels converts an attenuation, in centibels, to linear gain.
+func centibels(value float64) float64 {
+	return math.Pow(10, -value/200)
+}
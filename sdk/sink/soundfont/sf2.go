@@ -0,0 +1,277 @@
+package soundfont
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrInvalidSoundFont is returned when a file is not a valid SoundFont 2 file.
+var ErrInvalidSoundFont = errors.New("invalid SoundFont file")
+
+// SoundFont generator operators used by the renderer. Their numbering follows the
+// SoundFont 2.04 specification.
+const (
+	genStartAddrsOffset       = 0
+	genEndAddrsOffset         = 1
+	genStartloopAddrsOffset   = 2
+	genEndloopAddrsOffset     = 3
+	genStartAddrsCoarseOffset = 4
+	genEndAddrsCoarseOffset   = 12
+	genDelayVolEnv            = 33
+	genAttackVolEnv           = 34
+	genHoldVolEnv             = 35
+	genDecayVolEnv            = 36
+	genSustainVolEnv          = 37
+	genReleaseVolEnv          = 38
+	genInstrument             = 41
+	genKeyRange               = 43
+	genVelRange               = 44
+	genStartloopAddrsCoarse   = 45
+	genInitialAttenuation     = 48
+	genEndloopAddrsCoarse     = 50
+	genCoarseTune             = 51
+	genFineTune               = 52
+	genSampleID               = 53
+	genSampleModes            = 54
+	genScaleTuning            = 56
+	genOverridingRootKey      = 58
+	genCount                  = 61
+)
+
+// Preset is a playable sound of a SoundFont, selected with bank select and program change.
+type Preset struct {
+	Name    string
+	Bank    int
+	Program int
+	zones   []zone // Zones referencing instruments.
+}
+
+// Sample describes a recorded waveform stored in the SoundFont.
+type Sample struct {
+	Name            string
+	Start, End      int // Range of the sample in the sample data.
+	LoopStart       int
+	LoopEnd         int
+	SampleRate      int
+	OriginalPitch   int // MIDI note recorded in the sample.
+	PitchCorrection int // Correction of OriginalPitch, in cents.
+}
+
+// SoundFont is a parsed SoundFont 2 (.sf2) file.
+type SoundFont struct {
+	Name        string
+	Presets     []Preset
+	Samples     []Sample
+	instruments [][]zone  // Zones of each instrument, referencing samples.
+	data        []float32 // Sample data, normalized to [-1, 1].
+}
+
+// zone is a set of generators applying to a key and velocity range.
+type zone struct {
+	gens [genCount]int16
+	set  [genCount]bool
+}
+
+// has reports whether the zone sets a generator.
+func (z *zone) has(gen int) bool {
+	return z.set[gen]
+}
+
+// value returns the value of a generator, or def if it is not set.
+func (z *zone) value(gen int, def int) int {
+	if z.set[gen] {
+		return int(z.gens[gen])
+	}
+	return def
+}
+
+// span returns the low and high bytes of a range generator, or 0-127 if it is not set.
+func (z *zone) span(gen int) (int, int) {
+	if !z.set[gen] {
+		return 0, 127
+	}
+	amount := uint16(z.gens[gen])
+	return int(amount & 0xFF), int(amount >> 8)
+}
+
+// Load reads a SoundFont 2 file.
+//
+// path string: Path of the .sf2 file.
+//
+// Returns:
+//   - *SoundFont: The parsed SoundFont.
+//   - error: An error if the file cannot be read or is not a valid SoundFont.
+func Load(path string) (*SoundFont, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening SoundFont: %w", err)
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// Parse reads a SoundFont 2 file from r.
+//
+// r io.Reader: Reader providing the contents of an .sf2 file.
+//
+// Returns:
+//   - *SoundFont: The parsed SoundFont.
+//   - error: An error if the contents are not a valid SoundFont.
+func Parse(r io.Reader) (*SoundFont, error) {
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading SoundFont: %w", err)
+	}
+	if len(contents) < 12 || string(contents[0:4]) != "RIFF" || string(contents[8:12]) != "sfbk" {
+		return nil, fmt.Errorf("%w: missing sfbk header", ErrInvalidSoundFont)
+	}
+
+	chunks := map[string][]byte{}
+	if err := readChunks(contents[12:], chunks); err != nil {
+		return nil, err
+	}
+	for _, id := range []string{"smpl", "phdr", "pbag", "pgen", "inst", "ibag", "igen", "shdr"} {
+		if _, ok := chunks[id]; !ok {
+			return nil, fmt.Errorf("%w: missing %s chunk", ErrInvalidSoundFont, id)
+		}
+	}
+
+	font := &SoundFont{Name: fixedString(chunks["INAM"])}
+	smpl := chunks["smpl"]
+	font.data = make([]float32, len(smpl)/2)
+	for i := range font.data {
+		font.data[i] = float32(int16(binary.LittleEndian.Uint16(smpl[i*2:]))) / 32768
+	}
+
+	// Every header list ends with a terminal record, which is not a sample, instrument or preset.
+	samples := records(chunks["shdr"], 46)
+	for _, record := range samples[:max(len(samples)-1, 0)] {
+		font.Samples = append(font.Samples, Sample{
+			Name:            fixedString(record[0:20]),
+			Start:           int(binary.LittleEndian.Uint32(record[20:])),
+			End:             int(binary.LittleEndian.Uint32(record[24:])),
+			LoopStart:       int(binary.LittleEndian.Uint32(record[28:])),
+			LoopEnd:         int(binary.LittleEndian.Uint32(record[32:])),
+			SampleRate:      int(binary.LittleEndian.Uint32(record[36:])),
+			OriginalPitch:   int(record[40]),
+			PitchCorrection: int(int8(record[41])),
+		})
+	}
+
+	instrumentZones, err := zones(chunks["ibag"], chunks["igen"])
+	if err != nil {
+		return nil, err
+	}
+	presetZones, err := zones(chunks["pbag"], chunks["pgen"])
+	if err != nil {
+		return nil, err
+	}
+
+	// The terminal record marks the end of the last bag range.
+	instruments := records(chunks["inst"], 22)
+	for i := 0; i+1 < len(instruments); i++ {
+		first := int(binary.LittleEndian.Uint16(instruments[i][20:]))
+		last := int(binary.LittleEndian.Uint16(instruments[i+1][20:]))
+		if first > last || last > len(instrumentZones) {
+			return nil, fmt.Errorf("%w: bad instrument zone index", ErrInvalidSoundFont)
+		}
+		font.instruments = append(font.instruments, instrumentZones[first:last])
+	}
+
+	presets := records(chunks["phdr"], 38)
+	for i := 0; i+1 < len(presets); i++ {
+		first := int(binary.LittleEndian.Uint16(presets[i][24:]))
+		last := int(binary.LittleEndian.Uint16(presets[i+1][24:]))
+		if first > last || last > len(presetZones) {
+			return nil, fmt.Errorf("%w: bad preset zone index", ErrInvalidSoundFont)
+		}
+		font.Presets = append(font.Presets, Preset{
+			Name:    fixedString(presets[i][0:20]),
+			Program: int(binary.LittleEndian.Uint16(presets[i][20:])),
+			Bank:    int(binary.LittleEndian.Uint16(presets[i][22:])),
+			zones:   presetZones[first:last],
+		})
+	}
+	return font, nil
+}
+
+// Preset returns the preset with the given bank and program, if any.
+func (f *SoundFont) Preset(bank, program int) (*Preset, bool) {
+	for i := range f.Presets {
+		if f.Presets[i].Bank == bank && f.Presets[i].Program == program {
+			return &f.Presets[i], true
+		}
+	}
+	return nil, false
+}
+
+// readChunks collects the chunks of a RIFF body, descending into LIST chunks.
+func readChunks(body []byte, chunks map[string][]byte) error {
+	for len(body) >= 8 {
+		id := string(body[0:4])
+		size := int(binary.LittleEndian.Uint32(body[4:8]))
+		if size > len(body)-8 {
+			return fmt.Errorf("%w: truncated %s chunk", ErrInvalidSoundFont, id)
+		}
+		data := body[8 : 8+size]
+		if id == "LIST" {
+			if len(data) < 4 {
+				return fmt.Errorf("%w: empty LIST chunk", ErrInvalidSoundFont)
+			}
+			if err := readChunks(data[4:], chunks); err != nil {
+				return err
+			}
+		} else {
+			chunks[id] = data
+		}
+		// Chunks are padded to an even size.
+		body = body[8+size+size%2:]
+	}
+	return nil
+}
+
+// records splits a chunk into fixed-size records.
+func records(chunk []byte, size int) [][]byte {
+	result := make([][]byte, 0, len(chunk)/size)
+	for len(chunk) >= size {
+		result = append(result, chunk[:size])
+		chunk = chunk[size:]
+	}
+	return result
+}
+
+// zones builds the zones described by a bag chunk and its generator chunk.
+func zones(bags, gens []byte) ([]zone, error) {
+	bagRecords := records(bags, 4)
+	genRecords := records(gens, 4)
+	result := make([]zone, 0, len(bagRecords))
+	for i := 0; i+1 < len(bagRecords); i++ {
+		first := int(binary.LittleEndian.Uint16(bagRecords[i]))
+		last := int(binary.LittleEndian.Uint16(bagRecords[i+1]))
+		if first > last || last > len(genRecords) {
+			return nil, fmt.Errorf("%w: bad generator index", ErrInvalidSoundFont)
+		}
+		var z zone
+		for _, record := range genRecords[first:last] {
+			operator := int(binary.LittleEndian.Uint16(record))
+			if operator < genCount {
+				z.gens[operator] = int16(binary.LittleEndian.Uint16(record[2:]))
+				z.set[operator] = true
+			}
+		}
+		result = append(result, z)
+	}
+	return result, nil
+}
+
+// fixedString decodes a zero-padded string field.
+func fixedString(field []byte) string {
+	if i := bytes.IndexByte(field, 0); i >= 0 {
+		field = field[:i]
+	}
+	return string(field)
+}
@@ -0,0 +1,87 @@
+package soundfont
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/leandrodaf/midi/internal/audio"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Errors returned by Sink.
+var (
+	ErrAudioUnsupported = audio.ErrUnsupported
+	ErrSinkClosed       = errors.New("soundfont sink closed")
+)
+
+// DeviceName is the name OutputDevice lists the synthesizer under.
+const DeviceName = "SoundFont"
+
+// Sink plays captured events through a SoundFont Synth on the default audio output. It
+// implements sink.Sink, so it can be fed with sink.Drain.
+type Sink struct {
+	synth  *Synth
+	output io.Closer // Audio player reading from synth.
+	mu     sync.Mutex
+	closed bool
+}
+
+// New creates a SoundFont synthesizer and starts playing it on the default audio output.
+// Audio output is available on macOS and Windows; elsewhere ErrAudioUnsupported is returned.
+//
+// font *SoundFont: The SoundFont providing the instruments, e.g. from Load.
+// opts ...Option: A variadic list of option functions to customize the synthesizer.
+//
+// Returns:
+//   - *Sink: The sink, ready to receive events.
+//   - error: An error if the audio output could not be opened.
+func New(font *SoundFont, opts ...Option) (*Sink, error) {
+	synth := NewSynth(font, opts...)
+	output, err := audio.Open(synth.SampleRate(), synth)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{synth: synth, output: output}, nil
+}
+
+// Synth returns the synthesizer played by the sink.
+func (s *Sink) Synth() *Synth {
+	return s.synth
+}
+
+// Write plays an event.
+func (s *Sink) Write(event contracts.MIDI) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+	s.synth.Handle(event)
+	return nil
+}
+
+// Close stops the audio output. Sounding notes are cut.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.output.Close()
+}
+
+// OutputDevice returns the option listing the sink as an output device named DeviceName
+// on the client, so selecting it with SelectOutputDevice plays the messages passed to
+// Send and echoed by thru, like a hardware sound module.
+//
+// s *Sink: The sink playing the messages.
+//
+// Returns:
+//   - contracts.Option: The option to pass to midi.NewMIDIClient.
+func OutputDevice(s *Sink) contracts.Option {
+	return contracts.WithSoftwareOutput(DeviceName, s.Write)
+}
@@ -0,0 +1,310 @@
+// Package soundfont plays captured events through SoundFont 2 (.sf2) instruments, for
+// higher-quality auditioning than the oscillators of sdk/sink/synth. Sink plays them on the
+// default audio output on macOS and Windows; Synth itself renders samples on every platform.
+package soundfont
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/sink"
+)
+
+// Controllers and channels handled by the synthesizer.
+const (
+	ccBankSelect      = 0
+	ccAllSoundOff     = 120
+	ccAllNotesOff     = 123
+	percussionChannel = 9   // Zero-based channel 10, which plays drum kits.
+	percussionBank    = 128 // Bank holding drum kits in General MIDI SoundFonts.
+)
+
+// Envelope stages of a voice.
+const (
+	stageDelay = iota
+	stageAttack
+	stageHold
+	stageDecay
+	stageSustain
+	stageRelease
+	stageDone
+)
+
+// voice is one sample playing for a note.
+type voice struct {
+	region   region
+	channel  byte
+	note     byte
+	position float64 // Position in the sample data.
+	step     float64 // Position increase per output sample.
+	gain     float64 // Gain from velocity, attenuation and Options.Gain.
+	stage    int
+	elapsed  float64 // Seconds spent in the current stage.
+	level    float64 // Current envelope level, from 0 to 1.
+	from     float64 // Envelope level when the release started.
+	age      uint64  // Order in which the voice started, for voice stealing.
+}
+
+// Synth is a polyphonic SoundFont synthesizer rendering 32-bit float mono samples. It
+// follows program changes and bank selects per channel, and plays drum kits on channel 10.
+// It is safe for concurrent use: events can be handled while audio is rendered.
+type Synth struct {
+	font     *SoundFont
+	options  Options
+	interval float64 // Seconds per output sample.
+	mu       sync.Mutex
+	programs [16]int
+	banks    [16]int
+	voices   []voice
+	started  uint64 // Number of voices started so far.
+}
+
+// NewSynth creates a synthesizer playing a SoundFont.
+//
+// font *SoundFont: The SoundFont providing the instruments.
+// opts ...Option: A variadic list of option functions to customize the synthesizer.
+//
+// Returns:
+//   - *Synth: The synthesizer, silent until notes are played.
+func NewSynth(font *SoundFont, opts ...Option) *Synth {
+	options := applyDefaultOptions(opts...)
+	s := &Synth{
+		font:     font,
+		options:  options,
+		interval: 1 / float64(options.SampleRate),
+		voices:   make([]voice, 0, options.Polyphony),
+	}
+	s.banks[percussionChannel] = percussionBank
+	return s
+}
+
+// SampleRate returns the sample rate of the rendered audio.
+func (s *Synth) SampleRate() int {
+	return s.options.SampleRate
+}
+
+// Handle plays a captured event: note on and off, program change, bank select and the
+// all notes off and all sound off controllers. Other messages are ignored.
+func (s *Synth) Handle(event contracts.MIDI) {
+	channel := sink.Channel(event)
+	switch sink.MessageType(event) {
+	case "note_on":
+		s.NoteOn(channel, event.Note, event.Velocity)
+	case "note_off":
+		s.NoteOff(channel, event.Note)
+	case "program_change":
+		s.ProgramChange(channel, event.Note)
+	case "control_change":
+		switch event.Note {
+		case ccBankSelect:
+			s.BankSelect(channel, event.Velocity)
+		case ccAllSoundOff, ccAllNotesOff:
+			s.AllNotesOff()
+		}
+	}
+}
+
+// ProgramChange selects the preset a channel plays.
+func (s *Synth) ProgramChange(channel, program byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.programs[channel&0x0F] = int(program)
+}
+
+// BankSelect selects the bank of the presets a channel plays. Channel 10 always plays
+// the percussion bank.
+func (s *Synth) BankSelect(channel, bank byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if channel&0x0F != percussionChannel {
+		s.banks[channel&0x0F] = int(bank)
+	}
+}
+
+// NoteOn starts a note with the preset selected on the channel. When all voices are busy,
+// the oldest ones are replaced.
+func (s *Synth) NoteOn(channel, note, velocity byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel &= 0x0F
+	preset := s.preset(channel)
+	if preset == nil {
+		return
+	}
+
+	gain := s.options.Velocity(velocity) * s.options.Gain
+	for _, r := range s.font.regions(preset, int(note), int(velocity)) {
+		s.started++
+		v := voice{
+			region:   r,
+			channel:  channel,
+			note:     note,
+			position: float64(r.start),
+			step:     r.step(int(note), s.options.SampleRate),
+			gain:     gain * centibels(r.attenuation),
+			age:      s.started,
+		}
+		if len(s.voices) < s.options.Polyphony {
+			s.voices = append(s.voices, v)
+			continue
+		}
+		oldest := 0
+		for i := range s.voices {
+			if s.voices[i].age < s.voices[oldest].age {
+				oldest = i
+			}
+		}
+		s.voices[oldest] = v
+	}
+}
+
+// NoteOff releases every voice playing the note on the channel.
+func (s *Synth) NoteOff(channel, note byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.voices {
+		if s.voices[i].channel == channel&0x0F && s.voices[i].note == note {
+			s.voices[i].release()
+		}
+	}
+}
+
+// AllNotesOff releases every voice.
+func (s *Synth) AllNotesOff() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.voices {
+		s.voices[i].release()
+	}
+}
+
+// Render fills buf with the next samples, mixing every sounding voice.
+func (s *Synth) Render(buf []float32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range buf {
+		var sample float64
+		for j := range s.voices {
+			v := &s.voices[j]
+			if v.stage == stageDone {
+				continue
+			}
+			v.advanceEnvelope(s.interval)
+			sample += v.read() * v.gain * v.level
+		}
+		buf[i] = float32(max(min(sample, 1), -1))
+	}
+
+	// Drop voices whose envelope or sample ended.
+	active := s.voices[:0]
+	for _, v := range s.voices {
+		if v.stage != stageDone {
+			active = append(active, v)
+		}
+	}
+	s.voices = active
+}
+
+// Read renders samples as little-endian 32-bit floats, the format audio libraries such as
+// oto expect. It never ends: silence is rendered when no note sounds.
+func (s *Synth) Read(p []byte) (int, error) {
+	samples := make([]float32, len(p)/4)
+	s.Render(samples)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint32(p[i*4:], math.Float32bits(sample))
+	}
+	return len(samples) * 4, nil
+}
+
+// preset returns the preset selected on a channel, falling back to the same program in
+// bank 0 and then to the first preset of the SoundFont.
+func (s *Synth) preset(channel byte) *Preset {
+	if preset, ok := s.font.Preset(s.banks[channel], s.programs[channel]); ok {
+		return preset
+	}
+	if preset, ok := s.font.Preset(0, s.programs[channel]); ok {
+		return preset
+	}
+	if len(s.font.Presets) > 0 {
+		return &s.font.Presets[0]
+	}
+	return nil
+}
+
+// release starts the release stage of the voice.
+func (v *voice) release() {
+	if v.stage < stageRelease {
+		v.from = v.level
+		v.stage = stageRelease
+		v.elapsed = 0
+	}
+}
+
+// advanceEnvelope moves the volume envelope forward by interval seconds.
+func (v *voice) advanceEnvelope(interval float64) {
+	v.elapsed += interval
+	r := &v.region
+	switch v.stage {
+	case stageDelay:
+		v.level = 0
+		v.next(r.delay, stageAttack)
+	case stageAttack:
+		v.level = min(v.elapsed/r.attack, 1)
+		v.next(r.attack, stageHold)
+	case stageHold:
+		v.level = 1
+		v.next(r.hold, stageDecay)
+	case stageDecay:
+		v.level = 1 - (1-r.sustain)*min(v.elapsed/r.decay, 1)
+		v.next(r.decay, stageSustain)
+	case stageSustain:
+		v.level = r.sustain
+		if v.level <= 0 {
+			v.stage = stageDone
+		}
+	case stageRelease:
+		v.level = v.from * max(1-v.elapsed/r.release, 0)
+		v.next(r.release, stageDone)
+	}
+}
+
+// next moves to the following envelope stage once the current one lasted duration seconds.
+func (v *voice) next(duration float64, stage int) {
+	if v.elapsed >= duration {
+		v.stage = stage
+		v.elapsed = 0
+	}
+}
+
+// read returns the sample at the current position, interpolated linearly, and advances
+// the position, looping or ending the voice as the region requires.
+func (v *voice) read() float64 {
+	r := &v.region
+	loop := r.loop && (v.stage != stageRelease || r.loopAfterRelease)
+	if loop {
+		for v.position >= float64(r.loopEnd) {
+			v.position -= float64(r.loopEnd - r.loopStart)
+		}
+	} else if v.position >= float64(r.end-1) {
+		v.stage = stageDone
+		return 0
+	}
+
+	index := int(v.position)
+	next := index + 1
+	if loop && next >= r.loopEnd {
+		next = r.loopStart
+	}
+	fraction := v.position - float64(index)
+	value := float64(r.data[index])*(1-fraction) + float64(r.data[next])*fraction
+	v.position += v.step
+	return value
+}
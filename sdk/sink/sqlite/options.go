@@ -0,0 +1,61 @@
+package sqlite
+
+import "time"
+
+// Options holds the configuration of a SQLite sink.
+type Options struct {
+	Session       string        // Identifier stored with every event; defaults to the sink start time.
+	Device        string        // Name of the device the events come from.
+	BatchSize     int           // Number of events buffered before they are written in one transaction.
+	FlushInterval time.Duration // Maximum time an event stays buffered before being written.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithSession sets the session identifier stored with every event.
+func WithSession(session string) Option {
+	return func(opts *Options) {
+		opts.Session = session
+	}
+}
+
+// WithDevice sets the device name stored with every event.
+func WithDevice(device string) Option {
+	return func(opts *Options) {
+		opts.Device = device
+	}
+}
+
+// WithBatchSize sets how many events are buffered before a batch is written.
+func WithBatchSize(size int) Option {
+	return func(opts *Options) {
+		opts.BatchSize = size
+	}
+}
+
+// WithFlushInterval sets the maximum time an event stays buffered before being written.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.FlushInterval = interval
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Session == "" {
+		options.Session = time.Now().UTC().Format("20060102T150405Z")
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 256
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = time.Second
+	}
+	return options
+}
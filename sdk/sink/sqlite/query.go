@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Record is a persisted MIDI event together with the metadata stored alongside it.
+type Record struct {
+	contracts.MIDI
+	ID          int64  // Row identifier.
+	Session     string // Session the event was captured in.
+	Device      string // Device the event was captured from.
+	MessageType string // Decoded message type (note_on, control_change, ...).
+	Channel     byte   // Zero-based MIDI channel.
+}
+
+// Query narrows the events returned by Events. Zero values match everything.
+type Query struct {
+	Session     string    // Only events of this session.
+	Device      string    // Only events of this device.
+	From        time.Time // Only events at or after this time.
+	To          time.Time // Only events before this time.
+	MessageType string    // Only events of this decoded message type.
+	Channels    []byte    // Only events on these zero-based channels.
+	Notes       []byte    // Only events with these note numbers.
+	Limit       int       // Maximum number of events returned.
+}
+
+// SessionSummary describes one recorded session.
+type SessionSummary struct {
+	Session string    // Session identifier.
+	Device  string    // Device the session was captured from.
+	Start   time.Time // Time of the first event.
+	End     time.Time // Time of the last event.
+	Events  int64     // Number of events recorded.
+}
+
+// Events returns the persisted events matching q, ordered by timestamp.
+func Events(ctx context.Context, db *sql.DB, q Query) ([]Record, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if q.Session != "" {
+		where = append(where, "session = ?")
+		args = append(args, q.Session)
+	}
+	if q.Device != "" {
+		where = append(where, "device = ?")
+		args = append(args, q.Device)
+	}
+	if !q.From.IsZero() {
+		where = append(where, "timestamp >= ?")
+		args = append(args, q.From.UnixNano())
+	}
+	if !q.To.IsZero() {
+		where = append(where, "timestamp < ?")
+		args = append(args, q.To.UnixNano())
+	}
+	if q.MessageType != "" {
+		where = append(where, "message_type = ?")
+		args = append(args, q.MessageType)
+	}
+	if len(q.Channels) > 0 {
+		where = append(where, "channel IN ("+placeholders(len(q.Channels))+")")
+		for _, c := range q.Channels {
+			args = append(args, c)
+		}
+	}
+	if len(q.Notes) > 0 {
+		where = append(where, "note IN ("+placeholders(len(q.Notes))+")")
+		for _, n := range q.Notes {
+			args = append(args, n)
+		}
+	}
+
+	query := "SELECT id, session, device, timestamp, status, message_type, channel, note, velocity FROM " + TableName
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY timestamp, id"
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying MIDI events: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var (
+			r         Record
+			timestamp int64
+		)
+		if err := rows.Scan(&r.ID, &r.Session, &r.Device, &timestamp, &r.Command,
+			&r.MessageType, &r.Channel, &r.Note, &r.Velocity); err != nil {
+			return nil, fmt.Errorf("error reading MIDI event: %w", err)
+		}
+		r.Timestamp = uint64(timestamp)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Sessions returns a summary of every session stored in db, oldest first.
+func Sessions(ctx context.Context, db *sql.DB) ([]SessionSummary, error) {
+	rows, err := db.QueryContext(ctx, `SELECT session, device, MIN(timestamp), MAX(timestamp), COUNT(*)
+		FROM `+TableName+` GROUP BY session, device ORDER BY MIN(timestamp)`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying MIDI sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []SessionSummary
+	for rows.Next() {
+		var (
+			s          SessionSummary
+			start, end int64
+		)
+		if err := rows.Scan(&s.Session, &s.Device, &start, &end, &s.Events); err != nil {
+			return nil, fmt.Errorf("error reading MIDI session: %w", err)
+		}
+		s.Start = time.Unix(0, start).UTC()
+		s.End = time.Unix(0, end).UTC()
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// placeholders returns n comma-separated SQL parameter placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
@@ -0,0 +1,26 @@
+package sqlite
+
+// TableName is the table events are written to.
+const TableName = "midi_events"
+
+// schema creates the events table and its indexes. Timestamps are stored as Unix
+// nanoseconds so they can be compared and aggregated directly in SQL.
+const schema = `
+CREATE TABLE IF NOT EXISTS midi_events (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	session      TEXT    NOT NULL,
+	device       TEXT    NOT NULL,
+	timestamp    INTEGER NOT NULL,
+	status       INTEGER NOT NULL,
+	message_type TEXT    NOT NULL,
+	channel      INTEGER NOT NULL,
+	note         INTEGER NOT NULL,
+	velocity     INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS midi_events_session_timestamp ON midi_events (session, timestamp);
+CREATE INDEX IF NOT EXISTS midi_events_note ON midi_events (note);
+`
+
+const insertStatement = `INSERT INTO midi_events
+	(session, device, timestamp, status, message_type, channel, note, velocity)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
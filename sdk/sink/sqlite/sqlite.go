@@ -0,0 +1,176 @@
+// Package sqlite provides a sink that persists captured MIDI events into a SQLite
+// database so sessions can be analyzed afterwards with plain SQL.
+//
+// The package does not register a driver; open the database with the SQLite driver
+// of your choice (for example modernc.org/sqlite or github.com/mattn/go-sqlite3)
+// and pass the resulting *sql.DB to New.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/profiling"
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/sink"
+)
+
+// ErrSinkClosed is returned when writing to a sink that has already been closed.
+var ErrSinkClosed = errors.New("sqlite sink closed")
+
+// Sink buffers MIDI events and writes them to SQLite in batched transactions.
+type Sink struct {
+	db       *sql.DB
+	options  Options
+	mu       sync.Mutex // Protects pending, closed and flushErr.
+	pending  []contracts.MIDI
+	closed   bool
+	flushErr error          // Error from a background flush, reported on the next Write or Close.
+	done     chan struct{}  // Closed to stop the background flusher.
+	wg       sync.WaitGroup // Tracks the background flusher.
+}
+
+// New creates the events schema if needed and returns a sink writing to db.
+//
+// db *sql.DB: A database opened with any SQLite driver.
+// opts ...Option: A variadic list of option functions to customize the sink.
+//
+// Returns:
+//   - *Sink: The sink, ready to receive events.
+//   - error: An error if the schema could not be created.
+func New(db *sql.DB, opts ...Option) (*Sink, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("error creating %s schema: %w", TableName, err)
+	}
+
+	options := applyDefaultOptions(opts...)
+	s := &Sink{
+		db:      db,
+		options: options,
+		pending: make([]contracts.MIDI, 0, options.BatchSize),
+		done:    make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	profiling.Go(profiling.RoleScheduler, "sqlite", options.Device, s.flushPeriodically)
+	return s, nil
+}
+
+// Session returns the session identifier stored with the events of this sink.
+func (s *Sink) Session() string {
+	return s.options.Session
+}
+
+// Write buffers an event, writing the whole batch once it reaches the configured size.
+func (s *Sink) Write(event contracts.MIDI) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+	if err := s.flushErr; err != nil {
+		s.flushErr = nil
+		return err
+	}
+
+	s.pending = append(s.pending, event)
+	if len(s.pending) >= s.options.BatchSize {
+		return s.flushLocked()
+	}
+	return nil
+}
+
+// Flush writes all buffered events immediately.
+func (s *Sink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+// Close stops the background flusher and writes any buffered events.
+// The database itself is left open and remains owned by the caller.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.flushErr, s.flushLocked())
+}
+
+// flushPeriodically writes buffered events every FlushInterval until the sink is closed.
+func (s *Sink) flushPeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if err := s.flushLocked(); err != nil && s.flushErr == nil {
+				s.flushErr = err
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// flushLocked writes the pending batch in a single transaction. The caller must hold s.mu.
+// On failure the batch is kept so the next flush retries it.
+func (s *Sink) flushLocked() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting SQLite transaction: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, insertStatement)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("error preparing SQLite insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, event := range s.pending {
+		if _, err := stmt.ExecContext(ctx,
+			s.options.Session,
+			s.options.Device,
+			int64(event.Timestamp),
+			event.Command,
+			sink.MessageType(event),
+			sink.Channel(event),
+			event.Note,
+			event.Velocity,
+		); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("error inserting MIDI event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing SQLite transaction: %w", err)
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
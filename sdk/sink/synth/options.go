@@ -0,0 +1,104 @@
+package synth
+
+import (
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/tuning"
+	"github.com/leandrodaf/midi/sdk/velocity"
+)
+
+// Options holds the configuration of a Synth.
+type Options struct {
+	SampleRate int            // Samples per second of the rendered audio.
+	Waveform   Waveform       // Shape of the oscillator of every voice.
+	Polyphony  int            // Maximum number of notes sounding at once; the oldest note is cut beyond it.
+	Gain       float64        // Gain applied to every voice at full velocity, to leave headroom for chords.
+	Attack     time.Duration  // Time a voice takes to reach full level.
+	Release    time.Duration  // Time a voice takes to fade out after its note off.
+	Tuning     *tuning.Tuning // Tuning used to convert notes to frequencies.
+	Velocity   velocity.Curve // Curve used to convert velocities to gain.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithSampleRate sets the sample rate of the rendered audio.
+func WithSampleRate(rate int) Option {
+	return func(opts *Options) {
+		opts.SampleRate = rate
+	}
+}
+
+// WithWaveform sets the oscillator shape.
+func WithWaveform(waveform Waveform) Option {
+	return func(opts *Options) {
+		opts.Waveform = waveform
+	}
+}
+
+// WithPolyphony sets the maximum number of notes sounding at once.
+func WithPolyphony(voices int) Option {
+	return func(opts *Options) {
+		opts.Polyphony = voices
+	}
+}
+
+// WithGain sets the gain of a voice at full velocity.
+func WithGain(gain float64) Option {
+	return func(opts *Options) {
+		opts.Gain = gain
+	}
+}
+
+// WithEnvelope sets the attack and release times of every voice.
+func WithEnvelope(attack, release time.Duration) Option {
+	return func(opts *Options) {
+		opts.Attack = attack
+		opts.Release = release
+	}
+}
+
+// WithTuning sets the tuning used to convert notes to frequencies.
+func WithTuning(t *tuning.Tuning) Option {
+	return func(opts *Options) {
+		opts.Tuning = t
+	}
+}
+
+// WithVelocityCurve sets the curve used to convert velocities to gain.
+func WithVelocityCurve(curve velocity.Curve) Option {
+	return func(opts *Options) {
+		opts.Velocity = curve
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.SampleRate <= 0 {
+		options.SampleRate = 44100
+	}
+	if options.Polyphony <= 0 {
+		options.Polyphony = 16
+	}
+	if options.Gain <= 0 {
+		options.Gain = 0.2
+	}
+	if options.Attack <= 0 {
+		options.Attack = 5 * time.Millisecond
+	}
+	if options.Release <= 0 {
+		options.Release = 150 * time.Millisecond
+	}
+	if options.Tuning == nil {
+		options.Tuning = tuning.Standard
+	}
+	if options.Velocity == nil {
+		options.Velocity = velocity.GM
+	}
+	return options
+}
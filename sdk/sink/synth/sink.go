@@ -0,0 +1,71 @@
+package synth
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/leandrodaf/midi/internal/audio"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Errors returned by Sink.
+var (
+	ErrAudioUnsupported = audio.ErrUnsupported
+	ErrSinkClosed       = errors.New("synth sink closed")
+)
+
+// Sink plays captured events through a Synth on the default audio output. It implements
+// sink.Sink, so it can be fed with sink.Drain.
+type Sink struct {
+	synth  *Synth
+	output io.Closer // Audio player reading from synth.
+	mu     sync.Mutex
+	closed bool
+}
+
+// New creates a synthesizer and starts playing it on the default audio output.
+// Audio output is available on macOS and Windows; elsewhere ErrAudioUnsupported is returned.
+//
+// opts ...Option: A variadic list of option functions to customize the synthesizer.
+//
+// Returns:
+//   - *Sink: The sink, ready to receive events.
+//   - error: An error if the audio output could not be opened.
+func New(opts ...Option) (*Sink, error) {
+	synth := NewSynth(opts...)
+	output, err := audio.Open(synth.SampleRate(), synth)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{synth: synth, output: output}, nil
+}
+
+// Synth returns the synthesizer played by the sink.
+func (s *Sink) Synth() *Synth {
+	return s.synth
+}
+
+// Write plays an event.
+func (s *Sink) Write(event contracts.MIDI) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+	s.synth.Handle(event)
+	return nil
+}
+
+// Close stops the audio output. Sounding notes are cut.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.output.Close()
+}
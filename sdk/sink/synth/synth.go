@@ -0,0 +1,204 @@
+// Package synth renders captured notes to audio with a small built-in synthesizer, so
+// captured events can be heard during development without an external instrument.
+// Sink plays them on the default audio output on macOS and Windows; Synth itself renders
+// samples on every platform and can feed any audio library.
+package synth
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/sink"
+)
+
+// Waveform selects the shape of the oscillators.
+type Waveform int
+
+const (
+	// Sine is a pure tone.
+	Sine Waveform = iota
+	// Square is a hollow, reedy tone.
+	Square
+	// Sawtooth is a bright, buzzy tone.
+	Sawtooth
+	// Triangle is a soft tone between sine and square.
+	Triangle
+)
+
+// ccAllNotesOff is the control change that silences every note of a channel.
+const ccAllNotesOff = 123
+
+// voice is one sounding note.
+type voice struct {
+	channel   byte
+	note      byte
+	step      float64 // Phase increment per sample, in cycles.
+	phase     float64 // Current phase, in cycles.
+	gain      float64 // Gain from velocity and Options.Gain.
+	level     float64 // Current envelope level, from 0 to 1.
+	releasing bool    // Whether the note off was received.
+	age       uint64  // Order in which the voice started, for voice stealing.
+}
+
+// Synth is a polyphonic synthesizer rendering 32-bit float mono samples. It is safe for
+// concurrent use: events can be handled while audio is rendered.
+type Synth struct {
+	options     Options
+	attackStep  float64 // Envelope increase per sample during attack.
+	releaseStep float64 // Envelope decrease per sample during release.
+	mu          sync.Mutex
+	voices      []voice
+	started     uint64 // Number of voices started so far.
+}
+
+// NewSynth creates a synthesizer.
+//
+// opts ...Option: A variadic list of option functions to customize the synthesizer.
+//
+// Returns:
+//   - *Synth: The synthesizer, silent until notes are played.
+func NewSynth(opts ...Option) *Synth {
+	options := applyDefaultOptions(opts...)
+	rate := float64(options.SampleRate)
+	return &Synth{
+		options:     options,
+		attackStep:  1 / (options.Attack.Seconds() * rate),
+		releaseStep: 1 / (options.Release.Seconds() * rate),
+		voices:      make([]voice, 0, options.Polyphony),
+	}
+}
+
+// SampleRate returns the sample rate of the rendered audio.
+func (s *Synth) SampleRate() int {
+	return s.options.SampleRate
+}
+
+// Handle plays a captured event: note on and off, and the all notes off controller.
+// Other messages are ignored.
+func (s *Synth) Handle(event contracts.MIDI) {
+	channel := sink.Channel(event)
+	switch sink.MessageType(event) {
+	case "note_on":
+		s.NoteOn(channel, event.Note, event.Velocity)
+	case "note_off":
+		s.NoteOff(channel, event.Note)
+	case "control_change":
+		if event.Note == ccAllNotesOff {
+			s.AllNotesOff()
+		}
+	}
+}
+
+// NoteOn starts a note. When all voices are busy, the oldest one is replaced.
+func (s *Synth) NoteOn(channel, note, velocity byte) {
+	frequency := s.options.Tuning.NoteFrequency(note)
+	if frequency <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.started++
+	v := voice{
+		channel: channel,
+		note:    note,
+		step:    frequency / float64(s.options.SampleRate),
+		gain:    s.options.Velocity(velocity) * s.options.Gain,
+		age:     s.started,
+	}
+	if len(s.voices) < s.options.Polyphony {
+		s.voices = append(s.voices, v)
+		return
+	}
+	oldest := 0
+	for i := range s.voices {
+		if s.voices[i].age < s.voices[oldest].age {
+			oldest = i
+		}
+	}
+	s.voices[oldest] = v
+}
+
+// NoteOff releases every voice playing the note on the channel.
+func (s *Synth) NoteOff(channel, note byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.voices {
+		if s.voices[i].channel == channel && s.voices[i].note == note {
+			s.voices[i].releasing = true
+		}
+	}
+}
+
+// AllNotesOff releases every voice.
+func (s *Synth) AllNotesOff() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.voices {
+		s.voices[i].releasing = true
+	}
+}
+
+// Render fills buf with the next samples, mixing every sounding voice.
+func (s *Synth) Render(buf []float32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range buf {
+		var sample float64
+		for j := range s.voices {
+			v := &s.voices[j]
+			if v.releasing {
+				v.level = max(v.level-s.releaseStep, 0)
+			} else {
+				v.level = min(v.level+s.attackStep, 1)
+			}
+			sample += oscillate(s.options.Waveform, v.phase) * v.gain * v.level
+			v.phase += v.step
+			v.phase -= math.Floor(v.phase)
+		}
+		buf[i] = float32(max(min(sample, 1), -1))
+	}
+
+	// Drop voices that finished their release.
+	active := s.voices[:0]
+	for _, v := range s.voices {
+		if !v.releasing || v.level > 0 {
+			active = append(active, v)
+		}
+	}
+	s.voices = active
+}
+
+// Read renders samples as little-endian 32-bit floats, the format audio libraries such as
+// oto expect. It never ends: silence is rendered when no note sounds.
+func (s *Synth) Read(p []byte) (int, error) {
+	samples := make([]float32, len(p)/4)
+	s.Render(samples)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint32(p[i*4:], math.Float32bits(sample))
+	}
+	return len(samples) * 4, nil
+}
+
+// oscillate returns the value of a waveform at a phase between 0 and 1.
+func oscillate(waveform Waveform, phase float64) float64 {
+	switch waveform {
+	case Square:
+		if phase < 0.5 {
+			return 1
+		}
+		return -1
+	case Sawtooth:
+		return 2*phase - 1
+	case Triangle:
+		return 1 - 4*math.Abs(phase-0.5)
+	default:
+		return math.Sin(2 * math.Pi * phase)
+	}
+}
@@ -0,0 +1,78 @@
+package smf
+
+import "time"
+
+// Defaults of the conversion of captured events.
+const (
+	DefaultDivision = 480 // Ticks per quarter note.
+	DefaultTempo    = 120 // Quarter notes per minute.
+)
+
+// Options holds the configuration of the conversion of captured events to a File.
+type Options struct {
+	Division      uint16        // Ticks per quarter note.
+	Tempo         float64       // Quarter notes per minute, written at the start of the file.
+	TimestampUnit time.Duration // Duration of one unit of the event timestamps.
+	Name          string        // Name of the first track, typically the title of the session.
+	Origin        *uint64       // Timestamp of the start of the file; the first event by default.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithDivision sets the number of ticks per quarter note, the resolution of the file.
+func WithDivision(ticks uint16) Option {
+	return func(opts *Options) {
+		opts.Division = ticks
+	}
+}
+
+// WithTempo sets the tempo written to the file. Events keep their timing whatever the
+// tempo; it only sets where beats and bars fall in a DAW.
+func WithTempo(bpm float64) Option {
+	return func(opts *Options) {
+		opts.Tempo = bpm
+	}
+}
+
+// WithTimestampUnit sets the duration of one unit of the event timestamps, which is a
+// nanosecond for the events of the native backends.
+func WithTimestampUnit(unit time.Duration) Option {
+	return func(opts *Options) {
+		opts.TimestampUnit = unit
+	}
+}
+
+// WithName names the first track, typically after the session.
+func WithName(name string) Option {
+	return func(opts *Options) {
+		opts.Name = name
+	}
+}
+
+// WithOrigin sets the timestamp the file starts at, such as the start of a recording,
+// instead of the first event, keeping the silence before it.
+func WithOrigin(timestamp uint64) Option {
+	return func(opts *Options) {
+		opts.Origin = &timestamp
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Division == 0 || options.Division >= 0x8000 {
+		options.Division = DefaultDivision // The high bit selects SMPTE timing, which is not supported
+	}
+	if options.Tempo <= 0 {
+		options.Tempo = DefaultTempo
+	}
+	if options.TimestampUnit <= 0 {
+		options.TimestampUnit = time.Nanosecond
+	}
+	return options
+}
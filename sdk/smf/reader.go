@@ -0,0 +1,161 @@
+package smf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+)
+
+// ErrInvalidFile is wrapped by the errors of Read for data that is not a valid Standard
+// MIDI File.
+var ErrInvalidFile = errors.New("smf: invalid file")
+
+// Read reads a Standard MIDI File. Chunks of unknown types are skipped, and messages using
+// running status are stored with their status byte.
+//
+// r io.Reader: The source, typically a .mid file.
+//
+// Returns:
+//   - *File: The file.
+//   - error: An error wrapping ErrInvalidFile, or the error of r, if any.
+func Read(r io.Reader) (*File, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var file *File
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("%w: truncated chunk header", ErrInvalidFile)
+		}
+		kind, size := string(data[:4]), binary.BigEndian.Uint32(data[4:8])
+		if uint64(size) > uint64(len(data)-8) {
+			return nil, fmt.Errorf("%w: %s chunk of %d bytes truncated", ErrInvalidFile, kind, size)
+		}
+		chunk := data[8 : 8+size]
+		data = data[8+size:]
+
+		switch {
+		case file == nil && kind != "MThd":
+			return nil, fmt.Errorf("%w: missing MThd header", ErrInvalidFile)
+		case kind == "MThd":
+			if file != nil || len(chunk) < 6 {
+				return nil, fmt.Errorf("%w: invalid MThd header", ErrInvalidFile)
+			}
+			file = &File{
+				Format:   Format(binary.BigEndian.Uint16(chunk)),
+				Division: binary.BigEndian.Uint16(chunk[4:]),
+			}
+		case kind == "MTrk":
+			track, err := readTrack(chunk)
+			if err != nil {
+				return nil, fmt.Errorf("%w: track %d: %v", ErrInvalidFile, len(file.Tracks)+1, err)
+			}
+			file.Tracks = append(file.Tracks, track)
+		}
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%w: empty file", ErrInvalidFile)
+	}
+	return file, nil
+}
+
+// ReadFile reads the Standard MIDI File named name, such as a recording to replay with
+// the replay backend from the result of File.Events.
+//
+// name string: The path of the file.
+//
+// Returns:
+//   - *File: The file.
+//   - error: An error wrapping ErrInvalidFile, or the error opening or reading the file, if any.
+func ReadFile(name string) (*File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// readTrack decodes the events of a track chunk. The track name is moved to Name and the
+// end of track event is dropped, as WriteTo restores them.
+func readTrack(chunk []byte) (Track, error) {
+	var track Track
+	var tick uint64
+	var status byte // Running status; zero after SysEx and meta events.
+	for len(chunk) > 0 {
+		delta, n := readVarint(chunk)
+		if n == 0 {
+			return Track{}, errors.New("truncated delta time")
+		}
+		chunk = chunk[n:]
+		tick += delta
+		if len(chunk) == 0 {
+			return Track{}, errors.New("missing event after delta time")
+		}
+
+		var event []byte
+		switch b := chunk[0]; {
+		case b == 0xFF:
+			if len(chunk) < 2 {
+				return Track{}, errors.New("truncated meta event")
+			}
+			size, n := readVarint(chunk[2:])
+			if n == 0 || size > uint64(len(chunk)-2-n) {
+				return Track{}, fmt.Errorf("truncated meta event 0x%02X", chunk[1])
+			}
+			event = append([]byte{0xFF, chunk[1]}, chunk[2+n:2+n+int(size)]...)
+			chunk = chunk[2+n+int(size):]
+			status = 0
+		case b == 0xF0 || b == 0xF7:
+			size, n := readVarint(chunk[1:])
+			if n == 0 || size > uint64(len(chunk)-1-n) {
+				return Track{}, errors.New("truncated SysEx event")
+			}
+			event = append([]byte{b}, chunk[1+n:1+n+int(size)]...)
+			chunk = chunk[1+n+int(size):]
+			status = 0
+		default:
+			if b >= 0x80 {
+				status = b
+				chunk = chunk[1:]
+			} else if status == 0 {
+				return Track{}, fmt.Errorf("data byte 0x%02X without status", b)
+			}
+			length := midistream.DataLength(status)
+			if len(chunk) < length {
+				return Track{}, fmt.Errorf("message 0x%02X truncated", status)
+			}
+			event = append([]byte{status}, chunk[:length]...)
+			chunk = chunk[length:]
+		}
+
+		switch {
+		case isMeta(Event{Data: event}, MetaEndOfTrack):
+			return track, nil
+		case isMeta(Event{Data: event}, MetaTrackName) && tick == 0 && track.Name == "":
+			track.Name = string(event[2:])
+		default:
+			track.Events = append(track.Events, Event{Tick: tick, Data: event})
+		}
+	}
+	return track, nil
+}
+
+// readVarint decodes a variable-length quantity, returning it and its length in bytes, or
+// a length of zero if it is truncated or longer than 8 bytes.
+func readVarint(data []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(data) && i < 8; i++ {
+		v = v<<7 | uint64(data[i]&0x7F)
+		if data[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,147 @@
+// Package smf reads and writes Standard MIDI Files, so captured sessions open in DAWs and
+// sequencers, and files can be analyzed or replayed like captured events. Recordings are
+// saved as a single track, or split into one track per channel, keeping sources apart for
+// editing instead of flattening everything into a single track.
+package smf
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Format is the layout of the tracks of a file.
+type Format uint16
+
+const (
+	// SingleTrack files hold everything in one track.
+	SingleTrack Format = 0
+	// MultiTrack files hold simultaneous tracks; the first one carries the tempo.
+	MultiTrack Format = 1
+)
+
+// Meta event types.
+const (
+	MetaSequenceNumber    byte = 0x00 // Number of the sequence, in two bytes.
+	MetaText              byte = 0x01 // Free text, such as comments.
+	MetaCopyright         byte = 0x02 // Copyright notice.
+	MetaTrackName         byte = 0x03 // Name of the track.
+	MetaInstrumentName    byte = 0x04 // Name of the instrument playing the track.
+	MetaLyric             byte = 0x05 // Lyric, usually one syllable.
+	MetaMarker            byte = 0x06 // Marker, such as a rehearsal letter or "take 3".
+	MetaCuePoint          byte = 0x07 // Cue point, such as a stage direction.
+	MetaChannelPrefix     byte = 0x20 // Channel the following meta and SysEx events refer to.
+	MetaEndOfTrack        byte = 0x2F // End of the track, written by File.WriteTo.
+	MetaTempo             byte = 0x51 // Tempo, in microseconds per quarter note.
+	MetaSMPTEOffset       byte = 0x54 // SMPTE time the track starts at.
+	MetaTimeSignature     byte = 0x58 // Time signature, with the metronome rate.
+	MetaKeySignature      byte = 0x59 // Key signature, in sharps or flats and mode.
+	MetaSequencerSpecific byte = 0x7F // Data of a specific sequencer.
+)
+
+// File is a Standard MIDI File.
+type File struct {
+	Format   Format  // Layout of the tracks.
+	Division uint16  // Ticks per quarter note.
+	Tracks   []Track // Tracks, written in order.
+}
+
+// Track is a track of a File.
+type Track struct {
+	Name   string  // Name of the track, written as a track name meta event if not empty.
+	Events []Event // Events, ordered by tick.
+}
+
+// Event is an event of a Track. Data holds a MIDI message; a SysEx message, 0xF0 or 0xF7
+// followed by its bytes; or a meta event, 0xFF followed by its type and its data. SysEx
+// and meta events are stored without the length that precedes their bytes in files.
+type Event struct {
+	Tick uint64 // Time from the start of the track, in ticks.
+	Data []byte // Bytes of the event.
+}
+
+// Meta returns a meta event.
+func Meta(tick uint64, kind byte, data ...byte) Event {
+	return Event{Tick: tick, Data: append([]byte{0xFF, kind}, data...)}
+}
+
+// Marker returns a marker meta event labeled text.
+func Marker(tick uint64, text string) Event {
+	return Meta(tick, MetaMarker, []byte(text)...)
+}
+
+// Text returns a text meta event of the kind, such as MetaText, MetaCopyright or
+// MetaLyric.
+func Text(tick uint64, kind byte, text string) Event {
+	return Meta(tick, kind, []byte(text)...)
+}
+
+// TimeSignature returns a time signature meta event of numerator beats of 1/denominator
+// notes, such as 6/8, with a metronome click every beat. The denominator is rounded down
+// to a power of two.
+func TimeSignature(tick uint64, numerator, denominator uint8) Event {
+	power := byte(0)
+	for d := denominator; d > 1; d >>= 1 {
+		power++
+	}
+	click := byte(96 >> power) // MIDI clocks per beat, 24 per quarter note.
+	if numerator%3 == 0 && numerator > 3 && power >= 3 {
+		click *= 3 // Compound meters, such as 6/8, click every dotted beat.
+	}
+	return Meta(tick, MetaTimeSignature, numerator, power, max(click, 1), 8)
+}
+
+// KeySignature returns a key signature meta event with sharps sharps, or flats if
+// negative, from -7 to 7, in a minor key if minor is set.
+func KeySignature(tick uint64, sharps int8, minor bool) Event {
+	mode := byte(0)
+	if minor {
+		mode = 1
+	}
+	return Meta(tick, MetaKeySignature, byte(sharps), mode)
+}
+
+// MetaType returns the type of a meta event, or false if the event is not a meta event.
+func (e Event) MetaType() (byte, bool) {
+	if len(e.Data) < 2 || e.Data[0] != 0xFF {
+		return 0, false
+	}
+	return e.Data[1], true
+}
+
+// MetaData returns the data of a meta event after its type, or nil if the event is not a
+// meta event.
+func (e Event) MetaData() []byte {
+	if _, ok := e.MetaType(); !ok {
+		return nil
+	}
+	return e.Data[2:]
+}
+
+// Tempo returns the tempo set by a tempo meta event, in quarter notes per minute, or
+// false if the event is not one.
+func (e Event) Tempo() (float64, bool) {
+	if !isMeta(e, MetaTempo) || len(e.Data) != 5 || e.Data[2]|e.Data[3]|e.Data[4] == 0 {
+		return 0, false
+	}
+	return 60e6 / float64(uint32(e.Data[2])<<16|uint32(e.Data[3])<<8|uint32(e.Data[4])), true
+}
+
+// Tempo returns a tempo meta event setting bpm quarter notes per minute.
+func Tempo(tick uint64, bpm float64) Event {
+	var data [4]byte
+	binary.BigEndian.PutUint32(data[:], uint32(60e6/bpm))
+	return Meta(tick, MetaTempo, data[1:]...)
+}
+
+// Ticks converts a duration to ticks, rounded to the nearest one.
+//
+// d time.Duration: The duration to convert.
+// division uint16: Ticks per quarter note.
+// bpm float64: Tempo in quarter notes per minute.
+//
+// Returns:
+//   - uint64: The number of ticks lasting d.
+func Ticks(d time.Duration, division uint16, bpm float64) uint64 {
+	return uint64(math.Round(d.Minutes() * bpm * float64(division)))
+}
@@ -0,0 +1,140 @@
+package smf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// ErrStreamClosed is returned when writing to a Stream that has already been closed.
+var ErrStreamClosed = errors.New("smf: stream closed")
+
+// streamBufferSize is the number of bytes a Stream buffers before writing them out.
+const streamBufferSize = 4096
+
+// Stream writes a SingleTrack file event by event, so recordings too long to be held in
+// memory can be written as they happen. The length of the track is only known at the end,
+// so the file is complete once the stream is closed. It is not safe for concurrent use.
+type Stream struct {
+	w       io.WriteSeeker
+	options Options
+	offset  int64 // Offset of the length of the track chunk in w.
+	length  int64 // Bytes of the track written so far, including buffered ones.
+	tick    uint64
+	buf     bytes.Buffer
+	closed  bool
+}
+
+// NewStream writes the header of a SingleTrack file to w, at its current offset, with the
+// tempo and track name of the options, and returns a stream writing its events. Times are
+// converted as by NewMultiTrack.
+//
+// w io.WriteSeeker: The destination, typically a .mid file.
+// opts ...Option: A variadic list of option functions to customize the conversion.
+//
+// Returns:
+//   - *Stream: The stream, ready to receive events.
+//   - error: The error of w, if any.
+func NewStream(w io.WriteSeeker, opts ...Option) (*Stream, error) {
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	s := &Stream{w: w, options: applyDefaultOptions(opts...), offset: start + 18}
+
+	header := [22]byte{'M', 'T', 'h', 'd', 0, 0, 0, 6}
+	binary.BigEndian.PutUint16(header[8:], uint16(SingleTrack))
+	binary.BigEndian.PutUint16(header[10:], 1)
+	binary.BigEndian.PutUint16(header[12:], s.options.Division)
+	copy(header[14:], "MTrk") // The length is written by Close
+	s.buf.Write(header[:])
+
+	if s.options.Name != "" {
+		s.encode(Meta(0, MetaTrackName, []byte(s.options.Name)...))
+	}
+	s.encode(Tempo(0, s.options.Tempo))
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write converts a captured event and writes it. Events that cannot be stored in a file,
+// such as system reset messages, are skipped.
+func (s *Stream) Write(event contracts.MIDI) error {
+	data, ok := message(event)
+	if !ok {
+		return nil
+	}
+	if s.options.Origin == nil {
+		origin := event.Timestamp
+		s.options.Origin = &origin
+	}
+	at := time.Duration(max(event.Timestamp, *s.options.Origin)-*s.options.Origin) * s.options.TimestampUnit
+	return s.WriteEvent(Event{Tick: Ticks(at, s.options.Division, s.options.Tempo), Data: data})
+}
+
+// WriteEvent writes an event of the file, such as a marker. Events before the last one
+// written are moved to its tick, as a track cannot go back in time.
+func (s *Stream) WriteEvent(event Event) error {
+	if s.closed {
+		return ErrStreamClosed
+	}
+	s.encode(event)
+	if s.buf.Len() >= streamBufferSize {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Len returns the number of bytes of the file written so far, including buffered ones.
+func (s *Stream) Len() int64 {
+	return s.offset + 4 + s.length
+}
+
+// Flush writes the buffered events to w. The file is not valid until the stream is closed.
+func (s *Stream) Flush() error {
+	if s.buf.Len() == 0 {
+		return nil
+	}
+	_, err := s.w.Write(s.buf.Bytes())
+	s.buf.Reset()
+	return err
+}
+
+// Close writes an end of track event and the length of the track, leaving w at the end of
+// the file. It does not close w.
+func (s *Stream) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.encode(Meta(s.tick, MetaEndOfTrack))
+	s.closed = true
+	if err := s.Flush(); err != nil {
+		return err
+	}
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(s.length))
+	if _, err := s.w.Seek(s.offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := s.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// encode buffers an event with its delta time, counting the bytes of the track.
+func (s *Stream) encode(event Event) {
+	before := s.buf.Len()
+	tick := max(event.Tick, s.tick)
+	writeEvent(&s.buf, tick-s.tick, event.Data)
+	s.tick = tick
+	s.length += int64(s.buf.Len() - before)
+}
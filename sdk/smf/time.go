@@ -0,0 +1,124 @@
+package smf
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// defaultTempo is the tempo of files until their first tempo event: 120 quarter notes per
+// minute, in microseconds per quarter note.
+const defaultTempo = 500000
+
+// tempoChange is an entry of the tempo map of a file.
+type tempoChange struct {
+	tick  uint64
+	tempo uint64        // Microseconds per quarter note from tick on.
+	at    time.Duration // Time of tick.
+}
+
+// tempoMap returns the tempo changes of every track, ordered by tick, starting with the
+// default tempo at tick zero.
+func (f *File) tempoMap() []tempoChange {
+	changes := []tempoChange{{tempo: defaultTempo}}
+	for _, track := range f.Tracks {
+		for _, event := range track.Events {
+			if isMeta(event, MetaTempo) && len(event.Data) == 5 {
+				tempo := uint64(event.Data[2])<<16 | uint64(event.Data[3])<<8 | uint64(event.Data[4])
+				changes = append(changes, tempoChange{tick: event.Tick, tempo: max(tempo, 1)})
+			}
+		}
+	}
+	slices.SortStableFunc(changes, func(x, y tempoChange) int { return cmp.Compare(x.tick, y.tick) })
+	for i := 1; i < len(changes); i++ {
+		changes[i].at = changes[i-1].at + f.duration(changes[i].tick-changes[i-1].tick, changes[i-1].tempo)
+	}
+	return changes
+}
+
+// duration returns the duration of ticks at tempo, in microseconds per quarter note. With
+// SMPTE divisions ticks have a fixed duration and the tempo is ignored.
+func (f *File) duration(ticks, tempo uint64) time.Duration {
+	if f.Division&0x8000 != 0 {
+		fps, resolution := uint64(-int8(f.Division>>8)), uint64(f.Division&0xFF)
+		if fps == 29 {
+			return time.Duration(ticks * uint64(time.Second) * 100 / (2997 * resolution))
+		}
+		return time.Duration(ticks * uint64(time.Second) / max(fps*resolution, 1))
+	}
+	return time.Duration(ticks * tempo * uint64(time.Microsecond) / max(uint64(f.Division), 1))
+}
+
+// TempoChange is a change of tempo of a file.
+type TempoChange struct {
+	Tick uint64        // Tick the tempo starts at.
+	BPM  float64       // Tempo, in quarter notes per minute.
+	Time time.Duration // Time of Tick from the start of the file.
+}
+
+// TempoMap returns the tempo changes of every track, ordered by tick, starting with the
+// default tempo of 120 quarter notes per minute at tick zero when the file does not set
+// a tempo there.
+func (f *File) TempoMap() []TempoChange {
+	changes := f.tempoMap()
+	tempoMap := make([]TempoChange, 0, len(changes))
+	for i, change := range changes {
+		if i+1 < len(changes) && changes[i+1].tick == change.tick {
+			continue // Superseded by a change at the same tick
+		}
+		tempoMap = append(tempoMap, TempoChange{Tick: change.tick, BPM: 60e6 / float64(change.tempo), Time: change.at})
+	}
+	return tempoMap
+}
+
+// Time returns the time of tick from the start of the file, following the tempo changes
+// of every track.
+func (f *File) Time(tick uint64) time.Duration {
+	return f.timeOf(f.tempoMap(), tick)
+}
+
+// timeOf returns the time of tick with the tempo changes.
+func (f *File) timeOf(changes []tempoChange, tick uint64) time.Duration {
+	i, _ := slices.BinarySearchFunc(changes, tick, func(change tempoChange, tick uint64) int {
+		return cmp.Compare(change.tick, tick+1)
+	})
+	change := changes[max(i-1, 0)]
+	return change.at + f.duration(tick-change.tick, change.tempo)
+}
+
+// Events returns the MIDI messages of every track, merged in time order, with timestamps
+// in nanoseconds from the start of the file, so files can be analyzed and replayed like
+// captured events. SysEx and meta events are left out.
+func (f *File) Events() []contracts.MIDI {
+	type timed struct {
+		tick  uint64
+		event contracts.MIDI
+	}
+	var merged []timed
+	for _, track := range f.Tracks {
+		for _, event := range track.Events {
+			if len(event.Data) == 0 || event.Data[0] < 0x80 || event.Data[0] == 0xF0 || event.Data[0] == 0xF7 || event.Data[0] == 0xFF {
+				continue
+			}
+			message := contracts.MIDI{Command: event.Data[0]}
+			if len(event.Data) > 1 {
+				message.Note = event.Data[1]
+			}
+			if len(event.Data) > 2 {
+				message.Velocity = event.Data[2]
+			}
+			merged = append(merged, timed{tick: event.Tick, event: message})
+		}
+	}
+	slices.SortStableFunc(merged, func(x, y timed) int { return cmp.Compare(x.tick, y.tick) })
+
+	changes := f.tempoMap()
+	events := make([]contracts.MIDI, len(merged))
+	for i, m := range merged {
+		events[i] = m.event
+		events[i].Timestamp = uint64(f.timeOf(changes, m.tick))
+	}
+	return events
+}
@@ -0,0 +1,138 @@
+package smf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/leandrodaf/midi/internal/midi/midistream"
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Split names the track an event belongs to. Events with the same name share a track;
+// events named "" go to the first track, which also holds the tempo.
+type Split func(event contracts.MIDI) string
+
+// ByChannel puts the messages of each channel on a track of their own, named "Channel 1"
+// to "Channel 16", and system messages on the first track.
+func ByChannel(event contracts.MIDI) string {
+	if event.Command >= 0xF0 {
+		return ""
+	}
+	return fmt.Sprintf("Channel %d", event.Command&0x0F+1)
+}
+
+// ByDevice returns a split putting the messages of each source device, as told by
+// contracts.MIDI.SourceDeviceID, on a track of their own, so the parts of several
+// controllers captured together, e.g. with midi.NewInputGroup, stay apart. Tracks are
+// named after devices, as listed by ListDevices, or "Device 1", "Device 2"... for IDs
+// beyond them.
+//
+// devices []contracts.DeviceInfo: The devices the events were captured from, for the track names; may be nil.
+//
+// Returns:
+//   - Split: The split, for NewMultiTrack.
+func ByDevice(devices []contracts.DeviceInfo) Split {
+	return func(event contracts.MIDI) string {
+		return deviceName(devices, event.SourceDeviceID)
+	}
+}
+
+// ByDeviceAndChannel returns a split putting the messages of each channel of each source
+// device on a track of their own, named like "KeyStep Channel 1", and the system messages
+// of each device on a track named after the device, as by ByDevice.
+//
+// devices []contracts.DeviceInfo: The devices the events were captured from, for the track names; may be nil.
+//
+// Returns:
+//   - Split: The split, for NewMultiTrack.
+func ByDeviceAndChannel(devices []contracts.DeviceInfo) Split {
+	return func(event contracts.MIDI) string {
+		name := deviceName(devices, event.SourceDeviceID)
+		if channel := ByChannel(event); channel != "" {
+			return name + " " + channel
+		}
+		return name
+	}
+}
+
+// deviceName returns the name of the device with the given ID in devices, or
+// "Device <ID + 1>" when it is not listed or unnamed.
+func deviceName(devices []contracts.DeviceInfo, id int) string {
+	if id >= 0 && id < len(devices) && devices[id].Name != "" {
+		return devices[id].Name
+	}
+	return fmt.Sprintf("Device %d", id+1)
+}
+
+// NewSingleTrack converts captured events to a SingleTrack file, the format read by the
+// most software. Times are converted as by NewMultiTrack.
+//
+// events []contracts.MIDI: The captured events, in order.
+// opts ...Option: A variadic list of option functions to customize the conversion.
+//
+// Returns:
+//   - *File: The file, ready to be written with WriteTo.
+func NewSingleTrack(events []contracts.MIDI, opts ...Option) *File {
+	file := NewMultiTrack(events, func(contracts.MIDI) string { return "" }, opts...)
+	file.Format = SingleTrack
+	return file
+}
+
+// NewMultiTrack converts captured events to a MultiTrack file, split into tracks by split
+// and ordered by the first event of each track, after the first track. Times are measured
+// from the first event, or the origin of the options, and converted to ticks with the tempo of the options. Events that
+// cannot be stored in a file, such as system reset messages, are skipped.
+//
+// events []contracts.MIDI: The captured events, in order.
+// split Split: The track of each event, such as ByChannel or ByDevice(devices).
+// opts ...Option: A variadic list of option functions to customize the conversion.
+//
+// Returns:
+//   - *File: The file, ready to be written with WriteTo.
+func NewMultiTrack(events []contracts.MIDI, split Split, opts ...Option) *File {
+	options := applyDefaultOptions(opts...)
+	file := &File{
+		Format:   MultiTrack,
+		Division: options.Division,
+		Tracks:   []Track{{Name: options.Name, Events: []Event{Tempo(0, options.Tempo)}}},
+	}
+	if len(events) == 0 {
+		return file
+	}
+
+	origin := events[0].Timestamp
+	if options.Origin != nil {
+		origin = *options.Origin
+	}
+	tracks := map[string]int{"": 0}
+	for _, event := range events {
+		data, ok := message(event)
+		if !ok {
+			continue
+		}
+		name := split(event)
+		i, found := tracks[name]
+		if !found {
+			i = len(file.Tracks)
+			tracks[name] = i
+			file.Tracks = append(file.Tracks, Track{Name: name})
+		}
+		at := time.Duration(max(event.Timestamp, origin)-origin) * options.TimestampUnit
+		file.Tracks[i].Events = append(file.Tracks[i].Events, Event{
+			Tick: Ticks(at, options.Division, options.Tempo),
+			Data: data,
+		})
+	}
+	return file
+}
+
+// message returns the bytes of a captured event, or false if it cannot be stored in a
+// file: 0xFF is the status of meta events in files, and SysEx is captured apart.
+func message(event contracts.MIDI) ([]byte, bool) {
+	switch {
+	case event.Command < 0x80, event.Command == 0xF0, event.Command == 0xF7, event.Command == 0xFF:
+		return nil, false
+	}
+	data := []byte{event.Command, event.Note & 0x7F, event.Velocity & 0x7F}
+	return data[:1+midistream.DataLength(event.Command)], true
+}
@@ -0,0 +1,135 @@
+package smf
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"io"
+	"os"
+	"slices"
+)
+
+// WriteTo writes the file to w. The events of each track are sorted by tick, keeping the
+// order of events with the same tick, and an end of track event is added after the last
+// one unless the track already ends with one.
+//
+// w io.Writer: The destination, typically a .mid file.
+//
+// Returns:
+//   - int64: The number of bytes written.
+//   - error: The error of w, if any.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	b := bufio.NewWriter(counter)
+
+	header := [14]byte{'M', 'T', 'h', 'd', 0, 0, 0, 6}
+	binary.BigEndian.PutUint16(header[8:], uint16(f.Format))
+	binary.BigEndian.PutUint16(header[10:], uint16(len(f.Tracks)))
+	binary.BigEndian.PutUint16(header[12:], f.Division)
+	b.Write(header[:])
+
+	var chunk bytes.Buffer
+	for _, track := range f.Tracks {
+		chunk.Reset()
+		track.encode(&chunk)
+		var header [8]byte
+		copy(header[:], "MTrk")
+		binary.BigEndian.PutUint32(header[4:], uint32(chunk.Len()))
+		b.Write(header[:])
+		b.Write(chunk.Bytes())
+	}
+
+	err := b.Flush()
+	return counter.n, err
+}
+
+// WriteFile writes the file to the file named name, creating or truncating it.
+//
+// name string: The path of the file, typically ending with .mid.
+//
+// Returns:
+//   - error: The error creating or writing the file, if any.
+func (f *File) WriteFile(name string) error {
+	out, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteTo(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// encode writes the events of the track, without the chunk header.
+func (t Track) encode(chunk *bytes.Buffer) {
+	events := slices.Clone(t.Events)
+	slices.SortStableFunc(events, func(x, y Event) int {
+		return cmp.Compare(x.Tick, y.Tick)
+	})
+	if t.Name != "" {
+		events = slices.Insert(events, 0, Meta(0, MetaTrackName, []byte(t.Name)...))
+	}
+	if n := len(events); n == 0 || !isMeta(events[n-1], MetaEndOfTrack) {
+		var end uint64
+		if n > 0 {
+			end = events[n-1].Tick
+		}
+		events = append(events, Meta(end, MetaEndOfTrack))
+	}
+
+	var tick uint64
+	for _, event := range events {
+		writeEvent(chunk, event.Tick-tick, event.Data)
+		tick = event.Tick
+	}
+}
+
+// writeEvent writes an event of a track after its delta time, preceding the bytes of SysEx
+// and meta events with their length.
+func writeEvent(chunk *bytes.Buffer, delta uint64, data []byte) {
+	writeVarint(chunk, delta)
+	switch {
+	case len(data) >= 2 && data[0] == 0xFF:
+		chunk.Write(data[:2])
+		writeVarint(chunk, uint64(len(data)-2))
+		chunk.Write(data[2:])
+	case len(data) >= 1 && (data[0] == 0xF0 || data[0] == 0xF7):
+		chunk.WriteByte(data[0])
+		writeVarint(chunk, uint64(len(data)-1))
+		chunk.Write(data[1:])
+	default:
+		chunk.Write(data)
+	}
+}
+
+// isMeta reports whether event is a meta event of the kind.
+func isMeta(event Event, kind byte) bool {
+	return len(event.Data) >= 2 && event.Data[0] == 0xFF && event.Data[1] == kind
+}
+
+// writeVarint writes v as a variable-length quantity: 7 bits per byte, most significant
+// first, with the high bit set on every byte but the last.
+func writeVarint(b *bytes.Buffer, v uint64) {
+	var buf [10]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7F)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7F) | 0x80
+	}
+	b.Write(buf[i:])
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,73 @@
+package grid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// push2ReapplyPalette makes Push 2 redraw its LEDs with the reprogrammed palette.
+var push2ReapplyPalette = []byte{0xF0, 0x00, 0x21, 0x1D, 0x01, 0x01, 0x05, 0xF7}
+
+// push2PaletteEntry encodes a Push 2 palette entry: each component, and the white LED
+// level, as its low 7 bits followed by its top bit.
+func push2PaletteEntry(index byte, c Color) []byte {
+	white := byte((int(c.R) + int(c.G) + int(c.B)) / 3)
+	return []byte{0xF0, 0x00, 0x21, 0x1D, 0x01, 0x01, 0x03, index,
+		c.R & 0x7F, c.R >> 7, c.G & 0x7F, c.G >> 7, c.B & 0x7F, c.B >> 7, white & 0x7F, white >> 7, 0xF7}
+}
+
+// SetDisplaySegment writes a segment of a line of the model's text display, padding or
+// truncating text to the segment width. Characters outside printable ASCII are shown as '?'.
+func (g *Grid) SetDisplaySegment(line, segment int, text string) error {
+	d := g.profile.Display
+	if d.Lines == 0 {
+		return fmt.Errorf("%s has no text display", g.profile.Name)
+	}
+	if line < 0 || line >= d.Lines || segment < 0 || segment >= d.Segments {
+		return fmt.Errorf("invalid display segment %d of line %d", segment, line)
+	}
+	return g.sender.SendSysEx(pushDisplayLine(line, segment*d.Width, fit(text, d.Width)))
+}
+
+// SetDisplayLine writes a whole line of the model's text display.
+func (g *Grid) SetDisplayLine(line int, text string) error {
+	d := g.profile.Display
+	if d.Lines == 0 {
+		return fmt.Errorf("%s has no text display", g.profile.Name)
+	}
+	if line < 0 || line >= d.Lines {
+		return fmt.Errorf("invalid display line %d", line)
+	}
+	return g.sender.SendSysEx(pushDisplayLine(line, 0, fit(text, d.Segments*d.Width)))
+}
+
+// ClearDisplay blanks every line of the model's text display.
+func (g *Grid) ClearDisplay() error {
+	for line := 0; line < g.profile.Display.Lines; line++ {
+		if err := g.SetDisplayLine(line, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushDisplayLine encodes a write of text at a character offset of a Push display line.
+func pushDisplayLine(line, offset int, text string) []byte {
+	data := []byte{0xF0, 0x47, 0x7F, 0x15, byte(0x18 + line), 0x00, byte(len(text) + 1), byte(offset)}
+	for _, r := range text {
+		if r < 0x20 || r > 0x7E {
+			r = '?'
+		}
+		data = append(data, byte(r))
+	}
+	return append(data, 0xF7)
+}
+
+// fit pads or truncates text to width characters.
+func fit(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
@@ -0,0 +1,174 @@
+// Package grid drives pad-grid controllers such as the Novation Launchpad family, the
+// Akai APC family and Ableton Push: it maps pad coordinates to the notes of each model,
+// decodes pad presses, lights pads with the messages each model expects (RGB SysEx or
+// the nearest entry of its velocity palette) and writes to text displays.
+package grid
+
+import (
+	"fmt"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Sender sends messages to the controller, such as a contracts.ClientMIDI with the
+// controller selected as output device.
+type Sender interface {
+	Send(event contracts.MIDI) error // Sends a channel message.
+	SendSysEx(data []byte) error     // Sends a complete SysEx message, including F0 and F7.
+}
+
+// Color is the color of a pad LED. Models with fewer color levels use the most significant bits.
+type Color struct {
+	R, G, B uint8
+}
+
+// Common colors.
+var (
+	Off    = Color{}
+	Red    = Color{R: 255}
+	Green  = Color{G: 255}
+	Blue   = Color{B: 255}
+	Yellow = Color{R: 255, G: 255}
+	Amber  = Color{R: 255, G: 128}
+	White  = Color{R: 255, G: 255, B: 255}
+)
+
+// PadEvent is a pad press or release.
+type PadEvent struct {
+	Row      int  // Row of the pad, 0 at the top.
+	Column   int  // Column of the pad, 0 at the left.
+	Pressed  bool // Whether the pad went down.
+	Velocity byte // Velocity of the press, on velocity-sensitive models.
+}
+
+// Grid is a pad-grid controller of a known model.
+type Grid struct {
+	sender  Sender
+	profile Profile
+	palette []Color // Current palette of colorPalette models, updated by SetPaletteEntry.
+}
+
+// New creates a grid for a controller model.
+//
+// sender Sender: The output connected to the controller.
+// profile Profile: The model of the controller, e.g. LaunchpadX.
+//
+// Returns:
+//   - *Grid: The grid, ready to light pads.
+func New(sender Sender, profile Profile) *Grid {
+	return &Grid{sender: sender, profile: profile, palette: append([]Color(nil), profile.palette...)}
+}
+
+// Profile returns the model of the controller.
+func (g *Grid) Profile() Profile {
+	return g.profile
+}
+
+// Init switches the controller to the layout the profile assumes, e.g. the programmer
+// mode of the Launchpad X. Models with a programmable palette are loaded with the
+// palette the grid picks colors from.
+func (g *Grid) Init() error {
+	for _, message := range g.profile.init {
+		var err error
+		if message[0] == 0xF0 {
+			err = g.sender.SendSysEx(message)
+		} else {
+			err = g.sender.Send(contracts.MIDI{Command: message[0], Note: message[1], Velocity: message[2]})
+		}
+		if err != nil {
+			return fmt.Errorf("error initializing %s: %w", g.profile.Name, err)
+		}
+	}
+	if g.profile.paletteSysEx {
+		for index, c := range g.palette {
+			if err := g.sender.SendSysEx(push2PaletteEntry(byte(index), c)); err != nil {
+				return fmt.Errorf("error initializing %s: %w", g.profile.Name, err)
+			}
+		}
+		return g.sender.SendSysEx(push2ReapplyPalette)
+	}
+	return nil
+}
+
+// SetPaletteEntry reprograms a palette entry on models that support it, so that pads can
+// show colors beyond the default palette. Later SetPadColor calls pick from the new palette.
+func (g *Grid) SetPaletteEntry(index byte, c Color) error {
+	if !g.profile.paletteSysEx {
+		return fmt.Errorf("%s has no programmable palette", g.profile.Name)
+	}
+	if index > 127 {
+		return fmt.Errorf("invalid palette index %d", index)
+	}
+	if err := g.sender.SendSysEx(push2PaletteEntry(index, c)); err != nil {
+		return err
+	}
+	if err := g.sender.SendSysEx(push2ReapplyPalette); err != nil {
+		return err
+	}
+	for len(g.palette) <= int(index) {
+		g.palette = append(g.palette, Off)
+	}
+	g.palette[index] = c
+	return nil
+}
+
+// SetPadColor lights the pad at a row and column.
+func (g *Grid) SetPadColor(row, column int, c Color) error {
+	note, ok := g.profile.Note(row, column)
+	if !ok {
+		return fmt.Errorf("pad %d,%d outside the %dx%d grid", row, column, g.profile.Rows, g.profile.Columns)
+	}
+	message, sysex := g.colorMessage(note, c)
+	if sysex != nil {
+		return g.sender.SendSysEx(sysex)
+	}
+	return g.sender.Send(message)
+}
+
+// Clear turns every pad off.
+func (g *Grid) Clear() error {
+	for row := 0; row < g.profile.Rows; row++ {
+		for column := 0; column < g.profile.Columns; column++ {
+			if err := g.SetPadColor(row, column, Off); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Decode converts a captured message into a pad event. It reports false for messages
+// that are not pad presses or releases of the grid.
+func (g *Grid) Decode(event contracts.MIDI) (PadEvent, bool) {
+	status := event.Command & 0xF0
+	if status != 0x90 && status != 0x80 {
+		return PadEvent{}, false
+	}
+	row, column, ok := g.profile.Pad(event.Note)
+	if !ok {
+		return PadEvent{}, false
+	}
+	pressed := status == 0x90 && event.Velocity > 0
+	return PadEvent{Row: row, Column: column, Pressed: pressed, Velocity: event.Velocity}, true
+}
+
+// colorMessage encodes the message lighting the pad sending note. Exactly one of the
+// results is used: a channel message, or SysEx data when it is not nil.
+func (g *Grid) colorMessage(note byte, c Color) (contracts.MIDI, []byte) {
+	p := g.profile
+	switch p.color {
+	case colorRGB6:
+		return contracts.MIDI{}, []byte{0xF0, 0x00, 0x20, 0x29, 0x02, p.sysexID, 0x0B, note, c.R >> 2, c.G >> 2, c.B >> 2, 0xF7}
+	case colorRGB7:
+		return contracts.MIDI{}, []byte{0xF0, 0x00, 0x20, 0x29, 0x02, p.sysexID, 0x03, 0x03, note, c.R >> 1, c.G >> 1, c.B >> 1, 0xF7}
+	case colorAkaiRGB:
+		// Lights the pad range note..note with each component split into its top bit and low 7 bits.
+		return contracts.MIDI{}, []byte{0xF0, 0x47, 0x7F, p.sysexID, 0x24, 0x00, 0x08, note, note,
+			c.R >> 7, c.R & 0x7F, c.G >> 7, c.G & 0x7F, c.B >> 7, c.B & 0x7F, 0xF7}
+	case colorPalette:
+		return contracts.MIDI{Command: 0x90 | p.channel, Note: note, Velocity: nearest(g.palette, c)}, nil
+	default:
+		// Bits 2-3 set the copy and clear flags for normal, double-buffer free operation.
+		return contracts.MIDI{Command: 0x90, Note: note, Velocity: c.G>>6<<4 | 0x0C | c.R>>6}, nil
+	}
+}
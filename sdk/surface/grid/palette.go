@@ -0,0 +1,58 @@
+package grid
+
+// livePalette approximates the first 64 entries of the velocity palette shared by
+// Ableton Live, the Launchpad MK2, the APC40 MKII and Push: black, three greys and
+// white, then groups of four entries (light, full, dim, faint) per hue. It is used to
+// pick the entry nearest to a requested color.
+var livePalette = buildLivePalette()
+
+// paletteHues are the full-intensity colors of the hue groups of livePalette, from index 4.
+var paletteHues = []Color{
+	{R: 255}, {R: 255, G: 84}, {R: 255, G: 255}, {R: 84, G: 255},
+	{G: 255}, {G: 255, B: 25}, {G: 255, B: 85}, {G: 255, B: 153},
+	{G: 169, B: 255}, {G: 85, B: 255}, {B: 255}, {R: 84, B: 255},
+	{R: 255, B: 255}, {R: 255, B: 84},
+}
+
+// buildLivePalette builds livePalette from paletteHues.
+func buildLivePalette() []Color {
+	palette := []Color{{}, {R: 30, G: 30, B: 30}, {R: 127, G: 127, B: 127}, {R: 255, G: 255, B: 255}}
+	for _, hue := range paletteHues {
+		palette = append(palette,
+			mix(hue, White, 0.3),
+			hue,
+			scale(hue, 0.35),
+			scale(hue, 0.1),
+		)
+	}
+	return palette
+}
+
+// apcMiniPalette is the palette of the first APC Mini, whose pads light green, red or yellow.
+var apcMiniPalette = []Color{0: Off, 1: Green, 3: Red, 5: Yellow}
+
+// nearest returns the index of the palette entry closest to c.
+func nearest(palette []Color, c Color) byte {
+	best, bestDistance := 0, -1
+	for i, entry := range palette {
+		if i > 0 && entry == Off {
+			continue // Unused entry of a sparse palette.
+		}
+		dr, dg, db := int(entry.R)-int(c.R), int(entry.G)-int(c.G), int(entry.B)-int(c.B)
+		if distance := dr*dr + dg*dg + db*db; bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return byte(best)
+}
+
+// mix blends a with b by amount, from 0 (a) to 1 (b).
+func mix(a, b Color, amount float64) Color {
+	blend := func(x, y uint8) uint8 { return uint8(float64(x)*(1-amount) + float64(y)*amount) }
+	return Color{R: blend(a.R, b.R), G: blend(a.G, b.G), B: blend(a.B, b.B)}
+}
+
+// scale dims c by factor.
+func scale(c Color, factor float64) Color {
+	return Color{R: uint8(float64(c.R) * factor), G: uint8(float64(c.G) * factor), B: uint8(float64(c.B) * factor)}
+}
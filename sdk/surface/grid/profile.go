@@ -0,0 +1,125 @@
+package grid
+
+// layout identifies how a model numbers its pads.
+type layout int
+
+const (
+	layoutXY      layout = iota // note = row*16 + column, row 0 at the top (original Launchpad, S, Mini).
+	layoutDecimal               // note = row*10 + column, 11 at the bottom left (programmer layout).
+	layoutLinear                // note = base + row*columns + column, counting rows from the bottom.
+)
+
+// colorMode identifies how a model sets pad colors.
+type colorMode int
+
+const (
+	colorRedGreen colorMode = iota // Note velocity with 2-bit red and green brightness.
+	colorRGB6                      // Novation RGB SysEx with 6-bit components.
+	colorRGB7                      // Novation RGB SysEx with 7-bit components.
+	colorPalette                   // Note velocity selecting the nearest palette entry.
+	colorAkaiRGB                   // Akai RGB SysEx with 8-bit components split into two bytes.
+)
+
+// Profile describes a pad-grid controller model: how pads map to notes and how their
+// LEDs are lit.
+type Profile struct {
+	Name    string
+	Rows    int
+	Columns int
+	Display Display // Text display of the model; zero when it has none.
+
+	layout       layout
+	base         byte // First pad note of linear layouts.
+	color        colorMode
+	channel      byte     // MIDI channel of palette messages, which some models use for brightness.
+	palette      []Color  // Velocity palette of colorPalette models.
+	sysexID      byte     // Product ID used in SysEx headers.
+	paletteSysEx bool     // Whether the palette can be reprogrammed via SysEx (Push 2).
+	init         [][]byte // Messages selecting the mode the profile assumes, sent by Grid.Init.
+}
+
+// Display describes the character display of a model, divided into segments above
+// groups of pads or encoders.
+type Display struct {
+	Lines    int // Number of text lines.
+	Segments int // Number of segments per line.
+	Width    int // Characters per segment.
+}
+
+// Profiles of the supported models.
+var (
+	// LaunchpadS covers the original Launchpad, the Launchpad S and the first Launchpad Mini.
+	LaunchpadS = Profile{Name: "Launchpad S", Rows: 8, Columns: 8, layout: layoutXY, color: colorRedGreen,
+		init: [][]byte{{0xB0, 0x00, 0x01}}} // Selects the X-Y layout.
+	LaunchpadMK2 = Profile{Name: "Launchpad MK2", Rows: 8, Columns: 8, layout: layoutDecimal, color: colorRGB6,
+		sysexID: 0x18, init: [][]byte{{0xF0, 0x00, 0x20, 0x29, 0x02, 0x18, 0x22, 0x00, 0xF7}}} // Session layout.
+	LaunchpadX = Profile{Name: "Launchpad X", Rows: 8, Columns: 8, layout: layoutDecimal, color: colorRGB7,
+		sysexID: 0x0C, init: [][]byte{programmerMode(0x0C)}}
+	LaunchpadMiniMK3 = Profile{Name: "Launchpad Mini MK3", Rows: 8, Columns: 8, layout: layoutDecimal, color: colorRGB7,
+		sysexID: 0x0D, init: [][]byte{programmerMode(0x0D)}}
+	LaunchpadProMK3 = Profile{Name: "Launchpad Pro MK3", Rows: 8, Columns: 8, layout: layoutDecimal, color: colorRGB7,
+		sysexID: 0x0E, init: [][]byte{programmerMode(0x0E)}}
+
+	// APCMini is the first Akai APC Mini, with green, red and yellow pads.
+	APCMini = Profile{Name: "APC Mini", Rows: 8, Columns: 8, layout: layoutLinear, color: colorPalette,
+		palette: apcMiniPalette}
+	APCMiniMK2 = Profile{Name: "APC Mini MK2", Rows: 8, Columns: 8, layout: layoutLinear, color: colorAkaiRGB,
+		sysexID: 0x4F}
+	// APC40MK2 covers the 5x8 clip launch grid of the APC40 MKII.
+	APC40MK2 = Profile{Name: "APC40 MKII", Rows: 5, Columns: 8, layout: layoutLinear, color: colorPalette,
+		palette: livePalette, init: [][]byte{{0xF0, 0x47, 0x7F, 0x29, 0x60, 0x00, 0x04, 0x41, 0x00, 0x00, 0x00, 0xF7}}} // Ableton Live mode.
+	Push = Profile{Name: "Push", Rows: 8, Columns: 8, layout: layoutLinear, base: 36, color: colorPalette,
+		palette: livePalette, Display: Display{Lines: 4, Segments: 4, Width: 17}}
+	// Push2 has a graphical display driven over USB, which is outside the scope of MIDI.
+	Push2 = Profile{Name: "Push 2", Rows: 8, Columns: 8, layout: layoutLinear, base: 36, color: colorPalette,
+		palette: livePalette, paletteSysEx: true}
+)
+
+// Profiles lists the supported models.
+var Profiles = []Profile{
+	LaunchpadS, LaunchpadMK2, LaunchpadX, LaunchpadMiniMK3, LaunchpadProMK3,
+	APCMini, APCMiniMK2, APC40MK2, Push, Push2,
+}
+
+// programmerMode returns the SysEx message switching a Launchpad X-family model to its
+// programmer mode.
+func programmerMode(id byte) []byte {
+	return []byte{0xF0, 0x00, 0x20, 0x29, 0x02, id, 0x0E, 0x01, 0xF7}
+}
+
+// Note returns the note of the pad at a row and column, with row 0 at the top.
+// It reports false for coordinates outside the grid.
+func (p Profile) Note(row, column int) (byte, bool) {
+	if row < 0 || row >= p.Rows || column < 0 || column >= p.Columns {
+		return 0, false
+	}
+	switch p.layout {
+	case layoutXY:
+		return byte(row*16 + column), true
+	case layoutLinear:
+		return p.base + byte((p.Rows-1-row)*p.Columns+column), true
+	default:
+		return byte((p.Rows-row)*10 + column + 1), true
+	}
+}
+
+// Pad returns the row and column of the pad sending a note. It reports false for notes
+// that are not grid pads.
+func (p Profile) Pad(note byte) (row, column int, ok bool) {
+	switch p.layout {
+	case layoutXY:
+		row, column = int(note)/16, int(note)%16
+	case layoutLinear:
+		if note < p.base {
+			return 0, 0, false
+		}
+		index := int(note - p.base)
+		row, column = p.Rows-1-index/p.Columns, index%p.Columns
+	default:
+		row, column = p.Rows-int(note)/10, int(note)%10-1
+	}
+	if row < 0 || row >= p.Rows || column < 0 || column >= p.Columns {
+		return 0, 0, false
+	}
+	return row, column, true
+}
@@ -0,0 +1,96 @@
+package mackie
+
+// Button identifies a button of the surface by the note number it sends and lights with.
+type Button byte
+
+// Buttons of the channel strips, indexed by strip (0-7).
+const (
+	buttonRecArm     Button = 0x00
+	buttonSolo       Button = 0x08
+	buttonMute       Button = 0x10
+	buttonSelect     Button = 0x18
+	buttonVPotPush   Button = 0x20
+	buttonFaderTouch Button = 0x68
+)
+
+// Global buttons.
+const (
+	AssignTrack      Button = 0x28
+	AssignSend       Button = 0x29
+	AssignPan        Button = 0x2A
+	AssignPlugin     Button = 0x2B
+	AssignEQ         Button = 0x2C
+	AssignInstrument Button = 0x2D
+	BankLeft         Button = 0x2E
+	BankRight        Button = 0x2F
+	ChannelLeft      Button = 0x30
+	ChannelRight     Button = 0x31
+	Flip             Button = 0x32
+	GlobalView       Button = 0x33
+	NameValue        Button = 0x34
+	SMPTEBeats       Button = 0x35
+	Shift            Button = 0x46
+	OptionKey        Button = 0x47
+	Control          Button = 0x48
+	Alt              Button = 0x49
+	ReadOff          Button = 0x4A
+	Write            Button = 0x4B
+	Trim             Button = 0x4C
+	Touch            Button = 0x4D
+	Latch            Button = 0x4E
+	Group            Button = 0x4F
+	Save             Button = 0x50
+	Undo             Button = 0x51
+	Cancel           Button = 0x52
+	Enter            Button = 0x53
+	Marker           Button = 0x54
+	Nudge            Button = 0x55
+	Cycle            Button = 0x56
+	Drop             Button = 0x57
+	Replace          Button = 0x58
+	Click            Button = 0x59
+	SoloGlobal       Button = 0x5A
+	Rewind           Button = 0x5B
+	FastForward      Button = 0x5C
+	Stop             Button = 0x5D
+	Play             Button = 0x5E
+	Record           Button = 0x5F
+	Up               Button = 0x60
+	Down             Button = 0x61
+	Left             Button = 0x62
+	Right            Button = 0x63
+	Zoom             Button = 0x64
+	Scrub            Button = 0x65
+	MasterTouch      Button = 0x70
+)
+
+// RecArm returns the record arm button of a channel strip.
+func RecArm(strip int) Button { return buttonRecArm + Button(strip&7) }
+
+// Solo returns the solo button of a channel strip.
+func Solo(strip int) Button { return buttonSolo + Button(strip&7) }
+
+// Mute returns the mute button of a channel strip.
+func Mute(strip int) Button { return buttonMute + Button(strip&7) }
+
+// Select returns the select button of a channel strip.
+func Select(strip int) Button { return buttonSelect + Button(strip&7) }
+
+// VPotPush returns the push switch of a channel strip's V-Pot.
+func VPotPush(strip int) Button { return buttonVPotPush + Button(strip&7) }
+
+// FaderTouch returns the touch sensor of a channel strip's fader. MasterTouch is the
+// sensor of the master fader.
+func FaderTouch(strip int) Button { return buttonFaderTouch + Button(strip&7) }
+
+// Strip returns the channel strip of a strip button, or -1 for global buttons.
+func (b Button) Strip() int {
+	switch {
+	case b < AssignTrack:
+		return int(b & 7)
+	case b >= buttonFaderTouch && b < MasterTouch:
+		return int(b - buttonFaderTouch)
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,104 @@
+// Package mackie speaks the Mackie Control Universal (MCU) protocol used by most DAW
+// control surfaces. Decode turns captured messages into high-level events such as
+// FaderMoved or ButtonPressed, and Surface sends fader positions, LEDs, V-Pot rings,
+// meters and LCD text back to the device.
+package mackie
+
+import "github.com/leandrodaf/midi/sdk/contracts"
+
+// MasterFader is the fader index of the master fader; channel strips use 0-7.
+const MasterFader = 8
+
+// FaderMax is the highest position of a fader. Positions are 14-bit pitch bend values.
+const FaderMax = 0x3FFF
+
+// Controllers of the protocol.
+const (
+	ccVPot    = 0x10 // V-Pot rotation of strips 0-7 (0x10-0x17).
+	ccJog     = 0x3C // Jog wheel rotation.
+	ccVPotLED = 0x30 // LED ring of strips 0-7 (0x30-0x37).
+)
+
+// Event is a high-level event decoded from a surface message: one of FaderMoved,
+// ButtonPressed, ButtonReleased, VPotRotated or JogRotated.
+type Event interface {
+	mackieEvent()
+}
+
+// FaderMoved reports a new fader position.
+type FaderMoved struct {
+	Fader    int    // Fader index: 0-7 for channel strips, MasterFader for the master fader.
+	Position uint16 // Position, from 0 to FaderMax.
+}
+
+// ButtonPressed reports a button going down. Fader touch sensors are reported as buttons too.
+type ButtonPressed struct {
+	Button Button
+}
+
+// ButtonReleased reports a button going up.
+type ButtonReleased struct {
+	Button Button
+}
+
+// VPotRotated reports a V-Pot turned by a number of ticks; negative ticks are counterclockwise.
+type VPotRotated struct {
+	VPot  int // Strip of the V-Pot, 0-7.
+	Ticks int
+}
+
+// JogRotated reports the jog wheel turned by a number of ticks; negative ticks are counterclockwise.
+type JogRotated struct {
+	Ticks int
+}
+
+func (FaderMoved) mackieEvent()     {}
+func (ButtonPressed) mackieEvent()  {}
+func (ButtonReleased) mackieEvent() {}
+func (VPotRotated) mackieEvent()    {}
+func (JogRotated) mackieEvent()     {}
+
+// Decode converts a message captured from a surface into a high-level event. It reports
+// false for messages that are not part of the protocol.
+func Decode(event contracts.MIDI) (Event, bool) {
+	status := event.Command & 0xF0
+	channel := int(event.Command & 0x0F)
+
+	switch status {
+	case 0xE0:
+		// Each fader sends pitch bend on its own channel; the master fader uses channel 9.
+		if channel > MasterFader {
+			return nil, false
+		}
+		position := uint16(event.Note&0x7F) | uint16(event.Velocity&0x7F)<<7
+		return FaderMoved{Fader: channel, Position: position}, true
+	case 0x90, 0x80:
+		if channel != 0 {
+			return nil, false
+		}
+		if status == 0x90 && event.Velocity > 0 {
+			return ButtonPressed{Button: Button(event.Note)}, true
+		}
+		return ButtonReleased{Button: Button(event.Note)}, true
+	case 0xB0:
+		if channel != 0 {
+			return nil, false
+		}
+		switch {
+		case event.Note >= ccVPot && event.Note < ccVPot+8:
+			return VPotRotated{VPot: int(event.Note - ccVPot), Ticks: relative(event.Velocity)}, true
+		case event.Note == ccJog:
+			return JogRotated{Ticks: relative(event.Velocity)}, true
+		}
+	}
+	return nil, false
+}
+
+// relative decodes a sign-magnitude rotation value: bit 6 set means counterclockwise.
+func relative(value byte) int {
+	ticks := int(value & 0x3F)
+	if value&0x40 != 0 {
+		return -ticks
+	}
+	return ticks
+}
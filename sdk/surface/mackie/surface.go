@@ -0,0 +1,188 @@
+package mackie
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Sender sends messages to the surface, such as a contracts.ClientMIDI with the surface
+// selected as output device.
+type Sender interface {
+	Send(event contracts.MIDI) error // Sends a channel message.
+	SendSysEx(data []byte) error     // Sends a complete SysEx message, including F0 and F7.
+}
+
+// SysEx device IDs of the surfaces of the family.
+const (
+	DeviceMCU = 0x14 // Mackie Control Universal.
+	DeviceXT  = 0x15 // Mackie Control Universal XT extender.
+)
+
+// Size of the LCD: two lines of 56 characters, 7 per channel strip.
+const (
+	DisplayLines = 2
+	DisplayWidth = 56
+	CellWidth    = 7
+)
+
+// LEDState is the state of a button LED.
+type LEDState byte
+
+// Button LED states.
+const (
+	LEDOff   LEDState = 0x00
+	LEDFlash LEDState = 0x01
+	LEDOn    LEDState = 0x7F
+)
+
+// RingMode is the display mode of a V-Pot LED ring.
+type RingMode byte
+
+// V-Pot LED ring modes.
+const (
+	RingDot      RingMode = 0 // A single LED at the position.
+	RingBoostCut RingMode = 1 // LEDs from the center to the position.
+	RingWrap     RingMode = 2 // LEDs from the left to the position.
+	RingSpread   RingMode = 3 // LEDs spreading from the center by the position.
+)
+
+// RingPositions is the number of LEDs of a V-Pot ring; positions go from 1 to
+// RingPositions, and 0 turns the ring off.
+const RingPositions = 11
+
+// Meter levels beyond the 12 segments of the level meters.
+const (
+	MeterMax           = 12  // Highest meter segment.
+	MeterOverload      = 0xE // Lights the overload LED.
+	MeterClearOverload = 0xF // Clears the overload LED.
+)
+
+// Options holds the configuration of a Surface.
+type Options struct {
+	DeviceID byte // SysEx device ID of the surface, DeviceMCU or DeviceXT.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithDeviceID sets the SysEx device ID of the surface, e.g. DeviceXT for an extender.
+func WithDeviceID(id byte) Option {
+	return func(opts *Options) {
+		opts.DeviceID = id
+	}
+}
+
+// Surface sends feedback to a Mackie Control surface.
+type Surface struct {
+	sender  Sender
+	options Options
+}
+
+// New creates a surface sending through sender.
+//
+// sender Sender: The output connected to the surface.
+// opts ...Option: A variadic list of option functions to customize the surface.
+//
+// Returns:
+//   - *Surface: The surface, ready to send feedback.
+func New(sender Sender, opts ...Option) *Surface {
+	options := Options{DeviceID: DeviceMCU}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Surface{sender: sender, options: options}
+}
+
+// SetFader moves a motorized fader to a position, from 0 to FaderMax.
+func (s *Surface) SetFader(fader int, position uint16) error {
+	return s.sender.Send(FaderMessage(fader, position))
+}
+
+// SetLED sets the LED of a button.
+func (s *Surface) SetLED(button Button, state LEDState) error {
+	return s.sender.Send(LEDMessage(button, state))
+}
+
+// SetVPotRing sets the LED ring of a V-Pot.
+func (s *Surface) SetVPotRing(vpot int, mode RingMode, position int, center bool) error {
+	return s.sender.Send(RingMessage(vpot, mode, position, center))
+}
+
+// SetMeter sets the level meter of a channel strip.
+func (s *Surface) SetMeter(strip, level int) error {
+	return s.sender.Send(MeterMessage(strip, level))
+}
+
+// SetDisplay writes a line of the LCD, padding or truncating text to DisplayWidth.
+func (s *Surface) SetDisplay(line int, text string) error {
+	if line < 0 || line >= DisplayLines {
+		return fmt.Errorf("invalid display line %d", line)
+	}
+	return s.sender.SendSysEx(DisplayMessage(s.options.DeviceID, line*DisplayWidth, pad(text, DisplayWidth)))
+}
+
+// SetDisplayCell writes the part of an LCD line above a channel strip, padding or
+// truncating text to CellWidth.
+func (s *Surface) SetDisplayCell(strip, line int, text string) error {
+	if line < 0 || line >= DisplayLines {
+		return fmt.Errorf("invalid display line %d", line)
+	}
+	if strip < 0 || strip > 7 {
+		return fmt.Errorf("invalid channel strip %d", strip)
+	}
+	return s.sender.SendSysEx(DisplayMessage(s.options.DeviceID, line*DisplayWidth+strip*CellWidth, pad(text, CellWidth)))
+}
+
+// FaderMessage encodes a fader position as the pitch bend message the surface expects.
+func FaderMessage(fader int, position uint16) contracts.MIDI {
+	position = min(position, FaderMax)
+	return contracts.MIDI{
+		Command:  0xE0 | byte(min(max(fader, 0), MasterFader)),
+		Note:     byte(position & 0x7F),
+		Velocity: byte(position >> 7),
+	}
+}
+
+// LEDMessage encodes the LED state of a button.
+func LEDMessage(button Button, state LEDState) contracts.MIDI {
+	return contracts.MIDI{Command: 0x90, Note: byte(button) & 0x7F, Velocity: byte(state)}
+}
+
+// RingMessage encodes the LED ring of a V-Pot. position goes from 0 (off) to RingPositions.
+func RingMessage(vpot int, mode RingMode, position int, center bool) contracts.MIDI {
+	value := byte(mode&3)<<4 | byte(min(max(position, 0), RingPositions))
+	if center {
+		value |= 0x40
+	}
+	return contracts.MIDI{Command: 0xB0, Note: ccVPotLED + byte(vpot&7), Velocity: value}
+}
+
+// MeterMessage encodes the level of a strip meter as channel pressure. level goes from 0
+// to MeterMax, or is MeterOverload or MeterClearOverload.
+func MeterMessage(strip, level int) contracts.MIDI {
+	return contracts.MIDI{Command: 0xD0, Note: byte(strip&7)<<4 | byte(min(max(level, 0), 0xF))}
+}
+
+// DisplayMessage encodes an LCD update: text written from a character offset, where
+// line 2 starts at DisplayWidth. Characters outside printable ASCII are shown as '?'.
+func DisplayMessage(deviceID byte, offset int, text string) []byte {
+	data := []byte{0xF0, 0x00, 0x00, 0x66, deviceID, 0x12, byte(min(max(offset, 0), DisplayLines*DisplayWidth-1))}
+	for _, r := range text {
+		if r < 0x20 || r > 0x7E {
+			r = '?'
+		}
+		data = append(data, byte(r))
+	}
+	return append(data, 0xF7)
+}
+
+// pad pads or truncates text to width characters.
+func pad(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
@@ -0,0 +1,62 @@
+package throttle
+
+// DINBandwidth is the bandwidth of a DIN MIDI link in bytes per second: 31250 baud with
+// ten bits per byte.
+const DINBandwidth = 3125
+
+// Options holds the configuration of a Throttle.
+type Options struct {
+	Bandwidth int             // Bytes per second sent to the output.
+	QueueSize int             // Maximum number of queued messages; Send fails beyond it.
+	Coalesce  bool            // Whether queued continuous controller values are replaced by newer ones.
+	OnError   func(err error) // Optional handler of errors returned by the output.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithBandwidth sets the bytes per second sent to the output, e.g. DINBandwidth for DIN
+// MIDI or a lower value for devices that choke on dense traffic.
+func WithBandwidth(bytesPerSecond int) Option {
+	return func(opts *Options) {
+		opts.Bandwidth = bytesPerSecond
+	}
+}
+
+// WithQueueSize sets the maximum number of messages waiting to be sent.
+func WithQueueSize(size int) Option {
+	return func(opts *Options) {
+		opts.QueueSize = size
+	}
+}
+
+// WithoutCoalescing keeps every queued controller value instead of replacing it with newer ones.
+func WithoutCoalescing() Option {
+	return func(opts *Options) {
+		opts.Coalesce = false
+	}
+}
+
+// WithErrorHandler calls handler with the errors returned by the output. They are
+// discarded otherwise.
+func WithErrorHandler(handler func(err error)) Option {
+	return func(opts *Options) {
+		opts.OnError = handler
+	}
+}
+
+// applyDefaultOptions sets default values for Options if not explicitly provided.
+func applyDefaultOptions(opts ...Option) Options {
+	options := Options{Coalesce: true}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Bandwidth <= 0 {
+		options.Bandwidth = DINBandwidth
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = 1024
+	}
+	return options
+}
@@ -0,0 +1,304 @@
+// Package throttle paces the messages sent to a MIDI output to the bandwidth the link and
+// the device can take. DIN MIDI carries about 3125 bytes per second, and many devices
+// choke or drop messages when flooded; a Throttle queues messages, sends them no faster
+// than the configured bandwidth and, when the queue backs up, replaces queued controller
+// values with newer ones instead of sending every intermediate value.
+package throttle
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// Errors returned by a Throttle.
+var (
+	ErrQueueFull        = errors.New("throttle queue full")
+	ErrClosed           = errors.New("throttle closed")
+	ErrSysExUnsupported = contracts.ErrSysExUnsupported
+	ErrInvalidMessage   = errors.New("not a channel or system message")
+)
+
+// Sender sends messages to a MIDI output.
+type Sender interface {
+	Send(event contracts.MIDI) error // Sends a channel or system message.
+}
+
+// SysExSender is implemented by outputs that also accept SysEx messages, such as every
+// contracts.ClientMIDI.
+type SysExSender interface {
+	SendSysEx(data []byte) error // Sends a complete SysEx message, including F0 and F7.
+}
+
+// Stats reports the traffic of a Throttle.
+type Stats struct {
+	Sent      uint64 // Messages sent to the output.
+	Bytes     uint64 // Bytes sent to the output.
+	Coalesced uint64 // Queued controller values replaced by newer ones.
+	Rejected  uint64 // Messages rejected because the queue was full.
+	Queued    int    // Messages waiting to be sent.
+}
+
+// message is a queued message: a channel or system message, or a SysEx message when sysex is set.
+type message struct {
+	event contracts.MIDI
+	sysex []byte
+}
+
+// Throttle paces the messages sent to an output. It implements the Sender interfaces of
+// this module, so it can stand in for the output it wraps. It is safe for concurrent use.
+type Throttle struct {
+	out     Sender
+	options Options
+	perByte time.Duration // Time one byte takes at the configured bandwidth.
+
+	mu       sync.Mutex
+	realtime []message     // Queued system real-time messages, sent before the others.
+	queue    []message     // Queued messages, in order.
+	stats    Stats         // Traffic counters; Queued is computed on demand.
+	closed   bool          // Whether Close was called.
+	wake     chan struct{} // Signals the sender goroutine that a message was queued.
+	done     chan struct{} // Closed when the sender goroutine exits.
+}
+
+// New creates a throttle sending to out at the configured bandwidth, 3125 bytes per
+// second (DIN MIDI) by default.
+//
+// out Sender: The output to pace. SysEx messages are accepted if it implements SysExSender.
+// opts ...Option: Optional settings such as WithBandwidth and WithQueueSize.
+//
+// Returns:
+//   - *Throttle: The throttle, sending until Close is called.
+func New(out Sender, opts ...Option) *Throttle {
+	options := applyDefaultOptions(opts...)
+	t := &Throttle{
+		out:     out,
+		options: options,
+		perByte: time.Second / time.Duration(options.Bandwidth),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Send queues a message. System real-time messages, such as clock, are sent ahead of the
+// queued messages. It never blocks: it fails with ErrQueueFull when the queue is full, and
+// with ErrInvalidMessage for data bytes and SysEx status bytes.
+func (t *Throttle) Send(event contracts.MIDI) error {
+	if size(event) == 0 {
+		return ErrInvalidMessage
+	}
+	return t.enqueue(message{event: event})
+}
+
+// SendSysEx queues a complete SysEx message, including F0 and F7. It fails with
+// ErrSysExUnsupported if the output does not accept SysEx messages.
+func (t *Throttle) SendSysEx(data []byte) error {
+	if _, ok := t.out.(SysExSender); !ok {
+		return ErrSysExUnsupported
+	}
+	return t.enqueue(message{sysex: append([]byte(nil), data...)})
+}
+
+// Stats returns the traffic counters of the throttle.
+func (t *Throttle) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.stats
+	stats.Queued = len(t.realtime) + len(t.queue)
+	return stats
+}
+
+// Close sends the queued messages, still paced, and stops the throttle. Later sends fail
+// with ErrClosed. The wrapped output is not closed.
+func (t *Throttle) Close() error {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.wake)
+	}
+	t.mu.Unlock()
+
+	<-t.done
+	return nil
+}
+
+// enqueue adds msg to the queue, coalescing it with a queued controller value if possible.
+func (t *Throttle) enqueue(msg message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case t.closed:
+		return ErrClosed
+	case msg.sysex == nil && msg.event.Command >= 0xF8:
+		if len(t.realtime) >= t.options.QueueSize {
+			t.stats.Rejected++
+			return ErrQueueFull
+		}
+		t.realtime = append(t.realtime, msg)
+	case t.options.Coalesce && msg.sysex == nil && t.coalesce(msg.event):
+		t.stats.Coalesced++
+		return nil
+	default:
+		if len(t.queue) >= t.options.QueueSize {
+			t.stats.Rejected++
+			return ErrQueueFull
+		}
+		t.queue = append(t.queue, msg)
+	}
+
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// coalesce replaces the value of a queued message controlling the same thing as event,
+// and reports whether it did. Only continuous values are coalesced, and only when no
+// other message of the channel is queued after the replaced one, so the order of
+// notes, program changes and parameter selections is preserved. The caller must hold t.mu.
+func (t *Throttle) coalesce(event contracts.MIDI) bool {
+	if !continuous(event) {
+		return false
+	}
+	channel := event.Command & 0x0F
+	for i := len(t.queue) - 1; i >= 0; i-- {
+		queued := t.queue[i].event
+		if t.queue[i].sysex != nil {
+			return false
+		}
+		if queued.Command < 0xF0 && queued.Command&0x0F != channel {
+			continue
+		}
+		if !continuous(queued) {
+			return false
+		}
+		if sameControl(queued, event) {
+			t.queue[i].event = event
+			return true
+		}
+	}
+	return false
+}
+
+// run sends the queued messages at the configured bandwidth until the throttle is closed
+// and its queue is empty.
+func (t *Throttle) run() {
+	defer close(t.done)
+
+	var next time.Time // Earliest time the next message may start.
+	for {
+		msg, ok := t.dequeue()
+		if !ok {
+			return
+		}
+		start := time.Now()
+		if start.Before(next) {
+			time.Sleep(next.Sub(start))
+			start = next
+		}
+
+		var (
+			n   int
+			err error
+		)
+		if msg.sysex != nil {
+			n, err = len(msg.sysex), t.out.(SysExSender).SendSysEx(msg.sysex)
+		} else {
+			n, err = size(msg.event), t.out.Send(msg.event)
+		}
+		next = start.Add(time.Duration(n) * t.perByte)
+
+		t.mu.Lock()
+		t.stats.Sent++
+		t.stats.Bytes += uint64(n)
+		t.mu.Unlock()
+		if err != nil && t.options.OnError != nil {
+			t.options.OnError(err)
+		}
+	}
+}
+
+// dequeue waits for a queued message and removes it from the queue, real-time messages
+// first. It reports false when the throttle is closed and the queue is empty.
+func (t *Throttle) dequeue() (message, bool) {
+	for {
+		t.mu.Lock()
+		switch {
+		case len(t.realtime) > 0:
+			msg := t.realtime[0]
+			t.realtime = t.realtime[1:]
+			t.mu.Unlock()
+			return msg, true
+		case len(t.queue) > 0:
+			msg := t.queue[0]
+			t.queue = t.queue[1:]
+			t.mu.Unlock()
+			return msg, true
+		case t.closed:
+			t.mu.Unlock()
+			return message{}, false
+		}
+		t.mu.Unlock()
+		<-t.wake
+	}
+}
+
+// size returns the number of bytes of a channel or system message on the wire, or 0 for
+// status bytes that do not start a message of their own.
+func size(event contracts.MIDI) int {
+	switch status := event.Command; {
+	case status < 0x80, status == 0xF0, status == 0xF7:
+		return 0
+	case status&0xF0 == 0xC0, status&0xF0 == 0xD0, status == 0xF1, status == 0xF3:
+		return 2
+	case status < 0xF0, status == 0xF2:
+		return 3
+	default:
+		return 1
+	}
+}
+
+// continuous reports whether event sets a continuous value whose intermediate values may
+// be skipped: controllers other than bank select, data entry, parameter selection and
+// channel mode, channel and key pressure, and pitch bend.
+func continuous(event contracts.MIDI) bool {
+	switch event.Command & 0xF0 {
+	case 0xA0, 0xD0, 0xE0:
+		return true
+	case 0xB0:
+		switch controller := event.Note; {
+		case controller == 0x00, controller == 0x20: // Bank select.
+			return false
+		case controller == 0x06, controller == 0x26: // Data entry.
+			return false
+		case controller >= 0x60 && controller <= 0x65: // Data increment and decrement, NRPN and RPN selection.
+			return false
+		case controller >= 0x78: // Channel mode messages.
+			return false
+		default:
+			return true
+		}
+	default:
+		return false
+	}
+}
+
+// sameControl reports whether two continuous messages set the same value.
+func sameControl(a, b contracts.MIDI) bool {
+	if a.Command != b.Command {
+		return false
+	}
+	switch a.Command & 0xF0 {
+	case 0xA0, 0xB0: // Per key or per controller.
+		return a.Note == b.Note
+	default: // Per channel.
+		return true
+	}
+}
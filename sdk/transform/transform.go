@@ -0,0 +1,156 @@
+// Package transform provides common transforms of MIDI events, for the transformation
+// pipeline of the client set with contracts.WithTransforms and for the routes of
+// sdk/router: transposition, channel remapping, velocity scaling, curves and tables, and keyboard
+// splits and ranges.
+//
+//	client, err := midi.NewMIDIClient(contracts.WithTransforms(
+//		transform.Split(60, 1, 0),            // Bass on channel 2 below middle C.
+//		transform.VelocityCurve(velocity.GM), // Softer response for a light keybed.
+//	))
+//
+// Channels are zero-based, as returned by contracts.MIDI.Channel.
+package transform
+
+import (
+	"math"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"github.com/leandrodaf/midi/sdk/velocity"
+)
+
+// Chain combines transforms into one applying them in order, which drops the events any
+// of them drops.
+func Chain(transforms ...contracts.Transform) contracts.Transform {
+	return func(event contracts.MIDI) (contracts.MIDI, bool) {
+		for _, transform := range transforms {
+			var ok bool
+			if event, ok = transform(event); !ok {
+				return event, false
+			}
+		}
+		return event, true
+	}
+}
+
+// Transpose shifts the note of note on, note off and poly aftertouch messages by
+// semitones. Notes shifted out of the MIDI range are dropped.
+func Transpose(semitones int) contracts.Transform {
+	return func(event contracts.MIDI) (contracts.MIDI, bool) {
+		if !isNote(event) {
+			return event, true
+		}
+		note := int(event.Note) + semitones
+		if note < 0 || note > 127 {
+			return event, false
+		}
+		event.Note = byte(note)
+		return event, true
+	}
+}
+
+// RemapChannel moves the channel messages of channel from to channel to. Other messages
+// pass unchanged.
+func RemapChannel(from, to byte) contracts.Transform {
+	return func(event contracts.MIDI) (contracts.MIDI, bool) {
+		if isChannel(event) && event.Command&0x0F == from {
+			event.Command = event.Command&0xF0 | to&0x0F
+		}
+		return event, true
+	}
+}
+
+// SetChannel moves every channel message to channel, e.g. to play a controller on the
+// channel a sound module listens to.
+func SetChannel(channel byte) contracts.Transform {
+	return func(event contracts.MIDI) (contracts.MIDI, bool) {
+		if isChannel(event) {
+			event.Command = event.Command&0xF0 | channel&0x0F
+		}
+		return event, true
+	}
+}
+
+// ScaleVelocity multiplies the velocities of note ons by factor, rounded and limited to
+// 1-127, so note ons are not turned into note offs. Other messages pass unchanged.
+func ScaleVelocity(factor float64) contracts.Transform {
+	return func(event contracts.MIDI) (contracts.MIDI, bool) {
+		if isNoteOn(event) {
+			event.Velocity = clampVelocity(float64(event.Velocity) * factor)
+		}
+		return event, true
+	}
+}
+
+// VelocityCurve reshapes the velocities of note ons with curve: a velocity becomes the
+// gain curve returns for it, scaled to 127 and rounded. Note ons keep a velocity of at
+// least 1, so they are not turned into note offs; other messages pass unchanged.
+func VelocityCurve(curve velocity.Curve) contracts.Transform {
+	return func(event contracts.MIDI) (contracts.MIDI, bool) {
+		if isNoteOn(event) {
+			event.Velocity = clampVelocity(curve(event.Velocity) * velocity.MaxVelocity)
+		}
+		return event, true
+	}
+}
+
+// VelocityTable remaps the velocities of note ons with table, such as one built with
+// velocity.Interpolate or a preset like velocity.LightKeybed. Note ons keep a velocity of
+// at least 1, so they are not turned into note offs; other messages pass unchanged.
+func VelocityTable(table velocity.Table) contracts.Transform {
+	return func(event contracts.MIDI) (contracts.MIDI, bool) {
+		if isNoteOn(event) {
+			event.Velocity = max(table.Apply(event.Velocity), 1)
+		}
+		return event, true
+	}
+}
+
+// NoteRange keeps the note on, note off and poly aftertouch messages of the notes from low
+// to high, inclusive, and drops the others, e.g. to take one zone of a split keyboard.
+// Other messages pass unchanged.
+func NoteRange(low, high byte) contracts.Transform {
+	return func(event contracts.MIDI) (contracts.MIDI, bool) {
+		return event, !isNote(event) || event.Note >= low && event.Note <= high
+	}
+}
+
+// Split divides the keyboard at point: note on, note off and poly aftertouch messages of
+// the notes below point are moved to channel lower, and the others to channel upper, so
+// one keyboard plays two sounds. Other messages pass unchanged.
+func Split(point, lower, upper byte) contracts.Transform {
+	return func(event contracts.MIDI) (contracts.MIDI, bool) {
+		if !isNote(event) {
+			return event, true
+		}
+		channel := upper
+		if event.Note < point {
+			channel = lower
+		}
+		event.Command = event.Command&0xF0 | channel&0x0F
+		return event, true
+	}
+}
+
+// isChannel reports whether event is a channel message.
+func isChannel(event contracts.MIDI) bool {
+	return event.Command >= 0x80 && event.Command < 0xF0
+}
+
+// isNote reports whether event is a note on, note off or poly aftertouch message.
+func isNote(event contracts.MIDI) bool {
+	switch event.Command & 0xF0 {
+	case 0x80, 0x90, 0xA0:
+		return true
+	}
+	return false
+}
+
+// isNoteOn reports whether event is a note on with a non-zero velocity.
+func isNoteOn(event contracts.MIDI) bool {
+	return event.Command&0xF0 == 0x90 && event.Velocity > 0
+}
+
+// clampVelocity rounds value and limits it to 1-127.
+func clampVelocity(value float64) byte {
+	return byte(min(max(math.Round(value), 1), velocity.MaxVelocity))
+}
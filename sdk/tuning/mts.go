@@ -0,0 +1,94 @@
+package tuning
+
+import "math"
+
+// MIDI Tuning Standard message constants.
+const (
+	// MTSAllDevices is the device ID addressing every receiver.
+	MTSAllDevices = 0x7F
+	// mtsMaxNotes is the number of notes a single note tuning change can carry.
+	mtsMaxNotes = 127
+)
+
+// MTSSingleNoteChange returns real-time MIDI Tuning Standard single note tuning change
+// messages (F0 7F <device> 08 02 ...) retuning notes of the given tuning program to this
+// tuning. Messages carry at most 127 notes, so larger sets are split. Unmapped notes are
+// sent as "no change".
+//
+// device byte: The SysEx device ID, or MTSAllDevices.
+// program byte: The tuning program to modify (0-127).
+// notes ...byte: The MIDI notes to retune.
+//
+// Returns:
+//   - [][]byte: The complete SysEx messages, including F0 and F7.
+func (t *Tuning) MTSSingleNoteChange(device, program byte, notes ...byte) [][]byte {
+	var messages [][]byte
+	for start := 0; start < len(notes); start += mtsMaxNotes {
+		chunk := notes[start:min(start+mtsMaxNotes, len(notes))]
+		message := []byte{0xF0, 0x7F, device & 0x7F, 0x08, 0x02, program & 0x7F, byte(len(chunk))}
+		for _, note := range chunk {
+			message = append(message, note&0x7F)
+			message = append(message, mtsFrequency(t.NoteFrequency(note))...)
+		}
+		messages = append(messages, append(message, 0xF7))
+	}
+	return messages
+}
+
+// MTSBulkDump returns a non-real-time MIDI Tuning Standard bulk tuning dump
+// (F0 7E <device> 08 01 ...) describing all 128 notes of this tuning.
+//
+// device byte: The SysEx device ID, or MTSAllDevices.
+// program byte: The tuning program the dump is stored in (0-127).
+// name string: The tuning name; only the first 16 ASCII characters are used.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0, the checksum and F7.
+func (t *Tuning) MTSBulkDump(device, program byte, name string) []byte {
+	message := []byte{0xF0, 0x7E, device & 0x7F, 0x08, 0x01, program & 0x7F}
+
+	padded := [16]byte{}
+	for i := range padded {
+		padded[i] = ' '
+		if i < len(name) && name[i] < 0x80 {
+			padded[i] = name[i]
+		}
+	}
+	message = append(message, padded[:]...)
+
+	for note := 0; note < 128; note++ {
+		message = append(message, mtsFrequency(t.NoteFrequency(byte(note)))...)
+	}
+
+	var checksum byte
+	for _, b := range message[1:] {
+		checksum ^= b
+	}
+	return append(message, checksum&0x7F, 0xF7)
+}
+
+// mtsFrequency encodes a frequency as MTS frequency data: the equal-tempered semitone
+// below it and the fraction above it in 1/16384 semitone steps. A frequency of 0 is
+// encoded as "no change" (7F 7F 7F).
+func mtsFrequency(frequency float64) []byte {
+	if frequency <= 0 {
+		return []byte{0x7F, 0x7F, 0x7F}
+	}
+
+	note := Note(frequency)
+	if note <= 0 {
+		return []byte{0, 0, 0}
+	}
+	semitone := math.Floor(note)
+	fraction := math.Round((note - semitone) * 16384)
+	if fraction >= 16384 {
+		semitone++
+		fraction = 0
+	}
+	if semitone > 127 || semitone == 127 && fraction >= 16383 {
+		// 7F 7F 7F is reserved for "no change", so the top of the range is clamped below it.
+		return []byte{0x7F, 0x7F, 0x7E}
+	}
+	value := int(fraction)
+	return []byte{byte(semitone), byte(value >> 7), byte(value & 0x7F)}
+}
@@ -0,0 +1,287 @@
+package tuning
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidScala is returned when a Scala scale or keyboard mapping file cannot be parsed.
+var ErrInvalidScala = errors.New("invalid Scala file")
+
+// maxMappingSize is the largest map size of a keyboard mapping: one entry per MIDI key.
+const maxMappingSize = 128
+
+// Scale is a Scala (.scl) scale: the pitches of the degrees above the 1/1, in cents.
+// The last pitch is the period of the scale, usually the 2/1 octave.
+type Scale struct {
+	Description string    // Description line of the file.
+	Pitches     []float64 // Pitches of degrees 1 to n, in cents above degree 0.
+}
+
+// Period returns the interval after which the scale repeats, in cents.
+func (s *Scale) Period() float64 {
+	return s.Pitches[len(s.Pitches)-1]
+}
+
+// degreeCents returns the pitch of a scale degree in cents above degree 0. Degrees beyond
+// the scale, or below 0, are reached by adding or removing periods.
+func (s *Scale) degreeCents(degree int) float64 {
+	n := len(s.Pitches)
+	periods := floorDiv(degree, n)
+	index := degree - periods*n
+	cents := float64(periods) * s.Period()
+	if index > 0 {
+		cents += s.Pitches[index-1]
+	}
+	return cents
+}
+
+// KeyboardMapping is a Scala (.kbm) keyboard mapping, which assigns scale degrees to keys
+// and sets the reference frequency.
+type KeyboardMapping struct {
+	FirstNote          int     // First key to retune.
+	LastNote           int     // Last key to retune.
+	MiddleNote         int     // Key mapped to degree 0 of the scale.
+	ReferenceNote      int     // Key tuned to ReferenceFrequency.
+	ReferenceFrequency float64 // Frequency of ReferenceNote, in Hz.
+	OctaveDegree       int     // Scale degree of the formal octave, repeated every len(Mapping) keys.
+	Mapping            []int   // Scale degree of each key from MiddleNote on; -1 leaves the key unmapped. Empty maps keys linearly.
+}
+
+// LinearMapping returns the mapping used when no .kbm file is given: every key maps to the
+// next scale degree, degree 0 on middle C, with A4 tuned to reference.
+func LinearMapping(reference float64) *KeyboardMapping {
+	return &KeyboardMapping{
+		FirstNote:          0,
+		LastNote:           127,
+		MiddleNote:         60,
+		ReferenceNote:      69,
+		ReferenceFrequency: reference,
+	}
+}
+
+// LoadScale reads a Scala scale file.
+func LoadScale(path string) (*Scale, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseScale(file)
+}
+
+// ParseScale parses a Scala scale from r. Pitches containing a period are cents; others
+// are ratios such as 3/2 or 2.
+func ParseScale(r io.Reader) (*Scale, error) {
+	lines, err := scalaLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("%w: missing description or note count", ErrInvalidScala)
+	}
+
+	count, err := strconv.Atoi(firstField(lines[1]))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("%w: invalid note count %q", ErrInvalidScala, lines[1])
+	}
+	if len(lines)-2 < count {
+		return nil, fmt.Errorf("%w: %d pitches declared but %d found", ErrInvalidScala, count, len(lines)-2)
+	}
+
+	scale := &Scale{Description: strings.TrimSpace(lines[0]), Pitches: make([]float64, count)}
+	for i := range count {
+		cents, err := parsePitch(firstField(lines[i+2]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: pitch %d: %v", ErrInvalidScala, i+1, err)
+		}
+		scale.Pitches[i] = cents
+	}
+	if scale.Period() <= 0 {
+		return nil, fmt.Errorf("%w: the period must be above the 1/1", ErrInvalidScala)
+	}
+	return scale, nil
+}
+
+// LoadKeyboardMapping reads a Scala keyboard mapping file.
+func LoadKeyboardMapping(path string) (*KeyboardMapping, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseKeyboardMapping(file)
+}
+
+// ParseKeyboardMapping parses a Scala keyboard mapping from r.
+func ParseKeyboardMapping(r io.Reader) (*KeyboardMapping, error) {
+	lines, err := scalaLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) < 7 {
+		return nil, fmt.Errorf("%w: keyboard mapping header needs 7 values, found %d", ErrInvalidScala, len(lines))
+	}
+
+	var header [7]float64
+	for i := range header {
+		if header[i], err = strconv.ParseFloat(firstField(lines[i]), 64); err != nil {
+			return nil, fmt.Errorf("%w: keyboard mapping line %d: %v", ErrInvalidScala, i+1, err)
+		}
+	}
+
+	// The map covers at most the 128 MIDI keys; checking before allocating keeps
+	// malformed files from exhausting memory.
+	if header[0] < 0 || header[0] > maxMappingSize || header[0] != math.Trunc(header[0]) {
+		return nil, fmt.Errorf("%w: map size %v is not from 0 to %d", ErrInvalidScala, header[0], maxMappingSize)
+	}
+	if header[5] <= 0 {
+		return nil, fmt.Errorf("%w: invalid reference frequency %v", ErrInvalidScala, header[5])
+	}
+	size := int(header[0])
+	mapping := &KeyboardMapping{
+		FirstNote:          int(header[1]),
+		LastNote:           int(header[2]),
+		MiddleNote:         int(header[3]),
+		ReferenceNote:      int(header[4]),
+		ReferenceFrequency: header[5],
+		OctaveDegree:       int(header[6]),
+		Mapping:            make([]int, size),
+	}
+
+	for i := range size {
+		// Trailing entries may be omitted; they are unmapped.
+		mapping.Mapping[i] = -1
+		if 7+i >= len(lines) {
+			continue
+		}
+		entry := firstField(lines[7+i])
+		if entry == "x" || entry == "X" {
+			continue
+		}
+		if mapping.Mapping[i], err = strconv.Atoi(entry); err != nil {
+			return nil, fmt.Errorf("%w: mapping entry %d: %v", ErrInvalidScala, i, err)
+		}
+	}
+	if mapping.referenceDegree() < 0 {
+		return nil, fmt.Errorf("%w: reference key %d is unmapped", ErrInvalidScala, mapping.ReferenceNote)
+	}
+	return mapping, nil
+}
+
+// scalaFrequency returns the frequency of a key under a scale and keyboard mapping, and
+// whether the key is mapped at all.
+func scalaFrequency(scale *Scale, mapping *KeyboardMapping, key int) (float64, bool) {
+	if key < mapping.FirstNote || key > mapping.LastNote {
+		return 0, false
+	}
+	cents, ok := keyCents(scale, mapping, key)
+	if !ok {
+		return 0, false
+	}
+	// Mappings leaving the reference key unmapped give no pitch to tune the others from;
+	// ParseKeyboardMapping rejects them, and those built by hand map no key.
+	reference, ok := keyCents(scale, mapping, mapping.ReferenceNote)
+	if !ok {
+		return 0, false
+	}
+	return mapping.ReferenceFrequency * math.Exp2((cents-reference)/1200), true
+}
+
+// referenceDegree returns the entry of Mapping for the reference key, 0 for linear
+// mappings, or -1 when the reference key is unmapped.
+func (m *KeyboardMapping) referenceDegree() int {
+	size := len(m.Mapping)
+	if size == 0 {
+		return 0
+	}
+	offset := m.ReferenceNote - m.MiddleNote
+	return m.Mapping[offset-floorDiv(offset, size)*size]
+}
+
+// keyCents returns the pitch of a key in cents above the middle note.
+func keyCents(scale *Scale, mapping *KeyboardMapping, key int) (float64, bool) {
+	offset := key - mapping.MiddleNote
+	size := len(mapping.Mapping)
+	if size == 0 {
+		return scale.degreeCents(offset), true
+	}
+
+	patterns := floorDiv(offset, size)
+	degree := mapping.Mapping[offset-patterns*size]
+	if degree < 0 {
+		return 0, false
+	}
+	octave := mapping.OctaveDegree
+	if octave == 0 {
+		octave = len(scale.Pitches)
+	}
+	return float64(patterns)*scale.degreeCents(octave) + scale.degreeCents(degree), true
+}
+
+// scalaLines returns the lines of a Scala file without comments. Blank lines are kept
+// because the description of a scale may be empty.
+func scalaLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// parsePitch converts a Scala pitch (cents with a period, otherwise a ratio) to cents.
+func parsePitch(value string) (float64, error) {
+	if strings.Contains(value, ".") {
+		return strconv.ParseFloat(value, 64)
+	}
+
+	numerator, denominator := value, "1"
+	if i := strings.IndexByte(value, '/'); i >= 0 {
+		numerator, denominator = value[:i], value[i+1:]
+	}
+	n, err := strconv.ParseUint(numerator, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	d, err := strconv.ParseUint(denominator, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 || d == 0 {
+		return 0, fmt.Errorf("invalid ratio %q", value)
+	}
+	return 1200 * math.Log2(float64(n)/float64(d)), nil
+}
+
+// firstField returns the first whitespace-separated field of a line, since Scala allows
+// any text after a value.
+func firstField(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// floorDiv divides rounding towards negative infinity.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
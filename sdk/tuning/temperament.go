@@ -0,0 +1,24 @@
+package tuning
+
+// Temperament lists, for each pitch class starting at the root, the deviation in cents
+// from twelve-tone equal temperament.
+type Temperament [12]float64
+
+// Common temperaments, built on C. Use WithTemperament to build them on another root.
+var (
+	// EqualTemperament divides the octave into twelve equal semitones.
+	EqualTemperament = Temperament{}
+
+	// Pythagorean is built from pure fifths, with the wolf fifth between G# and Eb.
+	Pythagorean = Temperament{0, 13.685, 3.910, -5.865, 7.820, -1.955, 11.730, 1.955, 15.640, 5.865, -3.910, 9.775}
+
+	// JustIntonation is five-limit just intonation (1/1, 16/15, 9/8, 6/5, 5/4, 4/3, 45/32,
+	// 3/2, 8/5, 5/3, 9/5, 15/8).
+	JustIntonation = Temperament{0, 11.731, 3.910, 15.641, -13.686, -1.955, -9.776, 1.955, 13.686, -15.641, 17.596, -11.731}
+
+	// QuarterCommaMeantone has pure major thirds, with the wolf fifth between G# and Eb.
+	QuarterCommaMeantone = Temperament{0, -23.951, -6.843, 10.265, -13.686, 3.422, -20.529, -3.422, -27.373, -10.265, 6.843, -17.108}
+
+	// WerckmeisterIII is Werckmeister's well temperament with four fifths narrowed by a quarter comma.
+	WerckmeisterIII = Temperament{0, -9.775, -7.820, -5.865, -9.775, -1.955, -11.730, -3.910, -7.820, -11.730, -3.910, -7.820}
+)
@@ -0,0 +1,204 @@
+// Package tuning converts MIDI note numbers, including fractional and pitch-bent notes,
+// into frequencies for a configurable reference pitch and temperament, or for a
+// microtonal Scala scale, and produces MIDI Tuning Standard messages for them.
+package tuning
+
+import "math"
+
+// Standard reference pitch: A4, MIDI note 69, at 440 Hz.
+const (
+	StandardReference     = 440.0
+	StandardReferenceNote = 69.0
+)
+
+// PitchBendCenter is the 14-bit pitch bend value meaning no bend.
+const PitchBendCenter = 8192
+
+// Options holds the configuration of a Tuning.
+type Options struct {
+	Reference     float64          // Frequency of ReferenceNote, in Hz.
+	ReferenceNote float64          // Note tuned to exactly Reference.
+	Temperament   Temperament      // Deviations from equal temperament.
+	Root          int              // Pitch class (0 = C) the temperament is built on.
+	Scale         *Scale           // Optional Scala scale replacing the temperament.
+	Mapping       *KeyboardMapping // Keyboard mapping of Scale; nil maps keys linearly.
+}
+
+// Option is a function that modifies Options.
+type Option func(*Options)
+
+// WithReference sets the frequency of the reference note (A4 unless changed), e.g. 442 or 432.
+func WithReference(hz float64) Option {
+	return func(opts *Options) {
+		opts.Reference = hz
+	}
+}
+
+// WithReferenceNote sets which note is tuned to the reference frequency.
+func WithReferenceNote(note float64) Option {
+	return func(opts *Options) {
+		opts.ReferenceNote = note
+	}
+}
+
+// WithTemperament sets the temperament and the pitch class (0 = C, 9 = A) it is built on.
+func WithTemperament(temperament Temperament, root int) Option {
+	return func(opts *Options) {
+		opts.Temperament = temperament
+		opts.Root = root
+	}
+}
+
+// WithScale retunes notes to a Scala scale. With a nil mapping, keys map linearly to the
+// scale degrees, degree 0 on middle C, and the reference note and frequency of the tuning
+// are used. The temperament is ignored.
+func WithScale(scale *Scale, mapping *KeyboardMapping) Option {
+	return func(opts *Options) {
+		opts.Scale = scale
+		opts.Mapping = mapping
+	}
+}
+
+// Tuning maps notes to frequencies.
+type Tuning struct {
+	options Options
+}
+
+// Standard is twelve-tone equal temperament with A4 at 440 Hz.
+var Standard = New()
+
+// New creates a tuning. Without options it is twelve-tone equal temperament with A4 at 440 Hz.
+//
+// opts ...Option: A variadic list of option functions to customize the tuning.
+//
+// Returns:
+//   - *Tuning: The tuning.
+func New(opts ...Option) *Tuning {
+	options := Options{Reference: StandardReference, ReferenceNote: StandardReferenceNote}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.Root = ((options.Root % 12) + 12) % 12
+	if options.Scale != nil && options.Mapping == nil {
+		options.Mapping = LinearMapping(options.Reference)
+		options.Mapping.ReferenceNote = int(options.ReferenceNote)
+	}
+	return &Tuning{options: options}
+}
+
+// Frequency returns the frequency of a note in Hz. Fractional notes lie between the
+// neighbouring tempered notes, so bent notes glide smoothly. The reference note keeps
+// exactly the reference frequency whatever the temperament. Keys left unmapped by a
+// Scala keyboard mapping have a frequency of 0.
+func (t *Tuning) Frequency(note float64) float64 {
+	if t.options.Scale != nil {
+		return t.scaleFrequency(note)
+	}
+	cents := t.deviation(note) - t.deviation(t.options.ReferenceNote)
+	return t.options.Reference * math.Exp2((note-t.options.ReferenceNote+cents/100)/12)
+}
+
+// scaleFrequency returns the frequency of a note under the Scala scale, interpolating
+// geometrically between keys for fractional notes.
+func (t *Tuning) scaleFrequency(note float64) float64 {
+	base := math.Floor(note)
+	low, ok := scalaFrequency(t.options.Scale, t.options.Mapping, int(base))
+	if !ok {
+		return 0
+	}
+	frac := note - base
+	if frac == 0 {
+		return low
+	}
+	high, ok := scalaFrequency(t.options.Scale, t.options.Mapping, int(base)+1)
+	if !ok {
+		return 0
+	}
+	return low * math.Pow(high/low, frac)
+}
+
+// Mapped reports whether the tuning assigns a frequency to a MIDI note. Only Scala keyboard
+// mappings leave notes unmapped.
+func (t *Tuning) Mapped(note byte) bool {
+	return t.NoteFrequency(note) > 0
+}
+
+// NoteFrequency returns the frequency of a MIDI note in Hz.
+func (t *Tuning) NoteFrequency(note byte) float64 {
+	return t.Frequency(float64(note))
+}
+
+// BentFrequency returns the frequency of a MIDI note under a 14-bit pitch bend value
+// (0-16383, centred on PitchBendCenter) with the given bend range in semitones.
+func (t *Tuning) BentFrequency(note byte, bend uint16, bendRange float64) float64 {
+	return t.Frequency(Bend(note, bend, bendRange))
+}
+
+// Nearest returns the note closest to a frequency and the deviation from it in cents,
+// as needed by tuners. The result is meaningless for frequencies that are not positive.
+func (t *Tuning) Nearest(frequency float64) (note int, cents float64) {
+	if t.options.Scale != nil {
+		return t.nearestMapped(frequency)
+	}
+	guess := int(math.Round(t.options.ReferenceNote + 12*math.Log2(frequency/t.options.Reference)))
+	cents = math.Inf(1)
+	for candidate := guess - 1; candidate <= guess+1; candidate++ {
+		deviation := 1200 * math.Log2(frequency/t.Frequency(float64(candidate)))
+		if math.Abs(deviation) < math.Abs(cents) {
+			note, cents = candidate, deviation
+		}
+	}
+	return note, cents
+}
+
+// nearestMapped searches every mapped MIDI note for the one closest to frequency.
+func (t *Tuning) nearestMapped(frequency float64) (note int, cents float64) {
+	cents = math.Inf(1)
+	for candidate := 0; candidate <= 127; candidate++ {
+		f := t.Frequency(float64(candidate))
+		if f <= 0 {
+			continue
+		}
+		if deviation := 1200 * math.Log2(frequency/f); math.Abs(deviation) < math.Abs(cents) {
+			note, cents = candidate, deviation
+		}
+	}
+	return note, cents
+}
+
+// deviation returns the temperament deviation of a note in cents, interpolating
+// between pitch classes for fractional notes.
+func (t *Tuning) deviation(note float64) float64 {
+	base := math.Floor(note)
+	frac := note - base
+	low := t.pitchClassDeviation(int(base))
+	if frac == 0 {
+		return low
+	}
+	return low + (t.pitchClassDeviation(int(base)+1)-low)*frac
+}
+
+// pitchClassDeviation returns the deviation of the pitch class of note.
+func (t *Tuning) pitchClassDeviation(note int) float64 {
+	degree := ((note-t.options.Root)%12 + 12) % 12
+	return t.options.Temperament[degree]
+}
+
+// Frequency returns the frequency of a note in twelve-tone equal temperament with A4 at 440 Hz.
+func Frequency(note float64) float64 {
+	return Standard.Frequency(note)
+}
+
+// Note returns the fractional note of a frequency in twelve-tone equal temperament with
+// A4 at 440 Hz.
+func Note(frequency float64) float64 {
+	return StandardReferenceNote + 12*math.Log2(frequency/StandardReference)
+}
+
+// Bend returns the fractional note reached by bending note with a 14-bit pitch bend value
+// (0-16383, centred on PitchBendCenter) and a bend range in semitones (2 by default on
+// most instruments).
+func Bend(note byte, bend uint16, bendRange float64) float64 {
+	offset := float64(int(bend)-PitchBendCenter) / PitchBendCenter
+	return float64(note) + offset*bendRange
+}
@@ -0,0 +1,151 @@
+package ump
+
+import "github.com/leandrodaf/midi/sdk/contracts"
+
+// Controllers MIDI 1.0 uses to carry what MIDI 2.0 sends in single messages.
+const (
+	ccBankMSB = 0   // Bank select MSB.
+	ccBankLSB = 32  // Bank select LSB.
+	ccDataMSB = 6   // Data entry MSB.
+	ccDataLSB = 38  // Data entry LSB.
+	ccNRPNLSB = 98  // Non-registered parameter number LSB.
+	ccNRPNMSB = 99  // Non-registered parameter number MSB.
+	ccRPNLSB  = 100 // Registered parameter number LSB.
+	ccRPNMSB  = 101 // Registered parameter number MSB.
+)
+
+// noteOffVelocity is the velocity of the note off a note on with velocity zero becomes,
+// the center of the MIDI 2.0 range like 64 is of the MIDI 1.0 one.
+const noteOffVelocity = 0x8000
+
+// ScaleUp widens value from srcBits to dstBits bits with the min-center-max scaling of the
+// MIDI 2.0 specification: the minimum, center and maximum of the source range map to
+// those of the destination range, so that ScaleDown restores value exactly.
+//
+// value uint32: The value to scale, below 1<<srcBits.
+// srcBits uint: The resolution of value, at least 2 bits.
+// dstBits uint: The resolution of the result, from srcBits to 32 bits.
+//
+// Returns:
+//   - uint32: The scaled value.
+func ScaleUp(value uint32, srcBits, dstBits uint) uint32 {
+	scaleBits := dstBits - srcBits
+	shifted := value << scaleBits
+	if value <= 1<<(srcBits-1) {
+		return shifted
+	}
+
+	// Above the center, the bits below the sign bit are repeated to fill the low bits, so
+	// the maximum maps to the maximum.
+	repeatBits := srcBits - 1
+	repeat := value & (1<<repeatBits - 1)
+	if scaleBits > repeatBits {
+		repeat <<= scaleBits - repeatBits
+	} else {
+		repeat >>= repeatBits - scaleBits
+	}
+	for repeat != 0 {
+		shifted |= repeat
+		repeat >>= repeatBits
+	}
+	return shifted
+}
+
+// ScaleDown narrows value from srcBits to dstBits bits, inverting ScaleUp.
+func ScaleDown(value uint32, srcBits, dstBits uint) uint32 {
+	return value >> (srcBits - dstBits)
+}
+
+// Upconvert translates a MIDI 1.0 channel voice message to MIDI 2.0, scaling its values to
+// the MIDI 2.0 resolution. A note on with velocity zero becomes a note off. Controllers are
+// translated one by one, so registered and assignable parameters set through controllers
+// 101, 100, 99, 98, 6 and 38 stay control changes. It returns false for system messages.
+func Upconvert(event contracts.MIDI) (contracts.MIDI2Event, bool) {
+	if event.Command < 0x80 || event.Command >= 0xF0 {
+		return contracts.MIDI2Event{}, false
+	}
+	converted := contracts.MIDI2Event{
+		Timestamp:      event.Timestamp,
+		Channel:        event.Channel(),
+		Status:         contracts.MIDI2Status(event.Command >> 4),
+		SourceDeviceID: event.SourceDeviceID,
+	}
+	note, value := event.Note&0x7F, uint32(event.Velocity&0x7F)
+	switch converted.Status {
+	case contracts.MIDI2NoteOn:
+		converted.Note = note
+		if value == 0 {
+			converted.Status, converted.Velocity = contracts.MIDI2NoteOff, noteOffVelocity
+			break
+		}
+		converted.Velocity = uint16(ScaleUp(value, 7, 16))
+	case contracts.MIDI2NoteOff:
+		converted.Note, converted.Velocity = note, uint16(ScaleUp(value, 7, 16))
+	case contracts.MIDI2PolyPressure:
+		converted.Note, converted.Value = note, ScaleUp(value, 7, 32)
+	case contracts.MIDI2ControlChange:
+		converted.Index, converted.Value = note, ScaleUp(value, 7, 32)
+	case contracts.MIDI2ProgramChange:
+		converted.Program = note
+	case contracts.MIDI2ChannelPressure:
+		converted.Value = ScaleUp(uint32(note), 7, 32)
+	case contracts.MIDI2PitchBend:
+		converted.Value = ScaleUp(value<<7|uint32(note), 14, 32)
+	}
+	return converted, true
+}
+
+// Downconvert translates a MIDI 2.0 event to MIDI 1.0, appending the messages to dst and
+// returning the extended slice. A program change with a bank is preceded by bank select
+// controllers, and registered and assignable controllers become their parameter number and
+// data entry controllers. Note ons keep a velocity of at least one, so that they are not
+// taken for note offs. Per-note and relative messages have no MIDI 1.0 equivalent and are
+// dropped.
+func Downconvert(dst []contracts.MIDI, event contracts.MIDI2Event) []contracts.MIDI {
+	message := func(status, data1, data2 byte) contracts.MIDI {
+		return contracts.MIDI{
+			Timestamp:      event.Timestamp,
+			Command:        status | event.Channel&0x0F,
+			Note:           data1 & 0x7F,
+			Velocity:       data2 & 0x7F,
+			SourceDeviceID: event.SourceDeviceID,
+		}
+	}
+	value7 := byte(ScaleDown(event.Value, 32, 7))
+
+	switch event.Status {
+	case contracts.MIDI2NoteOff:
+		return append(dst, message(0x80, event.Note, byte(ScaleDown(uint32(event.Velocity), 16, 7))))
+	case contracts.MIDI2NoteOn:
+		velocity := max(byte(ScaleDown(uint32(event.Velocity), 16, 7)), 1)
+		return append(dst, message(0x90, event.Note, velocity))
+	case contracts.MIDI2PolyPressure:
+		return append(dst, message(0xA0, event.Note, value7))
+	case contracts.MIDI2ControlChange:
+		return append(dst, message(0xB0, event.Index, value7))
+	case contracts.MIDI2ProgramChange:
+		if event.Options&contracts.OptionBankValid != 0 {
+			dst = append(dst,
+				message(0xB0, ccBankMSB, byte(event.Bank>>7)),
+				message(0xB0, ccBankLSB, byte(event.Bank)))
+		}
+		return append(dst, message(0xC0, event.Program, 0))
+	case contracts.MIDI2ChannelPressure:
+		return append(dst, message(0xD0, value7, 0))
+	case contracts.MIDI2PitchBend:
+		bend := ScaleDown(event.Value, 32, 14)
+		return append(dst, message(0xE0, byte(bend), byte(bend>>7)))
+	case contracts.MIDI2RegisteredController, contracts.MIDI2AssignableController:
+		msb, lsb := byte(ccRPNMSB), byte(ccRPNLSB)
+		if event.Status == contracts.MIDI2AssignableController {
+			msb, lsb = ccNRPNMSB, ccNRPNLSB
+		}
+		data := ScaleDown(event.Value, 32, 14)
+		return append(dst,
+			message(0xB0, msb, byte(event.Bank)),
+			message(0xB0, lsb, event.Index),
+			message(0xB0, ccDataMSB, byte(data>>7)),
+			message(0xB0, ccDataLSB, byte(data)))
+	}
+	return dst
+}
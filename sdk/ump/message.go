@@ -0,0 +1,165 @@
+package ump
+
+import "github.com/leandrodaf/midi/sdk/contracts"
+
+// SysEx7 statuses of data 64 packets, telling where a packet falls in a message.
+const (
+	sysExComplete = 0x0 // The whole message fits in the packet.
+	sysExStart    = 0x1 // First packet of a message.
+	sysExContinue = 0x2 // Packet in the middle of a message.
+	sysExEnd      = 0x3 // Last packet of a message.
+)
+
+// sysExPerPacket is the number of SysEx bytes a data 64 packet carries.
+const sysExPerPacket = 6
+
+// header builds the first byte of a packet from its message type and group.
+func header(t MessageType, group byte) uint32 {
+	return uint32(t)<<28 | uint32(group&0x0F)<<24
+}
+
+// MIDI2 returns the MIDI 2.0 channel voice message of a TypeMIDI2 packet as an event,
+// or false for other packets and undefined statuses. The timestamp is left zero.
+func (p Packet) MIDI2() (contracts.MIDI2Event, bool) {
+	if p.Type() != TypeMIDI2 {
+		return contracts.MIDI2Event{}, false
+	}
+	status := contracts.MIDI2Status(p[0]>>20) & 0x0F
+	if status == 0x7 {
+		return contracts.MIDI2Event{}, false
+	}
+	event := contracts.MIDI2Event{
+		Group:   p.Group(),
+		Channel: byte(p[0]>>16) & 0x0F,
+		Status:  status,
+	}
+	data1, data2 := byte(p[0]>>8), byte(p[0])
+	switch status {
+	case contracts.MIDI2NoteOff, contracts.MIDI2NoteOn:
+		event.Note, event.AttributeType = data1&0x7F, data2
+		event.Velocity, event.Attribute = uint16(p[1]>>16), uint16(p[1])
+	case contracts.MIDI2PolyPressure, contracts.MIDI2PerNotePitchBend:
+		event.Note, event.Value = data1&0x7F, p[1]
+	case contracts.MIDI2RegisteredPerNoteController, contracts.MIDI2AssignablePerNoteController:
+		event.Note, event.Index, event.Value = data1&0x7F, data2, p[1]
+	case contracts.MIDI2RegisteredController, contracts.MIDI2AssignableController,
+		contracts.MIDI2RelativeRegistered, contracts.MIDI2RelativeAssignable:
+		event.Bank, event.Index, event.Value = uint16(data1&0x7F), data2&0x7F, p[1]
+	case contracts.MIDI2ControlChange:
+		event.Index, event.Value = data1&0x7F, p[1]
+	case contracts.MIDI2ProgramChange:
+		event.Options = data2
+		event.Program = byte(p[1]>>24) & 0x7F
+		event.Bank = uint16(p[1]>>8)&0x7F<<7 | uint16(p[1])&0x7F
+	case contracts.MIDI2ChannelPressure, contracts.MIDI2PitchBend:
+		event.Value = p[1]
+	case contracts.MIDI2PerNoteManagement:
+		event.Note, event.Options = data1&0x7F, data2
+	}
+	return event, true
+}
+
+// FromMIDI2 encodes a MIDI 2.0 event as a TypeMIDI2 packet. Fields that do not apply to
+// the status of the event are ignored.
+func FromMIDI2(event contracts.MIDI2Event) Packet {
+	var data1, data2 byte
+	var word uint32
+	switch event.Status {
+	case contracts.MIDI2NoteOff, contracts.MIDI2NoteOn:
+		data1, data2 = event.Note&0x7F, event.AttributeType
+		word = uint32(event.Velocity)<<16 | uint32(event.Attribute)
+	case contracts.MIDI2PolyPressure, contracts.MIDI2PerNotePitchBend:
+		data1, word = event.Note&0x7F, event.Value
+	case contracts.MIDI2RegisteredPerNoteController, contracts.MIDI2AssignablePerNoteController:
+		data1, data2, word = event.Note&0x7F, event.Index, event.Value
+	case contracts.MIDI2RegisteredController, contracts.MIDI2AssignableController,
+		contracts.MIDI2RelativeRegistered, contracts.MIDI2RelativeAssignable:
+		data1, data2, word = byte(event.Bank)&0x7F, event.Index&0x7F, event.Value
+	case contracts.MIDI2ControlChange:
+		data1, word = event.Index&0x7F, event.Value
+	case contracts.MIDI2ProgramChange:
+		data2 = event.Options
+		word = uint32(event.Program&0x7F)<<24 | uint32(event.Bank>>7&0x7F)<<8 | uint32(event.Bank&0x7F)
+	case contracts.MIDI2ChannelPressure, contracts.MIDI2PitchBend:
+		word = event.Value
+	case contracts.MIDI2PerNoteManagement:
+		data1, data2 = event.Note&0x7F, event.Options
+	}
+	first := header(TypeMIDI2, event.Group) | uint32(event.Status&0x0F)<<20 | uint32(event.Channel&0x0F)<<16 |
+		uint32(data1)<<8 | uint32(data2)
+	return Packet{first, word}
+}
+
+// MIDI1 returns the MIDI 1.0 message of a TypeMIDI1 or TypeSystem packet, or false for
+// other packets and invalid statuses. The timestamp is left zero.
+func (p Packet) MIDI1() (contracts.MIDI, bool) {
+	status := byte(p[0] >> 16)
+	switch p.Type() {
+	case TypeMIDI1:
+		if status < 0x80 || status >= 0xF0 {
+			return contracts.MIDI{}, false
+		}
+	case TypeSystem:
+		if status <= 0xF0 || status == 0xF7 {
+			return contracts.MIDI{}, false
+		}
+	default:
+		return contracts.MIDI{}, false
+	}
+	return contracts.MIDI{Command: status, Note: byte(p[0]>>8) & 0x7F, Velocity: byte(p[0]) & 0x7F}, true
+}
+
+// FromMIDI1 encodes a MIDI 1.0 channel, system common or real-time message as a packet of
+// the group, or returns false for SysEx and invalid statuses.
+func FromMIDI1(group byte, event contracts.MIDI) (Packet, bool) {
+	t := TypeMIDI1
+	switch {
+	case event.Command < 0x80 || event.Command == 0xF0 || event.Command == 0xF7:
+		return Packet{}, false
+	case event.Command > 0xF0:
+		t = TypeSystem
+	}
+	return Packet{header(t, group) | uint32(event.Command)<<16 | uint32(event.Note&0x7F)<<8 | uint32(event.Velocity&0x7F)}, true
+}
+
+// SysEx7 encodes a SysEx message as the data 64 packets of the group carrying it. The
+// 0xF0 and 0xF7 bytes framing data, if present, are not carried by the packets.
+func SysEx7(group byte, data []byte) []Packet {
+	if len(data) > 0 && data[0] == 0xF0 {
+		data = data[1:]
+	}
+	if len(data) > 0 && data[len(data)-1] == 0xF7 {
+		data = data[:len(data)-1]
+	}
+
+	packets := make([]Packet, 0, max(1, (len(data)+sysExPerPacket-1)/sysExPerPacket))
+	for first := true; first || len(data) > 0; first = false {
+		n := min(len(data), sysExPerPacket)
+		last := n == len(data)
+		status := sysExContinue
+		switch {
+		case first && last:
+			status = sysExComplete
+		case first:
+			status = sysExStart
+		case last:
+			status = sysExEnd
+		}
+
+		var payload [sysExPerPacket]byte
+		copy(payload[:], data[:n])
+		packets = append(packets, Packet{
+			header(TypeData64, group) | uint32(status)<<20 | uint32(n)<<16 | uint32(payload[0]&0x7F)<<8 | uint32(payload[1]&0x7F),
+			uint32(payload[2]&0x7F)<<24 | uint32(payload[3]&0x7F)<<16 | uint32(payload[4]&0x7F)<<8 | uint32(payload[5]&0x7F),
+		})
+		data = data[n:]
+	}
+	return packets
+}
+
+// sysEx7 appends the SysEx bytes a data 64 packet carries to dst.
+func (p Packet) sysEx7(dst []byte) []byte {
+	n := min(int(p[0]>>16)&0x0F, sysExPerPacket)
+	payload := [sysExPerPacket]byte{byte(p[0] >> 8), byte(p[0]), byte(p[1] >> 24), byte(p[1] >> 16), byte(p[1] >> 8), byte(p[1])}
+	return append(dst, payload[:n]...)
+}
@@ -0,0 +1,151 @@
+package ump
+
+import (
+	"fmt"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+)
+
+// maxSysEx is the default bound of the size of a reassembled SysEx message.
+const maxSysEx = 64 * 1024
+
+// Parser splits a stream of words into packets and delivers their messages: MIDI 2.0 and
+// MIDI 1.0 channel voice messages, system messages, and SysEx messages reassembled from
+// data 64 packets, separately for every group. Utility packets and message types without
+// handler are skipped. Malformed packets are reported to OnError. It is not safe for
+// concurrent use.
+type Parser struct {
+	OnMIDI2   func(event contracts.MIDI2Event)       // Called for every MIDI 2.0 channel voice message.
+	OnMIDI1   func(group byte, event contracts.MIDI) // Called for every MIDI 1.0 channel voice and system message.
+	OnSysEx   func(group byte, data []byte)          // Called for every complete SysEx message, including F0 and F7.
+	OnError   func(words []uint32, reason string)    // Called with the words of every malformed packet and why they were discarded.
+	MaxSysEx  int                                    // Longest SysEx message accepted, in bytes, including F0 and F7; 64 KiB when zero.
+	Timestamp uint64                                 // Timestamp given to the delivered events.
+
+	partial [4]uint32  // Words of a packet split across calls to Write.
+	pending int        // Number of words in partial.
+	sysex   [16][]byte // SysEx message being reassembled in every group.
+	skip    [16]bool   // Whether the rest of a message too long is being skipped in every group.
+}
+
+// Write parses words, keeping a packet left incomplete at the end for the next call.
+func (p *Parser) Write(words []uint32) {
+	if p.pending > 0 {
+		size := MessageType(p.partial[0] >> 28).Size()
+		n := copy(p.partial[p.pending:size], words)
+		p.pending += n
+		words = words[n:]
+		if p.pending < size {
+			return
+		}
+		p.pending = 0
+		p.packet(Packet(p.partial))
+	}
+
+	for len(words) > 0 {
+		packet, n, ok := Decode(words)
+		if !ok {
+			p.partial = [4]uint32{}
+			p.pending = copy(p.partial[:], words)
+			return
+		}
+		p.packet(packet)
+		words = words[n:]
+	}
+}
+
+// Reset discards a partial packet and the SysEx messages being reassembled.
+func (p *Parser) Reset() {
+	p.pending = 0
+	for group := range p.sysex {
+		p.sysex[group] = p.sysex[group][:0]
+		p.skip[group] = false
+	}
+}
+
+// packet delivers the message of a complete packet.
+func (p *Parser) packet(packet Packet) {
+	switch packet.Type() {
+	case TypeMIDI2:
+		if p.OnMIDI2 == nil {
+			return
+		}
+		event, ok := packet.MIDI2()
+		if !ok {
+			p.error(packet, "undefined MIDI 2.0 channel voice status")
+			return
+		}
+		event.Timestamp = p.Timestamp
+		p.OnMIDI2(event)
+	case TypeMIDI1, TypeSystem:
+		if p.OnMIDI1 == nil {
+			return
+		}
+		event, ok := packet.MIDI1()
+		if !ok {
+			p.error(packet, fmt.Sprintf("invalid status 0x%02X", byte(packet[0]>>16)))
+			return
+		}
+		event.Timestamp = p.Timestamp
+		p.OnMIDI1(packet.Group(), event)
+	case TypeData64:
+		if p.OnSysEx != nil {
+			p.sysEx7(packet)
+		}
+	}
+}
+
+// sysEx7 adds the bytes of a data 64 packet to the SysEx message of its group, delivering
+// the message when it is complete.
+func (p *Parser) sysEx7(packet Packet) {
+	group := packet.Group()
+	buffer := p.sysex[group]
+	status := byte(packet[0]>>20) & 0x0F
+	switch status {
+	case sysExComplete, sysExStart:
+		p.skip[group] = false
+		if len(buffer) > 0 {
+			p.error(packet, "SysEx start before the end of the previous message")
+		}
+		buffer = append(buffer[:0], 0xF0)
+	case sysExContinue, sysExEnd:
+		if p.skip[group] {
+			p.skip[group] = status == sysExContinue
+			return
+		}
+		if len(buffer) == 0 {
+			p.error(packet, "SysEx continuation without start")
+			return
+		}
+	default:
+		p.error(packet, fmt.Sprintf("invalid SysEx status %d", status))
+		return
+	}
+
+	buffer = packet.sysEx7(buffer)
+	limit := p.MaxSysEx
+	if limit <= 0 {
+		limit = maxSysEx
+	}
+	if len(buffer)+1 > limit {
+		p.sysex[group] = buffer[:0]
+		p.skip[group] = status == sysExStart || status == sysExContinue
+		p.error(packet, fmt.Sprintf("SysEx message longer than %d bytes", limit))
+		return
+	}
+	if status == sysExContinue || status == sysExStart {
+		p.sysex[group] = buffer
+		return
+	}
+
+	p.sysex[group] = buffer[:0]
+	message := append(make([]byte, 0, len(buffer)+1), buffer...)
+	p.OnSysEx(group, append(message, 0xF7))
+}
+
+// error reports a malformed packet to OnError.
+func (p *Parser) error(packet Packet, reason string) {
+	if p.OnError != nil {
+		p.OnError(packet.Words(), reason)
+	}
+}
@@ -0,0 +1,110 @@
+// Package ump reads and writes Universal MIDI Packets, the transport of MIDI 2.0, and
+// converts their channel voice messages between MIDI 2.0 events and MIDI 1.0 messages.
+package ump
+
+import "strconv"
+
+// MessageType identifies the kind of a packet, by the upper nibble of its first word. It
+// also sets the size of the packet.
+type MessageType byte
+
+// Message types of Universal MIDI Packets.
+const (
+	TypeUtility   MessageType = 0x0 // NOOP and jitter reduction timestamps; 32 bits.
+	TypeSystem    MessageType = 0x1 // System common and real-time messages; 32 bits.
+	TypeMIDI1     MessageType = 0x2 // MIDI 1.0 channel voice messages; 32 bits.
+	TypeData64    MessageType = 0x3 // 7-bit SysEx, up to six bytes per packet; 64 bits.
+	TypeMIDI2     MessageType = 0x4 // MIDI 2.0 channel voice messages; 64 bits.
+	TypeData128   MessageType = 0x5 // 8-bit SysEx and mixed data sets; 128 bits.
+	TypeFlexData  MessageType = 0xD // Flex data such as tempo and lyrics; 128 bits.
+	TypeUMPStream MessageType = 0xF // Endpoint and function block discovery; 128 bits.
+)
+
+// sizes holds the number of 32-bit words of packets of each message type.
+var sizes = [16]int{1, 1, 1, 2, 2, 4, 1, 1, 2, 2, 2, 3, 3, 4, 4, 4}
+
+// Size returns the number of 32-bit words of packets of the type, from 1 to 4.
+func (t MessageType) Size() int {
+	return sizes[t&0x0F]
+}
+
+// String names the message type, e.g. "MIDI2" or "Data64".
+func (t MessageType) String() string {
+	switch t {
+	case TypeUtility:
+		return "Utility"
+	case TypeSystem:
+		return "System"
+	case TypeMIDI1:
+		return "MIDI1"
+	case TypeData64:
+		return "Data64"
+	case TypeMIDI2:
+		return "MIDI2"
+	case TypeData128:
+		return "Data128"
+	case TypeFlexData:
+		return "FlexData"
+	case TypeUMPStream:
+		return "UMPStream"
+	default:
+		return "MessageType(" + strconv.Itoa(int(t)) + ")"
+	}
+}
+
+// Packet is a Universal MIDI Packet of one to four 32-bit words, most significant byte
+// first within each word. Words beyond the size of the packet are zero.
+type Packet [4]uint32
+
+// Type returns the message type of the packet.
+func (p Packet) Type() MessageType {
+	return MessageType(p[0] >> 28)
+}
+
+// Group returns the group (0-15) of the packet. Utility and UMP stream packets have none.
+func (p Packet) Group() byte {
+	return byte(p[0]>>24) & 0x0F
+}
+
+// Words returns the words of the packet, as many as its message type uses.
+func (p Packet) Words() []uint32 {
+	return p[:p.Type().Size()]
+}
+
+// Append appends the words of the packet to dst and returns the extended slice.
+func (p Packet) Append(dst []uint32) []uint32 {
+	return append(dst, p.Words()...)
+}
+
+// Decode returns the first packet of words and the number of words it uses. It returns
+// false when words is shorter than the packet its first word announces.
+func Decode(words []uint32) (Packet, int, bool) {
+	var p Packet
+	if len(words) == 0 {
+		return p, 0, false
+	}
+	size := MessageType(words[0] >> 28).Size()
+	if len(words) < size {
+		return p, 0, false
+	}
+	copy(p[:], words[:size])
+	return p, size, true
+}
+
+// AppendBytes appends the words to dst in big-endian order, as files and byte-oriented
+// transports carry them, and returns the extended slice.
+func AppendBytes(dst []byte, words []uint32) []byte {
+	for _, word := range words {
+		dst = append(dst, byte(word>>24), byte(word>>16), byte(word>>8), byte(word))
+	}
+	return dst
+}
+
+// Words converts big-endian bytes to words, ignoring a trailing partial word.
+func Words(data []byte) []uint32 {
+	words := make([]uint32, 0, len(data)/4)
+	for i := 0; i+4 <= len(data); i += 4 {
+		words = append(words, uint32(data[i])<<24|uint32(data[i+1])<<16|uint32(data[i+2])<<8|uint32(data[i+3]))
+	}
+	return words
+}
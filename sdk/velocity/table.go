@@ -0,0 +1,205 @@
+package velocity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidTable is returned for breakpoints or text that do not describe a velocity
+// table.
+var ErrInvalidTable = errors.New("invalid velocity table")
+
+// Table remaps velocities: a velocity v becomes Table[v]. Tables are what
+// contracts.WithVelocityCurve and contracts.WithDeviceVelocityCurve take, to compensate
+// for keybeds that are too light or too heavy as events are captured.
+type Table [MaxVelocity + 1]byte
+
+// Point is a breakpoint of a velocity table, as set in a curve editor: velocity In
+// becomes velocity Out.
+type Point struct {
+	In  byte // Velocity played.
+	Out byte // Velocity it becomes.
+}
+
+// Presets for common keybeds, to be refined with Interpolate or Exponent if needed.
+var (
+	LinearTable = NewTable(Linear)        // Leaves velocities unchanged.
+	LightKeybed = NewTable(Exponent(1.6)) // Lowers velocities, for keybeds giving loud notes for little effort.
+	HeavyKeybed = NewTable(Exponent(0.6)) // Raises velocities, for keybeds needing much effort for loud notes.
+)
+
+// presets names the presets for Preset.
+var presets = map[string]Table{
+	"linear": LinearTable,
+	"light":  LightKeybed,
+	"heavy":  HeavyKeybed,
+}
+
+// Preset returns the preset table of a name: "linear", "light" or "heavy", e.g. as set in
+// a configuration file.
+//
+// name string: The name of the preset, in any case.
+//
+// Returns:
+//   - Table: The preset table.
+//   - bool: false if there is no preset of that name.
+func Preset(name string) (Table, bool) {
+	table, ok := presets[strings.ToLower(strings.TrimSpace(name))]
+	return table, ok
+}
+
+// NewTable returns the table remapping velocities with curve: a velocity becomes the gain
+// curve returns for it, scaled to MaxVelocity and rounded. Velocity 0 stays 0 and the
+// others become at least 1, so note ons are not turned into note offs. NewTable(Linear)
+// leaves velocities unchanged and NewTable(Exponent(e)) lowers them for exponents above 1
+// and raises them below.
+//
+// curve Curve: The velocity curve.
+//
+// Returns:
+//   - Table: The velocity table.
+func NewTable(curve Curve) Table {
+	var t Table
+	for v := 1; v <= MaxVelocity; v++ {
+		t[v] = clamp(curve(byte(v)) * MaxVelocity)
+	}
+	return t
+}
+
+// Interpolate returns the table joining breakpoints with straight lines, the way curve
+// editors draw velocity curves. The curve starts at 0:0 and ends at 127:127 unless
+// breakpoints set velocities 0 and 127. Velocity 0 stays 0 and the others become at
+// least 1, so note ons are not turned into note offs.
+//
+// points ...Point: The breakpoints, in any order.
+//
+// Returns:
+//   - Table: The velocity table.
+//   - error: ErrInvalidTable if a velocity is above MaxVelocity or set twice.
+func Interpolate(points ...Point) (Table, error) {
+	sorted := slices.SortedFunc(slices.Values(points), func(a, b Point) int { return int(a.In) - int(b.In) })
+	for i, p := range sorted {
+		if p.In > MaxVelocity || p.Out > MaxVelocity {
+			return Table{}, fmt.Errorf("%w: point %d:%d is above %d", ErrInvalidTable, p.In, p.Out, MaxVelocity)
+		}
+		if i > 0 && sorted[i-1].In == p.In {
+			return Table{}, fmt.Errorf("%w: velocity %d is set twice", ErrInvalidTable, p.In)
+		}
+	}
+	if len(sorted) == 0 || sorted[0].In > 0 {
+		sorted = slices.Insert(sorted, 0, Point{0, 0})
+	}
+	if sorted[len(sorted)-1].In < MaxVelocity {
+		sorted = append(sorted, Point{MaxVelocity, MaxVelocity})
+	}
+
+	var t Table
+	next := 1
+	for v := 1; v <= MaxVelocity; v++ {
+		for int(sorted[next].In) < v {
+			next++
+		}
+		a, b := sorted[next-1], sorted[next]
+		position := float64(v-int(a.In)) / float64(int(b.In)-int(a.In))
+		t[v] = clamp(float64(a.Out) + position*(float64(b.Out)-float64(a.Out)))
+	}
+	return t, nil
+}
+
+// ParseTable returns the table described by text, as saved by a curve editor or written in
+// a configuration file: the name of a preset, the 128 velocities of a table, or
+// breakpoints for Interpolate written "in:out", such as "0:0 64:40 127:127". Values are
+// separated by spaces, commas or new lines.
+//
+// text string: The description of the table.
+//
+// Returns:
+//   - Table: The velocity table.
+//   - error: ErrInvalidTable if text describes no table.
+func ParseTable(text string) (Table, error) {
+	if table, ok := Preset(text); ok {
+		return table, nil
+	}
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	if len(fields) == 0 {
+		return Table{}, fmt.Errorf("%w: no values", ErrInvalidTable)
+	}
+	if !strings.Contains(text, ":") {
+		if len(fields) != len(Table{}) {
+			return Table{}, fmt.Errorf("%w: %d values instead of %d", ErrInvalidTable, len(fields), len(Table{}))
+		}
+		var t Table
+		for v, field := range fields {
+			value, err := parseVelocity(field)
+			if err != nil {
+				return Table{}, err
+			}
+			t[v] = value
+		}
+		return t, nil
+	}
+	points := make([]Point, 0, len(fields))
+	for _, field := range fields {
+		in, out, ok := strings.Cut(field, ":")
+		if !ok {
+			return Table{}, fmt.Errorf("%w: %q is not a point", ErrInvalidTable, field)
+		}
+		var p Point
+		var err error
+		if p.In, err = parseVelocity(in); err != nil {
+			return Table{}, err
+		}
+		if p.Out, err = parseVelocity(out); err != nil {
+			return Table{}, err
+		}
+		points = append(points, p)
+	}
+	return Interpolate(points...)
+}
+
+// parseVelocity parses a velocity from 0 to MaxVelocity.
+func parseVelocity(text string) (byte, error) {
+	value, err := strconv.Atoi(text)
+	if err != nil || value < 0 || value > MaxVelocity {
+		return 0, fmt.Errorf("%w: %q is not a velocity", ErrInvalidTable, text)
+	}
+	return byte(value), nil
+}
+
+// Apply returns the velocity velocity becomes; velocities above MaxVelocity are treated
+// as MaxVelocity.
+func (t Table) Apply(velocity byte) byte {
+	return t[min(velocity, MaxVelocity)]
+}
+
+// Curve returns the table as a curve, giving the gain of the velocity a velocity becomes,
+// e.g. to shape the gain of an audio consumer the same way.
+func (t Table) Curve() Curve {
+	return func(velocity byte) float64 {
+		return Linear(t.Apply(velocity))
+	}
+}
+
+// String returns the 128 velocities of the table separated by spaces, as read by
+// ParseTable.
+func (t Table) String() string {
+	var b strings.Builder
+	for v, value := range t {
+		if v > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.Itoa(int(value)))
+	}
+	return b.String()
+}
+
+// clamp rounds velocity and limits it to 1-MaxVelocity.
+func clamp(velocity float64) byte {
+	return byte(min(max(math.Round(velocity), 1), MaxVelocity))
+}
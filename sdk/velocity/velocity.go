@@ -0,0 +1,79 @@
+// Package velocity converts MIDI note velocities into the gain an audio consumer should
+// apply, using the response curves defined by the MIDI specifications or a custom exponent.
+// Tables remap velocities themselves, to compensate for a keybed that is too light or too
+// heavy, from curves, curve editor breakpoints or presets; the client applies them to
+// captured note ons with contracts.WithVelocityCurve:
+//
+//	table, err := velocity.Interpolate(velocity.Point{In: 64, Out: 40}, velocity.Point{In: 100, Out: 90})
+//	client, err := midi.NewMIDIClient(
+//		contracts.WithVelocityCurve(table),
+//		contracts.WithDeviceVelocityCurve("Keystation 49", velocity.LightKeybed),
+//	)
+package velocity
+
+import "math"
+
+// MaxVelocity is the highest MIDI velocity.
+const MaxVelocity = 127
+
+// DLSRange is the attenuation range, in dB, of the DLS and SoundFont velocity curve.
+const DLSRange = 96.0
+
+// Curve converts a MIDI velocity into a linear gain between 0 and 1. Velocities above
+// MaxVelocity are treated as MaxVelocity, and velocity 0 always yields a gain of 0.
+type Curve func(velocity byte) float64
+
+// Linear maps velocity proportionally to amplitude: 20·log10(v/127) dB.
+func Linear(velocity byte) float64 {
+	return normalize(velocity)
+}
+
+// GM is the response recommended by General MIDI 2: amplitude proportional to the
+// square of velocity, 40·log10(v/127) dB.
+func GM(velocity byte) float64 {
+	v := normalize(velocity)
+	return v * v
+}
+
+// DLS is the default velocity-to-attenuation curve of DLS and SoundFont 2 synthesizers:
+// the concave transform over a 96 dB range. It matches GM, except that the attenuation
+// never exceeds DLSRange.
+func DLS(velocity byte) float64 {
+	if velocity == 0 {
+		return 0
+	}
+	return FromDecibels(max(Decibels(GM(velocity)), -DLSRange))
+}
+
+// Exponent returns a curve with amplitude proportional to velocity raised to exponent.
+// Exponent 1 is Linear and 2 is GM; larger exponents widen the dynamic range.
+//
+// exponent float64: The power applied to the normalised velocity; must be positive.
+//
+// Returns:
+//   - Curve: The velocity curve.
+func Exponent(exponent float64) Curve {
+	return func(velocity byte) float64 {
+		return math.Pow(normalize(velocity), exponent)
+	}
+}
+
+// Decibels converts velocity into a gain in dB using the curve; velocity 0 yields -Inf.
+func (c Curve) Decibels(velocity byte) float64 {
+	return Decibels(c(velocity))
+}
+
+// Decibels converts a linear gain into dB. A gain of 0 yields -Inf.
+func Decibels(gain float64) float64 {
+	return 20 * math.Log10(gain)
+}
+
+// FromDecibels converts a gain in dB into a linear gain.
+func FromDecibels(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// normalize maps velocity to [0, 1].
+func normalize(velocity byte) float64 {
+	return float64(min(velocity, MaxVelocity)) / MaxVelocity
+}
@@ -0,0 +1,172 @@
+// Package korg builds and parses the SysEx messages of Korg instruments and converts
+// between 8-bit data and the 7-bit packing Korg data dumps use, in which every group of
+// seven data bytes is preceded by a byte holding their most significant bits.
+package korg
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ManufacturerID is the SysEx manufacturer ID of Korg.
+const ManufacturerID = 0x42
+
+// formatID is the high nibble of the byte following the manufacturer ID; the low nibble
+// is the global MIDI channel of the instrument.
+const formatID = 0x30
+
+// Function IDs shared by most Korg instruments.
+const (
+	FunctionCurrentProgramRequest = 0x10 // Requests the edit buffer.
+	FunctionProgramRequest        = 0x1C // Requests the stored programs.
+	FunctionGlobalRequest         = 0x0E // Requests the global settings.
+	FunctionWriteCompleted        = 0x21 // Acknowledges a program write.
+	FunctionWriteError            = 0x22 // Reports a failed program write.
+	FunctionLoadCompleted         = 0x23 // Acknowledges a received data dump.
+	FunctionLoadError             = 0x24 // Reports a rejected data dump.
+	FunctionCurrentProgram        = 0x40 // Edit buffer dump.
+	FunctionParameterChange       = 0x41 // Parameter change.
+	FunctionProgram               = 0x4C // Stored programs dump.
+	FunctionGlobal                = 0x51 // Global settings dump.
+)
+
+// Errors returned by the parsers.
+var (
+	ErrInvalidMessage = errors.New("not a Korg message")
+	ErrInvalidPacking = errors.New("invalid Korg 7-bit packed data")
+)
+
+// Model identifies the instrument a message addresses.
+type Model struct {
+	ID []byte // Model ID: one byte on older models, or 00 followed by a family and member code.
+}
+
+// Common models.
+var (
+	M1          = Model{ID: []byte{0x19}}
+	Wavestation = Model{ID: []byte{0x28}}
+	Triton      = Model{ID: []byte{0x50}}
+	MS2000      = Model{ID: []byte{0x58}} // MS2000 and microKORG.
+	Minilogue   = Model{ID: []byte{0x00, 0x01, 0x2C}}
+	Monologue   = Model{ID: []byte{0x00, 0x01, 0x44}}
+	Prologue    = Model{ID: []byte{0x00, 0x01, 0x4B}}
+)
+
+// Message is a parsed Korg message.
+type Message struct {
+	Channel  byte   // Global MIDI channel of the instrument, 0 to 15.
+	Function byte   // Function ID, e.g. FunctionCurrentProgram.
+	Data     []byte // Bytes following the function ID, still packed for data dumps.
+}
+
+// Message builds a message with the given function and 7-bit data.
+//
+// channel byte: The global MIDI channel of the instrument, 0 to 15.
+// function byte: The function ID, e.g. FunctionCurrentProgramRequest.
+// data []byte: The 7-bit bytes following the function ID.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0 and F7.
+func (m Model) Message(channel, function byte, data []byte) []byte {
+	msg := make([]byte, 0, 5+len(m.ID)+len(data))
+	msg = append(msg, 0xF0, ManufacturerID, formatID|channel&0x0F)
+	msg = append(msg, m.ID...)
+	msg = append(msg, function)
+	msg = append(msg, data...)
+	return append(msg, 0xF7)
+}
+
+// DataDump builds a data dump with the given function, packing 8-bit data into 7-bit bytes.
+//
+// channel byte: The global MIDI channel of the instrument, 0 to 15.
+// function byte: The function ID, e.g. FunctionCurrentProgram.
+// data []byte: The 8-bit data, e.g. a program.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0 and F7.
+func (m Model) DataDump(channel, function byte, data []byte) []byte {
+	return m.Message(channel, function, Pack(data))
+}
+
+// ParameterChange builds a parameter change in the format of the MS2000, microKORG and
+// Triton families: the parameter ID and the value as 14-bit numbers, least significant
+// 7 bits first.
+//
+// channel byte: The global MIDI channel of the instrument, 0 to 15.
+// parameter int: The parameter ID, 0 to 16383.
+// value int: The value, -8192 to 16383; negative values are sent in two's complement.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0 and F7.
+func (m Model) ParameterChange(channel byte, parameter, value int) []byte {
+	return m.Message(channel, FunctionParameterChange, []byte{
+		byte(parameter) & 0x7F, byte(parameter>>7) & 0x7F,
+		byte(value) & 0x7F, byte(value>>7) & 0x7F,
+	})
+}
+
+// Parse parses a message of the model.
+//
+// data []byte: The complete SysEx message, including F0 and F7.
+//
+// Returns:
+//   - Message: The parsed message; data dumps can be unpacked with Unpack.
+//   - error: ErrInvalidMessage if data is not a message of the model.
+func (m Model) Parse(data []byte) (Message, error) {
+	header := 3 + len(m.ID) // F0, manufacturer, format and channel, model ID.
+	if len(data) < header+2 || data[0] != 0xF0 || data[len(data)-1] != 0xF7 ||
+		data[1] != ManufacturerID || data[2]&0xF0 != formatID || !bytes.Equal(data[3:header], m.ID) {
+		return Message{}, ErrInvalidMessage
+	}
+	return Message{
+		Channel:  data[2] & 0x0F,
+		Function: data[header],
+		Data:     append([]byte(nil), data[header+1:len(data)-1]...),
+	}, nil
+}
+
+// Pack converts 8-bit data to Korg 7-bit packing: every group of up to seven bytes is
+// preceded by a byte whose bit i holds the most significant bit of byte i of the group.
+func Pack(data []byte) []byte {
+	packed := make([]byte, 0, len(data)+(len(data)+6)/7)
+	for start := 0; start < len(data); start += 7 {
+		group := data[start:min(start+7, len(data))]
+		var msbs byte
+		for i, b := range group {
+			msbs |= (b >> 7) << i
+		}
+		packed = append(packed, msbs)
+		for _, b := range group {
+			packed = append(packed, b&0x7F)
+		}
+	}
+	return packed
+}
+
+// Unpack converts Korg 7-bit packed data back to 8-bit data.
+//
+// packed []byte: The packed bytes, e.g. the Data of a parsed data dump.
+//
+// Returns:
+//   - []byte: The 8-bit data.
+//   - error: ErrInvalidPacking if a byte has its most significant bit set or a group
+//     header is not followed by any data.
+func Unpack(packed []byte) ([]byte, error) {
+	data := make([]byte, 0, len(packed)-len(packed)/8)
+	for start := 0; start < len(packed); start += 8 {
+		group := packed[start:min(start+8, len(packed))]
+		if len(group) < 2 {
+			return nil, ErrInvalidPacking
+		}
+		msbs := group[0]
+		for i, b := range group {
+			if b > 0x7F {
+				return nil, ErrInvalidPacking
+			}
+			if i > 0 {
+				data = append(data, b|(msbs>>(i-1)&1)<<7)
+			}
+		}
+	}
+	return data, nil
+}
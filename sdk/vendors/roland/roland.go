@@ -0,0 +1,206 @@
+// Package roland builds and parses the address-based SysEx messages of Roland instruments:
+// DT1 (data set 1) writes data at an address of the instrument's parameter map and RQ1
+// (data request 1) asks the instrument to send the data at an address back as DT1.
+//
+// Addresses and sizes are written as in Roland manuals, one 7-bit byte per hexadecimal
+// byte pair: 0x40007F is the three-byte address 40 00 7F.
+package roland
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ManufacturerID is the SysEx manufacturer ID of Roland.
+const ManufacturerID = 0x41
+
+// Command IDs following the model ID.
+const (
+	CommandRQ1 = 0x11 // Data request 1.
+	CommandDT1 = 0x12 // Data set 1.
+)
+
+// Device IDs.
+const (
+	DefaultDevice   = 0x10 // Device ID 17, the factory setting of most instruments.
+	BroadcastDevice = 0x7F // Addresses every device.
+)
+
+// Errors returned by Parse.
+var (
+	ErrInvalidMessage = errors.New("not a Roland DT1 or RQ1 message")
+	ErrChecksum       = errors.New("Roland checksum mismatch")
+)
+
+// Model identifies the parameter map a message addresses.
+type Model struct {
+	ID          []byte // Model ID, one to four bytes.
+	AddressSize int    // Bytes of addresses and sizes, usually 3 or 4.
+}
+
+// Common models.
+var (
+	GS       = Model{ID: []byte{0x42}, AddressSize: 3}                   // GS sound modules (SC-55, SC-88 and successors).
+	D50      = Model{ID: []byte{0x14}, AddressSize: 3}                   // D-50 and D-550.
+	JV1080   = Model{ID: []byte{0x6A}, AddressSize: 4}                   // JV-1080, JV-2080 and XP series.
+	Integra7 = Model{ID: []byte{0x00, 0x00, 0x64}, AddressSize: 4}       // INTEGRA-7.
+	JDXi     = Model{ID: []byte{0x00, 0x00, 0x00, 0x0E}, AddressSize: 4} // JD-Xi.
+)
+
+// Message is a parsed DT1 or RQ1 message.
+type Message struct {
+	Device  byte   // Device ID.
+	Command byte   // CommandDT1 or CommandRQ1.
+	Address uint32 // Address, in Roland notation.
+	Data    []byte // Data of a DT1 message.
+	Size    uint32 // Requested size of an RQ1 message, in Roland notation.
+}
+
+// Checksum returns the Roland checksum of address and data bytes: the value that makes the
+// sum of all the bytes and the checksum a multiple of 128.
+func Checksum(data ...[]byte) byte {
+	var sum int
+	for _, part := range data {
+		for _, b := range part {
+			sum += int(b)
+		}
+	}
+	return byte((128 - sum%128) % 128)
+}
+
+// DT1 builds a data set message writing data at address.
+//
+// device byte: The device ID, e.g. DefaultDevice.
+// address uint32: The address of the first byte, in Roland notation.
+// data []byte: The 7-bit data bytes.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0, the checksum and F7.
+func (m Model) DT1(device byte, address uint32, data []byte) []byte {
+	return m.message(device, CommandDT1, m.encode(address), data)
+}
+
+// DT1Chunks builds the data set messages writing data at address, split into messages of
+// at most size data bytes, as instruments limit the length of a DT1 message (often to 128
+// or 256 bytes). The addresses of the chunks follow the 7-bit address arithmetic.
+//
+// device byte: The device ID, e.g. DefaultDevice.
+// address uint32: The address of the first byte, in Roland notation.
+// data []byte: The 7-bit data bytes.
+// size int: The maximum number of data bytes per message.
+//
+// Returns:
+//   - [][]byte: The complete SysEx messages, in address order.
+func (m Model) DT1Chunks(device byte, address uint32, data []byte, size int) [][]byte {
+	size = max(size, 1)
+	var messages [][]byte
+	for offset := 0; offset < len(data); offset += size {
+		chunk := data[offset:min(offset+size, len(data))]
+		messages = append(messages, m.DT1(device, Offset(address, offset), chunk))
+	}
+	return messages
+}
+
+// RQ1 builds a data request message asking for size bytes starting at address.
+//
+// device byte: The device ID, e.g. DefaultDevice.
+// address uint32: The address of the first byte, in Roland notation.
+// size uint32: The number of bytes, in Roland notation: 0x000100 requests 128 bytes.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0, the checksum and F7.
+func (m Model) RQ1(device byte, address, size uint32) []byte {
+	return m.message(device, CommandRQ1, m.encode(address), m.encode(size))
+}
+
+// Parse parses a DT1 or RQ1 message of the model and verifies its checksum.
+//
+// data []byte: The complete SysEx message, including F0 and F7.
+//
+// Returns:
+//   - Message: The parsed message.
+//   - error: ErrInvalidMessage if data is not a DT1 or RQ1 message of the model, or ErrChecksum.
+func (m Model) Parse(data []byte) (Message, error) {
+	header := 3 + len(m.ID) + 1 // F0, manufacturer, device, model ID and command.
+	if len(data) < header+m.AddressSize+2 || data[0] != 0xF0 || data[len(data)-1] != 0xF7 ||
+		data[1] != ManufacturerID || !bytes.Equal(data[3:3+len(m.ID)], m.ID) {
+		return Message{}, ErrInvalidMessage
+	}
+
+	msg := Message{Device: data[2], Command: data[header-1]}
+	body := data[header : len(data)-2]
+	if Checksum(body) != data[len(data)-2] {
+		return Message{}, ErrChecksum
+	}
+	msg.Address = decode(body[:m.AddressSize])
+	body = body[m.AddressSize:]
+
+	switch msg.Command {
+	case CommandDT1:
+		msg.Data = append([]byte(nil), body...)
+	case CommandRQ1:
+		if len(body) != m.AddressSize {
+			return Message{}, fmt.Errorf("%w: RQ1 size of %d bytes", ErrInvalidMessage, len(body))
+		}
+		msg.Size = decode(body)
+	default:
+		return Message{}, fmt.Errorf("%w: command 0x%02X", ErrInvalidMessage, msg.Command)
+	}
+	return msg, nil
+}
+
+// Offset adds n bytes to address with the 7-bit carry of Roland addresses: Offset(0x40007F, 1)
+// is 0x400100.
+func Offset(address uint32, n int) uint32 {
+	return fromLinear(toLinear(address) + uint64(n))
+}
+
+// message builds a message with the checksum of its address and body.
+func (m Model) message(device, command byte, address, body []byte) []byte {
+	msg := make([]byte, 0, 4+len(m.ID)+len(address)+len(body)+2)
+	msg = append(msg, 0xF0, ManufacturerID, device)
+	msg = append(msg, m.ID...)
+	msg = append(msg, command)
+	msg = append(msg, address...)
+	msg = append(msg, body...)
+	return append(msg, Checksum(address, body), 0xF7)
+}
+
+// encode returns the AddressSize bytes of a value in Roland notation.
+func (m Model) encode(value uint32) []byte {
+	encoded := make([]byte, m.AddressSize)
+	for i := m.AddressSize - 1; i >= 0; i-- {
+		encoded[i] = byte(value) & 0x7F
+		value >>= 8
+	}
+	return encoded
+}
+
+// decode returns the value in Roland notation of address or size bytes.
+func decode(encoded []byte) uint32 {
+	var value uint32
+	for _, b := range encoded {
+		value = value<<8 | uint32(b&0x7F)
+	}
+	return value
+}
+
+// toLinear converts a value in Roland notation to a plain number of 7-bit digits.
+func toLinear(value uint32) uint64 {
+	var linear uint64
+	for shift := 24; shift >= 0; shift -= 8 {
+		linear = linear<<7 | uint64(value>>shift&0x7F)
+	}
+	return linear
+}
+
+// fromLinear converts a plain number of 7-bit digits to Roland notation.
+func fromLinear(linear uint64) uint32 {
+	var value uint32
+	for shift := 0; shift <= 24; shift += 8 {
+		value |= uint32(linear&0x7F) << shift
+		linear >>= 7
+	}
+	return value
+}
@@ -0,0 +1,258 @@
+// Package yamaha builds and parses the SysEx messages of Yamaha instruments: the
+// address-based parameter changes, bulk dumps and requests of XG modules and later
+// instruments, and the format-based bulk dumps and parameter changes of the DX7 era.
+//
+// Addresses are written as in Yamaha manuals, one 7-bit byte per hexadecimal byte pair:
+// 0x08_00_07 is the three-byte address 08 00 07.
+package yamaha
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ManufacturerID is the SysEx manufacturer ID of Yamaha.
+const ManufacturerID = 0x43
+
+// Message types, in the high nibble of the byte following the manufacturer ID. The low
+// nibble is the device number, 0 to 15.
+const (
+	TypeBulkDump         = 0x00
+	TypeParameterChange  = 0x10
+	TypeBulkRequest      = 0x20
+	TypeParameterRequest = 0x30
+)
+
+// Errors returned by the parsers.
+var (
+	ErrInvalidMessage = errors.New("not a Yamaha message")
+	ErrChecksum       = errors.New("Yamaha checksum mismatch")
+)
+
+// Model identifies the parameter map of address-based messages.
+type Model struct {
+	ID          []byte // Model ID, e.g. 4C for XG.
+	AddressSize int    // Bytes of addresses, usually 3.
+}
+
+// Common models.
+var (
+	XG    = Model{ID: []byte{0x4C}, AddressSize: 3} // XG sound modules and instruments in XG mode.
+	TG300 = Model{ID: []byte{0x2B}, AddressSize: 3} // TG300 and the TG300B mode of early XG modules.
+)
+
+// DX7 bulk dump formats.
+const (
+	FormatVoice     = 0x00 // One voice, 155 bytes.
+	FormatPerform   = 0x01 // Performance data of the TX7, 94 bytes.
+	FormatVoiceBank = 0x09 // 32 packed voices, 4096 bytes.
+)
+
+// DX7 parameter groups.
+const (
+	GroupVoice    = 0 // Voice parameters 0 to 155.
+	GroupFunction = 2 // Function parameters 64 to 77.
+)
+
+// Message is a parsed address-based message.
+type Message struct {
+	Type    byte   // TypeBulkDump, TypeParameterChange, TypeBulkRequest or TypeParameterRequest.
+	Device  byte   // Device number, 0 to 15.
+	Address uint32 // Address, in Yamaha notation.
+	Data    []byte // Data of a bulk dump or parameter change.
+}
+
+// Checksum returns the Yamaha checksum of the given bytes: the value that makes the sum of
+// all the bytes and the checksum a multiple of 128.
+func Checksum(data ...[]byte) byte {
+	var sum int
+	for _, part := range data {
+		for _, b := range part {
+			sum += int(b)
+		}
+	}
+	return byte((128 - sum%128) % 128)
+}
+
+// ParameterChange builds a message setting the parameter at address to data; parameters
+// of more than 7 bits take several data bytes.
+//
+// device byte: The device number, 0 to 15.
+// address uint32: The address of the parameter, in Yamaha notation.
+// data []byte: The 7-bit data bytes.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0 and F7.
+func (m Model) ParameterChange(device byte, address uint32, data []byte) []byte {
+	return m.message(TypeParameterChange|device&0x0F, m.encode(address), data)
+}
+
+// ParameterRequest builds a message asking for the parameter at address, answered with a
+// parameter change.
+//
+// device byte: The device number, 0 to 15.
+// address uint32: The address of the parameter, in Yamaha notation.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0 and F7.
+func (m Model) ParameterRequest(device byte, address uint32) []byte {
+	return m.message(TypeParameterRequest|device&0x0F, m.encode(address), nil)
+}
+
+// BulkDump builds a bulk dump of data starting at address. The byte count, address and
+// data are covered by the checksum.
+//
+// device byte: The device number, 0 to 15.
+// address uint32: The address of the first byte, in Yamaha notation.
+// data []byte: The 7-bit data bytes, at most 16383.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0, the checksum and F7.
+func (m Model) BulkDump(device byte, address uint32, data []byte) []byte {
+	count := []byte{byte(len(data)>>7) & 0x7F, byte(len(data)) & 0x7F}
+	addr := m.encode(address)
+	body := append(append(append([]byte(nil), count...), addr...), data...)
+	return m.message(TypeBulkDump|device&0x0F, body, []byte{Checksum(body)})
+}
+
+// BulkRequest builds a message asking for the block at address, answered with a bulk dump.
+//
+// device byte: The device number, 0 to 15.
+// address uint32: The address of the block, in Yamaha notation.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0 and F7.
+func (m Model) BulkRequest(device byte, address uint32) []byte {
+	return m.message(TypeBulkRequest|device&0x0F, m.encode(address), nil)
+}
+
+// Parse parses an address-based message of the model, verifying the checksum of bulk dumps.
+//
+// data []byte: The complete SysEx message, including F0 and F7.
+//
+// Returns:
+//   - Message: The parsed message.
+//   - error: ErrInvalidMessage if data is not a message of the model, or ErrChecksum.
+func (m Model) Parse(data []byte) (Message, error) {
+	header := 3 + len(m.ID) // F0, manufacturer, type and device, model ID.
+	if len(data) < header+m.AddressSize+1 || data[0] != 0xF0 || data[len(data)-1] != 0xF7 ||
+		data[1] != ManufacturerID || !bytes.Equal(data[3:header], m.ID) {
+		return Message{}, ErrInvalidMessage
+	}
+
+	msg := Message{Type: data[2] & 0x70, Device: data[2] & 0x0F}
+	body := data[header : len(data)-1]
+	if msg.Type == TypeBulkDump {
+		if len(body) < 2+m.AddressSize+1 {
+			return Message{}, ErrInvalidMessage
+		}
+		if Checksum(body[:len(body)-1]) != body[len(body)-1] {
+			return Message{}, ErrChecksum
+		}
+		count := int(body[0])<<7 | int(body[1])
+		body = body[2 : len(body)-1]
+		if len(body) != m.AddressSize+count {
+			return Message{}, fmt.Errorf("%w: byte count %d for %d data bytes", ErrInvalidMessage, count, len(body)-m.AddressSize)
+		}
+	}
+	if len(body) < m.AddressSize {
+		return Message{}, ErrInvalidMessage
+	}
+
+	msg.Address = decode(body[:m.AddressSize])
+	switch msg.Type {
+	case TypeBulkDump, TypeParameterChange:
+		msg.Data = append([]byte(nil), body[m.AddressSize:]...)
+	case TypeBulkRequest, TypeParameterRequest:
+	default:
+		return Message{}, fmt.Errorf("%w: type 0x%02X", ErrInvalidMessage, msg.Type)
+	}
+	return msg, nil
+}
+
+// FormatBulkDump builds a DX7-era bulk dump: a format number and a byte count followed by
+// the data and a checksum of the data alone.
+//
+// device byte: The device number, 0 to 15.
+// format byte: The data format, e.g. FormatVoice.
+// data []byte: The 7-bit data bytes, at most 16383.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0, the checksum and F7.
+func FormatBulkDump(device, format byte, data []byte) []byte {
+	msg := make([]byte, 0, len(data)+8)
+	msg = append(msg, 0xF0, ManufacturerID, TypeBulkDump|device&0x0F, format, byte(len(data)>>7)&0x7F, byte(len(data))&0x7F)
+	msg = append(msg, data...)
+	return append(msg, Checksum(data), 0xF7)
+}
+
+// ParseFormatBulkDump parses a DX7-era bulk dump and verifies its checksum.
+//
+// data []byte: The complete SysEx message, including F0 and F7.
+//
+// Returns:
+//   - byte: The device number.
+//   - byte: The data format.
+//   - []byte: The data bytes.
+//   - error: ErrInvalidMessage if data is not a format-based bulk dump, or ErrChecksum.
+func ParseFormatBulkDump(data []byte) (device, format byte, payload []byte, err error) {
+	if len(data) < 8 || data[0] != 0xF0 || data[len(data)-1] != 0xF7 || data[1] != ManufacturerID || data[2]&0x70 != TypeBulkDump {
+		return 0, 0, nil, ErrInvalidMessage
+	}
+	count := int(data[4])<<7 | int(data[5])
+	payload = data[6 : len(data)-2]
+	if len(payload) != count {
+		return 0, 0, nil, fmt.Errorf("%w: byte count %d for %d data bytes", ErrInvalidMessage, count, len(payload))
+	}
+	if Checksum(payload) != data[len(data)-2] {
+		return 0, 0, nil, ErrChecksum
+	}
+	return data[2] & 0x0F, data[3], append([]byte(nil), payload...), nil
+}
+
+// DX7ParameterChange builds a DX7-era parameter change.
+//
+// device byte: The device number, 0 to 15.
+// group byte: The parameter group, e.g. GroupVoice.
+// parameter int: The parameter number within the group, 0 to 511.
+// value byte: The 7-bit value.
+//
+// Returns:
+//   - []byte: The complete SysEx message, including F0 and F7.
+func DX7ParameterChange(device, group byte, parameter int, value byte) []byte {
+	return []byte{
+		0xF0, ManufacturerID, TypeParameterChange | device&0x0F,
+		(group&0x1F)<<2 | byte(parameter>>7)&0x03, byte(parameter) & 0x7F, value & 0x7F,
+		0xF7,
+	}
+}
+
+// message builds an address-based message from its type and device byte and body.
+func (m Model) message(typeDevice byte, body, trailer []byte) []byte {
+	msg := make([]byte, 0, 4+len(m.ID)+len(body)+len(trailer))
+	msg = append(msg, 0xF0, ManufacturerID, typeDevice)
+	msg = append(msg, m.ID...)
+	msg = append(msg, body...)
+	msg = append(msg, trailer...)
+	return append(msg, 0xF7)
+}
+
+// encode returns the AddressSize bytes of an address in Yamaha notation.
+func (m Model) encode(address uint32) []byte {
+	encoded := make([]byte, m.AddressSize)
+	for i := m.AddressSize - 1; i >= 0; i-- {
+		encoded[i] = byte(address) & 0x7F
+		address >>= 8
+	}
+	return encoded
+}
+
+// decode returns the address in Yamaha notation of address bytes.
+func decode(encoded []byte) uint32 {
+	var address uint32
+	for _, b := range encoded {
+		address = address<<8 | uint32(b&0x7F)
+	}
+	return address
+}
@@ -0,0 +1,151 @@
+package wire
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// maxMessageSize bounds the length prefix of Protobuf messages read by a Decoder. Events
+// take at most 26 bytes; the margin leaves room for fields of later versions.
+const maxMessageSize = 1 << 16
+
+// Encoder writes a stream of events in a format: JSON objects one per line, or Protobuf
+// messages each preceded by its length as a varint, as read by Decoder and by the
+// delimited readers of Protocol Buffers libraries. Every event is written to w with a
+// single call. It implements sink.Sink and is not safe for concurrent use.
+type Encoder struct {
+	w      io.Writer
+	format Format
+	buf    []byte
+}
+
+// NewEncoder creates an encoder writing to w.
+//
+// w io.Writer: The destination, such as a connection or a file; wrap it in a bufio.Writer to batch writes.
+// format Format: The encoding of the events.
+//
+// Returns:
+//   - *Encoder: The encoder, ready to write events.
+func NewEncoder(w io.Writer, format Format) *Encoder {
+	return &Encoder{w: w, format: format}
+}
+
+// Write encodes event and writes it to the stream.
+func (e *Encoder) Write(event contracts.MIDI) error {
+	e.buf = e.buf[:0]
+	switch e.format {
+	case JSON:
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		e.buf = append(append(e.buf, data...), '\n')
+	case Protobuf:
+		e.buf = protowire.AppendBytes(e.buf, AppendProto(nil, event))
+	default:
+		return fmt.Errorf("unknown wire format %s", e.format)
+	}
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+// Close implements sink.Sink. It flushes w if it has a Flush method, such as a
+// bufio.Writer, and leaves it open.
+func (e *Encoder) Close() error {
+	if flusher, ok := e.w.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Decoder reads a stream of events written by an Encoder in the same format.
+type Decoder struct {
+	r      *bufio.Reader
+	format Format
+	json   *json.Decoder
+	buf    []byte
+}
+
+// NewDecoder creates a decoder reading from r.
+//
+// r io.Reader: The source, such as a connection or a file. It is read ahead of the events decoded.
+// format Format: The encoding of the events.
+//
+// Returns:
+//   - *Decoder: The decoder, ready to read events.
+func NewDecoder(r io.Reader, format Format) *Decoder {
+	d := &Decoder{r: bufio.NewReader(r), format: format}
+	if format == JSON {
+		d.json = json.NewDecoder(d.r)
+	}
+	return d
+}
+
+// Decode reads the next event of the stream.
+//
+// Returns:
+//   - contracts.MIDI: The event.
+//   - error: io.EOF at the end of the stream, io.ErrUnexpectedEOF if it ends within an
+//     event, ErrInvalidMessage if an event cannot be decoded, or the error of the reader.
+func (d *Decoder) Decode() (contracts.MIDI, error) {
+	switch d.format {
+	case JSON:
+		var event contracts.MIDI
+		if err := d.json.Decode(&event); err != nil {
+			var syntax *json.SyntaxError
+			var mismatch *json.UnmarshalTypeError
+			if errors.As(err, &syntax) || errors.As(err, &mismatch) {
+				return contracts.MIDI{}, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+			}
+			return contracts.MIDI{}, err
+		}
+		return event, nil
+	case Protobuf:
+		size, err := readVarint(d.r)
+		if err != nil {
+			return contracts.MIDI{}, err
+		}
+		if size > maxMessageSize {
+			return contracts.MIDI{}, fmt.Errorf("%w: message of %d bytes", ErrInvalidMessage, size)
+		}
+		if cap(d.buf) < int(size) {
+			d.buf = make([]byte, size)
+		}
+		d.buf = d.buf[:size]
+		if _, err := io.ReadFull(d.r, d.buf); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return contracts.MIDI{}, err
+		}
+		return UnmarshalProto(d.buf)
+	default:
+		return contracts.MIDI{}, fmt.Errorf("unknown wire format %s", d.format)
+	}
+}
+
+// readVarint reads a varint from r, returning io.EOF if r ends before it and
+// io.ErrUnexpectedEOF if r ends within it.
+func readVarint(r io.ByteReader) (uint64, error) {
+	var value uint64
+	for shift := 0; shift < 64; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && shift > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		value |= uint64(b&0x7F) << shift
+		if b < 0x80 {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: length prefix overflows", ErrInvalidMessage)
+}
@@ -0,0 +1,177 @@
+// Package wire encodes MIDI events for transport over WebSockets, message queues and
+// files, as JSON or as Protocol Buffers, so consumers share one format. Single events are
+// encoded with Marshal and decoded with Unmarshal, one per message of the transport, and
+// streams of events with an Encoder and a Decoder:
+//
+//	encoder := wire.NewEncoder(conn, wire.JSON)
+//	go sink.Drain(eventChannel, encoder)
+//
+//	decoder := wire.NewDecoder(conn, wire.JSON)
+//	for {
+//		event, err := decoder.Decode()
+//		...
+//	}
+//
+// The JSON form of an event is that of contracts.MIDI.MarshalJSON. The Protobuf form is
+// the MIDIEvent message of midi.proto, for consumers in other languages; it is encoded
+// without generated code.
+package wire
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/leandrodaf/midi/sdk/contracts"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ErrInvalidMessage is returned when data does not hold an encoded event.
+var ErrInvalidMessage = errors.New("invalid encoded MIDI event")
+
+// Format is an encoding of events.
+type Format int
+
+const (
+	JSON     Format = iota // JSON objects; streams hold one per line.
+	Protobuf               // MIDIEvent messages of midi.proto; streams prefix each with its length as a varint.
+)
+
+// String names the format.
+func (f Format) String() string {
+	switch f {
+	case JSON:
+		return "json"
+	case Protobuf:
+		return "protobuf"
+	default:
+		return "Format(" + strconv.Itoa(int(f)) + ")"
+	}
+}
+
+// Field numbers of the MIDIEvent message.
+const (
+	fieldTimestamp      protowire.Number = 1
+	fieldCommand        protowire.Number = 2
+	fieldNote           protowire.Number = 3
+	fieldVelocity       protowire.Number = 4
+	fieldSourceDeviceID protowire.Number = 5
+)
+
+// Marshal encodes event in format.
+//
+// event contracts.MIDI: The event to encode.
+// format Format: The encoding.
+//
+// Returns:
+//   - []byte: The encoded event, without a trailing newline or length prefix.
+//   - error: An error if the format is unknown.
+func Marshal(event contracts.MIDI, format Format) ([]byte, error) {
+	switch format {
+	case JSON:
+		return json.Marshal(event)
+	case Protobuf:
+		return AppendProto(nil, event), nil
+	default:
+		return nil, fmt.Errorf("unknown wire format %s", format)
+	}
+}
+
+// Unmarshal decodes an event encoded by Marshal in format.
+//
+// data []byte: The encoded event.
+// format Format: The encoding.
+//
+// Returns:
+//   - contracts.MIDI: The event.
+//   - error: ErrInvalidMessage if data does not hold an event, or an error if the format is unknown.
+func Unmarshal(data []byte, format Format) (contracts.MIDI, error) {
+	switch format {
+	case JSON:
+		var event contracts.MIDI
+		if err := json.Unmarshal(data, &event); err != nil {
+			return contracts.MIDI{}, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+		}
+		return event, nil
+	case Protobuf:
+		return UnmarshalProto(data)
+	default:
+		return contracts.MIDI{}, fmt.Errorf("unknown wire format %s", format)
+	}
+}
+
+// AppendProto appends the MIDIEvent message of event to b. Fields of value zero are
+// omitted, as in proto3.
+func AppendProto(b []byte, event contracts.MIDI) []byte {
+	if event.Timestamp != 0 {
+		b = protowire.AppendTag(b, fieldTimestamp, protowire.VarintType)
+		b = protowire.AppendVarint(b, event.Timestamp)
+	}
+	for _, field := range []struct {
+		number protowire.Number
+		value  byte
+	}{{fieldCommand, event.Command}, {fieldNote, event.Note}, {fieldVelocity, event.Velocity}} {
+		if field.value != 0 {
+			b = protowire.AppendTag(b, field.number, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(field.value))
+		}
+	}
+	if event.SourceDeviceID != 0 {
+		b = protowire.AppendTag(b, fieldSourceDeviceID, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(int64(int32(event.SourceDeviceID))))
+	}
+	return b
+}
+
+// UnmarshalProto decodes a MIDIEvent message. Unknown fields are skipped, so messages of
+// later versions of the schema are read.
+//
+// data []byte: The message.
+//
+// Returns:
+//   - contracts.MIDI: The event.
+//   - error: ErrInvalidMessage if data is not a valid message or a byte field is above 255.
+func UnmarshalProto(data []byte) (contracts.MIDI, error) {
+	var event contracts.MIDI
+	for len(data) > 0 {
+		number, kind, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return contracts.MIDI{}, fmt.Errorf("%w: %w", ErrInvalidMessage, protowire.ParseError(n))
+		}
+		data = data[n:]
+		if kind != protowire.VarintType || number < fieldTimestamp || number > fieldSourceDeviceID {
+			n = protowire.ConsumeFieldValue(number, kind, data)
+			if n < 0 {
+				return contracts.MIDI{}, fmt.Errorf("%w: %w", ErrInvalidMessage, protowire.ParseError(n))
+			}
+			data = data[n:]
+			continue
+		}
+		value, n := protowire.ConsumeVarint(data)
+		if n < 0 {
+			return contracts.MIDI{}, fmt.Errorf("%w: %w", ErrInvalidMessage, protowire.ParseError(n))
+		}
+		data = data[n:]
+		switch number {
+		case fieldTimestamp:
+			event.Timestamp = value
+		case fieldSourceDeviceID:
+			event.SourceDeviceID = int(int32(value))
+		default:
+			if value > math.MaxUint8 {
+				return contracts.MIDI{}, fmt.Errorf("%w: field %d is %d, above 255", ErrInvalidMessage, number, value)
+			}
+			switch number {
+			case fieldCommand:
+				event.Command = byte(value)
+			case fieldNote:
+				event.Note = byte(value)
+			case fieldVelocity:
+				event.Velocity = byte(value)
+			}
+		}
+	}
+	return event, nil
+}